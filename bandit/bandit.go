@@ -23,6 +23,35 @@ type Strategy interface {
 	Serialize() interface{} // Return a JSON representation of the strategy
 }
 
+// RewardStrategy is a Strategy that can be updated with float64 rewards, e.g.
+// for latency or revenue based rewards that would lose information if they
+// were converted to integers. All of the strategies in this package implement
+// RewardStrategy; use WithRewards to adapt other strategies.
+type RewardStrategy interface {
+	Strategy
+	UpdateReward(arm int, reward float64) // Update the given arm with a float reward
+}
+
+// WithRewards returns the strategy as a RewardStrategy. If the strategy does not
+// implement UpdateReward, it is wrapped so that float rewards are rounded to the
+// nearest integer and passed to Update.
+func WithRewards(strategy Strategy) RewardStrategy {
+	if rs, ok := strategy.(RewardStrategy); ok {
+		return rs
+	}
+	return &intRewards{strategy}
+}
+
+// Adapts a Strategy with integer rewards to the RewardStrategy interface.
+type intRewards struct {
+	Strategy
+}
+
+// UpdateReward rounds the reward to the nearest integer and calls Update.
+func (s *intRewards) UpdateReward(arm int, reward float64) {
+	s.Update(arm, int(math.Round(reward)))
+}
+
 //===========================================================================
 // Epsilon Greedy Multi-Armed Bandit
 //===========================================================================
@@ -31,9 +60,10 @@ type Strategy interface {
 // maximizing value is selected with probability epsilon and a uniform random
 // selection is made with probability 1-epsilon.
 type EpsilonGreedy struct {
-	Epsilon float64   // Probability of selecting maximizing value
-	counts  []uint64  // Number of times each index was selected
-	values  []float64 // Reward values condition by frequency
+	Epsilon float64    // Probability of selecting maximizing value
+	Rand    *rand.Rand // Source of random selections, the global source if nil
+	counts  []uint64   // Number of times each index was selected
+	values  []float64  // Reward values condition by frequency
 }
 
 // Init the bandit with nArms number of possible choices, which are referred
@@ -46,7 +76,7 @@ func (b *EpsilonGreedy) Init(nArms int) {
 // Select the arm with the maximizing value with probability epsilon,
 // otherwise uniform random selection of all arms with probability 1-epsilon.
 func (b *EpsilonGreedy) Select() int {
-	if rand.Float64() > b.Epsilon {
+	if randFloat64(b.Rand) > b.Epsilon {
 		// Select the maximal value from values.
 		max := -1.0
 		idx := -1
@@ -63,18 +93,23 @@ func (b *EpsilonGreedy) Select() int {
 	}
 
 	// Otherwise return any of the values
-	return rand.Intn(len(b.values))
+	return randIntn(b.Rand, len(b.values))
 }
 
-// Update the selected arm with the reward so that the strategy can learn the
-// maximizing value (conditioned by the frequency of selection).
+// Update the selected arm with an integer reward (see UpdateReward).
 func (b *EpsilonGreedy) Update(arm, reward int) {
+	b.UpdateReward(arm, float64(reward))
+}
+
+// UpdateReward updates the selected arm with the reward so that the strategy
+// can learn the maximizing value (conditioned by the frequency of selection).
+func (b *EpsilonGreedy) UpdateReward(arm int, reward float64) {
 	// Update the frequency
 	b.counts[arm]++
 	n := float64(b.counts[arm])
 
 	value := b.values[arm]
-	b.values[arm] = ((n-1)/n)*value + (1/n)*float64(reward)
+	b.values[arm] = ((n-1)/n)*value + (1/n)*reward
 }
 
 // Counts returns the frequency each arm was selected
@@ -87,6 +122,11 @@ func (b *EpsilonGreedy) Values() []float64 {
 	return b.values
 }
 
+// SetRand sets the source of random selections, nil for the global source.
+func (b *EpsilonGreedy) SetRand(rng *rand.Rand) {
+	b.Rand = rng
+}
+
 // Serialize the bandit strategy to dump to JSON.
 func (b *EpsilonGreedy) Serialize() interface{} {
 	data := make(map[string]interface{})
@@ -106,8 +146,9 @@ func (b *EpsilonGreedy) Serialize() interface{} {
 // to an exploring learning strategy at start and prefering exploitation as
 // more selections are made.
 type AnnealingEpsilonGreedy struct {
-	counts []uint64  // Number of times each index was selected
-	values []float64 // Reward values condition by frequency
+	Rand   *rand.Rand // Source of random selections, the global source if nil
+	counts []uint64   // Number of times each index was selected
+	values []float64  // Reward values condition by frequency
 }
 
 // Init the bandit with nArms number of possible choices, which are referred
@@ -133,7 +174,7 @@ func (b *AnnealingEpsilonGreedy) Epsilon() float64 {
 // Select the arm with the maximizing value with probability epsilon,
 // otherwise uniform random selection of all arms with probability 1-epsilon.
 func (b *AnnealingEpsilonGreedy) Select() int {
-	if rand.Float64() > b.Epsilon() {
+	if randFloat64(b.Rand) > b.Epsilon() {
 		// Select the maximal value from values.
 		max := -1.0
 		idx := -1
@@ -150,18 +191,23 @@ func (b *AnnealingEpsilonGreedy) Select() int {
 	}
 
 	// Otherwise return any of the values
-	return rand.Intn(len(b.values))
+	return randIntn(b.Rand, len(b.values))
 }
 
-// Update the selected arm with the reward so that the strategy can learn the
-// maximizing value (conditioned by the frequency of selection).
+// Update the selected arm with an integer reward (see UpdateReward).
 func (b *AnnealingEpsilonGreedy) Update(arm, reward int) {
+	b.UpdateReward(arm, float64(reward))
+}
+
+// UpdateReward updates the selected arm with the reward so that the strategy
+// can learn the maximizing value (conditioned by the frequency of selection).
+func (b *AnnealingEpsilonGreedy) UpdateReward(arm int, reward float64) {
 	// Update the frequency
 	b.counts[arm]++
 	n := float64(b.counts[arm])
 
 	value := b.values[arm]
-	b.values[arm] = ((n-1)/n)*value + (1/n)*float64(reward)
+	b.values[arm] = ((n-1)/n)*value + (1/n)*reward
 }
 
 // Counts returns the frequency each arm was selected
@@ -174,6 +220,11 @@ func (b *AnnealingEpsilonGreedy) Values() []float64 {
 	return b.values
 }
 
+// SetRand sets the source of random selections, nil for the global source.
+func (b *AnnealingEpsilonGreedy) SetRand(rng *rand.Rand) {
+	b.Rand = rng
+}
+
 // Serialize the bandit strategy to dump to JSON.
 func (b *AnnealingEpsilonGreedy) Serialize() interface{} {
 	data := make(map[string]interface{})
@@ -192,8 +243,9 @@ func (b *AnnealingEpsilonGreedy) Serialize() interface{} {
 // While it tracks the frequency of selection and the reward costs, this
 // information does not affect the way it selects values.
 type Uniform struct {
-	counts []uint64  // Number of times each index was selected
-	values []float64 // Reward values condition by frequency
+	Rand   *rand.Rand // Source of random selections, the global source if nil
+	counts []uint64   // Number of times each index was selected
+	values []float64  // Reward values condition by frequency
 }
 
 // Init the bandit with nArms number of possible choices, which are referred
@@ -205,18 +257,23 @@ func (b *Uniform) Init(nArms int) {
 
 // Select the arm with equal probability for each choice.
 func (b *Uniform) Select() int {
-	return rand.Intn(len(b.values))
+	return randIntn(b.Rand, len(b.values))
 }
 
-// Update the selected arm with the reward so that the strategy can learn the
-// maximizing value (conditioned by the frequency of selection).
+// Update the selected arm with an integer reward (see UpdateReward).
 func (b *Uniform) Update(arm, reward int) {
+	b.UpdateReward(arm, float64(reward))
+}
+
+// UpdateReward updates the selected arm with the reward so that the strategy
+// can learn the maximizing value (conditioned by the frequency of selection).
+func (b *Uniform) UpdateReward(arm int, reward float64) {
 	// Update the frequency
 	b.counts[arm]++
 	n := float64(b.counts[arm])
 
 	value := b.values[arm]
-	b.values[arm] = ((n-1)/n)*value + (1/n)*float64(reward)
+	b.values[arm] = ((n-1)/n)*value + (1/n)*reward
 }
 
 // Counts returns the frequency each arm was selected
@@ -229,6 +286,11 @@ func (b *Uniform) Values() []float64 {
 	return b.values
 }
 
+// SetRand sets the source of random selections, nil for the global source.
+func (b *Uniform) SetRand(rng *rand.Rand) {
+	b.Rand = rng
+}
+
 // Serialize the bandit strategy to dump to JSON.
 func (b *Uniform) Serialize() interface{} {
 	data := make(map[string]interface{})
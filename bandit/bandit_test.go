@@ -0,0 +1,96 @@
+package bandit
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Test that the strategies converge on an arm that is clearly the best.
+func TestConvergence(t *testing.T) {
+	probs := []float64{0.1, 0.5, 0.9}
+	factories := map[string]func() Strategy{
+		"epsilon greedy":    func() Strategy { return &EpsilonGreedy{Epsilon: 0.1} },
+		"ucb1":              func() Strategy { return &UCB1{} },
+		"ucb2":              func() Strategy { return &UCB2{Alpha: 0.1} },
+		"thompson sampling": func() Strategy { return &ThompsonSampling{} },
+		"softmax":           func() Strategy { return &Softmax{Temperature: 0.1} },
+		"annealing softmax": func() Strategy { return &AnnealingSoftmax{} },
+		"budgeted":          func() Strategy { return &Budgeted{Epsilon: 0.1} },
+	}
+
+	for name, factory := range factories {
+		strategy := factory()
+		Seed(strategy, 42)
+		arms := selections(strategy, probs, 2000, 1)
+
+		// The best arm should be selected most of the time after learning
+		best := 0
+		for _, arm := range arms[1000:] {
+			if arm == 2 {
+				best++
+			}
+		}
+
+		if frac := float64(best) / 1000; frac < 0.8 {
+			t.Errorf("%s selected the best arm in %0.1f%% of the last 1000 trials", name, frac*100)
+		}
+
+		if counts := strategy.Counts(); counts[2] <= counts[0] || counts[2] <= counts[1] {
+			t.Errorf("%s did not select the best arm most often: %v", name, counts)
+		}
+	}
+}
+
+// Test that the budgeted strategy prefers the arm with the most reward per unit
+// cost and stops selecting arms when the budget is exhausted.
+func TestBudgeted(t *testing.T) {
+	// The expensive arm has the most reward but the cheap arm has the most reward per unit cost
+	rewards := []float64{1.0, 0.5}
+	costs := []float64{4.0, 1.0}
+
+	strategy := &Budgeted{Epsilon: 0.1, Budget: 1000, Rand: rand.New(rand.NewSource(42))}
+	strategy.Init(2)
+
+	trials := 0
+	for !strategy.Exhausted() {
+		arm := strategy.Select()
+		strategy.UpdateCost(arm, rewards[arm], costs[arm])
+		if trials++; trials > 1000 {
+			t.Fatal("budgeted strategy did not exhaust its budget")
+		}
+	}
+
+	if counts := strategy.Counts(); counts[1] < 5*counts[0] {
+		t.Errorf("expected the cheap arm to be selected most often: %v", counts)
+	}
+
+	if spent := strategy.Spent(); spent > strategy.Budget || spent < strategy.Budget-costs[0] {
+		t.Errorf("expected the budget to be spent but spent %v of %v", spent, strategy.Budget)
+	}
+
+	// Selections stay in range once the budget is exhausted
+	if arm := strategy.Select(); arm != 1 {
+		t.Errorf("expected the arm with the most reward per unit cost once exhausted got %d", arm)
+	}
+
+	if remaining := strategy.Remaining(); remaining != strategy.Budget-strategy.Spent() {
+		t.Errorf("unexpected remaining budget %v", remaining)
+	}
+
+	if ratio := strategy.Ratio(1); ratio != 0.5 {
+		t.Errorf("expected a reward per unit cost of 0.5 but got %v", ratio)
+	}
+
+	// Update charges a unit cost
+	unlimited := &Budgeted{}
+	unlimited.Init(2)
+	unlimited.Update(0, 1)
+	unlimited.UpdateReward(1, 1)
+	if unlimited.Exhausted() {
+		t.Error("expected an unlimited budget never to be exhausted")
+	}
+
+	if unlimited.Spent() != 2 || unlimited.Costs()[0] != 1 || unlimited.Costs()[1] != 1 {
+		t.Errorf("expected unit costs but spent %v with costs %v", unlimited.Spent(), unlimited.Costs())
+	}
+}
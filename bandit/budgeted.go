@@ -0,0 +1,209 @@
+package bandit
+
+import (
+	"math"
+	"math/rand"
+)
+
+//===========================================================================
+// Budgeted Multi-Armed Bandit
+//===========================================================================
+
+// Budgeted implements an epsilon greedy strategy for arms that have a cost as
+// well as a reward, e.g. to choose between expensive and cheap backends. The
+// mean reward and the mean cost of each arm are tracked and the arm with the
+// largest reward per unit cost is exploited with probability 1-epsilon, while a
+// uniform random selection of the affordable arms is made with probability
+// epsilon. Every arm is selected once before any arm is exploited so that its
+// cost is known. Arms without a cost are preferred to all other arms if their
+// mean reward is positive.
+//
+// If the Budget is positive, the costs of the updates are spent from it and
+// only arms whose mean cost is within the remaining budget are selected; once
+// no arm is affordable the budget is Exhausted, which callers should check
+// before selecting. Update and UpdateReward charge a unit cost, use UpdateCost
+// to charge the observed cost of the selection.
+//
+// Budgeted strategies cannot be discounted or windowed since the wrappers do
+// not pass the costs to the strategy.
+type Budgeted struct {
+	Epsilon float64    // Probability of exploring a random affordable arm
+	Budget  float64    // Total cost that can be spent, unlimited if not positive
+	Rand    *rand.Rand // Source of random selections, the global source if nil
+	counts  []uint64   // Number of times each index was selected
+	values  []float64  // Reward values condition by frequency
+	costs   []float64  // Cost values condition by frequency
+	spent   float64    // Total cost of all updates
+}
+
+// Init the bandit with nArms number of possible choices, which are referred
+// to by index in the Counts, Values, and Costs arrays.
+func (b *Budgeted) Init(nArms int) {
+	b.counts = make([]uint64, nArms, nArms)
+	b.values = make([]float64, nArms, nArms)
+	b.costs = make([]float64, nArms, nArms)
+	b.spent = 0.0
+}
+
+// Select the affordable arm with the largest reward per unit cost with
+// probability 1-epsilon, otherwise uniform random selection of all affordable
+// arms with probability epsilon. Arms that have not been selected yet are
+// selected first. The selection is always a valid arm so that the strategy can
+// be used wherever a Strategy is expected: if the budget is exhausted, the arm
+// with the largest reward per unit cost of all arms is returned.
+func (b *Budgeted) Select() int {
+	affordable := make([]int, 0, len(b.counts))
+	for i := range b.counts {
+		if b.affordable(i) {
+			affordable = append(affordable, i)
+		}
+	}
+
+	if len(affordable) == 0 {
+		all := make([]int, len(b.counts))
+		for i := range all {
+			all[i] = i
+		}
+		return b.best(all)
+	}
+
+	for _, i := range affordable {
+		if b.counts[i] == 0 {
+			return i
+		}
+	}
+
+	if randFloat64(b.Rand) > b.Epsilon {
+		return b.best(affordable)
+	}
+
+	// Otherwise return any of the affordable arms
+	return affordable[randIntn(b.Rand, len(affordable))]
+}
+
+// Exhausted returns true if no arm is affordable with the remaining budget. A
+// strategy with an unlimited budget is never exhausted.
+func (b *Budgeted) Exhausted() bool {
+	for i := range b.counts {
+		if b.affordable(i) {
+			return false
+		}
+	}
+	return len(b.counts) > 0
+}
+
+// Update the selected arm with an integer reward and a unit cost.
+func (b *Budgeted) Update(arm, reward int) {
+	b.UpdateCost(arm, float64(reward), 1.0)
+}
+
+// UpdateReward updates the selected arm with the reward and a unit cost so that
+// the strategy satisfies the RewardStrategy interface.
+func (b *Budgeted) UpdateReward(arm int, reward float64) {
+	b.UpdateCost(arm, reward, 1.0)
+}
+
+// UpdateCost updates the selected arm with the reward and the cost of the
+// selection so that the strategy can learn the reward per unit cost of the arm
+// (conditioned by the frequency of selection). The cost is spent from the
+// budget.
+func (b *Budgeted) UpdateCost(arm int, reward, cost float64) {
+	// Update the frequency
+	b.counts[arm]++
+	n := float64(b.counts[arm])
+
+	value := b.values[arm]
+	b.values[arm] = ((n-1)/n)*value + (1/n)*reward
+
+	mean := b.costs[arm]
+	b.costs[arm] = ((n-1)/n)*mean + (1/n)*cost
+	b.spent += cost
+}
+
+// Counts returns the frequency each arm was selected
+func (b *Budgeted) Counts() []uint64 {
+	return b.counts
+}
+
+// Values returns the reward distribution of each arm
+func (b *Budgeted) Values() []float64 {
+	return b.values
+}
+
+// Costs returns the cost distribution of each arm
+func (b *Budgeted) Costs() []float64 {
+	return b.costs
+}
+
+// Ratio returns the mean reward per unit of mean cost of the arm. Arms without
+// a cost have an infinite ratio if their mean reward is positive, otherwise
+// their ratio is their mean reward.
+func (b *Budgeted) Ratio(arm int) float64 {
+	if b.costs[arm] <= 0 {
+		if b.values[arm] > 0 {
+			return math.Inf(1)
+		}
+		return b.values[arm]
+	}
+	return b.values[arm] / b.costs[arm]
+}
+
+// Spent returns the total cost of all updates.
+func (b *Budgeted) Spent() float64 {
+	return b.spent
+}
+
+// Remaining returns the budget that has not been spent, or +Inf if the budget
+// is unlimited.
+func (b *Budgeted) Remaining() float64 {
+	if b.Budget <= 0 {
+		return math.Inf(1)
+	}
+	return math.Max(b.Budget-b.spent, 0)
+}
+
+// SetRand sets the source of random selections, nil for the global source.
+func (b *Budgeted) SetRand(rng *rand.Rand) {
+	b.Rand = rng
+}
+
+// Serialize the bandit strategy to dump to JSON.
+func (b *Budgeted) Serialize() interface{} {
+	data := make(map[string]interface{})
+	data["strategy"] = "budgeted epsilon greedy"
+	data["epsilon"] = b.Epsilon
+	data["budget"] = b.Budget
+	data["spent"] = b.spent
+	data["counts"] = b.counts
+	data["values"] = b.values
+	data["costs"] = b.costs
+	return data
+}
+
+// Returns the index of the arm with the maximal reward per unit cost, the first
+// arm if the arms are tied or there are no arms.
+func (b *Budgeted) best(arms []int) int {
+	max := math.Inf(-1)
+	idx := -1
+	for _, i := range arms {
+		if ratio := b.Ratio(i); idx < 0 || ratio > max {
+			max = ratio
+			idx = i
+		}
+	}
+
+	if idx < 0 {
+		return 0
+	}
+	return idx
+}
+
+// Returns true if the mean cost of the arm is within the remaining budget; arms
+// that have not been selected are affordable as long as budget remains.
+func (b *Budgeted) affordable(arm int) bool {
+	remaining := b.Remaining()
+	if b.counts[arm] == 0 {
+		return remaining > 0
+	}
+	return b.costs[arm] <= remaining
+}
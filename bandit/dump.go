@@ -0,0 +1,90 @@
+package bandit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+//===========================================================================
+// Strategy Persistence
+//===========================================================================
+
+// The serialized representation of a strategy, see the Serialize methods.
+type serialized struct {
+	Strategy    string      `json:"strategy"`
+	Epsilon     float64     `json:"epsilon"`
+	Alpha       float64     `json:"alpha"`
+	Temperature float64     `json:"temperature"`
+	Discount    float64     `json:"discount"`
+	Window      int         `json:"window"`
+	Weights     []float64   `json:"weights"`
+	Counts      []uint64    `json:"counts"`
+	Values      []float64   `json:"values"`
+	Rewards     [][]float64 `json:"rewards"`
+	Epochs      []int       `json:"epochs"`
+	Windows     [][]float64 `json:"windows"`
+	Budget      float64     `json:"budget"`
+	Spent       float64     `json:"spent"`
+	Costs       []float64   `json:"costs"`
+}
+
+// Dump writes the serialized state of the strategy to w as JSON so that it can
+// be restored with Load, e.g. to checkpoint a long-running service so that it
+// does not lose learned values across restarts.
+func Dump(strategy Strategy, w io.Writer) error {
+	return json.NewEncoder(w).Encode(strategy.Serialize())
+}
+
+// Load reads the JSON state of a strategy written by Dump and restores it with
+// FromSerialized.
+func Load(r io.Reader) (Strategy, error) {
+	var data map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return nil, fmt.Errorf("could not decode strategy: %s", err)
+	}
+	return FromSerialized(data)
+}
+
+// FromSerialized creates a strategy from its serialized representation, either
+// the value returned by Serialize or its decoded JSON, and restores its counts,
+// values, and parameters. The scalarization of a multi-objective strategy is
+// only restored if it is a WeightedSum; other scalarizations must be set on the
+// strategy again after it is restored.
+func FromSerialized(data interface{}) (_ Strategy, err error) {
+	// Round trip through JSON to normalize the types of the values
+	var raw []byte
+	if raw, err = json.Marshal(data); err != nil {
+		return nil, fmt.Errorf("could not serialize strategy: %s", err)
+	}
+
+	s := serialized{}
+	if err = json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("could not parse serialized strategy: %s", err)
+	}
+
+	if len(s.Values) != len(s.Counts) || (s.Rewards != nil && len(s.Rewards) != len(s.Counts)) || (s.Costs != nil && len(s.Costs) != len(s.Counts)) {
+		return nil, errors.New("serialized strategy has inconsistent number of arms")
+	}
+
+	conf := Config{
+		Strategy:    s.Strategy,
+		Arms:        len(s.Counts),
+		Epsilon:     s.Epsilon,
+		Weights:     s.Weights,
+		Alpha:       s.Alpha,
+		Temperature: s.Temperature,
+		Discount:    s.Discount,
+		Window:      s.Window,
+		Budget:      s.Budget,
+	}
+
+	var strategy Strategy
+	if strategy, err = conf.New(); err != nil {
+		return nil, err
+	}
+
+	restore(strategy, &checkpoint{Config: conf, Counts: s.Counts, Values: s.Values, Rewards: s.Rewards, Windows: s.Windows, Epochs: s.Epochs, Costs: s.Costs, Spent: s.Spent})
+	return strategy, nil
+}
@@ -0,0 +1,74 @@
+package bandit
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Test that every strategy created by Config.New is restored by Load to a state
+// that dumps identically to the original strategy.
+func TestDumpLoad(t *testing.T) {
+	probs := []float64{0.2, 0.5, 0.7}
+	configs := map[string]Config{
+		"epsilon greedy":           {Strategy: StrategyEpsilonGreedy, Epsilon: 0.1},
+		"annealing epsilon greedy": {Strategy: StrategyAnnealingEpsilonGreedy},
+		"uniform":                  {Strategy: StrategyUniform},
+		"multi-objective":          {Strategy: StrategyMultiObjective, Epsilon: 0.1, Weights: []float64{0.5, 0.5}},
+		"ucb1":                     {Strategy: StrategyUCB1},
+		"ucb2":                     {Strategy: StrategyUCB2, Alpha: 0.2},
+		"thompson sampling":        {Strategy: StrategyThompsonSampling},
+		"softmax":                  {Strategy: StrategySoftmax, Temperature: 0.2},
+		"annealing softmax":        {Strategy: StrategyAnnealingSoftmax},
+		"budgeted":                 {Strategy: StrategyBudgeted, Epsilon: 0.1, Budget: 1000},
+		"discounted":               {Strategy: StrategyEpsilonGreedy, Epsilon: 0.1, Discount: 0.9},
+		"discounted ucb2":          {Strategy: StrategyUCB2, Alpha: 0.2, Discount: 0.95},
+		"windowed":                 {Strategy: StrategySoftmax, Temperature: 0.2, Window: 10},
+		"windowed ucb2":            {Strategy: StrategyUCB2, Alpha: 0.2, Window: 10},
+	}
+
+	for name, conf := range configs {
+		conf.Arms = len(probs)
+		conf.Seed = 42
+
+		strategy, err := conf.New()
+		if err != nil {
+			t.Errorf("%s: could not create strategy: %s", name, err)
+			continue
+		}
+
+		// Train the strategy so that its state is not the initial state
+		selections(strategy, probs, 200, 1)
+		switch s := strategy.(type) {
+		case *MultiObjective:
+			s.UpdateVector(0, 0.25, 0.75)
+		case *Budgeted:
+			s.UpdateCost(1, 0.5, 2.5)
+		}
+
+		first := &bytes.Buffer{}
+		if err = Dump(strategy, first); err != nil {
+			t.Errorf("%s: could not dump strategy: %s", name, err)
+			continue
+		}
+
+		var loaded Strategy
+		if loaded, err = Load(bytes.NewReader(first.Bytes())); err != nil {
+			t.Errorf("%s: could not load strategy: %s", name, err)
+			continue
+		}
+
+		second := &bytes.Buffer{}
+		if err = Dump(loaded, second); err != nil {
+			t.Errorf("%s: could not dump loaded strategy: %s", name, err)
+			continue
+		}
+
+		if !bytes.Equal(first.Bytes(), second.Bytes()) {
+			t.Errorf("%s: loaded strategy does not match the dumped strategy\n%s\n%s", name, first, second)
+		}
+	}
+
+	if _, err := Load(bytes.NewBufferString(`{"strategy": "epsilon greedy", "counts": [1, 2], "values": [0.5]}`)); err == nil {
+		t.Error("expected an error loading a strategy with inconsistent arms")
+	}
+}
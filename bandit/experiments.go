@@ -0,0 +1,521 @@
+package bandit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Names of the strategies that can be created from an experiment config.
+const (
+	StrategyEpsilonGreedy          = "epsilon greedy"
+	StrategyAnnealingEpsilonGreedy = "annealing epsilon greedy"
+	StrategyUniform                = "uniform selection"
+	StrategyMultiObjective         = "multi-objective epsilon greedy"
+	StrategyUCB1                   = "ucb1"
+	StrategyUCB2                   = "ucb2"
+	StrategyThompsonSampling       = "thompson sampling"
+	StrategySoftmax                = "softmax"
+	StrategyAnnealingSoftmax       = "annealing softmax"
+	StrategyBudgeted               = "budgeted epsilon greedy"
+)
+
+//===========================================================================
+// Experiment Configuration
+//===========================================================================
+
+// Config describes the strategy of a named experiment so that it can be
+// created lazily by the Experiments registry and restored from a checkpoint.
+type Config struct {
+	Strategy    string    `json:"strategy"`              // name of the strategy, e.g. "epsilon greedy"
+	Arms        int       `json:"arms"`                  // number of choices in the experiment
+	Epsilon     float64   `json:"epsilon,omitempty"`     // epsilon of the greedy strategies
+	Weights     []float64 `json:"weights,omitempty"`     // objective weights of the multi-objective strategy
+	Alpha       float64   `json:"alpha,omitempty"`       // epoch growth of the ucb2 strategy
+	Temperature float64   `json:"temperature,omitempty"` // temperature of the softmax strategy
+	Discount    float64   `json:"discount,omitempty"`    // if positive, discount the rewards of the strategy
+	Window      int       `json:"window,omitempty"`      // if positive, average the rewards of the strategy in a sliding window
+	Budget      float64   `json:"budget,omitempty"`      // if positive, the total cost the budgeted strategy can spend
+	Seed        int64     `json:"seed,omitempty"`        // if not zero, seeds a source of random selections for the strategy
+}
+
+// New creates and initializes the strategy described by the config. If the
+// config has a discount or a window, the strategy is wrapped by Discounted or
+// Windowed respectively so that it adapts to rewards that drift over time. If
+// the config has a seed, the strategy makes random selections with its own
+// source seeded with it rather than the global source (see SetRand).
+func (c Config) New() (Strategy, error) {
+	if c.Arms < 1 {
+		return nil, errors.New("experiment must have at least one arm")
+	}
+
+	if c.Discount > 0 && c.Window > 0 {
+		return nil, errors.New("experiment cannot have both a discount and a window")
+	}
+
+	var strategy Strategy
+	switch strings.ToLower(strings.TrimSpace(c.Strategy)) {
+	case StrategyEpsilonGreedy, "epsilon-greedy":
+		strategy = &EpsilonGreedy{Epsilon: c.Epsilon}
+	case StrategyAnnealingEpsilonGreedy, "annealing":
+		strategy = &AnnealingEpsilonGreedy{}
+	case StrategyUniform, "uniform":
+		strategy = &Uniform{}
+	case StrategyMultiObjective, "multi-objective":
+		mo := &MultiObjective{Epsilon: c.Epsilon}
+		if len(c.Weights) > 0 {
+			mo.Scalarization = WeightedSum(c.Weights)
+		}
+		strategy = mo
+	case StrategyUCB1:
+		strategy = &UCB1{}
+	case StrategyUCB2:
+		strategy = &UCB2{Alpha: c.Alpha}
+	case StrategyThompsonSampling, "thompson":
+		strategy = &ThompsonSampling{}
+	case StrategySoftmax, "boltzmann":
+		strategy = &Softmax{Temperature: c.Temperature}
+	case StrategyAnnealingSoftmax:
+		strategy = &AnnealingSoftmax{}
+	case StrategyBudgeted, "budgeted":
+		strategy = &Budgeted{Epsilon: c.Epsilon, Budget: c.Budget}
+	default:
+		return nil, fmt.Errorf("unknown bandit strategy %q", c.Strategy)
+	}
+
+	if _, ok := strategy.(*MultiObjective); ok && (c.Discount > 0 || c.Window > 0) {
+		return nil, errors.New("multi-objective strategies cannot be discounted or windowed")
+	}
+
+	if _, ok := strategy.(*Budgeted); ok && (c.Discount > 0 || c.Window > 0) {
+		return nil, errors.New("budgeted strategies cannot be discounted or windowed")
+	}
+
+	switch {
+	case c.Discount > 0:
+		strategy = NewDiscounted(strategy, c.Discount)
+	case c.Window > 0:
+		strategy = NewWindowed(strategy, c.Window)
+	}
+
+	if c.Seed != 0 {
+		Seed(strategy, c.Seed)
+	}
+
+	strategy.Init(c.Arms)
+	return strategy, nil
+}
+
+//===========================================================================
+// Experiments Registry
+//===========================================================================
+
+// Experiments is a registry of named experiments so that application code can
+// select arms and update rewards by name rather than managing raw Strategy
+// instances. Strategies are created lazily from their config on first use. If
+// the registry has a path, the state of every experiment is checkpointed to it
+// as JSON and restored when the registry is created, so that learning carries
+// over between runs. The registry is safe for concurrent use.
+type Experiments struct {
+	sync.Mutex
+	path        string                 // path of the JSON checkpoint file, if any
+	configs     map[string]Config      // registered experiment configurations
+	strategies  map[string]Strategy    // lazily created strategies by name
+	checkpoints map[string]*checkpoint // restored state of strategies not yet created
+	stop        chan struct{}          // stops periodic checkpointing
+	done        chan struct{}          // closed when periodic checkpointing has stopped
+}
+
+// The state of an experiment in the checkpoint file.
+type checkpoint struct {
+	Config  Config      `json:"config"`
+	Counts  []uint64    `json:"counts"`
+	Values  []float64   `json:"values"`
+	Rewards [][]float64 `json:"rewards,omitempty"`
+	Windows [][]float64 `json:"windows,omitempty"`
+	Epochs  []int       `json:"epochs,omitempty"`
+	Costs   []float64   `json:"costs,omitempty"`
+	Spent   float64     `json:"spent,omitempty"`
+}
+
+// NewExperiments creates a registry that checkpoints to the JSON file at path,
+// restoring the experiments in the file if it exists. If path is empty, the
+// experiments are kept in memory only.
+func NewExperiments(path string) (e *Experiments, err error) {
+	e = &Experiments{
+		path:        path,
+		configs:     make(map[string]Config),
+		strategies:  make(map[string]Strategy),
+		checkpoints: make(map[string]*checkpoint),
+	}
+
+	if path == "" {
+		return e, nil
+	}
+
+	var data []byte
+	if data, err = ioutil.ReadFile(path); err != nil {
+		if os.IsNotExist(err) {
+			return e, nil
+		}
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, &e.checkpoints); err != nil {
+		return nil, fmt.Errorf("could not parse experiments checkpoint %s: %s", path, err)
+	}
+
+	for name, cp := range e.checkpoints {
+		e.configs[name] = cp.Config
+	}
+	return e, nil
+}
+
+// Register the config of a named experiment. If the experiment was restored
+// from a checkpoint with a different strategy or number of arms, the restored
+// state is discarded; otherwise learning continues from the checkpoint.
+func (e *Experiments) Register(name string, conf Config) error {
+	if _, err := conf.New(); err != nil {
+		return fmt.Errorf("invalid experiment %q: %s", name, err)
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	if _, ok := e.strategies[name]; ok {
+		return fmt.Errorf("experiment %q is already running", name)
+	}
+
+	if cp, ok := e.checkpoints[name]; ok {
+		if !strings.EqualFold(cp.Config.Strategy, conf.Strategy) || cp.Config.Arms != conf.Arms {
+			delete(e.checkpoints, name)
+		}
+	}
+
+	e.configs[name] = conf
+	return nil
+}
+
+// Names returns the names of the registered experiments in sorted order.
+func (e *Experiments) Names() []string {
+	e.Lock()
+	defer e.Unlock()
+
+	names := make([]string, 0, len(e.configs))
+	for name := range e.configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get the strategy of the named experiment, creating it from its config and
+// restoring its checkpointed state on first use. The strategy is not safe for
+// concurrent use, use the Select and Update methods of the registry instead.
+func (e *Experiments) Get(name string) (Strategy, error) {
+	e.Lock()
+	defer e.Unlock()
+	return e.get(name)
+}
+
+// Select an arm of the named experiment. An error is returned if the experiment
+// is budgeted and no arm is affordable with its remaining budget.
+func (e *Experiments) Select(name string) (int, error) {
+	e.Lock()
+	defer e.Unlock()
+
+	strategy, err := e.get(name)
+	if err != nil {
+		return -1, err
+	}
+
+	if b, ok := strategy.(*Budgeted); ok && b.Exhausted() {
+		return -1, fmt.Errorf("experiment %q has exhausted its budget", name)
+	}
+	return strategy.Select(), nil
+}
+
+// Update the arm of the named experiment with the reward.
+func (e *Experiments) Update(name string, arm, reward int) error {
+	return e.UpdateReward(name, arm, float64(reward))
+}
+
+// UpdateReward updates the arm of the named experiment with a float reward.
+func (e *Experiments) UpdateReward(name string, arm int, reward float64) error {
+	e.Lock()
+	defer e.Unlock()
+
+	strategy, err := e.get(name)
+	if err != nil {
+		return err
+	}
+
+	if arm < 0 || arm >= len(strategy.Counts()) {
+		return fmt.Errorf("experiment %q has no arm %d", name, arm)
+	}
+
+	WithRewards(strategy).UpdateReward(arm, reward)
+	return nil
+}
+
+// UpdateVector updates the arm of the named multi-objective experiment with the
+// vector of rewards, one per objective.
+func (e *Experiments) UpdateVector(name string, arm int, rewards ...float64) error {
+	e.Lock()
+	defer e.Unlock()
+
+	strategy, err := e.get(name)
+	if err != nil {
+		return err
+	}
+
+	mo, ok := strategy.(*MultiObjective)
+	if !ok {
+		return fmt.Errorf("experiment %q is not a multi-objective experiment", name)
+	}
+
+	if arm < 0 || arm >= len(mo.Counts()) {
+		return fmt.Errorf("experiment %q has no arm %d", name, arm)
+	}
+
+	mo.UpdateVector(arm, rewards...)
+	return nil
+}
+
+// UpdateCost updates the arm of the named budgeted experiment with the reward
+// and the cost of the selection.
+func (e *Experiments) UpdateCost(name string, arm int, reward, cost float64) error {
+	e.Lock()
+	defer e.Unlock()
+
+	strategy, err := e.get(name)
+	if err != nil {
+		return err
+	}
+
+	b, ok := strategy.(*Budgeted)
+	if !ok {
+		return fmt.Errorf("experiment %q is not a budgeted experiment", name)
+	}
+
+	if arm < 0 || arm >= len(b.Counts()) {
+		return fmt.Errorf("experiment %q has no arm %d", name, arm)
+	}
+
+	b.UpdateCost(arm, reward, cost)
+	return nil
+}
+
+// Checkpoint writes the state of all experiments to the checkpoint file. The
+// file is synced to disk and replaced atomically so that a crash does not
+// corrupt the checkpoint.
+func (e *Experiments) Checkpoint() (err error) {
+	if e.path == "" {
+		return errors.New("experiments have no checkpoint path")
+	}
+
+	e.Lock()
+	state := make(map[string]*checkpoint, len(e.configs))
+	for name, cp := range e.checkpoints {
+		state[name] = cp
+	}
+
+	for name, strategy := range e.strategies {
+		cp := &checkpoint{
+			Config: e.configs[name],
+			Counts: append([]uint64(nil), strategy.Counts()...),
+			Values: append([]float64(nil), strategy.Values()...),
+		}
+
+		if mo, ok := strategy.(*MultiObjective); ok {
+			for _, rewards := range mo.Rewards() {
+				cp.Rewards = append(cp.Rewards, append([]float64(nil), rewards...))
+			}
+		}
+
+		if w, ok := strategy.(*Windowed); ok {
+			for _, window := range w.Windows() {
+				cp.Windows = append(cp.Windows, append([]float64(nil), window...))
+			}
+		}
+
+		if ucb2, ok := unwrap(strategy).(*UCB2); ok {
+			cp.Epochs = append([]int(nil), ucb2.epochs...)
+		}
+
+		if b, ok := strategy.(*Budgeted); ok {
+			cp.Costs = append([]float64(nil), b.Costs()...)
+			cp.Spent = b.Spent()
+		}
+		state[name] = cp
+	}
+	e.Unlock()
+
+	var data []byte
+	if data, err = json.MarshalIndent(state, "", "  "); err != nil {
+		return err
+	}
+
+	return writeAtomic(e.path, data)
+}
+
+// Writes the data to a temporary file in the same directory as the path, syncs
+// it to disk, then renames it to the path so that the rename never replaces the
+// previous checkpoint with a file whose contents have not been written.
+func writeAtomic(path string, data []byte) (err error) {
+	var tmp *os.File
+	if tmp, err = ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+"-*"); err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	// Match the permissions of a checkpoint written with ioutil.WriteFile
+	if err = os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Start checkpointing the experiments every interval in a background go
+// routine until Stop is called. Errors are sent on echan if it is not nil; a
+// pending send is abandoned when Stop is called so that Stop never blocks on a
+// channel that is no longer being read.
+func (e *Experiments) Start(interval time.Duration, echan chan<- error) error {
+	if e.path == "" {
+		return errors.New("experiments have no checkpoint path")
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	if e.stop != nil {
+		return errors.New("experiments are already being checkpointed")
+	}
+
+	e.stop, e.done = make(chan struct{}), make(chan struct{})
+	go func(stop, done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := e.Checkpoint(); err != nil && echan != nil {
+					select {
+					case echan <- err:
+					case <-stop:
+						return
+					}
+				}
+			case <-stop:
+				return
+			}
+		}
+	}(e.stop, e.done)
+	return nil
+}
+
+// Stop periodic checkpointing and write a final checkpoint.
+func (e *Experiments) Stop() error {
+	e.Lock()
+	stop, done := e.stop, e.done
+	e.stop, e.done = nil, nil
+	e.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+
+	if e.path == "" {
+		return nil
+	}
+	return e.Checkpoint()
+}
+
+// Returns the strategy of the experiment, creating it if necessary (not thread-safe).
+func (e *Experiments) get(name string) (_ Strategy, err error) {
+	if strategy, ok := e.strategies[name]; ok {
+		return strategy, nil
+	}
+
+	conf, ok := e.configs[name]
+	if !ok {
+		return nil, fmt.Errorf("no experiment named %q", name)
+	}
+
+	var strategy Strategy
+	if strategy, err = conf.New(); err != nil {
+		return nil, fmt.Errorf("invalid experiment %q: %s", name, err)
+	}
+
+	if cp, ok := e.checkpoints[name]; ok {
+		restore(strategy, cp)
+		delete(e.checkpoints, name)
+	}
+
+	e.strategies[name] = strategy
+	return strategy, nil
+}
+
+// Restores the state of the strategy from the checkpoint if the number of arms
+// matches; the reward vectors of multi-objective strategies, the windows of
+// windowed strategies, the epochs of ucb2 strategies, and the costs of budgeted
+// strategies are also restored.
+func restore(strategy Strategy, cp *checkpoint) {
+	counts, values := strategy.Counts(), strategy.Values()
+	if len(cp.Counts) != len(counts) {
+		return
+	}
+	copy(counts, cp.Counts)
+
+	if mo, ok := strategy.(*MultiObjective); ok {
+		if len(cp.Rewards) == len(mo.rewards) {
+			for i, rewards := range cp.Rewards {
+				mo.rewards[i] = append([]float64(nil), rewards...)
+			}
+		}
+		return
+	}
+
+	if len(cp.Values) == len(values) {
+		copy(values, cp.Values)
+	}
+
+	if w, ok := strategy.(*Windowed); ok {
+		w.restore(cp.Windows)
+	}
+
+	if ucb2, ok := unwrap(strategy).(*UCB2); ok && len(cp.Epochs) == len(ucb2.epochs) {
+		copy(ucb2.epochs, cp.Epochs)
+	}
+
+	if b, ok := strategy.(*Budgeted); ok {
+		if len(cp.Costs) == len(b.costs) {
+			copy(b.costs, cp.Costs)
+		}
+		b.spent = cp.Spent
+	}
+}
@@ -0,0 +1,145 @@
+package bandit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Test that experiments are restored from a checkpoint with the same state,
+// including the epochs of ucb2 strategies.
+func TestExperimentsCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "experiments.json")
+	configs := map[string]Config{
+		"ucb2":     {Strategy: StrategyUCB2, Arms: 3, Alpha: 0.2},
+		"windowed": {Strategy: StrategyUCB2, Arms: 3, Alpha: 0.2, Window: 10},
+		"budgeted": {Strategy: StrategyBudgeted, Arms: 3, Epsilon: 0.1, Budget: 1000, Seed: 42},
+	}
+
+	experiments, err := NewExperiments(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, conf := range configs {
+		if err = experiments.Register(name, conf); err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 100; i++ {
+			var arm int
+			if arm, err = experiments.Select(name); err != nil {
+				t.Fatal(err)
+			}
+
+			if err = experiments.UpdateReward(name, arm, float64(arm)/2); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err = experiments.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+
+	var restored *Experiments
+	if restored, err = NewExperiments(path); err != nil {
+		t.Fatal(err)
+	}
+
+	for name := range configs {
+		original, _ := experiments.Get(name)
+		strategy, err := restored.Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(original.Counts(), strategy.Counts()) || !reflect.DeepEqual(original.Values(), strategy.Values()) {
+			t.Errorf("%s: restored counts and values do not match", name)
+		}
+
+		if ucb2, ok := unwrap(original).(*UCB2); ok {
+			epochs := unwrap(strategy).(*UCB2).epochs
+			if !reflect.DeepEqual(ucb2.epochs, epochs) {
+				t.Errorf("%s: restored epochs %v do not match %v", name, epochs, ucb2.epochs)
+			}
+		}
+
+		if b, ok := original.(*Budgeted); ok {
+			r := strategy.(*Budgeted)
+			if b.Spent() != r.Spent() || !reflect.DeepEqual(b.Costs(), r.Costs()) {
+				t.Errorf("%s: restored costs do not match", name)
+			}
+		}
+	}
+}
+
+// Test that Stop does not block when checkpoint errors are not being read.
+func TestExperimentsStop(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "checkpoints")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	experiments, err := NewExperiments(filepath.Join(dir, "experiments.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Checkpoints fail once the directory is removed
+	if err = os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	echan := make(chan error)
+	if err = experiments.Start(time.Millisecond, echan); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = <-echan; err == nil {
+		t.Fatal("expected a checkpoint error")
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- experiments.Stop() }()
+
+	select {
+	case err = <-stopped:
+		if err == nil {
+			t.Error("expected the final checkpoint to fail")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop blocked on the unread error channel")
+	}
+}
+
+// Test that selecting an arm of a budgeted experiment with an exhausted budget
+// returns an error.
+func TestExperimentsExhausted(t *testing.T) {
+	experiments, err := NewExperiments("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = experiments.Register("budgeted", Config{Strategy: StrategyBudgeted, Arms: 2, Epsilon: 0.1, Budget: 3, Seed: 42}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		var arm int
+		if arm, err = experiments.Select("budgeted"); err != nil {
+			t.Fatalf("expected an affordable arm on selection %d got %s", i, err)
+		}
+
+		if err = experiments.UpdateCost("budgeted", arm, 1, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if arm, err := experiments.Select("budgeted"); err == nil || arm != -1 {
+		t.Errorf("expected an error once the budget is exhausted got arm %d", arm)
+	}
+}
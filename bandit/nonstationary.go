@@ -0,0 +1,218 @@
+package bandit
+
+import "math"
+
+// Defaults of the non-stationary strategies if their parameters are not valid.
+const (
+	DefaultDiscount = 0.9
+	DefaultWindow   = 100
+)
+
+//===========================================================================
+// Discounted Rewards
+//===========================================================================
+
+// Discounted wraps a strategy so that the value of each arm is an exponentially
+// weighted average of its rewards rather than the average of all of its
+// rewards, so that arms whose reward distributions drift over time (e.g. a
+// replica that degrades) do not stay preferred because of their history. After
+// each update the value of the arm is discount*value + (1-discount)*reward; the
+// smaller the discount (between 0 and 1), the faster old rewards are forgotten.
+// The counts of the wrapped strategy are not discounted, so strategies whose
+// exploration depends on the counts (e.g. UCB1 or ThompsonSampling) still
+// become more confident over time; use Windowed for those strategies.
+//
+// Multi-objective strategies cannot be discounted since their values are
+// computed from their reward vectors.
+type Discounted struct {
+	RewardStrategy
+	Discount float64 // The weight of the previous value of an arm
+}
+
+// NewDiscounted wraps the strategy with the discount (or DefaultDiscount if the
+// discount is not between 0 and 1). The strategy must still be initialized.
+func NewDiscounted(strategy Strategy, discount float64) *Discounted {
+	return &Discounted{RewardStrategy: WithRewards(strategy), Discount: discount}
+}
+
+// Update the selected arm with an integer reward (see UpdateReward).
+func (b *Discounted) Update(arm, reward int) {
+	b.UpdateReward(arm, float64(reward))
+}
+
+// UpdateReward updates the wrapped strategy with the reward, then replaces the
+// value of the arm with the exponentially weighted average of its rewards. The
+// first reward of an arm is its value. Rewards are clamped to [0, 1] if the
+// wrapped strategy is ThompsonSampling, as it does with its own rewards.
+func (b *Discounted) UpdateReward(arm int, reward float64) {
+	played := b.Counts()[arm] > 0
+	value := b.Values()[arm]
+	reward = clampReward(b.RewardStrategy, reward)
+
+	b.RewardStrategy.UpdateReward(arm, reward)
+	if played {
+		discount := b.discount()
+		b.Values()[arm] = discount*value + (1-discount)*reward
+	}
+}
+
+// Serialize the wrapped strategy with the discount to dump to JSON.
+func (b *Discounted) Serialize() interface{} {
+	data := serializeMap(b.RewardStrategy)
+	data["discount"] = b.discount()
+	return data
+}
+
+// Returns the discount or the default discount if it is not between 0 and 1.
+func (b *Discounted) discount() float64 {
+	if b.Discount > 0 && b.Discount < 1 {
+		return b.Discount
+	}
+	return DefaultDiscount
+}
+
+//===========================================================================
+// Sliding Window Rewards
+//===========================================================================
+
+// Windowed wraps a strategy so that the value of each arm is the average of its
+// most recent rewards in a sliding window rather than the average of all of its
+// rewards, so that arms whose reward distributions drift over time (e.g. a
+// replica that degrades) do not stay preferred because of their history. The
+// count of each arm is also capped at the size of the window, so that the
+// confidence of strategies whose exploration depends on the counts (e.g. UCB1
+// or ThompsonSampling) only reflects the rewards in the window; the counts are
+// therefore not the total number of selections of each arm.
+//
+// Multi-objective strategies cannot be windowed since their values are
+// computed from their reward vectors.
+type Windowed struct {
+	RewardStrategy
+	Size    int         // The number of recent rewards of each arm that are averaged
+	windows [][]float64 // The most recent rewards of each arm in the order received
+}
+
+// NewWindowed wraps the strategy with a window of the specified size (or
+// DefaultWindow if the size is not positive). The strategy must still be
+// initialized.
+func NewWindowed(strategy Strategy, size int) *Windowed {
+	return &Windowed{RewardStrategy: WithRewards(strategy), Size: size}
+}
+
+// Init the wrapped strategy with nArms number of possible choices and create
+// an empty window for each arm.
+func (b *Windowed) Init(nArms int) {
+	b.RewardStrategy.Init(nArms)
+	b.windows = make([][]float64, nArms, nArms)
+}
+
+// Update the selected arm with an integer reward (see UpdateReward).
+func (b *Windowed) Update(arm, reward int) {
+	b.UpdateReward(arm, float64(reward))
+}
+
+// UpdateReward updates the wrapped strategy with the reward, then replaces the
+// value of the arm with the average of the rewards in its window, dropping the
+// oldest reward if the window is full. Rewards are clamped to [0, 1] if the
+// wrapped strategy is ThompsonSampling so that the window holds the rewards
+// that the posterior of the arm learned from.
+func (b *Windowed) UpdateReward(arm int, reward float64) {
+	reward = clampReward(b.RewardStrategy, reward)
+	b.RewardStrategy.UpdateReward(arm, reward)
+
+	size := b.size()
+	window := append(b.windows[arm], reward)
+	if len(window) > size {
+		window = window[len(window)-size:]
+	}
+	b.windows[arm] = window
+
+	total := 0.0
+	for _, r := range window {
+		total += r
+	}
+
+	b.Values()[arm] = total / float64(len(window))
+	if counts := b.Counts(); counts[arm] > uint64(len(window)) {
+		counts[arm] = uint64(len(window))
+	}
+}
+
+// Windows returns the rewards in the window of each arm, oldest first.
+func (b *Windowed) Windows() [][]float64 {
+	return b.windows
+}
+
+// Serialize the wrapped strategy with the window of each arm to dump to JSON.
+func (b *Windowed) Serialize() interface{} {
+	data := serializeMap(b.RewardStrategy)
+	data["window"] = b.size()
+	data["windows"] = b.windows
+	return data
+}
+
+// Returns the window size or the default size if it is not positive.
+func (b *Windowed) size() int {
+	if b.Size > 0 {
+		return b.Size
+	}
+	return DefaultWindow
+}
+
+// Restores the windows of each arm, e.g. from a checkpoint, keeping only the
+// most recent rewards that fit in the window.
+func (b *Windowed) restore(windows [][]float64) {
+	if len(windows) != len(b.windows) {
+		return
+	}
+
+	size := b.size()
+	for i, window := range windows {
+		if len(window) > size {
+			window = window[len(window)-size:]
+		}
+		b.windows[i] = append([]float64(nil), window...)
+	}
+}
+
+//===========================================================================
+// Helpers
+//===========================================================================
+
+// Returns the serialized representation of the strategy as a map so that a
+// wrapper can add its parameters, copying it so the strategy is not modified.
+func serializeMap(strategy Strategy) map[string]interface{} {
+	data := make(map[string]interface{})
+	if m, ok := strategy.Serialize().(map[string]interface{}); ok {
+		for key, val := range m {
+			data[key] = val
+		}
+	}
+	return data
+}
+
+// Returns the reward as it is recorded by the wrapped strategy: ThompsonSampling
+// treats rewards as the fraction of a success, clamping them to [0, 1].
+func clampReward(strategy Strategy, reward float64) float64 {
+	if _, ok := unwrap(strategy).(*ThompsonSampling); ok {
+		return math.Max(0, math.Min(1, reward))
+	}
+	return reward
+}
+
+// Returns the strategy wrapped by a non-stationary strategy (and WithRewards)
+// or the strategy itself if it is not wrapped.
+func unwrap(strategy Strategy) Strategy {
+	for {
+		switch s := strategy.(type) {
+		case *Discounted:
+			strategy = s.RewardStrategy
+		case *Windowed:
+			strategy = s.RewardStrategy
+		case *intRewards:
+			strategy = s.Strategy
+		default:
+			return strategy
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package bandit
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Test that the non-stationary strategies switch to a better arm after the
+// rewards of the preferred arm drift, while the stationary strategy does not.
+func TestDrift(t *testing.T) {
+	factories := map[string]func() Strategy{
+		"discounted epsilon greedy": func() Strategy { return NewDiscounted(&EpsilonGreedy{Epsilon: 0.1}, 0.9) },
+		"windowed epsilon greedy":   func() Strategy { return NewWindowed(&EpsilonGreedy{Epsilon: 0.1}, 20) },
+		"windowed ucb1":             func() Strategy { return NewWindowed(&UCB1{}, 20) },
+		"windowed thompson":         func() Strategy { return NewWindowed(&ThompsonSampling{}, 20) },
+	}
+
+	for name, factory := range factories {
+		strategy := factory()
+		Seed(strategy, 42)
+		if switched := drift(strategy); switched < 0.8 {
+			t.Errorf("%s selected the better arm in %0.1f%% of the trials after the drift", name, switched*100)
+		}
+	}
+
+	// Without forgetting, the history of the degraded arm keeps it preferred
+	stationary := &EpsilonGreedy{Epsilon: 0.1}
+	Seed(stationary, 42)
+	if switched := drift(stationary); switched > 0.5 {
+		t.Errorf("expected the stationary strategy to be slow to switch but switched in %0.1f%% of the trials", switched*100)
+	}
+}
+
+// Plays arm 0 as the best arm for 1000 trials, then degrades it below arm 1 for
+// 300 trials, returning the fraction of the last 200 trials that chose arm 1.
+func drift(strategy Strategy) float64 {
+	rs := WithRewards(strategy)
+	rs.Init(2)
+
+	rewards := []float64{1, 0.5}
+	switched := 0
+	for i := 0; i < 1300; i++ {
+		if i == 1000 {
+			rewards[0] = 0
+		}
+
+		arm := rs.Select()
+		if i >= 1100 && arm == 1 {
+			switched++
+		}
+		rs.UpdateReward(arm, rewards[arm])
+	}
+	return float64(switched) / 200
+}
+
+// Test that rewards are clamped to [0, 1] when the wrapped strategy is Thompson
+// sampling so that the value of the arm matches the posterior.
+func TestClampThompson(t *testing.T) {
+	windowed := NewWindowed(&ThompsonSampling{}, 3)
+	windowed.Init(1)
+	windowed.UpdateReward(0, 5)
+	windowed.UpdateReward(0, -3)
+	windowed.Update(0, 2)
+
+	if window := windowed.Windows()[0]; !reflect.DeepEqual(window, []float64{1, 0, 1}) {
+		t.Errorf("expected the rewards in the window to be clamped got %v", window)
+	}
+
+	if value := windowed.Values()[0]; value < 0.66 || value > 0.67 {
+		t.Errorf("expected the value of the window to be 2/3 got %v", value)
+	}
+
+	discounted := NewDiscounted(&ThompsonSampling{}, 0.5)
+	discounted.Init(1)
+	discounted.UpdateReward(0, 4)
+	discounted.UpdateReward(0, -4)
+
+	if value := discounted.Values()[0]; value != 0.5 {
+		t.Errorf("expected the discounted value of clamped rewards to be 0.5 got %v", value)
+	}
+
+	// Other strategies are not clamped
+	unclamped := NewWindowed(&EpsilonGreedy{}, 3)
+	unclamped.Init(1)
+	unclamped.UpdateReward(0, 5)
+	if value := unclamped.Values()[0]; value != 5 {
+		t.Errorf("expected the reward not to be clamped got %v", value)
+	}
+}
@@ -0,0 +1,210 @@
+package bandit
+
+import (
+	"math"
+	"math/rand"
+)
+
+//===========================================================================
+// Scalarization of Vector Rewards
+//===========================================================================
+
+// Scalarization orders the reward vectors of arms so that arms can be judged
+// on multiple objectives simultaneously, e.g. on both latency and error rate.
+// By convention every objective is maximized; negate the rewards of objectives
+// that should be minimized (e.g. pass -latency as the reward).
+type Scalarization interface {
+	Scalarize(rewards []float64) float64 // reduce a reward vector to a single value
+	Prefer(a, b []float64) bool          // true if reward vector a is preferred to b
+}
+
+// WeightedSum scalarizes reward vectors as the sum of each objective
+// multiplied by its weight. Objectives without a weight are ignored.
+type WeightedSum []float64
+
+// Scalarize returns the weighted sum of the rewards.
+func (w WeightedSum) Scalarize(rewards []float64) float64 {
+	sum := 0.0
+	for i, reward := range rewards {
+		if i < len(w) {
+			sum += w[i] * reward
+		}
+	}
+	return sum
+}
+
+// Prefer returns true if the weighted sum of a is greater than that of b.
+func (w WeightedSum) Prefer(a, b []float64) bool {
+	return w.Scalarize(a) > w.Scalarize(b)
+}
+
+// Lexicographic orders reward vectors by the first objective, breaking ties
+// with the second objective, and so on. Rewards within the tolerance of each
+// other are considered tied so that noisy objectives do not dominate.
+type Lexicographic struct {
+	Tolerance float64
+}
+
+// Scalarize returns the reward of the first (most important) objective.
+func (l Lexicographic) Scalarize(rewards []float64) float64 {
+	if len(rewards) == 0 {
+		return 0.0
+	}
+	return rewards[0]
+}
+
+// Prefer returns true if a is greater than b on the first objective on which
+// they are not tied.
+func (l Lexicographic) Prefer(a, b []float64) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if math.Abs(a[i]-b[i]) <= l.Tolerance {
+			continue
+		}
+		return a[i] > b[i]
+	}
+	return false
+}
+
+//===========================================================================
+// Multi-Objective Epsilon Greedy Multi-Armed Bandit
+//===========================================================================
+
+// MultiObjective implements an epsilon greedy strategy for vector rewards. The
+// mean reward vector of each arm is tracked and the arm that is preferred by
+// the scalarization is exploited with probability 1-epsilon, while a uniform
+// random selection is made with probability epsilon. If no scalarization is
+// specified, objectives are summed with equal weight.
+type MultiObjective struct {
+	Epsilon       float64       // Probability of exploring a random arm
+	Scalarization Scalarization // Orders the reward vectors of the arms
+	Rand          *rand.Rand    // Source of random selections, the global source if nil
+	counts        []uint64      // Number of times each index was selected
+	rewards       [][]float64   // Mean reward vectors condition by frequency
+}
+
+// Init the bandit with nArms number of possible choices, which are referred
+// to by index in both the Counts and Values arrays.
+func (b *MultiObjective) Init(nArms int) {
+	b.counts = make([]uint64, nArms, nArms)
+	b.rewards = make([][]float64, nArms, nArms)
+}
+
+// Select the arm with the preferred reward vector with probability 1-epsilon,
+// otherwise uniform random selection of all arms with probability epsilon.
+func (b *MultiObjective) Select() int {
+	if randFloat64(b.Rand) > b.Epsilon {
+		scalarization := b.scalarization()
+
+		// Find the index of the preferred reward vector.
+		idx := 0
+		for i := 1; i < len(b.rewards); i++ {
+			if scalarization.Prefer(b.rewards[i], b.rewards[idx]) {
+				idx = i
+			}
+		}
+
+		return idx
+	}
+
+	// Otherwise return any of the values
+	return randIntn(b.Rand, len(b.rewards))
+}
+
+// Update the selected arm with a single objective reward so that the strategy
+// satisfies the Strategy interface; use UpdateVector for multiple objectives.
+// The reward is a vector of one objective, so if the arm has been updated with
+// a vector, its other objectives are updated with zero; do not mix scalar and
+// vector updates of the same arm.
+func (b *MultiObjective) Update(arm, reward int) {
+	b.UpdateVector(arm, float64(reward))
+}
+
+// UpdateReward updates the selected arm with a single objective float reward so
+// that the strategy satisfies the RewardStrategy interface. As with Update, the
+// other objectives of an arm that has been updated with a vector are zeroed.
+func (b *MultiObjective) UpdateReward(arm int, reward float64) {
+	b.UpdateVector(arm, reward)
+}
+
+// UpdateVector updates the selected arm with a reward for each objective so
+// that the strategy can learn the preferred arm (conditioned by the frequency
+// of selection). Objectives missing from the vector are treated as zero, and if
+// the vector has more objectives than the arm has seen, the mean of each new
+// objective starts from zero as if it had been missing from earlier rewards.
+func (b *MultiObjective) UpdateVector(arm int, rewards ...float64) {
+	// Update the frequency
+	b.counts[arm]++
+	n := float64(b.counts[arm])
+
+	// Grow the mean reward vector to the number of objectives
+	for len(b.rewards[arm]) < len(rewards) {
+		b.rewards[arm] = append(b.rewards[arm], 0.0)
+	}
+
+	for i, value := range b.rewards[arm] {
+		reward := 0.0
+		if i < len(rewards) {
+			reward = rewards[i]
+		}
+		b.rewards[arm][i] = ((n-1)/n)*value + (1/n)*reward
+	}
+}
+
+// Counts returns the frequency each arm was selected
+func (b *MultiObjective) Counts() []uint64 {
+	return b.counts
+}
+
+// Values returns the scalarized reward of each arm
+func (b *MultiObjective) Values() []float64 {
+	scalarization := b.scalarization()
+	values := make([]float64, len(b.rewards))
+	for i, rewards := range b.rewards {
+		values[i] = scalarization.Scalarize(rewards)
+	}
+	return values
+}
+
+// Rewards returns the mean reward vector of each arm
+func (b *MultiObjective) Rewards() [][]float64 {
+	return b.rewards
+}
+
+// SetRand sets the source of random selections, nil for the global source.
+func (b *MultiObjective) SetRand(rng *rand.Rand) {
+	b.Rand = rng
+}
+
+// Serialize the bandit strategy to dump to JSON.
+func (b *MultiObjective) Serialize() interface{} {
+	data := make(map[string]interface{})
+	data["strategy"] = "multi-objective epsilon greedy"
+	data["epsilon"] = b.Epsilon
+	if weights, ok := b.Scalarization.(WeightedSum); ok {
+		data["weights"] = weights
+	}
+	data["counts"] = b.counts
+	data["values"] = b.Values()
+	data["rewards"] = b.rewards
+	return data
+}
+
+// Returns the scalarization or equal weights if none is specified.
+func (b *MultiObjective) scalarization() Scalarization {
+	if b.Scalarization != nil {
+		return b.Scalarization
+	}
+
+	objectives := 0
+	for _, rewards := range b.rewards {
+		if len(rewards) > objectives {
+			objectives = len(rewards)
+		}
+	}
+
+	weights := make(WeightedSum, objectives)
+	for i := range weights {
+		weights[i] = 1.0
+	}
+	return weights
+}
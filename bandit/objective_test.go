@@ -0,0 +1,110 @@
+package bandit
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// Test that reward vectors are ordered by the first objective that is not tied
+// within the tolerance of a lexicographic scalarization.
+func TestLexicographic(t *testing.T) {
+	tests := []struct {
+		tolerance float64
+		a, b      []float64
+		prefer    bool
+	}{
+		{0, []float64{1, 0}, []float64{0, 1}, true},
+		{0, []float64{0, 1}, []float64{1, 0}, false},
+		{0, []float64{1, 2}, []float64{1, 1}, true},
+		{0, []float64{1, 1}, []float64{1, 1}, false},
+		{0.1, []float64{1.05, 0}, []float64{1, 1}, false},
+		{0.1, []float64{1, 1}, []float64{1.05, 0}, true},
+		{0.1, []float64{1.2, 0}, []float64{1, 1}, true},
+		{0.1, []float64{1, 1.05}, []float64{1, 1}, false},
+		{0, []float64{1}, []float64{1, 5}, false},
+		{0, nil, []float64{1}, false},
+	}
+
+	for _, tc := range tests {
+		l := Lexicographic{Tolerance: tc.tolerance}
+		if prefer := l.Prefer(tc.a, tc.b); prefer != tc.prefer {
+			t.Errorf("expected prefer %v to %v with tolerance %v to be %t", tc.a, tc.b, tc.tolerance, tc.prefer)
+		}
+	}
+
+	if value := (Lexicographic{}).Scalarize([]float64{3, 4}); value != 3 {
+		t.Errorf("expected the first objective to be the scalarized value got %v", value)
+	}
+
+	if value := (Lexicographic{}).Scalarize(nil); value != 0 {
+		t.Errorf("expected an empty vector to be scalarized to zero got %v", value)
+	}
+}
+
+// Test that weighted sums ignore objectives without a weight.
+func TestWeightedSum(t *testing.T) {
+	w := WeightedSum{1, 0.5}
+	if value := w.Scalarize([]float64{2, 4, 100}); value != 4 {
+		t.Errorf("expected weighted sum of 4 got %v", value)
+	}
+
+	if !w.Prefer([]float64{2, 4}, []float64{3, 1}) || w.Prefer([]float64{3, 1}, []float64{2, 4}) {
+		t.Error("expected the vector with the larger weighted sum to be preferred")
+	}
+}
+
+// Test that the multi-objective strategy exploits the arm preferred by the
+// scalarization and that mean vectors grow with the number of objectives.
+func TestMultiObjective(t *testing.T) {
+	// Arm 1 is better on the first objective and arm 2 on the second
+	rewards := [][]float64{{0.1, 0.1}, {0.9, 0.1}, {0.85, 0.9}}
+
+	tests := []struct {
+		scalarization Scalarization
+		best          int
+	}{
+		{nil, 2},
+		{WeightedSum{1, 0}, 1},
+		{Lexicographic{}, 1},
+		{Lexicographic{Tolerance: 0.1}, 2},
+	}
+
+	for _, tc := range tests {
+		strategy := &MultiObjective{Epsilon: 0.1, Scalarization: tc.scalarization, Rand: rand.New(rand.NewSource(42))}
+		strategy.Init(3)
+		for arm, reward := range rewards {
+			strategy.UpdateVector(arm, reward...)
+		}
+
+		best := 0
+		for i := 0; i < 1000; i++ {
+			arm := strategy.Select()
+			if arm == tc.best {
+				best++
+			}
+			strategy.UpdateVector(arm, rewards[arm]...)
+		}
+
+		if best < 800 {
+			t.Errorf("expected %T %v to select arm %d most often but selected it %d times", tc.scalarization, tc.scalarization, tc.best, best)
+		}
+	}
+
+	// New objectives start from zero and missing objectives are averaged as zero
+	strategy := &MultiObjective{}
+	strategy.Init(1)
+	strategy.UpdateVector(0, 2)
+	strategy.UpdateVector(0, 4, 2)
+	strategy.UpdateVector(0, 6)
+
+	if rewards := strategy.Rewards()[0]; !reflect.DeepEqual(rewards, []float64{4, 2.0 / 3.0}) {
+		t.Errorf("unexpected mean reward vector %v", rewards)
+	}
+
+	// Scalar updates are a vector of one objective
+	strategy.UpdateReward(0, 8)
+	if rewards := strategy.Rewards()[0]; rewards[0] != 5 || rewards[1] != 0.5 {
+		t.Errorf("unexpected mean reward vector after a scalar update %v", rewards)
+	}
+}
@@ -0,0 +1,58 @@
+package bandit
+
+import "math/rand"
+
+//===========================================================================
+// Sources of Randomness
+//===========================================================================
+
+// Randomized is implemented by strategies that make random selections so that
+// each strategy can use its own source of randomness rather than the global
+// source of math/rand, e.g. so that simulations and tests are reproducible and
+// concurrent strategies do not contend for the lock of the global source. The
+// source is not safe for concurrent use, so it should not be shared by
+// strategies that are used concurrently.
+type Randomized interface {
+	SetRand(rng *rand.Rand) // Sets the source of random selections, nil for the global source
+}
+
+// SetRand sets the source of random selections of the strategy, or of the
+// strategy wrapped by Discounted, Windowed, or WithRewards. Returns false if the
+// strategy does not make random selections (e.g. UCB1) or is not Randomized.
+func SetRand(strategy Strategy, rng *rand.Rand) bool {
+	if r, ok := unwrap(strategy).(Randomized); ok {
+		r.SetRand(rng)
+		return true
+	}
+	return false
+}
+
+// Seed sets the source of random selections of the strategy to a new source
+// with the seed (see SetRand).
+func Seed(strategy Strategy, seed int64) bool {
+	return SetRand(strategy, rand.New(rand.NewSource(seed)))
+}
+
+// Returns a float64 in [0.0, 1.0) from rng or from the global source if rng is nil.
+func randFloat64(rng *rand.Rand) float64 {
+	if rng != nil {
+		return rng.Float64()
+	}
+	return rand.Float64()
+}
+
+// Returns an int in [0, n) from rng or from the global source if rng is nil.
+func randIntn(rng *rand.Rand, n int) int {
+	if rng != nil {
+		return rng.Intn(n)
+	}
+	return rand.Intn(n)
+}
+
+// Returns a standard normal float64 from rng or from the global source if rng is nil.
+func randNormFloat64(rng *rand.Rand) float64 {
+	if rng != nil {
+		return rng.NormFloat64()
+	}
+	return rand.NormFloat64()
+}
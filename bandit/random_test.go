@@ -0,0 +1,77 @@
+package bandit
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Returns the arms selected by the strategy in the trials, rewarding each arm
+// with a Bernoulli reward of its probability drawn from the seeded rewards.
+func selections(strategy Strategy, probs []float64, trials int, seed int64) []int {
+	rewards := rand.New(rand.NewSource(seed))
+	rs := WithRewards(strategy)
+	rs.Init(len(probs))
+
+	arms := make([]int, 0, trials)
+	for i := 0; i < trials; i++ {
+		arm := rs.Select()
+		arms = append(arms, arm)
+
+		reward := 0.0
+		if rewards.Float64() < probs[arm] {
+			reward = 1.0
+		}
+		rs.UpdateReward(arm, reward)
+	}
+	return arms
+}
+
+// Test that strategies with the same seed make the same selections.
+func TestSeed(t *testing.T) {
+	probs := []float64{0.2, 0.5, 0.7}
+	factories := map[string]func() Strategy{
+		"epsilon greedy":           func() Strategy { return &EpsilonGreedy{Epsilon: 0.2} },
+		"annealing epsilon greedy": func() Strategy { return &AnnealingEpsilonGreedy{} },
+		"uniform":                  func() Strategy { return &Uniform{} },
+		"multi-objective":          func() Strategy { return &MultiObjective{Epsilon: 0.2} },
+		"thompson sampling":        func() Strategy { return &ThompsonSampling{} },
+		"softmax":                  func() Strategy { return &Softmax{Temperature: 0.2} },
+		"annealing softmax":        func() Strategy { return &AnnealingSoftmax{} },
+		"budgeted":                 func() Strategy { return &Budgeted{Epsilon: 0.2} },
+		"discounted":               func() Strategy { return NewDiscounted(&EpsilonGreedy{Epsilon: 0.2}, 0.9) },
+		"windowed":                 func() Strategy { return NewWindowed(&Softmax{}, 20) },
+	}
+
+	for name, factory := range factories {
+		a, b, c := factory(), factory(), factory()
+		if !Seed(a, 42) || !Seed(b, 42) || !SetRand(c, rand.New(rand.NewSource(7))) {
+			t.Errorf("%s: could not set the source of random selections", name)
+			continue
+		}
+
+		first := selections(a, probs, 500, 1)
+		second := selections(b, probs, 500, 1)
+		other := selections(c, probs, 500, 1)
+
+		same := true
+		for i := range first {
+			if first[i] != second[i] {
+				t.Errorf("%s: selection %d differs with the same seed: %d != %d", name, i, first[i], second[i])
+				break
+			}
+
+			if first[i] != other[i] {
+				same = false
+			}
+		}
+
+		if same {
+			t.Errorf("%s: selections are the same with different seeds", name)
+		}
+	}
+
+	// Deterministic strategies do not make random selections
+	if SetRand(&UCB1{}, nil) || Seed(&UCB2{}, 42) {
+		t.Error("expected deterministic strategies not to be randomized")
+	}
+}
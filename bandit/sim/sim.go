@@ -0,0 +1,280 @@
+/*
+Package sim runs multi-armed bandit strategies against simulated reward
+distributions so that strategies and their parameters can be evaluated offline.
+
+Every strategy is run for a number of trials (the horizon) against the same
+arms, and the runs are repeated and averaged to produce a regret curve and
+summary statistics. Simulations are reproducible: the same seed produces the
+same results, and in each run every strategy observes the same sequence of
+rewards from each arm, so strategies are compared on the same draws.
+
+	env := sim.Config{
+		Arms:   []sim.Arm{sim.Bernoulli(0.1), sim.Bernoulli(0.5), sim.Bernoulli(0.8)},
+		Trials: 1000,
+		Runs:   100,
+		Seed:   42,
+	}
+
+	results, err := sim.Compare(env, map[string]sim.Factory{
+		"epsilon greedy": func() bandit.Strategy { return &bandit.EpsilonGreedy{Epsilon: 0.1} },
+		"ucb1":           func() bandit.Strategy { return &bandit.UCB1{} },
+	})
+*/
+package sim
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+
+	"github.com/bbengfort/x/bandit"
+	"github.com/bbengfort/x/stats"
+)
+
+//===========================================================================
+// Reward Distributions
+//===========================================================================
+
+// Arm is the reward distribution of an arm of a simulated bandit.
+type Arm interface {
+	Mean() float64               // the expected reward of the arm, used to compute regret
+	Draw(rng *rand.Rand) float64 // draw a reward from the distribution
+}
+
+// Bernoulli is an arm that is rewarded 1 with the specified probability and 0
+// otherwise, e.g. a click through rate.
+type Bernoulli float64
+
+// Mean returns the probability of a reward.
+func (a Bernoulli) Mean() float64 {
+	return float64(a)
+}
+
+// Draw returns 1 with the probability of the arm, otherwise 0.
+func (a Bernoulli) Draw(rng *rand.Rand) float64 {
+	if rng.Float64() < float64(a) {
+		return 1
+	}
+	return 0
+}
+
+// Gaussian is an arm whose rewards are normally distributed, e.g. a latency or
+// revenue based reward.
+type Gaussian struct {
+	Mu    float64 // the mean reward of the arm
+	Sigma float64 // the standard deviation of the rewards
+}
+
+// Mean returns the mean reward of the arm.
+func (a Gaussian) Mean() float64 {
+	return a.Mu
+}
+
+// Draw returns a normally distributed reward.
+func (a Gaussian) Draw(rng *rand.Rand) float64 {
+	return rng.NormFloat64()*a.Sigma + a.Mu
+}
+
+//===========================================================================
+// Simulation
+//===========================================================================
+
+// Factory creates a new strategy for each run of a simulation.
+type Factory func() bandit.Strategy
+
+// Config describes the simulated environment the strategies are run in.
+type Config struct {
+	Arms   []Arm // the reward distribution of each arm
+	Trials int   // the number of selections in each run
+	Runs   int   // the number of independent runs that are averaged (1 if not positive)
+	Seed   int64 // the seed of the random rewards and strategies
+}
+
+// Result is the evaluation of a strategy in a simulated environment.
+type Result struct {
+	Strategy string            // the name of the strategy
+	Regret   []float64         // the mean cumulative regret after each trial
+	Optimal  []float64         // the fraction of runs that selected the optimal arm at each trial
+	Counts   []uint64          // the number of times each arm was selected across all runs
+	Rewards  *stats.Statistics // the reward of every trial of every run
+	Total    *stats.Statistics // the cumulative regret at the end of each run
+}
+
+// String returns a summary of the result.
+func (r *Result) String() string {
+	optimal := 0.0
+	if n := len(r.Optimal); n > 0 {
+		optimal = r.Optimal[n-1]
+	}
+
+	return fmt.Sprintf(
+		"%s: regret %0.3f ± %0.3f, mean reward %0.3f, optimal arm selected %0.1f%% of the final trials",
+		r.Strategy, r.Total.Mean(), r.Total.StdDev(), r.Rewards.Mean(), optimal*100,
+	)
+}
+
+// Run the strategies created by the factory in the environment, returning the
+// regret curve and summary statistics averaged across runs. Regret is the
+// difference between the mean reward of the best arm and the mean reward of the
+// selected arm, so that it is not affected by the noise of the rewards.
+//
+// The seed of the config seeds a master source from which every run derives
+// independent sources for the rewards of each arm and for the random
+// selections of the strategy, so that the n-th reward of an arm in a run is the
+// same for every strategy and the selections are not correlated with the
+// rewards. Strategies that make random selections are given their source with
+// bandit.SetRand, so runs are reproducible and can run concurrently; strategies
+// that are not bandit.Randomized use the global source of math/rand and are not
+// reproducible.
+func Run(name string, factory Factory, conf Config) (_ *Result, err error) {
+	if err = conf.validate(); err != nil {
+		return nil, err
+	}
+
+	runs := conf.runs()
+	best, bestMean := conf.best()
+	result := &Result{
+		Strategy: name,
+		Regret:   make([]float64, conf.Trials),
+		Optimal:  make([]float64, conf.Trials),
+		Counts:   make([]uint64, len(conf.Arms)),
+		Rewards:  new(stats.Statistics),
+		Total:    new(stats.Statistics),
+	}
+
+	// Every run consumes the same number of seeds from the master source so that
+	// the sources of each run do not depend on the selections of previous runs
+	master := rand.New(rand.NewSource(conf.Seed))
+	rewards := make([]*rand.Rand, len(conf.Arms))
+
+	for run := 0; run < runs; run++ {
+		choices := rand.New(rand.NewSource(master.Int63()))
+		for i := range rewards {
+			rewards[i] = rand.New(rand.NewSource(master.Int63()))
+		}
+
+		strategy := bandit.WithRewards(factory())
+		bandit.SetRand(strategy, choices)
+		strategy.Init(len(conf.Arms))
+
+		regret := 0.0
+		for trial := 0; trial < conf.Trials; trial++ {
+			arm := strategy.Select()
+			if arm < 0 || arm >= len(conf.Arms) {
+				return nil, fmt.Errorf("strategy %q selected arm %d of %d arms", name, arm, len(conf.Arms))
+			}
+
+			reward := conf.Arms[arm].Draw(rewards[arm])
+			strategy.UpdateReward(arm, reward)
+
+			regret += bestMean - conf.Arms[arm].Mean()
+			result.Regret[trial] += regret
+			if arm == best {
+				result.Optimal[trial]++
+			}
+
+			result.Counts[arm]++
+			result.Rewards.Update(reward)
+		}
+		result.Total.Update(regret)
+	}
+
+	for trial := range result.Regret {
+		result.Regret[trial] /= float64(runs)
+		result.Optimal[trial] /= float64(runs)
+	}
+	return result, nil
+}
+
+// Compare runs every strategy in the same environment with the same seed and
+// returns the results sorted by name.
+func Compare(conf Config, strategies map[string]Factory) ([]*Result, error) {
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]*Result, 0, len(names))
+	for _, name := range names {
+		result, err := Run(name, strategies[name], conf)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// WriteCSV writes the regret curves of the results as CSV with a column for the
+// trial and a column for the cumulative regret of each strategy, e.g. to plot the
+// curves of a comparison.
+func WriteCSV(w io.Writer, results ...*Result) error {
+	trials := 0
+	header := []string{"trial"}
+	for _, result := range results {
+		header = append(header, result.Strategy)
+		if len(result.Regret) > trials {
+			trials = len(result.Regret)
+		}
+	}
+
+	out := csv.NewWriter(w)
+	if err := out.Write(header); err != nil {
+		return err
+	}
+
+	for trial := 0; trial < trials; trial++ {
+		row := []string{strconv.Itoa(trial + 1)}
+		for _, result := range results {
+			cell := ""
+			if trial < len(result.Regret) {
+				cell = strconv.FormatFloat(result.Regret[trial], 'f', -1, 64)
+			}
+			row = append(row, cell)
+		}
+
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// Returns an error if the environment cannot be simulated.
+func (c Config) validate() error {
+	if len(c.Arms) == 0 {
+		return errors.New("simulation must have at least one arm")
+	}
+
+	if c.Trials < 1 {
+		return errors.New("simulation must have at least one trial")
+	}
+	return nil
+}
+
+// Returns the number of runs, at least one.
+func (c Config) runs() int {
+	if c.Runs < 1 {
+		return 1
+	}
+	return c.Runs
+}
+
+// Returns the index and mean reward of the arm with the highest mean reward.
+func (c Config) best() (idx int, mean float64) {
+	mean = math.Inf(-1)
+	for i, arm := range c.Arms {
+		if m := arm.Mean(); m > mean {
+			idx, mean = i, m
+		}
+	}
+	return idx, mean
+}
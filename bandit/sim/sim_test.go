@@ -0,0 +1,73 @@
+package sim
+
+import (
+	"testing"
+
+	"github.com/bbengfort/x/bandit"
+)
+
+func TestRunReproducible(t *testing.T) {
+	env := Config{
+		Arms:   []Arm{Bernoulli(0.2), Bernoulli(0.5), Bernoulli(0.7)},
+		Trials: 200,
+		Runs:   20,
+		Seed:   42,
+	}
+
+	factory := func() bandit.Strategy { return &bandit.EpsilonGreedy{Epsilon: 0.1} }
+	a, err := Run("a", factory, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := Run("b", factory, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for trial := range a.Regret {
+		if a.Regret[trial] != b.Regret[trial] {
+			t.Fatalf("regret of trial %d is not reproducible: %v != %v", trial, a.Regret[trial], b.Regret[trial])
+		}
+	}
+}
+
+func TestRunIndependentSources(t *testing.T) {
+	// If the rewards and the random selections share a sequence of random numbers,
+	// softmax selects the arm whose reward was just drawn and does worse than
+	// selecting arms uniformly at random.
+	env := Config{
+		Arms:   []Arm{Bernoulli(0.2), Bernoulli(0.5), Bernoulli(0.7)},
+		Trials: 1000,
+		Runs:   100,
+		Seed:   42,
+	}
+
+	results, err := Compare(env, map[string]Factory{
+		"softmax": func() bandit.Strategy { return &bandit.Softmax{Temperature: 0.1} },
+		"uniform": func() bandit.Strategy { return &bandit.Uniform{} },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	softmax, uniform := results[0], results[1]
+	if softmax.Total.Mean() >= uniform.Total.Mean()/2 {
+		t.Errorf("softmax regret %0.1f is not much less than uniform regret %0.1f", softmax.Total.Mean(), uniform.Total.Mean())
+	}
+
+	if optimal := softmax.Optimal[env.Trials-1]; optimal < 0.7 {
+		t.Errorf("softmax selected the optimal arm in %0.0f%% of the final trials", optimal*100)
+	}
+}
+
+func TestRunInvalid(t *testing.T) {
+	factory := func() bandit.Strategy { return &bandit.Uniform{} }
+	if _, err := Run("empty", factory, Config{Trials: 10}); err == nil {
+		t.Error("expected error for a simulation without arms")
+	}
+
+	if _, err := Run("no trials", factory, Config{Arms: []Arm{Bernoulli(0.5)}}); err == nil {
+		t.Error("expected error for a simulation without trials")
+	}
+}
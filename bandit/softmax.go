@@ -0,0 +1,198 @@
+package bandit
+
+import (
+	"math"
+	"math/rand"
+)
+
+// DefaultTemperature is the temperature of the Softmax strategy if it is not specified.
+const DefaultTemperature = 0.1
+
+//===========================================================================
+// Softmax Multi-Armed Bandit
+//===========================================================================
+
+// Softmax implements Boltzmann exploration, selecting each arm with probability
+// proportional to exp(value/temperature). Unlike epsilon greedy, which explores
+// all arms uniformly, arms with higher values are explored more often than arms
+// with lower values. High temperatures approach uniform selection and low
+// temperatures approach greedy selection. If the Temperature is not positive,
+// DefaultTemperature is used.
+type Softmax struct {
+	Temperature float64    // Controls the amount of exploration
+	Rand        *rand.Rand // Source of random selections, the global source if nil
+	counts      []uint64   // Number of times each index was selected
+	values      []float64  // Reward values condition by frequency
+}
+
+// Init the bandit with nArms number of possible choices, which are referred
+// to by index in both the Counts and Values arrays.
+func (b *Softmax) Init(nArms int) {
+	b.counts = make([]uint64, nArms, nArms)
+	b.values = make([]float64, nArms, nArms)
+}
+
+// Select an arm with probability proportional to exp(value/temperature).
+func (b *Softmax) Select() int {
+	return boltzmann(b.Rand, b.values, b.temperature())
+}
+
+// Update the selected arm with an integer reward (see UpdateReward).
+func (b *Softmax) Update(arm, reward int) {
+	b.UpdateReward(arm, float64(reward))
+}
+
+// UpdateReward updates the selected arm with the reward so that the strategy
+// can learn the maximizing value (conditioned by the frequency of selection).
+func (b *Softmax) UpdateReward(arm int, reward float64) {
+	// Update the frequency
+	b.counts[arm]++
+	n := float64(b.counts[arm])
+
+	value := b.values[arm]
+	b.values[arm] = ((n-1)/n)*value + (1/n)*reward
+}
+
+// Counts returns the frequency each arm was selected
+func (b *Softmax) Counts() []uint64 {
+	return b.counts
+}
+
+// Values returns the reward distribution of each arm
+func (b *Softmax) Values() []float64 {
+	return b.values
+}
+
+// SetRand sets the source of random selections, nil for the global source.
+func (b *Softmax) SetRand(rng *rand.Rand) {
+	b.Rand = rng
+}
+
+// Serialize the bandit strategy to dump to JSON.
+func (b *Softmax) Serialize() interface{} {
+	data := make(map[string]interface{})
+	data["strategy"] = "softmax"
+	data["temperature"] = b.temperature()
+	data["counts"] = b.counts
+	data["values"] = b.values
+	return data
+}
+
+// Returns the temperature or the default temperature if it is not positive.
+func (b *Softmax) temperature() float64 {
+	if b.Temperature > 0 {
+		return b.Temperature
+	}
+	return DefaultTemperature
+}
+
+//===========================================================================
+// Annealing Softmax Multi-Armed Bandit
+//===========================================================================
+
+// AnnealingSoftmax implements Boltzmann exploration such that the temperature
+// starts high and decreases as more selections are made, leading to an
+// exploring strategy at the start and preferring exploitation as the strategy
+// learns (see AnnealingEpsilonGreedy).
+type AnnealingSoftmax struct {
+	Rand   *rand.Rand // Source of random selections, the global source if nil
+	counts []uint64   // Number of times each index was selected
+	values []float64  // Reward values condition by frequency
+}
+
+// Init the bandit with nArms number of possible choices, which are referred
+// to by index in both the Counts and Values arrays.
+func (b *AnnealingSoftmax) Init(nArms int) {
+	b.counts = make([]uint64, nArms, nArms)
+	b.values = make([]float64, nArms, nArms)
+}
+
+// Temperature is computed by the current number of trials such that the more
+// trials have occured, the smaller the temperature is (on a log scale).
+func (b *AnnealingSoftmax) Temperature() float64 {
+	// Compute the temperature based on the total number of trials
+	t := uint64(1)
+	for _, i := range b.counts {
+		t += i
+	}
+
+	// The more trials the smaller the temperature is
+	return 1 / math.Log(float64(t)+0.0000001)
+}
+
+// Select an arm with probability proportional to exp(value/temperature).
+func (b *AnnealingSoftmax) Select() int {
+	return boltzmann(b.Rand, b.values, b.Temperature())
+}
+
+// Update the selected arm with an integer reward (see UpdateReward).
+func (b *AnnealingSoftmax) Update(arm, reward int) {
+	b.UpdateReward(arm, float64(reward))
+}
+
+// UpdateReward updates the selected arm with the reward so that the strategy
+// can learn the maximizing value (conditioned by the frequency of selection).
+func (b *AnnealingSoftmax) UpdateReward(arm int, reward float64) {
+	// Update the frequency
+	b.counts[arm]++
+	n := float64(b.counts[arm])
+
+	value := b.values[arm]
+	b.values[arm] = ((n-1)/n)*value + (1/n)*reward
+}
+
+// Counts returns the frequency each arm was selected
+func (b *AnnealingSoftmax) Counts() []uint64 {
+	return b.counts
+}
+
+// Values returns the reward distribution of each arm
+func (b *AnnealingSoftmax) Values() []float64 {
+	return b.values
+}
+
+// SetRand sets the source of random selections, nil for the global source.
+func (b *AnnealingSoftmax) SetRand(rng *rand.Rand) {
+	b.Rand = rng
+}
+
+// Serialize the bandit strategy to dump to JSON.
+func (b *AnnealingSoftmax) Serialize() interface{} {
+	data := make(map[string]interface{})
+	data["strategy"] = "annealing softmax"
+	data["temperature"] = b.Temperature()
+	data["counts"] = b.counts
+	data["values"] = b.values
+	return data
+}
+
+// Selects an index with probability proportional to exp(value/temperature) using
+// rng (or the global source if nil). The maximum value is subtracted from every
+// value to prevent overflow.
+func boltzmann(rng *rand.Rand, values []float64, temperature float64) int {
+	max := math.Inf(-1)
+	for _, val := range values {
+		if val > max {
+			max = val
+		}
+	}
+
+	total := 0.0
+	weights := make([]float64, len(values))
+	for i, val := range values {
+		weights[i] = math.Exp((val - max) / temperature)
+		total += weights[i]
+	}
+
+	// Select the index whose cumulative probability exceeds a uniform sample.
+	z := randFloat64(rng) * total
+	cum := 0.0
+	for i, weight := range weights {
+		cum += weight
+		if cum > z {
+			return i
+		}
+	}
+
+	return len(values) - 1
+}
@@ -0,0 +1,144 @@
+package bandit
+
+import (
+	"math"
+	"math/rand"
+)
+
+//===========================================================================
+// Thompson Sampling Multi-Armed Bandit
+//===========================================================================
+
+// ThompsonSampling implements a Bayesian strategy for binary (Bernoulli)
+// rewards that maintains a Beta posterior of the probability of success of
+// each arm, starting from a uniform Beta(1, 1) prior. On every selection a
+// probability is sampled from the posterior of each arm and the arm with the
+// largest sample is selected, so arms are selected with the probability that
+// they are the best arm. Any positive integer reward is treated as a success
+// and any other reward as a failure, while float rewards between 0 and 1 are
+// fractional successes; the posteriors are derived from the counts and
+// values so that they are restored from checkpoints and warm starts.
+type ThompsonSampling struct {
+	Rand   *rand.Rand // Source of the posterior samples, the global source if nil
+	counts []uint64   // Number of times each index was selected
+	values []float64  // Reward values condition by frequency
+}
+
+// Init the bandit with nArms number of possible choices, which are referred
+// to by index in both the Counts and Values arrays.
+func (b *ThompsonSampling) Init(nArms int) {
+	b.counts = make([]uint64, nArms, nArms)
+	b.values = make([]float64, nArms, nArms)
+}
+
+// Select the arm with the largest sample from its Beta posterior.
+func (b *ThompsonSampling) Select() int {
+	max := -1.0
+	idx := -1
+
+	for i := range b.values {
+		alpha, beta := b.Posterior(i)
+		if sample := sampleBeta(b.Rand, alpha, beta); sample > max {
+			max = sample
+			idx = i
+		}
+	}
+
+	return idx
+}
+
+// Update the selected arm with an integer reward; any positive reward is a
+// success and any other reward is a failure.
+func (b *ThompsonSampling) Update(arm, reward int) {
+	success := 0.0
+	if reward > 0 {
+		success = 1.0
+	}
+	b.UpdateReward(arm, success)
+}
+
+// UpdateReward updates the selected arm with the reward, which is clamped to
+// the range [0, 1] and treated as the fraction of a success so that the
+// posterior can learn from probabilities or normalized rewards.
+func (b *ThompsonSampling) UpdateReward(arm int, reward float64) {
+	reward = math.Max(0, math.Min(1, reward))
+
+	// Update the frequency
+	b.counts[arm]++
+	n := float64(b.counts[arm])
+
+	value := b.values[arm]
+	b.values[arm] = ((n-1)/n)*value + (1/n)*reward
+}
+
+// Posterior returns the alpha (1 + successes) and beta (1 + failures)
+// parameters of the Beta posterior of the arm.
+func (b *ThompsonSampling) Posterior(arm int) (alpha, beta float64) {
+	n := float64(b.counts[arm])
+	successes := math.Max(0, math.Min(n, b.values[arm]*n))
+	return 1 + successes, 1 + n - successes
+}
+
+// Counts returns the frequency each arm was selected
+func (b *ThompsonSampling) Counts() []uint64 {
+	return b.counts
+}
+
+// Values returns the reward distribution of each arm
+func (b *ThompsonSampling) Values() []float64 {
+	return b.values
+}
+
+// SetRand sets the source of random selections, nil for the global source.
+func (b *ThompsonSampling) SetRand(rng *rand.Rand) {
+	b.Rand = rng
+}
+
+// Serialize the bandit strategy to dump to JSON.
+func (b *ThompsonSampling) Serialize() interface{} {
+	alphas := make([]float64, len(b.counts))
+	betas := make([]float64, len(b.counts))
+	for i := range b.counts {
+		alphas[i], betas[i] = b.Posterior(i)
+	}
+
+	data := make(map[string]interface{})
+	data["strategy"] = "thompson sampling"
+	data["counts"] = b.counts
+	data["values"] = b.values
+	data["alphas"] = alphas
+	data["betas"] = betas
+	return data
+}
+
+// Returns a random sample from the Beta(alpha, beta) distribution using the
+// ratio of two Gamma samples drawn from rng (or the global source if nil).
+func sampleBeta(rng *rand.Rand, alpha, beta float64) float64 {
+	x := sampleGamma(rng, alpha)
+	y := sampleGamma(rng, beta)
+	return x / (x + y)
+}
+
+// Returns a random sample from the Gamma(shape, 1) distribution using the
+// Marsaglia and Tsang method; shapes less than one are boosted by a uniform.
+func sampleGamma(rng *rand.Rand, shape float64) float64 {
+	if shape < 1 {
+		return sampleGamma(rng, shape+1) * math.Pow(randFloat64(rng), 1/shape)
+	}
+
+	d := shape - 1.0/3.0
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := randNormFloat64(rng)
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+
+		v = v * v * v
+		u := randFloat64(rng)
+		if u < 1-0.0331*x*x*x*x || math.Log(u) < 0.5*x*x+d*(1-v+math.Log(v)) {
+			return d * v
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package bandit
+
+import "math"
+
+// DefaultUCB2Alpha is the alpha of the UCB2 strategy if it is not specified.
+const DefaultUCB2Alpha = 0.5
+
+//===========================================================================
+// UCB1 Multi-Armed Bandit
+//===========================================================================
+
+// UCB1 implements the upper confidence bound strategy, which deterministically
+// selects the arm with the highest upper bound of the confidence interval of
+// its mean reward. Arms that have been selected less often have wider
+// confidence intervals, so exploration decreases as the strategy learns
+// without a tuning parameter. Every arm is selected once before any bounds
+// are computed. Rewards are expected to be in the range [0, 1].
+type UCB1 struct {
+	counts []uint64  // Number of times each index was selected
+	values []float64 // Reward values condition by frequency
+}
+
+// Init the bandit with nArms number of possible choices, which are referred
+// to by index in both the Counts and Values arrays.
+func (b *UCB1) Init(nArms int) {
+	b.counts = make([]uint64, nArms, nArms)
+	b.values = make([]float64, nArms, nArms)
+}
+
+// Select the arm with the maximal upper confidence bound, selecting any arms
+// that have not been selected yet first.
+func (b *UCB1) Select() int {
+	if idx := unplayed(b.counts); idx >= 0 {
+		return idx
+	}
+
+	total := 0.0
+	for _, count := range b.counts {
+		total += float64(count)
+	}
+
+	// Find the index of the maximal upper confidence bound.
+	max := math.Inf(-1)
+	idx := -1
+	for i, val := range b.values {
+		bound := val + math.Sqrt(2*math.Log(total)/float64(b.counts[i]))
+		if bound > max {
+			max = bound
+			idx = i
+		}
+	}
+
+	return idx
+}
+
+// Update the selected arm with an integer reward (see UpdateReward).
+func (b *UCB1) Update(arm, reward int) {
+	b.UpdateReward(arm, float64(reward))
+}
+
+// UpdateReward updates the selected arm with the reward so that the strategy
+// can learn the maximizing value (conditioned by the frequency of selection).
+func (b *UCB1) UpdateReward(arm int, reward float64) {
+	// Update the frequency
+	b.counts[arm]++
+	n := float64(b.counts[arm])
+
+	value := b.values[arm]
+	b.values[arm] = ((n-1)/n)*value + (1/n)*reward
+}
+
+// Counts returns the frequency each arm was selected
+func (b *UCB1) Counts() []uint64 {
+	return b.counts
+}
+
+// Values returns the reward distribution of each arm
+func (b *UCB1) Values() []float64 {
+	return b.values
+}
+
+// Serialize the bandit strategy to dump to JSON.
+func (b *UCB1) Serialize() interface{} {
+	data := make(map[string]interface{})
+	data["strategy"] = "ucb1"
+	data["counts"] = b.counts
+	data["values"] = b.values
+	return data
+}
+
+//===========================================================================
+// UCB2 Multi-Armed Bandit
+//===========================================================================
+
+// UCB2 implements a variant of the upper confidence bound strategy that plays
+// the selected arm for an epoch of consecutive selections whose length grows
+// exponentially with the number of epochs of the arm. Alpha (between 0 and 1)
+// controls how quickly the epochs grow; smaller values explore more like UCB1.
+// If Alpha is not positive, DefaultUCB2Alpha is used. Because an arm is played
+// for an entire epoch, Update should be called after every Select.
+type UCB2 struct {
+	Alpha   float64   // Controls the growth of the epochs of each arm
+	counts  []uint64  // Number of times each index was selected
+	values  []float64 // Reward values condition by frequency
+	epochs  []int     // Number of epochs each index was selected for
+	current int       // The arm being played in the current epoch
+	remain  int       // The number of selections remaining in the current epoch
+}
+
+// Init the bandit with nArms number of possible choices, which are referred
+// to by index in both the Counts and Values arrays.
+func (b *UCB2) Init(nArms int) {
+	b.counts = make([]uint64, nArms, nArms)
+	b.values = make([]float64, nArms, nArms)
+	b.epochs = make([]int, nArms, nArms)
+	b.current = 0
+	b.remain = 0
+}
+
+// Select the arm of the current epoch or, when the epoch is complete, start a
+// new epoch with the arm with the maximal upper confidence bound, selecting
+// any arms that have not been selected yet first.
+func (b *UCB2) Select() int {
+	if idx := unplayed(b.counts); idx >= 0 {
+		return idx
+	}
+
+	if b.remain > 0 {
+		b.remain--
+		return b.current
+	}
+
+	total := 0.0
+	for _, count := range b.counts {
+		total += float64(count)
+	}
+
+	// Find the index of the maximal upper confidence bound.
+	max := math.Inf(-1)
+	idx := -1
+	for i, val := range b.values {
+		tau := b.tau(b.epochs[i])
+		bonus := math.Sqrt(math.Max(0, (1+b.alpha())*math.Log(math.E*total/tau)) / (2 * tau))
+		if bound := val + bonus; bound > max {
+			max = bound
+			idx = i
+		}
+	}
+
+	// Play the arm for the length of its next epoch (at least once)
+	plays := int(b.tau(b.epochs[idx]+1) - b.tau(b.epochs[idx]))
+	if plays < 1 {
+		plays = 1
+	}
+
+	b.epochs[idx]++
+	b.current = idx
+	b.remain = plays - 1
+	return idx
+}
+
+// Update the selected arm with an integer reward (see UpdateReward).
+func (b *UCB2) Update(arm, reward int) {
+	b.UpdateReward(arm, float64(reward))
+}
+
+// UpdateReward updates the selected arm with the reward so that the strategy
+// can learn the maximizing value (conditioned by the frequency of selection).
+func (b *UCB2) UpdateReward(arm int, reward float64) {
+	// Update the frequency
+	b.counts[arm]++
+	n := float64(b.counts[arm])
+
+	value := b.values[arm]
+	b.values[arm] = ((n-1)/n)*value + (1/n)*reward
+}
+
+// Counts returns the frequency each arm was selected
+func (b *UCB2) Counts() []uint64 {
+	return b.counts
+}
+
+// Values returns the reward distribution of each arm
+func (b *UCB2) Values() []float64 {
+	return b.values
+}
+
+// Serialize the bandit strategy to dump to JSON.
+func (b *UCB2) Serialize() interface{} {
+	data := make(map[string]interface{})
+	data["strategy"] = "ucb2"
+	data["alpha"] = b.alpha()
+	data["counts"] = b.counts
+	data["values"] = b.values
+	data["epochs"] = b.epochs
+	return data
+}
+
+// Returns alpha or the default alpha if it is not positive.
+func (b *UCB2) alpha() float64 {
+	if b.Alpha > 0 {
+		return b.Alpha
+	}
+	return DefaultUCB2Alpha
+}
+
+// Returns the number of selections of an arm after r epochs, ceil((1+alpha)^r).
+func (b *UCB2) tau(r int) float64 {
+	return math.Ceil(math.Pow(1+b.alpha(), float64(r)))
+}
+
+// Returns the index of the first arm that has never been selected or -1 if all
+// of the arms have been selected.
+func unplayed(counts []uint64) int {
+	for i, count := range counts {
+		if count == 0 {
+			return i
+		}
+	}
+	return -1
+}
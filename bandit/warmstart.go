@@ -0,0 +1,91 @@
+package bandit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+//===========================================================================
+// Warm Start
+//===========================================================================
+
+// SelectionRecord is a historical selection of an arm and the reward that it
+// received, e.g. a line of a selection log written by a previous deployment.
+// Multi-objective strategies are updated with the reward vector if it is not
+// empty, otherwise with the scalar reward.
+type SelectionRecord struct {
+	Arm     int       `json:"arm"`               // the index of the selected arm
+	Reward  float64   `json:"reward"`            // the reward the arm received
+	Rewards []float64 `json:"rewards,omitempty"` // the reward of each objective, if any
+}
+
+// WarmStart replays the historical records in order to initialize the counts
+// and values of the strategy, so that a redeployed service does not relearn
+// from scratch and tests can construct strategies in a known state. The
+// strategy must be initialized with enough arms for the records; if any record
+// refers to an arm that does not exist, an error is returned and the strategy
+// is not updated.
+func WarmStart(strategy Strategy, records []SelectionRecord) error {
+	arms := len(strategy.Counts())
+	for i, record := range records {
+		if record.Arm < 0 || record.Arm >= arms {
+			return fmt.Errorf("record %d selects arm %d of %d arms", i, record.Arm, arms)
+		}
+	}
+
+	mo, isMO := strategy.(*MultiObjective)
+	rs := WithRewards(strategy)
+	for _, record := range records {
+		if isMO && len(record.Rewards) > 0 {
+			mo.UpdateVector(record.Arm, record.Rewards...)
+			continue
+		}
+		rs.UpdateReward(record.Arm, record.Reward)
+	}
+	return nil
+}
+
+// ReadSelectionLog reads the selection records in a log with one JSON record per
+// line, e.g. {"arm": 2, "reward": 1}, skipping blank lines.
+func ReadSelectionLog(r io.Reader) (records []SelectionRecord, err error) {
+	records = make([]SelectionRecord, 0)
+	scanner := bufio.NewScanner(r)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var record SelectionRecord
+		if err = json.Unmarshal([]byte(line), &record); err != nil {
+			return nil, fmt.Errorf("could not parse selection record on line %d: %s", n, err)
+		}
+		records = append(records, record)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// WarmStart replays the historical records into the strategy of the named
+// experiment, creating (and restoring) it if necessary.
+func (e *Experiments) WarmStart(name string, records []SelectionRecord) error {
+	e.Lock()
+	defer e.Unlock()
+
+	strategy, err := e.get(name)
+	if err != nil {
+		return err
+	}
+
+	if err = WarmStart(strategy, records); err != nil {
+		return fmt.Errorf("could not warm start experiment %q: %s", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package bandit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Test that replaying records initializes the counts and values of a strategy
+// and that records for arms that do not exist are rejected without an update.
+func TestWarmStart(t *testing.T) {
+	records := []SelectionRecord{
+		{Arm: 0, Reward: 1}, {Arm: 1, Reward: 0}, {Arm: 1, Reward: 1}, {Arm: 2, Reward: 0.5},
+	}
+
+	strategy := &EpsilonGreedy{Epsilon: 0.1}
+	strategy.Init(3)
+	if err := WarmStart(strategy, records); err != nil {
+		t.Fatal(err)
+	}
+
+	if counts := strategy.Counts(); !reflect.DeepEqual(counts, []uint64{1, 2, 1}) {
+		t.Errorf("unexpected counts after warm start: %v", counts)
+	}
+
+	if values := strategy.Values(); !reflect.DeepEqual(values, []float64{1, 0.5, 0.5}) {
+		t.Errorf("unexpected values after warm start: %v", values)
+	}
+
+	for _, arm := range []int{-1, 3} {
+		invalid := append(append([]SelectionRecord(nil), records...), SelectionRecord{Arm: arm, Reward: 1})
+		fresh := &EpsilonGreedy{Epsilon: 0.1}
+		fresh.Init(3)
+
+		if err := WarmStart(fresh, invalid); err == nil || !strings.Contains(err.Error(), "record 4") {
+			t.Errorf("expected an error for arm %d in record 4 got %v", arm, err)
+		}
+
+		if counts := fresh.Counts(); !reflect.DeepEqual(counts, []uint64{0, 0, 0}) {
+			t.Errorf("expected no updates when arm %d is rejected got %v", arm, counts)
+		}
+	}
+}
+
+// Test that multi-objective strategies replay reward vectors and fall back to
+// the scalar reward when a record has no vector.
+func TestWarmStartMultiObjective(t *testing.T) {
+	records := []SelectionRecord{
+		{Arm: 0, Rewards: []float64{1, -2}},
+		{Arm: 0, Rewards: []float64{3, -4}},
+		{Arm: 1, Reward: 2},
+	}
+
+	strategy := &MultiObjective{Scalarization: WeightedSum{1, 1}}
+	strategy.Init(2)
+	if err := WarmStart(strategy, records); err != nil {
+		t.Fatal(err)
+	}
+
+	if counts := strategy.Counts(); !reflect.DeepEqual(counts, []uint64{2, 1}) {
+		t.Errorf("unexpected counts after warm start: %v", counts)
+	}
+
+	if rewards := strategy.Rewards(); !reflect.DeepEqual(rewards, [][]float64{{2, -3}, {2}}) {
+		t.Errorf("unexpected mean reward vectors after warm start: %v", rewards)
+	}
+
+	if values := strategy.Values(); !reflect.DeepEqual(values, []float64{-1, 2}) {
+		t.Errorf("unexpected scalarized values after warm start: %v", values)
+	}
+}
+
+// Test reading a selection log with blank lines and reporting the line number
+// of records that cannot be parsed.
+func TestReadSelectionLog(t *testing.T) {
+	log := "{\"arm\": 0, \"reward\": 1}\n\n   \n{\"arm\": 2, \"reward\": 0.5, \"rewards\": [0.5, -1]}\n"
+	records, err := ReadSelectionLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []SelectionRecord{{Arm: 0, Reward: 1}, {Arm: 2, Reward: 0.5, Rewards: []float64{0.5, -1}}}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("expected records %v got %v", expected, records)
+	}
+
+	if records, err = ReadSelectionLog(strings.NewReader("")); err != nil || len(records) != 0 {
+		t.Errorf("expected no records from an empty log got %v: %v", records, err)
+	}
+
+	_, err = ReadSelectionLog(strings.NewReader("{\"arm\": 0, \"reward\": 1}\n\n{\"arm\": 1, \"reward\": }\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("expected an error on line 3 got %v", err)
+	}
+}
+
+// Test that experiments are warm started by name.
+func TestExperimentsWarmStart(t *testing.T) {
+	experiments, err := NewExperiments("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = experiments.Register("greedy", Config{Strategy: StrategyEpsilonGreedy, Arms: 2, Epsilon: 0.1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = experiments.WarmStart("greedy", []SelectionRecord{{Arm: 1, Reward: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	strategy, _ := experiments.Get("greedy")
+	if counts := strategy.Counts(); !reflect.DeepEqual(counts, []uint64{0, 1}) {
+		t.Errorf("unexpected counts after warm start: %v", counts)
+	}
+
+	err = experiments.WarmStart("greedy", []SelectionRecord{{Arm: 2, Reward: 1}})
+	if err == nil || !strings.Contains(err.Error(), `"greedy"`) {
+		t.Errorf("expected an error naming the experiment got %v", err)
+	}
+}
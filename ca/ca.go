@@ -0,0 +1,598 @@
+/*
+Package ca implements a pseudo certificate authority for testing purposes.
+
+The certificate authority creates a self-signed CA certificate and key pair
+and issues certificates signed by the CA so that TLS and mTLS connections can
+be tested without external tooling. The CA can be persisted in a certs
+directory so that it is reused between runs, or kept entirely in memory so
+that test suites can mint certificates in-process:
+
+	authority := ca.New("")
+	if err := authority.Init(ca.Subject{Organization: "Testing"}, false); err != nil {
+		return err
+	}
+
+	cert, err := authority.Issue(ca.Subject{CommonName: "localhost"})
+	tlsCert := cert.TLSCertificate()
+
+The ca command in the cmd/ca directory is a CLI wrapper around this package.
+*/
+package ca
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// File names of the CA certificate and private key in the certs directory.
+const (
+	CertFile = "ca.crt"
+	KeyFile  = "ca.key"
+)
+
+// DefaultKeyBits is the size of the RSA keys generated if not specified.
+const DefaultKeyBits = 4096
+
+// PEM block types of certificates and keys.
+const (
+	certificateBlock = "CERTIFICATE"
+	rsaKeyBlock      = "RSA PRIVATE KEY"
+	pkcs8KeyBlock    = "PRIVATE KEY"
+	csrBlock         = "CERTIFICATE REQUEST"
+	legacyCSRBlock   = "NEW CERTIFICATE REQUEST"
+)
+
+//===========================================================================
+// Certificate Authority
+//===========================================================================
+
+// CA is a certificate authority that issues certificates signed by its key.
+// The CA must be initialized or loaded before certificates can be issued.
+type CA struct {
+	Dir           string              // directory the CA files are stored in (empty for in-memory)
+	KeyBits       int                 // size of generated RSA keys, DefaultKeyBits if zero
+	Validity      Validity            // validity of issued certificates, DefaultValidity if zero
+	CAValidity    Validity            // validity of created CA certificates, DefaultCAValidity if zero
+	Cert          *x509.Certificate   // the CA certificate
+	Key           *rsa.PrivateKey     // the CA private key
+	Signer        crypto.Signer       // signs with a key outside the process, e.g. in an HSM; used instead of Key
+	Previous      *x509.Certificate   // the CA certificate before the last rotation
+	PreviousKey   *rsa.PrivateKey     // the CA private key before the last rotation
+	Cross         *x509.Certificate   // the CA certificate cross-signed by the previous CA
+	PreviousCross *x509.Certificate   // the previous CA certificate cross-signed by the CA
+	Chain         []*x509.Certificate // intermediate certificates to the root (empty for a root CA)
+	serials       map[string]*Record
+}
+
+// New creates a certificate authority that stores its certificate and key in
+// the specified directory. If the directory is empty, the CA is kept in memory
+// and nothing is written to disk.
+func New(dir string) *CA {
+	return &CA{Dir: dir}
+}
+
+// Init creates a new CA certificate and private key for the subject. If the
+// CA has a directory, the certificate and key are written to it; an error is
+// returned if they already exist unless force is true.
+func (c *CA) Init(subject Subject, force bool) (err error) {
+	if c.Dir != "" && !force {
+		if _, err = os.Stat(c.path(CertFile)); err == nil {
+			return errors.New("certificate file already exists")
+		}
+		if _, err = os.Stat(c.path(KeyFile)); err == nil {
+			return errors.New("private key file already exists")
+		}
+	}
+
+	// A new CA starts a new serial index
+	c.serials = make(map[string]*Record)
+
+	var (
+		cert *x509.Certificate
+		priv *rsa.PrivateKey
+	)
+	if cert, priv, err = c.root(subject.Name()); err != nil {
+		return err
+	}
+
+	c.Cert, c.Key, c.Signer = cert, priv, nil
+	c.Previous, c.PreviousKey, c.Cross, c.PreviousCross, c.Chain = nil, nil, nil, nil, nil
+	if c.Dir != "" {
+		if err = c.removeRotation(); err != nil {
+			return err
+		}
+
+		if err = os.Remove(c.path(ChainFile)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		if err = writePair(c.path(CertFile), c.path(KeyFile), c.Cert, c.Key); err != nil {
+			return err
+		}
+	}
+	return c.record(cert)
+}
+
+// Load the CA certificate, private key, and serial index from the CA directory.
+func (c *CA) Load() (err error) {
+	if c.Dir == "" {
+		return errors.New("no directory to load the ca from")
+	}
+
+	if c.Cert, c.Key, err = readPair(c.path(CertFile), c.path(KeyFile)); err != nil {
+		return err
+	}
+	c.Signer = nil
+
+	if err = c.loadRotation(); err != nil {
+		return err
+	}
+
+	if err = c.loadChain(); err != nil {
+		return err
+	}
+	return c.loadSerials()
+}
+
+// LoadOrCreate loads the CA from its directory if the CA certificate exists,
+// otherwise it initializes a new CA for the subject. This is useful in test
+// suites so that the same CA is used on every run.
+func (c *CA) LoadOrCreate(subject Subject) error {
+	if c.Dir != "" {
+		if _, err := os.Stat(c.path(CertFile)); err == nil {
+			return c.Load()
+		}
+
+		if err := os.MkdirAll(c.Dir, 0755); err != nil {
+			return err
+		}
+	}
+	return c.Init(subject, false)
+}
+
+// Issue a certificate for the subject signed by the CA. A new private key is
+// generated for the certificate and a unique random serial number is recorded
+// in the serial index. The DNS names of the subject may be wildcards but must
+// be valid (see ValidateDNSName) and the IP addresses must be specified. The
+// issued certificate is not written to disk; use its Write method to save it in
+// a directory.
+func (c *CA) Issue(subject Subject) (_ *Certificate, err error) {
+	if c.Cert == nil || c.signer() == nil {
+		return nil, errors.New("ca has not been initialized or loaded")
+	}
+
+	if err = validateSANs(subject.DNSNames, subject.IPAddresses); err != nil {
+		return nil, err
+	}
+
+	var priv *rsa.PrivateKey
+	if priv, err = rsa.GenerateKey(rand.Reader, c.keyBits()); err != nil {
+		return nil, fmt.Errorf("could not generate key: %s", err)
+	}
+
+	template := &x509.Certificate{
+		Subject:     subject.Name(),
+		DNSNames:    subject.DNSNames,
+		IPAddresses: subject.IPAddresses,
+	}
+
+	cert := &Certificate{Key: priv}
+	if cert.Cert, cert.Chain, err = c.sign(template, &priv.PublicKey); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// Sign a certificate signing request, issuing a certificate for a key that was
+// generated elsewhere, e.g. on a remote host or in an HSM. The signature of the
+// request is checked and the subject and subject alternative names of the
+// request are used. The subject alternative names must meet the same policy as
+// Issue: valid DNS names and specified IP addresses; requests for email address
+// or URI names are rejected. The returned certificate does not have a private key.
+func (c *CA) Sign(csr *x509.CertificateRequest) (_ *Certificate, err error) {
+	if c.Cert == nil || c.signer() == nil {
+		return nil, errors.New("ca has not been initialized or loaded")
+	}
+
+	if err = csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("invalid certificate signing request: %s", err)
+	}
+
+	if len(csr.EmailAddresses) > 0 || len(csr.URIs) > 0 {
+		return nil, errors.New("invalid certificate signing request: email address and uri subject alternative names are not supported")
+	}
+
+	if err = validateSANs(csr.DNSNames, csr.IPAddresses); err != nil {
+		return nil, fmt.Errorf("invalid certificate signing request: %s", err)
+	}
+
+	template := &x509.Certificate{
+		Subject:     csr.Subject,
+		DNSNames:    csr.DNSNames,
+		IPAddresses: csr.IPAddresses,
+	}
+
+	cert := &Certificate{}
+	if cert.Cert, cert.Chain, err = c.sign(template, csr.PublicKey); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// Completes the leaf certificate template with a serial number, validity, and
+// key usages, signs it with the CA key, and records it in the serial index.
+// Returns the signed certificate and the chain that clients need to verify it.
+func (c *CA) sign(template *x509.Certificate, pub interface{}) (_ *x509.Certificate, chain []*x509.Certificate, err error) {
+	if template.SerialNumber, err = c.serial(); err != nil {
+		return nil, nil, err
+	}
+
+	if template.SubjectKeyId, err = keyID(pub); err != nil {
+		return nil, nil, err
+	}
+
+	if template.NotBefore, template.NotAfter, err = c.Validity.Period(DefaultValidity); err != nil {
+		return nil, nil, err
+	}
+
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+
+	// Sign the certificate
+	var signed []byte
+	if signed, err = x509.CreateCertificate(rand.Reader, template, c.Cert, pub, c.signer()); err != nil {
+		return nil, nil, err
+	}
+
+	var cert *x509.Certificate
+	if cert, err = x509.ParseCertificate(signed); err != nil {
+		return nil, nil, err
+	}
+
+	// Clients that trust the root need the intermediate certificates and during
+	// a rotation, clients that only trust the previous CA can verify the
+	// certificate via the cross-signed CA certificate.
+	chain = append(chain, c.Chain...)
+	if c.Cross != nil {
+		chain = append(chain, c.Cross)
+	}
+
+	if err = c.record(cert); err != nil {
+		return nil, nil, err
+	}
+	return cert, chain, nil
+}
+
+// CertPool returns a certificate pool containing the CA certificate that can
+// be used as the RootCAs or ClientCAs of a tls.Config. If the CA has been
+// rotated, the previous CA certificate is also trusted.
+func (c *CA) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	if c.Cert != nil {
+		pool.AddCert(c.Cert)
+	}
+	if c.Previous != nil {
+		pool.AddCert(c.Previous)
+	}
+	return pool
+}
+
+// Creates a new self-signed root certificate and private key for the subject.
+func (c *CA) root(subject pkix.Name) (_ *x509.Certificate, _ *rsa.PrivateKey, err error) {
+	var priv *rsa.PrivateKey
+	if priv, err = rsa.GenerateKey(rand.Reader, c.keyBits()); err != nil {
+		return nil, nil, fmt.Errorf("could not generate ca key: %s", err)
+	}
+
+	var cert *x509.Certificate
+	if cert, err = c.selfSign(subject, priv); err != nil {
+		return nil, nil, err
+	}
+	return cert, priv, nil
+}
+
+// Creates a new root certificate for the subject self-signed by the signer.
+func (c *CA) selfSign(subject pkix.Name, signer crypto.Signer) (_ *x509.Certificate, err error) {
+	var serial *big.Int
+	if serial, err = c.serial(); err != nil {
+		return nil, err
+	}
+
+	var notBefore, notAfter time.Time
+	if notBefore, notAfter, err = c.CAValidity.Period(DefaultCAValidity); err != nil {
+		return nil, err
+	}
+
+	// Create a certificate
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+	}
+
+	var signed []byte
+	if signed, err = x509.CreateCertificate(rand.Reader, template, template, signer.Public(), signer); err != nil {
+		return nil, fmt.Errorf("create ca failed: %s", err)
+	}
+	return x509.ParseCertificate(signed)
+}
+
+// Returns the path of the file in the CA directory.
+func (c *CA) path(name string) string {
+	return filepath.Join(c.Dir, name)
+}
+
+// Returns the number of bits to generate RSA keys with.
+func (c *CA) keyBits() int {
+	if c.KeyBits > 0 {
+		return c.KeyBits
+	}
+	return DefaultKeyBits
+}
+
+//===========================================================================
+// Subject
+//===========================================================================
+
+// Subject describes the entity that a certificate is issued for. The DNS names
+// and IP addresses are added to issued certificates as subject alternative
+// names, which modern TLS stacks require to verify the host.
+type Subject struct {
+	CommonName    string
+	Organization  string
+	Country       string
+	Province      string
+	Locality      string
+	StreetAddress string
+	PostalCode    string
+	DNSNames      []string
+	IPAddresses   []net.IP
+}
+
+// Name returns the distinguished name of the subject, omitting empty fields.
+// If the subject has no common name, the first DNS name is used.
+func (s Subject) Name() pkix.Name {
+	name := pkix.Name{CommonName: s.CommonName}
+	if name.CommonName == "" && len(s.DNSNames) > 0 {
+		name.CommonName = s.DNSNames[0]
+	}
+
+	name.Organization = optional(s.Organization)
+	name.Country = optional(s.Country)
+	name.Province = optional(s.Province)
+	name.Locality = optional(s.Locality)
+	name.StreetAddress = optional(s.StreetAddress)
+	name.PostalCode = optional(s.PostalCode)
+	return name
+}
+
+// Returns a slice with the value or nil if the value is empty.
+func optional(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
+
+//===========================================================================
+// Issued Certificates
+//===========================================================================
+
+// Certificate is a certificate issued by the CA along with its private key.
+// The chain contains any intermediate certificates that clients may need to
+// verify the certificate, e.g. the cross-signed CA certificate after rotation.
+type Certificate struct {
+	Cert  *x509.Certificate
+	Key   *rsa.PrivateKey
+	Chain []*x509.Certificate
+}
+
+// CertPEM returns the PEM encoded certificate.
+func (c *Certificate) CertPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: certificateBlock, Bytes: c.Cert.Raw})
+}
+
+// BundlePEM returns the PEM encoded certificate followed by its chain.
+func (c *Certificate) BundlePEM() []byte {
+	bundle := c.CertPEM()
+	for _, cert := range c.Chain {
+		bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: certificateBlock, Bytes: cert.Raw})...)
+	}
+	return bundle
+}
+
+// CombinedPEM returns the PEM encoded private key followed by the certificate
+// and its chain in a single file, e.g. for container deployments.
+func (c *Certificate) CombinedPEM() []byte {
+	return append(c.KeyPEM(), c.BundlePEM()...)
+}
+
+// KeyPEM returns the PEM encoded private key or nil if the certificate was
+// signed from a request and does not have a private key.
+func (c *Certificate) KeyPEM() []byte {
+	if c.Key == nil {
+		return nil
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: rsaKeyBlock, Bytes: x509.MarshalPKCS1PrivateKey(c.Key)})
+}
+
+// TLSCertificate returns the certificate and key for use in a tls.Config. If
+// the certificate was signed from a request, the private key must be added.
+func (c *Certificate) TLSCertificate() tls.Certificate {
+	chain := [][]byte{c.Cert.Raw}
+	for _, cert := range c.Chain {
+		chain = append(chain, cert.Raw)
+	}
+
+	tlsCert := tls.Certificate{Certificate: chain, Leaf: c.Cert}
+	if c.Key != nil {
+		tlsCert.PrivateKey = c.Key
+	}
+	return tlsCert
+}
+
+// Write the certificate and private key to name.crt and name.key in the
+// specified directory. The private key is only readable by the user and is not
+// written if the certificate was signed from a request. If the certificate has
+// a chain, the bundle is also written to name.bundle.crt.
+func (c *Certificate) Write(dir, name string) (err error) {
+	if c.Key == nil {
+		err = writeCert(filepath.Join(dir, name+".crt"), c.Cert)
+	} else {
+		err = writePair(filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key"), c.Cert, c.Key)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if len(c.Chain) > 0 {
+		return ioutil.WriteFile(filepath.Join(dir, name+".bundle.crt"), c.BundlePEM(), 0644)
+	}
+	return nil
+}
+
+//===========================================================================
+// Helper Functions
+//===========================================================================
+
+// ParseCertificates parses all of the PEM encoded certificates in the data, e.g.
+// a certificate followed by its chain. Other PEM blocks are skipped.
+func ParseCertificates(data []byte) (certs []*x509.Certificate, err error) {
+	for {
+		var block *pem.Block
+		if block, data = pem.Decode(data); block == nil {
+			break
+		}
+
+		if block.Type != certificateBlock {
+			continue
+		}
+
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return certs, nil
+}
+
+// ParseCSR parses a PEM encoded certificate signing request.
+func ParseCSR(data []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || (block.Type != csrBlock && block.Type != legacyCSRBlock) {
+		return nil, errors.New("no certificate signing request found")
+	}
+	return x509.ParseCertificateRequest(block.Bytes)
+}
+
+// Computes the subject key id of a public key as the SHA-1 hash of the
+// marshaled public key (RFC 5280 section 4.2.1.2 method 1).
+func keyID(pub interface{}) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal public key: %s", err)
+	}
+
+	var info struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err = asn1.Unmarshal(der, &info); err != nil {
+		return nil, err
+	}
+
+	sum := sha1.Sum(info.PublicKey.Bytes)
+	return sum[:], nil
+}
+
+// Writes a PEM encoded certificate and private key to the specified paths.
+func writePair(certPath, keyPath string, cert *x509.Certificate, key *rsa.PrivateKey) (err error) {
+	if err = ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: certificateBlock, Bytes: cert.Raw}), 0644); err != nil {
+		return err
+	}
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: rsaKeyBlock, Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return ioutil.WriteFile(keyPath, keyPEM, 0600)
+}
+
+// Reads a PEM encoded certificate and RSA private key from the specified paths.
+func readPair(certPath, keyPath string) (cert *x509.Certificate, key *rsa.PrivateKey, err error) {
+	if cert, err = readCert(certPath); err != nil {
+		return nil, nil, err
+	}
+
+	if key, err = readKey(keyPath); err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+// Reads the first PEM encoded certificate from the specified path.
+func readCert(path string) (*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != certificateBlock {
+		return nil, fmt.Errorf("no certificate found in %s", path)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// Reads a PEM encoded PKCS1 or PKCS8 RSA private key from the specified path.
+func readKey(path string) (*rsa.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no private key found in %s", path)
+	}
+
+	switch block.Type {
+	case rsaKeyBlock:
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case pkcs8KeyBlock:
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		if rsaKey, ok := key.(*rsa.PrivateKey); ok {
+			return rsaKey, nil
+		}
+		return nil, fmt.Errorf("private key in %s is not an RSA key", path)
+	default:
+		return nil, fmt.Errorf("unknown private key type %q in %s", block.Type, path)
+	}
+}
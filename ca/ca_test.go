@@ -0,0 +1,802 @@
+package ca_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bbengfort/x/ca"
+	. "github.com/onsi/gomega"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// Use smaller keys in tests so that key generation is fast.
+const testKeyBits = 2048
+
+func TestInMemory(t *testing.T) {
+	RegisterTestingT(t)
+
+	authority := ca.New("")
+	authority.KeyBits = testKeyBits
+	Ω(authority.Init(ca.Subject{Organization: "Testing"}, false)).Should(Succeed())
+	Ω(authority.Cert.IsCA).Should(BeTrue())
+	Ω(authority.Cert.Subject.Organization).Should(Equal([]string{"Testing"}))
+
+	cert, err := authority.Issue(ca.Subject{CommonName: "localhost", Organization: "Testing"})
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(cert.Cert.Subject.CommonName).Should(Equal("localhost"))
+
+	// The issued certificate should be verified by the CA
+	_, err = cert.Cert.Verify(x509.VerifyOptions{
+		Roots:     authority.CertPool(),
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	Ω(err).ShouldNot(HaveOccurred())
+
+	// The PEM encoded certificate and key should be a valid key pair
+	_, err = tls.X509KeyPair(cert.CertPEM(), cert.KeyPEM())
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(cert.TLSCertificate().Leaf).Should(Equal(cert.Cert))
+}
+
+func TestIssueUninitialized(t *testing.T) {
+	RegisterTestingT(t)
+	_, err := ca.New("").Issue(ca.Subject{Organization: "Testing"})
+	Ω(err).Should(HaveOccurred())
+}
+
+func TestLoadOrCreate(t *testing.T) {
+	RegisterTestingT(t)
+
+	tmpDir, err := ioutil.TempDir("", "com.bengfort.x.ca")
+	Ω(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(tmpDir)
+
+	// Should create the CA in a directory that does not exist yet
+	dir := filepath.Join(tmpDir, "certs")
+	first := ca.New(dir)
+	first.KeyBits = testKeyBits
+	Ω(first.LoadOrCreate(ca.Subject{Organization: "Testing"})).Should(Succeed())
+	Ω(filepath.Join(dir, ca.CertFile)).Should(BeARegularFile())
+	Ω(filepath.Join(dir, ca.KeyFile)).Should(BeARegularFile())
+
+	// Should not overwrite the CA unless forced
+	Ω(first.Init(ca.Subject{Organization: "Testing"}, false)).ShouldNot(Succeed())
+
+	// Should load the same CA on the second call
+	second := ca.New(dir)
+	Ω(second.LoadOrCreate(ca.Subject{Organization: "Other"})).Should(Succeed())
+	Ω(second.Cert.Equal(first.Cert)).Should(BeTrue())
+	Ω(second.Key.Equal(first.Key)).Should(BeTrue())
+
+	// Issued certificates can be written to the directory
+	cert, err := second.Issue(ca.Subject{Organization: "Testing"})
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(cert.Write(dir, "testing")).Should(Succeed())
+
+	_, err = tls.LoadX509KeyPair(filepath.Join(dir, "testing.crt"), filepath.Join(dir, "testing.key"))
+	Ω(err).ShouldNot(HaveOccurred())
+
+	info, err := os.Stat(filepath.Join(dir, "testing.key"))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(info.Mode().Perm()).Should(Equal(os.FileMode(0600)))
+}
+
+func TestSerials(t *testing.T) {
+	RegisterTestingT(t)
+
+	tmpDir, err := ioutil.TempDir("", "com.bengfort.x.ca")
+	Ω(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(tmpDir)
+
+	authority := ca.New(tmpDir)
+	authority.KeyBits = testKeyBits
+	Ω(authority.Init(ca.Subject{Organization: "Testing"}, false)).Should(Succeed())
+
+	// Repeated issue calls should never reuse a serial number
+	serials := map[string]bool{authority.Cert.SerialNumber.String(): true}
+	for i := 0; i < 3; i++ {
+		cert, err := authority.Issue(ca.Subject{Organization: "Testing"})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(cert.Cert.SerialNumber.Sign()).Should(Equal(1))
+		Ω(serials).ShouldNot(HaveKey(cert.Cert.SerialNumber.String()))
+		serials[cert.Cert.SerialNumber.String()] = true
+	}
+
+	records := authority.Records()
+	Ω(records).Should(HaveLen(4))
+	Ω(records[0].IsCA).Should(BeTrue())
+	Ω(records[1].Subject).Should(Equal("O=Testing"))
+	Ω(records[1].Expired()).Should(BeFalse())
+	Ω(filepath.Join(tmpDir, ca.SerialFile)).Should(BeARegularFile())
+
+	// The serial index should be loaded with the CA
+	loaded := ca.New(tmpDir)
+	Ω(loaded.Load()).Should(Succeed())
+	Ω(loaded.Records()).Should(HaveLen(4))
+	for i, record := range loaded.Records() {
+		Ω(record.Serial).Should(Equal(records[i].Serial))
+	}
+}
+
+func TestSubjectAltNames(t *testing.T) {
+	RegisterTestingT(t)
+
+	authority := ca.New("")
+	authority.KeyBits = testKeyBits
+	Ω(authority.Init(ca.Subject{Organization: "Testing"}, false)).Should(Succeed())
+
+	cert, err := authority.Issue(ca.Subject{
+		Organization: "Testing",
+		DNSNames:     []string{"example.com", "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	})
+	Ω(err).ShouldNot(HaveOccurred())
+
+	// The common name should default to the first DNS name
+	Ω(cert.Cert.Subject.CommonName).Should(Equal("example.com"))
+	Ω(cert.Cert.DNSNames).Should(Equal([]string{"example.com", "localhost"}))
+	Ω(cert.Cert.VerifyHostname("localhost")).Should(Succeed())
+	Ω(cert.Cert.VerifyHostname("127.0.0.1")).Should(Succeed())
+	Ω(cert.Cert.VerifyHostname("other.com")).ShouldNot(Succeed())
+
+	// An explicit common name should not be replaced
+	name := ca.Subject{CommonName: "service", DNSNames: []string{"example.com"}}.Name()
+	Ω(name.CommonName).Should(Equal("service"))
+}
+
+func TestRevoke(t *testing.T) {
+	RegisterTestingT(t)
+
+	authority := ca.New("")
+	authority.KeyBits = testKeyBits
+	Ω(authority.Init(ca.Subject{Organization: "Testing"}, false)).Should(Succeed())
+
+	revoked, err := authority.Issue(ca.Subject{CommonName: "revoked"})
+	Ω(err).ShouldNot(HaveOccurred())
+	_, err = authority.Issue(ca.Subject{CommonName: "valid"})
+	Ω(err).ShouldNot(HaveOccurred())
+
+	// Should be able to revoke a certificate only once by its hex serial
+	serial := fmt.Sprintf("%x", revoked.Cert.SerialNumber)
+	Ω(authority.Revoke(serial)).Should(Succeed())
+	Ω(authority.Revoke(serial)).ShouldNot(Succeed())
+	Ω(authority.Revoke("abc123")).ShouldNot(Succeed())
+	Ω(authority.Revoke(fmt.Sprintf("%x", authority.Cert.SerialNumber))).ShouldNot(Succeed())
+
+	// The CRL should be signed by the CA and only contain the revoked certificate
+	data, err := authority.CRL(0)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	block, _ := pem.Decode(data)
+	Ω(block).ShouldNot(BeNil())
+	Ω(block.Type).Should(Equal("X509 CRL"))
+
+	crl, err := x509.ParseCRL(block.Bytes)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(authority.Cert.CheckCRLSignature(crl)).Should(Succeed())
+	Ω(crl.TBSCertList.RevokedCertificates).Should(HaveLen(1))
+	Ω(crl.TBSCertList.RevokedCertificates[0].SerialNumber).Should(Equal(revoked.Cert.SerialNumber))
+}
+
+func TestRotate(t *testing.T) {
+	RegisterTestingT(t)
+
+	tmpDir, err := ioutil.TempDir("", "com.bengfort.x.ca")
+	Ω(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(tmpDir)
+
+	authority := ca.New(tmpDir)
+	authority.KeyBits = testKeyBits
+	Ω(authority.Rotate(ca.Subject{})).ShouldNot(Succeed())
+	Ω(authority.Init(ca.Subject{Organization: "Testing"}, false)).Should(Succeed())
+	Ω(authority.Rotate(ca.Subject{Organization: "Testing"})).ShouldNot(Succeed())
+
+	before, err := authority.Issue(ca.Subject{CommonName: "before"})
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(before.Chain).Should(BeEmpty())
+
+	oldRoot := authority.Cert
+	Ω(authority.Rotate(ca.Subject{})).Should(Succeed())
+	Ω(authority.Cert.Equal(oldRoot)).Should(BeFalse())
+	Ω(authority.Previous.Equal(oldRoot)).Should(BeTrue())
+	Ω(authority.Cert.Subject.Organization).Should(Equal(oldRoot.Subject.Organization))
+	Ω(authority.Cert.Subject.SerialNumber).ShouldNot(BeEmpty())
+
+	after, err := authority.Issue(ca.Subject{CommonName: "after"})
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(after.Chain).Should(HaveLen(1))
+
+	oldPool := x509.NewCertPool()
+	oldPool.AddCert(oldRoot)
+	newPool := x509.NewCertPool()
+	newPool.AddCert(authority.Cert)
+
+	verify := func(cert *x509.Certificate, roots *x509.CertPool, intermediates ...*x509.Certificate) error {
+		pool := x509.NewCertPool()
+		for _, inter := range intermediates {
+			pool.AddCert(inter)
+		}
+		_, err := cert.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: pool,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		return err
+	}
+
+	// Certificates issued after rotation verify with either root via the bundle
+	Ω(verify(after.Cert, newPool, after.Chain...)).Should(Succeed())
+	Ω(verify(after.Cert, oldPool, after.Chain...)).Should(Succeed())
+	Ω(verify(after.Cert, oldPool)).ShouldNot(Succeed())
+
+	// Certificates issued before rotation verify with the new root via the cross-signed previous root
+	Ω(verify(before.Cert, oldPool)).Should(Succeed())
+	Ω(verify(before.Cert, newPool, authority.PreviousCross)).Should(Succeed())
+	Ω(verify(before.Cert, newPool)).ShouldNot(Succeed())
+
+	// The bundle should be written along with the certificate
+	Ω(after.Write(tmpDir, "after")).Should(Succeed())
+	_, err = tls.LoadX509KeyPair(filepath.Join(tmpDir, "after.bundle.crt"), filepath.Join(tmpDir, "after.key"))
+	Ω(err).ShouldNot(HaveOccurred())
+
+	// The rotation should be loaded with the CA
+	loaded := ca.New(tmpDir)
+	Ω(loaded.Load()).Should(Succeed())
+	Ω(loaded.Previous.Equal(oldRoot)).Should(BeTrue())
+	Ω(loaded.Cross.Equal(authority.Cross)).Should(BeTrue())
+	Ω(loaded.PreviousCross.Equal(authority.PreviousCross)).Should(BeTrue())
+	Ω(loaded.Records()).Should(HaveLen(6))
+
+	// Forcing a new CA should discard the rotation
+	Ω(loaded.Init(ca.Subject{Organization: "Testing"}, true)).Should(Succeed())
+	Ω(filepath.Join(tmpDir, ca.PreviousCertFile)).ShouldNot(BeAnExistingFile())
+	Ω(loaded.Load()).Should(Succeed())
+	Ω(loaded.Previous).Should(BeNil())
+}
+
+func TestSign(t *testing.T) {
+	RegisterTestingT(t)
+
+	authority := ca.New("")
+	authority.KeyBits = testKeyBits
+	Ω(authority.Init(ca.Subject{Organization: "Testing"}, false)).Should(Succeed())
+
+	// Create a request for a key generated elsewhere
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "remote"},
+		DNSNames: []string{"remote.local"},
+	}, key)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	csr, err := ca.ParseCSR(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+	Ω(err).ShouldNot(HaveOccurred())
+
+	cert, err := authority.Sign(csr)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(cert.Key).Should(BeNil())
+	Ω(cert.KeyPEM()).Should(BeNil())
+	Ω(cert.Cert.Subject.CommonName).Should(Equal("remote"))
+	Ω(cert.Cert.DNSNames).Should(Equal([]string{"remote.local"}))
+	Ω(cert.Cert.PublicKey).Should(Equal(&key.PublicKey))
+	Ω(cert.Cert.SubjectKeyId).Should(HaveLen(20))
+
+	_, err = cert.Cert.Verify(x509.VerifyOptions{
+		Roots:     authority.CertPool(),
+		DNSName:   "remote.local",
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	Ω(err).ShouldNot(HaveOccurred())
+
+	// Requests with invalid signatures should not be signed
+	csr.Signature[0] ^= 0xff
+	_, err = authority.Sign(csr)
+	Ω(err).Should(HaveOccurred())
+
+	// Requests must meet the same subject alternative name policy as Issue
+	for _, req := range []*x509.CertificateRequest{
+		{Subject: pkix.Name{CommonName: "remote"}, DNSNames: []string{"bad name"}},
+		{Subject: pkix.Name{CommonName: "remote"}, DNSNames: []string{"api*.remote.local"}},
+		{Subject: pkix.Name{CommonName: "remote"}, IPAddresses: []net.IP{net.IPv4zero}},
+		{Subject: pkix.Name{CommonName: "remote"}, EmailAddresses: []string{"admin@remote.local"}},
+		{Subject: pkix.Name{CommonName: "remote"}, URIs: []*url.URL{{Scheme: "spiffe", Host: "remote.local"}}},
+	} {
+		der, err = x509.CreateCertificateRequest(rand.Reader, req, key)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		csr, err = x509.ParseCertificateRequest(der)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, err = authority.Sign(csr)
+		Ω(err).Should(MatchError(ContainSubstring("invalid certificate signing request")))
+	}
+
+	_, err = ca.ParseCSR([]byte("not a request"))
+	Ω(err).Should(HaveOccurred())
+}
+
+func TestIntermediate(t *testing.T) {
+	RegisterTestingT(t)
+
+	tmpDir, err := ioutil.TempDir("", "com.bengfort.x.ca")
+	Ω(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(tmpDir)
+
+	root := ca.New("")
+	root.KeyBits = testKeyBits
+	Ω(root.Init(ca.Subject{Organization: "Testing"}, false)).Should(Succeed())
+
+	// Create two levels of intermediates, the second stored on disk
+	inter, err := root.Intermediate("", ca.Subject{CommonName: "Intermediate"})
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(inter.Cert.IsCA).Should(BeTrue())
+	Ω(inter.Chain).Should(HaveLen(1))
+	Ω(inter.Rotate(ca.Subject{})).ShouldNot(Succeed())
+
+	dir := filepath.Join(tmpDir, "issuing")
+	issuing, err := inter.Intermediate(dir, ca.Subject{CommonName: "Issuing"})
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(issuing.Chain).Should(HaveLen(2))
+	Ω(filepath.Join(dir, ca.ChainFile)).Should(BeARegularFile())
+
+	// The intermediates should be recorded by their issuers
+	Ω(root.Records()).Should(HaveLen(2))
+	Ω(inter.Records()).Should(HaveLen(2))
+
+	// Leaves issued by the loaded intermediate should verify against the root
+	loaded := ca.New(dir)
+	Ω(loaded.Load()).Should(Succeed())
+	Ω(loaded.Chain).Should(HaveLen(2))
+	Ω(loaded.Chain[0].Equal(issuing.Cert)).Should(BeTrue())
+
+	cert, err := loaded.Issue(ca.Subject{DNSNames: []string{"localhost"}})
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(cert.Chain).Should(HaveLen(2))
+	Ω(cert.TLSCertificate().Certificate).Should(HaveLen(3))
+
+	intermediates := x509.NewCertPool()
+	for _, c := range cert.Chain {
+		intermediates.AddCert(c)
+	}
+
+	chains, err := cert.Cert.Verify(x509.VerifyOptions{
+		Roots:         root.CertPool(),
+		Intermediates: intermediates,
+		DNSName:       "localhost",
+	})
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(chains[0]).Should(HaveLen(4))
+
+	// Without the chain the leaf should not verify against the root
+	_, err = cert.Cert.Verify(x509.VerifyOptions{Roots: root.CertPool()})
+	Ω(err).Should(HaveOccurred())
+}
+
+func TestVerify(t *testing.T) {
+	RegisterTestingT(t)
+
+	root := ca.New("")
+	root.KeyBits = testKeyBits
+	Ω(root.Init(ca.Subject{Organization: "Testing"}, false)).Should(Succeed())
+
+	inter, err := root.Intermediate("", ca.Subject{CommonName: "Intermediate"})
+	Ω(err).ShouldNot(HaveOccurred())
+
+	cert, err := inter.Issue(ca.Subject{DNSNames: []string{"localhost"}})
+	Ω(err).ShouldNot(HaveOccurred())
+
+	// The bundle should parse into the certificate and its chain
+	certs, err := ca.ParseCertificates(cert.BundlePEM())
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(certs).Should(HaveLen(2))
+	Ω(certs[0].Equal(cert.Cert)).Should(BeTrue())
+
+	_, err = ca.ParseCertificates([]byte("no certificates"))
+	Ω(err).Should(HaveOccurred())
+
+	// The root needs the chain, the intermediate includes its own chain
+	_, err = root.Verify(certs[0])
+	Ω(err).Should(HaveOccurred())
+
+	chains, err := root.Verify(certs[0], certs[1:]...)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(chains[0]).Should(HaveLen(3))
+
+	_, err = inter.Verify(certs[0])
+	Ω(err).ShouldNot(HaveOccurred())
+
+	// Revoked certificates should not verify
+	Ω(inter.Revoke(fmt.Sprintf("%x", cert.Cert.SerialNumber))).Should(Succeed())
+	_, err = inter.Verify(certs[0])
+	Ω(err).Should(MatchError(ContainSubstring("revoked")))
+}
+
+func TestValidity(t *testing.T) {
+	RegisterTestingT(t)
+
+	authority := ca.New("")
+	authority.KeyBits = testKeyBits
+	authority.CAValidity = ca.Validity{Duration: 30 * 24 * time.Hour}
+	Ω(authority.Init(ca.Subject{Organization: "Testing"}, false)).Should(Succeed())
+	Ω(authority.Cert.NotAfter.Sub(authority.Cert.NotBefore)).Should(Equal(30 * 24 * time.Hour))
+
+	// Issued certificates default to seven days
+	cert, err := authority.Issue(ca.Subject{CommonName: "localhost"})
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(cert.Cert.NotAfter.Sub(cert.Cert.NotBefore)).Should(Equal(ca.DefaultValidity))
+
+	// Backdating moves the start but not the end of the validity period
+	authority.Validity = ca.Validity{Duration: time.Hour, Backdate: 10 * time.Minute}
+	cert, err = authority.Issue(ca.Subject{CommonName: "localhost"})
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(cert.Cert.NotAfter.Sub(cert.Cert.NotBefore)).Should(Equal(70 * time.Minute))
+	Ω(cert.Cert.NotBefore).Should(BeTemporally("~", time.Now().Add(-10*time.Minute), time.Second))
+
+	// Deliberately expired certificates should fail verification
+	authority.Validity = ca.Validity{NotBefore: time.Now().AddDate(0, 0, -2), NotAfter: time.Now().AddDate(0, 0, -1)}
+	cert, err = authority.Issue(ca.Subject{CommonName: "localhost"})
+	Ω(err).ShouldNot(HaveOccurred())
+	_, err = authority.Verify(cert.Cert)
+	Ω(err).Should(HaveOccurred())
+
+	// A certificate cannot expire before it is valid
+	authority.Validity = ca.Validity{NotAfter: time.Now().AddDate(0, 0, -1)}
+	_, err = authority.Issue(ca.Subject{CommonName: "localhost"})
+	Ω(err).Should(HaveOccurred())
+}
+
+func TestOutputFormats(t *testing.T) {
+	RegisterTestingT(t)
+
+	authority := ca.New("")
+	authority.KeyBits = testKeyBits
+	Ω(authority.Init(ca.Subject{Organization: "Testing"}, false)).Should(Succeed())
+
+	inter, err := authority.Intermediate("", ca.Subject{CommonName: "Intermediate"})
+	Ω(err).ShouldNot(HaveOccurred())
+
+	cert, err := inter.Issue(ca.Subject{DNSNames: []string{"localhost"}})
+	Ω(err).ShouldNot(HaveOccurred())
+
+	// The combined PEM should contain the key pair and the chain
+	combined := cert.CombinedPEM()
+	pair, err := tls.X509KeyPair(combined, combined)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(pair.Certificate).Should(HaveLen(2))
+
+	// The PKCS#12 bundle should decode to the key pair and chain with the password
+	_, err = cert.PKCS12("")
+	Ω(err).Should(HaveOccurred())
+
+	data, err := cert.PKCS12("changeit")
+	Ω(err).ShouldNot(HaveOccurred())
+
+	_, _, _, err = pkcs12.DecodeChain(data, "wrong")
+	Ω(err).Should(MatchError(pkcs12.ErrIncorrectPassword))
+
+	key, leaf, chain, err := pkcs12.DecodeChain(data, "changeit")
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(key).Should(Equal(cert.Key))
+	Ω(leaf.Equal(cert.Cert)).Should(BeTrue())
+	Ω(chain).Should(HaveLen(len(cert.Chain)))
+	for i, c := range chain {
+		Ω(c.Equal(cert.Chain[i])).Should(BeTrue())
+	}
+
+	// Certificates signed from a request have no key to bundle
+	_, err = (&ca.Certificate{Cert: cert.Cert}).PKCS12("changeit")
+	Ω(err).Should(HaveOccurred())
+}
+
+// Decode the PKCS#12 bundle with openssl to ensure that the key and chain can be
+// recovered by other implementations with the password.
+func TestPKCS12OpenSSL(t *testing.T) {
+	openssl, err := exec.LookPath("openssl")
+	if err != nil {
+		t.Skip("openssl is not available to decode PKCS#12 bundles")
+	}
+	RegisterTestingT(t)
+
+	authority := ca.New("")
+	authority.KeyBits = testKeyBits
+	Ω(authority.Init(ca.Subject{Organization: "Testing"}, false)).Should(Succeed())
+
+	inter, err := authority.Intermediate("", ca.Subject{CommonName: "Intermediate"})
+	Ω(err).ShouldNot(HaveOccurred())
+
+	cert, err := inter.Issue(ca.Subject{DNSNames: []string{"localhost"}})
+	Ω(err).ShouldNot(HaveOccurred())
+
+	data, err := cert.PKCS12("changeit")
+	Ω(err).ShouldNot(HaveOccurred())
+
+	path := filepath.Join(t.TempDir(), "localhost.p12")
+	Ω(ioutil.WriteFile(path, data, 0600)).Should(Succeed())
+
+	// The wrong password cannot decode the bundle
+	_, err = exec.Command(openssl, "pkcs12", "-in", path, "-passin", "pass:wrong", "-nodes").Output()
+	Ω(err).Should(HaveOccurred())
+
+	out, err := exec.Command(openssl, "pkcs12", "-in", path, "-passin", "pass:changeit", "-nodes").Output()
+	Ω(err).ShouldNot(HaveOccurred())
+
+	var key crypto.PrivateKey
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(out); block != nil; block, rest = pem.Decode(rest) {
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+			Ω(err).ShouldNot(HaveOccurred())
+		case "CERTIFICATE":
+			c, err := x509.ParseCertificate(block.Bytes)
+			Ω(err).ShouldNot(HaveOccurred())
+			certs = append(certs, c)
+		}
+	}
+
+	// The private key and the chain of certificates should be recovered
+	Ω(key).ShouldNot(BeNil())
+	Ω(cert.Key.Equal(key)).Should(BeTrue())
+
+	expected := append([]*x509.Certificate{cert.Cert}, cert.Chain...)
+	Ω(certs).Should(HaveLen(len(expected)))
+	for i, c := range expected {
+		Ω(c.Equal(certs[i])).Should(BeTrue(), "certificate %d of the chain does not match", i)
+	}
+}
+
+// A signer that hides its private key, e.g. like a key in an HSM or keychain.
+type hsmSigner struct {
+	key   crypto.Signer
+	signs int
+}
+
+func (s *hsmSigner) Public() crypto.PublicKey {
+	return s.key.Public()
+}
+
+func (s *hsmSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.signs++
+	return s.key.Sign(rand, digest, opts)
+}
+
+func TestSigner(t *testing.T) {
+	RegisterTestingT(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Ω(err).ShouldNot(HaveOccurred())
+	signer := &hsmSigner{key: key}
+
+	dir := t.TempDir()
+	authority := ca.New(dir)
+	authority.KeyBits = testKeyBits
+	Ω(authority.InitSigner(ca.Subject{Organization: "Testing"}, signer, false)).Should(Succeed())
+	Ω(authority.InitSigner(ca.Subject{Organization: "Testing"}, signer, false)).ShouldNot(Succeed())
+	Ω(authority.Key).Should(BeNil())
+	Ω(authority.Cert.PublicKeyAlgorithm).Should(Equal(x509.ECDSA))
+
+	// Only the certificate is written to disk
+	Ω(filepath.Join(dir, ca.CertFile)).Should(BeAnExistingFile())
+	Ω(filepath.Join(dir, ca.KeyFile)).ShouldNot(BeAnExistingFile())
+
+	// Certificates and CRLs are signed by the signer
+	cert, err := authority.Issue(ca.Subject{DNSNames: []string{"localhost"}})
+	Ω(err).ShouldNot(HaveOccurred())
+	_, err = authority.Verify(cert.Cert)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	_, err = authority.CRL(0)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(signer.signs).Should(Equal(3))
+
+	// The CA is loaded with the signer rather than the key file
+	loaded := ca.New(dir)
+	Ω(loaded.Load()).ShouldNot(Succeed())
+	Ω(loaded.LoadSigner(signer)).Should(Succeed())
+	Ω(loaded.Cert.Equal(authority.Cert)).Should(BeTrue())
+	Ω(loaded.Records()).Should(HaveLen(len(authority.Records())))
+
+	cert, err = loaded.Issue(ca.Subject{DNSNames: []string{"localhost"}})
+	Ω(err).ShouldNot(HaveOccurred())
+	_, err = authority.Verify(cert.Cert)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	// The signer must match the certificate
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(ca.New(dir).LoadSigner(other)).Should(MatchError(ContainSubstring("does not match")))
+}
+
+func TestRotateSigner(t *testing.T) {
+	RegisterTestingT(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Ω(err).ShouldNot(HaveOccurred())
+	signer := &hsmSigner{key: key}
+
+	dir := t.TempDir()
+	authority := ca.New(dir)
+	authority.KeyBits = testKeyBits
+	Ω(authority.Init(ca.Subject{Organization: "Testing"}, false)).Should(Succeed())
+	oldRoot := authority.Cert
+
+	before, err := authority.Issue(ca.Subject{DNSNames: []string{"before"}})
+	Ω(err).ShouldNot(HaveOccurred())
+
+	// Rotate from a key file to a key held by a signer
+	Ω(authority.RotateSigner(ca.Subject{}, nil)).ShouldNot(Succeed())
+	Ω(authority.RotateSigner(ca.Subject{}, signer)).Should(Succeed())
+	Ω(authority.RotateSigner(ca.Subject{Organization: "Again"}, signer)).Should(MatchError(ContainSubstring("different key")))
+	Ω(authority.Key).Should(BeNil())
+	Ω(authority.Signer).Should(Equal(signer))
+	Ω(authority.Previous.Equal(oldRoot)).Should(BeTrue())
+	Ω(authority.PreviousKey).ShouldNot(BeNil())
+
+	// The new root key is not on disk but the previous root key is
+	Ω(filepath.Join(dir, ca.KeyFile)).ShouldNot(BeAnExistingFile())
+	Ω(filepath.Join(dir, ca.PreviousKeyFile)).Should(BeAnExistingFile())
+
+	after, err := authority.Issue(ca.Subject{DNSNames: []string{"after"}})
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(after.Chain).Should(HaveLen(1))
+	Ω(signer.signs).Should(BeNumerically(">", 0))
+
+	// The CA can only be loaded with the new signer
+	loaded := ca.New(dir)
+	Ω(loaded.Load()).ShouldNot(Succeed())
+	Ω(loaded.LoadSigner(signer)).Should(Succeed())
+	Ω(loaded.Previous.Equal(oldRoot)).Should(BeTrue())
+
+	for _, cert := range []*ca.Certificate{before, after} {
+		_, err = loaded.Verify(cert.Cert, cert.Chain...)
+		Ω(err).ShouldNot(HaveOccurred())
+	}
+
+	// Rotating a signer-backed CA to a key file discards the previous key file
+	// since the previous root key is held by the signer
+	Ω(loaded.Rotate(ca.Subject{Organization: "Rotated"})).Should(Succeed())
+	Ω(loaded.Key).ShouldNot(BeNil())
+	Ω(loaded.Signer).Should(BeNil())
+	Ω(loaded.PreviousKey).Should(BeNil())
+	Ω(filepath.Join(dir, ca.KeyFile)).Should(BeAnExistingFile())
+	Ω(filepath.Join(dir, ca.PreviousKeyFile)).ShouldNot(BeAnExistingFile())
+
+	reloaded := ca.New(dir)
+	Ω(reloaded.Load()).Should(Succeed())
+	Ω(reloaded.Previous.Equal(loaded.Previous)).Should(BeTrue())
+	Ω(reloaded.PreviousKey).Should(BeNil())
+}
+
+func TestSignerRegistry(t *testing.T) {
+	RegisterTestingT(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	var opened ca.SignerConfig
+	ca.RegisterSigner("hsm", func(conf ca.SignerConfig) (crypto.Signer, error) {
+		if conf.Label != "ca" {
+			return nil, fmt.Errorf("no key labeled %q", conf.Label)
+		}
+		opened = conf
+		return &hsmSigner{key: key}, nil
+	})
+
+	Ω(ca.Signers()).Should(ContainElement("hsm"))
+	Ω(func() { ca.RegisterSigner("hsm", nil) }).Should(Panic())
+
+	// Unknown signers and keys cannot be opened
+	_, err = ca.OpenSigner("vault", ca.SignerConfig{Label: "ca"})
+	Ω(err).Should(MatchError(ContainSubstring("unknown signer")))
+
+	_, err = ca.OpenSigner("hsm", ca.SignerConfig{Label: "missing"})
+	Ω(err).Should(HaveOccurred())
+
+	conf := ca.SignerConfig{Module: "/usr/lib/softhsm/libsofthsm2.so", Slot: 1, Label: "ca", PIN: "1234"}
+	signer, err := ca.OpenSigner("hsm", conf)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(opened).Should(Equal(conf))
+
+	// The opened signer can be used to initialize and load the CA
+	dir := t.TempDir()
+	Ω(ca.New(dir).InitSigner(ca.Subject{Organization: "Testing"}, signer, false)).Should(Succeed())
+	Ω(ca.New(dir).LoadSigner(signer)).Should(Succeed())
+	Ω(signer.(*hsmSigner).signs).Should(Equal(1))
+}
+
+func TestWildcard(t *testing.T) {
+	RegisterTestingT(t)
+
+	authority := ca.New("")
+	authority.KeyBits = testKeyBits
+	Ω(authority.Init(ca.Subject{Organization: "Testing"}, false)).Should(Succeed())
+
+	cert, err := authority.Issue(ca.Subject{DNSNames: []string{"*.example.test"}})
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(cert.Cert.Subject.CommonName).Should(Equal("*.example.test"))
+	Ω(cert.Cert.VerifyHostname("api.example.test")).Should(Succeed())
+	Ω(cert.Cert.VerifyHostname("example.test")).ShouldNot(Succeed())
+	Ω(cert.Cert.VerifyHostname("v1.api.example.test")).ShouldNot(Succeed())
+
+	for _, name := range []string{"", "*", "api*.example.test", "api.*.example.test", "*.*.example.test", "bad name.test", "-api.example.test", "api..test"} {
+		Ω(ca.ValidateDNSName(name)).ShouldNot(Succeed(), "expected %q to be invalid", name)
+		_, err = authority.Issue(ca.Subject{DNSNames: []string{name}})
+		Ω(err).Should(HaveOccurred())
+	}
+
+	for _, name := range []string{"localhost", "*.example.test", "my-service_1.svc.cluster.local", "example.test."} {
+		Ω(ca.ValidateDNSName(name)).Should(Succeed(), "expected %q to be valid", name)
+	}
+}
+
+func TestExpandSANs(t *testing.T) {
+	RegisterTestingT(t)
+
+	dns, ips, err := ca.ExpandSANs("api", "{{name}}.svc.cluster.local", "{{name}}", "*.{{name}}.example.test", "10.0.0.1", "localhost")
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(dns).Should(Equal([]string{"api.svc.cluster.local", "api", "*.api.example.test", "localhost"}))
+	Ω(ips).Should(HaveLen(1))
+	Ω(ips[0].Equal(net.ParseIP("10.0.0.1"))).Should(BeTrue())
+
+	_, _, err = ca.ExpandSANs("api", "{{namespace}}.svc")
+	Ω(err).Should(MatchError(ContainSubstring("unknown placeholder")))
+
+	_, _, err = ca.ExpandSANs("bad name", "{{name}}.svc")
+	Ω(err).Should(HaveOccurred())
+}
+
+func TestIssueBulk(t *testing.T) {
+	RegisterTestingT(t)
+
+	authority := ca.New("")
+	authority.KeyBits = testKeyBits
+	Ω(authority.Init(ca.Subject{Organization: "Testing"}, false)).Should(Succeed())
+
+	sub := ca.Subject{Organization: "Testing", IPAddresses: []net.IP{net.ParseIP("127.0.0.1")}}
+	certs, err := authority.IssueBulk([]string{"alpha", "bravo"}, sub, "{{name}}.svc.cluster.local", "{{name}}")
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(certs).Should(HaveLen(2))
+
+	for i, name := range []string{"alpha", "bravo"} {
+		cert := certs[i].Cert
+		Ω(cert.Subject.CommonName).Should(Equal(name))
+		Ω(cert.Subject.Organization).Should(Equal([]string{"Testing"}))
+		Ω(cert.DNSNames).Should(Equal([]string{name + ".svc.cluster.local", name}))
+		Ω(cert.VerifyHostname("127.0.0.1")).Should(Succeed())
+	}
+
+	// Without templates the name is the only DNS name
+	certs, err = authority.IssueBulk([]string{"charlie"}, ca.Subject{})
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(certs[0].Cert.DNSNames).Should(Equal([]string{"charlie"}))
+
+	// No certificates are issued or recorded if any name is invalid
+	records := len(authority.Records())
+	certs, err = authority.IssueBulk([]string{"delta", "bad name"}, ca.Subject{})
+	Ω(err).Should(MatchError(ContainSubstring("bad name")))
+	Ω(certs).Should(BeEmpty())
+	Ω(authority.Records()).Should(HaveLen(records))
+
+	certs, err = authority.IssueBulk([]string{"delta"}, ca.Subject{DNSNames: []string{"bad name"}})
+	Ω(err).Should(HaveOccurred())
+	Ω(certs).Should(BeEmpty())
+	Ω(authority.Records()).Should(HaveLen(records))
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bbengfort/x/ca"
+	"github.com/urfave/cli"
+)
+
+func inspect(c *cli.Context) (err error) {
+	if c.NArg() == 0 {
+		return cli.NewExitError("specify the certificate file(s) to inspect", 1)
+	}
+
+	for _, path := range c.Args() {
+		var certs []*x509.Certificate
+		if certs, err = readCertificates(path); err != nil {
+			return cli.NewExitError(err, 1)
+		}
+
+		for i, cert := range certs {
+			fmt.Printf("%s (certificate %d of %d)\n", path, i+1, len(certs))
+			describe(os.Stdout, cert)
+			fmt.Println()
+		}
+	}
+	return nil
+}
+
+func verify(c *cli.Context) (err error) {
+	if c.NArg() != 1 {
+		return cli.NewExitError("specify the certificate file to verify", 1)
+	}
+
+	var authority *ca.CA
+	if authority, err = load(c); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	// The first certificate is verified, the rest of the file is its chain
+	var certs []*x509.Certificate
+	if certs, err = readCertificates(c.Args().First()); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	var chains [][]*x509.Certificate
+	if chains, err = authority.Verify(certs[0], certs[1:]...); err != nil {
+		return cli.NewExitError(fmt.Sprintf("%s: verification failed: %s", c.Args().First(), err), 1)
+	}
+
+	fmt.Printf("%s: OK\n", c.Args().First())
+	for _, chain := range chains {
+		names := make([]string, 0, len(chain))
+		for _, cert := range chain {
+			names = append(names, cert.Subject.String())
+		}
+		fmt.Printf("  %s\n", strings.Join(names, " -> "))
+	}
+	return nil
+}
+
+// Reads all of the PEM encoded certificates in the file.
+func readCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	certs, err := ca.ParseCertificates(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %s", path, err)
+	}
+	return certs, nil
+}
+
+// Writes a human readable description of the certificate.
+func describe(w io.Writer, cert *x509.Certificate) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintf(tw, "  Subject:\t%s\n", cert.Subject)
+	fmt.Fprintf(tw, "  Issuer:\t%s\n", cert.Issuer)
+	fmt.Fprintf(tw, "  Serial Number:\t%x\n", cert.SerialNumber)
+	fmt.Fprintf(tw, "  CA:\t%t\n", cert.IsCA)
+	fmt.Fprintf(tw, "  Public Key:\t%s\n", publicKey(cert.PublicKey))
+
+	if len(cert.DNSNames) > 0 {
+		fmt.Fprintf(tw, "  DNS Names:\t%s\n", strings.Join(cert.DNSNames, ", "))
+	}
+
+	if len(cert.IPAddresses) > 0 {
+		ips := make([]string, 0, len(cert.IPAddresses))
+		for _, ip := range cert.IPAddresses {
+			ips = append(ips, ip.String())
+		}
+		fmt.Fprintf(tw, "  IP Addresses:\t%s\n", strings.Join(ips, ", "))
+	}
+
+	if len(cert.EmailAddresses) > 0 {
+		fmt.Fprintf(tw, "  Email Addresses:\t%s\n", strings.Join(cert.EmailAddresses, ", "))
+	}
+
+	if len(cert.URIs) > 0 {
+		uris := make([]string, 0, len(cert.URIs))
+		for _, uri := range cert.URIs {
+			uris = append(uris, uri.String())
+		}
+		fmt.Fprintf(tw, "  URIs:\t%s\n", strings.Join(uris, ", "))
+	}
+
+	fmt.Fprintf(tw, "  Not Before:\t%s\n", cert.NotBefore.Format(time.RFC3339))
+	fmt.Fprintf(tw, "  Not After:\t%s (%s)\n", cert.NotAfter.Format(time.RFC3339), expiration(cert))
+	fmt.Fprintf(tw, "  Key Usage:\t%s\n", strings.Join(keyUsages(cert.KeyUsage), ", "))
+	fmt.Fprintf(tw, "  Ext Key Usage:\t%s\n", strings.Join(extKeyUsages(cert.ExtKeyUsage), ", "))
+
+	if len(cert.SubjectKeyId) > 0 {
+		fmt.Fprintf(tw, "  Subject Key ID:\t%x\n", cert.SubjectKeyId)
+	}
+
+	if len(cert.AuthorityKeyId) > 0 {
+		fmt.Fprintf(tw, "  Authority Key ID:\t%x\n", cert.AuthorityKeyId)
+	}
+}
+
+// Describes when the certificate is or was valid relative to now.
+func expiration(cert *x509.Certificate) string {
+	now := time.Now()
+	switch {
+	case now.Before(cert.NotBefore):
+		return fmt.Sprintf("not yet valid, valid in %s", cert.NotBefore.Sub(now).Round(time.Minute))
+	case now.After(cert.NotAfter):
+		return fmt.Sprintf("expired %s ago", now.Sub(cert.NotAfter).Round(time.Minute))
+	default:
+		return fmt.Sprintf("expires in %s", cert.NotAfter.Sub(now).Round(time.Minute))
+	}
+}
+
+// Describes the algorithm and size of the public key.
+func publicKey(pub interface{}) string {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d bits", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return fmt.Sprintf("ECDSA %s", key.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return fmt.Sprintf("%T", pub)
+	}
+}
+
+// Names of the key usage bits in the order they are defined.
+var keyUsageNames = []string{
+	"digital signature", "content commitment", "key encipherment",
+	"data encipherment", "key agreement", "cert sign", "crl sign",
+	"encipher only", "decipher only",
+}
+
+// Returns the names of the key usages set on the certificate.
+func keyUsages(usage x509.KeyUsage) []string {
+	names := make([]string, 0, len(keyUsageNames))
+	for i, name := range keyUsageNames {
+		if usage&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		names = append(names, "none")
+	}
+	return names
+}
+
+// Names of the common extended key usages.
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:             "any",
+	x509.ExtKeyUsageServerAuth:      "server auth",
+	x509.ExtKeyUsageClientAuth:      "client auth",
+	x509.ExtKeyUsageCodeSigning:     "code signing",
+	x509.ExtKeyUsageEmailProtection: "email protection",
+	x509.ExtKeyUsageTimeStamping:    "time stamping",
+	x509.ExtKeyUsageOCSPSigning:     "ocsp signing",
+}
+
+// Returns the names of the extended key usages of the certificate.
+func extKeyUsages(usages []x509.ExtKeyUsage) []string {
+	names := make([]string, 0, len(usages))
+	for _, usage := range usages {
+		if name, ok := extKeyUsageNames[usage]; ok {
+			names = append(names, name)
+		} else {
+			names = append(names, fmt.Sprintf("unknown (%d)", usage))
+		}
+	}
+
+	if len(names) == 0 {
+		names = append(names, "none")
+	}
+	return names
+}
@@ -0,0 +1,835 @@
+package main
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bbengfort/x/ca"
+	"github.com/bbengfort/x/clock/clocks"
+	"github.com/urfave/cli"
+)
+
+func main() {
+	app := cli.NewApp()
+
+	app.Name = "ca"
+	app.Version = "1.0"
+	app.Usage = "a pseudo certificate authority for testing purposes"
+	app.Flags = []cli.Flag{}
+	app.Commands = []cli.Command{
+		{
+			Name:   "init",
+			Usage:  "create CA certs and keys if they do not exist",
+			Action: initCA,
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:   "c, certs",
+					Usage:  "local directory where certificates and keys are stored",
+					Value:  "fixtures/certs",
+					EnvVar: "CA_CERT_DIRECTORY",
+				},
+				cli.BoolFlag{
+					Name:  "f, force",
+					Usage: "overwrite keys even if they already exist",
+				},
+				requestFlag,
+			}, append(subjectFlags, validityFlags...)...),
+		},
+		{
+			Name:   "issue",
+			Usage:  "issue a certificate signed by the CA",
+			Action: issue,
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:   "c, certs",
+					Usage:  "local directory where certificates and keys are stored",
+					Value:  "fixtures/certs",
+					EnvVar: "CA_CERT_DIRECTORY",
+				},
+				cli.StringFlag{
+					Name:  "n, name",
+					Usage: "common name of the certificate (default first dns name)",
+				},
+				cli.StringSliceFlag{
+					Name:  "d, dns",
+					Usage: "dns name to add as a subject alternative name (repeatable)",
+				},
+				cli.StringSliceFlag{
+					Name:  "i, ip",
+					Usage: "ip address to add as a subject alternative name (repeatable)",
+				},
+				cli.StringFlag{
+					Name:  "f, output-format",
+					Usage: "write the certificate as pem (crt and key files), combined (one pem file), or p12",
+					Value: "pem",
+				},
+				cli.StringFlag{
+					Name:   "password",
+					Usage:  "password to protect the private key of a p12 bundle",
+					EnvVar: "CA_P12_PASSWORD",
+				},
+				requestFlag,
+				cli.StringFlag{
+					Name:  "signing",
+					Usage: "cfssl config.json or step-ca ca.json to take the validity period from",
+				},
+				cli.StringFlag{
+					Name:  "profile",
+					Usage: "cfssl signing profile or step-ca provisioner in the signing config (default the default profile)",
+				},
+			}, append(subjectFlags, validityFlags...)...),
+		},
+		{
+			Name:      "bulk",
+			Usage:     "issue a certificate for each name, expanding subject alternative name templates",
+			ArgsUsage: "name [name ...]",
+			Action:    bulk,
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:   "c, certs",
+					Usage:  "local directory where certificates and keys are stored",
+					Value:  "fixtures/certs",
+					EnvVar: "CA_CERT_DIRECTORY",
+				},
+				cli.StringSliceFlag{
+					Name:  "d, dns",
+					Usage: "dns name template, e.g. {{name}}.svc.cluster.local (repeatable, default {{name}})",
+				},
+				cli.StringSliceFlag{
+					Name:  "i, ip",
+					Usage: "ip address to add to every certificate as a subject alternative name (repeatable)",
+				},
+				cli.StringFlag{
+					Name:  "f, output-format",
+					Usage: "write the certificates as pem (crt and key files), combined (one pem file), or p12",
+					Value: "pem",
+				},
+				cli.StringFlag{
+					Name:   "password",
+					Usage:  "password to protect the private keys of p12 bundles",
+					EnvVar: "CA_P12_PASSWORD",
+				},
+			}, append(subjectFlags, validityFlags...)...),
+		},
+		{
+			Name:   "intermediate",
+			Usage:  "create an intermediate CA signed by the CA",
+			Action: intermediate,
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:   "c, certs",
+					Usage:  "local directory where certificates and keys are stored",
+					Value:  "fixtures/certs",
+					EnvVar: "CA_CERT_DIRECTORY",
+				},
+				cli.StringFlag{
+					Name:  "n, name",
+					Usage: "common name of the intermediate CA",
+					Value: "intermediate",
+				},
+				cli.StringFlag{
+					Name:  "d, dir",
+					Usage: "directory to store the intermediate CA in (default name in the certs directory)",
+				},
+			}, append(subjectFlags, validityFlags...)...),
+		},
+		{
+			Name:   "sign",
+			Usage:  "issue a certificate signed by the CA for a certificate signing request",
+			Action: sign,
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:   "c, certs",
+					Usage:  "local directory where certificates and keys are stored",
+					Value:  "fixtures/certs",
+					EnvVar: "CA_CERT_DIRECTORY",
+				},
+				cli.StringFlag{
+					Name:  "r, csr",
+					Usage: "path to the PEM encoded certificate signing request",
+				},
+				cli.StringFlag{
+					Name:  "n, name",
+					Usage: "name of the certificate file (default common name of the request)",
+				},
+			}, validityFlags...),
+		},
+		{
+			Name:   "rotate",
+			Usage:  "replace the CA with a new root cross-signed with the previous root",
+			Action: rotate,
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:   "c, certs",
+					Usage:  "local directory where certificates and keys are stored",
+					Value:  "fixtures/certs",
+					EnvVar: "CA_CERT_DIRECTORY",
+				},
+				cli.StringFlag{
+					Name:  "new-label",
+					Usage: "label of the key held by the signer for the new root, created if it does not exist",
+				},
+			}, append(subjectFlags, validityFlags...)...),
+		},
+		{
+			Name:      "inspect",
+			Usage:     "print the subject, SANs, validity, and key usage of certificates",
+			ArgsUsage: "file.crt [file.crt ...]",
+			Action:    inspect,
+		},
+		{
+			Name:      "verify",
+			Usage:     "verify a certificate and its chain against the CA",
+			ArgsUsage: "file.crt",
+			Action:    verify,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:   "c, certs",
+					Usage:  "local directory where certificates and keys are stored",
+					Value:  "fixtures/certs",
+					EnvVar: "CA_CERT_DIRECTORY",
+				},
+			},
+		},
+		{
+			Name:      "revoke",
+			Usage:     "revoke a certificate issued by the CA by its serial number",
+			ArgsUsage: "serial",
+			Action:    revoke,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:   "c, certs",
+					Usage:  "local directory where certificates and keys are stored",
+					Value:  "fixtures/certs",
+					EnvVar: "CA_CERT_DIRECTORY",
+				},
+			},
+		},
+		{
+			Name:   "crl",
+			Usage:  "write a certificate revocation list signed by the CA",
+			Action: crl,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:   "c, certs",
+					Usage:  "local directory where certificates and keys are stored",
+					Value:  "fixtures/certs",
+					EnvVar: "CA_CERT_DIRECTORY",
+				},
+				cli.StringFlag{
+					Name:  "o, out",
+					Usage: "path to write the crl to (default ca.crl in the certs directory, - for stdout)",
+				},
+				cli.DurationFlag{
+					Name:  "v, validity",
+					Usage: "duration until the next crl update",
+					Value: ca.DefaultCRLValidity,
+				},
+			},
+		},
+	}
+
+	// Commands that sign with the CA key can use an external signer instead
+	for i, cmd := range app.Commands {
+		switch cmd.Name {
+		case "init", "issue", "bulk", "intermediate", "sign", "rotate", "verify", "revoke", "crl":
+			app.Commands[i].Flags = append(cmd.Flags, signerFlags...)
+		}
+	}
+
+	app.After = closeSigner
+	app.Run(os.Args)
+}
+
+// Flag that specifies a JSON fixture from another tool to create a certificate from.
+var requestFlag = cli.StringFlag{
+	Name:  "request",
+	Usage: "cfssl csr.json or step certificate template describing the certificate",
+}
+
+// Flags that describe the subject of a certificate.
+var subjectFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "o, organization",
+		Usage: "name of organization to issue certificates for",
+	},
+	cli.StringFlag{
+		Name:  "C, country",
+		Usage: "country of the organization",
+	},
+	cli.StringFlag{
+		Name:  "p, province",
+		Usage: "province or state of the organization",
+	},
+	cli.StringFlag{
+		Name:  "l, locality",
+		Usage: "locality or city of the organization",
+	},
+	cli.StringFlag{
+		Name:  "a, address",
+		Usage: "streed address of the organization",
+	},
+	cli.StringFlag{
+		Name:  "P, postcode",
+		Usage: "postal code of the organization",
+	},
+}
+
+// Flags that specify an external signer that holds the CA key, e.g. a PKCS#11
+// token, rather than the ca.key file (see ca.RegisterSigner).
+var signerFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:   "signer",
+		Usage:  "name of the external signer that holds the ca key, pkcs11 or keychain (requires the build tag of the same name)",
+		EnvVar: "CA_SIGNER",
+	},
+	cli.StringFlag{
+		Name:   "module",
+		Usage:  "path of the pkcs11 module or name of the keychain of the signer",
+		EnvVar: "CA_SIGNER_MODULE",
+	},
+	cli.IntFlag{
+		Name:   "slot",
+		Usage:  "slot number of the token that holds the ca key",
+		EnvVar: "CA_SIGNER_SLOT",
+	},
+	cli.StringFlag{
+		Name:   "label",
+		Usage:  "label of the ca key in the token or keychain (created by init if it does not exist)",
+		EnvVar: "CA_SIGNER_LABEL",
+	},
+	cli.StringFlag{
+		Name:   "pin",
+		Usage:  "pin or password to unlock the token or keychain",
+		EnvVar: "CA_SIGNER_PIN",
+	},
+}
+
+// Flags that describe the validity period of a certificate.
+var validityFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "not-before",
+		Usage: "start of the validity period as a date or RFC3339 timestamp (default now)",
+	},
+	cli.StringFlag{
+		Name:  "e, expires",
+		Usage: "duration of the validity period (e.g. 90d or 8760h) or date it expires",
+	},
+	cli.DurationFlag{
+		Name:  "b, backdate",
+		Usage: "move the start of the validity period into the past to tolerate clock skew",
+	},
+}
+
+func initCA(c *cli.Context) (err error) {
+	var req *ca.Request
+	if req, err = request(c); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	authority := ca.New(c.String("certs"))
+	authority.KeyBits = req.KeyBits
+	if authority.CAValidity, err = validity(c, ca.Validity{Duration: req.Expiry}); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	// A new CA creates its key in the token or keychain if it does not exist
+	var signer crypto.Signer
+	if signer, err = openSigner(c, c.String("label"), true); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	if signer != nil {
+		err = authority.InitSigner(req.Subject, signer, c.Bool("force"))
+	} else {
+		err = authority.Init(req.Subject, c.Bool("force"))
+	}
+
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	return nil
+}
+
+func issue(c *cli.Context) (err error) {
+	var req *ca.Request
+	if req, err = request(c); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	sub := req.Subject
+	if name := c.String("name"); name != "" {
+		sub.CommonName = name
+	}
+
+	// DNS names may be templates of the common name, e.g. {{name}}.svc.cluster.local
+	for _, dns := range c.StringSlice("dns") {
+		if strings.Contains(dns, ca.NamePlaceholder) {
+			if sub.CommonName == "" {
+				return cli.NewExitError(fmt.Sprintf("specify the name to expand %q with", dns), 1)
+			}
+			dns = strings.ReplaceAll(dns, ca.NamePlaceholder, sub.CommonName)
+		}
+		sub.DNSNames = append(sub.DNSNames, dns)
+	}
+
+	if sub.IPAddresses, err = ipAddresses(c, sub.IPAddresses); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	if sub.Organization == "" && sub.CommonName == "" && len(sub.DNSNames) == 0 {
+		return cli.NewExitError("specify the name of the organization or a dns name", 1)
+	}
+
+	var format string
+	if format, err = outputFormat(c); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	// Load the CA key pairs
+	var authority *ca.CA
+	if authority, err = load(c); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	authority.KeyBits = req.KeyBits
+
+	var profile ca.Validity
+	if path := c.String("signing"); path != "" {
+		if profile, err = ca.LoadProfile(path, c.String("profile")); err != nil {
+			return cli.NewExitError(err, 1)
+		}
+	}
+
+	if authority.Validity, err = validity(c, profile); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	var cert *ca.Certificate
+	if cert, err = authority.Issue(sub); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	// Write out the certificate to disk, named by the organization if given
+	name := strings.TrimSpace(sub.Organization)
+	if name == "" {
+		name = cert.Cert.Subject.CommonName
+	}
+	name = fileName(name)
+	if err = write(c, cert, name, format); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	fmt.Printf("issued certificate %s with serial number %x\n", name, cert.Cert.SerialNumber)
+	return nil
+}
+
+func bulk(c *cli.Context) (err error) {
+	if c.NArg() == 0 {
+		return cli.NewExitError("specify the names of the certificates to issue", 1)
+	}
+
+	sub := subject(c)
+	if sub.IPAddresses, err = ipAddresses(c, nil); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	var format string
+	if format, err = outputFormat(c); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	var authority *ca.CA
+	if authority, err = load(c); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	if authority.Validity, err = validity(c, ca.Validity{}); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	// Issue all certificates before writing any; IssueBulk validates every name and
+	// template first and does not record any certificates in the index if it fails
+	var certs []*ca.Certificate
+	if certs, err = authority.IssueBulk(c.Args(), sub, c.StringSlice("dns")...); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	for _, cert := range certs {
+		name := fileName(cert.Cert.Subject.CommonName)
+		if err = write(c, cert, name, format); err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		fmt.Printf("issued certificate %s for %s with serial number %x\n", name, strings.Join(cert.Cert.DNSNames, ", "), cert.Cert.SerialNumber)
+	}
+	return nil
+}
+
+func intermediate(c *cli.Context) (err error) {
+	var authority *ca.CA
+	if authority, err = load(c); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	if authority.CAValidity, err = validity(c, ca.Validity{}); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	name := strings.TrimSpace(c.String("name"))
+	dir := c.String("dir")
+	if dir == "" {
+		dir = filepath.Join(c.String("certs"), strings.ToLower(strings.ReplaceAll(name, " ", "_")))
+	}
+
+	sub := subject(c)
+	sub.CommonName = name
+	if _, err = authority.Intermediate(dir, sub); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	fmt.Printf("created intermediate ca in %s, issue certificates with ca issue -c %s\n", dir, dir)
+	return nil
+}
+
+func sign(c *cli.Context) (err error) {
+	if c.String("csr") == "" {
+		return cli.NewExitError("specify the path to the certificate signing request", 1)
+	}
+
+	var data []byte
+	if data, err = ioutil.ReadFile(c.String("csr")); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	var csr *x509.CertificateRequest
+	if csr, err = ca.ParseCSR(data); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	name := c.String("name")
+	if name == "" {
+		if name = csr.Subject.CommonName; name == "" {
+			return cli.NewExitError("request has no common name, specify the name of the certificate", 1)
+		}
+	}
+	name = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), " ", "_"))
+
+	var authority *ca.CA
+	if authority, err = load(c); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	if authority.Validity, err = validity(c, ca.Validity{}); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	var cert *ca.Certificate
+	if cert, err = authority.Sign(csr); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	if err = cert.Write(c.String("certs"), name); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	fmt.Printf("signed certificate %s with serial number %x\n", name, cert.Cert.SerialNumber)
+	return nil
+}
+
+func rotate(c *cli.Context) (err error) {
+	var authority *ca.CA
+	if authority, err = load(c); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	if authority.CAValidity, err = validity(c, ca.Validity{}); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	// The new root key is held by the signer with the new label, otherwise it is
+	// generated and written to ca.key
+	label := c.String("new-label")
+	switch {
+	case label == "":
+		err = authority.Rotate(subject(c))
+	case c.String("signer") == "":
+		return cli.NewExitError("specify the signer that holds the key with the new label", 1)
+	default:
+		var signer crypto.Signer
+		if signer, err = openSigner(c, label, true); err != nil {
+			return cli.NewExitError(err, 1)
+		}
+		err = authority.RotateSigner(subject(c), signer)
+	}
+
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	fmt.Printf("rotated ca: previous root is %s, cross-signed roots are %s and %s\n", ca.PreviousCertFile, ca.CrossCertFile, ca.PreviousCrossCertFile)
+	return nil
+}
+
+func revoke(c *cli.Context) (err error) {
+	if c.NArg() != 1 {
+		return cli.NewExitError("specify the serial number of the certificate to revoke", 1)
+	}
+
+	var authority *ca.CA
+	if authority, err = load(c); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	if err = authority.Revoke(c.Args().First()); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	return nil
+}
+
+func crl(c *cli.Context) (err error) {
+	var authority *ca.CA
+	if authority, err = load(c); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	var data []byte
+	if data, err = authority.CRL(c.Duration("validity")); err != nil {
+		return cli.NewExitError(err, 1)
+	}
+
+	out := c.String("out")
+	switch out {
+	case "-":
+		_, err = os.Stdout.Write(data)
+	case "":
+		err = ioutil.WriteFile(filepath.Join(c.String("certs"), ca.CRLFile), data, 0644)
+	default:
+		err = ioutil.WriteFile(out, data, 0644)
+	}
+
+	if err != nil {
+		return cli.NewExitError(err, 1)
+	}
+	return nil
+}
+
+// The external signers opened by the command, closed after the command runs.
+var opened []crypto.Signer
+
+// Opens the key with the label in the external signer specified on the command
+// line, creating it if it does not exist and create is true. Returns nil if the
+// CA key should be read from the ca.key file instead.
+func openSigner(c *cli.Context, label string, create bool) (_ crypto.Signer, err error) {
+	name := c.String("signer")
+	if name == "" {
+		return nil, nil
+	}
+
+	conf := ca.SignerConfig{
+		Module: c.String("module"),
+		Slot:   c.Int("slot"),
+		Label:  label,
+		PIN:    c.String("pin"),
+		Create: create,
+	}
+
+	var signer crypto.Signer
+	if signer, err = ca.OpenSigner(name, conf); err != nil {
+		return nil, err
+	}
+
+	opened = append(opened, signer)
+	return signer, nil
+}
+
+// Closes the external signers that hold a session with a token or keychain.
+func closeSigner(c *cli.Context) (err error) {
+	for _, signer := range opened {
+		if closer, ok := signer.(io.Closer); ok {
+			if cerr := closer.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+	}
+	return err
+}
+
+// Loads the CA from the certs directory, signing with the external signer if
+// one is specified on the command line rather than with the ca.key file.
+func load(c *cli.Context) (authority *ca.CA, err error) {
+	var signer crypto.Signer
+	if signer, err = openSigner(c, c.String("label"), false); err != nil {
+		return nil, err
+	}
+
+	authority = ca.New(c.String("certs"))
+	if signer != nil {
+		err = authority.LoadSigner(signer)
+	} else {
+		err = authority.Load()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return authority, nil
+}
+
+// Creates the subject of a certificate from the command line flags.
+func subject(c *cli.Context) ca.Subject {
+	return ca.Subject{
+		Organization:  c.String("organization"),
+		Country:       c.String("country"),
+		Province:      c.String("province"),
+		Locality:      c.String("locality"),
+		StreetAddress: c.String("address"),
+		PostalCode:    c.String("postcode"),
+	}
+}
+
+// Loads the request specified on the command line, if any, overriding its
+// subject with the subject flags that are specified.
+func request(c *cli.Context) (req *ca.Request, err error) {
+	req = &ca.Request{}
+	if path := c.String("request"); path != "" {
+		if req, err = ca.LoadRequest(path); err != nil {
+			return nil, err
+		}
+	}
+
+	flags := subject(c)
+	for _, field := range []struct {
+		dst *string
+		src string
+	}{
+		{&req.Subject.Organization, flags.Organization},
+		{&req.Subject.Country, flags.Country},
+		{&req.Subject.Province, flags.Province},
+		{&req.Subject.Locality, flags.Locality},
+		{&req.Subject.StreetAddress, flags.StreetAddress},
+		{&req.Subject.PostalCode, flags.PostalCode},
+	} {
+		if field.src != "" {
+			*field.dst = field.src
+		}
+	}
+	return req, nil
+}
+
+// Parses the ip addresses specified on the command line, appending them to addrs.
+func ipAddresses(c *cli.Context, addrs []net.IP) ([]net.IP, error) {
+	for _, addr := range c.StringSlice("ip") {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return nil, fmt.Errorf("could not parse ip address %q", addr)
+		}
+		addrs = append(addrs, ip)
+	}
+	return addrs, nil
+}
+
+// Returns the output format specified on the command line, checking that a
+// password is specified for p12 bundles.
+func outputFormat(c *cli.Context) (string, error) {
+	format := strings.ToLower(c.String("output-format"))
+	switch format {
+	case "pem", "combined":
+	case "p12", "pkcs12":
+		if c.String("password") == "" {
+			return "", errors.New("specify a password to protect the p12 bundle")
+		}
+	default:
+		return "", fmt.Errorf("unknown output format %q, use pem, combined, or p12", format)
+	}
+	return format, nil
+}
+
+// Writes the certificate to the certs directory in the output format.
+func write(c *cli.Context, cert *ca.Certificate, name, format string) (err error) {
+	switch format {
+	case "pem":
+		return cert.Write(c.String("certs"), name)
+	case "combined":
+		// The combined file contains the private key so is only readable by the user
+		return ioutil.WriteFile(filepath.Join(c.String("certs"), name+".pem"), cert.CombinedPEM(), 0600)
+	default:
+		var data []byte
+		if data, err = cert.PKCS12(c.String("password")); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(c.String("certs"), name+".p12"), data, 0600)
+	}
+}
+
+// Returns the name of the files of a certificate, replacing the wildcard of a
+// wildcard dns name so that the file name does not need to be quoted.
+func fileName(name string) string {
+	name = strings.ReplaceAll(strings.TrimSpace(name), "*", "wildcard")
+	return strings.ToLower(strings.ReplaceAll(name, " ", "_"))
+}
+
+// Creates the validity period of a certificate from the command line flags,
+// which take precedence over the defaults, e.g. from a signing profile.
+func validity(c *cli.Context, defaults ca.Validity) (v ca.Validity, err error) {
+	v = defaults
+	if backdate := c.Duration("backdate"); backdate != 0 {
+		v.Backdate = backdate
+	}
+
+	if notBefore := c.String("not-before"); notBefore != "" {
+		if v.NotBefore, err = parseTime(notBefore); err != nil {
+			return v, fmt.Errorf("could not parse --not-before: %s", err)
+		}
+	}
+
+	if expires := c.String("expires"); expires != "" {
+		v.Duration, v.NotAfter = 0, time.Time{}
+		if v.Duration, err = parseDuration(expires); err != nil {
+			if v.NotAfter, err = parseTime(expires); err != nil {
+				return v, fmt.Errorf("could not parse --expires %q as a duration or date", expires)
+			}
+		}
+	}
+	return v, nil
+}
+
+// Layouts that dates and timestamps can be specified in on the command line.
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Parses a date or timestamp in the local timezone unless a zone is specified.
+func parseTime(value string) (t time.Time, err error) {
+	for _, layout := range timeLayouts {
+		if t, err = time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return t, fmt.Errorf("%q is not a date or RFC3339 timestamp", value)
+}
+
+// Parses a duration that may include weeks and days, e.g. 1w2d12h, since
+// time.ParseDuration has no units longer than an hour. A validity period is
+// a fixed length, so days are 24 hours rather than calendar days.
+func parseDuration(value string) (d time.Duration, err error) {
+	var offset clocks.Offset
+	if offset, err = clocks.ParseOffset(value); err != nil {
+		return 0, err
+	}
+
+	if d = time.Duration(offset.Days)*24*time.Hour + offset.Duration; d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return d, nil
+}
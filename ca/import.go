@@ -0,0 +1,254 @@
+package ca
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+)
+
+// Request describes a certificate to create as defined by the JSON fixtures of
+// other tools: a cfssl csr.json or a step certificate template. Requests allow
+// existing fixture definitions to be reused without translation.
+type Request struct {
+	Subject Subject       // subject and subject alternative names of the certificate
+	KeyBits int           // size of the RSA key, zero if unspecified
+	Expiry  time.Duration // validity of the CA certificate (cfssl ca.expiry), zero if unspecified
+}
+
+// LoadRequest reads a cfssl csr.json or step certificate template from the path.
+func LoadRequest(path string) (_ *Request, err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(path); err != nil {
+		return nil, err
+	}
+	return ParseRequest(data)
+}
+
+// ParseRequest parses a cfssl csr.json or a step certificate template. In cfssl
+// requests the first entry of names is used for the subject and the hosts are
+// added as DNS or IP subject alternative names; step templates use the subject
+// and sans fields. Only RSA keys are supported by the CA.
+func ParseRequest(data []byte) (_ *Request, err error) {
+	var doc struct {
+		CN    string `json:"CN"`
+		Names []struct {
+			C, ST, L, O, OU string
+			Street          string `json:"street"`
+			PostalCode      string `json:"postalCode"`
+		} `json:"names"`
+		Hosts []string `json:"hosts"`
+		Key   *struct {
+			Algo string `json:"algo"`
+			Size int    `json:"size"`
+		} `json:"key"`
+		CA *struct {
+			Expiry string `json:"expiry"`
+		} `json:"ca"`
+
+		// step certificate templates
+		Subject *struct {
+			CommonName    string     `json:"commonName"`
+			Organization  stringList `json:"organization"`
+			Country       stringList `json:"country"`
+			Province      stringList `json:"province"`
+			Locality      stringList `json:"locality"`
+			StreetAddress stringList `json:"streetAddress"`
+			PostalCode    stringList `json:"postalCode"`
+		} `json:"subject"`
+		SANs []san `json:"sans"`
+	}
+
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("could not parse request: %s", err)
+	}
+
+	req := &Request{}
+	if doc.Subject != nil {
+		req.Subject = Subject{
+			CommonName:    doc.Subject.CommonName,
+			Organization:  doc.Subject.Organization.first(),
+			Country:       doc.Subject.Country.first(),
+			Province:      doc.Subject.Province.first(),
+			Locality:      doc.Subject.Locality.first(),
+			StreetAddress: doc.Subject.StreetAddress.first(),
+			PostalCode:    doc.Subject.PostalCode.first(),
+		}
+	} else {
+		req.Subject.CommonName = doc.CN
+		if len(doc.Names) > 0 {
+			name := doc.Names[0]
+			req.Subject.Organization = name.O
+			req.Subject.Country = name.C
+			req.Subject.Province = name.ST
+			req.Subject.Locality = name.L
+			req.Subject.StreetAddress = name.Street
+			req.Subject.PostalCode = name.PostalCode
+		}
+	}
+
+	for _, host := range doc.Hosts {
+		doc.SANs = append(doc.SANs, san{Value: host})
+	}
+
+	for _, alt := range doc.SANs {
+		switch strings.ToLower(alt.Type) {
+		case "", "dns", "ip":
+			if ip := net.ParseIP(alt.Value); ip != nil {
+				req.Subject.IPAddresses = append(req.Subject.IPAddresses, ip)
+			} else if strings.ToLower(alt.Type) == "ip" {
+				return nil, fmt.Errorf("could not parse ip address %q", alt.Value)
+			} else {
+				req.Subject.DNSNames = append(req.Subject.DNSNames, alt.Value)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported subject alternative name type %q", alt.Type)
+		}
+	}
+
+	if doc.Key != nil {
+		if algo := strings.ToLower(doc.Key.Algo); algo != "" && algo != "rsa" {
+			return nil, fmt.Errorf("unsupported key algorithm %q, only rsa keys can be generated", doc.Key.Algo)
+		}
+		req.KeyBits = doc.Key.Size
+	}
+
+	if doc.CA != nil && doc.CA.Expiry != "" {
+		if req.Expiry, err = time.ParseDuration(doc.CA.Expiry); err != nil {
+			return nil, fmt.Errorf("could not parse ca expiry: %s", err)
+		}
+	}
+	return req, nil
+}
+
+// LoadProfile reads a cfssl config.json or step-ca ca.json from the path and
+// returns the validity of the named signing profile.
+func LoadProfile(path, profile string) (_ Validity, err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(path); err != nil {
+		return Validity{}, err
+	}
+	return ParseProfile(data, profile)
+}
+
+// ParseProfile returns the validity of certificates signed with the named
+// profile of a cfssl config.json (signing.profiles, or signing.default if the
+// profile is empty) or a step-ca ca.json, where the profile is the name of a
+// provisioner whose defaultTLSCertDuration claim overrides the authority's.
+func ParseProfile(data []byte, profile string) (_ Validity, err error) {
+	type claims struct {
+		DefaultTLSCertDuration string `json:"defaultTLSCertDuration"`
+	}
+
+	var doc struct {
+		Signing *struct {
+			Default  *struct{ Expiry string } `json:"default"`
+			Profiles map[string]struct {
+				Expiry string `json:"expiry"`
+			} `json:"profiles"`
+		} `json:"signing"`
+		Authority *struct {
+			Claims       *claims `json:"claims"`
+			Provisioners []struct {
+				Name   string  `json:"name"`
+				Claims *claims `json:"claims"`
+			} `json:"provisioners"`
+		} `json:"authority"`
+	}
+
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return Validity{}, fmt.Errorf("could not parse signing config: %s", err)
+	}
+
+	var expiry string
+	switch {
+	case doc.Signing != nil:
+		if profile == "" {
+			if doc.Signing.Default != nil {
+				expiry = doc.Signing.Default.Expiry
+			}
+			break
+		}
+
+		p, ok := doc.Signing.Profiles[profile]
+		if !ok {
+			return Validity{}, fmt.Errorf("no signing profile named %q", profile)
+		}
+		if expiry = p.Expiry; expiry == "" && doc.Signing.Default != nil {
+			expiry = doc.Signing.Default.Expiry
+		}
+
+	case doc.Authority != nil:
+		if doc.Authority.Claims != nil {
+			expiry = doc.Authority.Claims.DefaultTLSCertDuration
+		}
+
+		if profile != "" {
+			found := false
+			for _, prov := range doc.Authority.Provisioners {
+				if prov.Name != profile {
+					continue
+				}
+				if found = true; prov.Claims != nil && prov.Claims.DefaultTLSCertDuration != "" {
+					expiry = prov.Claims.DefaultTLSCertDuration
+				}
+				break
+			}
+
+			if !found {
+				return Validity{}, fmt.Errorf("no provisioner named %q", profile)
+			}
+		}
+
+	default:
+		return Validity{}, errors.New("no cfssl signing or step-ca authority section found")
+	}
+
+	var v Validity
+	if expiry != "" {
+		if v.Duration, err = time.ParseDuration(expiry); err != nil {
+			return Validity{}, fmt.Errorf("could not parse expiry: %s", err)
+		}
+	}
+	return v, nil
+}
+
+// A JSON field that may be specified as a string or as an array of strings, as
+// the subject fields of step certificate templates are.
+type stringList []string
+
+func (s *stringList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*s = []string{one}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(s))
+}
+
+// Returns the first value or an empty string if there are no values.
+func (s stringList) first() string {
+	if len(s) == 0 {
+		return ""
+	}
+	return s[0]
+}
+
+// A subject alternative name in a step certificate template, specified either
+// as a string or as an object with a type and value.
+type san struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+func (s *san) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &s.Value); err == nil {
+		return nil
+	}
+
+	type plain san
+	return json.Unmarshal(data, (*plain)(s))
+}
@@ -0,0 +1,109 @@
+package ca_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bbengfort/x/ca"
+	. "github.com/onsi/gomega"
+)
+
+func TestParseRequestCFSSL(t *testing.T) {
+	RegisterTestingT(t)
+
+	req, err := ca.ParseRequest([]byte(`{
+		"CN": "example.com",
+		"hosts": ["example.com", "www.example.com", "127.0.0.1"],
+		"key": {"algo": "rsa", "size": 2048},
+		"names": [{"C": "US", "ST": "Maryland", "L": "Baltimore", "O": "Example", "OU": "Testing"}],
+		"ca": {"expiry": "87600h"}
+	}`))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(req.KeyBits).Should(Equal(2048))
+	Ω(req.Expiry).Should(Equal(87600 * time.Hour))
+	Ω(req.Subject.CommonName).Should(Equal("example.com"))
+	Ω(req.Subject.Organization).Should(Equal("Example"))
+	Ω(req.Subject.Country).Should(Equal("US"))
+	Ω(req.Subject.Province).Should(Equal("Maryland"))
+	Ω(req.Subject.Locality).Should(Equal("Baltimore"))
+	Ω(req.Subject.DNSNames).Should(Equal([]string{"example.com", "www.example.com"}))
+	Ω(req.Subject.IPAddresses).Should(HaveLen(1))
+	Ω(req.Subject.IPAddresses[0].Equal(net.ParseIP("127.0.0.1"))).Should(BeTrue())
+
+	// Only RSA keys can be generated by the CA
+	_, err = ca.ParseRequest([]byte(`{"CN": "example.com", "key": {"algo": "ecdsa", "size": 256}}`))
+	Ω(err).Should(MatchError(ContainSubstring("unsupported key algorithm")))
+}
+
+func TestParseRequestStep(t *testing.T) {
+	RegisterTestingT(t)
+
+	req, err := ca.ParseRequest([]byte(`{
+		"subject": {"commonName": "localhost", "organization": ["Example"], "country": "US"},
+		"sans": ["localhost", {"type": "ip", "value": "::1"}]
+	}`))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(req.KeyBits).Should(BeZero())
+	Ω(req.Subject.CommonName).Should(Equal("localhost"))
+	Ω(req.Subject.Organization).Should(Equal("Example"))
+	Ω(req.Subject.Country).Should(Equal("US"))
+	Ω(req.Subject.DNSNames).Should(Equal([]string{"localhost"}))
+	Ω(req.Subject.IPAddresses).Should(HaveLen(1))
+
+	_, err = ca.ParseRequest([]byte(`{"subject": {"commonName": "localhost"}, "sans": [{"type": "ip", "value": "localhost"}]}`))
+	Ω(err).Should(HaveOccurred())
+
+	_, err = ca.ParseRequest([]byte(`{"sans": [{"type": "email", "value": "admin@example.com"}]}`))
+	Ω(err).Should(HaveOccurred())
+}
+
+func TestParseProfile(t *testing.T) {
+	RegisterTestingT(t)
+
+	cfssl := []byte(`{"signing": {
+		"default": {"expiry": "168h"},
+		"profiles": {
+			"server": {"expiry": "8760h", "usages": ["server auth"]},
+			"client": {"usages": ["client auth"]}
+		}
+	}}`)
+
+	v, err := ca.ParseProfile(cfssl, "")
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(v.Duration).Should(Equal(168 * time.Hour))
+
+	v, err = ca.ParseProfile(cfssl, "server")
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(v.Duration).Should(Equal(8760 * time.Hour))
+
+	// A profile without an expiry uses the default expiry
+	v, err = ca.ParseProfile(cfssl, "client")
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(v.Duration).Should(Equal(168 * time.Hour))
+
+	_, err = ca.ParseProfile(cfssl, "peer")
+	Ω(err).Should(HaveOccurred())
+
+	step := []byte(`{"authority": {
+		"claims": {"defaultTLSCertDuration": "24h"},
+		"provisioners": [
+			{"name": "admin", "type": "JWK"},
+			{"name": "acme", "type": "ACME", "claims": {"defaultTLSCertDuration": "2160h"}}
+		]
+	}}`)
+
+	v, err = ca.ParseProfile(step, "admin")
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(v.Duration).Should(Equal(24 * time.Hour))
+
+	v, err = ca.ParseProfile(step, "acme")
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(v.Duration).Should(Equal(2160 * time.Hour))
+
+	_, err = ca.ParseProfile(step, "missing")
+	Ω(err).Should(HaveOccurred())
+
+	_, err = ca.ParseProfile([]byte(`{}`), "")
+	Ω(err).Should(HaveOccurred())
+}
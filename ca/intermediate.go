@@ -0,0 +1,128 @@
+package ca
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"time"
+)
+
+// ChainFile is the name of the file in the directory of an intermediate CA
+// that contains the intermediate certificate followed by the certificates of
+// any intermediate CAs above it (but not the root).
+const ChainFile = "ca.chain.crt"
+
+// Intermediate creates an intermediate CA for the subject that is signed by
+// this CA. Certificates issued by the intermediate include the chain of
+// intermediate certificates so that clients that trust the root can verify
+// them. If dir is not empty the intermediate CA is written to that directory
+// so that it can be loaded with New(dir).Load(); otherwise it is kept in
+// memory. The intermediate is recorded in the serial index of this CA.
+func (c *CA) Intermediate(dir string, subject Subject) (_ *CA, err error) {
+	if c.Cert == nil || c.signer() == nil {
+		return nil, errors.New("ca has not been initialized or loaded")
+	}
+
+	var serial *big.Int
+	if serial, err = c.serial(); err != nil {
+		return nil, err
+	}
+
+	// The intermediate cannot outlive the signing CA
+	var notBefore, notAfter time.Time
+	if notBefore, notAfter, err = c.CAValidity.Period(DefaultIntermediateValidity); err != nil {
+		return nil, err
+	}
+
+	if c.Cert.NotAfter.Before(notAfter) {
+		notAfter = c.Cert.NotAfter
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject.Name(),
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+	}
+
+	inter := &CA{Dir: dir, KeyBits: c.KeyBits, Validity: c.Validity, serials: make(map[string]*Record)}
+
+	var priv *rsa.PrivateKey
+	if priv, err = rsa.GenerateKey(rand.Reader, inter.keyBits()); err != nil {
+		return nil, fmt.Errorf("could not generate intermediate key: %s", err)
+	}
+
+	var signed []byte
+	if signed, err = x509.CreateCertificate(rand.Reader, template, c.Cert, &priv.PublicKey, c.signer()); err != nil {
+		return nil, fmt.Errorf("create intermediate failed: %s", err)
+	}
+
+	var cert *x509.Certificate
+	if cert, err = x509.ParseCertificate(signed); err != nil {
+		return nil, err
+	}
+
+	inter.Cert, inter.Key = cert, priv
+	inter.Chain = append([]*x509.Certificate{cert}, c.Chain...)
+
+	if dir != "" {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+
+		if err = writePair(inter.path(CertFile), inter.path(KeyFile), inter.Cert, inter.Key); err != nil {
+			return nil, err
+		}
+
+		if err = writeChain(inter.path(ChainFile), inter.Chain); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = c.record(cert); err != nil {
+		return nil, err
+	}
+
+	if err = inter.record(cert); err != nil {
+		return nil, err
+	}
+	return inter, nil
+}
+
+// Loads the intermediate chain from the CA directory if the CA is an
+// intermediate CA.
+func (c *CA) loadChain() (err error) {
+	c.Chain = nil
+
+	var data []byte
+	if data, err = ioutil.ReadFile(c.path(ChainFile)); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if c.Chain, err = ParseCertificates(data); err != nil {
+		return fmt.Errorf("could not parse %s: %s", ChainFile, err)
+	}
+	return nil
+}
+
+// Writes PEM encoded certificates to the specified path.
+func writeChain(path string, chain []*x509.Certificate) error {
+	data := make([]byte, 0)
+	for _, cert := range chain {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: certificateBlock, Bytes: cert.Raw})...)
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
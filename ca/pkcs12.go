@@ -0,0 +1,28 @@
+package ca
+
+import (
+	"errors"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// PKCS12Iterations is the number of iterations used to derive the encryption
+// and integrity keys of PKCS#12 bundles from the password.
+const PKCS12Iterations = 2048
+
+// PKCS12 returns the private key, certificate, and chain as a PKCS#12 (.p12)
+// bundle protected by the password, e.g. for Java keystores or browsers. The
+// key and certificates are encrypted with PBES2 using PBKDF2-HMAC-SHA256 and
+// AES-256-CBC and the bundle is integrity protected by a SHA-256 MAC, the
+// defaults of OpenSSL 3 that are also supported by Java keystores and browsers.
+func (c *Certificate) PKCS12(password string) (_ []byte, err error) {
+	if c.Key == nil {
+		return nil, errors.New("certificate does not have a private key")
+	}
+
+	if password == "" {
+		return nil, errors.New("a password is required to protect the private key")
+	}
+
+	return pkcs12.Modern.WithIterations(PKCS12Iterations).Encode(c.Key, c.Cert, c.Chain, password)
+}
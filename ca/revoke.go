@@ -0,0 +1,90 @@
+package ca
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// CRLFile is the name of the certificate revocation list in the certs directory.
+const CRLFile = "ca.crl"
+
+// DefaultCRLValidity is how long a generated CRL is valid if not specified.
+const DefaultCRLValidity = 7 * 24 * time.Hour
+
+// PEM block type of certificate revocation lists.
+const crlBlock = "X509 CRL"
+
+// Revoke the certificate with the specified hex encoded serial number. The
+// revocation is recorded in the serial index, which is saved to disk if the CA
+// has a directory; use CRL to generate a revocation list that includes it.
+func (c *CA) Revoke(serial string) (err error) {
+	key := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(serial, "0x"), "0X"))
+	record, ok := c.serials[key]
+	if !ok {
+		return fmt.Errorf("no certificate with serial number %s has been issued", serial)
+	}
+
+	if record.IsCA {
+		return errors.New("cannot revoke the ca certificate")
+	}
+
+	if record.Revoked() {
+		return fmt.Errorf("certificate %s was already revoked at %s", key, record.RevokedAt.Format(time.RFC3339))
+	}
+
+	now := time.Now()
+	record.RevokedAt = &now
+	if c.Dir != "" {
+		return c.saveSerials()
+	}
+	return nil
+}
+
+// CRL returns a PEM encoded certificate revocation list signed by the CA that
+// contains every revoked certificate in the serial index. The CRL is valid for
+// the specified duration, or DefaultCRLValidity if zero.
+func (c *CA) CRL(validity time.Duration) (_ []byte, err error) {
+	if c.Cert == nil || c.signer() == nil {
+		return nil, errors.New("ca has not been initialized or loaded")
+	}
+
+	if validity <= 0 {
+		validity = DefaultCRLValidity
+	}
+
+	now := time.Now()
+	template := &x509.RevocationList{
+		Number:     big.NewInt(now.UnixNano()),
+		ThisUpdate: now,
+		NextUpdate: now.Add(validity),
+	}
+
+	for _, record := range c.Records() {
+		if !record.Revoked() {
+			continue
+		}
+
+		serial, ok := new(big.Int).SetString(record.Serial, 16)
+		if !ok {
+			return nil, fmt.Errorf("could not parse serial number %q", record.Serial)
+		}
+
+		template.RevokedCertificates = append(template.RevokedCertificates, pkix.RevokedCertificate{
+			SerialNumber:   serial,
+			RevocationTime: *record.RevokedAt,
+		})
+	}
+
+	var crl []byte
+	if crl, err = x509.CreateRevocationList(rand.Reader, template, c.Cert, c.signer()); err != nil {
+		return nil, fmt.Errorf("could not create crl: %s", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: crlBlock, Bytes: crl}), nil
+}
@@ -0,0 +1,239 @@
+package ca
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"time"
+)
+
+// File names of the previous CA certificate and key and of the cross-signed
+// CA certificates in the certs directory after a rotation.
+const (
+	PreviousCertFile      = "ca.prev.crt"
+	PreviousKeyFile       = "ca.prev.key"
+	CrossCertFile         = "ca.cross.crt"
+	PreviousCrossCertFile = "ca.prev.cross.crt"
+)
+
+// Rotate replaces the CA certificate and key with a new root for the subject.
+// If the subject is empty, the subject of the current CA certificate is used
+// with its serial number attribute set to the rotation time; the new root must
+// have a distinct name so that tools like openssl do not mistake the
+// cross-signed certificates for self-signed certificates. The current root
+// becomes the previous root and the two roots are cross-signed so that the
+// trust of clients is not broken during rotation:
+//
+//   - Cross is the new root signed by the previous root, so that clients that
+//     only trust the previous root can verify newly issued certificates.
+//   - PreviousCross is the previous root signed by the new root, so that
+//     clients that only trust the new root can verify certificates issued
+//     before the rotation.
+//
+// Certificates issued after the rotation include Cross in their chain. Only
+// one previous root is kept; rotating again discards the oldest root. The key
+// of the new root is generated in process and written to ca.key, even if the
+// current root is held by an external signer; use RotateSigner to keep the new
+// root key off disk.
+func (c *CA) Rotate(subject Subject) error {
+	return c.rotate(subject, nil)
+}
+
+// RotateSigner rotates the CA like Rotate, but the new root is self-signed by
+// the signer, e.g. a new key in a PKCS#11 token or an OS keychain, so that the
+// new root key is never written to disk. The signer must hold a different key
+// than the current root. After the rotation the CA must be loaded with
+// LoadSigner and the new signer.
+func (c *CA) RotateSigner(subject Subject, signer crypto.Signer) error {
+	if signer == nil {
+		return errors.New("no signer to rotate the ca with")
+	}
+
+	if c.Cert != nil && samePublicKey(c.Cert.PublicKey, signer.Public()) {
+		return errors.New("the rotated ca must have a different key than the current ca")
+	}
+	return c.rotate(subject, signer)
+}
+
+// Rotates the CA to a new root self-signed by the signer, or by a new private
+// key generated in process if the signer is nil.
+func (c *CA) rotate(subject Subject, signer crypto.Signer) (err error) {
+	if c.Cert == nil || c.signer() == nil {
+		return errors.New("ca has not been initialized or loaded")
+	}
+
+	if len(c.Chain) > 0 {
+		return errors.New("cannot rotate an intermediate ca")
+	}
+
+	name := subject.Name()
+	if name.String() == "" {
+		name = c.Cert.Subject
+		name.Names = nil
+		name.SerialNumber = time.Now().UTC().Format("20060102150405")
+	}
+
+	if name.String() == c.Cert.Subject.String() {
+		return errors.New("the rotated ca must have a different subject than the current ca")
+	}
+
+	prev := &CA{Cert: c.Cert, Key: c.Key, Signer: c.Signer, serials: c.serials}
+	next := &CA{Signer: signer, serials: c.serials, KeyBits: c.KeyBits}
+	if signer != nil {
+		next.Cert, err = c.selfSign(name, signer)
+	} else {
+		next.Cert, next.Key, err = c.root(name)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var cross, prevCross *x509.Certificate
+	if cross, err = prev.crossSign(next.Cert); err != nil {
+		return err
+	}
+
+	if prevCross, err = next.crossSign(prev.Cert); err != nil {
+		return err
+	}
+
+	c.Cert, c.Key, c.Signer = next.Cert, next.Key, next.Signer
+	c.Previous, c.PreviousKey = prev.Cert, prev.Key
+	c.Cross, c.PreviousCross = cross, prevCross
+
+	if c.Dir != "" {
+		// Keys held by external signers are not on disk, so only the certificate
+		// is written and any stale key file from an earlier root is removed
+		if err = writeRoot(c.path(PreviousCertFile), c.path(PreviousKeyFile), c.Previous, c.PreviousKey); err != nil {
+			return err
+		}
+
+		if err = writeCert(c.path(CrossCertFile), c.Cross); err != nil {
+			return err
+		}
+
+		if err = writeCert(c.path(PreviousCrossCertFile), c.PreviousCross); err != nil {
+			return err
+		}
+
+		if err = writeRoot(c.path(CertFile), c.path(KeyFile), c.Cert, c.Key); err != nil {
+			return err
+		}
+	}
+
+	// Record the new root and the cross-signed certificates in the serial index
+	for _, cert := range []*x509.Certificate{c.Cert, c.Cross, c.PreviousCross} {
+		if err = c.record(cert); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Signs the subject and public key of another CA certificate with the CA key,
+// creating a cross-signed CA certificate that chains to this CA. The subject
+// key id is kept so that certificates issued by the other CA chain to it.
+func (c *CA) crossSign(other *x509.Certificate) (_ *x509.Certificate, err error) {
+	var serial *big.Int
+	if serial, err = c.serial(); err != nil {
+		return nil, err
+	}
+
+	// The cross-signed certificate cannot outlive the signing CA
+	notAfter := other.NotAfter
+	if c.Cert.NotAfter.Before(notAfter) {
+		notAfter = c.Cert.NotAfter
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               other.Subject,
+		NotBefore:             time.Now(),
+		NotAfter:              notAfter,
+		SubjectKeyId:          other.SubjectKeyId,
+		IsCA:                  true,
+		ExtKeyUsage:           other.ExtKeyUsage,
+		KeyUsage:              other.KeyUsage,
+		BasicConstraintsValid: true,
+	}
+
+	var signed []byte
+	if signed, err = x509.CreateCertificate(rand.Reader, template, c.Cert, other.PublicKey, c.signer()); err != nil {
+		return nil, fmt.Errorf("could not cross-sign %s: %s", other.Subject, err)
+	}
+	return x509.ParseCertificate(signed)
+}
+
+// Loads the previous root and cross-signed certificates from the CA directory
+// if the CA has been rotated.
+func (c *CA) loadRotation() (err error) {
+	c.Previous, c.PreviousKey, c.Cross, c.PreviousCross = nil, nil, nil, nil
+	if _, err = os.Stat(c.path(PreviousCertFile)); os.IsNotExist(err) {
+		return nil
+	}
+
+	var (
+		prev          *x509.Certificate
+		prevKey       *rsa.PrivateKey
+		cross, pcross *x509.Certificate
+	)
+
+	if prev, err = readCert(c.path(PreviousCertFile)); err != nil {
+		return err
+	}
+
+	// The previous root key is not on disk if it was held by an external signer
+	if _, err = os.Stat(c.path(PreviousKeyFile)); err == nil {
+		if prevKey, err = readKey(c.path(PreviousKeyFile)); err != nil {
+			return err
+		}
+	}
+
+	if cross, err = readCert(c.path(CrossCertFile)); err != nil {
+		return err
+	}
+
+	if pcross, err = readCert(c.path(PreviousCrossCertFile)); err != nil {
+		return err
+	}
+
+	c.Previous, c.PreviousKey, c.Cross, c.PreviousCross = prev, prevKey, cross, pcross
+	return nil
+}
+
+// Removes the previous root and cross-signed certificates from the CA directory.
+func (c *CA) removeRotation() error {
+	for _, name := range []string{PreviousCertFile, PreviousKeyFile, CrossCertFile, PreviousCrossCertFile} {
+		if err := os.Remove(c.path(name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Writes a root certificate and its private key, or only the certificate if the
+// key is held by an external signer, removing any key file left by the
+// previous holder of the path.
+func writeRoot(certPath, keyPath string, cert *x509.Certificate, key *rsa.PrivateKey) (err error) {
+	if key != nil {
+		return writePair(certPath, keyPath, cert, key)
+	}
+
+	if err = os.Remove(keyPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return writeCert(certPath, cert)
+}
+
+// Writes a PEM encoded certificate to the specified path.
+func writeCert(path string, cert *x509.Certificate) error {
+	return ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: certificateBlock, Bytes: cert.Raw}), 0644)
+}
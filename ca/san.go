@@ -0,0 +1,147 @@
+package ca
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// NamePlaceholder is replaced by the name of each certificate when subject
+// alternative name templates are expanded, e.g. {{name}}.svc.cluster.local.
+const NamePlaceholder = "{{name}}"
+
+//===========================================================================
+// Subject Alternative Names
+//===========================================================================
+
+// ValidateDNSName checks that the name can be used as a DNS subject alternative
+// name. Wildcard names are allowed if the wildcard is the entire left-most
+// label and is followed by at least one other label, e.g. *.example.test, since
+// clients do not match partial or nested wildcards such as api*.example.test.
+func ValidateDNSName(name string) error {
+	if name == "" {
+		return errors.New("dns name is empty")
+	}
+
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+	for i, label := range labels {
+		if label == "" {
+			return fmt.Errorf("dns name %q has an empty label", name)
+		}
+
+		if strings.Contains(label, "*") {
+			if label != "*" || i != 0 {
+				return fmt.Errorf("dns name %q has a wildcard that is not the entire left-most label", name)
+			}
+
+			if len(labels) < 2 {
+				return fmt.Errorf("wildcard dns name %q must have a domain", name)
+			}
+			continue
+		}
+
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return fmt.Errorf("dns name %q has invalid character %q", name, c)
+			}
+		}
+
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("dns name %q has a label that begins or ends with a hyphen", name)
+		}
+	}
+	return nil
+}
+
+// Checks the subject alternative names of a certificate that the CA issues or
+// signs: DNS names must be valid and IP addresses must be specified.
+func validateSANs(dnsNames []string, ipAddresses []net.IP) (err error) {
+	for _, name := range dnsNames {
+		if err = ValidateDNSName(name); err != nil {
+			return err
+		}
+	}
+
+	for _, ip := range ipAddresses {
+		if ip == nil || ip.IsUnspecified() {
+			return fmt.Errorf("ip address %q is not specified", ip)
+		}
+	}
+	return nil
+}
+
+// ExpandSANs replaces the {{name}} placeholder in each of the templates with
+// the name and returns the resulting subject alternative names; templates that
+// expand to an IP address are returned as IP addresses, the others are
+// validated as DNS names. Templates without a placeholder are used as is.
+func ExpandSANs(name string, templates ...string) (dnsNames []string, ipAddresses []net.IP, err error) {
+	for _, template := range templates {
+		san := strings.TrimSpace(strings.ReplaceAll(template, NamePlaceholder, name))
+		if strings.Contains(san, "{{") || strings.Contains(san, "}}") {
+			return nil, nil, fmt.Errorf("unknown placeholder in template %q, only %s is supported", template, NamePlaceholder)
+		}
+
+		if ip := net.ParseIP(san); ip != nil {
+			ipAddresses = append(ipAddresses, ip)
+			continue
+		}
+
+		if err = ValidateDNSName(san); err != nil {
+			return nil, nil, fmt.Errorf("invalid template %q: %s", template, err)
+		}
+		dnsNames = append(dnsNames, san)
+	}
+	return dnsNames, ipAddresses, nil
+}
+
+// IssueBulk issues a certificate for each of the names, e.g. the services in a
+// cluster, by expanding the subject alternative name templates with the name
+// (see ExpandSANs). Each certificate has the name as its common name and the
+// rest of its subject from the specified subject; the DNS names and IP
+// addresses of the subject are added to every certificate. If there are no
+// templates, the name itself is the only DNS name. The certificates are
+// returned in the order of the names. Every name is validated before any
+// certificate is issued; if a certificate cannot be issued, the certificates
+// issued so far are removed from the serial index and none are returned.
+func (c *CA) IssueBulk(names []string, subject Subject, templates ...string) (certs []*Certificate, err error) {
+	if len(templates) == 0 {
+		templates = []string{NamePlaceholder}
+	}
+
+	if err = validateSANs(subject.DNSNames, subject.IPAddresses); err != nil {
+		return nil, err
+	}
+
+	// Expand and validate all subjects before issuing any certificates
+	subjects := make([]Subject, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, errors.New("cannot issue a certificate without a name")
+		}
+
+		sub := subject
+		sub.CommonName = name
+		if sub.DNSNames, sub.IPAddresses, err = ExpandSANs(name, templates...); err != nil {
+			return nil, fmt.Errorf("could not issue certificate for %q: %s", name, err)
+		}
+		sub.DNSNames = append(sub.DNSNames, subject.DNSNames...)
+		sub.IPAddresses = append(sub.IPAddresses, subject.IPAddresses...)
+		subjects = append(subjects, sub)
+	}
+
+	certs = make([]*Certificate, 0, len(subjects))
+	for _, sub := range subjects {
+		var cert *Certificate
+		if cert, err = c.Issue(sub); err != nil {
+			err = fmt.Errorf("could not issue certificate for %q: %s", sub.CommonName, err)
+			if ferr := c.forget(certs); ferr != nil {
+				err = fmt.Errorf("%s (could not remove issued certificates from the serial index: %s)", err, ferr)
+			}
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
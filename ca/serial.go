@@ -0,0 +1,161 @@
+package ca
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+)
+
+// SerialFile is the name of the index of issued serial numbers in the certs
+// directory.
+const SerialFile = "serial.json"
+
+// SerialBits is the size of randomly generated serial numbers. RFC 5280 limits
+// serial numbers to 20 octets, 128 bits leaves room for the sign bit.
+const SerialBits = 128
+
+// Maximum number of attempts to generate a serial number that has not been
+// issued before; a collision with 128 random bits should never happen.
+const maxSerialAttempts = 8
+
+// Record describes a certificate issued by the CA in the serial index.
+type Record struct {
+	Serial    string     `json:"serial"`            // hex encoded serial number
+	Subject   string     `json:"subject"`           // distinguished name of the subject
+	IsCA      bool       `json:"is_ca"`             // if the record is for the CA certificate
+	Issued    time.Time  `json:"issued"`            // timestamp the certificate was issued
+	NotAfter  time.Time  `json:"not_after"`         // expiration of the certificate
+	RevokedAt *time.Time `json:"revoked,omitempty"` // timestamp the certificate was revoked
+}
+
+// Expired returns true if the certificate described by the record has expired.
+func (r *Record) Expired() bool {
+	return time.Now().After(r.NotAfter)
+}
+
+// Revoked returns true if the certificate described by the record was revoked.
+func (r *Record) Revoked() bool {
+	return r.RevokedAt != nil
+}
+
+// Records returns the certificates issued by the CA ordered by issue time.
+func (c *CA) Records() []*Record {
+	records := make([]*Record, 0, len(c.serials))
+	for _, record := range c.serials {
+		records = append(records, record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Issued.Before(records[j].Issued)
+	})
+	return records
+}
+
+// Generates a random serial number that has not been issued by the CA.
+func (c *CA) serial() (*big.Int, error) {
+	limit := new(big.Int).Lsh(big.NewInt(1), SerialBits)
+	for i := 0; i < maxSerialAttempts; i++ {
+		serial, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return nil, fmt.Errorf("could not generate serial number: %s", err)
+		}
+
+		// Zero is not a valid serial number
+		if serial.Sign() == 0 {
+			continue
+		}
+
+		if _, ok := c.serials[serialKey(serial)]; !ok {
+			return serial, nil
+		}
+	}
+	return nil, errors.New("could not generate a unique serial number")
+}
+
+// Adds the certificate to the serial index, saving the index to disk if the
+// CA has a directory.
+func (c *CA) record(cert *x509.Certificate) error {
+	if c.serials == nil {
+		c.serials = make(map[string]*Record)
+	}
+
+	key := serialKey(cert.SerialNumber)
+	if _, ok := c.serials[key]; ok {
+		return fmt.Errorf("serial number %s has already been issued", key)
+	}
+
+	c.serials[key] = &Record{
+		Serial:   key,
+		Subject:  cert.Subject.String(),
+		IsCA:     cert.IsCA,
+		Issued:   time.Now(),
+		NotAfter: cert.NotAfter,
+	}
+
+	if c.Dir != "" {
+		return c.saveSerials()
+	}
+	return nil
+}
+
+// Removes the certificates from the serial index, saving the index to disk if
+// the CA has a directory, e.g. when a bulk issue fails part way through.
+func (c *CA) forget(certs []*Certificate) error {
+	if len(certs) == 0 {
+		return nil
+	}
+
+	for _, cert := range certs {
+		delete(c.serials, serialKey(cert.Cert.SerialNumber))
+	}
+
+	if c.Dir != "" {
+		return c.saveSerials()
+	}
+	return nil
+}
+
+// Loads the serial index from the CA directory. A missing index is not an
+// error so that directories created before the index existed can be loaded.
+func (c *CA) loadSerials() (err error) {
+	c.serials = make(map[string]*Record)
+
+	var data []byte
+	if data, err = ioutil.ReadFile(c.path(SerialFile)); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var records []*Record
+	if err = json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("could not parse %s: %s", SerialFile, err)
+	}
+
+	for _, record := range records {
+		c.serials[record.Serial] = record
+	}
+	return nil
+}
+
+// Saves the serial index to the CA directory.
+func (c *CA) saveSerials() (err error) {
+	var data []byte
+	if data, err = json.MarshalIndent(c.Records(), "", "  "); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path(SerialFile), data, 0644)
+}
+
+// Returns the key of the serial number in the index.
+func serialKey(serial *big.Int) string {
+	return fmt.Sprintf("%x", serial)
+}
@@ -0,0 +1,187 @@
+package ca
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+//===========================================================================
+// External Signers
+//===========================================================================
+
+// InitSigner creates a new self-signed CA certificate for the subject whose
+// private key is held by the signer, e.g. a key in a PKCS#11 token or an OS
+// keychain, so that the CA key never has to be written to disk on shared dev
+// machines. Any crypto.Signer can be used, e.g. the pkcs11 signer registered by
+// the pkcs11 build tag; RSA, ECDSA, and Ed25519 keys are supported. If the CA has
+// a directory only the certificate is written to it (any existing ca.key is
+// removed); an error is returned if the certificate already exists unless force
+// is true.
+func (c *CA) InitSigner(subject Subject, signer crypto.Signer, force bool) (err error) {
+	if signer == nil {
+		return errors.New("no signer to initialize the ca with")
+	}
+
+	if c.Dir != "" && !force {
+		if _, err = os.Stat(c.path(CertFile)); err == nil {
+			return errors.New("certificate file already exists")
+		}
+	}
+
+	// A new CA starts a new serial index
+	c.serials = make(map[string]*Record)
+
+	var cert *x509.Certificate
+	if cert, err = c.selfSign(subject.Name(), signer); err != nil {
+		return err
+	}
+
+	c.Cert, c.Key, c.Signer = cert, nil, signer
+	c.Previous, c.PreviousKey, c.Cross, c.PreviousCross, c.Chain = nil, nil, nil, nil, nil
+	if c.Dir != "" {
+		if err = c.removeRotation(); err != nil {
+			return err
+		}
+
+		for _, name := range []string{ChainFile, KeyFile} {
+			if err = os.Remove(c.path(name)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+
+		if err = writeCert(c.path(CertFile), c.Cert); err != nil {
+			return err
+		}
+	}
+	return c.record(cert)
+}
+
+// LoadSigner loads the CA certificate, chain, and serial index from the CA
+// directory and uses the signer to sign certificates rather than reading the
+// private key from ca.key. An error is returned if the public key of the signer
+// does not match the CA certificate.
+func (c *CA) LoadSigner(signer crypto.Signer) (err error) {
+	if c.Dir == "" {
+		return errors.New("no directory to load the ca from")
+	}
+
+	if signer == nil {
+		return errors.New("no signer to load the ca with")
+	}
+
+	if c.Cert, err = readCert(c.path(CertFile)); err != nil {
+		return err
+	}
+
+	if !samePublicKey(c.Cert.PublicKey, signer.Public()) {
+		return fmt.Errorf("signer public key does not match the certificate in %s", c.path(CertFile))
+	}
+	c.Key, c.Signer = nil, signer
+
+	if err = c.loadRotation(); err != nil {
+		return err
+	}
+
+	if err = c.loadChain(); err != nil {
+		return err
+	}
+	return c.loadSerials()
+}
+
+//===========================================================================
+// Signer Registry
+//===========================================================================
+
+// SignerConfig locates the key of an external signer, e.g. the PKCS#11 module
+// and the slot of the token holding the key, and the label of the key in it.
+// How each field is interpreted is up to the SignerOpener.
+type SignerConfig struct {
+	Module string // path of the PKCS#11 module or name of the keychain
+	Slot   int    // slot number of the token in the module
+	Label  string // label of the key in the token or keychain
+	PIN    string // pin or password to unlock the token or keychain
+	Create bool   // generate a new key with the label if it does not exist
+}
+
+// SignerOpener opens the key described by the config as a crypto.Signer. If the
+// signer holds a session with a token or keychain, it should implement
+// io.Closer so that the session can be closed when it is no longer needed.
+type SignerOpener func(conf SignerConfig) (crypto.Signer, error)
+
+var (
+	signersMu sync.RWMutex
+	signers   = make(map[string]SignerOpener)
+)
+
+// RegisterSigner makes an external signer available by name to OpenSigner, e.g.
+// so that the ca command can sign with keys in a PKCS#11 token or keychain
+// without this package linking their libraries. It is usually called from the
+// init function of the package that implements the signer; building with the
+// pkcs11 or keychain tags registers a "pkcs11" or "keychain" signer.
+// RegisterSigner panics if the opener is nil or a signer is already registered
+// with the name.
+func RegisterSigner(name string, open SignerOpener) {
+	signersMu.Lock()
+	defer signersMu.Unlock()
+
+	if open == nil {
+		panic("ca: register signer opener is nil")
+	}
+
+	if _, dup := signers[name]; dup {
+		panic("ca: register signer called twice for " + name)
+	}
+	signers[name] = open
+}
+
+// OpenSigner opens the key described by the config with the signer registered
+// with the name, which can then be used with InitSigner, LoadSigner, or
+// RotateSigner.
+func OpenSigner(name string, conf SignerConfig) (crypto.Signer, error) {
+	signersMu.RLock()
+	open, ok := signers[name]
+	signersMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown signer %q (registered signers: %v)", name, Signers())
+	}
+	return open(conf)
+}
+
+// Signers returns the names of the registered signers in sorted order.
+func Signers() []string {
+	signersMu.RLock()
+	defer signersMu.RUnlock()
+
+	names := make([]string, 0, len(signers))
+	for name := range signers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Returns the signer of the CA, preferring the external signer to the private
+// key, or nil if the CA has no key.
+func (c *CA) signer() crypto.Signer {
+	if c.Signer != nil {
+		return c.Signer
+	}
+
+	if c.Key != nil {
+		return c.Key
+	}
+	return nil
+}
+
+// Returns true if the public keys are the same; all of the standard library
+// public key types implement Equal.
+func samePublicKey(a, b crypto.PublicKey) bool {
+	key, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && key.Equal(b)
+}
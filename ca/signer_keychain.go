@@ -0,0 +1,84 @@
+//go:build keychain
+
+package ca
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/zalando/go-keyring"
+)
+
+func init() {
+	RegisterSigner("keychain", openKeychain)
+}
+
+// The keychain service that CA keys are stored under if the config has no module.
+const keychainService = "ca"
+
+// Reads the PEM encoded PKCS#8 private key stored with the label in the OS
+// keychain (the macOS Keychain, the Secret Service on Linux, or the Windows
+// Credential Manager) under the service named by the module of the config. If
+// the config creates keys and the keychain has no key with the label, an ECDSA
+// P-256 key is generated and stored in the keychain. The keychain is unlocked by
+// the OS, so the pin of the config is not used. Unlike a PKCS#11 token, the key
+// is loaded into memory to sign, but it is never written to the certs directory.
+func openKeychain(conf SignerConfig) (_ crypto.Signer, err error) {
+	if conf.Label == "" {
+		return nil, errors.New("the keychain signer requires a key label")
+	}
+
+	service := conf.Module
+	if service == "" {
+		service = keychainService
+	}
+
+	var secret string
+	if secret, err = keyring.Get(service, conf.Label); err != nil {
+		if errors.Is(err, keyring.ErrNotFound) && conf.Create {
+			return createKeychain(service, conf.Label)
+		}
+		return nil, fmt.Errorf("could not read key %q from keychain %s: %s", conf.Label, service, err)
+	}
+
+	block, _ := pem.Decode([]byte(secret))
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("key %q in keychain %s is not a pem encoded private key", conf.Label, service)
+	}
+
+	var key interface{}
+	if key, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		return nil, fmt.Errorf("could not parse key %q in keychain %s: %s", conf.Label, service, err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("key %q in keychain %s cannot sign", conf.Label, service)
+	}
+	return signer, nil
+}
+
+// Generates an ECDSA P-256 key and stores it with the label in the keychain.
+func createKeychain(service, label string) (_ crypto.Signer, err error) {
+	var key *ecdsa.PrivateKey
+	if key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		return nil, fmt.Errorf("could not generate key %q: %s", label, err)
+	}
+
+	var der []byte
+	if der, err = x509.MarshalPKCS8PrivateKey(key); err != nil {
+		return nil, err
+	}
+
+	secret := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err = keyring.Set(service, label, string(secret)); err != nil {
+		return nil, fmt.Errorf("could not store key %q in keychain %s: %s", label, service, err)
+	}
+	return key, nil
+}
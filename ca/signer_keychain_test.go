@@ -0,0 +1,46 @@
+//go:build keychain
+
+package ca
+
+import (
+	"crypto/x509"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/zalando/go-keyring"
+)
+
+func TestKeychainSigner(t *testing.T) {
+	RegisterTestingT(t)
+	keyring.MockInit()
+
+	// The key must exist unless the config creates it
+	_, err := OpenSigner("keychain", SignerConfig{Label: "testing"})
+	Ω(err).Should(MatchError(ContainSubstring("could not read key")))
+	_, err = OpenSigner("keychain", SignerConfig{Create: true})
+	Ω(err).Should(MatchError(ContainSubstring("requires a key label")))
+
+	created, err := OpenSigner("keychain", SignerConfig{Label: "testing", Create: true})
+	Ω(err).ShouldNot(HaveOccurred())
+
+	// The stored key is opened rather than replaced
+	for _, create := range []bool{false, true} {
+		signer, err := OpenSigner("keychain", SignerConfig{Label: "testing", Create: create})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(samePublicKey(signer.Public(), created.Public())).Should(BeTrue())
+	}
+
+	// Keys are stored per service
+	other, err := OpenSigner("keychain", SignerConfig{Module: "other", Label: "testing", Create: true})
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(samePublicKey(other.Public(), created.Public())).Should(BeFalse())
+
+	Ω(keyring.Set(keychainService, "garbage", "not a key")).Should(Succeed())
+	_, err = OpenSigner("keychain", SignerConfig{Label: "garbage"})
+	Ω(err).Should(MatchError(ContainSubstring("not a pem encoded private key")))
+
+	authority := New(t.TempDir())
+	Ω(authority.InitSigner(Subject{Organization: "Testing"}, created, false)).Should(Succeed())
+	Ω(authority.Cert.PublicKeyAlgorithm).Should(Equal(x509.ECDSA))
+	Ω(authority.RotateSigner(Subject{Organization: "Rotated"}, other)).Should(Succeed())
+}
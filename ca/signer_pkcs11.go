@@ -0,0 +1,319 @@
+//go:build pkcs11
+
+package ca
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/miekg/pkcs11"
+)
+
+func init() {
+	RegisterSigner("pkcs11", openPKCS11)
+}
+
+// A private key in a PKCS#11 token that signs in a session with the token until
+// it is closed. Sessions are not safe for concurrent use, so signing is
+// serialized by the mutex.
+type pkcs11Signer struct {
+	sync.Mutex
+	ctx     *pkcs11.Ctx
+	session pkcs11.SessionHandle
+	key     pkcs11.ObjectHandle
+	public  crypto.PublicKey
+}
+
+// Opens a session with the token in the slot of the PKCS#11 module, logs into
+// it with the pin, and finds the key pair with the label. If the config creates
+// keys and the token has no key with the label, an ECDSA P-256 key pair is
+// generated in the token.
+func openPKCS11(conf SignerConfig) (_ crypto.Signer, err error) {
+	if conf.Module == "" || conf.Label == "" {
+		return nil, errors.New("the pkcs11 signer requires a module and a key label")
+	}
+
+	ctx := pkcs11.New(conf.Module)
+	if ctx == nil {
+		return nil, fmt.Errorf("could not load pkcs11 module %s", conf.Module)
+	}
+
+	if err = ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return nil, fmt.Errorf("could not initialize pkcs11 module %s: %s", conf.Module, err)
+	}
+
+	s := &pkcs11Signer{ctx: ctx}
+	if s.session, err = ctx.OpenSession(uint(conf.Slot), pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION); err != nil {
+		ctx.Finalize()
+		ctx.Destroy()
+		return nil, fmt.Errorf("could not open a session with slot %d of %s: %s", conf.Slot, conf.Module, err)
+	}
+
+	if conf.PIN != "" {
+		if err = ctx.Login(s.session, pkcs11.CKU_USER, conf.PIN); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("could not log into slot %d of %s: %s", conf.Slot, conf.Module, err)
+		}
+	}
+
+	if conf.Create {
+		if err = s.create(conf.Label); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("could not create key %q in slot %d of %s: %s", conf.Label, conf.Slot, conf.Module, err)
+		}
+	}
+
+	if err = s.find(conf.Label); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("%s in slot %d of %s", err, conf.Slot, conf.Module)
+	}
+	return s, nil
+}
+
+// Public returns the public key of the key pair in the token.
+func (s *pkcs11Signer) Public() crypto.PublicKey {
+	return s.public
+}
+
+// Sign the digest with the private key in the token. RSA keys sign with PKCS#1
+// v1.5 padding and ECDSA keys return ASN.1 encoded signatures like the standard
+// library; RSA-PSS is not supported.
+func (s *pkcs11Signer) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) (_ []byte, err error) {
+	var (
+		mech *pkcs11.Mechanism
+		data []byte
+	)
+
+	switch s.public.(type) {
+	case *rsa.PublicKey:
+		if _, ok := opts.(*rsa.PSSOptions); ok {
+			return nil, errors.New("pkcs11 signer does not support rsa-pss signatures")
+		}
+
+		prefix, ok := digestInfoPrefixes[opts.HashFunc()]
+		if !ok {
+			return nil, fmt.Errorf("pkcs11 signer does not support %s digests", opts.HashFunc())
+		}
+		mech, data = pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS, nil), append(append([]byte(nil), prefix...), digest...)
+	case *ecdsa.PublicKey:
+		mech, data = pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil), digest
+	default:
+		return nil, fmt.Errorf("pkcs11 signer does not support %T keys", s.public)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if err = s.ctx.SignInit(s.session, []*pkcs11.Mechanism{mech}, s.key); err != nil {
+		return nil, err
+	}
+
+	var sig []byte
+	if sig, err = s.ctx.Sign(s.session, data); err != nil {
+		return nil, err
+	}
+
+	// PKCS#11 ECDSA signatures are the concatenation of r and s
+	if _, ok := s.public.(*ecdsa.PublicKey); ok {
+		if len(sig)%2 != 0 {
+			return nil, errors.New("pkcs11 token returned a malformed ecdsa signature")
+		}
+		half := len(sig) / 2
+		return asn1.Marshal(struct{ R, S *big.Int }{new(big.Int).SetBytes(sig[:half]), new(big.Int).SetBytes(sig[half:])})
+	}
+	return sig, nil
+}
+
+// Close logs out of the token and closes the session and the module.
+func (s *pkcs11Signer) Close() error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.ctx.Logout(s.session)
+	err := s.ctx.CloseSession(s.session)
+	s.ctx.Finalize()
+	s.ctx.Destroy()
+	return err
+}
+
+// Finds the private key with the label and reads the public key of the public
+// key object with the same label.
+func (s *pkcs11Signer) find(label string) (err error) {
+	if s.key, err = s.object(pkcs11.CKO_PRIVATE_KEY, label); err != nil {
+		return err
+	}
+
+	var pub pkcs11.ObjectHandle
+	if pub, err = s.object(pkcs11.CKO_PUBLIC_KEY, label); err != nil {
+		return err
+	}
+
+	var attrs []*pkcs11.Attribute
+	if attrs, err = s.ctx.GetAttributeValue(s.session, pub, []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, nil)}); err != nil {
+		return err
+	}
+
+	switch keyType := attrs[0].Value; {
+	case isKeyType(keyType, pkcs11.CKK_RSA):
+		if attrs, err = s.ctx.GetAttributeValue(s.session, pub, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_MODULUS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, nil),
+		}); err != nil {
+			return err
+		}
+
+		s.public = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(attrs[0].Value),
+			E: int(new(big.Int).SetBytes(attrs[1].Value).Int64()),
+		}
+	case isKeyType(keyType, pkcs11.CKK_EC):
+		if attrs, err = s.ctx.GetAttributeValue(s.session, pub, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+		}); err != nil {
+			return err
+		}
+
+		if s.public, err = ecPublicKey(attrs[0].Value, attrs[1].Value); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported pkcs11 key type %x labeled %q", keyType, label)
+	}
+	return nil
+}
+
+// Generates an ECDSA P-256 key pair with the label in the token unless the token
+// already has a private key with the label. The private key is sensitive and
+// cannot be extracted from the token.
+func (s *pkcs11Signer) create(label string) (err error) {
+	var objects []pkcs11.ObjectHandle
+	if objects, err = s.objects(pkcs11.CKO_PRIVATE_KEY, label); err != nil || len(objects) > 0 {
+		return err
+	}
+
+	var params []byte
+	if params, err = asn1.Marshal(oidP256); err != nil {
+		return err
+	}
+
+	public := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+		pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, params),
+	}
+
+	private := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+		pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+	}
+
+	mech := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_EC_KEY_PAIR_GEN, nil)}
+	_, _, err = s.ctx.GenerateKeyPair(s.session, mech, public, private)
+	return err
+}
+
+// Returns the handle of the only object of the class with the label.
+func (s *pkcs11Signer) object(class uint, label string) (_ pkcs11.ObjectHandle, err error) {
+	var objects []pkcs11.ObjectHandle
+	if objects, err = s.objects(class, label); err != nil {
+		return 0, err
+	}
+
+	switch len(objects) {
+	case 0:
+		return 0, fmt.Errorf("no key labeled %q", label)
+	case 1:
+		return objects[0], nil
+	default:
+		return 0, fmt.Errorf("more than one key labeled %q", label)
+	}
+}
+
+// Returns the handles of up to two objects of the class with the label, enough
+// to tell if the label is unique.
+func (s *pkcs11Signer) objects(class uint, label string) (objects []pkcs11.ObjectHandle, err error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+
+	if err = s.ctx.FindObjectsInit(s.session, template); err != nil {
+		return nil, err
+	}
+	defer s.ctx.FindObjectsFinal(s.session)
+
+	if objects, _, err = s.ctx.FindObjects(s.session, 2); err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// Object identifiers of the named curves of ECDSA keys in PKCS#11 tokens.
+var (
+	oidP256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}
+	oidP384 = asn1.ObjectIdentifier{1, 3, 132, 0, 34}
+	oidP521 = asn1.ObjectIdentifier{1, 3, 132, 0, 35}
+)
+
+// Parses the DER encoded curve parameters and point of an ECDSA public key. The
+// point should be wrapped in an octet string but some tokens return it raw.
+func ecPublicKey(params, point []byte) (_ *ecdsa.PublicKey, err error) {
+	var oid asn1.ObjectIdentifier
+	if _, err = asn1.Unmarshal(params, &oid); err != nil {
+		return nil, fmt.Errorf("could not parse ec parameters: %s", err)
+	}
+
+	var curve elliptic.Curve
+	switch {
+	case oid.Equal(oidP256):
+		curve = elliptic.P256()
+	case oid.Equal(oidP384):
+		curve = elliptic.P384()
+	case oid.Equal(oidP521):
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported ec curve %s", oid)
+	}
+
+	var raw []byte
+	if rest, err := asn1.Unmarshal(point, &raw); err != nil || len(rest) > 0 {
+		raw = point
+	}
+
+	x, y := elliptic.Unmarshal(curve, raw)
+	if x == nil {
+		return nil, errors.New("could not parse ec point")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// Returns true if the CKA_KEY_TYPE attribute value is the key type, encoding the
+// key type like the module encodes CK_ULONG values (in native byte order).
+func isKeyType(value []byte, keyType uint) bool {
+	return bytes.Equal(value, pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, keyType).Value)
+}
+
+// The DER encoded DigestInfo prefixes that PKCS#1 v1.5 signatures of each hash
+// begin with, since CKM_RSA_PKCS signs the DigestInfo rather than the digest.
+var digestInfoPrefixes = map[crypto.Hash][]byte{
+	crypto.SHA1:   {0x30, 0x21, 0x30, 0x09, 0x06, 0x05, 0x2b, 0x0e, 0x03, 0x02, 0x1a, 0x05, 0x00, 0x04, 0x14},
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+	crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+}
@@ -0,0 +1,103 @@
+//go:build pkcs11
+
+package ca
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"os"
+	"strconv"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestPKCS11DigestInfo(t *testing.T) {
+	RegisterTestingT(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	// Signing the DigestInfo without a hash is the same as signing the digest
+	for _, hash := range []crypto.Hash{crypto.SHA1, crypto.SHA256, crypto.SHA384, crypto.SHA512} {
+		h := hash.New()
+		h.Write([]byte("hello world"))
+		digest := h.Sum(nil)
+
+		expected, err := rsa.SignPKCS1v15(nil, key, hash, digest)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		actual, err := rsa.SignPKCS1v15(nil, key, 0, append(append([]byte(nil), digestInfoPrefixes[hash]...), digest...))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(actual).Should(Equal(expected), "digest info prefix of %s", hash)
+	}
+}
+
+func TestPKCS11ECPublicKey(t *testing.T) {
+	RegisterTestingT(t)
+
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		var oid asn1.ObjectIdentifier
+		switch curve {
+		case elliptic.P256():
+			oid = oidP256
+		case elliptic.P384():
+			oid = oidP384
+		case elliptic.P521():
+			oid = oidP521
+		}
+
+		params, err := asn1.Marshal(oid)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		raw := elliptic.Marshal(curve, key.X, key.Y)
+		point, err := asn1.Marshal(raw)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		// Points are usually wrapped in an octet string but may be raw
+		for _, p := range [][]byte{point, raw} {
+			pub, err := ecPublicKey(params, p)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(pub.Equal(&key.PublicKey)).Should(BeTrue())
+		}
+	}
+
+	params, _ := asn1.Marshal(asn1.ObjectIdentifier{1, 2, 3})
+	_, err := ecPublicKey(params, nil)
+	Ω(err).Should(MatchError(ContainSubstring("unsupported ec curve")))
+}
+
+// Signs with a key in a real token, e.g. SoftHSM, if CA_PKCS11_MODULE is set.
+// CA_PKCS11_SLOT, CA_PKCS11_LABEL, and CA_PKCS11_PIN locate the key pair.
+func TestPKCS11Signer(t *testing.T) {
+	module := os.Getenv("CA_PKCS11_MODULE")
+	if module == "" {
+		t.Skip("set CA_PKCS11_MODULE to test signing with a pkcs11 token")
+	}
+	RegisterTestingT(t)
+
+	slot, _ := strconv.Atoi(os.Getenv("CA_PKCS11_SLOT"))
+	signer, err := OpenSigner("pkcs11", SignerConfig{Module: module, Slot: slot, Label: os.Getenv("CA_PKCS11_LABEL"), PIN: os.Getenv("CA_PKCS11_PIN")})
+	Ω(err).ShouldNot(HaveOccurred())
+	defer signer.(*pkcs11Signer).Close()
+
+	authority := New(t.TempDir())
+	Ω(authority.InitSigner(Subject{Organization: "Testing"}, signer, false)).Should(Succeed())
+
+	cert, err := authority.Issue(Subject{DNSNames: []string{"localhost"}})
+	Ω(err).ShouldNot(HaveOccurred())
+	_, err = authority.Verify(cert.Cert)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	digest := sha256.Sum256([]byte("hello world"))
+	_, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	Ω(err).ShouldNot(HaveOccurred())
+}
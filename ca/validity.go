@@ -0,0 +1,51 @@
+package ca
+
+import (
+	"fmt"
+	"time"
+)
+
+// Default validity periods of certificates created by the CA.
+const (
+	DefaultValidity             = 7 * 24 * time.Hour
+	DefaultCAValidity           = 10 * 365 * 24 * time.Hour
+	DefaultIntermediateValidity = 5 * 365 * 24 * time.Hour
+)
+
+// Validity describes the period that a certificate is valid for. The zero
+// value starts the period now and uses the default duration for the kind of
+// certificate being created. Set NotBefore and NotAfter (or Duration) in the
+// past to create deliberately expired certificates for negative testing.
+type Validity struct {
+	NotBefore time.Time     // start of the validity period, now if zero
+	NotAfter  time.Time     // end of the validity period, NotBefore + Duration if zero
+	Duration  time.Duration // length of the validity period if NotAfter is zero
+	Backdate  time.Duration // moves NotBefore into the past to tolerate clock skew
+}
+
+// Period returns the start and end of the validity period, using the default
+// duration if neither NotAfter nor Duration is specified. The start is moved
+// into the past by the backdate but the end is not moved.
+func (v Validity) Period(defaultDuration time.Duration) (notBefore, notAfter time.Time, err error) {
+	if notBefore = v.NotBefore; notBefore.IsZero() {
+		notBefore = time.Now()
+	}
+
+	if notAfter = v.NotAfter; notAfter.IsZero() {
+		duration := v.Duration
+		if duration == 0 {
+			duration = defaultDuration
+		}
+		notAfter = notBefore.Add(duration)
+	}
+
+	if !notAfter.After(notBefore) {
+		return notBefore, notAfter, fmt.Errorf("certificate would expire at %s before it is valid at %s", notAfter.Format(time.RFC3339), notBefore.Format(time.RFC3339))
+	}
+
+	if v.Backdate < 0 {
+		return notBefore, notAfter, fmt.Errorf("cannot backdate by a negative duration %s", v.Backdate)
+	}
+
+	return notBefore.Add(-v.Backdate), notAfter, nil
+}
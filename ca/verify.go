@@ -0,0 +1,45 @@
+package ca
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Verify that the certificate was issued by the CA, returning the verified
+// chains from the certificate to the CA. The intermediates are any additional
+// certificates that were presented with the certificate; the intermediate
+// chain and cross-signed certificates of the CA are always included. An error
+// is also returned if the certificate was revoked by the CA.
+func (c *CA) Verify(cert *x509.Certificate, intermediates ...*x509.Certificate) (_ [][]*x509.Certificate, err error) {
+	if c.Cert == nil {
+		return nil, errors.New("ca has not been initialized or loaded")
+	}
+
+	pool := x509.NewCertPool()
+	for _, inter := range intermediates {
+		pool.AddCert(inter)
+	}
+
+	for _, inter := range append(c.Chain, c.Cross, c.PreviousCross) {
+		if inter != nil {
+			pool.AddCert(inter)
+		}
+	}
+
+	var chains [][]*x509.Certificate
+	if chains, err = cert.Verify(x509.VerifyOptions{
+		Roots:         c.CertPool(),
+		Intermediates: pool,
+		CurrentTime:   time.Now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, err
+	}
+
+	if record, ok := c.serials[serialKey(cert.SerialNumber)]; ok && record.Revoked() {
+		return nil, fmt.Errorf("certificate %s was revoked at %s", record.Serial, record.RevokedAt.Format(time.RFC3339))
+	}
+	return chains, nil
+}
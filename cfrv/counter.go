@@ -0,0 +1,182 @@
+// Implements state-based counter CRDTs keyed by process id
+
+package cfrv
+
+import (
+	"encoding/json"
+	"math"
+)
+
+//===========================================================================
+// Grow-Only Counter
+//===========================================================================
+
+// GCounter is a state-based grow-only counter. Each replica only increments
+// its own entry, identified by the same process id that the VersionFactory uses
+// to issue versions, and the value of the counter is the sum of all entries.
+// Merging takes the element-wise maximum of the entries, so replicas that
+// exchange their state in any order (or more than once) converge on the same
+// value. Note that the GCounter is not thread-safe.
+type GCounter struct {
+	pid    uint16      // the process id of the local replica
+	counts VectorClock // the count of every replica by process id
+}
+
+// The serialized representation of a GCounter.
+type gcounter struct {
+	PID    uint16            `json:"pid"`
+	Counts map[uint16]uint64 `json:"counts"`
+}
+
+// NewGCounter creates a zero valued counter for the specified process id.
+func NewGCounter(pid uint16) *GCounter {
+	return &GCounter{pid: pid, counts: make(VectorClock)}
+}
+
+// PID returns the process id of the local replica.
+func (c *GCounter) PID() uint16 {
+	return c.pid
+}
+
+// Increment the local replica's entry by one.
+func (c *GCounter) Increment() {
+	c.Add(1)
+}
+
+// Add delta to the local replica's entry.
+func (c *GCounter) Add(delta uint64) {
+	c.counts[c.pid] += delta
+}
+
+// Value returns the sum of the entries of all replicas.
+func (c *GCounter) Value() (value uint64) {
+	for _, count := range c.counts {
+		value += count
+	}
+	return value
+}
+
+// Count returns the entry of the replica with the specified process id.
+func (c *GCounter) Count(pid uint16) uint64 {
+	return c.counts[pid]
+}
+
+// State returns a copy of the per-replica entries as a vector clock.
+func (c *GCounter) State() VectorClock {
+	state := make(VectorClock, len(c.counts))
+	state.Update(c.counts)
+	return state
+}
+
+// Merge the state of the other counter into the local counter by taking the
+// maximum of the entries of each replica.
+func (c *GCounter) Merge(o *GCounter) {
+	c.counts.Update(o.counts)
+}
+
+// MarshalJSON serializes the process id and the per-replica entries.
+func (c *GCounter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(gcounter{PID: c.pid, Counts: c.counts})
+}
+
+// UnmarshalJSON replaces the process id and per-replica entries of the counter.
+func (c *GCounter) UnmarshalJSON(data []byte) error {
+	in := gcounter{}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	c.pid = in.PID
+	c.counts = make(VectorClock, len(in.Counts))
+	c.counts.Update(in.Counts)
+	return nil
+}
+
+//===========================================================================
+// Positive-Negative Counter
+//===========================================================================
+
+// PNCounter is a state-based counter that can be incremented and decremented
+// by composing two grow-only counters: one for increments and one for
+// decrements. The value of the counter is the difference between the two.
+// Note that the PNCounter is not thread-safe.
+type PNCounter struct {
+	p *GCounter // increments
+	n *GCounter // decrements
+}
+
+// The serialized representation of a PNCounter.
+type pncounter struct {
+	PID uint16            `json:"pid"`
+	P   map[uint16]uint64 `json:"p"`
+	N   map[uint16]uint64 `json:"n"`
+}
+
+// NewPNCounter creates a zero valued counter for the specified process id.
+func NewPNCounter(pid uint16) *PNCounter {
+	return &PNCounter{p: NewGCounter(pid), n: NewGCounter(pid)}
+}
+
+// PID returns the process id of the local replica.
+func (c *PNCounter) PID() uint16 {
+	return c.p.pid
+}
+
+// Increment the counter by one.
+func (c *PNCounter) Increment() {
+	c.p.Add(1)
+}
+
+// Decrement the counter by one.
+func (c *PNCounter) Decrement() {
+	c.n.Add(1)
+}
+
+// Add delta to the counter; a negative delta decrements the counter.
+func (c *PNCounter) Add(delta int64) {
+	switch {
+	case delta == math.MinInt64:
+		c.n.Add(uint64(math.MaxInt64) + 1)
+	case delta < 0:
+		c.n.Add(uint64(-delta))
+	default:
+		c.p.Add(uint64(delta))
+	}
+}
+
+// Value returns the total increments minus the total decrements of all replicas.
+func (c *PNCounter) Value() int64 {
+	return int64(c.p.Value() - c.n.Value())
+}
+
+// Count returns the net count of the replica with the specified process id.
+func (c *PNCounter) Count(pid uint16) int64 {
+	return int64(c.p.Count(pid) - c.n.Count(pid))
+}
+
+// Merge the state of the other counter into the local counter by merging the
+// increments and decrements separately.
+func (c *PNCounter) Merge(o *PNCounter) {
+	c.p.Merge(o.p)
+	c.n.Merge(o.n)
+}
+
+// MarshalJSON serializes the process id and the per-replica increments and
+// decrements.
+func (c *PNCounter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(pncounter{PID: c.p.pid, P: c.p.counts, N: c.n.counts})
+}
+
+// UnmarshalJSON replaces the process id and per-replica increments and
+// decrements of the counter.
+func (c *PNCounter) UnmarshalJSON(data []byte) error {
+	in := pncounter{}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	c.p, c.n = NewGCounter(in.PID), NewGCounter(in.PID)
+	c.p.counts.Update(in.P)
+	c.n.counts.Update(in.N)
+	return nil
+}
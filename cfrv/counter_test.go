@@ -0,0 +1,112 @@
+package cfrv
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// Test that grow-only counters converge regardless of merge order.
+func TestGCounter(t *testing.T) {
+	a, b, c := NewGCounter(1), NewGCounter(2), NewGCounter(3)
+	a.Increment()
+	a.Increment()
+	b.Add(5)
+	c.Increment()
+
+	a.Merge(b)
+	b.Merge(c)
+	c.Merge(a)
+	a.Merge(c)
+	b.Merge(a)
+
+	// Merging is idempotent
+	b.Merge(a)
+
+	for _, counter := range []*GCounter{a, b, c} {
+		if counter.Value() != 8 {
+			t.Errorf("expected counter %d to have value 8 but got %d", counter.PID(), counter.Value())
+		}
+	}
+
+	if a.Count(2) != 5 || a.Count(4) != 0 {
+		t.Errorf("unexpected counter state %s", a.State())
+	}
+
+	state := a.State()
+	state.Increment(1)
+	if a.Count(1) != 2 {
+		t.Error("expected state to be a copy of the counter entries")
+	}
+}
+
+// Test that positive-negative counters converge regardless of merge order.
+func TestPNCounter(t *testing.T) {
+	a, b := NewPNCounter(1), NewPNCounter(2)
+	a.Increment()
+	a.Add(4)
+	b.Decrement()
+	b.Add(-7)
+
+	a.Merge(b)
+	b.Merge(a)
+
+	if a.Value() != -3 || b.Value() != -3 {
+		t.Errorf("expected both counters to be -3 but got %d and %d", a.Value(), b.Value())
+	}
+
+	if a.Count(1) != 5 || a.Count(2) != -8 {
+		t.Errorf("unexpected per-replica counts %d and %d", a.Count(1), a.Count(2))
+	}
+}
+
+// Test that counters are serialized with their process id.
+func TestCounterJSON(t *testing.T) {
+	g := NewGCounter(7)
+	g.Add(3)
+	g.Merge(&GCounter{pid: 2, counts: VectorClock{2: 4}})
+
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != `{"pid":7,"counts":{"2":4,"7":3}}` {
+		t.Errorf("unexpected serialization %s", data)
+	}
+
+	other := new(GCounter)
+	if err = json.Unmarshal(data, other); err != nil {
+		t.Fatal(err)
+	}
+
+	if other.PID() != 7 || other.Value() != 7 || !other.State().Equals(g.State()) {
+		t.Errorf("unexpected deserialized counter %d %s", other.PID(), other.State())
+	}
+
+	pn := NewPNCounter(7)
+	pn.Add(10)
+	pn.Add(-4)
+
+	if data, err = json.Marshal(pn); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != `{"pid":7,"p":{"7":10},"n":{"7":4}}` {
+		t.Errorf("unexpected serialization %s", data)
+	}
+
+	opn := new(PNCounter)
+	if err = json.Unmarshal(data, opn); err != nil {
+		t.Fatal(err)
+	}
+
+	if opn.PID() != 7 || opn.Value() != 6 {
+		t.Errorf("unexpected deserialized counter %d %d", opn.PID(), opn.Value())
+	}
+
+	// The local replica can continue to increment after deserialization
+	opn.Increment()
+	if opn.Value() != 7 {
+		t.Errorf("expected value 7 after increment got %d", opn.Value())
+	}
+}
@@ -125,6 +125,14 @@ type VersionFactory struct {
 	latest map[string]uint64 // map of keys to latest seen scalar
 }
 
+// NewVersionFactory creates a version factory for the specified process id.
+func NewVersionFactory(pid uint16) *VersionFactory {
+	return &VersionFactory{
+		pid:    pid,
+		latest: make(map[string]uint64),
+	}
+}
+
 // Next creates and returns the next version for the given key.
 func (f *VersionFactory) Next(key string) *Version {
 	f.latest[key]++
@@ -0,0 +1,7 @@
+// Package pb contains the protocol buffer definitions for conflict-free
+// replicated versions so that services exchanging versions over gRPC share a
+// single wire encoding. Use the ToProto and FromProto methods in the cfrv
+// package to convert between these messages and the native version types.
+package pb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative cfrv.proto
@@ -0,0 +1,221 @@
+// Implements a version factory that persists issued versions to disk
+
+package cfrv
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// DefaultReserve is the number of scalars of a key that a PersistentFactory
+// reserves with each checkpoint if its Reserve is zero.
+const DefaultReserve = 100
+
+//===========================================================================
+// Persistent Version Factory
+//===========================================================================
+
+// PersistentFactory wraps a VersionFactory and checkpoints a block of scalars
+// for every key to disk before a version in the block is issued, so that the
+// checkpoint is only written once every Reserve versions rather than for every
+// version. When the factory is created it loads the checkpoint and resumes
+// after the reserved blocks, so that after a crash or restart it never issues a
+// version that is less than or equal to a version that was issued before, which
+// would break the ordering invariants of the versions. The unused scalars of a
+// reserved block are skipped after a restart.
+//
+// Checkpoints are written atomically by writing a temporary file in the same
+// directory, syncing it, renaming it to the checkpoint path, and syncing the
+// directory. Unlike the VersionFactory, the PersistentFactory is thread-safe.
+type PersistentFactory struct {
+	sync.Mutex
+	Reserve    uint64            // the number of scalars reserved per checkpoint (DefaultReserve if zero)
+	versions   VersionFactory    // issues versions, only accessed with the lock held
+	path       string            // the path to the checkpoint on disk
+	checkpoint map[string]uint64 // the reserved scalars written to disk
+}
+
+// The serialized representation of the checkpoint on disk.
+type checkpoint struct {
+	PID    uint16            `json:"pid"`
+	Latest map[string]uint64 `json:"latest"`
+}
+
+// NewPersistentFactory creates a factory for the given process id that
+// checkpoints issued versions to the specified path. If a checkpoint already
+// exists at the path it is loaded so that new versions are greater than any
+// previously issued version. An error is returned if the checkpoint cannot be
+// read or if it was written by a factory with a different process id.
+func NewPersistentFactory(pid uint16, path string) (*PersistentFactory, error) {
+	f := &PersistentFactory{
+		versions:   *NewVersionFactory(pid),
+		path:       path,
+		checkpoint: make(map[string]uint64),
+	}
+
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Next returns the next version for the given key, checkpointing a new block of
+// scalars if the reserved block of the key is used up. If the checkpoint cannot
+// be written, no version is issued and an error is returned.
+func (f *PersistentFactory) Next(key string) (*Version, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	vers := &Version{Scalar: f.versions.latest[key] + 1, PID: f.versions.pid}
+	if err := f.reserve(key, vers.Scalar); err != nil {
+		return nil, err
+	}
+
+	f.versions.latest[key] = vers.Scalar
+	return vers, nil
+}
+
+// Update the latest version with the version for the given key, checkpointing a
+// new block of scalars if the scalar is beyond the reserved block of the key.
+func (f *PersistentFactory) Update(key string, vers *Version) error {
+	f.Lock()
+	defer f.Unlock()
+
+	if vers.Scalar <= f.versions.latest[key] {
+		return nil
+	}
+
+	if err := f.reserve(key, vers.Scalar); err != nil {
+		return err
+	}
+
+	f.versions.latest[key] = vers.Scalar
+	return nil
+}
+
+// Path returns the location of the checkpoint on disk.
+func (f *PersistentFactory) Path() string {
+	return f.path
+}
+
+// Load the checkpoint from disk if it exists (not thread-safe).
+func (f *PersistentFactory) load() error {
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("could not read version checkpoint: %s", err)
+	}
+
+	cp := checkpoint{}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return fmt.Errorf("could not parse version checkpoint: %s", err)
+	}
+
+	if cp.PID != f.versions.pid {
+		return fmt.Errorf("version checkpoint belongs to pid %d not %d", cp.PID, f.versions.pid)
+	}
+
+	for key, scalar := range cp.Latest {
+		f.versions.latest[key] = scalar
+		f.checkpoint[key] = scalar
+	}
+	return nil
+}
+
+// Checkpoints a block of scalars starting at the scalar for the key if the
+// scalar has not already been reserved (not thread-safe).
+func (f *PersistentFactory) reserve(key string, scalar uint64) error {
+	if scalar <= f.checkpoint[key] {
+		return nil
+	}
+
+	block := f.Reserve
+	if block == 0 {
+		block = DefaultReserve
+	}
+	return f.save(key, scalar+block-1)
+}
+
+// Atomically write the checkpoint with the updated scalar for the key to disk
+// (not thread-safe). The in-memory checkpoint is only modified on success.
+func (f *PersistentFactory) save(key string, scalar uint64) (err error) {
+	cp := checkpoint{PID: f.versions.pid, Latest: make(map[string]uint64, len(f.checkpoint)+1)}
+	for k, v := range f.checkpoint {
+		cp.Latest[k] = v
+	}
+	cp.Latest[key] = scalar
+
+	var data []byte
+	if data, err = json.Marshal(cp); err != nil {
+		return err
+	}
+
+	if err = writeAtomic(f.path, data); err != nil {
+		return fmt.Errorf("could not write version checkpoint: %s", err)
+	}
+
+	f.checkpoint[key] = scalar
+	return nil
+}
+
+// Writes the data to a temporary file in the same directory as the path, syncs
+// it to disk, then renames it to the path so that readers never see a partially
+// written file. The directory is synced after the rename so that the rename
+// itself survives a crash.
+func writeAtomic(path string, data []byte) (err error) {
+	dir := filepath.Dir(path)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	var tmp *os.File
+	if tmp, err = ioutil.TempFile(dir, "."+filepath.Base(path)+"-*"); err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// Syncs the directory to disk so that the entries in it are durable. Windows
+// does not support syncing directories, renames are durable once they return.
+func syncDir(dir string) (err error) {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	var d *os.File
+	if d, err = os.Open(dir); err != nil {
+		return err
+	}
+
+	if err = d.Sync(); err != nil {
+		d.Close()
+		return err
+	}
+	return d.Close()
+}
@@ -0,0 +1,158 @@
+package cfrv
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test that a persistent factory never issues versions lower than the
+// checkpoint after it is restarted.
+func TestPersistentFactory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfrv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "versions.json")
+	factory, err := NewPersistentFactory(7, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, err := factory.Next("foo"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := factory.Update("bar", &Version{Scalar: 42, PID: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restart the factory from the checkpoint
+	factory, err = NewPersistentFactory(7, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vers, err := factory.Next("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Versions resume after the reserved blocks
+	if vers.Scalar != DefaultReserve+1 || vers.PID != 7 {
+		t.Errorf("expected version %d.7 after restart but got %s", DefaultReserve+1, vers)
+	}
+
+	if vers, _ = factory.Next("bar"); vers.Scalar != 42+DefaultReserve {
+		t.Errorf("expected version %d.7 after restart but got %s", 42+DefaultReserve, vers)
+	}
+}
+
+// Test that the checkpoint is only written when a new block is reserved.
+func TestPersistentFactoryReserve(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "versions.json")
+	factory, err := NewPersistentFactory(7, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	factory.Reserve = 3
+
+	expected := []uint64{3, 3, 3, 6, 6, 6, 9}
+	for i, reserved := range expected {
+		vers, err := factory.Next("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if vers.Scalar != uint64(i+1) {
+			t.Errorf("expected version %d.7 got %s", i+1, vers)
+		}
+
+		if latest := checkpointed(t, path)["foo"]; latest != reserved {
+			t.Errorf("expected %d to be reserved after version %s got %d", reserved, vers, latest)
+		}
+	}
+
+	// Updates within the reserved block are not written
+	if err = factory.Update("foo", &Version{Scalar: 9, PID: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = factory.Update("foo", &Version{Scalar: 20, PID: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	if latest := checkpointed(t, path)["foo"]; latest != 22 {
+		t.Errorf("expected 22 to be reserved after the update got %d", latest)
+	}
+}
+
+// Returns the reserved scalars in the checkpoint on disk.
+func checkpointed(t *testing.T, path string) map[string]uint64 {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cp := checkpoint{}
+	if err = json.Unmarshal(data, &cp); err != nil {
+		t.Fatal(err)
+	}
+	return cp.Latest
+}
+
+// Test that a checkpoint cannot be loaded by a factory with a different pid.
+func TestPersistentFactoryPID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfrv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "versions.json")
+	factory, err := NewPersistentFactory(7, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = factory.Next("foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewPersistentFactory(8, path); err == nil {
+		t.Error("expected error loading checkpoint with a different pid")
+	}
+}
+
+// Test that no version is issued if the checkpoint cannot be written.
+func TestPersistentFactoryWriteError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cfrv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	factory, err := NewPersistentFactory(7, filepath.Join(dir, "blocked", "versions.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The checkpoint directory is a file so the checkpoint cannot be written
+	if err = ioutil.WriteFile(filepath.Join(dir, "blocked"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if vers, err := factory.Next("foo"); err == nil || vers != nil {
+		t.Error("expected an error and no version when checkpoint fails")
+	}
+
+	if factory.versions.latest["foo"] != 0 {
+		t.Error("expected latest scalar to be unchanged when checkpoint fails")
+	}
+}
@@ -0,0 +1,67 @@
+// Implements conversions to and from the protocol buffer definitions
+
+package cfrv
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/bbengfort/x/cfrv/pb"
+)
+
+//===========================================================================
+// Protocol Buffer Converters
+//===========================================================================
+
+// ToProto converts the version into its protocol buffer representation.
+func (v Version) ToProto() *pb.Version {
+	return &pb.Version{Scalar: v.Scalar, Pid: uint32(v.PID)}
+}
+
+// FromProto loads the version from its protocol buffer representation. A nil
+// message is loaded as the zero version. An error is returned if the pid in
+// the message cannot be represented as a uint16.
+func (v *Version) FromProto(in *pb.Version) error {
+	if in == nil {
+		*v = NullVersion
+		return nil
+	}
+
+	if in.Pid > math.MaxUint16 {
+		return fmt.Errorf("pid %d overflows uint16", in.Pid)
+	}
+
+	v.Scalar = in.Scalar
+	v.PID = uint16(in.Pid)
+	return nil
+}
+
+// ToProto converts the vector clock into its protocol buffer representation.
+func (c VectorClock) ToProto() *pb.VectorClock {
+	out := &pb.VectorClock{Clock: make(map[uint32]uint64, len(c))}
+	for pid, scalar := range c {
+		out.Clock[uint32(pid)] = scalar
+	}
+	return out
+}
+
+// FromProto replaces the contents of the vector clock with its protocol buffer
+// representation. An error is returned if any pid in the message cannot be
+// represented as a uint16, in which case the clock is not modified.
+func (c *VectorClock) FromProto(in *pb.VectorClock) error {
+	if c == nil {
+		return errors.New("cannot load into a nil vector clock pointer")
+	}
+
+	clock := make(VectorClock, len(in.GetClock()))
+	for pid, scalar := range in.GetClock() {
+		if pid > math.MaxUint16 {
+			return fmt.Errorf("pid %d overflows uint16", pid)
+		}
+		clock[uint16(pid)] = scalar
+	}
+
+	*c = clock
+	return nil
+}
@@ -0,0 +1,96 @@
+package cfrv
+
+import (
+	"testing"
+
+	"github.com/bbengfort/x/cfrv/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// Test that versions and vector clocks survive a round trip over the wire.
+func TestProtoRoundTrip(t *testing.T) {
+	vers := &Version{Scalar: 42, PID: 7}
+	data, err := proto.Marshal(vers.ToProto())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &pb.Version{}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	other := &Version{}
+	if err := other.FromProto(msg); err != nil {
+		t.Fatal(err)
+	}
+	if !other.Equals(vers) {
+		t.Errorf("expected version %s got %s", vers, other)
+	}
+
+	clock := VectorClock{1: 4, 2: 8, 3: 1}
+	if data, err = proto.Marshal(clock.ToProto()); err != nil {
+		t.Fatal(err)
+	}
+
+	cmsg := &pb.VectorClock{}
+	if err := proto.Unmarshal(data, cmsg); err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded VectorClock
+	if err := loaded.FromProto(cmsg); err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Equals(clock) {
+		t.Errorf("expected clock %s got %s", clock, loaded)
+	}
+}
+
+// Test that pids that overflow a uint16 are rejected.
+func TestProtoPIDOverflow(t *testing.T) {
+	vers := &Version{Scalar: 1, PID: 1}
+	if err := vers.FromProto(&pb.Version{Scalar: 2, Pid: 70000}); err == nil {
+		t.Error("expected pid overflow error")
+	}
+	if vers.Scalar != 1 || vers.PID != 1 {
+		t.Errorf("version modified on error: %s", vers)
+	}
+
+	clock := VectorClock{1: 1}
+	if err := clock.FromProto(&pb.VectorClock{Clock: map[uint32]uint64{70000: 1}}); err == nil {
+		t.Error("expected pid overflow error")
+	}
+	if len(clock) != 1 || clock[1] != 1 {
+		t.Errorf("clock modified on error: %s", clock)
+	}
+
+	if err := vers.FromProto(nil); err != nil || !vers.IsZero() {
+		t.Errorf("expected nil message to load the zero version")
+	}
+}
+
+// Test the ordering of vector clocks, including concurrent clocks.
+func TestVectorClock(t *testing.T) {
+	a := VectorClock{}
+	a.Increment(1)
+
+	b := VectorClock{}
+	b.Update(a)
+	b.Increment(2)
+
+	if !a.Lesser(b) || !b.Greater(a) || a.Concurrent(b) {
+		t.Errorf("expected %s to happen before %s", a, b)
+	}
+
+	a.Increment(1)
+	if !a.Concurrent(b) || a.Lesser(b) || a.Greater(b) {
+		t.Errorf("expected %s to be concurrent with %s", a, b)
+	}
+
+	a.Update(b)
+	b.Update(a)
+	if !a.Equals(b) || a.String() != "[1:2 2:1]" {
+		t.Errorf("expected %s to equal %s", a, b)
+	}
+}
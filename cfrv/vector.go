@@ -0,0 +1,98 @@
+// Implements vector clocks keyed by process id
+
+package cfrv
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//===========================================================================
+// Vector Clock
+//===========================================================================
+
+// VectorClock maps process ids to the latest scalar observed from that process.
+// Unlike a Lamport scalar Version, vector clocks can detect when two versions
+// were concurrently generated. Missing process ids are treated as zero. Note
+// that a VectorClock is a map and is not thread-safe.
+type VectorClock map[uint16]uint64
+
+// Increment the scalar for the specified process id, e.g. on a local event.
+func (c VectorClock) Increment(pid uint16) {
+	c[pid]++
+}
+
+// Update the clock with the element-wise maximum of the local and other clock.
+func (c VectorClock) Update(o VectorClock) {
+	for pid, scalar := range o {
+		if scalar > c[pid] {
+			c[pid] = scalar
+		}
+	}
+}
+
+// Equals returns true if both clocks have the same scalar for every process.
+func (c VectorClock) Equals(o VectorClock) bool {
+	return c.compare(o) == 0
+}
+
+// Lesser returns true if the local clock happened before the other clock.
+func (c VectorClock) Lesser(o VectorClock) bool {
+	return c.compare(o) == -1
+}
+
+// Greater returns true if the other clock happened before the local clock.
+func (c VectorClock) Greater(o VectorClock) bool {
+	return c.compare(o) == 1
+}
+
+// Concurrent returns true if neither clock happened before the other.
+func (c VectorClock) Concurrent(o VectorClock) bool {
+	return c.compare(o) == 2
+}
+
+// String returns a representation of the clock ordered by process id.
+func (c VectorClock) String() string {
+	pids := make([]int, 0, len(c))
+	for pid := range c {
+		pids = append(pids, int(pid))
+	}
+	sort.Ints(pids)
+
+	parts := make([]string, 0, len(pids))
+	for _, pid := range pids {
+		parts = append(parts, fmt.Sprintf("%d:%d", pid, c[uint16(pid)]))
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+// compare returns 0 if the clocks are equal, -1 if c < o, 1 if c > o, and 2 if
+// the clocks are concurrent.
+func (c VectorClock) compare(o VectorClock) int {
+	var less, more bool
+	for pid, scalar := range c {
+		if scalar > o[pid] {
+			more = true
+		} else if scalar < o[pid] {
+			less = true
+		}
+	}
+
+	for pid, scalar := range o {
+		if _, ok := c[pid]; !ok && scalar > 0 {
+			less = true
+		}
+	}
+
+	switch {
+	case less && more:
+		return 2
+	case less:
+		return -1
+	case more:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,129 @@
+// Implements a map container that stamps values with their versions
+
+package cfrv
+
+import (
+	"sort"
+	"sync"
+)
+
+//===========================================================================
+// Versioned Map
+//===========================================================================
+
+// VersionedMap stores values along with the Lamport scalar version that wrote
+// them. Writes that are not newer than the stored version are rejected so that
+// replicas applying the same updates in any order converge on the same state
+// (last writer wins). Unlike the VersionFactory, the VersionedMap is
+// thread-safe.
+type VersionedMap[K comparable, V any] struct {
+	sync.RWMutex
+	items map[K]versioned[V]
+}
+
+// A value and the version that wrote it.
+type versioned[V any] struct {
+	value V
+	vers  Version
+}
+
+// NewVersionedMap creates an empty versioned map.
+func NewVersionedMap[K comparable, V any]() *VersionedMap[K, V] {
+	return &VersionedMap[K, V]{items: make(map[K]versioned[V])}
+}
+
+// Get returns the value stored for the key and true if the key exists.
+func (m *VersionedMap[K, V]) Get(key K) (value V, ok bool) {
+	value, _, ok = m.GetVersion(key)
+	return value, ok
+}
+
+// GetVersion returns the value and the version stored for the key and true if
+// the key exists, otherwise the version is nil.
+func (m *VersionedMap[K, V]) GetVersion(key K) (value V, vers *Version, ok bool) {
+	m.RLock()
+	defer m.RUnlock()
+
+	var item versioned[V]
+	if item, ok = m.items[key]; !ok {
+		return value, nil, false
+	}
+
+	vers = &Version{}
+	*vers = item.vers
+	return item.value, vers, true
+}
+
+// PutIfNewer stores the value for the key if the version is greater than the
+// version currently stored, returning true if the value was stored. Stale or
+// duplicate writes and writes with a nil or zero version are rejected.
+func (m *VersionedMap[K, V]) PutIfNewer(key K, value V, vers *Version) bool {
+	if vers == nil || vers.IsZero() {
+		return false
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	if item, ok := m.items[key]; ok && !vers.Greater(&item.vers) {
+		return false
+	}
+
+	m.items[key] = versioned[V]{value: value, vers: *vers}
+	return true
+}
+
+// Delete the key from the map if the version is greater than or equal to the
+// version currently stored, returning true if the key was deleted. Deletes with
+// a nil or zero version are rejected. Note that the map does not keep
+// tombstones, so a delayed write with an earlier version can recreate the key.
+func (m *VersionedMap[K, V]) Delete(key K, vers *Version) bool {
+	if vers == nil || vers.IsZero() {
+		return false
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	if item, ok := m.items[key]; !ok || !vers.GreaterEqual(&item.vers) {
+		return false
+	}
+
+	delete(m.items, key)
+	return true
+}
+
+// Len returns the number of keys in the map.
+func (m *VersionedMap[K, V]) Len() int {
+	m.RLock()
+	defer m.RUnlock()
+	return len(m.items)
+}
+
+// Range calls fn for every key in the map in version order (earliest first)
+// until fn returns false. The map is not locked while fn is called, so fn may
+// modify the map; the iteration is over a snapshot of the map when Range was
+// called.
+func (m *VersionedMap[K, V]) Range(fn func(key K, value V, vers Version) bool) {
+	type entry struct {
+		key  K
+		item versioned[V]
+	}
+
+	m.RLock()
+	entries := make([]entry, 0, len(m.items))
+	for key, item := range m.items {
+		entries = append(entries, entry{key, item})
+	}
+	m.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].item.vers.Lesser(&entries[j].item.vers)
+	})
+
+	for _, e := range entries {
+		if !fn(e.key, e.item.value, e.item.vers) {
+			return
+		}
+	}
+}
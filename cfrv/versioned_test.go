@@ -0,0 +1,78 @@
+package cfrv
+
+import "testing"
+
+// Test that stale writes are rejected by the versioned map.
+func TestVersionedMap(t *testing.T) {
+	m := NewVersionedMap[string, int]()
+
+	if !m.PutIfNewer("foo", 1, &Version{Scalar: 2, PID: 1}) {
+		t.Error("expected the first write to be stored")
+	}
+
+	if m.PutIfNewer("foo", 2, &Version{Scalar: 1, PID: 3}) {
+		t.Error("expected a write with an earlier version to be rejected")
+	}
+
+	if m.PutIfNewer("foo", 3, &Version{Scalar: 2, PID: 1}) {
+		t.Error("expected a write with the same version to be rejected")
+	}
+
+	if m.PutIfNewer("bar", 1, nil) || m.PutIfNewer("bar", 1, &NullVersion) {
+		t.Error("expected a write without a version to be rejected")
+	}
+
+	if !m.PutIfNewer("foo", 4, &Version{Scalar: 2, PID: 2}) {
+		t.Error("expected a write with a later version to be stored")
+	}
+
+	val, vers, ok := m.GetVersion("foo")
+	if !ok || val != 4 || vers.String() != "2.2" {
+		t.Errorf("expected value 4 at version 2.2 but got %d at %s", val, vers)
+	}
+
+	if _, ok := m.Get("bar"); ok || m.Len() != 1 {
+		t.Error("expected only foo to be in the map")
+	}
+
+	if m.Delete("foo", nil) || m.Delete("foo", &NullVersion) || m.Len() != 1 {
+		t.Error("expected a delete without a version to be rejected")
+	}
+
+	if m.Delete("foo", &Version{Scalar: 1, PID: 1}) {
+		t.Error("expected a delete with an earlier version to be rejected")
+	}
+
+	if !m.Delete("foo", &Version{Scalar: 3, PID: 1}) || m.Len() != 0 {
+		t.Error("expected a delete with a later version to remove the key")
+	}
+}
+
+// Test that the versioned map iterates in version order.
+func TestVersionedMapRange(t *testing.T) {
+	m := NewVersionedMap[string, int]()
+	m.PutIfNewer("c", 3, &Version{Scalar: 3, PID: 1})
+	m.PutIfNewer("a", 1, &Version{Scalar: 1, PID: 2})
+	m.PutIfNewer("d", 4, &Version{Scalar: 3, PID: 2})
+	m.PutIfNewer("b", 2, &Version{Scalar: 2, PID: 1})
+
+	var keys string
+	m.Range(func(key string, value int, vers Version) bool {
+		keys += key
+		return true
+	})
+
+	if keys != "abcd" {
+		t.Errorf("expected keys in version order but got %q", keys)
+	}
+
+	keys = ""
+	m.Range(func(key string, value int, vers Version) bool {
+		keys += key
+		return len(keys) < 2
+	})
+
+	if keys != "ab" {
+		t.Errorf("expected iteration to stop early but got %q", keys)
+	}
+}
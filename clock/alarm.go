@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+//===========================================================================
+// Alarm
+//===========================================================================
+
+// Layouts of the times of day that an alarm can be set for.
+var alarmLayouts = []string{"15:04", "15:04:05", time.Kitchen, "3PM"}
+
+func alarm(c *cli.Context) (err error) {
+	arg := strings.TrimSpace(strings.Join(c.Args().Slice(), " "))
+	if arg == "" {
+		return cli.Exit("specify a time or datetime to set the alarm for", 1)
+	}
+
+	var loc *time.Location
+	if loc, err = location(c); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	var target time.Time
+	if target, err = parseAlarm(arg, clk.Now().In(loc)); err != nil {
+		if target, err = parseDatetime(arg, c.String("tz"), c.Bool("local"), c.Bool("utc")); err != nil {
+			return cli.Exit(fmt.Errorf("could not parse %q as a time or datetime", arg), 1)
+		}
+	}
+
+	if !target.After(clk.Now()) {
+		return cli.Exit(fmt.Errorf("alarm time %s is in the past", target.Format(time.RFC1123)), 1)
+	}
+
+	check := c.Duration("check")
+	if check <= 0 {
+		return cli.Exit("check interval must be greater than zero", 1)
+	}
+
+	if !c.Bool("quiet") {
+		fmt.Printf("alarm set for %s (%s)\n", target.Format(time.RFC1123), remaining(clk.Until(target)))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err = sleepUntil(ctx, target, check); err != nil {
+		return cli.Exit("alarm canceled", 1)
+	}
+
+	if msg := c.String("message"); msg != "" {
+		fmt.Println(msg)
+	}
+
+	if command := c.String("exec"); command != "" || c.String("message") == "" {
+		if err = complete(command); err != nil {
+			return cli.Exit(err, 1)
+		}
+	}
+	return nil
+}
+
+// Parses a time of day, returning its next occurrence after now, i.e. today if
+// the time has not passed yet, otherwise tomorrow.
+func parseAlarm(s string, now time.Time) (time.Time, error) {
+	for _, layout := range alarmLayouts {
+		tod, err := time.Parse(layout, strings.ToUpper(s))
+		if err != nil {
+			continue
+		}
+
+		dt := time.Date(now.Year(), now.Month(), now.Day(), tod.Hour(), tod.Minute(), tod.Second(), 0, now.Location())
+		if !dt.After(now) {
+			dt = dt.AddDate(0, 0, 1)
+		}
+		return dt, nil
+	}
+	return time.Time{}, fmt.Errorf("could not parse %q as a time of day", s)
+}
+
+// Blocks until the wall clock reaches the deadline or the context is canceled.
+// Go timers use the monotonic clock, which does not advance while the system is
+// suspended, so the wall clock is re-checked every interval instead of relying
+// on a single timer for the entire duration.
+func sleepUntil(ctx context.Context, deadline time.Time, interval time.Duration) error {
+	deadline = deadline.Round(0)
+	for {
+		wait := deadline.Sub(clk.Now().Round(0))
+		if wait <= 0 {
+			return nil
+		}
+
+		if wait > interval {
+			wait = interval
+		}
+
+		timer := clk.NewTimer(wait)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestParseAlarm(t *testing.T) {
+	now := frozen
+
+	tests := []struct {
+		s        string
+		expected time.Time
+		err      bool
+	}{
+		{"15:00", time.Date(2024, 5, 2, 15, 0, 0, 0, time.UTC), false},
+		{"15:00:30", time.Date(2024, 5, 2, 15, 0, 30, 0, time.UTC), false},
+		{"3:00PM", time.Date(2024, 5, 2, 15, 0, 0, 0, time.UTC), false},
+		{"3:00pm", time.Date(2024, 5, 2, 15, 0, 0, 0, time.UTC), false},
+		{"3pm", time.Date(2024, 5, 2, 15, 0, 0, 0, time.UTC), false},
+		{"14:30", time.Date(2024, 5, 3, 14, 30, 0, 0, time.UTC), false},
+		{"9:00", time.Date(2024, 5, 3, 9, 0, 0, 0, time.UTC), false},
+		{"00:00", time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC), false},
+		{"14:30:01", time.Date(2024, 5, 2, 14, 30, 1, 0, time.UTC), false},
+		{"25:00", time.Time{}, true},
+		{"noon", time.Time{}, true},
+		{"2024-05-03 09:00", time.Time{}, true},
+	}
+
+	for _, tc := range tests {
+		dt, err := parseAlarm(tc.s, now)
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected an error parsing %q got %s", tc.s, dt)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("expected no error parsing %q got %s", tc.s, err)
+			continue
+		}
+
+		if !dt.Equal(tc.expected) {
+			t.Errorf("expected alarm %q to be set for %s got %s", tc.s, tc.expected, dt)
+		}
+	}
+
+	// The time of day is in the location of now
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("timezone database is not available: %s", err)
+	}
+
+	if dt, _ := parseAlarm("9:00", now.In(loc)); !dt.Equal(time.Date(2024, 5, 3, 9, 0, 0, 0, loc)) {
+		t.Errorf("expected the alarm to be set for tomorrow morning in Tokyo got %s", dt)
+	}
+}
+
+func TestSleepUntil(t *testing.T) {
+	fake := freeze(t, frozen)
+	deadline := frozen.Add(time.Hour)
+
+	done := make(chan error, 1)
+	go func() { done <- sleepUntil(context.Background(), deadline, time.Minute) }()
+
+	// Advancing the clock by less than the deadline only fires the check
+	fake.BlockUntil(1)
+	fake.Advance(30 * time.Minute)
+	select {
+	case err := <-done:
+		t.Fatalf("woke before the deadline: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// Jump the wall clock past the deadline as if the system was suspended
+	fake.BlockUntil(1)
+	fake.Set(deadline.Add(time.Hour))
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected no error after the deadline got %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("did not wake after the wall clock passed the deadline")
+	}
+
+	// A deadline in the past returns immediately
+	if err := sleepUntil(context.Background(), frozen, time.Minute); err != nil {
+		t.Errorf("expected no error for a past deadline got %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { done <- sleepUntil(ctx, fake.Now().Add(time.Hour), time.Minute) }()
+	fake.BlockUntil(1)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("expected the sleep to be canceled got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("sleep was not canceled")
+	}
+}
+
+func TestAlarm(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires a posix shell")
+	}
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--utc", "alarm", "-m", "wake up", "14:30:05"}, "alarm set for Thu, 02 May 2024 14:30:05 UTC (00:00:05)\nwake up"},
+		{[]string{"--utc", "alarm", "-q", "-m", "wake up", "14:31"}, "wake up"},
+		{[]string{"--utc", "alarm", "-q", "2024-05-02 14:30:10"}, "\a"},
+		{[]string{"--utc", "alarm", "-q", "-m", "wake up", "--exec", "echo ran", "2:31pm"}, "wake up\nran"},
+		{[]string{"--tz", "Asia/Tokyo", "alarm", "-m", "good morning", "23:31"}, "alarm set for Thu, 02 May 2024 23:31:00 JST (00:01:00)\ngood morning"},
+	}
+
+	for _, tc := range tests {
+		tickTock(t, freeze(t, frozen))
+		out, err := run(t, tc.args...)
+		if err != nil {
+			t.Errorf("expected no error running %q got %s", tc.args, err)
+			continue
+		}
+
+		if out != tc.expected {
+			t.Errorf("expected %q running %q got %q", tc.expected, tc.args, out)
+		}
+	}
+}
+
+func TestAlarmErrors(t *testing.T) {
+	freeze(t, frozen)
+
+	tests := [][]string{
+		{"--utc", "alarm"},
+		{"--utc", "alarm", "lunch"},
+		{"--utc", "alarm", "2024-05-01 09:00"},
+		{"--utc", "alarm", "--check", "0s", "15:00"},
+		{"--tz", "Mars/Olympus_Mons", "alarm", "15:00"},
+	}
+
+	for _, args := range tests {
+		if out, err := run(t, args...); err == nil {
+			t.Errorf("expected an error running %q got %q", args, out)
+		}
+	}
+}
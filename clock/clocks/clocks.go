@@ -0,0 +1,97 @@
+/*
+Package clocks abstracts the time package behind a Clock interface so that code
+that reads the time, sleeps, or waits on timers and tickers can be tested with a
+fake clock instead of waiting on real time.
+
+Code under test accepts a Clock, which is Real() in production:
+
+	type Service struct {
+		Clock clocks.Clock
+	}
+
+	func (s *Service) Expired(deadline time.Time) bool {
+		return s.Clock.Now().After(deadline)
+	}
+
+Tests inject a Fake clock whose time is frozen until it is advanced; advancing
+the clock fires the timers, tickers, and sleepers that are due in order:
+
+	clock := clocks.NewFake(time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC))
+	go worker(clock) // calls clock.Sleep(time.Minute)
+
+	clock.BlockUntil(1)        // wait for the worker to sleep
+	clock.Advance(time.Minute) // wake the worker
+
+ParseOffset parses compound durations with weeks and days such as 1w2d12h, which
+time.ParseDuration does not support, into an Offset that adds the days to the
+calendar date so that they are correct across daylight savings transitions.
+
+The clock command in the parent directory is a reference consumer of the package.
+*/
+package clocks
+
+import "time"
+
+//===========================================================================
+// Clock Interface
+//===========================================================================
+
+// Clock provides the functionality of the time package that depends on the
+// current time so that it can be replaced by a Fake clock in tests.
+type Clock interface {
+	Now() time.Time                            // the current time
+	Since(t time.Time) time.Duration           // the time elapsed since t
+	Until(t time.Time) time.Duration           // the duration until t
+	Sleep(d time.Duration)                     // pause the current go routine for the duration
+	After(d time.Duration) <-chan time.Time    // sends the current time after the duration
+	NewTimer(d time.Duration) Timer            // a timer that fires once after the duration
+	NewTicker(d time.Duration) Ticker          // a ticker that fires every period
+	AfterFunc(d time.Duration, f func()) Timer // calls f in its own go routine after the duration
+}
+
+// Timer is the interface of a time.Timer, see the time package for details.
+// The channel of a timer created by AfterFunc is nil.
+type Timer interface {
+	C() <-chan time.Time        // receives the time when the timer fires
+	Stop() bool                 // prevents the timer from firing, false if it already fired or was stopped
+	Reset(d time.Duration) bool // changes the timer to fire after the duration, false if it was not active
+}
+
+// Ticker is the interface of a time.Ticker, see the time package for details.
+type Ticker interface {
+	C() <-chan time.Time   // receives the time of each tick
+	Stop()                 // turns off the ticker
+	Reset(d time.Duration) // stops the ticker and resets its period to the duration
+}
+
+//===========================================================================
+// Real Clock
+//===========================================================================
+
+// Real returns a Clock that is backed by the time package.
+func Real() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                            { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration           { return time.Since(t) }
+func (realClock) Until(t time.Time) time.Duration           { return time.Until(t) }
+func (realClock) Sleep(d time.Duration)                     { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time    { return time.After(d) }
+func (realClock) NewTimer(d time.Duration) Timer            { return realTimer{time.NewTimer(d)} }
+func (realClock) NewTicker(d time.Duration) Ticker          { return realTicker{time.NewTicker(d)} }
+func (realClock) AfterFunc(d time.Duration, f func()) Timer { return realTimer{time.AfterFunc(d, f)} }
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
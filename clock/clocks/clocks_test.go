@@ -0,0 +1,183 @@
+package clocks
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+// Test that both clocks satisfy the interface.
+var _ Clock = Real()
+var _ Clock = &Fake{}
+
+func TestFakeNow(t *testing.T) {
+	clock := NewFake(epoch)
+	if !clock.Now().Equal(epoch) {
+		t.Fatalf("expected clock to be frozen at %s got %s", epoch, clock.Now())
+	}
+
+	clock.Advance(time.Hour)
+	if since := clock.Since(epoch); since != time.Hour {
+		t.Errorf("expected an hour since epoch, got %s", since)
+	}
+
+	if until := clock.Until(epoch.Add(3 * time.Hour)); until != 2*time.Hour {
+		t.Errorf("expected two hours until deadline, got %s", until)
+	}
+
+	clock.Set(epoch)
+	if !clock.Now().Equal(epoch) {
+		t.Errorf("expected the clock to be set back to %s got %s", epoch, clock.Now())
+	}
+
+	if NewFake(time.Time{}).Now().IsZero() {
+		t.Error("expected a zero time to freeze the clock at the current time")
+	}
+}
+
+func TestFakeSleep(t *testing.T) {
+	clock := NewFake(epoch)
+	done := make(chan time.Time)
+	go func() {
+		clock.Sleep(time.Minute)
+		done <- clock.Now()
+	}()
+
+	clock.BlockUntil(1)
+	clock.Advance(59 * time.Second)
+	select {
+	case <-done:
+		t.Fatal("sleeper woke before the clock was advanced by the duration")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	clock.Advance(time.Second)
+	select {
+	case now := <-done:
+		if !now.Equal(epoch.Add(time.Minute)) {
+			t.Errorf("unexpected time after sleeping %s", now)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("sleeper did not wake when the clock was advanced")
+	}
+
+	if n := clock.Waiters(); n != 0 {
+		t.Errorf("expected no waiters, got %d", n)
+	}
+}
+
+func TestFakeTimer(t *testing.T) {
+	clock := NewFake(epoch)
+	early := clock.NewTimer(time.Second)
+	late := clock.NewTimer(2 * time.Second)
+	stopped := clock.NewTimer(time.Second)
+
+	if !stopped.Stop() || stopped.Stop() {
+		t.Error("expected only the first stop to stop the timer")
+	}
+
+	clock.Advance(time.Hour)
+	if ts := <-early.C(); !ts.Equal(epoch.Add(time.Second)) {
+		t.Errorf("expected the timer to fire at its deadline, got %s", ts)
+	}
+
+	if ts := <-late.C(); !ts.Equal(epoch.Add(2 * time.Second)) {
+		t.Errorf("expected the timer to fire at its deadline, got %s", ts)
+	}
+
+	select {
+	case <-stopped.C():
+		t.Error("stopped timer fired")
+	default:
+	}
+
+	if early.Reset(time.Minute) {
+		t.Error("expected reset of a fired timer to return false")
+	}
+
+	clock.Advance(time.Minute)
+	if ts := <-early.C(); !ts.Equal(epoch.Add(time.Hour + time.Minute)) {
+		t.Errorf("expected the reset timer to fire at its new deadline, got %s", ts)
+	}
+
+	// Timers with no duration fire immediately
+	select {
+	case <-clock.After(0):
+	default:
+		t.Error("expected a timer with no duration to fire immediately")
+	}
+}
+
+func TestFakeTicker(t *testing.T) {
+	clock := NewFake(epoch)
+	ticker := clock.NewTicker(time.Second)
+
+	for i := 1; i <= 3; i++ {
+		clock.Advance(time.Second)
+		if ts := <-ticker.C(); !ts.Equal(epoch.Add(time.Duration(i) * time.Second)) {
+			t.Errorf("unexpected tick %d at %s", i, ts)
+		}
+	}
+
+	// Ticks are dropped if the channel is not read
+	clock.Advance(10 * time.Second)
+	if ts := <-ticker.C(); !ts.Equal(epoch.Add(4 * time.Second)) {
+		t.Errorf("expected the first dropped tick to be kept, got %s", ts)
+	}
+
+	ticker.Reset(time.Minute)
+	clock.Advance(time.Minute)
+	<-ticker.C()
+
+	ticker.Stop()
+	clock.Advance(time.Hour)
+	select {
+	case <-ticker.C():
+		t.Error("stopped ticker ticked")
+	default:
+	}
+}
+
+func TestFakeAfterFunc(t *testing.T) {
+	clock := NewFake(epoch)
+	var calls int32
+	done := make(chan struct{})
+	timer := clock.AfterFunc(time.Second, func() {
+		atomic.AddInt32(&calls, 1)
+		close(done)
+	})
+
+	if timer.C() != nil {
+		t.Error("expected the channel of an after func timer to be nil")
+	}
+
+	clock.Advance(time.Second)
+	<-done
+
+	if timer.Stop() {
+		t.Error("expected stop to return false after the function was called")
+	}
+
+	clock.Advance(time.Hour)
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("expected the function to be called once, got %d", n)
+	}
+}
+
+func TestReal(t *testing.T) {
+	clock := Real()
+	start := clock.Now()
+	clock.Sleep(time.Millisecond)
+	if clock.Since(start) < time.Millisecond {
+		t.Error("expected real time to pass")
+	}
+
+	timer := clock.NewTimer(time.Millisecond)
+	<-timer.C()
+
+	ticker := clock.NewTicker(time.Millisecond)
+	<-ticker.C()
+	ticker.Stop()
+}
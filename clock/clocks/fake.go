@@ -0,0 +1,270 @@
+package clocks
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+//===========================================================================
+// Fake Clock
+//===========================================================================
+
+// Fake is a Clock whose time is frozen until it is advanced, so that tests can
+// control the passage of time deterministically. Advancing the clock fires the
+// timers, tickers, and sleepers that are due in the order of their deadlines,
+// with the time of the clock set to each deadline as it fires. Like a real
+// ticker, a fake ticker drops ticks if its channel is not read. A Fake clock is
+// safe for concurrent use.
+type Fake struct {
+	sync.Mutex
+	now     time.Time
+	waiters []*waiter  // active timers, tickers, and sleepers
+	changed *sync.Cond // signaled when a waiter is added or removed
+}
+
+// A timer, ticker, or sleeper waiting on a fake clock.
+type waiter struct {
+	clock    *Fake
+	deadline time.Time
+	period   time.Duration  // the period of a ticker, zero for a timer
+	c        chan time.Time // receives the time the waiter fires
+	fn       func()         // called instead of sending on c for AfterFunc
+}
+
+// NewFake creates a fake clock frozen at the specified time. If the time is
+// zero, the clock is frozen at the current time.
+func NewFake(now time.Time) *Fake {
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	f := &Fake{now: now}
+	f.changed = sync.NewCond(&f.Mutex)
+	return f
+}
+
+// Now returns the frozen time of the clock.
+func (f *Fake) Now() time.Time {
+	f.Lock()
+	defer f.Unlock()
+	return f.now
+}
+
+// Since returns the duration between t and the time of the clock.
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// Until returns the duration between the time of the clock and t.
+func (f *Fake) Until(t time.Time) time.Duration {
+	return t.Sub(f.Now())
+}
+
+// Sleep blocks until the clock is advanced by the duration.
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+// After returns a channel that receives the time of the clock once it has been
+// advanced by the duration.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// NewTimer creates a timer that fires once the clock has been advanced by the
+// duration; if the duration is not positive the timer fires immediately.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	f.Lock()
+	defer f.Unlock()
+
+	w := &waiter{clock: f, c: make(chan time.Time, 1)}
+	f.schedule(w, d)
+	return (*fakeTimer)(w)
+}
+
+// NewTicker creates a ticker that fires every time the clock is advanced by the
+// period. It panics if the period is not positive, like time.NewTicker.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+
+	f.Lock()
+	defer f.Unlock()
+
+	w := &waiter{clock: f, period: d, c: make(chan time.Time, 1)}
+	f.schedule(w, d)
+	return (*fakeTicker)(w)
+}
+
+// AfterFunc calls f in its own go routine once the clock has been advanced by
+// the duration. The channel of the returned timer is nil.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	f.Lock()
+	defer f.Unlock()
+
+	w := &waiter{clock: f, fn: fn}
+	f.schedule(w, d)
+	return (*fakeTimer)(w)
+}
+
+// Advance the time of the clock by the duration, firing the timers, tickers,
+// and sleepers whose deadlines are reached in the order of their deadlines.
+func (f *Fake) Advance(d time.Duration) {
+	f.Lock()
+	defer f.Unlock()
+	f.advance(f.now.Add(d))
+}
+
+// Set the time of the clock, firing the timers, tickers, and sleepers whose
+// deadlines are reached if the time moves forward. Moving the clock backward
+// does not fire anything.
+func (f *Fake) Set(t time.Time) {
+	f.Lock()
+	defer f.Unlock()
+
+	if t.Before(f.now) {
+		f.now = t
+		return
+	}
+	f.advance(t)
+}
+
+// BlockUntil blocks until at least n timers, tickers, and sleepers are waiting
+// on the clock, e.g. so that a test advances the clock only after the go
+// routine under test has started to sleep.
+func (f *Fake) BlockUntil(n int) {
+	f.Lock()
+	defer f.Unlock()
+	for len(f.waiters) < n {
+		f.changed.Wait()
+	}
+}
+
+// Waiters returns the number of timers, tickers, and sleepers waiting on the clock.
+func (f *Fake) Waiters() int {
+	f.Lock()
+	defer f.Unlock()
+	return len(f.waiters)
+}
+
+// Fires the waiters with deadlines up to the time then sets the clock to the
+// time (not thread-safe).
+func (f *Fake) advance(t time.Time) {
+	for len(f.waiters) > 0 && !f.waiters[0].deadline.After(t) {
+		w := f.waiters[0]
+		f.waiters = f.waiters[1:]
+		f.now = w.deadline
+		w.fire(f.now)
+
+		if w.period > 0 {
+			w.deadline = w.deadline.Add(w.period)
+			f.add(w)
+		}
+	}
+
+	f.now = t
+	f.changed.Broadcast()
+}
+
+// Schedules the waiter to fire after the duration, firing it immediately if the
+// duration is not positive (not thread-safe).
+func (f *Fake) schedule(w *waiter, d time.Duration) {
+	w.deadline = f.now.Add(d)
+	if d <= 0 && w.period == 0 {
+		w.fire(f.now)
+		return
+	}
+
+	f.add(w)
+	f.changed.Broadcast()
+}
+
+// Adds the waiter in the order of the deadlines; waiters with the same deadline
+// fire in the order they were added (not thread-safe).
+func (f *Fake) add(w *waiter) {
+	i := sort.Search(len(f.waiters), func(i int) bool {
+		return f.waiters[i].deadline.After(w.deadline)
+	})
+
+	f.waiters = append(f.waiters, nil)
+	copy(f.waiters[i+1:], f.waiters[i:])
+	f.waiters[i] = w
+}
+
+// Removes the waiter, returning false if it was not waiting (not thread-safe).
+func (f *Fake) remove(w *waiter) bool {
+	for i, other := range f.waiters {
+		if other == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			f.changed.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+// Sends the time on the channel of the waiter, dropping it if the channel is
+// full, or calls the function of the waiter in its own go routine.
+func (w *waiter) fire(t time.Time) {
+	if w.fn != nil {
+		go w.fn()
+		return
+	}
+
+	select {
+	case w.c <- t:
+	default:
+	}
+}
+
+//===========================================================================
+// Fake Timers and Tickers
+//===========================================================================
+
+type fakeTimer waiter
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.Lock()
+	defer t.clock.Unlock()
+	return t.clock.remove((*waiter)(t))
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.Lock()
+	defer t.clock.Unlock()
+
+	active := t.clock.remove((*waiter)(t))
+	t.clock.schedule((*waiter)(t), d)
+	return active
+}
+
+type fakeTicker waiter
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.Lock()
+	defer t.clock.Unlock()
+	t.clock.remove((*waiter)(t))
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("non-positive interval for Ticker.Reset")
+	}
+
+	t.clock.Lock()
+	defer t.clock.Unlock()
+
+	t.clock.remove((*waiter)(t))
+	t.period = d
+	t.clock.schedule((*waiter)(t), d)
+}
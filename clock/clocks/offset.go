@@ -0,0 +1,89 @@
+package clocks
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//===========================================================================
+// Duration Arithmetic
+//===========================================================================
+
+// Offset is a parsed duration that separates calendar components (weeks and
+// days) from clock components (hours, minutes, etc.) so that adding an offset
+// to a timestamp moves the calendar date correctly across DST transitions.
+type Offset struct {
+	Days     int
+	Duration time.Duration
+}
+
+// Add the offset to the specified timestamp.
+func (o Offset) Add(dt time.Time) time.Time {
+	return dt.AddDate(0, 0, o.Days).Add(o.Duration)
+}
+
+// matches a single component of a compound duration such as 2d or 30m
+var offsetComponent = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*(w|d|h|ms|us|µs|ns|m|s)`)
+
+// ParseOffset parses a compound duration such as 3h30m, 2d4h, or 1w into an
+// offset. In
+// addition to the units supported by time.ParseDuration, w (weeks) and d
+// (days) are supported as integer calendar offsets. Components may be
+// separated by whitespace and the entire duration may be negated with a
+// leading minus sign.
+func ParseOffset(s string) (o Offset, err error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return o, fmt.Errorf("no duration specified")
+	}
+
+	sign := 1
+	orig := s
+	switch s[0] {
+	case '-':
+		sign = -1
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	// Ensure that the components cover the entire duration string
+	matches := offsetComponent.FindAllStringSubmatchIndex(s, -1)
+	pos := 0
+	for _, m := range matches {
+		if strings.TrimSpace(s[pos:m[0]]) != "" {
+			return Offset{}, fmt.Errorf("could not parse duration %q", orig)
+		}
+		pos = m[1]
+
+		num, unit := s[m[2]:m[3]], s[m[4]:m[5]]
+		switch unit {
+		case "w", "d":
+			var n int
+			if n, err = strconv.Atoi(num); err != nil {
+				return Offset{}, fmt.Errorf("%s must be specified as a whole number in %q", unit, orig)
+			}
+			if unit == "w" {
+				n *= 7
+			}
+			o.Days += n
+		default:
+			var d time.Duration
+			if d, err = time.ParseDuration(num + unit); err != nil {
+				return Offset{}, fmt.Errorf("could not parse duration %q", orig)
+			}
+			o.Duration += d
+		}
+	}
+
+	if len(matches) == 0 || strings.TrimSpace(s[pos:]) != "" {
+		return Offset{}, fmt.Errorf("could not parse duration %q", orig)
+	}
+
+	o.Days *= sign
+	o.Duration *= time.Duration(sign)
+	return o, nil
+}
@@ -0,0 +1,82 @@
+package clocks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseOffset(t *testing.T) {
+	tests := []struct {
+		s        string
+		days     int
+		duration time.Duration
+		err      bool
+	}{
+		{"3h", 0, 3 * time.Hour, false},
+		{"3h30m", 0, 3*time.Hour + 30*time.Minute, false},
+		{"2d4h", 2, 4 * time.Hour, false},
+		{"1w", 7, 0, false},
+		{"1w2d", 9, 0, false},
+		{"2d 4h 30m", 2, 4*time.Hour + 30*time.Minute, false},
+		{"-1w", -7, 0, false},
+		{"-1d12h", -1, -12 * time.Hour, false},
+		{"+90m", 0, 90 * time.Minute, false},
+		{"1.5h", 0, 90 * time.Minute, false},
+		{"1s500ms", 0, 1500 * time.Millisecond, false},
+		{"10us5ns", 0, 10*time.Microsecond + 5*time.Nanosecond, false},
+		{" 5m ", 0, 5 * time.Minute, false},
+		{"", 0, 0, true},
+		{"-", 0, 0, true},
+		{"5", 0, 0, true},
+		{"1.5d", 0, 0, true},
+		{"1y", 0, 0, true},
+		{"3h foo", 0, 0, true},
+		{"foo 3h", 0, 0, true},
+		{"3h-30m", 0, 0, true},
+	}
+
+	for _, tc := range tests {
+		o, err := ParseOffset(tc.s)
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected an error parsing %q got %+v", tc.s, o)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("expected no error parsing %q got %s", tc.s, err)
+			continue
+		}
+
+		if o.Days != tc.days || o.Duration != tc.duration {
+			t.Errorf("expected %q to be %dd%s got %dd%s", tc.s, tc.days, tc.duration, o.Days, o.Duration)
+		}
+	}
+}
+
+func TestOffsetAdd(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone database is not available: %s", err)
+	}
+
+	// Clocks spring forward on March 10, 2024 in New York
+	before := time.Date(2024, time.March, 9, 9, 0, 0, 0, loc)
+
+	tests := []struct {
+		offset   Offset
+		expected time.Time
+	}{
+		{Offset{Days: 1}, time.Date(2024, time.March, 10, 9, 0, 0, 0, loc)},
+		{Offset{Duration: 24 * time.Hour}, time.Date(2024, time.March, 10, 10, 0, 0, 0, loc)},
+		{Offset{Days: 7, Duration: time.Hour}, time.Date(2024, time.March, 16, 10, 0, 0, 0, loc)},
+		{Offset{Days: -1, Duration: -time.Hour}, time.Date(2024, time.March, 8, 8, 0, 0, 0, loc)},
+	}
+
+	for _, tc := range tests {
+		if dt := tc.offset.Add(before); !dt.Equal(tc.expected) {
+			t.Errorf("expected %s plus %+v to be %s got %s", before, tc.offset, tc.expected, dt)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+//===========================================================================
+// User Configuration
+//===========================================================================
+
+// Formats defined by the user in the configuration file, loaded before the
+// command is run. Names are stored in lower case.
+var userFormats map[string]string
+
+// Config is the user configuration file, ~/.clockrc by default, which can be
+// written in either TOML or JSON. The formats map names to Go layouts so that
+// clock can be invoked with project-specific layouts by name.
+type Config struct {
+	Formats map[string]string `toml:"formats" json:"formats"`
+}
+
+// Returns the path to the configuration file, from $CLOCKRC or ~/.clockrc.
+func configPath() string {
+	if path := os.Getenv("CLOCKRC"); path != "" {
+		return path
+	}
+
+	usr, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(usr.HomeDir, ".clockrc")
+}
+
+// Load the user configuration file at path. A missing configuration file is
+// not an error. The file is parsed as JSON if it starts with a brace and as
+// TOML otherwise; every user format must be a valid layout.
+func loadConfig(path string) (conf *Config, err error) {
+	conf = &Config{}
+	if path == "" {
+		return conf, nil
+	}
+
+	var data []byte
+	if data, err = ioutil.ReadFile(path); err != nil {
+		if os.IsNotExist(err) {
+			return conf, nil
+		}
+		return nil, fmt.Errorf("could not read config: %v", err)
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		err = json.Unmarshal(data, conf)
+	} else {
+		err = toml.Unmarshal(data, conf)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not parse config %q: %v", path, err)
+	}
+
+	for name, layout := range conf.Formats {
+		if isStrftime(layout) {
+			if layout, err = strftime(layout); err != nil {
+				return nil, fmt.Errorf("format %q in %q: %v", name, path, err)
+			}
+			conf.Formats[name] = layout
+		}
+
+		if !validLayout(layout) {
+			return nil, fmt.Errorf("format %q in %q is not a valid layout: %q", name, path, layout)
+		}
+	}
+	return conf, nil
+}
+
+// Load the user formats from the configuration file.
+func loadUserFormats(path string) error {
+	conf, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	userFormats = make(map[string]string, len(conf.Formats))
+	for name, layout := range conf.Formats {
+		userFormats[strings.ToLower(name)] = layout
+	}
+	return nil
+}
+
+// Returns a description of the user formats ordered by name.
+func describeUserFormats() string {
+	names := make([]string, 0, len(userFormats))
+	for name := range userFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("- %s (%s)", name, userFormats[name]))
+	}
+	return strings.Join(lines, "\n")
+}
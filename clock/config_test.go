@@ -0,0 +1,127 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Writes the configuration file to a temporary directory and returns its path.
+func writeConfig(t *testing.T, name, data string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected map[string]string
+		err      string
+	}{
+		{"[formats]\nmylog = \"2006-01-02 15:04:05.000\"\n", map[string]string{"mylog": "2006-01-02 15:04:05.000"}, ""},
+		{`{"formats": {"mylog": "2006-01-02 15:04:05.000"}}`, map[string]string{"mylog": "2006-01-02 15:04:05.000"}, ""},
+		{"  \n{\"formats\": {\"day\": \"Monday\"}}", map[string]string{"day": "Monday"}, ""},
+		{"[formats]\niso = \"%Y-%m-%d\"\n", map[string]string{"iso": "2006-01-02"}, ""},
+		{"", nil, ""},
+		{"[formats]\nbad = \"%Q\"\n", nil, `format "bad"`},
+		{"[formats\n", nil, "could not parse config"},
+		{`{"formats": []}`, nil, "could not parse config"},
+	}
+
+	for _, tc := range tests {
+		conf, err := loadConfig(writeConfig(t, "clockrc", tc.data))
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected error containing %q loading %q got %v", tc.err, tc.data, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("expected no error loading %q got %s", tc.data, err)
+			continue
+		}
+
+		if len(conf.Formats) != len(tc.expected) {
+			t.Errorf("expected %d formats loading %q got %d", len(tc.expected), tc.data, len(conf.Formats))
+		}
+
+		for name, layout := range tc.expected {
+			if conf.Formats[name] != layout {
+				t.Errorf("expected format %q to be %q got %q", name, layout, conf.Formats[name])
+			}
+		}
+	}
+
+	// A missing or unspecified configuration file is not an error
+	for _, path := range []string{"", filepath.Join(t.TempDir(), "missing")} {
+		if conf, err := loadConfig(path); err != nil || len(conf.Formats) != 0 {
+			t.Errorf("expected an empty config for %q got %+v (%v)", path, conf, err)
+		}
+	}
+
+	if _, err := loadConfig(t.TempDir()); err == nil || !strings.Contains(err.Error(), "could not read config") {
+		t.Errorf("expected an error reading a directory as the config got %v", err)
+	}
+}
+
+func TestConfigPath(t *testing.T) {
+	t.Setenv("CLOCKRC", "/etc/clockrc")
+	if path := configPath(); path != "/etc/clockrc" {
+		t.Errorf("expected the config path from $CLOCKRC got %q", path)
+	}
+
+	t.Setenv("CLOCKRC", "")
+	if path := configPath(); filepath.Base(path) != ".clockrc" {
+		t.Errorf("expected the config in the home directory got %q", path)
+	}
+}
+
+func TestUserFormats(t *testing.T) {
+	t.Cleanup(func() { userFormats = nil })
+	freeze(t, frozen)
+
+	path := writeConfig(t, "clockrc", "[formats]\nMyLog = \"2006-01-02 15:04:05.000\"\nkitchen = \"15:04\"\nday = \"%A\"\n")
+	if err := loadUserFormats(path); err != nil {
+		t.Fatalf("expected no error loading user formats got %s", err)
+	}
+
+	expected := "- day (Monday)\n- kitchen (15:04)\n- mylog (2006-01-02 15:04:05.000)"
+	if desc := describeUserFormats(); desc != expected {
+		t.Errorf("expected user formats sorted by lower case name got %q", desc)
+	}
+
+	t.Setenv("CLOCKRC", path)
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--utc", "mylog"}, "2024-05-02 14:30:00.000"},
+		{[]string{"--utc", "MYLOG"}, "2024-05-02 14:30:00.000"},
+		{[]string{"--utc", "day"}, "Thursday"},
+		{[]string{"--utc", "kitchen"}, "2:30PM"},
+		{[]string{"--utc", "after", "-f", "mylog", "1h"}, "2024-05-02 15:30:00.000"},
+	}
+
+	for _, tc := range tests {
+		out, err := run(t, tc.args...)
+		if err != nil {
+			t.Errorf("expected no error running %q got %s", tc.args, err)
+			continue
+		}
+
+		if out != tc.expected {
+			t.Errorf("expected %q running %q got %q", tc.expected, tc.args, out)
+		}
+	}
+
+	// An invalid configuration file stops the command
+	t.Setenv("CLOCKRC", writeConfig(t, "invalid", "[formats]\nbad = \"%Q\"\n"))
+	if out, err := run(t, "--utc"); err == nil {
+		t.Errorf("expected an error with an invalid config got %q", out)
+	}
+}
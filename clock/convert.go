@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+//===========================================================================
+// Timezone Conversion
+//===========================================================================
+
+// The default layout of the timestamps in the conversion table.
+const convertLayout = "Mon 2006-01-02 15:04 MST"
+
+func convert(c *cli.Context) (err error) {
+	// The source timezone defaults to the timezone from the global flags
+	from := c.String("from")
+	if from == "" {
+		var loc *time.Location
+		if loc, err = location(c); err != nil {
+			return cli.Exit(err, 1)
+		}
+		from = loc.String()
+	}
+
+	var src *time.Location
+	if src, err = time.LoadLocation(from); err != nil {
+		return cli.Exit(fmt.Errorf("cannot parse location %q", from), 1)
+	}
+
+	// Parse the instant in the source timezone or use now if not specified
+	dt := clk.Now().In(src)
+	if arg := strings.TrimSpace(strings.Join(c.Args().Slice(), " ")); arg != "" {
+		if dt, err = parseDatetime(arg, src.String(), false, false); err != nil {
+			return cli.Exit(err, 1)
+		}
+	}
+
+	layout := convertLayout
+	if c.String("format") != "" {
+		if layout, err = parseLayout(c.String("format")); err != nil {
+			return cli.Exit(err, 1)
+		}
+	}
+
+	var lang *Locale
+	if lang, err = loadLocale(c); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	zones := []*time.Location{src}
+	var names []string
+	for _, to := range c.StringSlice("to") {
+		names = append(names, strings.Split(to, ",")...)
+	}
+
+	for _, name := range names {
+		var loc *time.Location
+		if loc, err = time.LoadLocation(strings.TrimSpace(name)); err != nil {
+			return cli.Exit(fmt.Errorf("cannot parse location %q", name), 1)
+		}
+		zones = append(zones, loc)
+	}
+
+	if len(zones) == 1 {
+		return cli.Exit("specify one or more timezones to convert to with --to", 1)
+	}
+
+	buf := new(bytes.Buffer)
+	tw := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ZONE\tTIME\tOFFSET")
+	for _, loc := range zones {
+		ts := dt.In(loc)
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", loc, lang.Format(ts, layout), ts.Format("-07:00"))
+	}
+	tw.Flush()
+
+	return output(c, strings.TrimSuffix(buf.String(), "\n"))
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	freeze(t, frozen)
+
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{
+			[]string{"--utc", "convert", "--to", "America/New_York,Asia/Tokyo"},
+			[]string{
+				"ZONE TIME OFFSET",
+				"UTC Thu 2024-05-02 14:30 UTC +00:00",
+				"America/New_York Thu 2024-05-02 10:30 EDT -04:00",
+				"Asia/Tokyo Thu 2024-05-02 23:30 JST +09:00",
+			},
+		},
+		{
+			[]string{"convert", "--from", "America/New_York", "--to", "Europe/Berlin", "--to", "Asia/Tokyo", "2024-05-01 14:00"},
+			[]string{
+				"ZONE TIME OFFSET",
+				"America/New_York Wed 2024-05-01 14:00 EDT -04:00",
+				"Europe/Berlin Wed 2024-05-01 20:00 CEST +02:00",
+				"Asia/Tokyo Thu 2024-05-02 03:00 JST +09:00",
+			},
+		},
+		{
+			// Standard time in both zones is a different offset than in May
+			[]string{"--tz", "Europe/London", "convert", "-T", "America/New_York", "-f", "kitchen", "2024-01-15 09:00"},
+			[]string{
+				"ZONE TIME OFFSET",
+				"Europe/London 9:00AM +00:00",
+				"America/New_York 4:00AM -05:00",
+			},
+		},
+		{
+			[]string{"--utc", "--locale", "de", "convert", "--to", "Europe/Berlin", "-f", "Monday 2 January"},
+			[]string{
+				"ZONE TIME OFFSET",
+				"UTC Donnerstag 2 Mai +00:00",
+				"Europe/Berlin Donnerstag 2 Mai +02:00",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		out, err := run(t, tc.args...)
+		if err != nil {
+			t.Errorf("expected no error running %q got %s", tc.args, err)
+			continue
+		}
+
+		// Compare the rows of the table without the column padding
+		lines := strings.Split(out, "\n")
+		for i, line := range lines {
+			lines[i] = strings.Join(strings.Fields(line), " ")
+		}
+
+		if strings.Join(lines, "\n") != strings.Join(tc.expected, "\n") {
+			t.Errorf("unexpected table running %q:\n%s", tc.args, out)
+		}
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	freeze(t, frozen)
+
+	tests := [][]string{
+		{"--utc", "convert"},
+		{"--utc", "convert", "2024-05-01 14:00"},
+		{"--utc", "convert", "--to", "Mars/Olympus_Mons"},
+		{"convert", "--from", "Mars/Olympus_Mons", "--to", "Asia/Tokyo"},
+		{"--tz", "Mars/Olympus_Mons", "convert", "--to", "Asia/Tokyo"},
+		{"--utc", "convert", "--to", "Asia/Tokyo", "May 1st"},
+		{"--utc", "--locale", "??", "convert", "--to", "Asia/Tokyo"},
+	}
+
+	for _, args := range tests {
+		if out, err := run(t, args...); err == nil {
+			t.Errorf("expected an error running %q got %q", args, out)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/bbengfort/x/clock/clocks"
+	cli "github.com/urfave/cli/v2"
+)
+
+//===========================================================================
+// Countdown Timer
+//===========================================================================
+
+func countdown(c *cli.Context) (err error) {
+	arg := strings.TrimSpace(strings.Join(c.Args().Slice(), " "))
+	if arg == "" {
+		return cli.Exit("specify a datetime or duration to count down to", 1)
+	}
+
+	// Durations are relative to the start of each countdown, datetimes are fixed
+	var target func(time.Time) time.Time
+	if offset, perr := clocks.ParseOffset(arg); perr == nil {
+		target = offset.Add
+	} else {
+		var dt time.Time
+		if dt, err = parseDatetime(arg, c.String("tz"), c.Bool("local"), c.Bool("utc")); err != nil {
+			return cli.Exit(fmt.Errorf("could not parse %q as a duration or datetime", arg), 1)
+		}
+
+		if c.Int("repeat") != 0 {
+			return cli.Exit("only duration countdowns can be repeated", 1)
+		}
+		target = func(time.Time) time.Time { return dt }
+	}
+
+	interval := c.Duration("interval")
+	if interval <= 0 {
+		return cli.Exit("interval must be greater than zero", 1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	for i := 0; c.Int("repeat") < 0 || i <= c.Int("repeat"); i++ {
+		if err = tick(ctx, target(clk.Now()), interval, c.Bool("noline")); err != nil {
+			fmt.Println()
+			return cli.Exit("countdown canceled", 1)
+		}
+
+		if err = complete(c.String("exec")); err != nil {
+			return cli.Exit(err, 1)
+		}
+	}
+	return nil
+}
+
+// Live updates the remaining time on the terminal every interval until the
+// deadline is reached or the context is canceled.
+func tick(ctx context.Context, deadline time.Time, interval time.Duration, noline bool) error {
+	ticker := clk.NewTicker(interval)
+	defer ticker.Stop()
+
+	timer := clk.NewTimer(clk.Until(deadline))
+	defer timer.Stop()
+
+	for {
+		// Carriage return and clear the line to update the countdown in place
+		fmt.Printf("\r\033[K%s", remaining(clk.Until(deadline)))
+
+		select {
+		case <-ticker.C():
+		case <-timer.C():
+			fmt.Printf("\r\033[K%s", remaining(0))
+			if !noline {
+				fmt.Println()
+			}
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Runs the command with the shell when the countdown completes, otherwise rings
+// the terminal bell.
+func complete(command string) error {
+	if command == "" {
+		fmt.Print("\a")
+		return nil
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("could not execute %q: %s", command, err)
+	}
+	return nil
+}
+
+// Formats the remaining duration as a clock, rounding up to the nearest second
+// so that the countdown only shows zero when it is complete.
+func remaining(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+
+	secs := int64((d + time.Second - 1) / time.Second)
+	days, secs := secs/86400, secs%86400
+	hours, secs := secs/3600, secs%3600
+	mins, secs := secs/60, secs%60
+
+	if days > 0 {
+		return fmt.Sprintf("%dd %02d:%02d:%02d", days, hours, mins, secs)
+	}
+	return fmt.Sprintf("%02d:%02d:%02d", hours, mins, secs)
+}
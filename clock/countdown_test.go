@@ -0,0 +1,113 @@
+package main
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bbengfort/x/clock/clocks"
+)
+
+func TestRemaining(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		expected string
+	}{
+		{0, "00:00:00"},
+		{-time.Minute, "00:00:00"},
+		{time.Nanosecond, "00:00:01"},
+		{time.Second, "00:00:01"},
+		{1500 * time.Millisecond, "00:00:02"},
+		{25 * time.Minute, "00:25:00"},
+		{23*time.Hour + 59*time.Minute + 59*time.Second, "23:59:59"},
+		{24 * time.Hour, "1d 00:00:00"},
+		{50*time.Hour + 3*time.Minute + 4*time.Second, "2d 02:03:04"},
+	}
+
+	for _, tc := range tests {
+		if actual := remaining(tc.d); actual != tc.expected {
+			t.Errorf("expected %s remaining to be %q got %q", tc.d, tc.expected, actual)
+		}
+	}
+}
+
+// Advances the fake clock by a second every millisecond, starting when the first
+// timer is waiting on it, until the test is done so that countdowns and alarms
+// complete without waiting in real time.
+func tickTock(t *testing.T, fake *clocks.Fake) {
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+
+	go func() {
+		fake.BlockUntil(1)
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+				fake.Advance(time.Second)
+			}
+		}
+	}()
+}
+
+func TestCountdown(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires a posix shell")
+	}
+
+	tickTock(t, freeze(t, frozen))
+
+	tests := []struct {
+		args   []string
+		suffix string
+		count  int
+	}{
+		{[]string{"--utc", "countdown", "2024-05-02 14:31"}, "\r\033[K00:00:00\n\a", 1},
+		{[]string{"countdown", "3s"}, "\r\033[K00:00:00\n\a", 1},
+		{[]string{"--noline", "countdown", "3s"}, "\r\033[K00:00:00\a", 1},
+		{[]string{"countdown", "--exec", "echo done", "2s"}, "00:00:00\ndone\n", 1},
+		{[]string{"countdown", "--exec", "echo done", "--repeat", "2", "2s"}, "00:00:00\ndone\n", 3},
+	}
+
+	for _, tc := range tests {
+		out, err := run(t, tc.args...)
+		if err != nil {
+			t.Errorf("expected no error running %q got %s", tc.args, err)
+			continue
+		}
+
+		// The output is compared with the trailing newline that run trims
+		if tc.suffix[len(tc.suffix)-1] == '\n' {
+			out += "\n"
+		}
+
+		if !strings.HasSuffix(out, tc.suffix) {
+			t.Errorf("expected output of %q to end with %q got %q", tc.args, tc.suffix, out)
+		}
+
+		if n := strings.Count(out, "00:00:00"); n != tc.count {
+			t.Errorf("expected %q to complete %d times got %d", tc.args, tc.count, n)
+		}
+	}
+}
+
+func TestCountdownErrors(t *testing.T) {
+	tickTock(t, freeze(t, frozen))
+
+	tests := [][]string{
+		{"countdown"},
+		{"countdown", "soon"},
+		{"countdown", "--interval", "0s", "5m"},
+		{"countdown", "--interval", "-1s", "5m"},
+		{"--utc", "countdown", "--repeat", "1", "2024-05-03"},
+		{"countdown", "--exec", "exit 3", "1ns"},
+	}
+
+	for _, args := range tests {
+		if out, err := run(t, args...); err == nil {
+			t.Errorf("expected an error running %q got %q", args, out)
+		}
+	}
+}
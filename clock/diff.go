@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	cli "github.com/urfave/cli/v2"
+)
+
+//===========================================================================
+// Datetime Differences
+//===========================================================================
+
+// The built-in named formats that timestamps are parsed with by diff.
+var namedLayouts = []string{
+	"rfc3339nano", "code", "blog", "ansic", "ruby", "unix", "rfc822", "rfc822z",
+	"rfc850", "rfc1123", "rfc1123z", "date", "stampnano", "kitchen",
+}
+
+func diff(c *cli.Context) (err error) {
+	if c.NArg() != 2 {
+		return cli.Exit("specify two datetimes to compute the difference between", 1)
+	}
+
+	var loc *time.Location
+	if loc, err = location(c); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	var t1, t2 time.Time
+	if t1, err = parseAny(c.Args().Get(0), loc); err != nil {
+		return cli.Exit(err, 1)
+	}
+	if t2, err = parseAny(c.Args().Get(1), loc); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	delta := t2.Sub(t1)
+	if c.Bool("abs") && delta < 0 {
+		delta = -delta
+	}
+
+	if c.Bool("seconds") {
+		return output(c, strconv.FormatFloat(delta.Seconds(), 'f', -1, 64))
+	}
+
+	// Humanize the magnitude and describe the direction unless --abs is set
+	human := strings.TrimSpace(humanize.RelTime(t1, t2, "", ""))
+	if !c.Bool("abs") && delta != 0 {
+		human = strings.TrimSpace(humanize.RelTime(t2, t1, "earlier", "later"))
+	}
+	return output(c, fmt.Sprintf("%s (%s)", delta, human))
+}
+
+// Parse a timestamp in any of the supported layouts: the datetime formats
+// accepted by until, the built-in and user named formats, or a unix timestamp.
+// Timestamps without a timezone are parsed in the specified location.
+func parseAny(s string, loc *time.Location) (dt time.Time, err error) {
+	s = strings.TrimSpace(s)
+	if dt, err = parseDatetime(s, loc.String(), false, false); err == nil {
+		return dt, nil
+	}
+
+	names := append([]string{}, namedLayouts...)
+	for name := range userFormats {
+		names = append(names, name)
+	}
+
+	for _, name := range names {
+		var layout string
+		if layout, err = parseLayout(name); err != nil {
+			continue
+		}
+
+		if dt, err = time.ParseInLocation(layout, s, loc); err == nil {
+			return dt, nil
+		}
+	}
+
+	if dt, err = parseEpoch(s, Auto); err == nil {
+		return dt.In(loc), nil
+	}
+	return time.Time{}, fmt.Errorf("could not parse %q into a datetime", s)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAny(t *testing.T) {
+	t.Cleanup(func() { userFormats = nil })
+	freeze(t, frozen)
+
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone database is not available: %s", err)
+	}
+
+	userFormats = map[string]string{"mylog": "2006/01/02 15h04"}
+	expected := time.Date(2024, time.May, 1, 9, 0, 0, 0, loc)
+
+	tests := []struct {
+		s        string
+		expected time.Time
+		err      bool
+	}{
+		{"2024-05-01 09:00", expected, false},
+		{"2024-05-01 09:00:00", expected, false},
+		{"2024-05-01", expected.Add(-9 * time.Hour), false},
+		{"2024-05-01T13:00:00Z", expected, false},
+		{"2024-05-01T09:00:00.000000001-04:00", expected.Add(time.Nanosecond), false},
+		{"Wed May  1 09:00:00 2024", expected, false},
+		{"Wed May 01 09:00:00 -0400 2024", expected, false},
+		{"01 May 24 09:00 EDT", expected, false},
+		{"Wed, 01 May 2024 13:00:00 GMT", expected, false},
+		{"May 01, 2024", expected.Add(-9 * time.Hour), false},
+		{"2024/05/01 09h00", expected, false},
+		{"1714568400", expected, false},
+		{"1714568400000", expected, false},
+		{" 2024-05-01 09:00 ", expected, false},
+		{"", time.Time{}, true},
+		{"yesterday", time.Time{}, true},
+		{"2024-13-01", time.Time{}, true},
+	}
+
+	for _, tc := range tests {
+		dt, err := parseAny(tc.s, loc)
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected an error parsing %q got %s", tc.s, dt)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("expected no error parsing %q got %s", tc.s, err)
+			continue
+		}
+
+		if !dt.Equal(tc.expected) {
+			t.Errorf("expected %q to be %s got %s", tc.s, tc.expected, dt)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	freeze(t, frozen)
+
+	tests := []struct {
+		args     []string
+		expected string
+		err      bool
+	}{
+		{[]string{"--utc", "diff", "2024-05-01 09:00", "2024-05-02 11:30"}, "26h30m0s (1 day later)", false},
+		{[]string{"--utc", "diff", "2024-05-02 11:30", "2024-05-01 09:00"}, "-26h30m0s (1 day earlier)", false},
+		{[]string{"--utc", "diff", "--abs", "2024-05-02 11:30", "2024-05-01 09:00"}, "26h30m0s (1 day)", false},
+		{[]string{"--utc", "diff", "2024-05-01 09:00", "2024-05-01 09:00"}, "0s (now)", false},
+		{[]string{"--utc", "diff", "--seconds", "2024-05-01 09:00", "2024-05-01 09:01:30"}, "90", false},
+		{[]string{"--utc", "diff", "-s", "2024-05-01 09:01:30", "2024-05-01 09:00"}, "-90", false},
+		{[]string{"--utc", "diff", "-s", "-a", "1714660200.5", "1714660200"}, "0.5", false},
+		{[]string{"--tz", "America/New_York", "diff", "-s", "2024-05-01 09:00", "2024-05-01T13:00:00Z"}, "0", false},
+		{[]string{"--tz", "America/New_York", "diff", "2024-03-09 12:00", "2024-03-10 12:00"}, "23h0m0s (23 hours later)", false},
+		{[]string{"--utc", "diff", "2024-05-01"}, "", true},
+		{[]string{"--utc", "diff", "2024-05-01", "2024-05-02", "2024-05-03"}, "", true},
+		{[]string{"--utc", "diff", "2024-05-01", "tomorrow"}, "", true},
+		{[]string{"--tz", "Mars/Olympus_Mons", "diff", "2024-05-01", "2024-05-02"}, "", true},
+	}
+
+	for _, tc := range tests {
+		out, err := run(t, tc.args...)
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected an error running %q got %q", tc.args, out)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("expected no error running %q got %s", tc.args, err)
+			continue
+		}
+
+		if out != tc.expected {
+			t.Errorf("expected %q running %q got %q", tc.expected, tc.args, out)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestAfter(t *testing.T) {
+	freeze(t, frozen)
+
+	tests := []struct {
+		args     []string
+		expected string
+		err      bool
+	}{
+		{[]string{"--utc", "after", "3h30m"}, "2024-05-02T18:00:00Z", false},
+		{[]string{"--utc", "after", "2d4h"}, "2024-05-04T18:30:00Z", false},
+		{[]string{"--utc", "after", "--", "-1w"}, "2024-04-25T14:30:00Z", false},
+		{[]string{"--utc", "after", "-f", "date", "--from", "2024-02-28", "1d"}, "February 29, 2024", false},
+		{[]string{"--tz", "America/New_York", "after", "-f", "2006-01-02 15:04 MST", "--from", "2024-03-09 09:00", "1d"}, "2024-03-10 09:00 EDT", false},
+		{[]string{"--utc", "after", "1y"}, "", true},
+		{[]string{"--utc", "after", "--from", "yesterday", "1d"}, "", true},
+	}
+
+	for _, tc := range tests {
+		out, err := run(t, tc.args...)
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected an error running %q got %q", tc.args, out)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("expected no error running %q got %s", tc.args, err)
+			continue
+		}
+
+		if out != tc.expected {
+			t.Errorf("expected %q running %q got %q", tc.expected, tc.args, out)
+		}
+	}
+}
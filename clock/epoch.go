@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+//===========================================================================
+// Unix Timestamps
+//===========================================================================
+
+// EpochUnit specifies the precision of a unix timestamp.
+type EpochUnit uint8
+
+// Units of unix timestamps; Auto infers the unit from the magnitude of the value.
+const (
+	Auto EpochUnit = iota
+	Seconds
+	Millis
+	Micros
+	Nanos
+)
+
+// Timestamp returns the unix timestamp of the time in the specified unit.
+func (u EpochUnit) Timestamp(dt time.Time) int64 {
+	switch u {
+	case Millis:
+		return dt.UnixNano() / int64(time.Millisecond)
+	case Micros:
+		return dt.UnixNano() / int64(time.Microsecond)
+	case Nanos:
+		return dt.UnixNano()
+	default:
+		return dt.Unix()
+	}
+}
+
+// Time returns the time of the unix timestamp in the specified unit.
+func (u EpochUnit) Time(ts int64) time.Time {
+	switch u {
+	case Millis:
+		return time.Unix(0, ts*int64(time.Millisecond))
+	case Micros:
+		return time.Unix(0, ts*int64(time.Microsecond))
+	case Nanos:
+		return time.Unix(0, ts)
+	default:
+		return time.Unix(ts, 0)
+	}
+}
+
+// Parse a unix timestamp in the specified unit. Fractional seconds are allowed
+// when the unit is seconds (or inferred). If the unit is Auto, the unit is
+// inferred by the magnitude of the timestamp: values less than 1e11 are
+// seconds (until the year 5138), less than 1e14 are milliseconds, less than
+// 1e17 are microseconds, and anything greater is nanoseconds.
+func parseEpoch(s string, unit EpochUnit) (dt time.Time, err error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return dt, fmt.Errorf("no timestamp specified")
+	}
+
+	if (unit == Auto || unit == Seconds) && strings.Contains(s, ".") {
+		var secs float64
+		if secs, err = strconv.ParseFloat(s, 64); err != nil {
+			return dt, fmt.Errorf("could not parse timestamp %q", s)
+		}
+		whole := int64(secs)
+		return time.Unix(whole, int64((secs-float64(whole))*float64(time.Second))), nil
+	}
+
+	var ts int64
+	if ts, err = strconv.ParseInt(s, 10, 64); err != nil {
+		return dt, fmt.Errorf("could not parse timestamp %q", s)
+	}
+
+	if unit == Auto {
+		mag := ts
+		if mag < 0 {
+			mag = -mag
+		}
+
+		switch {
+		case mag < 1e11:
+			unit = Seconds
+		case mag < 1e14:
+			unit = Millis
+		case mag < 1e17:
+			unit = Micros
+		default:
+			unit = Nanos
+		}
+	}
+	return unit.Time(ts), nil
+}
+
+// Returns the flags used to specify the unit of a unix timestamp.
+func epochFlags(verb string) []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:    "seconds",
+			Aliases: []string{"s"},
+			Usage:   verb + " the timestamp in seconds",
+		},
+		&cli.BoolFlag{
+			Name:    "millis",
+			Aliases: []string{"m"},
+			Usage:   verb + " the timestamp in milliseconds",
+		},
+		&cli.BoolFlag{
+			Name:    "micros",
+			Aliases: []string{"U"},
+			Usage:   verb + " the timestamp in microseconds",
+		},
+		&cli.BoolFlag{
+			Name:    "nanos",
+			Aliases: []string{"N"},
+			Usage:   verb + " the timestamp in nanoseconds",
+		},
+	}
+}
+
+// Get the unit specified by the epoch flags, ensuring only one is specified.
+func epochUnit(c *cli.Context) (unit EpochUnit, err error) {
+	for flag, u := range map[string]EpochUnit{"seconds": Seconds, "millis": Millis, "micros": Micros, "nanos": Nanos} {
+		if c.Bool(flag) {
+			if unit != Auto {
+				return Auto, fmt.Errorf("specify only one of --seconds, --millis, --micros, or --nanos")
+			}
+			unit = u
+		}
+	}
+	return unit, nil
+}
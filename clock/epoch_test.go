@@ -0,0 +1,126 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseEpoch(t *testing.T) {
+	ts := time.Date(2024, 5, 2, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		s        string
+		unit     EpochUnit
+		expected time.Time
+		err      bool
+	}{
+		{"1714660200", Auto, ts, false},
+		{"1714660200000", Auto, ts, false},
+		{"1714660200000000", Auto, ts, false},
+		{"1714660200000000000", Auto, ts, false},
+		{"1714660200.25", Auto, ts.Add(250 * time.Millisecond), false},
+		{" 1714660200 ", Auto, ts, false},
+		{"-86400", Auto, time.Unix(-86400, 0), false},
+		{"0", Auto, time.Unix(0, 0), false},
+		{"99999999999", Auto, time.Unix(99999999999, 0), false},
+		{"100000000000", Auto, time.Unix(100000000, 0), false},
+		{"1714660200", Millis, time.Unix(1714660, 200000000), false},
+		{"1714660200", Micros, time.Unix(1714, 660200000), false},
+		{"1714660200", Nanos, time.Unix(1, 714660200), false},
+		{"1714660200", Seconds, ts, false},
+		{"1714660200.5", Seconds, ts.Add(500 * time.Millisecond), false},
+		{"1714660200.5", Millis, time.Time{}, true},
+		{"", Auto, time.Time{}, true},
+		{"yesterday", Auto, time.Time{}, true},
+		{"1e9", Auto, time.Time{}, true},
+	}
+
+	for _, tc := range tests {
+		dt, err := parseEpoch(tc.s, tc.unit)
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected an error parsing %q got %s", tc.s, dt)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("expected no error parsing %q got %s", tc.s, err)
+			continue
+		}
+
+		if !dt.Equal(tc.expected) {
+			t.Errorf("expected %q to be %s got %s", tc.s, tc.expected.UTC(), dt.UTC())
+		}
+	}
+}
+
+func TestEpochUnit(t *testing.T) {
+	ts := time.Date(2024, 5, 2, 14, 30, 0, 123456789, time.UTC)
+
+	tests := []struct {
+		unit     EpochUnit
+		expected int64
+	}{
+		{Auto, 1714660200},
+		{Seconds, 1714660200},
+		{Millis, 1714660200123},
+		{Micros, 1714660200123456},
+		{Nanos, 1714660200123456789},
+	}
+
+	for _, tc := range tests {
+		if actual := tc.unit.Timestamp(ts); actual != tc.expected {
+			t.Errorf("expected timestamp %d in unit %d got %d", tc.expected, tc.unit, actual)
+		}
+
+		// Round trip the timestamp truncated to the precision of the unit
+		if tc.unit != Auto {
+			if rt := tc.unit.Time(tc.expected); rt.UnixNano() > ts.UnixNano() || ts.Sub(rt) >= time.Second {
+				t.Errorf("expected timestamp %d in unit %d to be %s got %s", tc.expected, tc.unit, ts, rt.UTC())
+			}
+		}
+	}
+}
+
+func TestEpochCommands(t *testing.T) {
+	freeze(t, frozen.Add(123456789*time.Nanosecond))
+
+	tests := []struct {
+		args     []string
+		expected string
+		err      bool
+	}{
+		{[]string{"epoch"}, "1714660200", false},
+		{[]string{"epoch", "--seconds"}, "1714660200", false},
+		{[]string{"epoch", "--millis"}, "1714660200123", false},
+		{[]string{"epoch", "-U"}, "1714660200123456", false},
+		{[]string{"epoch", "--nanos"}, "1714660200123456789", false},
+		{[]string{"epoch", "--millis", "--nanos"}, "", true},
+		{[]string{"--utc", "parse", "1714660200"}, "2024-05-02T14:30:00Z", false},
+		{[]string{"--utc", "parse", "-f", "kitchen", "1714660200000"}, "2:30PM", false},
+		{[]string{"--tz", "Asia/Tokyo", "parse", "-f", "2006-01-02 15:04 MST", "1714660200"}, "2024-05-02 23:30 JST", false},
+		{[]string{"--utc", "parse", "--millis", "1714660200"}, "1970-01-20T20:17:40Z", false},
+		{[]string{"--utc", "parse", "now"}, "", true},
+		{[]string{"--utc", "parse", "-s", "-m", "1714660200"}, "", true},
+	}
+
+	for _, tc := range tests {
+		out, err := run(t, tc.args...)
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected an error running %q got %q", tc.args, out)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("expected no error running %q got %s", tc.args, err)
+			continue
+		}
+
+		if out != tc.expected {
+			t.Errorf("expected %q running %q got %q", tc.expected, tc.args, out)
+		}
+	}
+}
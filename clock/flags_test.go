@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+func TestDayPatterns(t *testing.T) {
+	tests := []struct {
+		s        string
+		business string
+		calendar string
+	}{
+		{"5bd", "5", "5"},
+		{"-2bd", "-2", "-2"},
+		{"+3 bd", "+3", "+3"},
+		{"5", "", "5"},
+		{"5d", "", "5"},
+		{"-10d", "", "-10"},
+		{"5h", "", ""},
+		{"1w", "", ""},
+		{"2d4h", "", ""},
+		{"bd", "", ""},
+		{"1.5bd", "", ""},
+	}
+
+	for _, tc := range tests {
+		for _, pattern := range []struct {
+			name     string
+			expected string
+		}{{"business", tc.business}, {"calendar", tc.calendar}} {
+			re := businessDays
+			if pattern.name == "calendar" {
+				re = calendarDays
+			}
+
+			var actual string
+			if match := re.FindStringSubmatch(tc.s); match != nil {
+				actual = match[1]
+			}
+
+			if actual != pattern.expected {
+				t.Errorf("expected %s days pattern to match %q in %q got %q", pattern.name, pattern.expected, tc.s, actual)
+			}
+		}
+	}
+}
+
+func TestBusinessCalendar(t *testing.T) {
+	thanksgiving := date(2024, time.November, 28)
+
+	tests := []struct {
+		holidays     string
+		skipWeekends bool
+		holiday      bool
+	}{
+		{"us", false, true},
+		{"none", false, false},
+		{"us", true, false},
+		{"none", true, false},
+	}
+
+	for _, tc := range tests {
+		set := flag.NewFlagSet("test", flag.ContinueOnError)
+		set.String("holidays", tc.holidays, "")
+		set.Bool("skip-weekends", tc.skipWeekends, "")
+
+		cal, err := businessCalendar(cli.NewContext(cli.NewApp(), set, nil))
+		if err != nil {
+			t.Errorf("expected no error loading %q calendar got %s", tc.holidays, err)
+			continue
+		}
+
+		if _, ok := cal.Holiday(thanksgiving); ok != tc.holiday {
+			t.Errorf("expected thanksgiving holiday %t with %q calendar and skip weekends %t got %t", tc.holiday, tc.holidays, tc.skipWeekends, ok)
+		}
+	}
+
+	// The holiday calendar is not loaded when only weekends are skipped
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	set.String("holidays", "missing.json", "")
+	set.Bool("skip-weekends", true, "")
+	if _, err := businessCalendar(cli.NewContext(cli.NewApp(), set, nil)); err != nil {
+		t.Errorf("expected the holiday calendar to be ignored got %s", err)
+	}
+}
+
+func TestBusinessDayFlags(t *testing.T) {
+	// The Friday before Thanksgiving week
+	freeze(t, time.Date(2024, time.November, 22, 12, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		args     []string
+		expected string
+		err      bool
+	}{
+		{[]string{"--utc", "after", "-f", "2006-01-02", "-b", "5"}, "2024-12-02", false},
+		{[]string{"--utc", "after", "-f", "2006-01-02", "--business-days", "5d"}, "2024-12-02", false},
+		{[]string{"--utc", "after", "-f", "2006-01-02", "-b", "5bd"}, "2024-12-02", false},
+		{[]string{"--utc", "after", "-f", "2006-01-02", "-w", "5"}, "2024-11-29", false},
+		{[]string{"--utc", "after", "-f", "2006-01-02", "-b", "-w", "5"}, "2024-11-29", false},
+		{[]string{"--utc", "after", "-f", "2006-01-02", "-b", "--", "-1"}, "2024-11-21", false},
+		{[]string{"--utc", "after", "-f", "2006-01-02", "5d"}, "2024-11-27", false},
+		{[]string{"--utc", "after", "-b", "5h"}, "", true},
+		{[]string{"--utc", "after", "-w", "1w"}, "", true},
+		{[]string{"--utc", "--holidays", "missing.json", "after", "-b", "5"}, "", true},
+		{[]string{"--utc", "until", "-b", "2024-12-02"}, "5 business days", false},
+		{[]string{"--utc", "until", "--skip-weekends", "2024-12-02"}, "6 weekdays", false},
+		{[]string{"--utc", "--holidays", "none", "until", "-b", "2024-12-02"}, "6 business days", false},
+		{[]string{"--utc", "until", "-b", "2024-11-15"}, "-5 business days", false},
+		{[]string{"--utc", "until", "-b", "2024-11-23"}, "0 business days", false},
+		{[]string{"--utc", "until", "-b", "next week"}, "", true},
+	}
+
+	for _, tc := range tests {
+		out, err := run(t, tc.args...)
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected an error running %q got %q", tc.args, out)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("expected no error running %q got %s", tc.args, err)
+			continue
+		}
+
+		if out != tc.expected {
+			t.Errorf("expected %q running %q got %q", tc.expected, tc.args, out)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// The layout used to specify holiday dates in a holiday calendar file.
+const holidayLayout = "2006-01-02"
+
+//===========================================================================
+// Holiday Calendars
+//===========================================================================
+
+// Calendar determines if the given date is a holiday, returning the name of
+// the holiday if so. Calendars are used to skip non-business days when
+// performing business day arithmetic.
+type Calendar interface {
+	Holiday(date time.Time) (name string, ok bool)
+}
+
+// Load a holiday calendar by name. The names "us" or "federal" return the
+// built-in US federal holiday calendar and "none" disables holidays so that
+// only weekends are skipped. Any other name is treated as the path to a JSON
+// file that maps dates in YYYY-MM-DD format to the name of the holiday.
+func loadCalendar(name string) (Calendar, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "none":
+		return noHolidays{}, nil
+	case "us", "federal":
+		return usFederal{}, nil
+	}
+	return loadHolidayFile(name)
+}
+
+// Returns the calendar to use for business day arithmetic from the command
+// line; holidays are ignored if only weekends should be skipped.
+func businessCalendar(c *cli.Context) (Calendar, error) {
+	if c.Bool("skip-weekends") {
+		return noHolidays{}, nil
+	}
+	return loadCalendar(c.String("holidays"))
+}
+
+// noHolidays is a calendar without any holidays.
+type noHolidays struct{}
+
+// Holiday always returns false.
+func (noHolidays) Holiday(date time.Time) (string, bool) {
+	return "", false
+}
+
+// holidayFile is a calendar loaded from a JSON file of dates to names.
+type holidayFile map[string]string
+
+// Load the holiday calendar from a JSON file, validating the dates.
+func loadHolidayFile(path string) (cal holidayFile, err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(path); err != nil {
+		return nil, fmt.Errorf("could not read holiday calendar: %v", err)
+	}
+
+	if err = json.Unmarshal(data, &cal); err != nil {
+		return nil, fmt.Errorf("could not parse holiday calendar %q: %v", path, err)
+	}
+
+	for date := range cal {
+		if _, err = time.Parse(holidayLayout, date); err != nil {
+			return nil, fmt.Errorf("invalid holiday date %q in %s", date, path)
+		}
+	}
+	return cal, nil
+}
+
+// Holiday looks up the date in the holiday file.
+func (c holidayFile) Holiday(date time.Time) (string, bool) {
+	name, ok := c[date.Format(holidayLayout)]
+	return name, ok
+}
+
+// usFederal computes the observed US federal holidays for any year. Holidays
+// that fall on a Saturday are observed the Friday before and holidays that
+// fall on a Sunday are observed the Monday after.
+type usFederal struct{}
+
+// Holiday returns the name of the observed federal holiday on the date.
+func (c usFederal) Holiday(date time.Time) (string, bool) {
+	key := date.Format(holidayLayout)
+
+	// Check the following year since New Year's Day can be observed in December.
+	for _, year := range []int{date.Year(), date.Year() + 1} {
+		if name, ok := c.holidays(year)[key]; ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Returns the observed holidays of the year keyed by date.
+func (usFederal) holidays(year int) map[string]string {
+	days := map[string]time.Time{
+		"New Year's Day":              observed(date(year, time.January, 1)),
+		"Martin Luther King, Jr. Day": nthWeekday(year, time.January, time.Monday, 3),
+		"Washington's Birthday":       nthWeekday(year, time.February, time.Monday, 3),
+		"Memorial Day":                nthWeekday(year, time.May, time.Monday, -1),
+		"Juneteenth":                  observed(date(year, time.June, 19)),
+		"Independence Day":            observed(date(year, time.July, 4)),
+		"Labor Day":                   nthWeekday(year, time.September, time.Monday, 1),
+		"Columbus Day":                nthWeekday(year, time.October, time.Monday, 2),
+		"Veterans Day":                observed(date(year, time.November, 11)),
+		"Thanksgiving Day":            nthWeekday(year, time.November, time.Thursday, 4),
+		"Christmas Day":               observed(date(year, time.December, 25)),
+	}
+
+	holidays := make(map[string]string, len(days))
+	for name, day := range days {
+		if name == "Juneteenth" && year < 2021 {
+			continue
+		}
+		holidays[day.Format(holidayLayout)] = name
+	}
+	return holidays
+}
+
+//===========================================================================
+// Business Day Arithmetic
+//===========================================================================
+
+// Returns true if the date is not a weekend and is not a holiday.
+func isBusinessDay(dt time.Time, cal Calendar) bool {
+	if wd := dt.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		return false
+	}
+
+	if cal != nil {
+		if _, ok := cal.Holiday(dt); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Adds n business days to the datetime, skipping weekends and holidays. If n
+// is negative, the business days are subtracted from the datetime instead.
+// The time of day of the original datetime is preserved.
+func addBusinessDays(dt time.Time, n int, cal Calendar) time.Time {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+
+	for n > 0 {
+		dt = dt.AddDate(0, 0, step)
+		if isBusinessDay(dt, cal) {
+			n--
+		}
+	}
+	return dt
+}
+
+// Counts the number of business days after start up to and including end. If
+// end is before start, the count is negative.
+func businessDaysBetween(start, end time.Time, cal Calendar) (n int) {
+	sign := 1
+	if end.Before(start) {
+		start, end, sign = end, start, -1
+	}
+
+	start, end = midnight(start), midnight(end)
+	for dt := start.AddDate(0, 0, 1); !dt.After(end); dt = dt.AddDate(0, 0, 1) {
+		if isBusinessDay(dt, cal) {
+			n++
+		}
+	}
+	return sign * n
+}
+
+//===========================================================================
+// Date Helpers
+//===========================================================================
+
+// Returns midnight on the specified date in UTC.
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// Returns midnight on the same date as the datetime in its location.
+func midnight(dt time.Time) time.Time {
+	return time.Date(dt.Year(), dt.Month(), dt.Day(), 0, 0, 0, 0, dt.Location())
+}
+
+// Returns the date a holiday is observed if it falls on a weekend.
+func observed(dt time.Time) time.Time {
+	switch dt.Weekday() {
+	case time.Saturday:
+		return dt.AddDate(0, 0, -1)
+	case time.Sunday:
+		return dt.AddDate(0, 0, 1)
+	}
+	return dt
+}
+
+// Returns the nth weekday of the month, e.g. the 3rd Monday of January. If n
+// is negative then the last weekday of the month is returned.
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	if n < 0 {
+		dt := date(year, month+1, 1).AddDate(0, 0, -1)
+		for dt.Weekday() != weekday {
+			dt = dt.AddDate(0, 0, -1)
+		}
+		return dt
+	}
+
+	dt := date(year, month, 1)
+	for dt.Weekday() != weekday {
+		dt = dt.AddDate(0, 0, 1)
+	}
+	return dt.AddDate(0, 0, 7*(n-1))
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUSFederal(t *testing.T) {
+	tests := []struct {
+		date    time.Time
+		holiday string
+	}{
+		{date(2024, time.January, 1), "New Year's Day"},
+		{date(2023, time.January, 2), "New Year's Day"},   // Sunday observed on Monday
+		{date(2021, time.December, 31), "New Year's Day"}, // 2022 observed in the prior year
+		{date(2024, time.January, 15), "Martin Luther King, Jr. Day"},
+		{date(2024, time.February, 19), "Washington's Birthday"},
+		{date(2024, time.May, 27), "Memorial Day"},
+		{date(2021, time.June, 18), "Juneteenth"}, // Saturday observed on Friday
+		{date(2020, time.June, 19), ""},           // not a federal holiday until 2021
+		{date(2026, time.July, 3), "Independence Day"},
+		{date(2026, time.July, 4), ""},
+		{date(2024, time.September, 2), "Labor Day"},
+		{date(2024, time.October, 14), "Columbus Day"},
+		{date(2024, time.November, 11), "Veterans Day"},
+		{date(2024, time.November, 28), "Thanksgiving Day"},
+		{date(2024, time.December, 25), "Christmas Day"},
+		{date(2024, time.December, 24), ""},
+	}
+
+	for _, tc := range tests {
+		name, ok := usFederal{}.Holiday(tc.date)
+		if ok != (tc.holiday != "") || name != tc.holiday {
+			t.Errorf("expected %s to be %q got %q", tc.date.Format(holidayLayout), tc.holiday, name)
+		}
+	}
+}
+
+func TestNthWeekday(t *testing.T) {
+	tests := []struct {
+		month   time.Month
+		weekday time.Weekday
+		n       int
+		day     int
+	}{
+		{time.May, time.Wednesday, 1, 1},
+		{time.May, time.Monday, 1, 6},
+		{time.May, time.Monday, 3, 20},
+		{time.May, time.Monday, -1, 27},
+		{time.May, time.Friday, -1, 31},
+		{time.February, time.Thursday, -1, 29},
+	}
+
+	for _, tc := range tests {
+		if dt := nthWeekday(2024, tc.month, tc.weekday, tc.n); !dt.Equal(date(2024, tc.month, tc.day)) {
+			t.Errorf("expected %s %d of %s 2024 to be the %d got %s", tc.weekday, tc.n, tc.month, tc.day, dt.Format(holidayLayout))
+		}
+	}
+}
+
+func TestBusinessDays(t *testing.T) {
+	// The Friday before Thanksgiving week in the afternoon
+	start := time.Date(2024, time.November, 22, 15, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		start    time.Time
+		n        int
+		cal      Calendar
+		expected time.Time
+	}{
+		{start, 0, usFederal{}, start},
+		{start, 1, usFederal{}, start.AddDate(0, 0, 3)},
+		{start, 5, usFederal{}, start.AddDate(0, 0, 10)},
+		{start, 5, noHolidays{}, start.AddDate(0, 0, 7)},
+		{start, 5, nil, start.AddDate(0, 0, 7)},
+		{start, -1, usFederal{}, start.AddDate(0, 0, -1)},
+		{start, -5, usFederal{}, start.AddDate(0, 0, -7)},
+		{start.AddDate(0, 0, 10), -5, usFederal{}, start},
+		{start.AddDate(0, 0, 1), 1, usFederal{}, start.AddDate(0, 0, 3)},
+		{start.AddDate(0, 0, 1), -1, usFederal{}, start},
+	}
+
+	for _, tc := range tests {
+		if dt := addBusinessDays(tc.start, tc.n, tc.cal); !dt.Equal(tc.expected) {
+			t.Errorf("expected %s %+d business days to be %s got %s", tc.start, tc.n, tc.expected, dt)
+		}
+
+		// Counting is the inverse of adding when starting on a business day
+		if isBusinessDay(tc.start, tc.cal) {
+			if n := businessDaysBetween(tc.start, tc.expected, tc.cal); n != tc.n {
+				t.Errorf("expected %d business days between %s and %s got %d", tc.n, tc.start, tc.expected, n)
+			}
+		}
+	}
+
+	if n := businessDaysBetween(start, start.Add(-time.Hour), usFederal{}); n != 0 {
+		t.Errorf("expected no business days within the same date got %d", n)
+	}
+}
+
+func TestIsBusinessDay(t *testing.T) {
+	tests := []struct {
+		date     time.Time
+		cal      Calendar
+		expected bool
+	}{
+		{date(2024, time.November, 27), usFederal{}, true},
+		{date(2024, time.November, 28), usFederal{}, false},
+		{date(2024, time.November, 28), noHolidays{}, true},
+		{date(2024, time.November, 30), noHolidays{}, false},
+		{date(2024, time.December, 1), nil, false},
+		{date(2024, time.December, 2), holidayFile{"2024-12-02": "Company Offsite"}, false},
+		{date(2024, time.December, 3), holidayFile{"2024-12-02": "Company Offsite"}, true},
+	}
+
+	for _, tc := range tests {
+		if actual := isBusinessDay(tc.date, tc.cal); actual != tc.expected {
+			t.Errorf("expected business day %t for %s got %t", tc.expected, tc.date.Format("Mon 2006-01-02"), actual)
+		}
+	}
+}
+
+func TestLoadCalendar(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	if err := os.WriteFile(valid, []byte(`{"2024-12-24": "Christmas Eve"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"12/24/2024": "Christmas Eve"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		holiday  string
+		expected string
+		err      bool
+	}{
+		{"", "2024-12-25", "", false},
+		{"none", "2024-12-25", "", false},
+		{"US", "2024-12-25", "Christmas Day", false},
+		{" federal ", "2024-12-25", "Christmas Day", false},
+		{valid, "2024-12-24", "Christmas Eve", false},
+		{valid, "2024-12-25", "", false},
+		{invalid, "", "", true},
+		{filepath.Join(dir, "missing.json"), "", "", true},
+	}
+
+	for _, tc := range tests {
+		cal, err := loadCalendar(tc.name)
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected an error loading calendar %q", tc.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("expected no error loading calendar %q got %s", tc.name, err)
+			continue
+		}
+
+		dt, _ := time.Parse(holidayLayout, tc.holiday)
+		if name, _ := cal.Holiday(dt); name != tc.expected {
+			t.Errorf("expected %s to be %q in calendar %q got %q", tc.holiday, tc.expected, tc.name, name)
+		}
+	}
+}
+
+func TestAfterBusinessDays(t *testing.T) {
+	freeze(t, frozen)
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--utc", "after", "-f", "2006-01-02", "--from", "2024-11-22", "5bd"}, "2024-12-02"},
+		{[]string{"--utc", "--holidays", "none", "after", "-f", "2006-01-02", "--from", "2024-11-22", "5bd"}, "2024-11-29"},
+		{[]string{"--utc", "after", "-f", "2006-01-02", "--from", "2024-12-02", "--", "-5bd"}, "2024-11-22"},
+		{[]string{"--utc", "after", "-f", "2006-01-02 15:04", "1bd"}, "2024-05-03 14:30"},
+	}
+
+	for _, tc := range tests {
+		out, err := run(t, tc.args...)
+		if err != nil {
+			t.Errorf("expected no error running %q got %s", tc.args, err)
+			continue
+		}
+
+		if out != tc.expected {
+			t.Errorf("expected %q running %q got %q", tc.expected, tc.args, out)
+		}
+	}
+}
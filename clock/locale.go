@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/text/language"
+)
+
+// The named layout that prints yesterday, today, or tomorrow for dates within a
+// day of now and the date (in the date layout) otherwise.
+const (
+	relativeLayout = "relative"
+	dateLayout     = "January 02, 2006"
+)
+
+//===========================================================================
+// Locales
+//===========================================================================
+
+// Locale translates the English month and day names produced by Go layouts into
+// another language, and names the days relative to today for human-facing
+// output. The zero value of a locale is English.
+type Locale struct {
+	Tag        language.Tag
+	Months     [12]string // full month names, January first
+	ShortMonth [12]string // abbreviated month names, January first
+	Days       [7]string  // full day names, Sunday first
+	ShortDays  [7]string  // abbreviated day names, Sunday first
+	Relative   [3]string  // yesterday, today, and tomorrow
+}
+
+// Locales supported by the --locale flag; the first locale is the default when
+// the requested locale cannot be matched.
+var locales = []*Locale{
+	{
+		Tag:      language.English,
+		Relative: [3]string{"yesterday", "today", "tomorrow"},
+	},
+	{
+		Tag:        language.German,
+		Months:     [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		ShortMonth: [12]string{"Jan", "Feb", "Mär", "Apr", "Mai", "Jun", "Jul", "Aug", "Sep", "Okt", "Nov", "Dez"},
+		Days:       [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		ShortDays:  [7]string{"So", "Mo", "Di", "Mi", "Do", "Fr", "Sa"},
+		Relative:   [3]string{"gestern", "heute", "morgen"},
+	},
+	{
+		Tag:        language.French,
+		Months:     [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		ShortMonth: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		Days:       [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		ShortDays:  [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+		Relative:   [3]string{"hier", "aujourd'hui", "demain"},
+	},
+	{
+		Tag:        language.Spanish,
+		Months:     [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		ShortMonth: [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+		Days:       [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		ShortDays:  [7]string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"},
+		Relative:   [3]string{"ayer", "hoy", "mañana"},
+	},
+	{
+		Tag:        language.Italian,
+		Months:     [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		ShortMonth: [12]string{"gen", "feb", "mar", "apr", "mag", "giu", "lug", "ago", "set", "ott", "nov", "dic"},
+		Days:       [7]string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+		ShortDays:  [7]string{"dom", "lun", "mar", "mer", "gio", "ven", "sab"},
+		Relative:   [3]string{"ieri", "oggi", "domani"},
+	},
+	{
+		Tag:        language.Portuguese,
+		Months:     [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		ShortMonth: [12]string{"jan", "fev", "mar", "abr", "mai", "jun", "jul", "ago", "set", "out", "nov", "dez"},
+		Days:       [7]string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"},
+		ShortDays:  [7]string{"dom", "seg", "ter", "qua", "qui", "sex", "sáb"},
+		Relative:   [3]string{"ontem", "hoje", "amanhã"},
+	},
+	{
+		Tag:        language.Dutch,
+		Months:     [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		ShortMonth: [12]string{"jan", "feb", "mrt", "apr", "mei", "jun", "jul", "aug", "sep", "okt", "nov", "dec"},
+		Days:       [7]string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+		ShortDays:  [7]string{"zo", "ma", "di", "wo", "do", "vr", "za"},
+		Relative:   [3]string{"gisteren", "vandaag", "morgen"},
+	},
+}
+
+// matches the month and day name elements of Go layouts, longest first so that
+// e.g. January is not matched as Jan followed by uary.
+var nameElements = regexp.MustCompile(`January|Jan|Monday|Mon`)
+
+// Load the locale specified by the locale flag, matching it against the
+// supported locales with the golang.org/x/text language matcher so that
+// regional variants such as de-AT or pt_BR (or POSIX locales such as
+// fr_FR.UTF-8) use the translations of their language. If no locale is
+// specified, English is returned.
+func loadLocale(c *cli.Context) (*Locale, error) {
+	name := strings.TrimSpace(c.String("locale"))
+	if idx := strings.IndexAny(name, ".@"); idx >= 0 {
+		name = name[:idx]
+	}
+
+	switch strings.ToLower(name) {
+	case "", "c", "posix":
+		return locales[0], nil
+	}
+
+	tag, err := language.Parse(name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse locale %q", name)
+	}
+
+	tags := make([]language.Tag, 0, len(locales))
+	for _, locale := range locales {
+		tags = append(tags, locale.Tag)
+	}
+
+	_, idx, confidence := language.NewMatcher(tags).Match(tag)
+	if confidence == language.No {
+		return nil, fmt.Errorf("locale %q is not supported", name)
+	}
+	return locales[idx], nil
+}
+
+// Format the time with the layout, translating month and day names into the
+// language of the locale. The names are translated by layout element rather
+// than in the formatted string since Go formats May the same for both January
+// and Jan. The relative layout prints the name of the day if the time is
+// yesterday, today, or tomorrow in its timezone and the date otherwise.
+func (l *Locale) Format(dt time.Time, layout string) string {
+	if layout == relativeLayout {
+		if day := daysFromToday(dt); day >= -1 && day <= 1 {
+			return l.Relative[day+1]
+		}
+		layout = dateLayout
+	}
+
+	if l.Months[0] == "" {
+		return dt.Format(layout)
+	}
+
+	var s strings.Builder
+	pos := 0
+	for _, m := range nameElements.FindAllStringIndex(layout, -1) {
+		s.WriteString(dt.Format(layout[pos:m[0]]))
+		s.WriteString(l.translate(dt, layout[m[0]:m[1]]))
+		pos = m[1]
+	}
+	s.WriteString(dt.Format(layout[pos:]))
+	return s.String()
+}
+
+// Returns the name of the month or day of the time for the layout element in
+// the language of the locale.
+func (l *Locale) translate(dt time.Time, element string) string {
+	switch element {
+	case "January":
+		return l.Months[dt.Month()-1]
+	case "Jan":
+		return l.ShortMonth[dt.Month()-1]
+	case "Monday":
+		return l.Days[dt.Weekday()]
+	default:
+		return l.ShortDays[dt.Weekday()]
+	}
+}
+
+// Returns the number of calendar days between today and the date of the time,
+// both in the timezone of the time, e.g. -1 for yesterday.
+func daysFromToday(dt time.Time) int {
+	now := clk.Now().In(dt.Location())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	date := time.Date(dt.Year(), dt.Month(), dt.Day(), 0, 0, 0, 0, time.UTC)
+	return int(date.Sub(today).Hours() / 24)
+}
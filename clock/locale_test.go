@@ -0,0 +1,170 @@
+package main
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	cli "github.com/urfave/cli/v2"
+	"golang.org/x/text/language"
+)
+
+func TestLoadLocale(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected language.Tag
+		err      bool
+	}{
+		{"", language.English, false},
+		{"C", language.English, false},
+		{"POSIX", language.English, false},
+		{"en_US.UTF-8", language.English, false},
+		{"de", language.German, false},
+		{"de-AT", language.German, false},
+		{"fr_CA", language.French, false},
+		{"fr_FR.UTF-8", language.French, false},
+		{"es-MX", language.Spanish, false},
+		{"it", language.Italian, false},
+		{"pt_BR", language.Portuguese, false},
+		{"nl_BE@euro", language.Dutch, false},
+		{"??", language.Und, true},
+	}
+
+	for _, tc := range tests {
+		set := flag.NewFlagSet("test", flag.ContinueOnError)
+		set.String("locale", tc.name, "")
+
+		locale, err := loadLocale(cli.NewContext(cli.NewApp(), set, nil))
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected an error loading locale %q got %s", tc.name, locale.Tag)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("expected no error loading locale %q got %s", tc.name, err)
+			continue
+		}
+
+		if locale.Tag != tc.expected {
+			t.Errorf("expected locale %q to be %s got %s", tc.name, tc.expected, locale.Tag)
+		}
+	}
+}
+
+func TestLocaleFormat(t *testing.T) {
+	freeze(t, frozen)
+	dt := time.Date(2024, time.March, 3, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		locale   int
+		layout   string
+		expected string
+	}{
+		{0, "Monday, January 2, 2006", "Sunday, March 3, 2024"},
+		{1, "Monday, January 2, 2006", "Sonntag, März 3, 2024"},
+		{1, "Mon Jan 2", "So Mär 3"},
+		{2, "Monday 2 January 2006", "dimanche 3 mars 2024"},
+		{2, "Mon 2 Jan", "dim. 3 mars"},
+		{3, "Monday 2 January", "domingo 3 marzo"},
+		{4, "Monday 2 January", "domenica 3 marzo"},
+		{5, "Monday 2 January", "domingo 3 março"},
+		{6, "Monday 2 January", "zondag 3 maart"},
+		{1, "2006-01-02 15:04 MST", "2024-03-03 09:00 UTC"},
+		{1, "Monday (Mayday)", "Sonntag (Mayday)"},
+	}
+
+	for _, tc := range tests {
+		if actual := locales[tc.locale].Format(dt, tc.layout); actual != tc.expected {
+			t.Errorf("expected %q in %s got %q", tc.expected, locales[tc.locale].Tag, actual)
+		}
+	}
+
+	// Every month and day name is translated
+	for _, locale := range locales[1:] {
+		for month := time.January; month <= time.December; month++ {
+			dt := time.Date(2024, month, 1, 0, 0, 0, 0, time.UTC)
+			if actual := locale.Format(dt, "January Jan"); actual != locale.Months[month-1]+" "+locale.ShortMonth[month-1] {
+				t.Errorf("expected %s to be translated in %s got %q", month, locale.Tag, actual)
+			}
+		}
+
+		for day := 0; day < 7; day++ {
+			dt := time.Date(2024, time.March, 3+day, 0, 0, 0, 0, time.UTC)
+			if actual := locale.Format(dt, "Monday Mon"); actual != locale.Days[day]+" "+locale.ShortDays[day] {
+				t.Errorf("expected %s to be translated in %s got %q", dt.Weekday(), locale.Tag, actual)
+			}
+		}
+	}
+}
+
+func TestRelativeLayout(t *testing.T) {
+	// Thursday, May 2, 2024 at 14:30 UTC is already Friday in Tokyo
+	freeze(t, frozen)
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("timezone database is not available: %s", err)
+	}
+
+	tests := []struct {
+		dt       time.Time
+		locale   int
+		expected string
+	}{
+		{frozen, 0, "today"},
+		{frozen.Add(-14*time.Hour - 30*time.Minute), 0, "today"},
+		{frozen.Add(9*time.Hour + 29*time.Minute), 0, "today"},
+		{frozen.Add(9*time.Hour + 30*time.Minute), 0, "tomorrow"},
+		{frozen.Add(-15 * time.Hour), 0, "yesterday"},
+		{frozen.AddDate(0, 0, 2), 0, "May 04, 2024"},
+		{frozen.AddDate(0, 0, -2), 0, "April 30, 2024"},
+		{frozen.AddDate(0, 0, 1), 2, "demain"},
+		{frozen.AddDate(0, 0, -1), 1, "gestern"},
+		{frozen.AddDate(0, 0, 2), 1, "Mai 04, 2024"},
+		{frozen.In(tokyo), 0, "today"},
+		{frozen.In(tokyo).Add(10 * time.Hour), 0, "tomorrow"},
+		{time.Date(2024, time.May, 1, 23, 0, 0, 0, tokyo), 0, "yesterday"},
+	}
+
+	for _, tc := range tests {
+		if actual := locales[tc.locale].Format(tc.dt, relativeLayout); actual != tc.expected {
+			t.Errorf("expected %s to be %q in %s got %q", tc.dt, tc.expected, locales[tc.locale].Tag, actual)
+		}
+	}
+}
+
+func TestLocaleCommands(t *testing.T) {
+	freeze(t, frozen)
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--utc", "--locale", "de", "date"}, "Mai 02, 2024"},
+		{[]string{"--utc", "-L", "es_MX.UTF-8", "Monday"}, "jueves"},
+		{[]string{"--utc", "relative"}, "today"},
+		{[]string{"--utc", "--locale", "fr", "after", "-f", "relative", "1d"}, "demain"},
+		{[]string{"--utc", "--locale", "pt-BR", "after", "-f", "relative", "--", "-1d"}, "ontem"},
+		{[]string{"--utc", "--locale", "nl", "after", "-f", "relative", "1w"}, "mei 09, 2024"},
+		{[]string{"--utc", "--locale", "it", "parse", "-f", "Mon 2 Jan", "1714660200"}, "gio 2 mag"},
+	}
+
+	for _, tc := range tests {
+		out, err := run(t, tc.args...)
+		if err != nil {
+			t.Errorf("expected no error running %q got %s", tc.args, err)
+			continue
+		}
+
+		if out != tc.expected {
+			t.Errorf("expected %q running %q got %q", tc.expected, tc.args, out)
+		}
+	}
+
+	t.Setenv("CLOCK_LOCALE", "de_DE.UTF-8")
+	if out, _ := run(t, "--utc", "Monday"); out != "Donnerstag" {
+		t.Errorf("expected the locale from $CLOCK_LOCALE got %q", out)
+	}
+}
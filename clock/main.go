@@ -3,21 +3,39 @@ package main
 import (
 	"fmt"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/atotto/clipboard"
+	"github.com/bbengfort/x/clock/clocks"
 	"github.com/dustin/go-humanize"
 	cli "github.com/urfave/cli/v2"
 )
 
+// The clock that commands read the current time from and wait on, so that the
+// commands can be run against a fake clock (see the clocks package).
+var clk clocks.Clock = clocks.Real()
+
 func main() {
+	newApp().Run(os.Args)
+}
+
+// Returns the clock application with its flags and commands.
+func newApp() *cli.App {
 	app := cli.NewApp()
 	app.Name = "clock"
 	app.Version = "2.1"
 	app.Usage = "a simple timekeeping utility"
 	app.UsageText = "clock [-ncul] [-tz=<zone>] <fmt>\n   clock [global opts] cmd [cmdopts]"
 	app.Action = clock
+	app.Before = func(c *cli.Context) error {
+		if err := loadUserFormats(configPath()); err != nil {
+			return cli.Exit(err, 1)
+		}
+		return nil
+	}
 	app.Flags = []cli.Flag{
 		&cli.BoolFlag{
 			Name:    "noline",
@@ -46,6 +64,19 @@ func main() {
 			Aliases: []string{"l"},
 			Usage:   "shortcut for -tz=local",
 		},
+		&cli.StringFlag{
+			Name:    "holidays",
+			Aliases: []string{"H"},
+			Usage:   "holiday calendar for business days: us, none or path to a JSON file",
+			Value:   "us",
+			EnvVars: []string{"CLOCK_HOLIDAYS"},
+		},
+		&cli.StringFlag{
+			Name:    "locale",
+			Aliases: []string{"L"},
+			Usage:   "print month and day names in the language of the locale, e.g. de or fr-CA",
+			EnvVars: []string{"CLOCK_LOCALE"},
+		},
 	}
 
 	// Define other commands available to the application
@@ -55,12 +86,177 @@ func main() {
 			Usage:     "get the date or time after the specified duration",
 			UsageText: "clock [global opts] after [opts] <duration>",
 			Action:    after,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "format",
+					Aliases: []string{"f"},
+					Usage:   "the layout or named format to print the timestamp with",
+				},
+				&cli.StringFlag{
+					Name:    "from",
+					Aliases: []string{"F"},
+					Usage:   "the date or datetime to add the duration to (default now)",
+				},
+				&cli.BoolFlag{
+					Name:    "business-days",
+					Aliases: []string{"b"},
+					Usage:   "add the number of days as business days, skipping weekends and holidays",
+				},
+				&cli.BoolFlag{
+					Name:    "skip-weekends",
+					Aliases: []string{"w"},
+					Usage:   "add the number of days as weekdays, skipping weekends but not holidays",
+				},
+			},
 		},
 		{
 			Name:      "until",
 			Usage:     "get the amount of time until the specified date/time",
 			UsageText: "clock [global opts] until [opts] datetime",
 			Action:    until,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:    "business-days",
+					Aliases: []string{"b"},
+					Usage:   "count the number of business days until the date",
+				},
+				&cli.BoolFlag{
+					Name:    "skip-weekends",
+					Aliases: []string{"w"},
+					Usage:   "count the number of weekdays until the date, including holidays",
+				},
+			},
+		},
+		{
+			Name:      "convert",
+			Usage:     "convert a date/time into one or more timezones",
+			UsageText: "clock [global opts] convert [opts] [datetime]",
+			Action:    convert,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "from",
+					Aliases: []string{"F"},
+					Usage:   "the timezone of the datetime (default the global timezone)",
+				},
+				&cli.StringSliceFlag{
+					Name:    "to",
+					Aliases: []string{"T"},
+					Usage:   "comma separated timezones to convert the datetime to",
+				},
+				&cli.StringFlag{
+					Name:    "format",
+					Aliases: []string{"f"},
+					Usage:   "the layout or named format to print the timestamps with",
+				},
+			},
+		},
+		{
+			Name:      "countdown",
+			Usage:     "live countdown to the specified datetime or for a duration",
+			UsageText: "clock [global opts] countdown [opts] <datetime|duration>",
+			Action:    countdown,
+			Flags: []cli.Flag{
+				&cli.DurationFlag{
+					Name:    "interval",
+					Aliases: []string{"i"},
+					Usage:   "how often to refresh the countdown",
+					Value:   time.Second,
+				},
+				&cli.StringFlag{
+					Name:    "exec",
+					Aliases: []string{"e"},
+					Usage:   "command to run when the countdown completes instead of beeping",
+				},
+				&cli.IntFlag{
+					Name:    "repeat",
+					Aliases: []string{"r"},
+					Usage:   "number of times to repeat a duration countdown (-1 repeats forever)",
+				},
+			},
+		},
+		{
+			Name:      "alarm",
+			Usage:     "wait until the specified time and run a command or print a message",
+			UsageText: "clock [global opts] alarm [opts] <time|datetime>",
+			Action:    alarm,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "exec",
+					Aliases: []string{"e"},
+					Usage:   "command to run when the alarm goes off instead of beeping",
+				},
+				&cli.StringFlag{
+					Name:    "message",
+					Aliases: []string{"m"},
+					Usage:   "message to print when the alarm goes off",
+				},
+				&cli.DurationFlag{
+					Name:    "check",
+					Aliases: []string{"i"},
+					Usage:   "how often to check the wall clock, e.g. after the system resumes from suspend",
+					Value:   time.Second,
+				},
+				&cli.BoolFlag{
+					Name:    "quiet",
+					Aliases: []string{"q"},
+					Usage:   "do not print when the alarm is set for",
+				},
+			},
+		},
+		{
+			Name:      "epoch",
+			Usage:     "print the current time as a unix timestamp",
+			UsageText: "clock epoch [--millis|--micros|--nanos]",
+			Action:    epoch,
+			Flags:     epochFlags("print"),
+		},
+		{
+			Name:      "parse",
+			Usage:     "convert a unix timestamp into a formatted datetime",
+			UsageText: "clock [global opts] parse [opts] <timestamp>",
+			Action:    parse,
+			Flags: append(epochFlags("parse"), &cli.StringFlag{
+				Name:    "format",
+				Aliases: []string{"f"},
+				Usage:   "the layout or named format to print the timestamp with",
+			}),
+		},
+		{
+			Name:      "diff",
+			Usage:     "compute the duration between two datetimes",
+			UsageText: "clock [global opts] diff [opts] <datetime> <datetime>",
+			Action:    diff,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:    "abs",
+					Aliases: []string{"a"},
+					Usage:   "print the absolute value of the difference",
+				},
+				&cli.BoolFlag{
+					Name:    "seconds",
+					Aliases: []string{"s"},
+					Usage:   "print only the number of seconds (machine readable)",
+				},
+			},
+		},
+		{
+			Name:      "drift",
+			Usage:     "report the offset of the local clock from an NTP server",
+			UsageText: "clock drift [opts] [server]",
+			Action:    drift,
+			Flags: []cli.Flag{
+				&cli.DurationFlag{
+					Name:    "threshold",
+					Aliases: []string{"T"},
+					Usage:   "exit with status 2 if the absolute offset exceeds the threshold",
+				},
+				&cli.DurationFlag{
+					Name:    "timeout",
+					Aliases: []string{"w"},
+					Usage:   "time to wait for the NTP server to respond",
+					Value:   5 * time.Second,
+				},
+			},
 		},
 		{
 			Name:      "fmt",
@@ -70,8 +266,7 @@ func main() {
 		},
 	}
 
-	// Run the CLI program
-	app.Run(os.Args)
+	return app
 }
 
 //===========================================================================
@@ -81,18 +276,11 @@ func main() {
 func clock(c *cli.Context) (err error) {
 	// Get the current time in the specified location
 	var loc *time.Location
-	locName := c.String("tz")
-	if c.Bool("local") {
-		locName = "Local"
-	}
-	if c.Bool("utc") {
-		locName = "UTC"
-	}
-	if loc, err = time.LoadLocation(locName); err != nil {
-		return cli.Exit(fmt.Errorf("cannot parse location %q", locName), 1)
+	if loc, err = location(c); err != nil {
+		return cli.Exit(err, 1)
 	}
 
-	dt := time.Now().In(loc)
+	dt := clk.Now().In(loc)
 
 	// Determine how to output the time
 	var layout string
@@ -100,26 +288,67 @@ func clock(c *cli.Context) (err error) {
 		return cli.Exit(err, 1)
 	}
 
-	ts := dt.Format(layout)
+	var lang *Locale
+	if lang, err = loadLocale(c); err != nil {
+		return cli.Exit(err, 1)
+	}
 
-	if c.Bool("copy") {
-		if clipboard.Unsupported {
-			return cli.Exit("clipboard not supported", 1)
+	return output(c, lang.Format(dt, layout))
+}
+
+func after(c *cli.Context) (err error) {
+	var loc *time.Location
+	if loc, err = location(c); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	var layout string
+	if layout, err = parseLayout(c.String("format")); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	var lang *Locale
+	if lang, err = loadLocale(c); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	// Determine the base time to add the duration to
+	base := clk.Now().In(loc)
+	if from := c.String("from"); from != "" {
+		if base, err = parseDatetime(from, c.String("tz"), c.Bool("local"), c.Bool("utc")); err != nil {
+			return cli.Exit(err, 1)
 		}
-		clipboard.WriteAll(ts)
-	} else {
-		if c.Bool("noline") {
-			fmt.Print(ts)
-		} else {
-			fmt.Println(ts)
+		base = base.In(loc)
+	}
+
+	// Business day offsets skip weekends and holidays in the calendar; with the
+	// business day flags the offset may be specified as a plain number of days.
+	arg := strings.TrimSpace(strings.Join(c.Args().Slice(), " "))
+	pattern := businessDays
+	if c.Bool("business-days") || c.Bool("skip-weekends") {
+		pattern = calendarDays
+	}
+
+	if match := pattern.FindStringSubmatch(arg); match != nil {
+		var cal Calendar
+		if cal, err = businessCalendar(c); err != nil {
+			return cli.Exit(err, 1)
 		}
+
+		n, _ := strconv.Atoi(match[1])
+		dt := addBusinessDays(base, n, cal)
+		return output(c, lang.Format(dt, layout))
 	}
 
-	return nil
-}
+	if pattern == calendarDays {
+		return cli.Exit(fmt.Errorf("could not parse %q as a number of days", arg), 1)
+	}
 
-func after(c *cli.Context) (err error) {
-	return cli.Exit("this command has not been implemented yet", 1)
+	var offset clocks.Offset
+	if offset, err = clocks.ParseOffset(arg); err != nil {
+		return cli.Exit(err, 1)
+	}
+	return output(c, lang.Format(offset.Add(base), layout))
 }
 
 func until(c *cli.Context) (err error) {
@@ -129,19 +358,88 @@ func until(c *cli.Context) (err error) {
 		return cli.Exit(err, 1)
 	}
 
-	if c.Bool("copy") {
-		if clipboard.Unsupported {
-			return cli.Exit("clipboard not supported", 1)
+	if c.Bool("business-days") || c.Bool("skip-weekends") {
+		var cal Calendar
+		if cal, err = businessCalendar(c); err != nil {
+			return cli.Exit(err, 1)
 		}
-		clipboard.WriteAll(humanize.Time(ts))
-	} else {
-		if c.Bool("noline") {
-			fmt.Print(humanize.Time(ts))
-		} else {
-			fmt.Println(humanize.Time(ts))
+
+		unit := "business days"
+		if c.Bool("skip-weekends") {
+			unit = "weekdays"
 		}
+
+		n := businessDaysBetween(clk.Now().In(ts.Location()), ts, cal)
+		return output(c, fmt.Sprintf("%d %s", n, unit))
+	}
+
+	return output(c, humanize.Time(ts))
+}
+
+func epoch(c *cli.Context) (err error) {
+	var unit EpochUnit
+	if unit, err = epochUnit(c); err != nil {
+		return cli.Exit(err, 1)
 	}
 
+	if unit == Auto {
+		unit = Seconds
+	}
+	return output(c, strconv.FormatInt(unit.Timestamp(clk.Now()), 10))
+}
+
+func parse(c *cli.Context) (err error) {
+	var loc *time.Location
+	if loc, err = location(c); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	var layout string
+	if layout, err = parseLayout(c.String("format")); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	var lang *Locale
+	if lang, err = loadLocale(c); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	var unit EpochUnit
+	if unit, err = epochUnit(c); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	var ts time.Time
+	if ts, err = parseEpoch(c.Args().First(), unit); err != nil {
+		return cli.Exit(err, 1)
+	}
+	return output(c, lang.Format(ts.In(loc), layout))
+}
+
+func drift(c *cli.Context) (err error) {
+	server := c.Args().First()
+	if server == "" {
+		server = defaultNTPServer
+	}
+
+	var d *Drift
+	if d, err = queryNTP(server, c.Duration("timeout")); err != nil {
+		return cli.Exit(fmt.Errorf("could not query %s: %s", server, err), 1)
+	}
+
+	if err = output(c, d.String()); err != nil {
+		return err
+	}
+
+	// Health check mode: exit with a distinct status if the clock has drifted
+	offset := d.Offset
+	if offset < 0 {
+		offset = -offset
+	}
+
+	if threshold := c.Duration("threshold"); threshold > 0 && offset > threshold {
+		return cli.Exit(fmt.Sprintf("clock offset %s exceeds threshold %s", signed(d.Offset), threshold), 2)
+	}
 	return nil
 }
 
@@ -152,12 +450,16 @@ command is to use the Go time layout by specifying the format via a reference ti
 
 Mon Jan 2 15:04:05 MST 2006
 
+Format strings with strftime directives such as "%Y-%m-%d %H:%M" (as used by Python and
+C) are translated into the equivalent Go layout.
+
 You can also specify one of the following helper formats:
 
 - json (default)
 - code
 - date
 - today
+- relative (yesterday, today, tomorrow, or the date)
 - blog
 - file
 - ansic
@@ -169,10 +471,55 @@ You can also specify one of the following helper formats:
 - rfc850
 - rfc1123 (or rfc1123z)
 - stamp (or stampmilli, stampmicro, stampnano)
+
+Additional named formats can be defined in ~/.clockrc (or the path in $CLOCKRC) as
+either TOML or JSON, mapping names to layouts in a formats table, e.g.:
+
+[formats]
+mylog = "2006-01-02 15:04:05.000"
+
+Built-in format names take precedence over user formats with the same name.
+
+Month and day names are printed in the language of the --locale flag (or $CLOCK_LOCALE),
+e.g. clock --locale de date. Supported languages are English, German, French, Spanish,
+Italian, Portuguese, and Dutch; regional variants such as pt-BR use their language. The
+relative format names the day as yesterday, today, or tomorrow in the locale, e.g.
+clock --locale fr after -f relative 1d prints demain.
+
+The after command adds a duration to the current time (or the time specified with
+--from) and prints the result. Durations may be compound and in addition to the units
+understood by Go (h, m, s, ms, us, ns) accept w (weeks) and d (days), e.g. clock after
+3h30m, clock after 2d4h, or clock after -1w.
+
+The diff command prints the duration between two datetimes in any of the formats above
+(or a unix timestamp), e.g. clock diff "2024-05-01 09:00" "2024-05-02 11:30".
+
+The convert command prints the same instant in multiple timezones, e.g. clock convert
+"2024-05-01 14:00" --from America/New_York --to Europe/Berlin,Asia/Tokyo.
+
+The countdown command live updates the time remaining until a datetime or for a
+duration, e.g. clock countdown 25m, and rings the terminal bell (or runs the command
+specified by --exec) when it completes. Duration countdowns can be repeated with --repeat.
+The alarm command waits until a time of day (its next occurrence) or datetime, then
+prints --message and/or runs --exec, e.g. clock alarm 14:30 --exec 'notify-send lunch'.
+
+Unix timestamps can be printed with the epoch command (in seconds by default or with
+--millis, --micros, or --nanos) and converted back into any of the above formats with the
+parse command, e.g. clock parse -f kitchen 1712345678. When no unit is specified, parse
+infers the unit from the magnitude of the timestamp.
+
+Business days can be computed with the after and until commands, e.g. clock after 5bd.
+Weekends and holidays are skipped; the holiday calendar is specified with --holidays
+as either us (US federal holidays, the default), none, or the path to a JSON file that
+maps YYYY-MM-DD dates to holiday names. With --business-days (-b) the offset can be a
+plain number of days, and --skip-weekends (-w) skips weekends but not holidays.
 `
 
 func fmtHelp(c *cli.Context) (err error) {
 	fmt.Println(strings.TrimSpace(fmtHelpStr))
+	if len(userFormats) > 0 {
+		fmt.Printf("\nUser formats defined in %s:\n\n%s\n", configPath(), describeUserFormats())
+	}
 	return nil
 }
 
@@ -180,6 +527,45 @@ func fmtHelp(c *cli.Context) (err error) {
 // Helper Function
 //===========================================================================
 
+// matches business day offsets such as 5bd or -2bd
+var businessDays = regexp.MustCompile(`^([+-]?\d+)\s*bd$`)
+
+// matches day offsets such as 5, 5d, or 5bd when business days are requested
+var calendarDays = regexp.MustCompile(`^([+-]?\d+)\s*(?:bd|d)?$`)
+
+// write the output to the clipboard or to stdout as specified by the flags
+func output(c *cli.Context, s string) error {
+	if c.Bool("copy") {
+		if clipboard.Unsupported {
+			return cli.Exit("clipboard not supported", 1)
+		}
+		clipboard.WriteAll(s)
+		return nil
+	}
+
+	if c.Bool("noline") {
+		fmt.Print(s)
+	} else {
+		fmt.Println(s)
+	}
+	return nil
+}
+
+// get the location specified by the tz, local, and utc flags
+func location(c *cli.Context) (loc *time.Location, err error) {
+	locName := c.String("tz")
+	if c.Bool("local") {
+		locName = "Local"
+	}
+	if c.Bool("utc") {
+		locName = "UTC"
+	}
+	if loc, err = time.LoadLocation(locName); err != nil {
+		return nil, fmt.Errorf("cannot parse location %q", locName)
+	}
+	return loc, nil
+}
+
 // parse the layout name or verify that the layout is valid
 func parseLayout(s string) (layout string, err error) {
 	name := strings.ToLower(s)
@@ -189,7 +575,9 @@ func parseLayout(s string) (layout string, err error) {
 	case "code":
 		return "Mon Jan 02 15:04:05 2006 -0700", nil
 	case "date", "today":
-		return "January 02, 2006", nil
+		return dateLayout, nil
+	case relativeLayout:
+		return relativeLayout, nil
 	case "blog":
 		return "2020-01-02 15:04:05 -0700", nil
 	case "file":
@@ -224,14 +612,29 @@ func parseLayout(s string) (layout string, err error) {
 		return time.StampNano, nil
 	}
 
-	var dt time.Time
-	if dt, err = time.Parse(s, time.Now().Format(s)); err != nil || dt.IsZero() {
-		// Why does this not return isZero?!
+	if layout, ok := userFormats[name]; ok {
+		return layout, nil
+	}
+
+	if isStrftime(s) {
+		if s, err = strftime(s); err != nil {
+			return "", err
+		}
+	}
+
+	if !validLayout(s) {
 		return "", fmt.Errorf("%q is not a valid layout or layout name", s)
 	}
 	return s, nil
 }
 
+// check that the layout can format and parse the current time
+func validLayout(s string) bool {
+	dt, err := time.Parse(s, clk.Now().Format(s))
+	// Why does this not return isZero?!
+	return err == nil && !dt.IsZero()
+}
+
 func parseDatetime(s, tz string, local, utc bool) (dt time.Time, err error) {
 	var loc *time.Location
 	switch {
@@ -247,14 +650,14 @@ func parseDatetime(s, tz string, local, utc bool) (dt time.Time, err error) {
 		return time.Time{}, err
 	}
 
-	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04", "2006-01-02 15:04:05"} {
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04", "2006-01-02 15:04:05", time.RFC3339} {
 		if dt, err = time.ParseInLocation(layout, s, loc); err == nil && !dt.IsZero() {
 			return dt, nil
 		}
 	}
 
 	if dt, err = time.ParseInLocation("15:04", s, loc); err == nil && !dt.IsZero() {
-		today := time.Now().In(loc)
+		today := clk.Now().In(loc)
 		return time.Date(today.Year(), today.Month(), today.Day(), dt.Hour(), dt.Minute(), 0, 0, loc), nil
 	}
 
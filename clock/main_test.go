@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bbengfort/x/clock/clocks"
+	cli "github.com/urfave/cli/v2"
+)
+
+// Thursday, May 2, 2024 at 14:30 UTC; most tests run against a clock frozen here.
+var frozen = time.Date(2024, 5, 2, 14, 30, 0, 0, time.UTC)
+
+// Freezes the clock that the commands read the current time from at now and
+// restores the real clock when the test completes.
+func freeze(t *testing.T, now time.Time) *clocks.Fake {
+	fake := clocks.NewFake(now)
+	clk = fake
+	t.Cleanup(func() { clk = clocks.Real() })
+	return fake
+}
+
+// Runs the clock application with the arguments and returns what it printed
+// to stdout without the trailing newline. Exit errors are returned rather than
+// exiting the test binary and the user configuration is isolated from $HOME.
+func run(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	if os.Getenv("CLOCKRC") == "" {
+		t.Setenv("CLOCKRC", filepath.Join(t.TempDir(), "clockrc"))
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	app := newApp()
+	app.ExitErrHandler = func(*cli.Context, error) {}
+	err = app.Run(append([]string{"clock"}, args...))
+
+	w.Close()
+	out, rerr := io.ReadAll(r)
+	if rerr != nil {
+		t.Fatal(rerr)
+	}
+	return strings.TrimSuffix(string(out), "\n"), err
+}
+
+func TestRun(t *testing.T) {
+	freeze(t, frozen)
+
+	out, err := run(t, "--utc")
+	if err != nil {
+		t.Fatalf("expected no error got %s", err)
+	}
+	if out != "2024-05-02T14:30:00Z" {
+		t.Errorf("expected the frozen time in the default layout got %q", out)
+	}
+
+	if _, err = run(t, "--tz", "Mars/Olympus_Mons"); err == nil {
+		t.Error("expected an error for an unknown timezone")
+	}
+}
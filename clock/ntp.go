@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Default NTP server to query for the clock drift.
+const defaultNTPServer = "pool.ntp.org"
+
+// NTP timestamps count seconds since 1900-01-01 rather than the unix epoch.
+const ntpEpochOffset = 2208988800
+
+// Size of an NTP packet without extension fields or authentication.
+const ntpPacketSize = 48
+
+// Drift is the result of an SNTP query comparing the local clock to a server.
+type Drift struct {
+	Server  string        // address of the server that was queried
+	Offset  time.Duration // amount the local clock is behind the server (negative if ahead)
+	Delay   time.Duration // round-trip network delay of the query
+	Stratum uint8         // distance of the server from a reference clock
+}
+
+// String describes the drift of the local clock.
+func (d *Drift) String() string {
+	return fmt.Sprintf("offset %s delay %s (%s stratum %d)", signed(d.Offset), d.Delay, d.Server, d.Stratum)
+}
+
+// queryNTP sends a single SNTP (RFC 4330) client request to the server and
+// computes the offset of the local clock and the round-trip delay from the
+// four timestamps of the exchange. If the server does not specify a port, the
+// standard NTP port 123 is used.
+func queryNTP(server string, timeout time.Duration) (_ *Drift, err error) {
+	addr := server
+	if _, _, err = net.SplitHostPort(server); err != nil {
+		addr = net.JoinHostPort(server, "123")
+	}
+
+	var conn net.Conn
+	if conn, err = net.DialTimeout("udp", addr, timeout); err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	// Leap indicator 0, version 4, mode 3 (client); the transmit timestamp is
+	// echoed back by the server as the originate timestamp.
+	req := make([]byte, ntpPacketSize)
+	req[0] = 0<<6 | 4<<3 | 3
+
+	sent := time.Now()
+	binary.BigEndian.PutUint64(req[40:], toNTP(sent))
+	if _, err = conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	rep := make([]byte, ntpPacketSize)
+	var n int
+	if n, err = conn.Read(rep); err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(sent)
+
+	if n < ntpPacketSize {
+		return nil, errors.New("short ntp response")
+	}
+
+	if mode := rep[0] & 0x7; mode != 4 {
+		return nil, fmt.Errorf("unexpected ntp response mode %d", mode)
+	}
+
+	if leap := rep[0] >> 6; leap == 3 {
+		return nil, errors.New("ntp server clock is not synchronized")
+	}
+
+	drift := &Drift{Server: addr, Stratum: rep[1]}
+	if drift.Stratum == 0 {
+		return nil, fmt.Errorf("ntp server sent kiss of death %q", string(rep[12:16]))
+	}
+
+	if binary.BigEndian.Uint64(rep[24:]) != binary.BigEndian.Uint64(req[40:]) {
+		return nil, errors.New("ntp response does not match the request")
+	}
+
+	// t1 and t4 are the local send and receive times, t2 and t3 are the server
+	// receive and transmit times; the monotonic elapsed time is used for t4-t1.
+	t1 := sent
+	t2 := fromNTP(binary.BigEndian.Uint64(rep[32:]))
+	t3 := fromNTP(binary.BigEndian.Uint64(rep[40:]))
+	t4 := t1.Add(elapsed)
+
+	drift.Offset = (t2.Sub(t1) + t3.Sub(t4)) / 2
+	drift.Delay = elapsed - t3.Sub(t2)
+	if drift.Delay < 0 {
+		drift.Delay = 0
+	}
+	return drift, nil
+}
+
+// converts a time into a 64-bit NTP timestamp
+func toNTP(t time.Time) uint64 {
+	secs := uint64(t.Unix() + ntpEpochOffset)
+	frac := (uint64(t.Nanosecond()) << 32) / uint64(time.Second)
+	return secs<<32 | frac
+}
+
+// converts a 64-bit NTP timestamp into a time
+func fromNTP(ts uint64) time.Time {
+	secs := int64(ts>>32) - ntpEpochOffset
+	nsec := int64(((ts & 0xffffffff) * uint64(time.Second)) >> 32)
+	return time.Unix(secs, nsec)
+}
+
+// formats a duration with an explicit sign
+func signed(d time.Duration) string {
+	if d >= 0 {
+		return "+" + d.String()
+	}
+	return d.String()
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Starts an SNTP server on the loopback interface whose clock is offset from the
+// local clock. The reply can be modified before it is sent to test bad responses.
+func ntpServer(t *testing.T, offset time.Duration, modify func(rep []byte)) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		req := make([]byte, ntpPacketSize)
+		for {
+			n, addr, err := conn.ReadFrom(req)
+			if err != nil {
+				return
+			}
+
+			recv := time.Now().Add(offset)
+			rep := make([]byte, ntpPacketSize)
+			rep[0] = 0<<6 | 4<<3 | 4
+			rep[1] = 2
+			if n >= ntpPacketSize {
+				copy(rep[24:32], req[40:48])
+			}
+			binary.BigEndian.PutUint64(rep[32:], toNTP(recv))
+			binary.BigEndian.PutUint64(rep[40:], toNTP(time.Now().Add(offset)))
+
+			if modify != nil {
+				modify(rep)
+			}
+			conn.WriteTo(rep, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestQueryNTP(t *testing.T) {
+	tests := []struct {
+		offset time.Duration
+	}{
+		{0}, {time.Hour}, {-90 * time.Second}, {1500 * time.Millisecond},
+	}
+
+	for _, tc := range tests {
+		addr := ntpServer(t, tc.offset, nil)
+		drift, err := queryNTP(addr, time.Second)
+		if err != nil {
+			t.Errorf("expected no error querying a server offset by %s got %s", tc.offset, err)
+			continue
+		}
+
+		if diff := drift.Offset - tc.offset; diff < -50*time.Millisecond || diff > 50*time.Millisecond {
+			t.Errorf("expected an offset of about %s got %s", tc.offset, drift.Offset)
+		}
+
+		if drift.Delay < 0 || drift.Delay > time.Second {
+			t.Errorf("expected a small round trip delay got %s", drift.Delay)
+		}
+
+		if drift.Server != addr || drift.Stratum != 2 {
+			t.Errorf("unexpected server %q or stratum %d", drift.Server, drift.Stratum)
+		}
+	}
+}
+
+func TestQueryNTPErrors(t *testing.T) {
+	tests := []struct {
+		modify func([]byte)
+		err    string
+	}{
+		{func(rep []byte) { rep[0] = 0<<6 | 4<<3 | 3 }, "unexpected ntp response mode 3"},
+		{func(rep []byte) { rep[0] = 3<<6 | 4<<3 | 4 }, "not synchronized"},
+		{func(rep []byte) { rep[1] = 0; copy(rep[12:16], "RATE") }, `kiss of death "RATE"`},
+		{func(rep []byte) { rep[24]++ }, "does not match the request"},
+	}
+
+	for _, tc := range tests {
+		addr := ntpServer(t, 0, tc.modify)
+		if _, err := queryNTP(addr, time.Second); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("expected error containing %q got %v", tc.err, err)
+		}
+	}
+
+	// A server that never responds times out
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err = queryNTP(conn.LocalAddr().String(), 50*time.Millisecond); err == nil {
+		t.Error("expected an error when the server does not respond")
+	}
+}
+
+func TestNTPTimestamps(t *testing.T) {
+	tests := []time.Time{
+		time.Unix(0, 0),
+		time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 2, 14, 30, 0, 500000000, time.UTC),
+		time.Date(2036, 2, 7, 6, 28, 15, 999999999, time.UTC),
+	}
+
+	for _, ts := range tests {
+		// NTP fractions have a resolution of about 233 picoseconds
+		if rt := fromNTP(toNTP(ts)); rt.Sub(ts) > time.Nanosecond || ts.Sub(rt) > time.Nanosecond {
+			t.Errorf("expected %s to round trip got %s", ts, rt)
+		}
+	}
+
+	if ts := toNTP(time.Unix(0, 0)); ts>>32 != ntpEpochOffset || ts&0xffffffff != 0 {
+		t.Errorf("expected the unix epoch to be %d seconds after the ntp epoch got %d", ntpEpochOffset, ts>>32)
+	}
+}
+
+func TestDrift(t *testing.T) {
+	tests := []struct {
+		offset time.Duration
+		sign   string
+	}{
+		{time.Second, "offset +1s"},
+		{-time.Second, "offset -1s"},
+		{0, "offset +0s"},
+	}
+
+	for _, tc := range tests {
+		d := &Drift{Server: "127.0.0.1:123", Offset: tc.offset, Delay: time.Millisecond, Stratum: 1}
+		if s := d.String(); !strings.HasPrefix(s, tc.sign) || !strings.HasSuffix(s, "(127.0.0.1:123 stratum 1)") {
+			t.Errorf("unexpected drift description %q", s)
+		}
+	}
+
+	// The threshold health check exits with status 2
+	addr := ntpServer(t, time.Minute, nil)
+	if _, err := run(t, "drift", "--threshold", "1h", addr); err != nil {
+		t.Errorf("expected no error within the threshold got %s", err)
+	}
+
+	_, err := run(t, "drift", "--threshold", "1s", addr)
+	if code, ok := err.(interface{ ExitCode() int }); !ok || code.ExitCode() != 2 {
+		t.Errorf("expected exit status 2 when the threshold is exceeded got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+//===========================================================================
+// strftime Layouts
+//===========================================================================
+
+// Go layouts for strftime directives (the character following the %). The
+// directives prefixed with a - (e.g. %-d) are the unpadded forms.
+var strftimeDirectives = map[string]string{
+	"a":  "Mon",
+	"A":  "Monday",
+	"b":  "Jan",
+	"h":  "Jan",
+	"B":  "January",
+	"c":  "Mon Jan _2 15:04:05 2006",
+	"C":  "", // century is not supported by Go layouts
+	"d":  "02",
+	"-d": "2",
+	"D":  "01/02/06",
+	"e":  "_2",
+	"f":  "000000",
+	"F":  "2006-01-02",
+	"H":  "15",
+	"I":  "03",
+	"-I": "3",
+	"j":  "002",
+	"L":  "000",
+	"m":  "01",
+	"-m": "1",
+	"M":  "04",
+	"-M": "4",
+	"n":  "\n",
+	"p":  "PM",
+	"r":  "03:04:05 PM",
+	"R":  "15:04",
+	"S":  "05",
+	"-S": "5",
+	"t":  "\t",
+	"T":  "15:04:05",
+	"x":  "01/02/06",
+	"X":  "15:04:05",
+	"y":  "06",
+	"Y":  "2006",
+	"z":  "-0700",
+	":z": "-07:00",
+	"Z":  "MST",
+	"%":  "%",
+}
+
+// Returns true if the layout looks like a strftime format string rather than a
+// Go reference time layout.
+func isStrftime(s string) bool {
+	return strings.Contains(s, "%")
+}
+
+// Translate a strftime format string such as %Y-%m-%d %H:%M into the equivalent
+// Go layout. Literal text is copied as is; note that Go interprets reference
+// time values in literal text (e.g. Jan or 2006) as part of the layout. An
+// error is returned for unknown or unsupported directives.
+func strftime(s string) (string, error) {
+	var layout strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			layout.WriteByte(s[i])
+			continue
+		}
+
+		// Directives are one character with an optional - or : modifier
+		if i+1 >= len(s) {
+			return "", fmt.Errorf("incomplete directive at the end of %q", s)
+		}
+
+		directive := s[i+1 : i+2]
+		if (directive == "-" || directive == ":") && i+2 < len(s) {
+			directive = s[i+1 : i+3]
+		}
+
+		translated, ok := strftimeDirectives[directive]
+		if !ok || translated == "" {
+			return "", fmt.Errorf("unsupported strftime directive %%%s in %q", directive, s)
+		}
+
+		layout.WriteString(translated)
+		i += len(directive)
+	}
+	return layout.String(), nil
+}
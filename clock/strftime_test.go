@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStrftime(t *testing.T) {
+	tests := []struct {
+		s      string
+		layout string
+		err    bool
+	}{
+		{"%Y-%m-%d %H:%M", "2006-01-02 15:04", false},
+		{"%F %T", "2006-01-02 15:04:05", false},
+		{"%a %b %e %H:%M:%S %Y", "Mon Jan _2 15:04:05 2006", false},
+		{"%A, %B %-d", "Monday, January 2", false},
+		{"%I:%M %p", "03:04 PM", false},
+		{"%-I:%M%p", "3:04PM", false},
+		{"%-m/%-d/%y", "1/2/06", false},
+		{"%D", "01/02/06", false},
+		{"%H:%M:%S.%L", "15:04:05.000", false},
+		{"%S.%f", "05.000000", false},
+		{"%j", "002", false},
+		{"%z %:z %Z", "-0700 -07:00 MST", false},
+		{"100%%", "100%", false},
+		{"%n%t", "\n\t", false},
+		{"day %d of %h", "day 02 of Jan", false},
+		{"%", "", true},
+		{"%Y-%", "", true},
+		{"%Q", "", true},
+		{"%C", "", true},
+		{"%-", "", true},
+		{"%-Y", "", true},
+		{"%:Y", "", true},
+	}
+
+	for _, tc := range tests {
+		layout, err := strftime(tc.s)
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected an error translating %q got %q", tc.s, layout)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("expected no error translating %q got %s", tc.s, err)
+			continue
+		}
+
+		if layout != tc.layout {
+			t.Errorf("expected %q to be translated to %q got %q", tc.s, tc.layout, layout)
+		}
+	}
+}
+
+func TestIsStrftime(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected bool
+	}{
+		{"%Y-%m-%d", true},
+		{"100%%", true},
+		{"2006-01-02", false},
+		{"kitchen", false},
+		{"", false},
+	}
+
+	for _, tc := range tests {
+		if actual := isStrftime(tc.s); actual != tc.expected {
+			t.Errorf("expected isStrftime(%q) to be %t got %t", tc.s, tc.expected, actual)
+		}
+	}
+}
+
+func TestStrftimeFormat(t *testing.T) {
+	freeze(t, frozen.Add(7*time.Second+123456789*time.Nanosecond))
+
+	tests := []struct {
+		args     []string
+		expected string
+		err      bool
+	}{
+		{[]string{"--utc", "%Y-%m-%d %H:%M"}, "2024-05-02 14:30", false},
+		{[]string{"--utc", "%A %-d %B %Y"}, "Thursday 2 May 2024", false},
+		{[]string{"--utc", "%-I:%M:%S %p"}, "2:30:07 PM", false},
+		{[]string{"--utc", "%T.%L"}, "14:30:07.123", false},
+		{[]string{"--utc", "%j"}, "123", false},
+		{[]string{"--tz", "Asia/Kolkata", "%H:%M %:z"}, "20:00 +05:30", false},
+		{[]string{"--utc", "--locale", "fr", "%a %d %b"}, "jeu. 02 mai", false},
+		{[]string{"--utc", "after", "-f", "%F", "1w"}, "2024-05-09", false},
+		{[]string{"--utc", "parse", "-f", "%s", "1714660200"}, "", true},
+		{[]string{"--utc", "%Q"}, "", true},
+	}
+
+	for _, tc := range tests {
+		out, err := run(t, tc.args...)
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected an error running %q got %q", tc.args, out)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("expected no error running %q got %s", tc.args, err)
+			continue
+		}
+
+		if out != tc.expected {
+			t.Errorf("expected %q running %q got %q", tc.expected, tc.args, out)
+		}
+	}
+}
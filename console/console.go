@@ -4,6 +4,7 @@ Package console implements simple hierarchical logging functionality.
 package console
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strings"
@@ -57,14 +58,25 @@ func SetLogLevel(level uint8) {
 //===========================================================================
 
 // Print to the standard logger at the specified level. Arguments are handled
-// in the manner of log.Printf, but a newline is appended.
+// in the manner of log.Printf, but a newline is appended. Repeated messages
+// are suppressed if a deduplication window is set.
 func print(level uint8, msg string, a ...interface{}) {
+	output(logger, defaultPrefix(), level, msg, a...)
+}
+
+// Print to the specified logger at the specified level, first updating the
+// prefix of the logger if a dynamic prefix function is specified.
+func output(l *log.Logger, prefix func() string, level uint8, msg string, a ...interface{}) {
 	if level >= logLevel {
 		if !strings.HasSuffix(msg, "\n") {
 			msg += "\n"
 		}
 
-		logger.Printf(msg, a...)
+		if prefix != nil {
+			l.SetPrefix(prefix())
+		}
+
+		dedup(l, fmt.Sprintf(msg, a...))
 	}
 }
 
@@ -74,11 +86,6 @@ func Warn(msg string, a ...interface{}) {
 	print(LevelWarn, msg, a...)
 }
 
-// Warne is a helper function to simply warn about an error received.
-func Warne(err error) {
-	Warn(err.Error())
-}
-
 // Status prints to the standard logger if level is status or greater;
 // arguments are handled in the manner of log.Printf, but a newline is
 // appended.
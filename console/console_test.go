@@ -1 +1,247 @@
 package console
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Redirects the logger to a buffer for the duration of a test.
+func capture(t *testing.T, level uint8) *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	prevLogger, prevLevel := logger, logLevel
+	logger = log.New(buf, "", 0)
+	logLevel = level
+
+	t.Cleanup(func() {
+		logger, logLevel = prevLogger, prevLevel
+	})
+	return buf
+}
+
+func TestWrap(t *testing.T) {
+	if Wrap(nil, "nothing happened") != nil {
+		t.Error("expected wrapping a nil error to return nil")
+	}
+
+	cause := errors.New("connection refused")
+	err := Wrap(cause, "could not sync %d peers", 3)
+	if err.Error() != "could not sync 3 peers: connection refused" {
+		t.Errorf("unexpected error message %q", err.Error())
+	}
+
+	if !errors.Is(err, cause) {
+		t.Error("expected wrapped error to unwrap to its cause")
+	}
+}
+
+func TestErroreInfo(t *testing.T) {
+	buf := capture(t, LevelInfo)
+	Errore(Wrap(errors.New("connection refused"), "could not sync"))
+
+	if out := buf.String(); out != "error: could not sync: connection refused\n" {
+		t.Errorf("unexpected output at info level: %q", out)
+	}
+}
+
+func TestErroreDebug(t *testing.T) {
+	buf := capture(t, LevelDebug)
+	Errore(Wrap(errors.New("connection refused"), "could not sync"))
+
+	out := buf.String()
+	if !strings.Contains(out, "  caused by: connection refused\n") {
+		t.Errorf("expected cause in debug output: %q", out)
+	}
+
+	if !strings.Contains(out, "  at github.com/bbengfort/x/console.TestErroreDebug (console/console_test.go:") {
+		t.Errorf("expected stack of the wrap in debug output: %q", out)
+	}
+}
+
+func TestWarneDebug(t *testing.T) {
+	buf := capture(t, LevelDebug)
+	Warne(Wrap(errors.New("100% failure"), "could not sync"))
+
+	if out := buf.String(); out != "could not sync: 100% failure\n  caused by: 100% failure\n" {
+		t.Errorf("unexpected warne output at debug level: %q", out)
+	}
+}
+
+func TestDedup(t *testing.T) {
+	buf := capture(t, LevelInfo)
+	SetDedupWindow(time.Hour)
+	t.Cleanup(func() { SetDedupWindow(0) })
+
+	for i := 0; i < 5; i++ {
+		Warn("could not connect to %s", "peer")
+	}
+	Info("connected")
+	Info("connected")
+
+	expected := "could not connect to peer\nlast message repeated 4 times\nconnected\n"
+	if out := buf.String(); out != expected {
+		t.Errorf("unexpected dedup output: %q", out)
+	}
+
+	// Disabling deduplication flushes the pending summary
+	SetDedupWindow(0)
+	expected += "last message repeated 1 time\n"
+	if out := buf.String(); out != expected {
+		t.Errorf("unexpected dedup output after disable: %q", out)
+	}
+}
+
+func TestDedupWindow(t *testing.T) {
+	buf := capture(t, LevelInfo)
+	SetDedupWindow(20 * time.Millisecond)
+	t.Cleanup(func() { SetDedupWindow(0) })
+
+	Info("retrying")
+	Info("retrying")
+	Info("retrying")
+	time.Sleep(50 * time.Millisecond)
+	Info("retrying")
+
+	dedupMu.Lock()
+	out := buf.String()
+	dedupMu.Unlock()
+
+	if out != "retrying\nlast message repeated 2 times\nretrying\n" {
+		t.Errorf("unexpected dedup output: %q", out)
+	}
+}
+
+// Redirects reported errors to a buffer for the duration of a test.
+func captureErrors(t *testing.T, json bool) *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	SetErrorOutput(buf)
+	SetJSON(json)
+
+	t.Cleanup(func() {
+		SetErrorOutput(os.Stderr)
+		SetJSON(false)
+	})
+	return buf
+}
+
+func TestError(t *testing.T) {
+	buf := captureErrors(t, false)
+	report := Error("not_found", "no such peer", "run peers sync")
+
+	if out := buf.String(); out != "error[not_found]: no such peer\n  hint: run peers sync\n" {
+		t.Errorf("unexpected error output: %q", out)
+	}
+
+	if report.Error() != "not_found: no such peer" {
+		t.Errorf("unexpected report error message %q", report.Error())
+	}
+
+	buf.Reset()
+	Error("timeout", "deadline exceeded", "")
+	if out := buf.String(); out != "error[timeout]: deadline exceeded\n" {
+		t.Errorf("unexpected error output without hint: %q", out)
+	}
+}
+
+func TestErrorJSON(t *testing.T) {
+	buf := captureErrors(t, true)
+	Error("not_found", "no such peer", "run peers sync")
+	Error("timeout", "deadline exceeded", "")
+
+	expected := `{"error":{"code":"not_found","message":"no such peer","hint":"run peers sync"}}` + "\n" +
+		`{"error":{"code":"timeout","message":"deadline exceeded"}}` + "\n"
+	if out := buf.String(); out != expected {
+		t.Errorf("unexpected json error output: %q", out)
+	}
+}
+
+func TestNamedLogger(t *testing.T) {
+	capture(t, LevelInfo)
+	t.Cleanup(func() {
+		namedMu.Lock()
+		delete(named, "raft")
+		delete(named, "sync")
+		namedMu.Unlock()
+	})
+
+	raft := InitNamed("raft", "[raft] ", 0)
+	if Named("raft") != raft {
+		t.Fatal("expected named logger to be returned by name")
+	}
+
+	buf := new(bytes.Buffer)
+	raft.logger.SetOutput(buf)
+
+	role := "follower"
+	raft.Info("started")
+	raft.SetPrefixFunc(func() string { return "[raft " + role + "] " })
+	raft.Info("election timeout")
+	role = "leader"
+	raft.Info("elected")
+	raft.Debug("heartbeat")
+
+	if raft.Prefix() != "[raft leader] " {
+		t.Errorf("unexpected dynamic prefix %q", raft.Prefix())
+	}
+
+	raft.SetPrefix("[consensus] ")
+	raft.Warn("stepping down")
+
+	expected := "[raft] started\n[raft follower] election timeout\n[raft leader] elected\n[consensus] stepping down\n"
+	if out := buf.String(); out != expected {
+		t.Errorf("unexpected named logger output: %q", out)
+	}
+
+	// Reinitializing the logger updates it in place
+	if InitNamed("raft", "[raft] ", log.Lmsgprefix) != raft || raft.Prefix() != "[raft] " {
+		t.Error("expected the named logger to be reinitialized in place")
+	}
+
+	if other := Named("sync"); other.Prefix() != "[sync] " || other.Name() != "sync" {
+		t.Errorf("unexpected default named logger %q with prefix %q", other.Name(), other.Prefix())
+	}
+}
+
+func TestNamedDedup(t *testing.T) {
+	buf := capture(t, LevelInfo)
+	SetDedupWindow(time.Hour)
+	t.Cleanup(func() {
+		SetDedupWindow(0)
+		namedMu.Lock()
+		delete(named, "raft")
+		namedMu.Unlock()
+	})
+
+	raft := InitNamed("raft", "[raft] ", 0)
+	raft.logger.SetOutput(buf)
+
+	// Identical messages from different loggers are not duplicates
+	Info("connecting")
+	raft.Info("connecting")
+	raft.Info("connecting")
+	Info("connecting")
+
+	expected := "connecting\n[raft] connecting\n[raft] last message repeated 1 time\nconnecting\n"
+	if out := buf.String(); out != expected {
+		t.Errorf("unexpected named dedup output: %q", out)
+	}
+}
+
+func TestSetPrefix(t *testing.T) {
+	buf := capture(t, LevelInfo)
+	t.Cleanup(func() { SetPrefixFunc(nil) })
+
+	SetPrefix("[app] ")
+	Info("started")
+
+	SetPrefixFunc(func() string { return "[app leader] " })
+	Status("elected")
+
+	if out := buf.String(); out != "[app] started\n[app leader] elected\n" {
+		t.Errorf("unexpected prefix output: %q", out)
+	}
+}
@@ -0,0 +1,96 @@
+package console
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+//===========================================================================
+// Message deduplication
+//===========================================================================
+
+// Deduplication state, protected by dedupMu. The window is zero (disabled) by
+// default.
+var (
+	dedupMu      sync.Mutex
+	dedupWindow  time.Duration
+	dedupLogger  *log.Logger
+	dedupLast    string
+	dedupSince   time.Time
+	dedupRepeats int
+	dedupTimer   *time.Timer
+)
+
+// SetDedupWindow suppresses identical messages that are repeated within the
+// window of the first occurrence of the message, e.g. from a tight retry loop.
+// When the window elapses or a different message is printed, a summary of the
+// form "last message repeated N times" is printed in place of the suppressed
+// messages. A window of zero (the default) disables deduplication; setting the
+// window flushes any pending summary.
+func SetDedupWindow(window time.Duration) {
+	dedupMu.Lock()
+	defer dedupMu.Unlock()
+
+	flushRepeats()
+	dedupWindow = window
+	dedupLast = ""
+}
+
+// Writes the message to the logger unless it is a duplicate of the last message
+// written to the same logger within the deduplication window. The message must
+// already be formatted.
+func dedup(l *log.Logger, msg string) {
+	dedupMu.Lock()
+	defer dedupMu.Unlock()
+
+	if dedupWindow <= 0 {
+		l.Print(msg)
+		return
+	}
+
+	now := time.Now()
+	if l == dedupLogger && msg == dedupLast && now.Sub(dedupSince) < dedupWindow {
+		dedupRepeats++
+		if dedupTimer == nil {
+			var timer *time.Timer
+			timer = time.AfterFunc(dedupWindow-now.Sub(dedupSince), func() {
+				dedupMu.Lock()
+				defer dedupMu.Unlock()
+
+				// Ignore the timer if the repeats were already flushed
+				if dedupTimer == timer {
+					flushRepeats()
+					dedupLast = ""
+				}
+			})
+			dedupTimer = timer
+		}
+		return
+	}
+
+	flushRepeats()
+	l.Print(msg)
+	dedupLogger = l
+	dedupLast = msg
+	dedupSince = now
+}
+
+// Prints a summary of the suppressed messages, if any, and stops the timer.
+// The caller must hold dedupMu.
+func flushRepeats() {
+	if dedupTimer != nil {
+		dedupTimer.Stop()
+		dedupTimer = nil
+	}
+
+	switch dedupRepeats {
+	case 0:
+		return
+	case 1:
+		dedupLogger.Print("last message repeated 1 time\n")
+	default:
+		dedupLogger.Printf("last message repeated %d times\n", dedupRepeats)
+	}
+	dedupRepeats = 0
+}
@@ -0,0 +1,152 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// StackDepth is the maximum number of frames of the stack that are captured
+// when an error is wrapped and printed when the level is debug or trace.
+var StackDepth = 8
+
+//===========================================================================
+// Error wrapping with stack capture
+//===========================================================================
+
+// Wrap annotates the error with a message formatted in the manner of
+// fmt.Sprintf and captures an abbreviated stack of the caller so that the
+// origin of the error can be printed by Errore. Wrapped errors can be
+// inspected with errors.Is and errors.As. Wrap returns nil if err is nil.
+func Wrap(err error, msg string, a ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+
+	return &stackError{
+		msg:    fmt.Sprintf(msg, a...),
+		err:    err,
+		frames: callers(3),
+	}
+}
+
+// stackError is an error with a message and the stack where it was wrapped.
+type stackError struct {
+	msg    string
+	err    error
+	frames []uintptr
+}
+
+// Error returns the message along with the wrapped error message.
+func (e *stackError) Error() string {
+	return e.msg + ": " + e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *stackError) Unwrap() error {
+	return e.err
+}
+
+//===========================================================================
+// Error output functions
+//===========================================================================
+
+// Warne is a helper function to simply warn about an error received. If the
+// level is debug or trace, the chain of wrapped errors is also printed.
+func Warne(err error) {
+	warne(Warn, err)
+}
+
+// Errore prints the error at the warn level. If the level is debug or trace,
+// the chain of wrapped errors and an abbreviated stack are also printed. The
+// stack is the stack captured by the innermost call to Wrap, or the stack of
+// the caller of Errore if the error was not wrapped by this package.
+func Errore(err error) {
+	errore(Warn, err)
+}
+
+//===========================================================================
+// Helpers
+//===========================================================================
+
+// Prints the error and, if the level is debug or trace, the chain of wrapped
+// errors using the specified warn function.
+func warne(warn func(string, ...interface{}), err error) {
+	if logLevel > LevelDebug {
+		warn("%s", err)
+		return
+	}
+
+	lines := []string{err.Error()}
+	lines = append(lines, causes(err)...)
+	warn("%s", strings.Join(lines, "\n"))
+}
+
+// Prints the error, the chain of wrapped errors, and the stack using the
+// specified warn function (see Errore). Must be called directly by the exported
+// function so that the stack of the caller is captured correctly.
+func errore(warn func(string, ...interface{}), err error) {
+	if logLevel > LevelDebug {
+		warn("error: %s", err)
+		return
+	}
+
+	var frames []uintptr
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if serr, ok := e.(*stackError); ok {
+			frames = serr.frames
+		}
+	}
+
+	if frames == nil {
+		frames = callers(4)
+	}
+
+	lines := []string{"error: " + err.Error()}
+	lines = append(lines, causes(err)...)
+	lines = append(lines, stack(frames)...)
+	warn("%s", strings.Join(lines, "\n"))
+}
+
+// Returns a line for each error wrapped by err.
+func causes(err error) []string {
+	lines := make([]string, 0)
+	for e := errors.Unwrap(err); e != nil; e = errors.Unwrap(e) {
+		lines = append(lines, "  caused by: "+e.Error())
+	}
+	return lines
+}
+
+// Returns the program counters of the stack, skipping the specified frames.
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, StackDepth)
+	n := runtime.Callers(skip, pcs)
+	return pcs[:n]
+}
+
+// Returns a line for each frame in the stack, ignoring runtime frames.
+func stack(pcs []uintptr) []string {
+	lines := make([]string, 0, len(pcs))
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" && !strings.HasPrefix(frame.Function, "runtime.") {
+			lines = append(lines, fmt.Sprintf("  at %s (%s:%d)", frame.Function, shortPath(frame.File), frame.Line))
+		}
+
+		if !more {
+			break
+		}
+	}
+	return lines
+}
+
+// Returns the file name and its parent directory from a full path.
+func shortPath(path string) string {
+	parts := strings.Split(path, "/")
+	if len(parts) > 2 {
+		return strings.Join(parts[len(parts)-2:], "/")
+	}
+	return path
+}
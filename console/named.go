@@ -0,0 +1,176 @@
+package console
+
+import (
+	"log"
+	"os"
+	"sync"
+)
+
+// Named loggers and the dynamic prefix of the default logger, protected by
+// namedMu.
+var (
+	namedMu    sync.RWMutex
+	named      = make(map[string]*Logger)
+	prefixFunc func() string
+)
+
+//===========================================================================
+// Named loggers
+//===========================================================================
+
+// Logger is a named console logger for a subsystem with its own prefix and
+// flags, e.g. so that the raft and replication subsystems of a process can be
+// distinguished in the console output. Named loggers share the log level and
+// deduplication window of the package.
+type Logger struct {
+	sync.RWMutex
+	name   string
+	logger *log.Logger
+	prefix func() string
+}
+
+// InitNamed initializes the named logger with the prefix and log options and
+// returns it. If the named logger already exists it is reinitialized in place
+// so that references to the logger remain valid.
+func InitNamed(name, prefix string, flag int) *Logger {
+	namedMu.Lock()
+	defer namedMu.Unlock()
+
+	l, ok := named[name]
+	if !ok {
+		l = &Logger{name: name}
+		named[name] = l
+	}
+
+	l.Lock()
+	l.logger = log.New(os.Stdout, prefix, flag)
+	l.prefix = nil
+	l.Unlock()
+	return l
+}
+
+// Named returns the named logger, initializing it with the prefix "[name] "
+// and the flags of the default logger if it has not been initialized.
+func Named(name string) *Logger {
+	namedMu.RLock()
+	l, ok := named[name]
+	namedMu.RUnlock()
+
+	if ok {
+		return l
+	}
+
+	flag := log.LstdFlags
+	if logger != nil {
+		flag = logger.Flags()
+	}
+	return InitNamed(name, "["+name+"] ", flag)
+}
+
+// Name returns the name of the logger.
+func (l *Logger) Name() string {
+	return l.name
+}
+
+// Prefix returns the current prefix of the logger, evaluating the dynamic
+// prefix function if one is set.
+func (l *Logger) Prefix() string {
+	l.RLock()
+	defer l.RUnlock()
+
+	if l.prefix != nil {
+		return l.prefix()
+	}
+	return l.logger.Prefix()
+}
+
+// SetPrefix changes the prefix of the logger at runtime, replacing any dynamic
+// prefix function.
+func (l *Logger) SetPrefix(prefix string) {
+	l.Lock()
+	defer l.Unlock()
+	l.prefix = nil
+	l.logger.SetPrefix(prefix)
+}
+
+// SetPrefixFunc sets a function that is called to compute the prefix every time
+// a message is printed, e.g. to include the current role of a replica
+// ("leader" or "follower") so that the output is informative during role
+// transitions. The function must be safe for concurrent use. A nil function
+// keeps the last computed prefix.
+func (l *Logger) SetPrefixFunc(fn func() string) {
+	l.Lock()
+	defer l.Unlock()
+	l.prefix = fn
+}
+
+// Warn prints to the named logger if level is warn or greater.
+func (l *Logger) Warn(msg string, a ...interface{}) {
+	l.print(LevelWarn, msg, a...)
+}
+
+// Status prints to the named logger if level is status or greater.
+func (l *Logger) Status(msg string, a ...interface{}) {
+	l.print(LevelStatus, msg, a...)
+}
+
+// Info prints to the named logger if level is info or greater.
+func (l *Logger) Info(msg string, a ...interface{}) {
+	l.print(LevelInfo, msg, a...)
+}
+
+// Debug prints to the named logger if level is debug or greater.
+func (l *Logger) Debug(msg string, a ...interface{}) {
+	l.print(LevelDebug, msg, a...)
+}
+
+// Trace prints to the named logger if level is trace or greater.
+func (l *Logger) Trace(msg string, a ...interface{}) {
+	l.print(LevelTrace, msg, a...)
+}
+
+// Warne warns about an error received using the named logger (see Warne).
+func (l *Logger) Warne(err error) {
+	warne(l.Warn, err)
+}
+
+// Errore prints the error using the named logger (see Errore).
+func (l *Logger) Errore(err error) {
+	errore(l.Warn, err)
+}
+
+// Print to the named logger at the specified level.
+func (l *Logger) print(level uint8, msg string, a ...interface{}) {
+	l.RLock()
+	defer l.RUnlock()
+	output(l.logger, l.prefix, level, msg, a...)
+}
+
+//===========================================================================
+// Default logger prefix
+//===========================================================================
+
+// SetPrefix changes the prefix of the default console logger at runtime,
+// replacing any dynamic prefix function.
+func SetPrefix(prefix string) {
+	namedMu.Lock()
+	defer namedMu.Unlock()
+	prefixFunc = nil
+	logger.SetPrefix(prefix)
+}
+
+// SetPrefixFunc sets a function that is called to compute the prefix of the
+// default console logger every time a message is printed (see
+// Logger.SetPrefixFunc).
+func SetPrefixFunc(fn func() string) {
+	namedMu.Lock()
+	defer namedMu.Unlock()
+	prefixFunc = fn
+}
+
+// Returns the dynamic prefix function of the default logger, if any.
+func defaultPrefix() func() string {
+	namedMu.RLock()
+	defer namedMu.RUnlock()
+	return prefixFunc
+}
@@ -0,0 +1,199 @@
+/*
+Package diff computes line-based and structural differences between contents.
+
+Unified returns a unified diff of the lines of two contents, e.g. to show a user
+the changes they made before they are saved:
+
+	fmt.Print(diff.Unified(original, edited, "config.yaml", "edited"))
+
+JSON returns the structural changes between two JSON documents as a list of the
+paths that were added, removed, or modified, e.g. to log configuration changes
+or to report what a synchronization merge changed:
+
+	changes, err := diff.JSON(before, after)
+	for _, change := range changes {
+		log.Println(change)
+	}
+*/
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Context is the number of unchanged lines shown around each change in a
+// unified diff.
+const Context = 3
+
+//===========================================================================
+// Line Diffs
+//===========================================================================
+
+// Op describes how a line changed in a diff.
+type Op byte
+
+// Line operations, represented by the prefix of the line in a unified diff.
+const (
+	Equal  Op = ' '
+	Delete Op = '-'
+	Insert Op = '+'
+)
+
+// Line is a line of a diff and how it changed.
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// String returns the line prefixed by its operation as in a unified diff.
+func (l Line) String() string {
+	return string(l.Op) + l.Text
+}
+
+// Lines computes the line diff from a to b using the longest common subsequence,
+// returning the lines of both in order: unchanged lines, lines deleted from a,
+// and lines inserted from b.
+func Lines(a, b []string) []Line {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := make([]Line, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, Line{Equal, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{Delete, a[i]})
+			i++
+		default:
+			lines = append(lines, Line{Insert, b[j]})
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		lines = append(lines, Line{Delete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, Line{Insert, b[j]})
+	}
+	return lines
+}
+
+// Unified returns a unified diff of the changes from a to b, labeling the
+// original and changed content with the specified names. An empty string is
+// returned if the contents are the same.
+func Unified(a, b []byte, nameA, nameB string) string {
+	lines := Lines(split(a), split(b))
+
+	changed := false
+	for _, line := range lines {
+		if line.Op != Equal {
+			changed = true
+			break
+		}
+	}
+
+	if !changed {
+		return ""
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "--- %s\n+++ %s\n", nameA, nameB)
+
+	// Line numbers (0-indexed) in a and b at the start of each diff line
+	posA, posB := make([]int, len(lines)+1), make([]int, len(lines)+1)
+	for i, line := range lines {
+		posA[i+1], posB[i+1] = posA[i], posB[i]
+		if line.Op != Insert {
+			posA[i+1]++
+		}
+		if line.Op != Delete {
+			posB[i+1]++
+		}
+	}
+
+	for i := 0; i < len(lines); {
+		if lines[i].Op == Equal {
+			i++
+			continue
+		}
+
+		// Extend the hunk until there are more than twice the context unchanged lines
+		start := i - Context
+		if start < 0 {
+			start = 0
+		}
+
+		end := i
+		for end < len(lines) {
+			if lines[end].Op != Equal {
+				end++
+				continue
+			}
+
+			run := end
+			for run < len(lines) && lines[run].Op == Equal {
+				run++
+			}
+
+			if run == len(lines) || run-end > 2*Context {
+				end += Context
+				if end > len(lines) {
+					end = len(lines)
+				}
+				break
+			}
+			end = run
+		}
+
+		countA, countB := posA[end]-posA[start], posB[end]-posB[start]
+		fmt.Fprintf(buf, "@@ -%s +%s @@\n", hunkRange(posA[start], countA), hunkRange(posB[start], countB))
+		for _, line := range lines[start:end] {
+			fmt.Fprintf(buf, "%s\n", line)
+		}
+		i = end
+	}
+	return buf.String()
+}
+
+// Formats the range of a hunk, which is 1-indexed unless the range is empty.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// Splits the contents into lines, ignoring the trailing newline.
+func split(data []byte) []string {
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,89 @@
+package diff_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bbengfort/x/diff"
+	. "github.com/onsi/gomega"
+)
+
+func TestLines(t *testing.T) {
+	RegisterTestingT(t)
+
+	lines := diff.Lines([]string{"a", "b", "c"}, []string{"a", "c", "d"})
+	Ω(lines).Should(Equal([]diff.Line{
+		{diff.Equal, "a"},
+		{diff.Delete, "b"},
+		{diff.Equal, "c"},
+		{diff.Insert, "d"},
+	}))
+	Ω(lines[1].String()).Should(Equal("-b"))
+
+	Ω(diff.Lines(nil, nil)).Should(BeEmpty())
+	Ω(diff.Lines(nil, []string{"a"})).Should(Equal([]diff.Line{{diff.Insert, "a"}}))
+}
+
+func TestUnified(t *testing.T) {
+	RegisterTestingT(t)
+
+	Ω(diff.Unified([]byte("a\nb\n"), []byte("a\nb\n"), "a", "b")).Should(BeEmpty())
+	Ω(diff.Unified(nil, []byte("a\n"), "a", "b")).Should(Equal("--- a\n+++ b\n@@ -0,0 +1 @@\n+a\n"))
+	Ω(diff.Unified([]byte("a\nb\n"), []byte("a\n"), "a", "b")).Should(Equal("--- a\n+++ b\n@@ -1,2 +1 @@\n a\n-b\n"))
+}
+
+func TestJSON(t *testing.T) {
+	RegisterTestingT(t)
+
+	a := []byte(`{"name": "alpha", "port": 3264, "tags": ["a", "b"], "info": {"region": "us-east-1", "zone": "a"}}`)
+	b := []byte(`{"name": "alpha", "port": 3265, "tags": ["a"], "info": {"region": "us-east-1", "rack": 4}, "odd key": true}`)
+
+	changes, err := diff.JSON(a, b)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(changes).Should(Equal([]diff.Change{
+		{Path: "info.rack", Type: diff.Added, New: float64(4)},
+		{Path: "info.zone", Type: diff.Removed, Old: "a"},
+		{Path: `["odd key"]`, Type: diff.Added, New: true},
+		{Path: "port", Type: diff.Modified, Old: float64(3264), New: float64(3265)},
+		{Path: "tags[1]", Type: diff.Removed, Old: "b"},
+	}))
+
+	strs := make([]string, 0, len(changes))
+	for _, change := range changes {
+		strs = append(strs, change.String())
+	}
+	Ω(strings.Join(strs, "\n")).Should(Equal(`+ info.rack: 4
+- info.zone: "a"
++ ["odd key"]: true
+~ port: 3264 -> 3265
+- tags[1]: "b"`))
+
+	// Identical documents have no changes regardless of formatting
+	changes, err = diff.JSON([]byte(`{"a": [1, 2]}`), []byte("{\"a\":[1,2]}\n"))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(changes).Should(BeEmpty())
+
+	// A change in the type of a value replaces it entirely
+	changes, err = diff.JSON([]byte(`{"a": {"b": 1}}`), []byte(`{"a": [1]}`))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(changes).Should(HaveLen(1))
+	Ω(changes[0].String()).Should(Equal(`~ a: {"b":1} -> [1]`))
+
+	_, err = diff.JSON([]byte(`{`), []byte(`{}`))
+	Ω(err).Should(MatchError(HavePrefix("could not parse original document")))
+}
+
+func TestValues(t *testing.T) {
+	RegisterTestingT(t)
+
+	type peer struct {
+		Name string `json:"name"`
+		Port uint16 `json:"port,omitempty"`
+	}
+
+	changes, err := diff.Values([]*peer{{"alpha", 3264}}, []*peer{{"alpha", 0}, {"bravo", 3264}})
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(changes).Should(HaveLen(2))
+	Ω(changes[0].String()).Should(Equal("- [0].port: 3264"))
+	Ω(changes[1].String()).Should(Equal(`+ [1]: {"name":"bravo","port":3264}`))
+}
@@ -0,0 +1,185 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//===========================================================================
+// Structural Diffs
+//===========================================================================
+
+// ChangeType describes how a value changed in a structural diff.
+type ChangeType uint8
+
+// Structural change types.
+const (
+	Added ChangeType = iota + 1
+	Removed
+	Modified
+)
+
+// String returns a human readable representation of the change type.
+func (t ChangeType) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalJSON encodes the change type as its string representation.
+func (t ChangeType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// Change is a value that was added, removed, or modified at a path in a JSON
+// document. The path uses dotted object keys and bracketed array indices from
+// the root of the document, e.g. "peers[2].port"; the root itself is "". Old is
+// nil if the value was added and New is nil if the value was removed.
+type Change struct {
+	Path string      `json:"path"`
+	Type ChangeType  `json:"type"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// String returns a single line description of the change, e.g. for logging.
+func (c Change) String() string {
+	path := c.Path
+	if path == "" {
+		path = "."
+	}
+
+	switch c.Type {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", path, encode(c.New))
+	case Removed:
+		return fmt.Sprintf("- %s: %s", path, encode(c.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", path, encode(c.Old), encode(c.New))
+	}
+}
+
+// JSON returns the structural changes from the JSON document a to b. Objects are
+// compared key by key (in sorted order) and arrays are compared index by index,
+// so an element inserted into an array modifies every element after it. An error
+// is returned if either document cannot be parsed.
+func JSON(a, b []byte) (_ []Change, err error) {
+	var va, vb interface{}
+	if err = json.Unmarshal(a, &va); err != nil {
+		return nil, fmt.Errorf("could not parse original document: %s", err)
+	}
+
+	if err = json.Unmarshal(b, &vb); err != nil {
+		return nil, fmt.Errorf("could not parse changed document: %s", err)
+	}
+	return compare(va, vb), nil
+}
+
+// Values returns the structural changes from a to b as described by JSON, after
+// marshaling both values to JSON so that the diff uses their JSON field names.
+func Values(a, b interface{}) (_ []Change, err error) {
+	var da, db []byte
+	if da, err = json.Marshal(a); err != nil {
+		return nil, fmt.Errorf("could not marshal original value: %s", err)
+	}
+
+	if db, err = json.Marshal(b); err != nil {
+		return nil, fmt.Errorf("could not marshal changed value: %s", err)
+	}
+	return JSON(da, db)
+}
+
+// Compares two parsed JSON documents and returns the changes in path order.
+func compare(a, b interface{}) []Change {
+	changes := make([]Change, 0)
+	walk("", a, b, &changes)
+	return changes
+}
+
+// Recursively compares the values at the path, appending changes.
+func walk(path string, a, b interface{}, changes *[]Change) {
+	switch va := a.(type) {
+	case map[string]interface{}:
+		if vb, ok := b.(map[string]interface{}); ok {
+			for _, key := range keys(va, vb) {
+				old, inA := va[key]
+				val, inB := vb[key]
+				switch {
+				case !inB:
+					*changes = append(*changes, Change{Path: join(path, key), Type: Removed, Old: old})
+				case !inA:
+					*changes = append(*changes, Change{Path: join(path, key), Type: Added, New: val})
+				default:
+					walk(join(path, key), old, val, changes)
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if vb, ok := b.([]interface{}); ok {
+			for i := 0; i < len(va) || i < len(vb); i++ {
+				elem := path + "[" + strconv.Itoa(i) + "]"
+				switch {
+				case i >= len(vb):
+					*changes = append(*changes, Change{Path: elem, Type: Removed, Old: va[i]})
+				case i >= len(va):
+					*changes = append(*changes, Change{Path: elem, Type: Added, New: vb[i]})
+				default:
+					walk(elem, va[i], vb[i], changes)
+				}
+			}
+			return
+		}
+	}
+
+	// Scalars or values whose type has changed
+	if encode(a) != encode(b) {
+		*changes = append(*changes, Change{Path: path, Type: Modified, Old: a, New: b})
+	}
+}
+
+// Returns the sorted union of the keys of both objects.
+func keys(a, b map[string]interface{}) []string {
+	union := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		union = append(union, key)
+	}
+
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			union = append(union, key)
+		}
+	}
+
+	sort.Strings(union)
+	return union
+}
+
+// Joins an object key to the path, quoting keys that are not simple identifiers.
+func join(path, key string) string {
+	if key == "" || strings.ContainsAny(key, ".[]\" ") {
+		key = "[" + strconv.Quote(key) + "]"
+		return path + key
+	}
+
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Encodes a parsed JSON value for comparison and display.
+func encode(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
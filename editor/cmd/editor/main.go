@@ -0,0 +1,72 @@
+/*
+Wrapper for a command line editor to edit files.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bbengfort/x/editor"
+)
+
+func main() {
+	name := flag.String("e", "", "specify the editor you wish to use")
+	args := flag.String("args", "", "additional arguments to pass to the editor")
+	isJSON := flag.Bool("j", false, "validate json")
+	isValid := flag.Bool("v", false, "validate json, yaml, or toml by the file extension")
+	isPreview := flag.Bool("p", false, "preview and confirm changes before saving")
+	isReview := flag.Bool("r", false, "review changes with a diff and re-edit or abort before saving")
+	isView := flag.Bool("R", false, "view the file read-only without saving changes")
+	isDiscard := flag.Bool("d", false, "confirm before discarding changes and report when nothing changed")
+
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Println("specify the path of the file you wish to edit")
+		return
+	}
+
+	opts := []editor.Option{editor.WithEditor(*name), editor.WithPrompt(os.Stdin, os.Stdout)}
+	if *args != "" {
+		words, err := editor.Split(*args)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		opts = append(opts, editor.WithArgs(words...))
+	}
+
+	if *isJSON {
+		opts = append(opts, editor.WithValidator(editor.ValidateJSON))
+	}
+
+	if *isPreview {
+		opts = append(opts, editor.WithPreview(os.Stdin, os.Stdout))
+	}
+
+	if *isReview {
+		opts = append(opts, editor.WithReview(os.Stdin, os.Stdout))
+	}
+
+	if *isView {
+		opts = append(opts, editor.WithReadOnly())
+	}
+
+	if *isDiscard {
+		opts = append(opts, editor.WithConfirmDiscard(os.Stdin, os.Stdout))
+	}
+
+	for _, arg := range flag.Args() {
+		fileOpts := append([]editor.Option{}, opts...)
+		if *isValid {
+			if validate := editor.ValidatorFor(arg); validate != nil {
+				fileOpts = append(fileOpts, editor.WithValidator(validate))
+			}
+		}
+
+		if err := editor.Edit(arg, fileOpts...); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package editor
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// Flags that GUI editors require to block until the file is closed; without them
+// the editor returns immediately and the unchanged temporary file is read back.
+var waitFlags = map[string]string{
+	"atom":          "--wait",
+	"bbedit":        "-w",
+	"code":          "--wait",
+	"code-insiders": "--wait",
+	"codium":        "--wait",
+	"gedit":         "--wait",
+	"gvim":          "-f",
+	"kate":          "--block",
+	"mate":          "-w",
+	"mvim":          "-f",
+	"subl":          "-w",
+	"zed":           "--wait",
+}
+
+// WithArgs passes additional arguments to the editor before the path of the file
+// being edited, e.g. to open the editor at a specific line.
+func WithArgs(args ...string) Option {
+	return func(o *options) {
+		o.args = append(o.args, args...)
+	}
+}
+
+// Split a command line into words as a POSIX shell would (without expansion):
+// words are separated by unquoted whitespace, single quotes preserve everything
+// up to the closing quote, and in double quotes or unquoted words a backslash
+// escapes the following character. This is how $VISUAL and $EDITOR values such
+// as `code --wait` or `"/Applications/My Editor" -f` are parsed.
+func Split(s string) (words []string, err error) {
+	var (
+		word    strings.Builder
+		inWord  bool
+		escaped bool
+		quote   rune
+	)
+
+	for _, c := range s {
+		switch {
+		case escaped:
+			// A backslash in double quotes only escapes characters special to the shell
+			if quote == '"' && !strings.ContainsRune(`"\$`+"`", c) {
+				word.WriteRune('\\')
+			}
+			word.WriteRune(c)
+			escaped = false
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(c)
+			}
+		case c == '\\':
+			escaped, inWord = true, true
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote, inWord = c, true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+
+	if escaped {
+		return nil, errors.New("unterminated escape in command")
+	}
+
+	if quote != 0 {
+		return nil, errors.New("unterminated quote in command")
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// Returns the wait flag of the editor if it is a GUI editor that does not block
+// by default and the flag (or its long form) is not already in the arguments.
+func waitFlag(editor string, args []string) string {
+	name := strings.TrimSuffix(strings.ToLower(filepath.Base(editor)), ".exe")
+	flag, ok := waitFlags[name]
+	if !ok {
+		return ""
+	}
+
+	for _, arg := range args {
+		if arg == flag || arg == "--wait" || arg == "--block" || arg == "--nofork" {
+			return ""
+		}
+	}
+	return flag
+}
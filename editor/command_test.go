@@ -0,0 +1,88 @@
+package editor_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/bbengfort/x/editor"
+	. "github.com/onsi/gomega"
+)
+
+func TestSplit(t *testing.T) {
+	RegisterTestingT(t)
+
+	tests := []struct {
+		command  string
+		expected []string
+	}{
+		{"vim", []string{"vim"}},
+		{"  code --wait  ", []string{"code", "--wait"}},
+		{`"/Applications/My Editor" -f`, []string{"/Applications/My Editor", "-f"}},
+		{`emacs -nw --eval '(setq x "y")'`, []string{"emacs", "-nw", "--eval", `(setq x "y")`}},
+		{`my\ editor "a\"b" "c\d"`, []string{"my editor", `a"b`, `c\d`}},
+		{`vim ""`, []string{"vim", ""}},
+		{"", nil},
+	}
+
+	for _, tc := range tests {
+		words, err := editor.Split(tc.command)
+		Ω(err).ShouldNot(HaveOccurred(), tc.command)
+		Ω(words).Should(Equal(tc.expected), tc.command)
+	}
+
+	for _, command := range []string{`vim "unterminated`, `vim 'unterminated`, `vim \`} {
+		_, err := editor.Split(command)
+		Ω(err).Should(HaveOccurred(), command)
+	}
+}
+
+// Creates an editor named name that records its arguments and replaces the
+// contents of the file (the last argument) with the specified contents.
+func argsEditor(t *testing.T, name, contents string) (path, argsf string) {
+	dir := t.TempDir()
+	path = filepath.Join(dir, name)
+	argsf = filepath.Join(dir, "args")
+	script := "#!/bin/sh\n" +
+		"for arg in \"$@\"; do printf '%s\\n' \"$arg\" >> " + argsf + "; file=\"$arg\"; done\n" +
+		"printf '%s' '" + contents + "' > \"$file\"\n"
+	if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path, argsf
+}
+
+func TestEditorArgs(t *testing.T) {
+	RegisterTestingT(t)
+
+	path, argsf := argsEditor(t, "fake-editor", "world")
+	data, err := editor.EditBytes([]byte("hello"), editor.WithEditor(path+` -n "two words"`), editor.WithArgs("+1"))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(string(data)).Should(Equal("world"))
+
+	args, err := ioutil.ReadFile(argsf)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(string(args)).Should(HavePrefix("-n\ntwo words\n+1\n"))
+}
+
+func TestEditorWaitFlag(t *testing.T) {
+	RegisterTestingT(t)
+
+	// GUI editors are passed their wait flag
+	path, argsf := argsEditor(t, "code", "world")
+	_, err := editor.EditBytes([]byte("hello"), editor.WithEditor(path))
+	Ω(err).ShouldNot(HaveOccurred())
+
+	args, err := ioutil.ReadFile(argsf)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(string(args)).Should(HavePrefix("--wait\n"))
+
+	// The wait flag is not repeated if it is already specified
+	path, argsf = argsEditor(t, "subl", "world")
+	_, err = editor.EditBytes([]byte("hello"), editor.WithEditor(path+" -w -n"))
+	Ω(err).ShouldNot(HaveOccurred())
+
+	args, err = ioutil.ReadFile(argsf)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(string(args)).Should(HavePrefix("-w\n-n\n/"))
+}
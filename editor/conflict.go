@@ -0,0 +1,187 @@
+package editor
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrConflict is returned when the original file changed while it was being
+// edited and the conflict was not resolved by overwriting or merging.
+var ErrConflict = errors.New("file changed on disk while editing, changes discarded")
+
+// Markers that delimit the conflicting regions of a merged file.
+const (
+	markerEdited = "<<<<<<< edited"
+	markerSep    = "======="
+	markerDisk   = ">>>>>>> on disk"
+)
+
+// Resolution describes how to handle changes made to the original file while
+// it was being edited.
+type Resolution uint8
+
+const (
+	Abort     Resolution = iota // discard the edit, keeping the changes on disk
+	Overwrite                   // replace the changes on disk with the edit
+	Merge                       // re-open the editor with both changes marked
+)
+
+// Resolver is called with the path of the original file if it changed while it
+// was being edited to determine how to resolve the conflict.
+type Resolver func(path string) (Resolution, error)
+
+// WithResolver specifies how to resolve conflicts if the original file changed
+// while it was being edited. By default Edit aborts with ErrConflict rather
+// than silently clobbering the concurrent changes.
+func WithResolver(resolve Resolver) Option {
+	return func(o *options) {
+		o.resolve = resolve
+	}
+}
+
+// WithPrompt resolves conflicts by asking the user on out whether to overwrite
+// the file, merge the changes, or abort, reading the answer from in. Unlike
+// WithResolver(Prompt(in, out)), the answer is read from the same buffer as the
+// other prompts of the edit that read from in, e.g. WithReview(in, out).
+func WithPrompt(in io.Reader, out io.Writer) Option {
+	return func(o *options) {
+		o.resolve = prompt(o.reader(in), out)
+	}
+}
+
+// Prompt returns a resolver that asks the user whether to overwrite the file,
+// merge the changes, or abort; an empty or unknown response aborts. The
+// resolver buffers in, so use WithPrompt instead if other options of the edit
+// also read from in.
+func Prompt(in io.Reader, out io.Writer) Resolver {
+	return prompt(bufio.NewReader(in), out)
+}
+
+// Returns a resolver that reads the answers from the buffered reader.
+func prompt(in *bufio.Reader, out io.Writer) Resolver {
+	return func(path string) (Resolution, error) {
+		fmt.Fprintf(out, "%s changed on disk while editing: [o]verwrite, [m]erge, or [a]bort? ", path)
+
+		answer, err := in.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return Abort, err
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "o", "overwrite":
+			return Overwrite, nil
+		case "m", "merge":
+			return Merge, nil
+		default:
+			return Abort, nil
+		}
+	}
+}
+
+//===========================================================================
+// Snapshots
+//===========================================================================
+
+// snapshot records the state of the original file before it is edited.
+type snapshot struct {
+	modified time.Time
+	size     int64
+	hash     [sha256.Size]byte
+}
+
+// Takes a snapshot of the file at the path.
+func snap(path string) (s *snapshot, err error) {
+	var stat os.FileInfo
+	if stat, err = os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if data, err = ioutil.ReadFile(path); err != nil {
+		return nil, err
+	}
+	return &snapshot{modified: stat.ModTime(), size: stat.Size(), hash: sha256.Sum256(data)}, nil
+}
+
+// Returns true if the contents of the file changed since the snapshot. The
+// contents are only hashed if the modification time or size changed.
+func (s *snapshot) changed(path string) (bool, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+
+	if stat.ModTime().Equal(s.modified) && stat.Size() == s.size {
+		return false, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	return sha256.Sum256(data) != s.hash, nil
+}
+
+//===========================================================================
+// Merging
+//===========================================================================
+
+// Merges the edited contents with the contents on disk by marking the region
+// between the common leading and trailing lines of both with conflict markers.
+func merge(edited, disk []byte) []byte {
+	a, b := splitLines(edited), splitLines(disk)
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	buf := &bytes.Buffer{}
+	write := func(lines ...string) {
+		for _, line := range lines {
+			buf.WriteString(line)
+			buf.WriteByte('\n')
+		}
+	}
+
+	write(a[:prefix]...)
+	write(markerEdited)
+	write(a[prefix : len(a)-suffix]...)
+	write(markerSep)
+	write(b[prefix : len(b)-suffix]...)
+	write(markerDisk)
+	write(a[len(a)-suffix:]...)
+	return buf.Bytes()
+}
+
+// Returns true if the contents contain conflict markers that were not resolved.
+func unresolved(data []byte) bool {
+	for _, line := range splitLines(data) {
+		if line == markerEdited || line == markerDisk {
+			return true
+		}
+	}
+	return false
+}
+
+// Splits the contents into lines without their line endings.
+func splitLines(data []byte) []string {
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,10 @@
+package editor
+
+import "github.com/bbengfort/x/diff"
+
+// Diff returns a unified diff of the changes from a to b, labeling the original
+// and edited content with the specified names. An empty string is returned if
+// the contents are the same. See the diff package for structural diffs.
+func Diff(a, b []byte, nameA, nameB string) string {
+	return diff.Unified(a, b, nameA, nameB)
+}
@@ -0,0 +1,574 @@
+/*
+Package editor opens a command line editor on files or in-memory content.
+
+The editor is specified by the caller, the $VISUAL or $EDITOR environment
+variables, or is found by searching the $PATH for vim, emacs, or nano. Edits are made to a
+temporary copy so that the original is only modified if the editor exits
+successfully and the edited content is valid and confirmed:
+
+	data, err := editor.EditBytes(conf, editor.WithSuffix(".json"), editor.WithValidator(editor.ValidateJSON))
+
+The editor command in the cmd/editor directory is a CLI wrapper around this
+package.
+*/
+package editor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+)
+
+const (
+	envVisual = "VISUAL"
+	envEditor = "EDITOR"
+	envPath   = "PATH"
+)
+
+var editorSearch = [3]string{"vim", "emacs", "nano"}
+
+// ErrDiscarded is returned when the user does not confirm the changes.
+var ErrDiscarded = errors.New("changes discarded")
+
+//===========================================================================
+// Options
+//===========================================================================
+
+// Option configures how content is edited.
+type Option func(*options)
+
+type options struct {
+	editor   string
+	args     []string
+	suffix   string
+	validate Validator
+	confirm  Confirmer
+	resolve  Resolver
+	review   *reviewer
+	discard  *discarder
+	readonly bool
+	in       io.Reader
+	out      io.Writer
+	readers  map[io.Reader]*bufio.Reader
+}
+
+// Returns the buffered reader of the input, creating it on first use so that
+// every prompt of an edit reads from the same buffer and answers that one prompt
+// read ahead are not lost to the next. Options are applied to new options for
+// every edit, so the buffered readers are not shared between edits.
+func (o *options) reader(in io.Reader) *bufio.Reader {
+	if in == nil || !reflect.TypeOf(in).Comparable() {
+		return bufio.NewReader(in)
+	}
+
+	if r, ok := o.readers[in]; ok {
+		return r
+	}
+
+	if o.readers == nil {
+		o.readers = make(map[io.Reader]*bufio.Reader)
+	}
+	o.readers[in] = bufio.NewReader(in)
+	return o.readers[in]
+}
+
+// WithEditor specifies the editor to use instead of $VISUAL or $EDITOR. Like the
+// environment variables, the editor may include arguments, e.g. "code --wait".
+func WithEditor(name string) Option {
+	return func(o *options) {
+		o.editor = name
+	}
+}
+
+// WithSuffix specifies the extension of the temporary file that is edited, e.g.
+// so that the editor and preview highlight the content. Edit uses the extension
+// of the original file by default.
+func WithSuffix(ext string) Option {
+	return func(o *options) {
+		o.suffix = ext
+	}
+}
+
+// WithValidator validates the edited content before it is saved, re-opening the
+// editor with the validation error until the content is valid.
+func WithValidator(validate Validator) Option {
+	return func(o *options) {
+		o.validate = validate
+	}
+}
+
+// WithConfirm asks for confirmation of the edited content before it is saved.
+func WithConfirm(confirm Confirmer) Option {
+	return func(o *options) {
+		o.confirm = confirm
+	}
+}
+
+// WithPreview previews the edited content on out and asks the user to confirm
+// the changes by reading the answer from in before it is saved.
+func WithPreview(in io.Reader, out io.Writer) Option {
+	return func(o *options) {
+		o.in, o.out = in, out
+	}
+}
+
+//===========================================================================
+// Editing
+//===========================================================================
+
+// Edit the file at the specified path using a command line editor. The original
+// file is only overwritten if the editor exits successfully and the changes are
+// validated and confirmed. If the original file changed while it was being
+// edited, the conflict is resolved as specified by WithResolver.
+func Edit(path string, opts ...Option) (err error) {
+	o := &options{suffix: filepath.Ext(path)}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	// Create a temporary file and copy the original file to it
+	var tmpf string
+	if tmpf, err = mktmpf(o.suffix); err != nil {
+		return fmt.Errorf("could not create temporary file for editing: %v", err)
+	}
+	defer os.Remove(tmpf)
+
+	if err = copy2(path, tmpf); err != nil {
+		return fmt.Errorf("could not copy source contents into temporary file for editing: %v", err)
+	}
+
+	if o.readonly {
+		return o.view(tmpf)
+	}
+
+	// Record the state of the original file to detect concurrent changes
+	var orig *snapshot
+	if orig, err = snap(path); err != nil {
+		return err
+	}
+
+	if err = o.edit(tmpf, path); err != nil {
+		if err == errUnchanged {
+			return nil
+		}
+		return err
+	}
+
+	if err = o.conflicts(path, tmpf, orig); err != nil {
+		return err
+	}
+
+	// If the editor exited succesfully, copy temporary file back to original file
+	if err = copy2(tmpf, path); err != nil {
+		return fmt.Errorf("could not copy temporary file contents back to source after editing: %v", err)
+	}
+	return nil
+}
+
+// EditBytes opens a command line editor on the content and returns the edited
+// content, e.g. to edit a configuration that is not stored in a file.
+func EditBytes(content []byte, opts ...Option) (_ []byte, err error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var tmpf string
+	if tmpf, err = mktmpf(o.suffix); err != nil {
+		return nil, fmt.Errorf("could not create temporary file for editing: %v", err)
+	}
+	defer os.Remove(tmpf)
+
+	if err = ioutil.WriteFile(tmpf, content, 0600); err != nil {
+		return nil, fmt.Errorf("could not write content into temporary file for editing: %v", err)
+	}
+
+	if o.readonly {
+		if err = o.view(tmpf); err != nil {
+			return nil, err
+		}
+		return content, nil
+	}
+
+	if err = o.edit(tmpf, ""); err != nil {
+		if err == errUnchanged {
+			return content, nil
+		}
+		return nil, err
+	}
+	return ioutil.ReadFile(tmpf)
+}
+
+// EditReader reads the content from r, opens a command line editor on it, and
+// writes the edited content to w.
+func EditReader(r io.Reader, w io.Writer, opts ...Option) (err error) {
+	var content []byte
+	if content, err = ioutil.ReadAll(r); err != nil {
+		return err
+	}
+
+	if content, err = EditBytes(content, opts...); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, bytes.NewReader(content))
+	return err
+}
+
+// Executes the editor on the temporary file then validates and confirms the
+// changes. If the changes are invalid the editor is re-opened with the error
+// injected as a comment; the edit is only discarded if the user saves the file
+// without changes or, when reviewing, chooses to abort. If WithConfirmDiscard
+// is specified, the user must confirm that changes are discarded and errUnchanged
+// is returned if the editor exits without changes. The name is the path of the
+// original file, if any, for the preview.
+func (o *options) edit(tmpf, name string) (err error) {
+	// Find the editor to use and the arguments to pass to it
+	var (
+		editor string
+		args   []string
+	)
+	if editor, args, err = findEditor(o.editor); err != nil {
+		return err
+	}
+	args = append(append(args, o.args...), tmpf)
+
+	// Keep the original contents to diff the changes against
+	var original []byte
+	if original, err = ioutil.ReadFile(tmpf); err != nil {
+		return err
+	}
+
+	// Confirm the changes before overwriting the original
+	confirm := o.confirm
+	if confirm == nil && o.out != nil {
+		confirm = preview(name, o.reader(o.in), o.out)
+	}
+
+	var previous []byte
+	for {
+		if err = run(editor, args); err != nil {
+			return err
+		}
+
+		var data []byte
+		if data, err = ioutil.ReadFile(tmpf); err != nil {
+			return err
+		}
+
+		// There is nothing to validate, review, or confirm if nothing changed
+		if o.discard != nil && bytes.Equal(data, original) {
+			o.discard.unchanged()
+			return errUnchanged
+		}
+
+		// Validate the written file before editing the original
+		if o.validate != nil {
+			data = stripErrors(data, o.suffix)
+			if verr := o.validate.Validate(data); verr != nil {
+				if o.review == nil && previous != nil && bytes.Equal(data, previous) {
+					var keep bool
+					if keep, err = o.keep(original, data, "changes are invalid"); err != nil {
+						return err
+					}
+
+					if !keep {
+						return fmt.Errorf("validation error: %s", verr)
+					}
+					continue
+				}
+
+				previous = data
+				if err = ioutil.WriteFile(tmpf, injectError(data, o.suffix, verr), 0600); err != nil {
+					return err
+				}
+
+				if o.review != nil {
+					var action string
+					if action, err = o.review.invalid(verr, original, data, name); err != nil {
+						return fmt.Errorf("could not review changes: %v", err)
+					}
+
+					if action != actionEdit {
+						var keep bool
+						if keep, err = o.keep(original, data, "changes are invalid"); err != nil {
+							return err
+						}
+
+						if !keep {
+							return fmt.Errorf("validation error: %s", verr)
+						}
+					}
+				}
+				continue
+			}
+
+			if err = ioutil.WriteFile(tmpf, data, 0600); err != nil {
+				return err
+			}
+		}
+
+		// Review the changes before overwriting the original
+		if o.review != nil {
+			var action string
+			if action, err = o.review.changes(original, data, name); err != nil {
+				return fmt.Errorf("could not review changes: %v", err)
+			}
+
+			switch action {
+			case actionEdit:
+				continue
+			case actionQuit:
+				var keep bool
+				if keep, err = o.keep(original, data, "changes not applied"); err != nil {
+					return err
+				}
+
+				if keep {
+					continue
+				}
+				return ErrDiscarded
+			}
+		}
+
+		if confirm != nil {
+			var ok bool
+			if ok, err = confirm(tmpf); err != nil {
+				return fmt.Errorf("could not confirm changes: %v", err)
+			}
+
+			if !ok {
+				var keep bool
+				if keep, err = o.keep(original, data, "changes not confirmed"); err != nil {
+					return err
+				}
+
+				if keep {
+					continue
+				}
+				return ErrDiscarded
+			}
+		}
+		return nil
+	}
+}
+
+// Executes the editor with the arguments, connecting it to the terminal.
+func run(editor string, args []string) (err error) {
+	cmd := exec.Command(editor, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err = cmd.Run(); err != nil {
+		return fmt.Errorf("could not exec %s: %v", editor, err)
+	}
+	return nil
+}
+
+// Checks if the original file changed while it was being edited and resolves
+// the conflict, re-opening the editor on the merged contents until the file on
+// disk no longer changes underneath the edit or the edit is overwritten.
+func (o *options) conflicts(path, tmpf string, orig *snapshot) (err error) {
+	for {
+		var changed bool
+		if changed, err = orig.changed(path); err != nil || !changed {
+			return err
+		}
+
+		resolution := Abort
+		if o.resolve != nil {
+			if resolution, err = o.resolve(path); err != nil {
+				return fmt.Errorf("could not resolve conflict: %v", err)
+			}
+		}
+
+		switch resolution {
+		case Overwrite:
+			return nil
+		case Merge:
+			var edited, disk []byte
+			if edited, err = ioutil.ReadFile(tmpf); err != nil {
+				return err
+			}
+
+			if orig, err = snap(path); err != nil {
+				return err
+			}
+
+			if disk, err = ioutil.ReadFile(path); err != nil {
+				return err
+			}
+
+			if err = ioutil.WriteFile(tmpf, merge(edited, disk), 0600); err != nil {
+				return err
+			}
+
+			if err = o.edit(tmpf, path); err != nil && err != errUnchanged {
+				return err
+			}
+
+			var merged []byte
+			if merged, err = ioutil.ReadFile(tmpf); err != nil {
+				return err
+			}
+
+			if unresolved(merged) {
+				return errors.New("conflict markers remain in the merged file, changes discarded")
+			}
+		default:
+			return ErrConflict
+		}
+	}
+}
+
+// Finds the path to the specified editor, or if none is specified, uses the $VISUAL
+// or $EDITOR environment variables or a search for the standard editors. The editor
+// is split into words so that it can include arguments; the wait flag is added for
+// GUI editors that would otherwise return immediately. Returns an error if an editor
+// can not be found in the $PATH.
+func findEditor(name string) (_ string, args []string, err error) {
+	if name == "" {
+		if name = os.Getenv(envVisual); name == "" {
+			name = os.Getenv(envEditor)
+		}
+	}
+
+	// Determine if the specified editor can be executed
+	if name != "" {
+		// If name is a full path to an executable (that may contain spaces), use it.
+		if path := expand(name); isExecutable(path) {
+			if flag := waitFlag(path, nil); flag != "" {
+				args = append(args, flag)
+			}
+			return path, args, nil
+		}
+
+		var words []string
+		if words, err = Split(name); err != nil {
+			return "", nil, fmt.Errorf("could not parse editor %q: %v", name, err)
+		}
+
+		if len(words) == 0 {
+			return "", nil, fmt.Errorf("could not parse editor %q", name)
+		}
+
+		// Expand environment variables and ~ for the home directory.
+		path, args := expand(words[0]), words[1:]
+		if flag := waitFlag(path, args); flag != "" {
+			args = append(args, flag)
+		}
+
+		// If the path is executable, return it, otherwise check if it exists in the $PATH.
+		if isExecutable(path) {
+			return path, args, nil
+		}
+
+		if path, err = inPath(path); err != nil {
+			return "", nil, err
+		}
+		return path, args, nil
+	}
+
+	// Search for one of the editors in the $PATH
+	for _, name := range editorSearch {
+		if path, err := inPath(name); err == nil {
+			return path, nil, nil
+		}
+	}
+
+	// Could not find an editor
+	return "", nil, errors.New("could not find an editor")
+}
+
+// Returns true if the file exists and it can be executed on Unix systems.
+func isExecutable(path string) bool {
+	if stat, err := os.Stat(path); err == nil {
+		if !stat.IsDir() {
+			return stat.Mode()&0111 != 0
+		}
+	}
+	return false
+}
+
+// Searches for the specified editor in the $PATH
+func inPath(name string) (path string, err error) {
+	var fname string
+	if fname, err = exec.LookPath(name); err != nil {
+		return "", fmt.Errorf("could not find %q in $PATH", name)
+	}
+	if path, err = filepath.Abs(fname); err != nil {
+		return fname, nil
+	}
+	return path, nil
+}
+
+// Expand the path from environment variables and handle ~ for the home directory.
+func expand(path string) string {
+	if strings.HasPrefix(path, "~") {
+		path = strings.Replace(path, "~", "$HOME", 1)
+	}
+	return os.ExpandEnv(path)
+}
+
+// Creates a temporary file with the suffix so that editors detect the file type.
+func mktmpf(suffix string) (_ string, err error) {
+	var f *os.File
+	if f, err = ioutil.TempFile("", "goedit-*"+suffix); err != nil {
+		return "", err
+	}
+	f.Close()
+	return f.Name(), nil
+}
+
+// Copy the contents from the src path to the dst path
+func copy2(src, dst string) (err error) {
+	// Check the source path to make sure it is editable.
+	var stat os.FileInfo
+	if stat, err = os.Stat(src); err != nil {
+		return fmt.Errorf("could not stat source file: %v", err)
+	}
+
+	if !stat.Mode().IsRegular() {
+		return fmt.Errorf("%q is not a regular file", src)
+	}
+
+	var (
+		source *os.File
+		target *os.File
+	)
+
+	if source, err = os.Open(src); err != nil {
+		return fmt.Errorf("could not open %q: %v", src, err)
+	}
+	defer source.Close()
+
+	if target, err = os.Create(dst); err != nil {
+		return fmt.Errorf("could not create %q: %v", dst, err)
+	}
+	defer target.Close()
+
+	if _, err = io.Copy(target, source); err != nil {
+		return fmt.Errorf("could not copy file: %v", err)
+	}
+
+	// Attempt to change the mode of the target file to the original mode (ignore errors)
+	target.Close()
+	os.Chmod(dst, stat.Mode())
+
+	// Attempt to cahnge the owners of the target file to the original owners (ignore errors)
+	if info, ok := stat.Sys().(*syscall.Stat_t); ok {
+		os.Chown(dst, int(info.Uid), int(info.Gid))
+	}
+
+	return nil
+}
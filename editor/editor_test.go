@@ -0,0 +1,156 @@
+package editor_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bbengfort/x/editor"
+	. "github.com/onsi/gomega"
+)
+
+// Creates an executable script that acts as an editor, replacing the contents
+// of the file it is called with by the specified contents.
+func fakeEditor(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "fake-editor")
+	script := "#!/bin/sh\ncat > \"$1\" <<'EOF'\n" + contents + "\nEOF\n"
+	if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestEdit(t *testing.T) {
+	RegisterTestingT(t)
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	Ω(ioutil.WriteFile(path, []byte(`{"color": "red"}`), 0644)).Should(Succeed())
+
+	Ω(editor.Edit(path, editor.WithEditor(fakeEditor(t, `{"color": "blue"}`)))).Should(Succeed())
+	data, err := ioutil.ReadFile(path)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(strings.TrimSpace(string(data))).Should(Equal(`{"color": "blue"}`))
+
+	// The original file should not be modified if validation fails
+	err = editor.Edit(path, editor.WithEditor(fakeEditor(t, `{"color":`)), editor.WithValidator(editor.ValidateJSON))
+	Ω(err).Should(MatchError(ContainSubstring("validation error")))
+	data, err = ioutil.ReadFile(path)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(strings.TrimSpace(string(data))).Should(Equal(`{"color": "blue"}`))
+
+	// The original file should not be modified if the changes are not confirmed
+	out := &bytes.Buffer{}
+	err = editor.Edit(path, editor.WithEditor(fakeEditor(t, `{"color": "green"}`)), editor.WithPreview(strings.NewReader("n\n"), out))
+	Ω(err).Should(Equal(editor.ErrDiscarded))
+	Ω(out.String()).Should(ContainSubstring(`1 │ {"color": "green"}`))
+	Ω(out.String()).Should(ContainSubstring("apply changes to " + path))
+	data, err = ioutil.ReadFile(path)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(strings.TrimSpace(string(data))).Should(Equal(`{"color": "blue"}`))
+}
+
+func TestEditBytes(t *testing.T) {
+	RegisterTestingT(t)
+
+	data, err := editor.EditBytes([]byte("hello"), editor.WithEditor(fakeEditor(t, "world")))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(string(data)).Should(Equal("world\n"))
+
+	_, err = editor.EditBytes([]byte("{}"), editor.WithEditor(fakeEditor(t, "not json")), editor.WithValidator(editor.ValidateJSON))
+	Ω(err).Should(HaveOccurred())
+
+	// The suffix is used for the temporary file so editors can detect the type
+	var tmpf string
+	confirm := func(path string) (bool, error) {
+		tmpf = path
+		return true, nil
+	}
+	_, err = editor.EditBytes([]byte("a: 1"), editor.WithEditor(fakeEditor(t, "a: 2")), editor.WithSuffix(".yaml"), editor.WithConfirm(confirm))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(filepath.Ext(tmpf)).Should(Equal(".yaml"))
+	_, err = os.Stat(tmpf)
+	Ω(os.IsNotExist(err)).Should(BeTrue(), "temporary file should be removed")
+}
+
+func TestEditReader(t *testing.T) {
+	RegisterTestingT(t)
+
+	out := &bytes.Buffer{}
+	Ω(editor.EditReader(strings.NewReader("hello"), out, editor.WithEditor(fakeEditor(t, "world")))).Should(Succeed())
+	Ω(out.String()).Should(Equal("world\n"))
+}
+
+func TestMissingEditor(t *testing.T) {
+	RegisterTestingT(t)
+
+	_, err := editor.EditBytes([]byte("hello"), editor.WithEditor("not-a-real-editor-xyz"))
+	Ω(err).Should(HaveOccurred())
+}
+
+// Creates an editor that replaces the contents of the file with the edit and
+// modifies the original file while it is "editing". If the editor is opened
+// again, e.g. to merge, it replaces the contents with the resolved contents.
+func concurrentEditor(t *testing.T, original, edit, concurrent, resolved string) string {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "concurrent-editor")
+	opened := filepath.Join(dir, "opened")
+	script := "#!/bin/sh\n" +
+		"if [ -f " + opened + " ]; then printf '%s' '" + resolved + "' > \"$1\"; exit 0; fi\n" +
+		"touch " + opened + "\n" +
+		"printf '%s' '" + edit + "' > \"$1\"\n" +
+		"printf '%s' '" + concurrent + "' > " + original + "\n"
+	if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestEditConflict(t *testing.T) {
+	RegisterTestingT(t)
+
+	read := func(path string) string {
+		data, err := ioutil.ReadFile(path)
+		Ω(err).ShouldNot(HaveOccurred())
+		return string(data)
+	}
+
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	reset := func() {
+		Ω(ioutil.WriteFile(path, []byte("a\nb\nc\n"), 0644)).Should(Succeed())
+	}
+
+	// By default concurrent changes are not clobbered
+	reset()
+	err := editor.Edit(path, editor.WithEditor(concurrentEditor(t, path, "a\nB\nc\n", "a\nb\nc\nd\n", "")))
+	Ω(err).Should(Equal(editor.ErrConflict))
+	Ω(read(path)).Should(Equal("a\nb\nc\nd\n"))
+
+	// Abort when prompted
+	reset()
+	out := &bytes.Buffer{}
+	err = editor.Edit(path, editor.WithEditor(concurrentEditor(t, path, "a\nB\nc\n", "a\nb\nc\nd\n", "")), editor.WithResolver(editor.Prompt(strings.NewReader("a\n"), out)))
+	Ω(err).Should(Equal(editor.ErrConflict))
+	Ω(out.String()).Should(ContainSubstring("changed on disk while editing"))
+	Ω(read(path)).Should(Equal("a\nb\nc\nd\n"))
+
+	// Overwrite the concurrent changes
+	reset()
+	err = editor.Edit(path, editor.WithEditor(concurrentEditor(t, path, "a\nB\nc\n", "a\nb\nc\nd\n", "")), editor.WithResolver(editor.Prompt(strings.NewReader("o\n"), out)))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(read(path)).Should(Equal("a\nB\nc\n"))
+
+	// Merge the changes by re-opening the editor
+	reset()
+	err = editor.Edit(path, editor.WithEditor(concurrentEditor(t, path, "a\nB\nc\n", "a\nb\nc\nd\n", "a\nB\nc\nd\n")), editor.WithResolver(editor.Prompt(strings.NewReader("m\n"), out)))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(read(path)).Should(Equal("a\nB\nc\nd\n"))
+
+	// Unresolved conflict markers discard the merge
+	reset()
+	err = editor.Edit(path, editor.WithEditor(concurrentEditor(t, path, "a\nB\nc\n", "a\nb\nc\nd\n", "<<<<<<< edited\n")), editor.WithResolver(editor.Prompt(strings.NewReader("m\n"), out)))
+	Ω(err).Should(MatchError(ContainSubstring("conflict markers")))
+	Ω(read(path)).Should(Equal("a\nb\nc\nd\n"))
+}
@@ -0,0 +1,125 @@
+package editor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ANSI escape codes used to highlight the preview on a terminal.
+const (
+	ansiReset  = "\033[0m"
+	ansiDim    = "\033[2m"
+	ansiGreen  = "\033[32m"
+	ansiCyan   = "\033[36m"
+	ansiPurple = "\033[35m"
+)
+
+// Confirmer is called with the path of the edited temporary file before the
+// original is overwritten; if it returns false the edit is discarded.
+type Confirmer func(string) (bool, error)
+
+// highlighter colors a single line of the file for display on a terminal.
+type highlighter func(string) string
+
+// Returns a confirmer that previews the edited file with line numbers and asks
+// the user to confirm the changes. The preview is highlighted if the output is
+// a terminal and a highlighter for the file type of the edited file exists. The
+// path of the original file is optional and is only used in the question.
+func preview(path string, in *bufio.Reader, out io.Writer) Confirmer {
+	return func(tmpf string) (_ bool, err error) {
+		var data []byte
+		if data, err = ioutil.ReadFile(tmpf); err != nil {
+			return false, err
+		}
+
+		var hl highlighter
+		if isTerminal(out) {
+			hl = highlighters[strings.ToLower(filepath.Ext(tmpf))]
+		}
+
+		question := "apply changes?"
+		if path != "" {
+			question = fmt.Sprintf("apply changes to %s?", path)
+		}
+
+		render(out, string(data), hl)
+		return ask(in, out, question)
+	}
+}
+
+// Writes the contents with line numbers, highlighting each line if specified.
+func render(out io.Writer, contents string, hl highlighter) {
+	lines := strings.Split(strings.TrimSuffix(contents, "\n"), "\n")
+	width := len(fmt.Sprintf("%d", len(lines)))
+
+	for i, line := range lines {
+		if hl != nil {
+			fmt.Fprintf(out, "%s%*d │%s %s\n", ansiDim, width, i+1, ansiReset, hl(line))
+		} else {
+			fmt.Fprintf(out, "%*d │ %s\n", width, i+1, line)
+		}
+	}
+}
+
+// Prompts the user with a Y/n question; an empty response is a yes.
+func ask(in *bufio.Reader, out io.Writer, question string) (bool, error) {
+	fmt.Fprintf(out, "%s [Y/n] ", question)
+
+	answer, err := in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "", "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// Returns true if the writer is a character device such as a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+//===========================================================================
+// Highlighters
+//===========================================================================
+
+// Highlighters by file extension, used if the preview is on a terminal.
+var highlighters = map[string]highlighter{
+	".json": highlightJSON,
+}
+
+// Matches the tokens of a JSON document that are highlighted.
+var jsonTokens = regexp.MustCompile(`"(?:[^"\\]|\\.)*"\s*:?|-?\d+(?:\.\d+)?(?:[eE][+-]?\d+)?|\btrue\b|\bfalse\b|\bnull\b`)
+
+// Highlights keys, strings, numbers, and literals of a line of JSON.
+func highlightJSON(line string) string {
+	return jsonTokens.ReplaceAllStringFunc(line, func(token string) string {
+		switch {
+		case strings.HasSuffix(token, ":"):
+			return ansiCyan + token + ansiReset
+		case strings.HasPrefix(token, `"`):
+			return ansiGreen + token + ansiReset
+		default:
+			return ansiPurple + token + ansiReset
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package editor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WithReview prompts the user on out, reading the answer from in, before the
+// edited content is saved and when it is invalid, with the option to apply the
+// changes, re-open the editor, view a unified diff of the changes, or abort.
+func WithReview(in io.Reader, out io.Writer) Option {
+	return func(o *options) {
+		o.review = &reviewer{in: o.reader(in), out: out}
+	}
+}
+
+// Actions that the user can choose when reviewing an edit.
+const (
+	actionApply = "apply"
+	actionEdit  = "edit"
+	actionDiff  = "diff"
+	actionQuit  = "quit"
+)
+
+// reviewer prompts the user to choose what to do with an edit. The buffered
+// reader is shared by every prompt of the edit so that buffered answers are not
+// lost.
+type reviewer struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// Prompts the user to apply, re-edit, diff, or abort the valid changes. Diffs
+// are shown until the user chooses one of the other actions.
+func (r *reviewer) changes(original, edited []byte, name string) (string, error) {
+	for {
+		action, err := r.choose("apply changes?", actionApply, actionApply, actionEdit, actionDiff, actionQuit)
+		if err != nil || action != actionDiff {
+			return action, err
+		}
+		r.diff(original, edited, name)
+	}
+}
+
+// Reports the validation error and prompts the user to re-edit, diff, or abort
+// the invalid changes.
+func (r *reviewer) invalid(verr error, original, edited []byte, name string) (string, error) {
+	fmt.Fprintf(r.out, "validation error: %s\n", verr)
+	for {
+		action, err := r.choose("edit again?", actionEdit, actionEdit, actionDiff, actionQuit)
+		if err != nil || action != actionDiff {
+			return action, err
+		}
+		r.diff(original, edited, name)
+	}
+}
+
+// Writes the unified diff of the changes to the output.
+func (r *reviewer) diff(original, edited []byte, name string) {
+	if name == "" {
+		name = "original"
+	}
+
+	diff := Diff(original, edited, name, "edited")
+	if diff == "" {
+		diff = "no changes\n"
+	}
+	fmt.Fprint(r.out, diff)
+}
+
+// Asks the question until the user responds with one of the actions or its
+// first letter; an empty response chooses the default and end of input quits.
+func (r *reviewer) choose(question, def string, actions ...string) (_ string, err error) {
+	choices := make([]string, 0, len(actions))
+	for _, action := range actions {
+		choices = append(choices, "["+action[:1]+"]"+action[1:])
+	}
+
+	for {
+		fmt.Fprintf(r.out, "%s %s (default %s): ", question, strings.Join(choices, ", "), def)
+
+		var answer string
+		if answer, err = r.in.ReadString('\n'); err != nil && err != io.EOF {
+			return "", err
+		}
+
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer == "" {
+			if err == io.EOF {
+				return actionQuit, nil
+			}
+			return def, nil
+		}
+
+		for _, action := range actions {
+			if answer == action || answer == action[:1] {
+				return action, nil
+			}
+		}
+
+		if err == io.EOF {
+			return actionQuit, nil
+		}
+		fmt.Fprintf(r.out, "unknown response %q\n", answer)
+	}
+}
@@ -0,0 +1,77 @@
+package editor_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bbengfort/x/editor"
+	. "github.com/onsi/gomega"
+)
+
+func TestDiff(t *testing.T) {
+	RegisterTestingT(t)
+
+	Ω(editor.Diff([]byte("a\nb\n"), []byte("a\nb\n"), "a", "b")).Should(BeEmpty())
+
+	a := []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n")
+	b := []byte("1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n15\n16\n")
+	Ω(editor.Diff(a, b, "original", "edited")).Should(Equal(`--- original
++++ edited
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -11,5 +11,5 @@
+ 11
+ 12
+ 13
+-14
+ 15
++16
+`))
+
+	// Additions to an empty file
+	Ω(editor.Diff(nil, []byte("a\n"), "original", "edited")).Should(Equal("--- original\n+++ edited\n@@ -0,0 +1 @@\n+a\n"))
+}
+
+func TestReview(t *testing.T) {
+	RegisterTestingT(t)
+
+	// View the diff then apply the changes
+	path, _ := sequenceEditor(t, "a: 2\n")
+	out := &bytes.Buffer{}
+	data, err := editor.EditBytes([]byte("a: 1\n"), editor.WithEditor(path), editor.WithReview(strings.NewReader("d\na\n"), out))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(string(data)).Should(Equal("a: 2\n"))
+	Ω(out.String()).Should(ContainSubstring("-a: 1\n+a: 2\n"))
+
+	// Re-edit and then abort the changes
+	path, _ = sequenceEditor(t, "a: 2\n", "a: 3\n")
+	_, err = editor.EditBytes([]byte("a: 1\n"), editor.WithEditor(path), editor.WithReview(strings.NewReader("e\nq\n"), out))
+	Ω(err).Should(Equal(editor.ErrDiscarded))
+
+	// Unknown responses are asked again, end of input aborts
+	path, _ = sequenceEditor(t, "a: 2\n")
+	out.Reset()
+	_, err = editor.EditBytes([]byte("a: 1\n"), editor.WithEditor(path), editor.WithReview(strings.NewReader("x\n"), out))
+	Ω(err).Should(Equal(editor.ErrDiscarded))
+	Ω(out.String()).Should(ContainSubstring(`unknown response "x"`))
+
+	// Invalid changes prompt to edit again, defaulting to re-editing
+	path, _ = sequenceEditor(t, `{"a": }`, `{"a": 2}`)
+	out.Reset()
+	data, err = editor.EditBytes([]byte(`{"a": 1}`), editor.WithEditor(path), editor.WithSuffix(".json"), editor.WithValidator(editor.ValidateJSON), editor.WithReview(strings.NewReader("\na\n"), out))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(string(data)).Should(Equal(`{"a": 2}`))
+	Ω(out.String()).Should(ContainSubstring("validation error"))
+
+	// Invalid changes can be aborted
+	path, _ = sequenceEditor(t, `{"a": }`)
+	_, err = editor.EditBytes([]byte(`{"a": 1}`), editor.WithEditor(path), editor.WithValidator(editor.ValidateJSON), editor.WithReview(strings.NewReader("q\n"), out))
+	Ω(err).Should(MatchError(ContainSubstring("validation error")))
+}
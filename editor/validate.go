@@ -0,0 +1,115 @@
+package editor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Validator checks the edited content before it is saved. If the content is
+// invalid, the editor is re-opened with the error injected as a comment so
+// that the user can fix the content rather than losing the edit. Implement the
+// interface to validate content against a JSON Schema or application rules.
+type Validator interface {
+	Validate(data []byte) error
+}
+
+// ValidateFunc is an adapter to allow the use of ordinary functions as validators.
+type ValidateFunc func([]byte) error
+
+// Validate calls f(data).
+func (f ValidateFunc) Validate(data []byte) error {
+	return f(data)
+}
+
+// Built-in validators that check the content is syntactically valid.
+var (
+	ValidateJSON ValidateFunc = validateJSON
+	ValidateYAML ValidateFunc = validateYAML
+	ValidateTOML ValidateFunc = validateTOML
+)
+
+func validateJSON(data []byte) error {
+	var v interface{}
+	return json.Unmarshal(data, &v)
+}
+
+func validateYAML(data []byte) error {
+	var v interface{}
+	return yaml.Unmarshal(data, &v)
+}
+
+func validateTOML(data []byte) error {
+	var v interface{}
+	return toml.Unmarshal(data, &v)
+}
+
+// ValidatorFor returns the built-in validator for the extension of the path or
+// nil if there is no validator for the file type.
+func ValidatorFor(path string) Validator {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return ValidateJSON
+	case ".yaml", ".yml":
+		return ValidateYAML
+	case ".toml":
+		return ValidateTOML
+	default:
+		return nil
+	}
+}
+
+//===========================================================================
+// Error Comments
+//===========================================================================
+
+// Marks the comments injected into the edited content so they can be removed.
+const commentMarker = "editor:"
+
+// Returns the line comment prefix to inject errors into a file with the suffix.
+// JSON has no comments, but the injected lines are removed before validation.
+func commentPrefix(suffix string) string {
+	switch strings.ToLower(suffix) {
+	case ".json", ".js", ".go", ".c", ".h", ".java":
+		return "//"
+	case ".sql", ".lua":
+		return "--"
+	default:
+		return "#"
+	}
+}
+
+// Prepends the validation error to the content as comments for the user.
+func injectError(data []byte, suffix string, err error) []byte {
+	prefix := commentPrefix(suffix) + " " + commentMarker
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s validation error, fix the content or save it unchanged to abort\n", prefix)
+	for _, line := range strings.Split(strings.TrimSpace(err.Error()), "\n") {
+		fmt.Fprintf(buf, "%s %s\n", prefix, line)
+	}
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+// Removes the injected error comments from the content.
+func stripErrors(data []byte, suffix string) []byte {
+	prefix := commentPrefix(suffix) + " " + commentMarker
+	if !bytes.Contains(data, []byte(prefix)) {
+		return data
+	}
+
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	out := make([]byte, 0, len(data))
+	for _, line := range lines {
+		if !bytes.HasPrefix(line, []byte(prefix)) {
+			out = append(out, line...)
+		}
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package editor_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bbengfort/x/editor"
+	. "github.com/onsi/gomega"
+)
+
+// Creates an editor that writes the nth contents on its nth invocation and
+// saves a copy of the file it was opened with as seen-n in the returned dir.
+func sequenceEditor(t *testing.T, contents ...string) (path, dir string) {
+	dir = t.TempDir()
+	path = filepath.Join(dir, "sequence-editor")
+	count := filepath.Join(dir, "count")
+
+	script := "#!/bin/sh\nn=$(cat " + count + " 2>/dev/null || echo 0)\n" +
+		"cp \"$1\" " + dir + "/seen-$n\necho $((n+1)) > " + count + "\ncase $n in\n"
+	for i, c := range contents {
+		script += fmt.Sprintf("%d) printf '%%s' '%s' > \"$1\" ;;\n", i, c)
+	}
+	script += "esac\n"
+
+	if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path, dir
+}
+
+func TestValidators(t *testing.T) {
+	RegisterTestingT(t)
+
+	Ω(editor.ValidateJSON.Validate([]byte(`{"a": 1}`))).Should(Succeed())
+	Ω(editor.ValidateJSON.Validate([]byte(`{"a": }`))).ShouldNot(Succeed())
+	Ω(editor.ValidateYAML.Validate([]byte("a: 1\nb: [1, 2]\n"))).Should(Succeed())
+	Ω(editor.ValidateYAML.Validate([]byte("a: [1, 2\n"))).ShouldNot(Succeed())
+	Ω(editor.ValidateTOML.Validate([]byte("a = 1\n[b]\nc = \"d\"\n"))).Should(Succeed())
+	Ω(editor.ValidateTOML.Validate([]byte("a = \n"))).ShouldNot(Succeed())
+
+	Ω(editor.ValidatorFor("config.json")).ShouldNot(BeNil())
+	Ω(editor.ValidatorFor("config.YML")).ShouldNot(BeNil())
+	Ω(editor.ValidatorFor("Cargo.toml")).ShouldNot(BeNil())
+	Ω(editor.ValidatorFor("notes.txt")).Should(BeNil())
+}
+
+func TestValidationRetry(t *testing.T) {
+	RegisterTestingT(t)
+
+	// The editor is re-opened with the error as a comment until the content is valid
+	path, dir := sequenceEditor(t, `{"a": }`, `// editor: leftover comment
+{"a": 1}`)
+	data, err := editor.EditBytes([]byte(`{"a": 0}`), editor.WithEditor(path), editor.WithSuffix(".json"), editor.WithValidator(editor.ValidateJSON))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(string(data)).Should(Equal(`{"a": 1}`), "injected comments should be removed")
+
+	seen, err := ioutil.ReadFile(filepath.Join(dir, "seen-1"))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(string(seen)).Should(HavePrefix("// editor: validation error"))
+	Ω(string(seen)).Should(HaveSuffix(`{"a": }`))
+
+	// Saving the invalid content unchanged aborts the edit
+	path, _ = sequenceEditor(t, "a = ")
+	_, err = editor.EditBytes([]byte("a = 1"), editor.WithEditor(path), editor.WithSuffix(".toml"), editor.WithValidator(editor.ValidateTOML))
+	Ω(err).Should(MatchError(ContainSubstring("validation error")))
+
+	// Custom validators, e.g. a schema, can be used
+	schema := editor.ValidateFunc(func(data []byte) error {
+		if !strings.Contains(string(data), "name:") {
+			return errors.New("missing required field name")
+		}
+		return nil
+	})
+
+	path, dir = sequenceEditor(t, "port: 80", "name: web\nport: 80")
+	data, err = editor.EditBytes([]byte("port: 8080"), editor.WithEditor(path), editor.WithSuffix(".yaml"), editor.WithValidator(schema))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(string(data)).Should(Equal("name: web\nport: 80"))
+
+	seen, err = ioutil.ReadFile(filepath.Join(dir, "seen-1"))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(string(seen)).Should(ContainSubstring("# editor: missing required field name"))
+}
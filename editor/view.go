@@ -0,0 +1,170 @@
+package editor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Returned by edit if the editor exits without changes when WithConfirmDiscard is
+// specified so that the original is not rewritten; it is never returned to callers.
+var errUnchanged = errors.New("no changes")
+
+// Flags that open editors in their read-only mode so that the user is warned
+// before modifying content that is only being viewed.
+var viewFlags = map[string]string{
+	"gvim": "-R",
+	"mvim": "-R",
+	"nano": "-v",
+	"nvim": "-R",
+	"vi":   "-R",
+	"vim":  "-R",
+}
+
+// WithReadOnly opens the editor to view the content without writing it back, e.g.
+// to show a generated configuration. The temporary file is made read-only and
+// the editor is opened in its read-only mode if it has one; if the user forces
+// changes anyway they are discarded. Edit does not modify the original file and
+// EditBytes returns the original content; validation, review, and confirmation
+// are skipped since there are no changes to save.
+func WithReadOnly() Option {
+	return func(o *options) {
+		o.readonly = true
+	}
+}
+
+// WithConfirmDiscard prompts the user on out, reading the answer from in, before
+// changes are discarded, e.g. because they were not confirmed or are invalid; if
+// the user does not confirm, the editor is re-opened with the changes. If the
+// editor exits without changes, nothing is validated, reviewed, or confirmed and
+// the user is told that there are no changes; the original is not rewritten.
+func WithConfirmDiscard(in io.Reader, out io.Writer) Option {
+	return func(o *options) {
+		o.discard = &discarder{in: o.reader(in), out: out}
+	}
+}
+
+// View opens a command line editor on the file without modifying it, see WithReadOnly.
+func View(path string, opts ...Option) error {
+	return Edit(path, append(opts[:len(opts):len(opts)], WithReadOnly())...)
+}
+
+// ViewBytes opens a command line editor on the content without returning changes
+// to it, see WithReadOnly.
+func ViewBytes(content []byte, opts ...Option) (err error) {
+	_, err = EditBytes(content, append(opts[:len(opts):len(opts)], WithReadOnly())...)
+	return err
+}
+
+// Executes the editor on the read-only temporary file. If the user changes the
+// file anyway and WithConfirmDiscard is specified, the user is told that the
+// changes were discarded.
+func (o *options) view(tmpf string) (err error) {
+	var (
+		editor string
+		args   []string
+	)
+	if editor, args, err = findEditor(o.editor); err != nil {
+		return err
+	}
+
+	if flag := viewFlag(editor, args); flag != "" {
+		args = append(args, flag)
+	}
+	args = append(append(args, o.args...), tmpf)
+
+	var original []byte
+	if original, err = ioutil.ReadFile(tmpf); err != nil {
+		return err
+	}
+
+	if err = os.Chmod(tmpf, 0400); err != nil {
+		return fmt.Errorf("could not make temporary file read-only: %v", err)
+	}
+
+	if err = run(editor, args); err != nil {
+		return err
+	}
+
+	if o.discard != nil {
+		if data, err := ioutil.ReadFile(tmpf); err == nil && !bytes.Equal(data, original) {
+			fmt.Fprintln(o.discard.out, "read-only: changes discarded")
+		}
+	}
+	return nil
+}
+
+// Returns true if the user chooses to keep editing rather than discarding the
+// changes. Edits are always discarded if WithConfirmDiscard is not specified and
+// are discarded without asking if there are no changes to lose.
+func (o *options) keep(original, edited []byte, reason string) (bool, error) {
+	if o.discard == nil || bytes.Equal(original, edited) {
+		return false, nil
+	}
+
+	discard, err := o.discard.confirm(reason)
+	if err != nil {
+		return false, fmt.Errorf("could not confirm discard: %v", err)
+	}
+	return !discard, nil
+}
+
+// Returns the read-only flag of the editor if it has one and the flag is not
+// already in the arguments.
+func viewFlag(editor string, args []string) string {
+	name := strings.TrimSuffix(strings.ToLower(filepath.Base(editor)), ".exe")
+	flag, ok := viewFlags[name]
+	if !ok {
+		return ""
+	}
+
+	for _, arg := range args {
+		if arg == flag {
+			return ""
+		}
+	}
+	return flag
+}
+
+//===========================================================================
+// Discard Confirmation
+//===========================================================================
+
+// discarder asks the user to confirm that changes are discarded. The buffered
+// reader is shared by every prompt of the edit so that buffered answers are not
+// lost.
+type discarder struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// Prompts the user with a y/N question; an empty response keeps the changes so
+// that they are not lost by accident, unless the input has ended.
+func (d *discarder) confirm(reason string) (_ bool, err error) {
+	fmt.Fprintf(d.out, "%s, discard changes? [y/N] ", reason)
+
+	var answer string
+	if answer, err = d.in.ReadString('\n'); err != nil && err != io.EOF {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	case "":
+		return err == io.EOF, nil
+	default:
+		return false, nil
+	}
+}
+
+// Tells the user that the editor exited without changes.
+func (d *discarder) unchanged() {
+	fmt.Fprintln(d.out, "no changes")
+}
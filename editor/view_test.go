@@ -0,0 +1,123 @@
+package editor_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bbengfort/x/editor"
+	. "github.com/onsi/gomega"
+)
+
+func TestView(t *testing.T) {
+	RegisterTestingT(t)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	Ω(ioutil.WriteFile(path, []byte("a: 1\n"), 0644)).Should(Succeed())
+	stat, err := os.Stat(path)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	// The original file is not modified even if the editor writes the file
+	out := &bytes.Buffer{}
+	Ω(editor.View(path, editor.WithEditor(fakeEditor(t, "a: 2")), editor.WithConfirmDiscard(strings.NewReader(""), out))).Should(Succeed())
+	data, err := ioutil.ReadFile(path)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(string(data)).Should(Equal("a: 1\n"))
+	Ω(out.String()).Should(ContainSubstring("read-only: changes discarded"))
+
+	after, err := os.Stat(path)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(after.Mode()).Should(Equal(stat.Mode()))
+	Ω(after.ModTime()).Should(Equal(stat.ModTime()))
+
+	// The editor is given a read-only temporary file and nothing is validated or confirmed
+	editorPath, dir := sequenceEditor(t)
+	confirm := func(string) (bool, error) {
+		t.Error("read-only content should not be confirmed")
+		return false, nil
+	}
+	data, err = editor.EditBytes([]byte("not json"), editor.WithEditor(editorPath), editor.WithReadOnly(), editor.WithValidator(editor.ValidateJSON), editor.WithConfirm(confirm))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(string(data)).Should(Equal("not json"))
+
+	seen, err := os.Stat(filepath.Join(dir, "seen-0"))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(seen.Mode().Perm()).Should(Equal(os.FileMode(0400)))
+
+	Ω(editor.ViewBytes([]byte("hello"), editor.WithEditor(fakeEditor(t, "world")))).Should(Succeed())
+}
+
+func TestConfirmDiscard(t *testing.T) {
+	RegisterTestingT(t)
+
+	// Exiting without changes does not confirm or rewrite the original
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	Ω(ioutil.WriteFile(path, []byte("a: 1\n"), 0644)).Should(Succeed())
+	stat, err := os.Stat(path)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	editorPath, _ := sequenceEditor(t)
+	out := &bytes.Buffer{}
+	err = editor.Edit(path, editor.WithEditor(editorPath), editor.WithPreview(strings.NewReader("n\n"), out), editor.WithConfirmDiscard(strings.NewReader(""), out))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(out.String()).Should(Equal("no changes\n"))
+
+	after, err := os.Stat(path)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(after.ModTime()).Should(Equal(stat.ModTime()))
+
+	// Declining to discard unconfirmed changes re-opens the editor with the changes
+	editorPath, dir := sequenceEditor(t, "a: 2\n")
+	out.Reset()
+	data, err := editor.EditBytes([]byte("a: 1\n"), editor.WithEditor(editorPath), editor.WithPreview(strings.NewReader("n\ny\n"), out), editor.WithConfirmDiscard(strings.NewReader("\n"), out))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(string(data)).Should(Equal("a: 2\n"))
+	Ω(out.String()).Should(ContainSubstring("changes not confirmed, discard changes? [y/N]"))
+
+	seen, err := ioutil.ReadFile(filepath.Join(dir, "seen-1"))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(string(seen)).Should(Equal("a: 2\n"))
+
+	// Confirming the discard discards the changes
+	editorPath, _ = sequenceEditor(t, "a: 2\n")
+	_, err = editor.EditBytes([]byte("a: 1\n"), editor.WithEditor(editorPath), editor.WithReview(strings.NewReader("q\n"), out), editor.WithConfirmDiscard(strings.NewReader("y\n"), out))
+	Ω(err).Should(Equal(editor.ErrDiscarded))
+
+	// Invalid changes can be kept and fixed instead of discarded
+	editorPath, _ = sequenceEditor(t, `{"a": }`, `{"a": }`, `{"a": 2}`)
+	data, err = editor.EditBytes([]byte(`{"a": 1}`), editor.WithEditor(editorPath), editor.WithSuffix(".json"), editor.WithValidator(editor.ValidateJSON), editor.WithConfirmDiscard(strings.NewReader("n\n"), out))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(string(data)).Should(Equal(`{"a": 2}`))
+
+	// End of input discards the changes
+	editorPath, _ = sequenceEditor(t, "a: 2\n")
+	_, err = editor.EditBytes([]byte("a: 1\n"), editor.WithEditor(editorPath), editor.WithReview(strings.NewReader(""), out), editor.WithConfirmDiscard(strings.NewReader(""), out))
+	Ω(err).Should(Equal(editor.ErrDiscarded))
+}
+
+func TestSharedInput(t *testing.T) {
+	RegisterTestingT(t)
+
+	// Every prompt of an edit reads its answer from the same input
+	editorPath, _ := sequenceEditor(t, "a: 2\n")
+	out := &bytes.Buffer{}
+	in := strings.NewReader("n\n\ny\n")
+	data, err := editor.EditBytes([]byte("a: 1\n"), editor.WithEditor(editorPath), editor.WithPreview(in, out), editor.WithConfirmDiscard(in, out))
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(string(data)).Should(Equal("a: 2\n"))
+	Ω(strings.Count(out.String(), "apply changes? [Y/n]")).Should(Equal(2))
+
+	// Conflicts are resolved with answers from the input of the review
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	Ω(ioutil.WriteFile(path, []byte("a\nb\nc\n"), 0644)).Should(Succeed())
+
+	in = strings.NewReader("a\no\n")
+	err = editor.Edit(path, editor.WithEditor(concurrentEditor(t, path, "a\nB\nc\n", "a\nb\nc\nd\n", "")), editor.WithReview(in, out), editor.WithPrompt(in, out))
+	Ω(err).ShouldNot(HaveOccurred())
+	data, err = ioutil.ReadFile(path)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(string(data)).Should(Equal("a\nB\nc\n"))
+}
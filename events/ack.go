@@ -0,0 +1,296 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Defaults of the retry policy for event types with at-least-once delivery.
+const (
+	DefaultAckTimeout  = 5 * time.Second
+	DefaultBackoff     = 100 * time.Millisecond
+	DefaultMaxBackoff  = 30 * time.Second
+	DefaultMaxAttempts = 10
+	DefaultErrorBuffer = 64
+)
+
+// ErrNotAcknowledged is the error of a delivery attempt when the callback did not
+// return an error but did not acknowledge the event within the ack timeout.
+var ErrNotAcknowledged = errors.New("event was not acknowledged")
+
+//===========================================================================
+// At-Least-Once Delivery
+//===========================================================================
+
+// RetryPolicy describes how events of a reliable type are redelivered to the
+// callbacks that have not acknowledged them. Zero values use the defaults.
+type RetryPolicy struct {
+	AckTimeout  time.Duration // how long to wait for an ack after a callback returns
+	Backoff     time.Duration // delay before the first redelivery, doubled after each attempt
+	MaxBackoff  time.Duration // the maximum delay between redeliveries
+	MaxAttempts int           // the number of attempts before giving up, negative to retry until acked
+}
+
+// Delivery is the event passed to callbacks of reliable event types. The
+// callback must Ack the delivery, either before it returns or later, e.g. once
+// the event has been persisted, otherwise the event is delivered again.
+type Delivery interface {
+	Event
+	Ack()         // acknowledge the event so that it is not delivered again
+	Attempt() int // the delivery attempt, starting at 1
+}
+
+// Ack acknowledges the event if it is a Delivery, returning true if so; callbacks
+// that are registered for both reliable and fire-and-forget event types can call
+// Ack on every event they receive.
+func Ack(e Event) bool {
+	if d, ok := e.(Delivery); ok {
+		d.Ack()
+		return true
+	}
+	return false
+}
+
+// DeliveryError is sent on the errors channel of the dispatcher when an event
+// could not be delivered to a callback in the maximum number of attempts.
+type DeliveryError struct {
+	Event    Event // the event that was not acknowledged
+	Attempts int   // the number of times the event was delivered
+	Err      error // the error of the last attempt
+}
+
+// Error describes the failed delivery.
+func (e *DeliveryError) Error() string {
+	return fmt.Sprintf("%s event not acknowledged after %d attempts: %s", e.Event.Type(), e.Attempts, e.Err)
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// Reliable opts the event type into at-least-once delivery for events that must
+// not be lost, e.g. persistence triggers. Callbacks of the type receive a
+// Delivery that they must Ack; if a callback returns an error or does not
+// acknowledge the event within the ack timeout, the event is delivered to that
+// callback again in the background with exponential backoff. When the attempts
+// are exhausted, a *DeliveryError is sent on the Errors channel. Because failed
+// deliveries are retried, Dispatch does not return the errors of the callbacks
+// of reliable types. Passing a nil policy restores fire-and-forget delivery.
+func (d *Dispatcher) Reliable(etype Type, policy *RetryPolicy) {
+	d.Lock()
+	defer d.Unlock()
+
+	if policy == nil {
+		delete(d.reliable, etype)
+		return
+	}
+
+	if d.reliable == nil {
+		d.reliable = make(map[Type]RetryPolicy)
+	}
+	d.reliable[etype] = *policy
+	d.retrying()
+}
+
+// Errors returns the channel that failed deliveries of reliable events are sent
+// on. The channel is buffered; if it is full, redelivery blocks until the error
+// is received or StopRetries is called, so the channel should be drained.
+func (d *Dispatcher) Errors() <-chan error {
+	d.Lock()
+	defer d.Unlock()
+	d.retrying()
+	return d.errors
+}
+
+// Pending returns the number of deliveries of reliable events that have not been
+// acknowledged or abandoned.
+func (d *Dispatcher) Pending() int {
+	d.rmu.Lock()
+	defer d.rmu.Unlock()
+	return d.pending
+}
+
+// StopRetries abandons the redelivery of all pending events without sending
+// errors, e.g. when shutting down. Events dispatched afterwards are retried.
+func (d *Dispatcher) StopRetries() {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.stop != nil {
+		close(d.stop)
+		d.stop = nil
+		d.retrying()
+	}
+}
+
+// Creates the errors and stop channels if they do not exist (not thread-safe).
+func (d *Dispatcher) retrying() {
+	if d.errors == nil {
+		d.errors = make(chan error, DefaultErrorBuffer)
+	}
+
+	if d.stop == nil {
+		d.stop = make(chan struct{})
+	}
+}
+
+// Delivers the event to each callback, redelivering it in the background to the
+// callbacks that do not acknowledge it (not thread-safe, surrounded by locks).
+func (d *Dispatcher) deliver(e *event, policy RetryPolicy, callbacks []Callback) {
+	for _, cb := range callbacks {
+		dv := &delivery{
+			event:    e,
+			callback: cb,
+			policy:   policy,
+			acked:    make(chan struct{}),
+			errors:   d.errors,
+			stop:     d.stop,
+		}
+
+		d.track(1)
+		err := dv.attempt(1)
+		if err == nil && dv.isAcked() {
+			d.track(-1)
+			continue
+		}
+		go d.redeliver(dv, err)
+	}
+}
+
+// Redelivers the event until it is acknowledged, the attempts are exhausted, or
+// the retries are stopped.
+func (d *Dispatcher) redeliver(dv *delivery, err error) {
+	defer d.track(-1)
+	backoff := dv.policy.backoff()
+
+	for n := 1; ; n++ {
+		// Wait for the callback to acknowledge the event
+		if err == nil {
+			timer := time.NewTimer(dv.policy.ackTimeout())
+			select {
+			case <-dv.acked:
+				timer.Stop()
+				return
+			case <-dv.stop:
+				timer.Stop()
+				return
+			case <-timer.C:
+				err = ErrNotAcknowledged
+			}
+		}
+
+		if max := dv.policy.maxAttempts(); max > 0 && n >= max {
+			select {
+			case dv.errors <- &DeliveryError{Event: dv.event, Attempts: n, Err: err}:
+			case <-dv.acked:
+			case <-dv.stop:
+			}
+			return
+		}
+
+		// Back off before delivering the event again
+		timer := time.NewTimer(backoff)
+		select {
+		case <-dv.acked:
+			timer.Stop()
+			return
+		case <-dv.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if backoff *= 2; backoff > dv.policy.maxBackoff() {
+			backoff = dv.policy.maxBackoff()
+		}
+
+		if err = dv.attempt(n + 1); err == nil && dv.isAcked() {
+			return
+		}
+	}
+}
+
+// Updates the number of pending deliveries.
+func (d *Dispatcher) track(delta int) {
+	d.rmu.Lock()
+	d.pending += delta
+	d.rmu.Unlock()
+}
+
+func (p RetryPolicy) ackTimeout() time.Duration {
+	if p.AckTimeout > 0 {
+		return p.AckTimeout
+	}
+	return DefaultAckTimeout
+}
+
+func (p RetryPolicy) backoff() time.Duration {
+	if p.Backoff > 0 {
+		return p.Backoff
+	}
+	return DefaultBackoff
+}
+
+func (p RetryPolicy) maxBackoff() time.Duration {
+	if p.MaxBackoff > 0 {
+		return p.MaxBackoff
+	}
+	return DefaultMaxBackoff
+}
+
+func (p RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts == 0 {
+		return DefaultMaxAttempts
+	}
+	return p.MaxAttempts
+}
+
+// delivery tracks the attempts to deliver an event to a single callback.
+type delivery struct {
+	event    *event
+	callback Callback
+	policy   RetryPolicy
+	once     sync.Once
+	acked    chan struct{} // closed when the event is acknowledged
+	errors   chan<- error  // the errors channel of the dispatcher
+	stop     chan struct{} // closed when retries are stopped
+}
+
+// Delivers the event to the callback, returning the error of the callback.
+func (d *delivery) attempt(n int) error {
+	return d.callback(&attempt{event: d.event, delivery: d, n: n})
+}
+
+func (d *delivery) ack() {
+	d.once.Do(func() { close(d.acked) })
+}
+
+func (d *delivery) isAcked() bool {
+	select {
+	case <-d.acked:
+		return true
+	default:
+		return false
+	}
+}
+
+// attempt is the Delivery passed to the callback for a single delivery attempt;
+// acknowledging any attempt acknowledges the delivery.
+type attempt struct {
+	*event
+	delivery *delivery
+	n        int
+}
+
+// Ack acknowledges the event so that it is not delivered again.
+func (a *attempt) Ack() {
+	a.delivery.ack()
+}
+
+// Attempt returns the delivery attempt, starting at 1.
+func (a *attempt) Attempt() int {
+	return a.n
+}
@@ -0,0 +1,136 @@
+package events_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	. "github.com/bbengfort/x/events"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("At-Least-Once Delivery", func() {
+
+	var FooEvent = Type(42)
+	var policy = &RetryPolicy{AckTimeout: 20 * time.Millisecond, Backoff: time.Millisecond, MaxAttempts: 3}
+
+	It("should deliver acknowledged events once", func() {
+		dispatcher := new(Dispatcher)
+		dispatcher.Init("source")
+		dispatcher.Reliable(FooEvent, policy)
+
+		var calls int32
+		dispatcher.Register(FooEvent, func(e Event) error {
+			atomic.AddInt32(&calls, 1)
+			Ω(e.(Delivery).Attempt()).Should(Equal(1))
+			Ω(Ack(e)).Should(BeTrue())
+			return nil
+		})
+
+		Ω(dispatcher.Dispatch(FooEvent, "value")).Should(Succeed())
+		Ω(dispatcher.Pending()).Should(Equal(0))
+
+		time.Sleep(50 * time.Millisecond)
+		Ω(atomic.LoadInt32(&calls)).Should(Equal(int32(1)))
+	})
+
+	It("should redeliver events when the callback returns an error", func() {
+		dispatcher := new(Dispatcher)
+		dispatcher.Init(nil)
+		dispatcher.Reliable(FooEvent, policy)
+
+		var calls int32
+		dispatcher.Register(FooEvent, func(e Event) error {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				return errors.New("could not persist event")
+			}
+			Ack(e)
+			return nil
+		})
+
+		// Errors of callbacks of reliable events are not returned
+		Ω(dispatcher.Dispatch(FooEvent, nil)).Should(Succeed())
+		Ω(dispatcher.Pending()).Should(Equal(1))
+
+		Eventually(dispatcher.Pending).Should(Equal(0))
+		Ω(atomic.LoadInt32(&calls)).Should(Equal(int32(3)))
+		Consistently(dispatcher.Errors()).ShouldNot(Receive())
+	})
+
+	It("should accept acknowledgements after the callback returns", func() {
+		dispatcher := new(Dispatcher)
+		dispatcher.Init(nil)
+		dispatcher.Reliable(FooEvent, policy)
+
+		var calls int32
+		deliveries := make(chan Delivery, 1)
+		dispatcher.Register(FooEvent, func(e Event) error {
+			atomic.AddInt32(&calls, 1)
+			deliveries <- e.(Delivery)
+			return nil
+		})
+
+		Ω(dispatcher.Dispatch(FooEvent, nil)).Should(Succeed())
+		(<-deliveries).Ack()
+
+		Eventually(dispatcher.Pending).Should(Equal(0))
+		Ω(atomic.LoadInt32(&calls)).Should(Equal(int32(1)))
+	})
+
+	It("should surface events that are never acknowledged", func() {
+		dispatcher := new(Dispatcher)
+		dispatcher.Init(nil)
+		dispatcher.Reliable(FooEvent, policy)
+
+		var calls int32
+		dispatcher.Register(FooEvent, func(e Event) error {
+			atomic.AddInt32(&calls, 1)
+			return nil
+		})
+
+		Ω(dispatcher.Dispatch(FooEvent, "lost")).Should(Succeed())
+
+		var err error
+		Eventually(dispatcher.Errors()).Should(Receive(&err))
+		Ω(errors.Is(err, ErrNotAcknowledged)).Should(BeTrue())
+
+		derr, ok := err.(*DeliveryError)
+		Ω(ok).Should(BeTrue())
+		Ω(derr.Attempts).Should(Equal(3))
+		Ω(derr.Event.Value()).Should(Equal("lost"))
+		Ω(atomic.LoadInt32(&calls)).Should(Equal(int32(3)))
+		Eventually(dispatcher.Pending).Should(Equal(0))
+	})
+
+	It("should abandon pending deliveries when retries are stopped", func() {
+		dispatcher := new(Dispatcher)
+		dispatcher.Init(nil)
+		dispatcher.Reliable(FooEvent, &RetryPolicy{AckTimeout: time.Hour, MaxAttempts: -1})
+		dispatcher.Register(FooEvent, func(e Event) error { return nil })
+
+		Ω(dispatcher.Dispatch(FooEvent, nil)).Should(Succeed())
+		Ω(dispatcher.Pending()).Should(Equal(1))
+
+		dispatcher.StopRetries()
+		Eventually(dispatcher.Pending).Should(Equal(0))
+		Ω(dispatcher.Errors()).ShouldNot(Receive())
+	})
+
+	It("should deliver fire-and-forget events without acknowledgement", func() {
+		dispatcher := new(Dispatcher)
+		dispatcher.Init(nil)
+		dispatcher.Reliable(FooEvent, policy)
+		dispatcher.Reliable(FooEvent, nil)
+
+		dispatcher.Register(FooEvent, func(e Event) error {
+			Ω(Ack(e)).Should(BeFalse())
+			return nil
+		})
+
+		Ω(dispatcher.Dispatch(FooEvent, nil)).Should(Succeed())
+		Ω(dispatcher.Pending()).Should(Equal(0))
+	})
+
+})
@@ -0,0 +1,106 @@
+package events
+
+import (
+	"os"
+	"time"
+)
+
+//===========================================================================
+// Standard Event Catalog
+//===========================================================================
+
+// The standard event types are dispatched by several x packages so that they
+// can interoperate without agreeing on magic numbers. Each type has a typed
+// payload that is dispatched as the value of the event (by pointer); timeout
+// and election timeout events are dispatched without a value. Custom event
+// types should start at FirstCustomEvent so they do not collide with future
+// standard types.
+const FirstCustomEvent Type = 64
+
+// Heartbeat is the value of a HeartbeatEvent, dispatched periodically to
+// indicate that the source (e.g. a peer or subsystem) is alive.
+type Heartbeat struct {
+	Source   string    // name of the peer or subsystem that is alive
+	Sequence uint64    // monotonically increasing heartbeat number
+	Sent     time.Time // when the heartbeat was sent by the source
+}
+
+// PeerChangeKind describes how a peer changed in a PeerChangeEvent.
+type PeerChangeKind uint8
+
+// Kinds of peer changes.
+const (
+	PeerAdded PeerChangeKind = iota + 1
+	PeerRemoved
+	PeerUpdated
+)
+
+// String returns the name of the kind of peer change.
+func (k PeerChangeKind) String() string {
+	switch k {
+	case PeerAdded:
+		return "added"
+	case PeerRemoved:
+		return "removed"
+	case PeerUpdated:
+		return "updated"
+	default:
+		return "unknown"
+	}
+}
+
+// PeerChange is the value of a PeerChangeEvent, dispatched when a peer joins,
+// leaves, or changes its address in the network.
+type PeerChange struct {
+	Kind PeerChangeKind // how the peer changed
+	PID  uint16         // the process id of the peer, if known
+	Name string         // the unique name of the peer
+	Addr string         // the address of the peer (the previous address if removed)
+}
+
+// Shutdown is the value of a ShutdownEvent, dispatched when the process or a
+// subsystem begins shutting down.
+type Shutdown struct {
+	Reason string    // human readable reason for the shutdown
+	Signal os.Signal // the signal that caused the shutdown, nil if none
+	Err    error     // the fatal error that caused the shutdown, nil if none
+}
+
+// Error is the value of an ErrorEvent, dispatched when a subsystem encounters
+// an error that listeners should know about, e.g. to log or to shut down.
+type Error struct {
+	Source string // name of the subsystem that encountered the error
+	Err    error  // the error that occurred
+	Fatal  bool   // if the subsystem cannot continue after the error
+}
+
+// Error returns the message of the underlying error prefixed with the source.
+func (e *Error) Error() string {
+	if e.Source == "" {
+		return e.Err.Error()
+	}
+	return e.Source + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Complete is the value of a CompleteEvent, dispatched when a bounded task, e.g.
+// an interval that fires a limited number of times or until a deadline, stops on
+// its own rather than being stopped.
+type Complete struct {
+	Reason string // why the task completed, e.g. limit or deadline
+	Count  uint64 // the number of times the task ran
+}
+
+// ValidateStandard registers validators for the standard event types so that
+// dispatching a standard event with the wrong payload returns an error.
+func (d *Dispatcher) ValidateStandard() {
+	d.Validate(HeartbeatEvent, OfType[*Heartbeat]())
+	d.Validate(PeerChangeEvent, OfType[*PeerChange]())
+	d.Validate(ShutdownEvent, OfType[*Shutdown]())
+	d.Validate(ErrorEvent, OfType[*Error]())
+	d.Validate(CompleteEvent, OfType[*Complete]())
+}
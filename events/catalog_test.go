@@ -0,0 +1,55 @@
+package events_test
+
+import (
+	"errors"
+	"syscall"
+
+	. "github.com/bbengfort/x/events"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Standard Event Catalog", func() {
+
+	It("should name the standard event types", func() {
+		Ω(HeartbeatEvent.String()).Should(Equal("heartbeat"))
+		Ω(PeerChangeEvent.String()).Should(Equal("peer change"))
+		Ω(ShutdownEvent.String()).Should(Equal("shutdown"))
+		Ω(ErrorEvent.String()).Should(Equal("error"))
+		Ω(CompleteEvent.String()).Should(Equal("complete"))
+		Ω(FirstCustomEvent.String()).Should(Equal("custom"))
+		Ω(PeerRemoved.String()).Should(Equal("removed"))
+	})
+
+	It("should validate the payloads of standard events", func() {
+		dispatcher := new(Dispatcher)
+		dispatcher.Init(nil)
+		dispatcher.ValidateStandard()
+
+		var shutdown *Shutdown
+		dispatcher.Register(ShutdownEvent, func(e Event) error {
+			shutdown = e.Value().(*Shutdown)
+			return nil
+		})
+
+		Ω(dispatcher.Dispatch(ShutdownEvent, &Shutdown{Reason: "interrupted", Signal: syscall.SIGINT})).Should(Succeed())
+		Ω(shutdown.Signal).Should(Equal(syscall.SIGINT))
+
+		Ω(dispatcher.Dispatch(HeartbeatEvent, &Heartbeat{Source: "alpha", Sequence: 1})).Should(Succeed())
+		Ω(dispatcher.Dispatch(PeerChangeEvent, &PeerChange{Kind: PeerAdded, Name: "bravo"})).Should(Succeed())
+		Ω(dispatcher.Dispatch(HeartbeatEvent, "alpha")).ShouldNot(Succeed())
+		Ω(dispatcher.Dispatch(ErrorEvent, errors.New("boom"))).ShouldNot(Succeed())
+		Ω(dispatcher.Dispatch(CompleteEvent, &Complete{Reason: "limit", Count: 3})).Should(Succeed())
+
+		// Timeout events do not have a payload
+		Ω(dispatcher.Dispatch(TimeoutEvent, nil)).Should(Succeed())
+	})
+
+	It("should wrap errors in error events", func() {
+		cause := errors.New("connection refused")
+		err := &Error{Source: "replica", Err: cause, Fatal: true}
+		Ω(err.Error()).Should(Equal("replica: connection refused"))
+		Ω(errors.Is(err, cause)).Should(BeTrue())
+	})
+})
@@ -8,15 +8,22 @@ import (
 	"sync"
 )
 
-// Some standard event types
+// Some standard event types, see catalog.go for the payloads of each type.
 const (
 	UnknownEvent Type = iota
 	TimeoutEvent
+	HeartbeatEvent
+	PeerChangeEvent
+	ShutdownEvent
+	ErrorEvent
+	CompleteEvent
+	ElectionTimeoutEvent
 )
 
 // Names of event types
 var eventTypeStrings = [...]string{
-	"unknown", "timeout",
+	"unknown", "timeout", "heartbeat", "peer change", "shutdown", "error", "complete",
+	"election timeout",
 }
 
 //===========================================================================
@@ -45,8 +52,18 @@ type Callback func(Event) error
 // those events occur, dispatch them to all callback functions.
 type Dispatcher struct {
 	sync.RWMutex
-	source    interface{}
-	callbacks map[Type][]Callback
+	source     interface{}
+	callbacks  map[Type][]Callback
+	validators map[Type]Validator   // checks event values before they are delivered
+	hmu        sync.Mutex           // guards the history separately from callbacks
+	hsize      int                  // the maximum number of events per type in the history
+	history    map[Type]*ring       // recently dispatched events if the history is enabled
+	reliable   map[Type]RetryPolicy // event types with at-least-once delivery
+	errors     chan error           // failed deliveries of reliable events
+	stop       chan struct{}        // closed to abandon pending redeliveries
+	rmu        sync.Mutex           // guards the number of pending deliveries
+	pending    int                  // deliveries of reliable events that have not been acknowledged
+	fanout     []*Dispatcher        // dispatchers that combine the events of this dispatcher with FanIn
 }
 
 // Init a dispatcher with the source, creating the callbacks map.
@@ -83,8 +100,9 @@ func (d *Dispatcher) Remove(etype Type, callback Callback) {
 	}
 }
 
-// Dispatch an event, ensuring that the event is properly formatted.
-// Currently this method simply warns if there is an error.
+// Dispatch an event, ensuring that the event is properly formatted. If a
+// validator is registered for the event type and the value is invalid, a
+// *ValidationError is returned and no callbacks are called.
 // TODO: return list of errors or do better error handling.
 func (d *Dispatcher) Dispatch(etype Type, value interface{}) error {
 	d.RLock()
@@ -94,15 +112,36 @@ func (d *Dispatcher) Dispatch(etype Type, value interface{}) error {
 
 // Internal dispatch event that is not thread-safe (surrounded by locks).
 func (d *Dispatcher) dispatch(etype Type, value interface{}) error {
-	// Create the event
-	e := &event{
+	return d.emit(&event{
 		etype:  etype,
 		source: d.source,
+		origin: d,
 		value:  value,
+	})
+}
+
+// Validates, records, forwards, and delivers the event to the callbacks (not
+// thread-safe, surrounded by locks).
+func (d *Dispatcher) emit(e *event) error {
+	// Validate the event value
+	if err := d.validate(e.etype, e.value); err != nil {
+		return err
+	}
+
+	// Record the event in the history
+	d.record(e)
+
+	// Forward the event to the dispatchers that fan in this dispatcher
+	d.forward(e)
+
+	// Reliable events are redelivered to callbacks until they are acknowledged
+	if policy, ok := d.reliable[e.etype]; ok {
+		d.deliver(e, policy, d.callbacks[e.etype])
+		return nil
 	}
 
 	// Dispatch the event to all callbacks
-	for _, cb := range d.callbacks[etype] {
+	for _, cb := range d.callbacks[e.etype] {
 		if err := cb(e); err != nil {
 			return err
 		}
@@ -127,6 +166,7 @@ type Event interface {
 type event struct {
 	etype  Type
 	source interface{}
+	origin *Dispatcher
 	value  interface{}
 }
 
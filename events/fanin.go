@@ -0,0 +1,63 @@
+package events
+
+//===========================================================================
+// Fan In
+//===========================================================================
+
+// FanIn returns a dispatcher that combines the events of the dispatchers into a
+// single stream, e.g. so that a top-level supervisor can observe the events of
+// many subsystems without registering callbacks with each of them. Every event
+// dispatched by one of the dispatchers, of any type, is also dispatched by the
+// combined dispatcher after it is validated and recorded by the original
+// dispatcher. The events are tagged with their source: Source returns the
+// source of the original dispatcher rather than of the combined dispatcher,
+// and Origin returns the original dispatcher itself.
+//
+// The combined dispatcher is an ordinary dispatcher: callbacks are registered
+// with it by event type, and its validators, history, and reliable event types
+// apply to the combined stream. Errors returned by its callbacks are not
+// returned to the original dispatcher so that observers cannot interfere with
+// the subsystems they observe. Events can also be dispatched directly on the
+// combined dispatcher, whose source is nil. Dispatchers can be combined by more
+// than one FanIn, and combined dispatchers can be fanned in again, but the
+// dispatchers must not form a cycle.
+func FanIn(dispatchers ...*Dispatcher) *Dispatcher {
+	combined := new(Dispatcher)
+	combined.Init(nil)
+
+	for _, d := range dispatchers {
+		if d == nil {
+			continue
+		}
+
+		d.Lock()
+		d.fanout = append(d.fanout, combined)
+		d.Unlock()
+	}
+	return combined
+}
+
+// Origin returns the dispatcher that originally dispatched the event, e.g. to
+// determine which of the dispatchers combined by FanIn an event came from if
+// their sources are not distinct. Nil is returned for events that were not
+// created by a dispatcher.
+func Origin(e Event) *Dispatcher {
+	switch e := e.(type) {
+	case *event:
+		return e.origin
+	case *attempt:
+		return e.origin
+	default:
+		return nil
+	}
+}
+
+// Forwards the event to the dispatchers that fan in this dispatcher, ignoring
+// the errors of their callbacks (not thread-safe, surrounded by locks).
+func (d *Dispatcher) forward(e *event) {
+	for _, combined := range d.fanout {
+		combined.RLock()
+		combined.emit(e)
+		combined.RUnlock()
+	}
+}
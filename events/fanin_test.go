@@ -0,0 +1,109 @@
+package events_test
+
+import (
+	"errors"
+
+	. "github.com/bbengfort/x/events"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fan In", func() {
+
+	var FooEvent = Type(42)
+	var BarEvent = Type(64)
+
+	It("should combine the events of multiple dispatchers", func() {
+		alpha := new(Dispatcher)
+		alpha.Init("alpha")
+		bravo := new(Dispatcher)
+		bravo.Init("bravo")
+
+		combined := FanIn(alpha, bravo)
+
+		var sources []interface{}
+		var origins []*Dispatcher
+		combined.Register(FooEvent, func(e Event) error {
+			sources = append(sources, e.Source())
+			origins = append(origins, Origin(e))
+			return nil
+		})
+
+		var bars int
+		combined.Register(BarEvent, func(e Event) error {
+			bars++
+			return nil
+		})
+
+		Ω(alpha.Dispatch(FooEvent, 1)).Should(Succeed())
+		Ω(bravo.Dispatch(FooEvent, 2)).Should(Succeed())
+		Ω(bravo.Dispatch(BarEvent, 3)).Should(Succeed())
+		Ω(combined.Dispatch(FooEvent, 4)).Should(Succeed())
+
+		Ω(sources).Should(Equal([]interface{}{"alpha", "bravo", nil}))
+		Ω(origins).Should(Equal([]*Dispatcher{alpha, bravo, combined}))
+		Ω(bars).Should(Equal(1))
+	})
+
+	It("should still deliver events to the original callbacks", func() {
+		alpha := new(Dispatcher)
+		alpha.Init("alpha")
+
+		var count int
+		alpha.Register(FooEvent, func(e Event) error {
+			count++
+			return nil
+		})
+
+		combined := FanIn(alpha)
+		combined.Register(FooEvent, func(e Event) error {
+			count += 10
+			return errors.New("observer failed")
+		})
+
+		// Errors of the combined dispatcher are not returned to the original
+		Ω(alpha.Dispatch(FooEvent, nil)).Should(Succeed())
+		Ω(count).Should(Equal(11))
+	})
+
+	It("should not forward invalid events", func() {
+		alpha := new(Dispatcher)
+		alpha.Init("alpha")
+		alpha.Validate(FooEvent, OfType[int]())
+
+		combined := FanIn(alpha)
+		combined.SetHistory(10)
+
+		Ω(alpha.Dispatch(FooEvent, "not an int")).ShouldNot(Succeed())
+		Ω(alpha.Dispatch(FooEvent, 1)).Should(Succeed())
+
+		history := combined.History(FooEvent, 0)
+		Ω(history).Should(HaveLen(1))
+		Ω(history[0].Value()).Should(Equal(1))
+		Ω(history[0].Source()).Should(Equal("alpha"))
+	})
+
+	It("should fan in combined dispatchers", func() {
+		alpha := new(Dispatcher)
+		alpha.Init("alpha")
+		bravo := new(Dispatcher)
+		bravo.Init("bravo")
+		charlie := new(Dispatcher)
+		charlie.Init("charlie")
+
+		top := FanIn(FanIn(alpha, bravo), charlie, nil)
+
+		var sources []interface{}
+		top.Register(FooEvent, func(e Event) error {
+			sources = append(sources, e.Source())
+			return nil
+		})
+
+		alpha.Dispatch(FooEvent, nil)
+		bravo.Dispatch(FooEvent, nil)
+		charlie.Dispatch(FooEvent, nil)
+		Ω(sources).Should(Equal([]interface{}{"alpha", "bravo", "charlie"}))
+	})
+
+})
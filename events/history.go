@@ -0,0 +1,100 @@
+package events
+
+import "time"
+
+//===========================================================================
+// Event History
+//===========================================================================
+
+// Record is an event in the history of a dispatcher along with the time that
+// the dispatcher dispatched it.
+type Record struct {
+	Event
+	Time time.Time
+}
+
+// SetHistory enables a bounded history of the most recently dispatched events
+// for each event type, keeping at most size events per type. Setting the size
+// to zero disables the history and discards any events that have been
+// recorded. Changing the size of the history also clears recorded events.
+func (d *Dispatcher) SetHistory(size int) {
+	d.hmu.Lock()
+	defer d.hmu.Unlock()
+
+	if size <= 0 {
+		d.hsize = 0
+		d.history = nil
+		return
+	}
+
+	d.hsize = size
+	d.history = make(map[Type]*ring)
+}
+
+// History returns records of up to n of the most recently dispatched events of
+// the specified type in the order they were dispatched. If n is less than or
+// equal to zero, all of the events in the history are returned. If the
+// history is not enabled then nil is returned.
+func (d *Dispatcher) History(etype Type, n int) []Record {
+	d.hmu.Lock()
+	defer d.hmu.Unlock()
+
+	if d.history == nil {
+		return nil
+	}
+
+	r, ok := d.history[etype]
+	if !ok {
+		return []Record{}
+	}
+	return r.last(n)
+}
+
+// Records the event in the history with the current time if it is enabled
+// (thread-safe).
+func (d *Dispatcher) record(e Event) {
+	d.hmu.Lock()
+	defer d.hmu.Unlock()
+
+	if d.history == nil {
+		return
+	}
+
+	r, ok := d.history[e.Type()]
+	if !ok {
+		r = &ring{records: make([]Record, 0, d.hsize)}
+		d.history[e.Type()] = r
+	}
+	r.append(Record{Event: e, Time: time.Now()})
+}
+
+// ring is a fixed size circular buffer of records, overwriting the oldest
+// record when it is full.
+type ring struct {
+	records []Record // the buffer with a capacity of the maximum history size
+	next    int      // the index to write the next record to once full
+}
+
+// Append a record to the ring, overwriting the oldest record if it is full.
+func (r *ring) append(rec Record) {
+	if len(r.records) < cap(r.records) {
+		r.records = append(r.records, rec)
+		return
+	}
+
+	r.records[r.next] = rec
+	r.next = (r.next + 1) % len(r.records)
+}
+
+// Returns the last n records in chronological order.
+func (r *ring) last(n int) []Record {
+	if n <= 0 || n > len(r.records) {
+		n = len(r.records)
+	}
+
+	records := make([]Record, 0, n)
+	for i := len(r.records) - n; i < len(r.records); i++ {
+		records = append(records, r.records[(r.next+i)%len(r.records)])
+	}
+	return records
+}
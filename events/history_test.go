@@ -0,0 +1,88 @@
+package events_test
+
+import (
+	"time"
+
+	. "github.com/bbengfort/x/events"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Event History", func() {
+
+	var FooEvent = Type(42)
+	var BarEvent = Type(64)
+
+	It("should not record history by default", func() {
+		dispatcher := new(Dispatcher)
+		dispatcher.Init(nil)
+
+		dispatcher.Dispatch(FooEvent, 1)
+		Ω(dispatcher.History(FooEvent, 10)).Should(BeNil())
+	})
+
+	It("should record a bounded history per event type", func() {
+		dispatcher := new(Dispatcher)
+		dispatcher.Init("source")
+		dispatcher.SetHistory(3)
+
+		for i := 0; i < 5; i++ {
+			dispatcher.Dispatch(FooEvent, i)
+		}
+		dispatcher.Dispatch(BarEvent, "bar")
+
+		history := dispatcher.History(FooEvent, 0)
+		Ω(history).Should(HaveLen(3))
+		for i, e := range history {
+			Ω(e.Type()).Should(Equal(FooEvent))
+			Ω(e.Source()).Should(Equal("source"))
+			Ω(e.Value()).Should(Equal(i + 2))
+
+			if i > 0 {
+				Ω(e.Time).ShouldNot(BeTemporally("<", history[i-1].Time))
+			}
+		}
+
+		Ω(dispatcher.History(BarEvent, 0)).Should(HaveLen(1))
+		Ω(dispatcher.History(Type(99), 0)).Should(BeEmpty())
+	})
+
+	It("should return the last n events in dispatch order", func() {
+		dispatcher := new(Dispatcher)
+		dispatcher.Init(nil)
+		dispatcher.SetHistory(10)
+
+		before := time.Now()
+		for i := 0; i < 15; i++ {
+			dispatcher.Dispatch(FooEvent, i)
+		}
+		after := time.Now()
+
+		history := dispatcher.History(FooEvent, 2)
+		Ω(history).Should(HaveLen(2))
+		Ω(history[0].Value()).Should(Equal(13))
+		Ω(history[1].Value()).Should(Equal(14))
+
+		// Records have the time the event was dispatched
+		for _, rec := range history {
+			Ω(rec.Time).ShouldNot(BeTemporally("<", before))
+			Ω(rec.Time).ShouldNot(BeTemporally(">", after))
+		}
+
+		Ω(dispatcher.History(FooEvent, 50)).Should(HaveLen(10))
+	})
+
+	It("should clear the history when disabled", func() {
+		dispatcher := new(Dispatcher)
+		dispatcher.Init(nil)
+		dispatcher.SetHistory(10)
+
+		dispatcher.Dispatch(FooEvent, nil)
+		Ω(dispatcher.History(FooEvent, 0)).Should(HaveLen(1))
+
+		dispatcher.SetHistory(0)
+		Ω(dispatcher.History(FooEvent, 0)).Should(BeNil())
+	})
+
+})
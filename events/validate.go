@@ -0,0 +1,85 @@
+package events
+
+import (
+	"fmt"
+	"reflect"
+)
+
+//===========================================================================
+// Payload Validation
+//===========================================================================
+
+// Validator checks the value of an event before it is delivered to callbacks,
+// returning an error if the value does not have the expected shape.
+type Validator func(value interface{}) error
+
+// ValidationError is returned by Dispatch when the value of an event fails
+// the validator registered for its type; callbacks are not called.
+type ValidationError struct {
+	Type  Type        // the type of the event that was dispatched
+	Value interface{} // the invalid value of the event
+	Err   error       // the error returned by the validator
+}
+
+// Error describes the invalid event value.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s event value %T: %s", e.Type, e.Value, e.Err)
+}
+
+// Unwrap returns the error returned by the validator.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Validate registers a validator for the specified event type that checks the
+// value of every dispatched event of that type before it is recorded or
+// delivered to callbacks, so that mismatched payloads are caught at dispatch
+// time rather than deep inside listeners. Only one validator is registered
+// per type; registering a nil validator removes it.
+func (d *Dispatcher) Validate(etype Type, validator Validator) {
+	d.Lock()
+	defer d.Unlock()
+
+	if validator == nil {
+		delete(d.validators, etype)
+		return
+	}
+
+	if d.validators == nil {
+		d.validators = make(map[Type]Validator)
+	}
+	d.validators[etype] = validator
+}
+
+// Internal validation of an event value that is not thread-safe.
+func (d *Dispatcher) validate(etype Type, value interface{}) error {
+	validator, ok := d.validators[etype]
+	if !ok {
+		return nil
+	}
+
+	if err := validator(value); err != nil {
+		return &ValidationError{Type: etype, Value: value, Err: err}
+	}
+	return nil
+}
+
+// OfType returns a validator that requires event values to be of type T,
+// e.g. dispatcher.Validate(VoteEvent, events.OfType[*Vote]()). A nil value
+// is only valid if T is an interface or pointer type.
+func OfType[T any]() Validator {
+	return func(value interface{}) error {
+		if _, ok := value.(T); ok {
+			return nil
+		}
+
+		expected := reflect.TypeOf((*T)(nil)).Elem()
+		if value == nil {
+			switch expected.Kind() {
+			case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func:
+				return nil
+			}
+		}
+		return fmt.Errorf("expected %s value", expected)
+	}
+}
@@ -0,0 +1,75 @@
+package events_test
+
+import (
+	"errors"
+
+	. "github.com/bbengfort/x/events"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Event Validation", func() {
+
+	var FooEvent = Type(42)
+	var BarEvent = Type(64)
+
+	It("should not deliver invalid values to callbacks", func() {
+		dispatcher := new(Dispatcher)
+		dispatcher.Init(nil)
+		dispatcher.SetHistory(10)
+		dispatcher.Validate(FooEvent, OfType[int]())
+
+		calls := 0
+		dispatcher.Register(FooEvent, func(e Event) error {
+			calls++
+			return nil
+		})
+
+		Ω(dispatcher.Dispatch(FooEvent, 42)).Should(Succeed())
+		Ω(calls).Should(Equal(1))
+
+		err := dispatcher.Dispatch(FooEvent, "42")
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(Equal("invalid custom event value string: expected int value"))
+		Ω(calls).Should(Equal(1))
+		Ω(dispatcher.History(FooEvent, 0)).Should(HaveLen(1))
+
+		var verr *ValidationError
+		Ω(errors.As(err, &verr)).Should(BeTrue())
+		Ω(verr.Type).Should(Equal(FooEvent))
+		Ω(verr.Value).Should(Equal("42"))
+
+		// Other event types should not be validated
+		Ω(dispatcher.Dispatch(BarEvent, "bar")).Should(Succeed())
+	})
+
+	It("should return the error of custom validators", func() {
+		dispatcher := new(Dispatcher)
+		dispatcher.Init(nil)
+
+		errNegative := errors.New("value must be positive")
+		dispatcher.Validate(FooEvent, func(value interface{}) error {
+			if n, ok := value.(int); !ok || n < 0 {
+				return errNegative
+			}
+			return nil
+		})
+
+		Ω(dispatcher.Dispatch(FooEvent, 1)).Should(Succeed())
+		Ω(errors.Is(dispatcher.Dispatch(FooEvent, -1), errNegative)).Should(BeTrue())
+
+		// Removing the validator should allow any value
+		dispatcher.Validate(FooEvent, nil)
+		Ω(dispatcher.Dispatch(FooEvent, -1)).Should(Succeed())
+	})
+
+	It("should only allow nil values for nilable types", func() {
+		Ω(OfType[*Dispatcher]()(nil)).Should(Succeed())
+		Ω(OfType[error]()(nil)).Should(Succeed())
+		Ω(OfType[error]()(errors.New("foo"))).Should(Succeed())
+		Ω(OfType[int]()(nil)).ShouldNot(Succeed())
+		Ω(OfType[*Dispatcher]()(new(Dispatcher))).Should(Succeed())
+		Ω(OfType[*Dispatcher]()(Dispatcher{})).ShouldNot(Succeed())
+	})
+})
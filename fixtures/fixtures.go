@@ -0,0 +1,219 @@
+/*
+Package fixtures loads test fixtures from a testdata directory, compares output
+with golden files, and scaffolds temporary directories for tests.
+
+Fixtures are decoded into typed values by their extension (.json, .yaml, or
+.yml) using generics:
+
+	peers := fixtures.Load[[]*peers.Peer](t, "peers.json")
+
+Golden files are compared with the output of a test and are rewritten when the
+tests are run with the -update flag, which the test package defines:
+
+	var _ = flag.Bool("update", false, "update golden files")
+
+	fixtures.Golden(t, "report.txt", []byte(report.String()))
+
+	$ go test ./mypkg -update
+
+Golden files are also rewritten if $FIXTURES_UPDATE is true, e.g. in packages
+that do not define the flag.
+*/
+package fixtures
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bbengfort/x/diff"
+	"gopkg.in/yaml.v2"
+)
+
+// Dir is the directory that fixtures and golden files are loaded from,
+// relative to the package under test.
+var Dir = "testdata"
+
+// The name of the flag and the environment variable that rewrite golden files.
+const (
+	updateFlag = "update"
+	updateEnv  = "FIXTURES_UPDATE"
+)
+
+// Updating returns true if the tests were run with the -update flag or with
+// $FIXTURES_UPDATE set to true, in which case golden files are rewritten rather
+// than compared. The flag is not defined by this package, since defining it when
+// the package is initialized would panic in test packages that define their own
+// -update flag; instead it is looked up when golden files are compared, after
+// the test package has defined it and the flags have been parsed.
+func Updating() bool {
+	if f := flag.Lookup(updateFlag); f != nil && f.Value.String() == "true" {
+		return true
+	}
+
+	update, _ := strconv.ParseBool(os.Getenv(updateEnv))
+	return update
+}
+
+// Path returns the path of the named fixture in the fixtures directory.
+func Path(name string) string {
+	return filepath.Join(Dir, filepath.FromSlash(name))
+}
+
+//===========================================================================
+// Loading Fixtures
+//===========================================================================
+
+// Read returns the contents of the named fixture, failing the test if it cannot
+// be read.
+func Read(t testing.TB, name string) []byte {
+	t.Helper()
+	data, err := ioutil.ReadFile(Path(name))
+	if err != nil {
+		t.Fatalf("could not read fixture %s: %s", name, err)
+	}
+	return data
+}
+
+// Load decodes the named fixture into a value of type T by the extension of the
+// fixture, failing the test if it cannot be read or decoded.
+func Load[T any](t testing.TB, name string) T {
+	t.Helper()
+	val, err := Parse[T](Path(name))
+	if err != nil {
+		t.Fatalf("could not load fixture %s: %s", name, err)
+	}
+	return val
+}
+
+// Parse decodes the JSON or YAML file at path into a value of type T by the
+// extension of the file (.json, .yaml, or .yml).
+func Parse[T any](path string) (val T, err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(path); err != nil {
+		return val, err
+	}
+	return Decode[T](filepath.Ext(path), data)
+}
+
+// Decode the data into a value of type T using the decoder for the extension.
+func Decode[T any](ext string, data []byte) (val T, err error) {
+	switch strings.ToLower(ext) {
+	case ".json":
+		err = json.Unmarshal(data, &val)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &val)
+	default:
+		err = fmt.Errorf("unknown fixture format %q", ext)
+	}
+	return val, err
+}
+
+//===========================================================================
+// Golden Files
+//===========================================================================
+
+// Golden compares the actual output of a test with the contents of the named
+// golden file, failing the test with a unified diff if they differ. If the tests
+// are run with the -update flag, the golden file is written with the actual
+// output instead.
+func Golden(t testing.TB, name string, actual []byte) {
+	t.Helper()
+	path := Path(name)
+
+	if Updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("could not create golden file directory: %s", err)
+		}
+
+		if err := ioutil.WriteFile(path, actual, 0644); err != nil {
+			t.Fatalf("could not update golden file %s: %s", name, err)
+		}
+		return
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read golden file %s (run with -update to create it): %s", name, err)
+	}
+
+	if changes := diff.Unified(expected, actual, name, "actual"); changes != "" {
+		t.Errorf("output does not match golden file %s (run with -update to update it):\n%s", name, changes)
+	}
+}
+
+// GoldenJSON marshals the value as indented JSON and compares it with the named
+// golden file (see Golden).
+func GoldenJSON(t testing.TB, name string, val interface{}) {
+	t.Helper()
+	data, err := json.MarshalIndent(val, "", "  ")
+	if err != nil {
+		t.Fatalf("could not marshal %T: %s", val, err)
+	}
+	Golden(t, name, append(data, '\n'))
+}
+
+//===========================================================================
+// Temporary Directories
+//===========================================================================
+
+// Scaffold creates a temporary directory that is removed when the test ends,
+// containing the files mapped from their slash separated paths relative to the
+// directory to their contents, and returns the path of the directory.
+func Scaffold(t testing.TB, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, contents := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("could not scaffold %s: %s", name, err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatalf("could not scaffold %s: %s", name, err)
+		}
+	}
+	return root
+}
+
+// Copy copies the named fixture (a file or a directory) into a temporary
+// directory that is removed when the test ends and returns the path of the copy,
+// so that tests can modify fixtures without changing the originals.
+func Copy(t testing.TB, name string) string {
+	t.Helper()
+	src := Path(name)
+	dst := filepath.Join(t.TempDir(), filepath.Base(src))
+
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(target, data, info.Mode().Perm())
+	})
+
+	if err != nil {
+		t.Fatalf("could not copy fixture %s: %s", name, err)
+	}
+	return dst
+}
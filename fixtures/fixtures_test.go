@@ -0,0 +1,128 @@
+package fixtures
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test packages define the flag that rewrites golden files.
+var _ = flag.Bool(updateFlag, false, "update golden files with the output of the tests")
+
+type config struct {
+	Name  string   `json:"name" yaml:"name"`
+	Port  int      `json:"port" yaml:"port"`
+	Peers []string `json:"peers" yaml:"peers"`
+}
+
+// Records failures rather than failing the test so that failures can be tested.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+}
+
+// Test that JSON and YAML fixtures are decoded into typed values.
+func TestLoad(t *testing.T) {
+	expected := config{Name: "alpha", Port: 3264, Peers: []string{"bravo", "charlie"}}
+	for _, name := range []string{"config.json", "config.yaml"} {
+		conf := Load[config](t, name)
+		if conf.Name != expected.Name || conf.Port != expected.Port || strings.Join(conf.Peers, ",") != "bravo,charlie" {
+			t.Errorf("unexpected %s fixture: %+v", name, conf)
+		}
+	}
+
+	if ptr := Load[*config](t, "config.json"); ptr == nil || ptr.Port != 3264 {
+		t.Errorf("unexpected pointer fixture: %+v", ptr)
+	}
+
+	if _, err := Parse[config](Path("tree/a.txt")); err == nil {
+		t.Error("expected an error for an unknown fixture format")
+	}
+
+	r := &recorder{TB: t}
+	Load[config](r, "missing.json")
+	if len(r.failures) != 1 {
+		t.Errorf("expected a missing fixture to fail the test: %v", r.failures)
+	}
+}
+
+// Test comparing output with golden files and updating them.
+func TestGolden(t *testing.T) {
+	conf := Load[config](t, "config.json")
+	GoldenJSON(t, "report.golden", conf)
+
+	r := &recorder{TB: t}
+	conf.Port = 3265
+	GoldenJSON(r, "report.golden", conf)
+	if len(r.failures) != 1 || !strings.Contains(r.failures[0], "-  \"port\": 3264,\n+  \"port\": 3265,") {
+		t.Errorf("expected a diff of the golden file: %v", r.failures)
+	}
+
+	// Update golden files in a temporary fixtures directory
+	defer func(dir string) { Dir = dir }(Dir)
+	Dir = t.TempDir()
+
+	if err := flag.Set(updateFlag, "true"); err != nil {
+		t.Fatal(err)
+	}
+	defer flag.Set(updateFlag, "false")
+
+	Golden(t, "nested/output.txt", []byte("updated\n"))
+	if data, err := ioutil.ReadFile(filepath.Join(Dir, "nested", "output.txt")); err != nil || string(data) != "updated\n" {
+		t.Errorf("expected golden file to be updated: %q %v", data, err)
+	}
+
+	// Golden files can also be updated with the environment variable
+	flag.Set(updateFlag, "false")
+	t.Setenv(updateEnv, "1")
+	Golden(t, "nested/output.txt", []byte("updated again\n"))
+	if data, err := ioutil.ReadFile(filepath.Join(Dir, "nested", "output.txt")); err != nil || string(data) != "updated again\n" {
+		t.Errorf("expected golden file to be updated: %q %v", data, err)
+	}
+
+	t.Setenv(updateEnv, "false")
+	if Updating() {
+		t.Error("expected golden files not to be updated")
+	}
+}
+
+// Test scaffolding temporary directories.
+func TestScaffold(t *testing.T) {
+	root := Scaffold(t, map[string]string{"a.txt": "alpha\n", "nested/b.txt": "bravo\n"})
+	if data, err := ioutil.ReadFile(filepath.Join(root, "nested", "b.txt")); err != nil || string(data) != "bravo\n" {
+		t.Errorf("unexpected scaffolded file: %q %v", data, err)
+	}
+
+	dir := Copy(t, "tree")
+	if filepath.Base(dir) != "tree" {
+		t.Errorf("unexpected copy path %s", dir)
+	}
+
+	for name, contents := range map[string]string{"a.txt": "alpha\n", "nested/b.txt": "bravo\n"} {
+		if data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name))); err != nil || string(data) != contents {
+			t.Errorf("unexpected copied file %s: %q %v", name, data, err)
+		}
+	}
+
+	// Modifying the copy does not modify the fixture
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(Read(t, "tree/a.txt")) != "alpha\n" {
+		t.Error("expected the fixture not to be modified")
+	}
+}
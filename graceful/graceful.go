@@ -0,0 +1,255 @@
+/*
+Package graceful orchestrates the shutdown of the subsystems of a process.
+
+Subsystems register shutdown functions with the Manager as they are started,
+each with its own timeout. When the process receives a signal or a subsystem
+reports a fatal error, the shutdown functions are run one at a time in the
+reverse order that they were registered (like defer), so that a subsystem is
+always shut down before the subsystems that it depends on, which must be
+started (and registered) first. Progress is logged with a console logger and
+the pid file of the process is freed once all subsystems are shut down:
+
+	m := graceful.New()
+	m.PID = pid.New(pid.Path("myapp.pid"))
+
+	db := openDatabase()
+	m.Register("database", 5*time.Second, db.Shutdown)
+
+	srv := startServer(db)
+	m.Register("server", 30*time.Second, srv.Shutdown)
+
+	if err := m.Wait(); err != nil {
+		os.Exit(1)
+	}
+
+Progress is logged with the "graceful" named console logger unless another
+logger is set, so the console package does not have to be initialized first.
+*/
+package graceful
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bbengfort/x/console"
+	"github.com/bbengfort/x/pid"
+)
+
+// DefaultTimeout is used for shutdown functions registered without a timeout.
+const DefaultTimeout = 10 * time.Second
+
+// Func shuts down a subsystem. The context is canceled when the timeout of the
+// subsystem expires; the function should return as soon as possible when it is.
+type Func func(ctx context.Context) error
+
+// Manager runs the registered shutdown functions exactly once when the process
+// is signaled, a fatal error is reported, or Shutdown is called. The zero value
+// is not ready to use, create a manager with New. Managers are thread-safe.
+type Manager struct {
+	sync.Mutex
+	PID      *pid.PID        // the pid file to free after shutdown (optional)
+	Logger   *console.Logger // the logger to report progress with (optional)
+	hooks    []hook
+	fatal    chan error
+	once     sync.Once
+	done     chan struct{}
+	err      error
+	shutdown bool
+}
+
+// A registered shutdown function.
+type hook struct {
+	name    string
+	timeout time.Duration
+	fn      Func
+}
+
+// New creates a manager without any registered subsystems.
+func New() *Manager {
+	return &Manager{
+		hooks: make([]hook, 0),
+		fatal: make(chan error, 1),
+		done:  make(chan struct{}),
+	}
+}
+
+// Register a shutdown function for the named subsystem. Subsystems should be
+// registered after the subsystems they depend on since they are shut down in
+// the reverse order of registration. If the timeout is zero, DefaultTimeout is
+// used. An error is returned if shutdown has already started.
+func (m *Manager) Register(name string, timeout time.Duration, fn Func) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.shutdown {
+		return fmt.Errorf("cannot register %q: shutdown already started", name)
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	m.hooks = append(m.hooks, hook{name: name, timeout: timeout, fn: fn})
+	return nil
+}
+
+// Fatal reports an unrecoverable error from a subsystem, causing Wait to shut
+// down the process. Only the first fatal error is kept.
+func (m *Manager) Fatal(err error) {
+	if err == nil {
+		return
+	}
+
+	select {
+	case m.fatal <- err:
+	default:
+	}
+}
+
+// Wait blocks until the process receives one of the signals (SIGINT and SIGTERM
+// if none are specified) or a fatal error is reported, then shuts down all
+// subsystems. The returned error contains the fatal error, if any, along with
+// any errors returned by the shutdown functions.
+func (m *Manager) Wait(signals ...os.Signal) error {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, signals...)
+	defer signal.Stop(quit)
+
+	var errs Errors
+	select {
+	case sig := <-quit:
+		m.log().Info("received %s, shutting down", sig)
+	case err := <-m.fatal:
+		m.log().Errore(err)
+		errs = append(errs, err)
+	case <-m.done:
+		// Shutdown was called directly
+	}
+
+	if err := m.Shutdown(context.Background()); err != nil {
+		var serrs Errors
+		if errors.As(err, &serrs) {
+			errs = append(errs, serrs...)
+		} else {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Shutdown runs the shutdown functions in the reverse order of registration,
+// waiting at most the timeout of each subsystem, then frees the pid file.
+// Errors and timeouts are logged and do not prevent the remaining subsystems
+// from being shut down. If the context is canceled, the remaining subsystems
+// are skipped. Shutdown only runs once; subsequent calls wait for the first to
+// complete and return the same error.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.once.Do(func() {
+		defer close(m.done)
+
+		m.Lock()
+		m.shutdown = true
+		hooks := m.hooks
+		m.Unlock()
+
+		logger := m.log()
+		var errs Errors
+		for i := len(hooks) - 1; i >= 0; i-- {
+			if err := ctx.Err(); err != nil {
+				errs = append(errs, fmt.Errorf("shutdown aborted before %s: %w", hooks[i].name, err))
+				break
+			}
+
+			if err := hooks[i].run(ctx, logger); err != nil {
+				logger.Warne(err)
+				errs = append(errs, err)
+			}
+		}
+
+		if m.PID != nil {
+			if err := m.PID.Free(); err != nil {
+				err = fmt.Errorf("could not free pid file: %w", err)
+				logger.Warne(err)
+				errs = append(errs, err)
+			}
+		}
+
+		if len(errs) > 0 {
+			m.err = errs
+			logger.Status("shutdown complete with %d errors", len(errs))
+			return
+		}
+		logger.Status("shutdown complete")
+	})
+
+	<-m.done
+	return m.err
+}
+
+// Done returns a channel that is closed when shutdown is complete.
+func (m *Manager) Done() <-chan struct{} {
+	return m.done
+}
+
+// Returns the logger of the manager or the graceful named logger if it has none.
+func (m *Manager) log() *console.Logger {
+	m.Lock()
+	defer m.Unlock()
+	if m.Logger != nil {
+		return m.Logger
+	}
+	return console.Named("graceful")
+}
+
+// Runs the shutdown function, returning an error if it fails or times out.
+func (h hook) run(parent context.Context, logger *console.Logger) error {
+	logger.Info("shutting down %s", h.name)
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(parent, h.timeout)
+	defer cancel()
+
+	// Buffered so the function can complete after it has timed out
+	errc := make(chan error, 1)
+	go func() {
+		errc <- h.fn(ctx)
+	}()
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			return console.Wrap(err, "could not shut down %s", h.name)
+		}
+		logger.Debug("shut down %s in %s", h.name, time.Since(start))
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("could not shut down %s: timed out after %s", h.name, h.timeout)
+	}
+}
+
+// Errors collects the errors that occurred during shutdown.
+type Errors []error
+
+// Error returns the messages of all of the errors.
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
@@ -0,0 +1,158 @@
+package graceful_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/bbengfort/x/console"
+	"github.com/bbengfort/x/graceful"
+	"github.com/bbengfort/x/pid"
+	. "github.com/onsi/gomega"
+)
+
+func init() {
+	// The console is left uninitialized when the test binary is run by
+	// TestUninitializedConsole to check that progress can still be logged.
+	if os.Getenv("GRACEFUL_UNINITIALIZED_CONSOLE") == "" {
+		console.Init("", log.LstdFlags)
+	}
+	console.SetLogLevel(console.LevelSilent)
+}
+
+func TestShutdownOrder(t *testing.T) {
+	RegisterTestingT(t)
+
+	dir, err := ioutil.TempDir("", "graceful")
+	Ω(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(dir)
+
+	m := graceful.New()
+	m.PID = pid.New(filepath.Join(dir, "test.pid"))
+	Ω(m.PID.Save()).Should(Succeed())
+
+	order := make([]string, 0, 3)
+	for _, name := range []string{"database", "cache", "server"} {
+		name := name
+		Ω(m.Register(name, time.Second, func(ctx context.Context) error {
+			order = append(order, name)
+			return nil
+		})).Should(Succeed())
+	}
+
+	Ω(m.Shutdown(context.Background())).Should(Succeed())
+	Ω(order).Should(Equal([]string{"server", "cache", "database"}))
+	Ω(m.Done()).Should(BeClosed())
+	Ω(filepath.Join(dir, "test.pid")).ShouldNot(BeAnExistingFile())
+
+	// Shutdown only runs once and no more subsystems can be registered
+	Ω(m.Shutdown(context.Background())).Should(Succeed())
+	Ω(order).Should(HaveLen(3))
+	Ω(m.Register("late", 0, nil)).ShouldNot(Succeed())
+}
+
+func TestShutdownErrors(t *testing.T) {
+	RegisterTestingT(t)
+
+	var ran bool
+	m := graceful.New()
+	m.Register("first", time.Second, func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+	m.Register("slow", 10*time.Millisecond, func(ctx context.Context) error {
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	})
+	m.Register("broken", time.Second, func(ctx context.Context) error {
+		return errors.New("connection reset")
+	})
+
+	err := m.Shutdown(context.Background())
+	Ω(err).Should(HaveOccurred())
+	Ω(err.Error()).Should(Equal("could not shut down broken: connection reset; could not shut down slow: timed out after 10ms"))
+	Ω(ran).Should(BeTrue())
+
+	var errs graceful.Errors
+	Ω(errors.As(err, &errs)).Should(BeTrue())
+	Ω(errs).Should(HaveLen(2))
+}
+
+func TestWaitFatal(t *testing.T) {
+	RegisterTestingT(t)
+
+	var ran bool
+	m := graceful.New()
+	m.Register("server", time.Second, func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+
+	fatal := errors.New("disk full")
+	go m.Fatal(fatal)
+
+	err := m.Wait()
+	Ω(err).Should(Equal(graceful.Errors{fatal}))
+	Ω(ran).Should(BeTrue())
+}
+
+func TestWaitSignal(t *testing.T) {
+	RegisterTestingT(t)
+
+	var ran bool
+	m := graceful.New()
+	m.Register("server", time.Second, func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	}()
+
+	Ω(m.Wait(syscall.SIGUSR1)).Should(Succeed())
+	Ω(ran).Should(BeTrue())
+}
+
+func TestLogger(t *testing.T) {
+	RegisterTestingT(t)
+
+	m := graceful.New()
+	m.Logger = console.Named("shutdown")
+	m.Register("broken", time.Second, func(ctx context.Context) error {
+		return errors.New("connection reset")
+	})
+
+	Ω(m.Shutdown(context.Background())).Should(MatchError("could not shut down broken: connection reset"))
+}
+
+func TestUninitializedConsole(t *testing.T) {
+	RegisterTestingT(t)
+
+	if os.Getenv("GRACEFUL_UNINITIALIZED_CONSOLE") != "" {
+		console.SetLogLevel(console.LevelTrace)
+		m := graceful.New()
+		m.Register("broken", time.Second, func(ctx context.Context) error {
+			return errors.New("connection reset")
+		})
+		go m.Fatal(errors.New("disk full"))
+		m.Wait()
+		return
+	}
+
+	// Run this test in a new process in which console.Init is never called
+	cmd := exec.Command(os.Args[0], "-test.run", "^TestUninitializedConsole$")
+	cmd.Env = append(os.Environ(), "GRACEFUL_UNINITIALIZED_CONSOLE=1")
+	out, err := cmd.CombinedOutput()
+	Ω(err).ShouldNot(HaveOccurred(), string(out))
+	Ω(string(out)).Should(ContainSubstring("[graceful] "))
+	Ω(string(out)).Should(ContainSubstring("shutdown complete with 1 errors"))
+}
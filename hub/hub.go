@@ -0,0 +1,275 @@
+/*
+Package hub implements a backpressure-aware publish/subscribe broadcast hub.
+
+A Hub fans messages published by one or more producers out to many
+subscribers, each of which receives messages on its own buffered channel.
+Because a single slow subscriber should not be able to block the producer or
+the other subscribers, publishing never blocks. Instead, when a subscriber's
+buffer is full, the subscriber's policy determines what happens: the new
+message can be dropped, the oldest buffered message can be evicted to make
+room for the new message, or the subscriber can be disconnected.
+
+This is useful for fanning server state updates out to websocket or SSE
+clients, where some clients may be on slow connections:
+
+	h := hub.New()
+	sub := h.Subscribe(16, hub.DropOldest)
+	defer sub.Unsubscribe()
+
+	go func() {
+		for msg := range sub.C() {
+			// send msg to the client
+		}
+	}()
+
+	h.Publish(update)
+*/
+package hub
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Policy determines how a slow subscriber with a full buffer is handled.
+type Policy uint8
+
+// Slow subscriber policies
+const (
+	DropNewest Policy = iota // drop the published message for the subscriber
+	DropOldest               // evict the oldest buffered message to make room
+	Disconnect               // unsubscribe and close the subscriber channel
+)
+
+// Names of the slow subscriber policies
+var policyStrings = [...]string{
+	"drop newest", "drop oldest", "disconnect",
+}
+
+// String returns the name of the policy.
+func (p Policy) String() string {
+	if int(p) < len(policyStrings) {
+		return policyStrings[p]
+	}
+	return "unknown"
+}
+
+//===========================================================================
+// Hub
+//===========================================================================
+
+// Hub broadcasts published messages to all of its subscribers. The zero value
+// is not ready to use, create a hub with New. Hubs are thread-safe.
+type Hub struct {
+	sync.RWMutex
+	subscribers  map[uint64]*Subscription
+	nextID       uint64
+	closed       bool
+	published    uint64 // number of messages published
+	delivered    uint64 // number of messages delivered to subscriber buffers
+	dropped      uint64 // number of messages dropped for slow subscribers
+	disconnected uint64 // number of slow subscribers disconnected
+}
+
+// New creates a hub without any subscribers.
+func New() *Hub {
+	return &Hub{subscribers: make(map[uint64]*Subscription)}
+}
+
+// Subscribe to the messages published to the hub. Messages are buffered in
+// the subscription channel up to the specified buffer size; when the buffer
+// is full the policy determines how the slow subscriber is handled. A negative
+// buffer is treated as zero (unbuffered), so messages are only delivered if the
+// subscriber is receiving when they are published. If the hub is closed, the
+// returned subscription channel is already closed.
+func (h *Hub) Subscribe(buffer int, policy Policy) *Subscription {
+	if buffer < 0 {
+		buffer = 0
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	h.nextID++
+	sub := &Subscription{
+		id:     h.nextID,
+		hub:    h,
+		policy: policy,
+		msgs:   make(chan interface{}, buffer),
+	}
+
+	if h.closed {
+		sub.closed = true
+		close(sub.msgs)
+		return sub
+	}
+
+	h.subscribers[sub.id] = sub
+	return sub
+}
+
+// Publish a message to all subscribers without blocking. Returns the number of
+// subscribers whose buffer the message was delivered to.
+func (h *Hub) Publish(msg interface{}) (delivered int) {
+	slow := make([]*Subscription, 0)
+
+	h.RLock()
+	if h.closed {
+		h.RUnlock()
+		return 0
+	}
+
+	atomic.AddUint64(&h.published, 1)
+	for _, sub := range h.subscribers {
+		if sub.send(msg) {
+			delivered++
+			continue
+		}
+
+		atomic.AddUint64(&h.dropped, 1)
+		atomic.AddUint64(&sub.dropped, 1)
+		if sub.policy == Disconnect {
+			slow = append(slow, sub)
+		}
+	}
+	h.RUnlock()
+
+	atomic.AddUint64(&h.delivered, uint64(delivered))
+
+	// Disconnect slow subscribers once the read lock is released
+	if len(slow) > 0 {
+		h.Lock()
+		for _, sub := range slow {
+			if h.remove(sub) {
+				h.disconnected++
+			}
+		}
+		h.Unlock()
+	}
+	return delivered
+}
+
+// Len returns the number of subscribers connected to the hub.
+func (h *Hub) Len() int {
+	h.RLock()
+	defer h.RUnlock()
+	return len(h.subscribers)
+}
+
+// Close the hub, closing all subscriber channels. Messages published after the
+// hub is closed are ignored. Close can be called more than once.
+func (h *Hub) Close() {
+	h.Lock()
+	defer h.Unlock()
+
+	h.closed = true
+	for _, sub := range h.subscribers {
+		h.remove(sub)
+	}
+}
+
+// Metrics returns a snapshot of the hub's delivery metrics.
+func (h *Hub) Metrics() Metrics {
+	h.RLock()
+	defer h.RUnlock()
+
+	return Metrics{
+		Subscribers:  len(h.subscribers),
+		Published:    atomic.LoadUint64(&h.published),
+		Delivered:    atomic.LoadUint64(&h.delivered),
+		Dropped:      atomic.LoadUint64(&h.dropped),
+		Disconnected: h.disconnected,
+	}
+}
+
+// Removes the subscriber and closes its channel (not thread-safe). Returns
+// false if the subscriber had already been removed.
+func (h *Hub) remove(sub *Subscription) bool {
+	if sub.closed {
+		return false
+	}
+
+	delete(h.subscribers, sub.id)
+	sub.closed = true
+	close(sub.msgs)
+	return true
+}
+
+// Metrics describe the delivery of messages by a hub.
+type Metrics struct {
+	Subscribers  int    `json:"subscribers"`  // number of connected subscribers
+	Published    uint64 `json:"published"`    // number of messages published
+	Delivered    uint64 `json:"delivered"`    // number of messages delivered to subscribers
+	Dropped      uint64 `json:"dropped"`      // number of messages dropped for slow subscribers
+	Disconnected uint64 `json:"disconnected"` // number of slow subscribers disconnected
+}
+
+//===========================================================================
+// Subscription
+//===========================================================================
+
+// Subscription receives messages published to a hub on its channel.
+type Subscription struct {
+	id      uint64
+	hub     *Hub
+	policy  Policy
+	msgs    chan interface{}
+	closed  bool   // guarded by the hub lock
+	dropped uint64 // number of messages dropped for this subscriber
+}
+
+// C returns the channel that published messages are received on. The channel
+// is closed when the subscriber unsubscribes, is disconnected for being too
+// slow, or when the hub is closed.
+func (s *Subscription) C() <-chan interface{} {
+	return s.msgs
+}
+
+// Policy returns the slow subscriber policy of the subscription.
+func (s *Subscription) Policy() Policy {
+	return s.policy
+}
+
+// Dropped returns the number of messages dropped for this subscriber.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Unsubscribe from the hub, closing the subscription channel. Unsubscribe can
+// be called more than once.
+func (s *Subscription) Unsubscribe() {
+	s.hub.Lock()
+	defer s.hub.Unlock()
+	s.hub.remove(s)
+}
+
+// Sends the message to the subscriber without blocking, applying the policy
+// if the buffer is full. Returns true if the message was buffered. Must be
+// called with the hub read lock held so the channel is not closed.
+func (s *Subscription) send(msg interface{}) bool {
+	select {
+	case s.msgs <- msg:
+		return true
+	default:
+	}
+
+	if s.policy != DropOldest || cap(s.msgs) == 0 {
+		return false
+	}
+
+	// Evict the oldest message then try again; another publisher may have
+	// filled the buffer in the meantime so the message may still be dropped.
+	select {
+	case <-s.msgs:
+		atomic.AddUint64(&s.dropped, 1)
+		atomic.AddUint64(&s.hub.dropped, 1)
+	default:
+	}
+
+	select {
+	case s.msgs <- msg:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,171 @@
+package hub_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/bbengfort/x/hub"
+	. "github.com/onsi/gomega"
+)
+
+func TestBroadcast(t *testing.T) {
+	RegisterTestingT(t)
+
+	h := hub.New()
+	subs := make([]*hub.Subscription, 0, 3)
+	for i := 0; i < 3; i++ {
+		subs = append(subs, h.Subscribe(4, hub.DropNewest))
+	}
+	Ω(h.Len()).Should(Equal(3))
+
+	Ω(h.Publish("hello")).Should(Equal(3))
+	for _, sub := range subs {
+		Ω(sub.C()).Should(Receive(Equal("hello")))
+	}
+
+	// Unsubscribed subscribers should not receive messages
+	subs[0].Unsubscribe()
+	subs[0].Unsubscribe()
+	Ω(subs[0].C()).Should(BeClosed())
+	Ω(h.Publish("world")).Should(Equal(2))
+
+	metrics := h.Metrics()
+	Ω(metrics.Subscribers).Should(Equal(2))
+	Ω(metrics.Published).Should(Equal(uint64(2)))
+	Ω(metrics.Delivered).Should(Equal(uint64(5)))
+	Ω(metrics.Dropped).Should(BeZero())
+}
+
+func TestDropNewest(t *testing.T) {
+	RegisterTestingT(t)
+
+	h := hub.New()
+	slow := h.Subscribe(2, hub.DropNewest)
+	fast := h.Subscribe(8, hub.DropNewest)
+
+	for i := 0; i < 4; i++ {
+		h.Publish(i)
+	}
+
+	// The slow subscriber should only have the first messages
+	Ω(slow.C()).Should(Receive(Equal(0)))
+	Ω(slow.C()).Should(Receive(Equal(1)))
+	Ω(slow.C()).ShouldNot(Receive())
+	Ω(slow.Dropped()).Should(Equal(uint64(2)))
+
+	// The fast subscriber should not be affected
+	Ω(fast.C()).Should(HaveLen(4))
+	Ω(fast.Dropped()).Should(BeZero())
+	Ω(h.Metrics().Dropped).Should(Equal(uint64(2)))
+}
+
+func TestNegativeBuffer(t *testing.T) {
+	RegisterTestingT(t)
+
+	// A negative buffer should be unbuffered rather than panicking
+	h := hub.New()
+	var sub *hub.Subscription
+	Ω(func() { sub = h.Subscribe(-1, hub.DropNewest) }).ShouldNot(Panic())
+	Ω(cap(sub.C())).Should(BeZero())
+
+	Ω(h.Publish("dropped")).Should(BeZero())
+	Ω(sub.Dropped()).Should(Equal(uint64(1)))
+}
+
+func TestDropOldest(t *testing.T) {
+	RegisterTestingT(t)
+
+	h := hub.New()
+	slow := h.Subscribe(2, hub.DropOldest)
+
+	for i := 0; i < 4; i++ {
+		Ω(h.Publish(i)).Should(Equal(1))
+	}
+
+	// The slow subscriber should only have the latest messages
+	Ω(slow.C()).Should(Receive(Equal(2)))
+	Ω(slow.C()).Should(Receive(Equal(3)))
+	Ω(slow.Dropped()).Should(Equal(uint64(2)))
+
+	// Unbuffered subscribers cannot evict messages
+	unbuffered := h.Subscribe(0, hub.DropOldest)
+	h.Publish(4)
+	Ω(unbuffered.Dropped()).Should(Equal(uint64(1)))
+}
+
+func TestDisconnect(t *testing.T) {
+	RegisterTestingT(t)
+
+	h := hub.New()
+	slow := h.Subscribe(1, hub.Disconnect)
+	fast := h.Subscribe(4, hub.Disconnect)
+
+	Ω(h.Publish(1)).Should(Equal(2))
+	Ω(h.Publish(2)).Should(Equal(1))
+	Ω(h.Len()).Should(Equal(1))
+
+	// Buffered messages can still be read before the channel is closed
+	Ω(slow.C()).Should(Receive(Equal(1)))
+	Ω(slow.C()).Should(BeClosed())
+	Ω(fast.C()).Should(HaveLen(2))
+
+	// Unsubscribing a disconnected subscriber should not panic
+	slow.Unsubscribe()
+	Ω(h.Metrics().Disconnected).Should(Equal(uint64(1)))
+}
+
+func TestClose(t *testing.T) {
+	RegisterTestingT(t)
+
+	h := hub.New()
+	sub := h.Subscribe(1, hub.DropNewest)
+	h.Close()
+	h.Close()
+
+	Ω(sub.C()).Should(BeClosed())
+	Ω(h.Len()).Should(BeZero())
+	Ω(h.Publish("ignored")).Should(BeZero())
+	Ω(h.Subscribe(1, hub.DropNewest).C()).Should(BeClosed())
+}
+
+func TestConcurrency(t *testing.T) {
+	RegisterTestingT(t)
+
+	h := hub.New()
+	var wg sync.WaitGroup
+
+	// Concurrent publishers, subscribers, and slow subscribers should not race
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				h.Publish(j)
+			}
+		}()
+
+		go func(policy hub.Policy) {
+			defer wg.Done()
+			sub := h.Subscribe(8, policy)
+			for j := 0; j < 100; j++ {
+				select {
+				case <-sub.C():
+				default:
+				}
+			}
+			sub.Unsubscribe()
+		}(hub.Policy(i % 3))
+	}
+
+	wg.Wait()
+	Ω(h.Len()).Should(BeZero())
+	Ω(h.Metrics().Published).Should(Equal(uint64(4000)))
+}
+
+func TestPolicyString(t *testing.T) {
+	RegisterTestingT(t)
+	Ω(hub.DropNewest.String()).Should(Equal("drop newest"))
+	Ω(hub.DropOldest.String()).Should(Equal("drop oldest"))
+	Ω(hub.Disconnect.String()).Should(Equal("disconnect"))
+	Ω(hub.Policy(42).String()).Should(Equal("unknown"))
+}
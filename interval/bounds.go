@@ -0,0 +1,72 @@
+package interval
+
+import (
+	"time"
+
+	"github.com/bbengfort/x/events"
+)
+
+// Reasons that a bounded interval completes, the Reason of the events.Complete
+// value of the events.CompleteEvent dispatched by the interval.
+const (
+	CompletedLimit    = "limit"
+	CompletedDeadline = "deadline"
+)
+
+//===========================================================================
+// Bounded Intervals
+//===========================================================================
+
+// Limit the interval to fire at most n times after it is started, e.g. for a
+// heartbeat that is retried a bounded number of times. When the interval has
+// fired n times it stops on its own and dispatches an events.CompleteEvent
+// (with the reason CompletedLimit) to the callbacks registered for that type
+// on the interval's dispatcher. The count is reset when the interval is
+// started; a limit of zero or less removes the limit.
+func (t *FixedInterval) Limit(n int) {
+	t.Lock()
+	defer t.Unlock()
+
+	if n < 0 {
+		n = 0
+	}
+	t.limit = uint64(n)
+}
+
+// Until bounds the interval so that it does not fire after the deadline. If the
+// next fire would be scheduled after the deadline, the interval stops on its
+// own and dispatches an events.CompleteEvent with the reason CompletedDeadline
+// instead. An interval cannot be started after its deadline; a zero time
+// removes the deadline.
+func (t *FixedInterval) Until(deadline time.Time) {
+	t.Lock()
+	defer t.Unlock()
+	t.deadline = deadline
+}
+
+// Fires returns the number of times the interval has fired since it was started.
+func (t *FixedInterval) Fires() uint64 {
+	t.RLock()
+	defer t.RUnlock()
+	return t.fires
+}
+
+// returns true if the interval has fired the limited number of times (not
+// thread-safe).
+func (t *FixedInterval) exhausted() bool {
+	return t.limit > 0 && t.fires >= t.limit
+}
+
+// returns true if an event dispatched after the delay would be after the
+// deadline (not thread-safe).
+func (t *FixedInterval) expired(delay time.Duration) bool {
+	return !t.deadline.IsZero() && time.Now().Add(delay).After(t.deadline)
+}
+
+// dispatches the complete event, reporting any errors of the callbacks. Must be
+// called without the lock held so that callbacks can restart the interval.
+func (t *FixedInterval) complete(reason string, fires uint64) {
+	if err := t.Dispatcher.Dispatch(events.CompleteEvent, &events.Complete{Reason: reason, Count: fires}); err != nil {
+		t.report(err)
+	}
+}
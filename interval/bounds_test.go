@@ -0,0 +1,103 @@
+package interval_test
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/bbengfort/x/events"
+	. "github.com/bbengfort/x/interval"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bounded Intervals", func() {
+
+	var calls int64
+	var echan chan error
+	var completed chan *events.Complete
+
+	delay := 5 * time.Millisecond
+
+	// Creates a fixed interval that counts its fires and reports completion
+	bounded := func() *FixedInterval {
+		ticker := NewFixedInterval(delay, events.TimeoutEvent, echan)
+		ticker.Register(func(e events.Event) error {
+			atomic.AddInt64(&calls, 1)
+			return nil
+		})
+		ticker.Dispatcher.Register(events.CompleteEvent, func(e events.Event) error {
+			completed <- e.Value().(*events.Complete)
+			return nil
+		})
+		return ticker
+	}
+
+	BeforeEach(func() {
+		calls = 0
+		echan = make(chan error, 10)
+		completed = make(chan *events.Complete, 10)
+	})
+
+	It("should stop after firing the limited number of times", func() {
+		ticker := bounded()
+		ticker.Limit(3)
+		Ω(ticker.Start()).Should(BeTrue())
+
+		var complete *events.Complete
+		Eventually(completed).Should(Receive(&complete))
+		Ω(complete.Reason).Should(Equal(CompletedLimit))
+		Ω(complete.Count).Should(Equal(uint64(3)))
+		Ω(ticker.Fires()).Should(Equal(uint64(3)))
+		Ω(ticker.Running()).Should(BeFalse())
+
+		Consistently(func() int64 { return atomic.LoadInt64(&calls) }, 4*delay).Should(Equal(int64(3)))
+		Ω(echan).ShouldNot(Receive())
+	})
+
+	It("should reset the count when restarted", func() {
+		ticker := bounded()
+		ticker.Limit(1)
+
+		Ω(ticker.Start()).Should(BeTrue())
+		Eventually(completed).Should(Receive())
+
+		Ω(ticker.Start()).Should(BeTrue())
+		Eventually(completed).Should(Receive())
+		Ω(atomic.LoadInt64(&calls)).Should(Equal(int64(2)))
+	})
+
+	It("should stop before firing after the deadline", func() {
+		ticker := bounded()
+		ticker.Until(time.Now().Add(18 * time.Millisecond))
+		Ω(ticker.Start()).Should(BeTrue())
+
+		var complete *events.Complete
+		Eventually(completed).Should(Receive(&complete))
+		Ω(complete.Reason).Should(Equal(CompletedDeadline))
+		Ω(complete.Count).Should(BeNumerically(">=", 1))
+		Ω(complete.Count).Should(BeNumerically("<=", 3))
+		Ω(ticker.Running()).Should(BeFalse())
+	})
+
+	It("should not start after the deadline", func() {
+		ticker := bounded()
+		ticker.Until(time.Now().Add(-time.Second))
+		Ω(ticker.Start()).Should(BeFalse())
+		Ω(ticker.Running()).Should(BeFalse())
+	})
+
+	It("should remove the limit and deadline", func() {
+		ticker := bounded()
+		ticker.Limit(1)
+		ticker.Until(time.Now())
+		ticker.Limit(0)
+		ticker.Until(time.Time{})
+
+		Ω(ticker.Start()).Should(BeTrue())
+		Eventually(func() int64 { return atomic.LoadInt64(&calls) }).Should(BeNumerically(">=", 3))
+		Ω(completed).ShouldNot(Receive())
+		ticker.Stop()
+	})
+
+})
@@ -0,0 +1,32 @@
+package interval
+
+import (
+	"time"
+
+	"github.com/bbengfort/x/events"
+)
+
+//===========================================================================
+// Election Timeouts
+//===========================================================================
+
+// NewElectionTimeout creates a random interval for the election timeout of a
+// Raft replica that dispatches an events.ElectionTimeoutEvent after a delay
+// chosen uniformly in [base, 2*base). A follower interrupts the timeout every
+// time it hears from the leader, which reschedules it with a new random delay
+// so that replicas rarely time out at the same time and split the vote; if the
+// timeout fires the follower becomes a candidate and starts an election:
+//
+//	timeout := interval.NewElectionTimeout(150 * time.Millisecond)
+//	timeout.Register(replica.campaign)
+//	timeout.Start()
+//
+//	// on every AppendEntries from the leader
+//	timeout.Interrupt()
+//
+// The timeout has no error channel; errors returned by the callbacks are
+// dispatched as an events.ErrorEvent on the interval's dispatcher instead. The
+// base must be positive.
+func NewElectionTimeout(base time.Duration) *RandomInterval {
+	return NewRandomInterval(base, 2*base, events.ElectionTimeoutEvent, nil)
+}
@@ -0,0 +1,96 @@
+package interval_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bbengfort/x/events"
+	. "github.com/bbengfort/x/interval"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Election Timeouts", func() {
+
+	base := 5 * time.Millisecond
+
+	It("should return delays uniformly in [base, 2*base)", func() {
+		timeout := NewElectionTimeout(base)
+
+		var lower, upper int
+		for i := 0; i < 1000; i++ {
+			delay := timeout.GetDelay()
+			Ω(delay).Should(BeNumerically(">=", base))
+			Ω(delay).Should(BeNumerically("<", 2*base))
+
+			if delay < base+base/2 {
+				lower++
+			} else {
+				upper++
+			}
+		}
+
+		Ω(lower).Should(BeNumerically("~", 500, 100))
+		Ω(upper).Should(BeNumerically("~", 500, 100))
+	})
+
+	It("should dispatch an election timeout event", func() {
+		timeout := NewElectionTimeout(base)
+		fired := make(chan events.Event, 10)
+		timeout.Register(func(e events.Event) error {
+			fired <- e
+			return nil
+		})
+
+		Ω(timeout.Start()).Should(BeTrue())
+		defer timeout.Stop()
+
+		var e events.Event
+		Eventually(fired).Should(Receive(&e))
+		Ω(e.Type()).Should(Equal(events.ElectionTimeoutEvent))
+		Ω(e.Type().String()).Should(Equal("election timeout"))
+	})
+
+	It("should not fire while it is interrupted", func() {
+		timeout := NewElectionTimeout(base)
+		fired := make(chan events.Event, 10)
+		timeout.Register(func(e events.Event) error {
+			fired <- e
+			return nil
+		})
+
+		Ω(timeout.Start()).Should(BeTrue())
+		defer timeout.Stop()
+
+		// Heartbeats from the leader arrive faster than the timeout
+		for i := 0; i < 10; i++ {
+			time.Sleep(base / 2)
+			Ω(timeout.Interrupt()).Should(BeTrue())
+		}
+		Ω(fired).ShouldNot(Receive())
+
+		Eventually(fired).Should(Receive())
+	})
+
+	It("should dispatch callback errors as error events", func() {
+		timeout := NewElectionTimeout(base)
+		timeout.Register(func(e events.Event) error {
+			return errors.New("could not campaign")
+		})
+
+		reported := make(chan *events.Error, 10)
+		timeout.Dispatcher.Register(events.ErrorEvent, func(e events.Event) error {
+			reported <- e.Value().(*events.Error)
+			return nil
+		})
+
+		Ω(timeout.Start()).Should(BeTrue())
+
+		var err *events.Error
+		Eventually(reported).Should(Receive(&err))
+		Ω(err.Error()).Should(Equal("election timeout: could not campaign"))
+		Ω(timeout.Running()).Should(BeFalse())
+	})
+
+})
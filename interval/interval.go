@@ -4,7 +4,9 @@ Package interval provides an interface for time based event dispatchers.
 package interval
 
 import (
+	"context"
 	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/bbengfort/x/events"
@@ -24,16 +26,19 @@ import (
 //
 // Interval objects can be started and stopped. On start, the interval
 // schedules the next event after the delay returned by GetDelay(). On stop
-// no events will be dispatched by the handler. Intervals can be
-// interrupted which resets the timer to a new delay. Timer state (running or
-// not running) can be determined by the Running() method.
+// no events will be dispatched by the handler, though callbacks of an event
+// that is being dispatched may still be executing; use StopWait to also wait
+// for in-flight callbacks to complete. Intervals can be interrupted which
+// resets the timer to a new delay. Timer state (running or not running) can
+// be determined by the Running() method.
 type Interval interface {
-	Start() bool                       // start the interval to periodically call its function
-	Stop() bool                        // stop the interval, the function will not be called
-	Interrupt() bool                   // interrupt the interval, setting it to the next period
-	Running() bool                     // whether or not the interval is running
-	GetDelay() time.Duration           // the duration of the current interval period
-	Register(callback events.Callback) // register a handler for the interval event
+	Start() bool                        // start the interval to periodically call its function
+	Stop() bool                         // stop the interval, the function will not be called
+	StopWait(ctx context.Context) error // stop the interval and wait for in-flight callbacks
+	Interrupt() bool                    // interrupt the interval, setting it to the next period
+	Running() bool                      // whether or not the interval is running
+	GetDelay() time.Duration            // the duration of the current interval period
+	Register(callback events.Callback)  // register a handler for the interval event
 }
 
 // NewFixedInterval creates and initializes a new fixed interval.
@@ -50,6 +55,13 @@ func NewRandomInterval(minDelay, maxDelay time.Duration, etype events.Type, echa
 	return interval
 }
 
+// NewJitteredInterval creates and initializes a new jittered interval.
+func NewJitteredInterval(period time.Duration, jitter float64, etype events.Type, echan chan<- error) *JitteredInterval {
+	interval := new(JitteredInterval)
+	interval.Init(period, jitter, etype, echan)
+	return interval
+}
+
 //===========================================================================
 // FixedInterval Declaration
 //===========================================================================
@@ -57,13 +69,28 @@ func NewRandomInterval(minDelay, maxDelay time.Duration, etype events.Type, echa
 // FixedInterval dispatches it's internal event type on a routine period. It
 // does that by wrapping a time.Timer object, adding the additional Interval
 // functionality as well as the event dispatcher functionality.
+//
+// Intervals that embed the FixedInterval to compute a different delay should
+// set the next function to their GetDelay method after calling Init so that
+// the timer is scheduled using their delay rather than the fixed delay.
 type FixedInterval struct {
 	events.Dispatcher
-	delay       time.Duration // The fixed interval to push events on
-	etype       events.Type   // The type of event dispatched by the timer
-	echan       chan<- error  // Channel to send errors on
-	initialized bool          // If the interval has been initialized
-	timer       *time.Timer   // The internal timer to wrap
+	sync.RWMutex                      // Guards the timer separately from the dispatcher
+	delay        time.Duration        // The fixed interval to push events on
+	etype        events.Type          // The type of event dispatched by the timer
+	echan        chan<- error         // Channel to send errors on
+	initialized  bool                 // If the interval has been initialized
+	timer        *time.Timer          // The internal timer to wrap
+	generation   uint64               // Incremented when the timer is scheduled to ignore stale callbacks
+	next         func() time.Duration // Computes the delay to schedule the timer with
+	scheduled    time.Time            // When the timer is scheduled to fire
+	firing       chan struct{}        // Closed when the in-flight dispatch completes, nil if idle
+	halted       bool                 // If the interval was stopped during an in-flight dispatch
+	sink         Sink                 // Receives metrics about the interval if instrumented
+	prefix       string               // Prefix of the names of the metrics
+	limit        uint64               // The maximum number of fires after start, zero for no limit
+	deadline     time.Time            // The interval does not fire after the deadline if not zero
+	fires        uint64               // The number of times the interval fired since it was started
 }
 
 // Init the Fixed Interval with the specified delay
@@ -72,6 +99,7 @@ func (t *FixedInterval) Init(delay time.Duration, etype events.Type, echan chan<
 	t.etype = etype
 	t.delay = delay
 	t.echan = echan
+	t.next = t.GetDelay
 
 	// Initialize the dispatcher
 	t.Dispatcher.Init(t)
@@ -86,7 +114,8 @@ func (t *FixedInterval) GetDelay() time.Duration {
 }
 
 // Start the interval to periodically issue events. Returns true if the
-// ticker gets started, false if it's already started or uninitialized.
+// ticker gets started, false if it's already started, uninitialized, or the
+// first event would be dispatched after the deadline of the interval.
 func (t *FixedInterval) Start() bool {
 	t.Lock()
 	defer t.Unlock()
@@ -97,44 +126,117 @@ func (t *FixedInterval) Start() bool {
 	}
 
 	// Create the new timer with the delay
-	t.timer = time.AfterFunc(t.GetDelay(), t.action)
-	return true
+	t.fires = 0
+	return t.schedule()
 }
 
-// dispatches the fixed interval event when the timer goes off and resets the
-// timer to prepare for the next event dispatch.
-func (t *FixedInterval) action() {
+// dispatches the fixed interval event when the timer of the generation goes off
+// and resets the timer to prepare for the next event dispatch. The lock is not
+// held while the callbacks are executing so that they can stop or interrupt the
+// interval. If the timer was stopped or replaced by Interrupt after it fired but
+// before the callback acquired the lock, the callback is stale and returns
+// without touching the current timer.
+func (t *FixedInterval) action(generation uint64) {
 	t.Lock()
-	defer t.Unlock()
-
-	if !t.running() || t.timer.Stop() {
-		// Something went wrong here, not sure how
+	if t.timer == nil || t.generation != generation {
+		t.Unlock()
 		return
 	}
 
-	// Set the timer to nil to indicate we've stopped
+	// Set the timer to nil and mark the dispatch as in-flight
 	t.timer = nil
+	t.firing = make(chan struct{})
+	t.halted = false
+	fired := time.Now()
+	t.Unlock()
 
 	// Dispatch the internal event
-	if err := t.Dispatcher.Dispatch(t.etype, nil); err != nil {
+	err := t.Dispatcher.Dispatch(t.etype, nil)
+
+	t.Lock()
+	t.measure(fired)
+	t.fires++
+	close(t.firing)
+	t.firing = nil
+
+	// Create a new timer for the next action unless stopped during the dispatch
+	// or the interval has reached its limit or deadline
+	var completed string
+	if err == nil && !t.halted {
+		switch {
+		case t.exhausted():
+			completed = CompletedLimit
+		case !t.schedule():
+			completed = CompletedDeadline
+		}
+	}
+	fires := t.fires
+	t.Unlock()
+
+	if err != nil {
+		t.report(err)
+	}
+
+	if completed != "" {
+		t.complete(completed, fires)
+	}
+}
+
+// sends the error on the error channel or, if the interval has no error channel,
+// dispatches it as an events.ErrorEvent on the interval's dispatcher so that it
+// is not lost. Must be called without the lock held.
+func (t *FixedInterval) report(err error) {
+	if t.echan != nil {
 		t.echan <- err
 		return
 	}
-
-	// Create a new timer for the next action
-	t.timer = time.AfterFunc(t.GetDelay(), t.action)
+	t.Dispatcher.Dispatch(events.ErrorEvent, &events.Error{Source: t.etype.String(), Err: err})
 }
 
 // Stop the interval so that no more events are dispatched. Returns true if
 // the call stops the interval, false if already expired or never started.
+// Callbacks of an in-flight event may still be executing when Stop returns.
 func (t *FixedInterval) Stop() bool {
 	t.Lock()
 	defer t.Unlock()
+	return t.stop()
+}
+
+// StopWait stops the interval so that no more events are dispatched and blocks
+// until the callbacks of an in-flight event have completed, e.g. so that state
+// can be cleanly transitioned after the interval is stopped. If the context is
+// done before the callbacks complete, the context error is returned; the
+// interval is still stopped.
+func (t *FixedInterval) StopWait(ctx context.Context) error {
+	t.Lock()
+	t.stop()
+	firing := t.firing
+	t.Unlock()
+
+	if firing == nil {
+		return nil
+	}
+
+	select {
+	case <-firing:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
+// stops the timer or halts the in-flight dispatch (not thread-safe).
+func (t *FixedInterval) stop() bool {
 	if !t.running() {
 		return false
 	}
 
+	if t.timer == nil {
+		// The event is being dispatched, prevent the timer from being rescheduled
+		t.halted = true
+		return true
+	}
+
 	// Stop the timer and set it to nil
 	stopped := t.timer.Stop()
 	t.timer = nil
@@ -143,22 +245,37 @@ func (t *FixedInterval) Stop() bool {
 
 // Interrupt the current interval, stopping and starting it again. Returns
 // true if the interval was running and is successfully reset, false if the
-// ticker was stopped or uninitialized.
+// ticker was stopped or uninitialized or if the reset interval would fire
+// after its deadline, in which case the interval completes.
 func (t *FixedInterval) Interrupt() bool {
 	t.Lock()
-	defer t.Unlock()
-
 	if !t.running() {
+		t.Unlock()
 		return false
 	}
 
-	// Stop the timer and drain the channel
-	if !t.timer.Stop() {
-		<-t.timer.C
+	// If the event is being dispatched the timer is rescheduled when it completes
+	if t.timer == nil {
+		t.Unlock()
+		return true
 	}
 
+	// Stop the timer (timers created by AfterFunc have no channel to drain)
+	t.timer.Stop()
 	t.timer = nil
-	t.timer = time.AfterFunc(t.GetDelay(), t.action)
+
+	if t.sink != nil {
+		t.sink.Incr(t.prefix+".interrupts", 1)
+	}
+
+	if !t.schedule() {
+		fires := t.fires
+		t.Unlock()
+		t.complete(CompletedDeadline, fires)
+		return false
+	}
+
+	t.Unlock()
 	return true
 }
 
@@ -169,9 +286,25 @@ func (t *FixedInterval) Running() bool {
 	return t.running()
 }
 
-// returns true if timer is running (not thread-safe).
+// creates a new timer for the next delay, returning false without creating a
+// timer if the event would be dispatched after the deadline (not thread-safe).
+func (t *FixedInterval) schedule() bool {
+	delay := t.next()
+	if t.expired(delay) {
+		return false
+	}
+
+	t.generation++
+	generation := t.generation
+	t.scheduled = time.Now().Add(delay)
+	t.timer = time.AfterFunc(delay, func() { t.action(generation) })
+	return true
+}
+
+// returns true if timer is running or an event is being dispatched and the
+// interval has not been stopped (not thread-safe).
 func (t *FixedInterval) running() bool {
-	return t.timer != nil
+	return t.timer != nil || (t.firing != nil && !t.halted)
 }
 
 // Register the specific event type with the callback
@@ -196,6 +329,7 @@ func (t *RandomInterval) Init(minDelay, maxDelay time.Duration, etype events.Typ
 	t.minDelay = int64(minDelay)
 	t.maxDelay = int64(maxDelay)
 	t.FixedInterval.Init(0, etype, echan)
+	t.next = t.GetDelay
 }
 
 // GetDelay returns a random integer in the range (minDelay, maxDelay) on
@@ -206,63 +340,48 @@ func (t *RandomInterval) GetDelay() time.Duration {
 	return t.delay
 }
 
-// Start the interval to periodically issue events. Returns true if the
-// ticker gets started, false if it's already started or uninitialized.
-func (t *RandomInterval) Start() bool {
-	t.Lock()
-	defer t.Unlock()
-
-	// If the timer is already started or uninitialized return false.
-	if t.running() || !t.initialized {
-		return false
-	}
+//===========================================================================
+// JitteredInterval Declaration
+//===========================================================================
 
-	// Create the new timer with the delay
-	t.timer = time.AfterFunc(t.GetDelay(), t.action)
-	return true
+// JitteredInterval dispatches its internal event on a fixed period that is
+// randomly adjusted by up to a percentage of the period on every event. For
+// example a period of 100ms with a jitter of 0.1 will dispatch events with a
+// delay between 90ms and 110ms. Unlike the RandomInterval, the jitter is
+// expressed relative to the period rather than as a range of delays.
+type JitteredInterval struct {
+	FixedInterval
+	period int64   // The fixed period around which the delay is jittered
+	jitter float64 // The maximum percent of the period to adjust the delay by
 }
 
-// dispatches the fixed interval event when the timer goes off and resets the
-// timer to prepare for the next event dispatch.
-func (t *RandomInterval) action() {
-	t.Lock()
-	defer t.Unlock()
-
-	if !t.running() || t.timer.Stop() {
-		// Something went wrong here, not sure how
-		return
+// Init the jittered interval. The jitter is specified as a fraction of the
+// period between 0.0 and 1.0, values outside this range are clamped.
+func (t *JitteredInterval) Init(period time.Duration, jitter float64, etype events.Type, echan chan<- error) {
+	if jitter < 0 {
+		jitter = 0
 	}
 
-	// Set the timer to nil to indicate we've stopped
-	t.timer = nil
-
-	// Dispatch the internal event
-	if err := t.Dispatcher.Dispatch(t.etype, nil); err != nil {
-		t.echan <- err
-		return
+	if jitter > 1 {
+		jitter = 1
 	}
 
-	// Create a new timer for the next action
-	t.timer = time.AfterFunc(t.GetDelay(), t.action)
+	t.period = int64(period)
+	t.jitter = jitter
+	t.FixedInterval.Init(period, etype, echan)
+	t.next = t.GetDelay
 }
 
-// Interrupt the current interval, stopping and starting it again. Returns
-// true if the interval was running and is successfully reset, false if the
-// ticker was stopped or uninitialized.
-func (t *RandomInterval) Interrupt() bool {
-	t.Lock()
-	defer t.Unlock()
-
-	if !t.running() {
-		return false
+// GetDelay returns a random delay in the range [period-jitter, period+jitter)
+// on every request for the delay so that no two timeouts occur at the same
+// time. If the jitter is zero, the period is always returned.
+func (t *JitteredInterval) GetDelay() time.Duration {
+	spread := int64(float64(t.period) * t.jitter)
+	if spread <= 0 {
+		t.delay = time.Duration(t.period)
+		return t.delay
 	}
 
-	// Stop the timer and drain the channel
-	if !t.timer.Stop() {
-		<-t.timer.C
-	}
-
-	t.timer = nil
-	t.timer = time.AfterFunc(t.GetDelay(), t.action)
-	return true
+	t.delay = time.Duration(t.period - spread + rand.Int63n(2*spread))
+	return t.delay
 }
@@ -1,7 +1,9 @@
 package interval_test
 
 import (
+	"context"
 	"math/rand"
+	"sync/atomic"
 	"time"
 
 	"github.com/bbengfort/x/events"
@@ -109,6 +111,30 @@ var _ = Describe("Interval", func() {
 			Ω(calls).Should(Equal(int64(2)))
 		})
 
+		It("should keep firing when interrupted while the timer fires", func() {
+			var fired int64
+			ticker := NewFixedInterval(50*time.Microsecond, events.TimeoutEvent, nil)
+			ticker.Register(func(e events.Event) error {
+				atomic.AddInt64(&fired, 1)
+				return nil
+			})
+
+			Ω(ticker.Start()).Should(BeTrue())
+			defer ticker.Stop()
+
+			// The callback of a timer that already fired races the interrupt that
+			// replaces it and must not stop the new timer, or the interval stalls
+			for i := 0; i < 20; i++ {
+				for deadline := time.Now().Add(10 * time.Millisecond); time.Now().Before(deadline); {
+					ticker.Interrupt()
+				}
+
+				before := atomic.LoadInt64(&fired)
+				Eventually(func() int64 { return atomic.LoadInt64(&fired) }, 100*time.Millisecond).Should(BeNumerically(">", before))
+			}
+			Ω(ticker.Running()).Should(BeTrue())
+		})
+
 		It("should be able to stop an interval", func() {
 			ticker := new(FixedInterval)
 			ticker.Init(delay, events.TimeoutEvent, echan)
@@ -124,6 +150,74 @@ var _ = Describe("Interval", func() {
 			Ω(calls).Should(BeNumerically("==", 1))
 		})
 
+		It("should wait for in-flight callbacks when stopped", func() {
+			ticker := NewFixedInterval(delay, events.TimeoutEvent, echan)
+
+			entered := make(chan struct{}, 1)
+			var completed int32
+			ticker.Register(func(e events.Event) error {
+				entered <- struct{}{}
+				time.Sleep(10 * time.Millisecond)
+				atomic.AddInt32(&completed, 1)
+				return nil
+			})
+
+			Ω(ticker.Start()).Should(BeTrue())
+			Eventually(entered).Should(Receive())
+
+			// Stop returns while the callback is still executing
+			Ω(ticker.Running()).Should(BeTrue())
+			Ω(ticker.StopWait(context.Background())).Should(Succeed())
+			Ω(atomic.LoadInt32(&completed)).Should(Equal(int32(1)))
+			Ω(ticker.Running()).Should(BeFalse())
+
+			// No more events are dispatched after stopping
+			time.Sleep(wait)
+			Ω(atomic.LoadInt32(&completed)).Should(Equal(int32(1)))
+		})
+
+		It("should return when the context expires before callbacks complete", func() {
+			ticker := NewFixedInterval(delay, events.TimeoutEvent, echan)
+
+			entered := make(chan struct{}, 1)
+			release := make(chan struct{})
+			ticker.Register(func(e events.Event) error {
+				entered <- struct{}{}
+				<-release
+				return nil
+			})
+
+			Ω(ticker.Start()).Should(BeTrue())
+			Eventually(entered).Should(Receive())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+			defer cancel()
+			Ω(ticker.StopWait(ctx)).Should(MatchError(context.DeadlineExceeded))
+			Ω(ticker.Running()).Should(BeFalse())
+
+			// The interval is not rescheduled when the callback completes
+			close(release)
+			time.Sleep(wait)
+			Ω(entered).ShouldNot(Receive())
+
+			// Stopping an idle interval does not block
+			Ω(ticker.StopWait(context.Background())).Should(Succeed())
+		})
+
+		It("should allow callbacks to stop the interval", func() {
+			ticker := NewFixedInterval(delay, events.TimeoutEvent, echan)
+			ticker.Register(func(e events.Event) error {
+				calls++
+				ticker.Stop()
+				return nil
+			})
+
+			Ω(ticker.Start()).Should(BeTrue())
+			time.Sleep(wait)
+			Ω(calls).Should(Equal(int64(1)))
+			Ω(ticker.Running()).Should(BeFalse())
+		})
+
 	})
 
 	Describe("Random Interval", func() {
@@ -239,4 +333,50 @@ var _ = Describe("Interval", func() {
 		})
 	})
 
+	Describe("Jittered Interval", func() {
+
+		BeforeEach(func() {
+			// Set the random seed to produce expected behavior
+			rand.Seed(42)
+		})
+
+		It("should not start an uninitialized interval", func() {
+			ticker := new(JitteredInterval)
+			Ω(ticker.Start()).Should(BeFalse())
+		})
+
+		It("should return a delay within the jitter of the period", func() {
+			ticker := NewJitteredInterval(delay*2, 0.1, events.TimeoutEvent, echan)
+
+			for i := 0; i < 1000; i++ {
+				d := ticker.GetDelay()
+				Ω(d).Should(BeNumerically(">=", 9*time.Millisecond))
+				Ω(d).Should(BeNumerically("<", 11*time.Millisecond))
+			}
+		})
+
+		It("should return the period with no jitter", func() {
+			ticker := NewJitteredInterval(delay, 0.0, events.TimeoutEvent, echan)
+			Ω(ticker.GetDelay()).Should(Equal(delay))
+
+			ticker = NewJitteredInterval(delay, -0.5, events.TimeoutEvent, echan)
+			Ω(ticker.GetDelay()).Should(Equal(delay))
+		})
+
+		It("should emit an event at a jittered interval", func() {
+			ticker := NewJitteredInterval(delay, 0.1, events.TimeoutEvent, echan)
+			ticker.Register(counter)
+
+			// Start the ticker
+			started = time.Now()
+			Ω(ticker.Start()).Should(BeTrue())
+			time.Sleep(wait)
+			ticker.Stop()
+
+			Ω(calls).Should(BeNumerically(">=", 3))
+			Ω(calls).Should(BeNumerically("<=", 5))
+			Ω(since).Should(BeNumerically("<", wait+delay))
+		})
+	})
+
 })
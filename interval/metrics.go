@@ -0,0 +1,51 @@
+package interval
+
+import "time"
+
+//===========================================================================
+// Interval Metrics
+//===========================================================================
+
+// Sink receives metrics emitted by an instrumented interval. A *stats.Registry
+// satisfies this interface so that interval metrics can be reported alongside
+// the other metrics of a process.
+type Sink interface {
+	Incr(name string, delta uint64)       // increment a counter
+	Time(name string, d ...time.Duration) // record a duration
+}
+
+// Instrument the interval so that it emits metrics to the sink with names
+// prefixed by the specified prefix (e.g. "heartbeat"):
+//
+//   - prefix.fires: counter of the number of times the interval fired
+//   - prefix.interrupts: counter of the number of times the interval was interrupted
+//   - prefix.drift: durations between when the interval was scheduled to fire and when it fired
+//   - prefix.latency: durations of the callbacks of each fire
+//
+// Passing a nil sink stops the interval from emitting metrics.
+func (t *FixedInterval) Instrument(sink Sink, prefix string) {
+	t.Lock()
+	defer t.Unlock()
+	t.sink = sink
+	t.prefix = prefix
+}
+
+// records the metrics of a fire of the interval (not thread-safe).
+func (t *FixedInterval) measure(fired time.Time) {
+	if t.sink == nil {
+		return
+	}
+
+	t.sink.Incr(t.prefix+".fires", 1)
+	t.sink.Time(t.prefix+".drift", nonzero(fired.Sub(t.scheduled)))
+	t.sink.Time(t.prefix+".latency", nonzero(time.Since(fired)))
+}
+
+// Benchmarks record zero durations as timeouts, so durations are recorded
+// with a minimum of one nanosecond (timers never fire early).
+func nonzero(d time.Duration) time.Duration {
+	if d <= 0 {
+		return time.Nanosecond
+	}
+	return d
+}
@@ -0,0 +1,52 @@
+package interval_test
+
+import (
+	"time"
+
+	"github.com/bbengfort/x/events"
+	. "github.com/bbengfort/x/interval"
+	"github.com/bbengfort/x/stats"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Interval Metrics", func() {
+
+	It("should emit metrics to a stats registry", func() {
+		registry := stats.NewRegistry()
+		ticker := NewFixedInterval(5*time.Millisecond, events.TimeoutEvent, make(chan error, 1))
+		ticker.Instrument(registry, "heartbeat")
+		ticker.Register(func(e events.Event) error {
+			time.Sleep(time.Millisecond)
+			return nil
+		})
+
+		ticker.Start()
+		Eventually(func() uint64 { return registry.Count("heartbeat.fires") }).Should(BeNumerically(">=", 3))
+		ticker.Interrupt()
+		time.Sleep(12 * time.Millisecond)
+		ticker.Stop()
+
+		fires := registry.Count("heartbeat.fires")
+		Ω(fires).Should(BeNumerically(">=", 3))
+		Ω(registry.Count("heartbeat.interrupts")).Should(Equal(uint64(1)))
+		Ω(registry.Benchmark("heartbeat.drift").N()).Should(Equal(fires))
+		Ω(registry.Benchmark("heartbeat.drift").Timeouts()).Should(BeZero())
+		Ω(registry.Benchmark("heartbeat.latency").N()).Should(Equal(fires))
+		Ω(registry.Benchmark("heartbeat.latency").Fastest()).Should(BeNumerically(">=", time.Millisecond))
+	})
+
+	It("should not emit metrics without a sink", func() {
+		registry := stats.NewRegistry()
+		ticker := NewJitteredInterval(2*time.Millisecond, 0.1, events.TimeoutEvent, make(chan error, 1))
+		ticker.Instrument(registry, "election")
+		ticker.Instrument(nil, "")
+
+		ticker.Start()
+		time.Sleep(10 * time.Millisecond)
+		ticker.Stop()
+
+		Ω(registry.Names()).Should(BeEmpty())
+	})
+})
@@ -10,14 +10,20 @@ panic (primarily when  there are many go routines all making progress in
 different parts of the program), you can print a report of which callers are
 attempting to acquire locks, and which callers have not released their locks
 yet, helping to diagnose contention issues.
+
+If the Trace option of a lock is set, lock hold periods are also wrapped in
+runtime/trace regions named by the caller so that go tool trace visualizes the
+critical sections of the program.
 */
 package lock
 
 import (
+	"context"
 	"fmt"
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 )
 
 // UnknownCaller is used as the default caller if we cannot query it.
@@ -35,11 +41,24 @@ func caller() string {
 
 // MutexD wraps sync.Mutex to provide tracking for methods that call the lock
 // object. Use the same way you would use a Mutex!
+//
+// If Trace is set and the execution tracer is running, the wait for the lock
+// and the period the lock is held are recorded as runtime/trace regions named
+// by the caller, e.g. "lock main.(*Server).Handle".
+//
+// If Name is set, the lock registers itself when it is first used so that its
+// statistics are included in the global Report. If another lock is already
+// registered with the name, a suffix is added to keep the name unique.
 type MutexD struct {
 	sync.Mutex
+	Name        string // register the lock with this name for the global Report (optional)
+	Trace       bool   // record lock waits and hold periods as runtime/trace regions
 	initialized bool
+	registered  string
 	locks       map[string]int64
+	labels      map[string]int64
 	signals     chan *lockSignal
+	tracer      tracer
 }
 
 // Init the lock and internal data structures like the map. No need to Init()
@@ -47,21 +66,57 @@ type MutexD struct {
 func (l *MutexD) Init() {
 	if !l.initialized {
 		l.locks = make(map[string]int64)
+		l.labels = make(map[string]int64)
 		l.signals = make(chan *lockSignal, 1000)
+
+		if l.Name != "" {
+			l.registered = register(l.Name, l)
+		}
+
 		go l.listner()
 		l.initialized = true
 	}
 }
 
+// Unregister removes the lock from the global Report, e.g. when the object it
+// protects is discarded. Unlike the Unregister function, it never removes
+// another lock that was registered with the same name.
+func (l *MutexD) Unregister() {
+	if l.registered != "" {
+		unregister(l.registered, l)
+	}
+}
+
 // The listener grabs lock signals from the channels and updates the map
 // accordingly. This is done to avoid concurrent map reads and writes.
 func (l *MutexD) listner() {
 	for s := range l.signals {
+		if s.stats != nil {
+			stats := LockStats{Name: l.registered}
+			stats.Locks, stats.Callers = outstanding(l.locks)
+			_, stats.Labels = outstanding(l.labels)
+			s.stats <- stats
+			continue
+		}
+
+		if s.report != nil {
+			s.report <- l.report()
+			continue
+		}
+
 		if s.locked {
 			l.locks[s.caller]++
 		} else {
 			l.locks[s.caller]--
 		}
+
+		if s.label != "" {
+			if s.locked {
+				l.labels[s.label]++
+			} else {
+				l.labels[s.label]--
+			}
+		}
 	}
 }
 
@@ -72,8 +127,9 @@ func (l *MutexD) listner() {
 // any locks in the system.
 func (l *MutexD) Lock() {
 	l.Init()
-	l.signals <- &lockSignal{lock: writeLock, locked: true, caller: caller()}
-	l.Mutex.Lock()
+	c := caller()
+	l.signals <- &lockSignal{lock: writeLock, locked: true, caller: c}
+	l.tracer.acquire(l.Trace, nil, "lock "+c, l.Mutex.Lock)
 }
 
 // Unlock the data structure, allowing any other blocked calls that have
@@ -83,21 +139,67 @@ func (l *MutexD) Lock() {
 func (l *MutexD) Unlock() {
 	l.Init()
 	l.signals <- &lockSignal{lock: writeLock, locked: false, caller: caller()}
+	l.tracer.release(l.Trace)
+	l.Mutex.Unlock()
+}
+
+// LockLabeled locks the data structure like Lock, but also records the
+// acquisition under the label stored in the context by WithLabel so that
+// contention can be grouped by request type or tenant rather than only by
+// the call site. The lock must be released with UnlockLabeled using a
+// context with the same label. If the lock is traced, its regions belong to
+// the runtime/trace task of the context.
+func (l *MutexD) LockLabeled(ctx context.Context) {
+	l.Init()
+	c := caller()
+	l.signals <- &lockSignal{lock: writeLock, locked: true, caller: c, label: Label(ctx)}
+	l.tracer.acquire(l.Trace, ctx, "lock "+c, l.Mutex.Lock)
+}
+
+// UnlockLabeled unlocks the data structure like Unlock, removing the
+// acquisition from the label stored in the context by WithLabel.
+func (l *MutexD) UnlockLabeled(ctx context.Context) {
+	l.Init()
+	l.signals <- &lockSignal{lock: writeLock, locked: false, caller: caller(), label: Label(ctx)}
+	l.tracer.release(l.Trace)
 	l.Mutex.Unlock()
 }
 
 // String returns a report about who is attempting to acquire locks and which
 // callers currently hold locks. E.g. if more than one lock is in the lock
 // map than the first one is holding the lock and the others are awaiting it.
+// Locks acquired with a label are also reported by label.
 func (l *MutexD) String() string {
+	l.Init()
+	report := make(chan string, 1)
+	l.signals <- &lockSignal{report: report}
+	return <-report
+}
+
+// Returns the report of the lock; must only be called by the listener so that
+// the maps are not read concurrently.
+func (l *MutexD) report() string {
 	output := make([]string, 0)
 	for key, val := range l.locks {
 		msg := fmt.Sprintf("%d locks requested by %s", val, key)
 		output = append(output, msg)
 	}
+
+	for key, val := range l.labels {
+		msg := fmt.Sprintf("%d locks requested with label %q", val, key)
+		output = append(output, msg)
+	}
 	return strings.Join(output, "\n")
 }
 
+// Returns the stats of the lock, collected by the listener so that the maps are
+// not read concurrently.
+func (l *MutexD) snapshot() LockStats {
+	stats := make(chan LockStats, 1)
+	l.signals <- &lockSignal{stats: stats}
+	return <-stats
+}
+
 //===========================================================================
 // RW Mutex Diagnostics
 //===========================================================================
@@ -105,12 +207,34 @@ func (l *MutexD) String() string {
 // RWMutexD wraps a sync.RWMutex to provide tracking for methods that call the
 // lock object. Use the same way you would use a mutex in order to diagnose
 // requested read locks, write locks, and currently held read and write locks.
+//
+// RWMutexD also tracks the number of active readers and how long writers wait
+// to acquire the lock behind readers. If a writer waits longer than the
+// StarvationThreshold, OnStarvation is called (if set) and a warning is added
+// to the report.
+//
+// If Trace is set and the execution tracer is running, lock waits and hold
+// periods are recorded as runtime/trace regions named by the caller, e.g.
+// "lock main.(*Cache).Put" or "rlock main.(*Cache).Get".
+//
+// If Name is set, the lock registers itself when it is first used so that its
+// statistics are included in the global Report. If another lock is already
+// registered with the name, a suffix is added to keep the name unique.
 type RWMutexD struct {
 	sync.RWMutex
-	initialized bool
-	wlocks      map[string]int64
-	rlocks      map[string]int64
-	signals     chan *lockSignal
+	Name                string                                  // register the lock with this name for the global Report (optional)
+	StarvationThreshold time.Duration                           // warn when a writer waits longer than this behind readers (disabled if zero)
+	OnStarvation        func(caller string, wait time.Duration) // called when a writer exceeds the threshold (optional)
+	Trace               bool                                    // record lock waits and hold periods as runtime/trace regions
+	initialized         bool
+	registered          string
+	wlocks              map[string]int64
+	rlocks              map[string]int64
+	wlabels             map[string]int64
+	rlabels             map[string]int64
+	signals             chan *lockSignal
+	starvation          starvation
+	tracer              tracer
 }
 
 // Init the lock and internal data structures like the maps. No need to call
@@ -119,34 +243,69 @@ func (l *RWMutexD) Init() {
 	if !l.initialized {
 		l.wlocks = make(map[string]int64)
 		l.rlocks = make(map[string]int64)
+		l.wlabels = make(map[string]int64)
+		l.rlabels = make(map[string]int64)
 		l.signals = make(chan *lockSignal, 1000)
 
+		if l.Name != "" {
+			l.registered = register(l.Name, l)
+		}
+
 		go l.listner()
 
 		l.initialized = true
 	}
 }
 
+// Unregister removes the lock from the global Report, e.g. when the object it
+// protects is discarded. Unlike the Unregister function, it never removes
+// another lock that was registered with the same name.
+func (l *RWMutexD) Unregister() {
+	if l.registered != "" {
+		unregister(l.registered, l)
+	}
+}
+
 // The listener grabs lock signals from the channels and updates the map
 // accordingly. This is done to avoid concurrent map reads and writes. This
 // listener specializes itself by detecting the lock type.
 func (l *RWMutexD) listner() {
 	for s := range l.signals {
-		switch s.lock {
-		case writeLock:
-			if s.locked {
-				l.wlocks[s.caller]++
-			} else {
-				l.wlocks[s.caller]--
-			}
-		case readLock:
+		if s.stats != nil {
+			stats := LockStats{Name: l.registered, Starvation: l.starvation.snapshot()}
+			var wcallers, rcallers, wlabels, rlabels int
+			stats.Locks, wcallers = outstanding(l.wlocks)
+			stats.RLocks, rcallers = outstanding(l.rlocks)
+			_, wlabels = outstanding(l.wlabels)
+			_, rlabels = outstanding(l.rlabels)
+			stats.Callers, stats.Labels = wcallers+rcallers, wlabels+rlabels
+			s.stats <- stats
+			continue
+		}
+
+		if s.report != nil {
+			s.report <- l.report()
+			continue
+		}
+
+		locks, labels := l.wlocks, l.wlabels
+		if s.lock == readLock {
+			locks, labels = l.rlocks, l.rlabels
+		}
+
+		if s.locked {
+			locks[s.caller]++
+		} else {
+			locks[s.caller]--
+		}
+
+		if s.label != "" {
 			if s.locked {
-				l.rlocks[s.caller]++
+				labels[s.label]++
 			} else {
-				l.rlocks[s.caller]--
+				labels[s.label]--
 			}
 		}
-
 	}
 }
 
@@ -157,8 +316,9 @@ func (l *RWMutexD) listner() {
 // any locks in the system.
 func (l *RWMutexD) Lock() {
 	l.Init()
-	l.signals <- &lockSignal{lock: writeLock, locked: true, caller: caller()}
-	l.RWMutex.Lock()
+	c := caller()
+	l.signals <- &lockSignal{lock: writeLock, locked: true, caller: c}
+	l.lock(nil, c)
 }
 
 // Unlock the data structure, allowing any other blocked calls that have
@@ -168,6 +328,7 @@ func (l *RWMutexD) Lock() {
 func (l *RWMutexD) Unlock() {
 	l.Init()
 	l.signals <- &lockSignal{lock: writeLock, locked: false, caller: caller()}
+	l.tracer.release(l.Trace)
 	l.RWMutex.Unlock()
 }
 
@@ -178,8 +339,10 @@ func (l *RWMutexD) Unlock() {
 // any locks in the system.
 func (l *RWMutexD) RLock() {
 	l.Init()
-	l.signals <- &lockSignal{lock: readLock, locked: true, caller: caller()}
-	l.RWMutex.RLock()
+	c := caller()
+	l.signals <- &lockSignal{lock: readLock, locked: true, caller: c}
+	l.tracer.acquire(l.Trace, nil, "rlock "+c, l.RWMutex.RLock)
+	l.starvation.read(1)
 }
 
 // RUnlock the data structure, allowing any other blocked calls that have
@@ -189,13 +352,65 @@ func (l *RWMutexD) RLock() {
 func (l *RWMutexD) RUnlock() {
 	l.Init()
 	l.signals <- &lockSignal{lock: readLock, locked: false, caller: caller()}
+	l.starvation.read(-1)
+	l.tracer.release(l.Trace)
+	l.RWMutex.RUnlock()
+}
+
+// LockLabeled locks the data structure like Lock, but also records the
+// acquisition under the label stored in the context by WithLabel. The lock
+// must be released with UnlockLabeled using a context with the same label.
+func (l *RWMutexD) LockLabeled(ctx context.Context) {
+	l.Init()
+	c := caller()
+	l.signals <- &lockSignal{lock: writeLock, locked: true, caller: c, label: Label(ctx)}
+	l.lock(ctx, c)
+}
+
+// UnlockLabeled unlocks the data structure like Unlock, removing the
+// acquisition from the label stored in the context by WithLabel.
+func (l *RWMutexD) UnlockLabeled(ctx context.Context) {
+	l.Init()
+	l.signals <- &lockSignal{lock: writeLock, locked: false, caller: caller(), label: Label(ctx)}
+	l.tracer.release(l.Trace)
+	l.RWMutex.Unlock()
+}
+
+// RLockLabeled read locks the data structure like RLock, but also records the
+// acquisition under the label stored in the context by WithLabel. The lock
+// must be released with RUnlockLabeled using a context with the same label.
+func (l *RWMutexD) RLockLabeled(ctx context.Context) {
+	l.Init()
+	c := caller()
+	l.signals <- &lockSignal{lock: readLock, locked: true, caller: c, label: Label(ctx)}
+	l.tracer.acquire(l.Trace, ctx, "rlock "+c, l.RWMutex.RLock)
+	l.starvation.read(1)
+}
+
+// RUnlockLabeled read unlocks the data structure like RUnlock, removing the
+// acquisition from the label stored in the context by WithLabel.
+func (l *RWMutexD) RUnlockLabeled(ctx context.Context) {
+	l.Init()
+	l.signals <- &lockSignal{lock: readLock, locked: false, caller: caller(), label: Label(ctx)}
+	l.starvation.read(-1)
+	l.tracer.release(l.Trace)
 	l.RWMutex.RUnlock()
 }
 
 // String returns a report about who is attempting to acquire locks and which
 // callers currently hold locks. E.g. if more than one lock is in the lock
 // map than the first one is holding the lock and the others are awaiting it.
+// Locks acquired with a label are also reported by label.
 func (l *RWMutexD) String() string {
+	l.Init()
+	report := make(chan string, 1)
+	l.signals <- &lockSignal{report: report}
+	return <-report
+}
+
+// Returns the report of the lock; must only be called by the listener so that
+// the maps are not read concurrently.
+func (l *RWMutexD) report() string {
 	output := make([]string, 0)
 
 	// Write locks
@@ -210,9 +425,57 @@ func (l *RWMutexD) String() string {
 		output = append(output, msg)
 	}
 
+	// Labeled locks
+	for key, val := range l.wlabels {
+		msg := fmt.Sprintf("%d locks requested with label %q", val, key)
+		output = append(output, msg)
+	}
+
+	for key, val := range l.rlabels {
+		msg := fmt.Sprintf("%d read locks requested with label %q", val, key)
+		output = append(output, msg)
+	}
+
+	// Reader starvation warning
+	if stats := l.starvation.snapshot(); stats.Starved > 0 {
+		msg := fmt.Sprintf(
+			"WARNING: %d writers starved by readers longer than %s (longest wait %s by %s)",
+			stats.Starved, l.StarvationThreshold, stats.MaxWriterWait, stats.MaxWaiter,
+		)
+		output = append(output, msg)
+	}
+
 	return strings.Join(output, "\n")
 }
 
+// Starvation returns the reader starvation diagnostics of the lock.
+func (l *RWMutexD) Starvation() Starvation {
+	return l.starvation.snapshot()
+}
+
+// Returns the stats of the lock, collected by the listener so that the maps are
+// not read concurrently.
+func (l *RWMutexD) snapshot() LockStats {
+	stats := make(chan LockStats, 1)
+	l.signals <- &lockSignal{stats: stats}
+	return <-stats
+}
+
+// Acquires the write lock, recording how long the caller waited if readers
+// were holding the lock when it was requested.
+func (l *RWMutexD) lock(ctx context.Context, caller string) {
+	readers := l.starvation.readers()
+	start := time.Now()
+	l.tracer.acquire(l.Trace, ctx, "lock "+caller, l.RWMutex.Lock)
+
+	if readers > 0 {
+		wait := time.Since(start)
+		if l.starvation.wait(caller, wait, l.StarvationThreshold) && l.OnStarvation != nil {
+			l.OnStarvation(caller, wait)
+		}
+	}
+}
+
 //===========================================================================
 // Lock signals to the listeners
 //===========================================================================
@@ -228,7 +491,37 @@ type lockType uint8
 // to the internal maps of the Lock object. LockSignal objects are sent to a
 // channel that serializes the lock information.
 type lockSignal struct {
-	locked bool     // true for lock false for unlock
-	lock   lockType // either ReadLock or WriteLock
-	caller string   // name of the calling function
+	locked bool             // true for lock false for unlock
+	lock   lockType         // either ReadLock or WriteLock
+	caller string           // name of the calling function
+	label  string           // label from the context of the caller (optional)
+	stats  chan<- LockStats // requests the stats of the lock rather than (un)locking
+	report chan<- string    // requests the report of the lock rather than (un)locking
+}
+
+//===========================================================================
+// Context labels
+//===========================================================================
+
+// labelKey is the context key for lock labels.
+type labelKey struct{}
+
+// WithLabel returns a copy of the parent context with the label that is used
+// to group lock acquisitions made by LockLabeled and RLockLabeled, e.g. the
+// request type or the tenant the lock is acquired on behalf of.
+func WithLabel(parent context.Context, label string) context.Context {
+	return context.WithValue(parent, labelKey{}, label)
+}
+
+// Label returns the lock label stored in the context or an empty string if
+// the context has no label.
+func Label(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	if label, ok := ctx.Value(labelKey{}).(string); ok {
+		return label
+	}
+	return ""
 }
@@ -1,7 +1,11 @@
 package lock
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"runtime/trace"
+	"sync"
 	"testing"
 	"time"
 
@@ -80,3 +84,189 @@ func ExampleRWMutexD() {
 	// 1 locks requested by github.com/bbengfort/x/lock.(*Lockable).Alpha
 	// 2 read locks requested by github.com/bbengfort/x/lock.(*Lockable).Bravo
 }
+
+func TestLabel(t *testing.T) {
+	RegisterTestingT(t)
+	Ω(Label(context.Background())).Should(BeEmpty())
+	Ω(Label(WithLabel(context.Background(), "tenant-a"))).Should(Equal("tenant-a"))
+}
+
+func TestLockLabeled(t *testing.T) {
+	RegisterTestingT(t)
+	ctx := WithLabel(context.Background(), "tenant-a")
+
+	l := new(RWMutexD)
+	l.LockLabeled(ctx)
+	l.UnlockLabeled(ctx)
+	l.RLockLabeled(ctx)
+
+	Eventually(l.String).Should(ContainSubstring(`1 read locks requested with label "tenant-a"`))
+	Eventually(l.String).Should(ContainSubstring(`0 locks requested with label "tenant-a"`))
+
+	m := new(MutexD)
+	m.LockLabeled(ctx)
+	Eventually(m.String).Should(ContainSubstring(`1 locks requested with label "tenant-a"`))
+}
+
+func TestStarvation(t *testing.T) {
+	RegisterTestingT(t)
+
+	var starved string
+	l := &RWMutexD{StarvationThreshold: 10 * time.Millisecond}
+	l.OnStarvation = func(caller string, wait time.Duration) {
+		starved = caller
+	}
+
+	l.RLock()
+	l.RLock()
+	Ω(l.Starvation().ActiveReaders).Should(Equal(int64(2)))
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		l.RUnlock()
+		l.RUnlock()
+	}()
+
+	l.Lock()
+	l.Unlock()
+
+	stats := l.Starvation()
+	Ω(stats.ActiveReaders).Should(BeZero())
+	Ω(stats.PeakReaders).Should(Equal(int64(2)))
+	Ω(stats.Starved).Should(Equal(uint64(1)))
+	Ω(stats.MaxWriterWait).Should(BeNumerically(">", 10*time.Millisecond))
+	Ω(stats.MaxWaiter).Should(Equal("github.com/bbengfort/x/lock.TestStarvation"))
+	Ω(starved).Should(Equal(stats.MaxWaiter))
+	Ω(l.String()).Should(ContainSubstring("WARNING: 1 writers starved by readers"))
+}
+
+func TestTrace(t *testing.T) {
+	RegisterTestingT(t)
+
+	buf := new(bytes.Buffer)
+	Ω(trace.Start(buf)).Should(Succeed())
+
+	ctx, task := trace.NewTask(context.Background(), "request")
+	m := &MutexD{Trace: true}
+	m.LockLabeled(ctx)
+	m.UnlockLabeled(ctx)
+	task.End()
+
+	rw := &RWMutexD{Trace: true}
+	rw.RLock()
+	rw.RLock()
+	rw.RUnlock()
+	rw.RUnlock()
+	rw.Lock()
+	rw.Unlock()
+
+	trace.Stop()
+
+	// All of the hold period regions have been ended
+	Ω(m.tracer.regions).Should(BeEmpty())
+	Ω(rw.tracer.regions).Should(BeEmpty())
+
+	// The regions are named by the caller of the lock
+	Ω(buf.String()).Should(ContainSubstring("wait lock github.com/bbengfort/x/lock.TestTrace"))
+	Ω(buf.String()).Should(ContainSubstring("rlock github.com/bbengfort/x/lock.TestTrace"))
+
+	// No regions are recorded if tracing is not enabled for the lock
+	untraced := &MutexD{}
+	untraced.Lock()
+	untraced.Unlock()
+	Ω(untraced.tracer.regions).Should(BeNil())
+}
+
+func TestReport(t *testing.T) {
+	RegisterTestingT(t)
+	defer Unregister("alpha")
+	defer Unregister("bravo")
+
+	alpha := &MutexD{Name: "alpha"}
+	bravo := &RWMutexD{Name: "bravo"}
+	unnamed := new(MutexD)
+
+	alpha.Lock()
+	go func() {
+		alpha.Lock()
+		alpha.Unlock()
+	}()
+
+	bravo.RLock()
+	bravo.RLockLabeled(WithLabel(context.Background(), "tenant-a"))
+	unnamed.Lock()
+
+	Eventually(func() int64 { return Report().TotalLocks }).Should(Equal(int64(2)))
+
+	report := Report()
+	Ω(report.Locks).Should(HaveLen(2))
+	Ω(report.TotalRLocks).Should(Equal(int64(2)))
+	Ω(report.Contended).Should(Equal(1))
+
+	Ω(report.Locks[0].Name).Should(Equal("alpha"))
+	Ω(report.Locks[0].Callers).Should(Equal(2))
+	Ω(report.Locks[1].Name).Should(Equal("bravo"))
+	Ω(report.Locks[1].Labels).Should(Equal(1))
+	Ω(report.Locks[1].Starvation.ActiveReaders).Should(Equal(int64(2)))
+	Ω(report.String()).Should(HavePrefix("2 registered locks: 2 locks and 2 read locks requested, 1 contended"))
+
+	alpha.Unlock()
+	Eventually(func() int64 { return Report().TotalLocks }).Should(BeZero())
+
+	Unregister("alpha")
+	Ω(Report().Locks).Should(HaveLen(1))
+}
+
+func TestRegisterDuplicateNames(t *testing.T) {
+	RegisterTestingT(t)
+
+	first := &MutexD{Name: "charlie"}
+	second := &RWMutexD{Name: "charlie"}
+	first.Init()
+	second.Init()
+	defer first.Unregister()
+	defer second.Unregister()
+
+	first.Lock()
+	defer first.Unlock()
+
+	names := func() []string {
+		names := make([]string, 0)
+		for _, stats := range Report().Locks {
+			names = append(names, stats.Name)
+		}
+		return names
+	}
+
+	Ω(names()).Should(ContainElements("charlie", "charlie#2"))
+	Eventually(func() int64 { return Report().TotalLocks }).Should(Equal(int64(1)))
+
+	// Unregistering a lock does not remove the other lock with the same name
+	second.Unregister()
+	second.Unregister()
+	Ω(names()).Should(ContainElement("charlie"))
+	Ω(names()).ShouldNot(ContainElement("charlie#2"))
+}
+
+func TestStringConcurrency(t *testing.T) {
+	RegisterTestingT(t)
+
+	l := new(RWMutexD)
+	l.Init()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.RLock()
+				_ = l.String()
+				l.RUnlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	Ω(l.String()).Should(Equal("0 read locks requested by github.com/bbengfort/x/lock.TestStringConcurrency.func1"))
+}
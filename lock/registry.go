@@ -0,0 +1,166 @@
+package lock
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//===========================================================================
+// Global Lock Registry
+//===========================================================================
+
+// Registered locks by name; locks register themselves when they are
+// initialized if their Name is set.
+var registry = struct {
+	sync.RWMutex
+	locks map[string]tracked
+}{locks: make(map[string]tracked)}
+
+// tracked is implemented by the diagnostic locks so that the registry can
+// collect their statistics.
+type tracked interface {
+	snapshot() LockStats
+}
+
+// LockStats summarizes the outstanding lock requests of a registered lock.
+// Outstanding requests are locks that have been requested but not released,
+// e.g. if there are 3 outstanding write locks, one is held and two are waiting.
+type LockStats struct {
+	Name       string     // the name the lock is registered with
+	Locks      int64      // the number of outstanding write locks
+	RLocks     int64      // the number of outstanding read locks
+	Callers    int        // the number of callers with outstanding locks
+	Labels     int        // the number of labels with outstanding locks
+	Starvation Starvation // reader starvation diagnostics (RWMutexD only)
+}
+
+// String returns a one line summary of the outstanding lock requests.
+func (s LockStats) String() string {
+	return fmt.Sprintf(
+		"%s: %d locks and %d read locks requested by %d callers (%d labels)",
+		s.Name, s.Locks, s.RLocks, s.Callers, s.Labels,
+	)
+}
+
+// Summary is the aggregate report of all registered locks.
+type Summary struct {
+	Locks         []LockStats   // the stats of every registered lock sorted by name
+	TotalLocks    int64         // the number of outstanding write locks across all locks
+	TotalRLocks   int64         // the number of outstanding read locks across all locks
+	Contended     int           // the number of locks with more than one outstanding write lock
+	Starved       uint64        // the number of writers starved by readers across all locks
+	MaxWriterWait time.Duration // the longest a writer waited behind readers on any lock
+	MaxWaiter     string        // the lock and caller of the writer that waited the longest
+}
+
+// String returns a report with the totals across all locks followed by a line
+// for every registered lock.
+func (s Summary) String() string {
+	output := []string{
+		fmt.Sprintf(
+			"%d registered locks: %d locks and %d read locks requested, %d contended",
+			len(s.Locks), s.TotalLocks, s.TotalRLocks, s.Contended,
+		),
+	}
+
+	if s.Starved > 0 {
+		msg := fmt.Sprintf(
+			"WARNING: %d writers starved by readers (longest wait %s by %s)",
+			s.Starved, s.MaxWriterWait, s.MaxWaiter,
+		)
+		output = append(output, msg)
+	}
+
+	for _, stats := range s.Locks {
+		output = append(output, "  "+stats.String())
+	}
+	return strings.Join(output, "\n")
+}
+
+// Report returns the totals across all registered locks, so that the entire
+// locking picture of a server is available from one call. A MutexD or RWMutexD
+// is registered when it is first used if its Name is set.
+func Report() Summary {
+	registry.RLock()
+	locks := make([]tracked, 0, len(registry.locks))
+	for _, l := range registry.locks {
+		locks = append(locks, l)
+	}
+	registry.RUnlock()
+
+	summary := Summary{Locks: make([]LockStats, 0, len(locks))}
+	for _, l := range locks {
+		stats := l.snapshot()
+		summary.Locks = append(summary.Locks, stats)
+		summary.TotalLocks += stats.Locks
+		summary.TotalRLocks += stats.RLocks
+		summary.Starved += stats.Starvation.Starved
+
+		if stats.Locks > 1 {
+			summary.Contended++
+		}
+
+		if stats.Starvation.MaxWriterWait > summary.MaxWriterWait {
+			summary.MaxWriterWait = stats.Starvation.MaxWriterWait
+			summary.MaxWaiter = stats.Name + " " + stats.Starvation.MaxWaiter
+		}
+	}
+
+	sort.Slice(summary.Locks, func(i, j int) bool {
+		return summary.Locks[i].Name < summary.Locks[j].Name
+	})
+	return summary
+}
+
+// Unregister removes the named lock from the registry, e.g. when the object it
+// protects is discarded. The name is the name in the Report, which has a suffix
+// if the Name of the lock was already registered; prefer the Unregister method
+// of the lock.
+func Unregister(name string) {
+	registry.Lock()
+	defer registry.Unlock()
+	delete(registry.locks, name)
+}
+
+// Adds the lock to the registry and returns the name it was registered with. If
+// another lock is registered with the name, a suffix is added, e.g. cache#2, so
+// that neither lock is hidden from the report.
+func register(name string, l tracked) string {
+	registry.Lock()
+	defer registry.Unlock()
+
+	unique := name
+	for i := 2; ; i++ {
+		if _, ok := registry.locks[unique]; !ok {
+			break
+		}
+		unique = fmt.Sprintf("%s#%d", name, i)
+	}
+
+	registry.locks[unique] = l
+	return unique
+}
+
+// Removes the lock from the registry if it is still registered with the name.
+func unregister(name string, l tracked) {
+	registry.Lock()
+	defer registry.Unlock()
+	if registry.locks[name] == l {
+		delete(registry.locks, name)
+	}
+}
+
+// Returns the number of outstanding requests and the number of keys with
+// outstanding requests in the map.
+func outstanding(requests map[string]int64) (total int64, keys int) {
+	for _, val := range requests {
+		total += val
+		if val != 0 {
+			keys++
+		}
+	}
+	return total, keys
+}
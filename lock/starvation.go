@@ -0,0 +1,81 @@
+package lock
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+//===========================================================================
+// Reader Starvation Diagnostics
+//===========================================================================
+
+// Starvation reports how long writers of an RWMutexD have waited to acquire
+// the lock while readers were holding it. In read-heavy workloads a steady
+// stream of readers can delay writers far longer than expected.
+type Starvation struct {
+	ActiveReaders int64         // the number of readers currently holding the lock
+	PeakReaders   int64         // the maximum number of readers that held the lock at once
+	MaxWriterWait time.Duration // the longest a writer waited behind readers
+	MaxWaiter     string        // the caller of the writer that waited the longest
+	Starved       uint64        // the number of writers that waited longer than the threshold
+}
+
+// String returns a short report of the reader starvation diagnostics.
+func (s Starvation) String() string {
+	return fmt.Sprintf(
+		"%d active readers (peak %d), longest writer wait %s by %s, %d writers starved",
+		s.ActiveReaders, s.PeakReaders, s.MaxWriterWait, s.MaxWaiter, s.Starved,
+	)
+}
+
+// Tracks reader starvation diagnostics for an RWMutexD. A separate mutex is
+// used rather than the listener so that the active readers are accurate when
+// a writer requests the lock.
+type starvation struct {
+	sync.Mutex
+	stats Starvation
+}
+
+// Returns the number of readers currently holding the lock.
+func (s *starvation) readers() int64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.stats.ActiveReaders
+}
+
+// Records a reader acquiring (delta=1) or releasing (delta=-1) the lock.
+func (s *starvation) read(delta int64) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.stats.ActiveReaders += delta
+	if s.stats.ActiveReaders > s.stats.PeakReaders {
+		s.stats.PeakReaders = s.stats.ActiveReaders
+	}
+}
+
+// Records how long a writer waited behind readers, returning true if the wait
+// exceeded the threshold.
+func (s *starvation) wait(caller string, wait, threshold time.Duration) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if wait > s.stats.MaxWriterWait {
+		s.stats.MaxWriterWait = wait
+		s.stats.MaxWaiter = caller
+	}
+
+	if threshold > 0 && wait > threshold {
+		s.stats.Starved++
+		return true
+	}
+	return false
+}
+
+// Returns a copy of the current diagnostics.
+func (s *starvation) snapshot() Starvation {
+	s.Lock()
+	defer s.Unlock()
+	return s.stats
+}
@@ -0,0 +1,89 @@
+package lock
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"runtime/trace"
+	"strconv"
+	"sync"
+)
+
+//===========================================================================
+// Execution Tracer Regions
+//===========================================================================
+
+// Tracks the runtime/trace regions of lock hold periods so that go tool trace
+// can visualize the critical sections attributed to the callers of the lock.
+// Regions must be ended on the goroutine that started them, so regions are
+// tracked per goroutine; if a lock is released on a different goroutine than
+// the one that acquired it, its hold period is not ended in the trace.
+type tracer struct {
+	sync.Mutex
+	regions map[int64][]*trace.Region
+}
+
+// Acquires the lock, tracing the wait for the lock and starting a region for
+// the hold period if enabled and the execution tracer is running. The regions
+// belong to the task of the context (if any) so that critical sections can be
+// attributed to requests.
+func (t *tracer) acquire(enabled bool, ctx context.Context, name string, lock func()) {
+	if !enabled || !trace.IsEnabled() {
+		lock()
+		return
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	trace.WithRegion(ctx, "wait "+name, lock)
+	region := trace.StartRegion(ctx, name)
+
+	id := goid()
+	t.Lock()
+	if t.regions == nil {
+		t.regions = make(map[int64][]*trace.Region)
+	}
+	t.regions[id] = append(t.regions[id], region)
+	t.Unlock()
+}
+
+// Ends the most recent hold period region started by the current goroutine.
+func (t *tracer) release(enabled bool) {
+	if !enabled {
+		return
+	}
+
+	id := goid()
+	t.Lock()
+	regions := t.regions[id]
+	if len(regions) == 0 {
+		t.Unlock()
+		return
+	}
+
+	region := regions[len(regions)-1]
+	if len(regions) == 1 {
+		delete(t.regions, id)
+	} else {
+		t.regions[id] = regions[:len(regions)-1]
+	}
+	t.Unlock()
+
+	region.End()
+}
+
+// Returns the id of the current goroutine by parsing the header of its stack,
+// e.g. "goroutine 42 [running]:", or 0 if it cannot be determined.
+func goid() int64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	if idx := bytes.IndexByte(buf, ' '); idx > 0 {
+		buf = buf[:idx]
+	}
+
+	id, _ := strconv.ParseInt(string(buf), 10, 64)
+	return id
+}
@@ -0,0 +1,170 @@
+package net
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// DefaultStagger is the delay between connection attempts recommended by
+// RFC 8305 (Happy Eyeballs Version 2) when no stagger is specified.
+const DefaultStagger = 250 * time.Millisecond
+
+// HappyDialer connects to dual-stack hosts by racing connection attempts to
+// all resolved IPv4 and IPv6 addresses as described in RFC 8305. Addresses are
+// interleaved by family and a new attempt is started every Stagger interval
+// or as soon as the previous attempt fails; the first successful connection is
+// returned and all other attempts are canceled. The zero value is ready to use.
+type HappyDialer struct {
+	Stagger  time.Duration // delay between attempts (DefaultStagger if zero)
+	Resolver *net.Resolver // resolver used to lookup the host (net.DefaultResolver if nil)
+	Dialer   *net.Dialer   // dialer used for each attempt (zero net.Dialer if nil)
+
+	// used to stub out connection attempts in tests
+	dial func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// DialContextHappy connects to the host and port over TCP using a HappyDialer
+// with the default stagger, returning the first successful connection.
+func DialContextHappy(ctx context.Context, host string, port int) (net.Conn, error) {
+	return (&HappyDialer{}).DialContext(ctx, host, port)
+}
+
+// DialContext resolves the host and races connection attempts to each of its
+// addresses, returning the first successful connection. If every attempt
+// fails, the error from the first attempt is returned.
+func (d *HappyDialer) DialContext(ctx context.Context, host string, port int) (net.Conn, error) {
+	resolver := d.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses found for %q", host)
+	}
+
+	return d.race(ctx, interleave(addrs), port)
+}
+
+// Races connection attempts to the addresses in order, starting a new attempt
+// every stagger interval or when the previous attempt fails. The zone of an
+// address (e.g. of an IPv6 link-local address) is kept so it can be dialed.
+func (d *HappyDialer) race(ctx context.Context, ips []net.IPAddr, port int) (net.Conn, error) {
+	stagger := d.Stagger
+	if stagger <= 0 {
+		stagger = DefaultStagger
+	}
+
+	// Canceling the context aborts any attempts that are still in flight
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+
+	// Buffered so that attempts that complete after a winner never block
+	results := make(chan result, len(ips))
+	dial := d.dialFunc()
+	next, pending := 0, 0
+
+	var delay <-chan time.Time
+	attempt := func() {
+		addr := net.JoinHostPort(ips[next].String(), strconv.Itoa(port))
+		go func() {
+			conn, err := dial(ctx, "tcp", addr)
+			results <- result{conn, err}
+		}()
+
+		next++
+		pending++
+		delay = nil
+		if next < len(ips) {
+			delay = time.After(stagger)
+		}
+	}
+
+	// Close any connections that succeed after the first one is returned
+	drain := func() {
+		for ; pending > 0; pending-- {
+			if r := <-results; r.conn != nil {
+				r.conn.Close()
+			}
+		}
+	}
+
+	var first error
+	attempt()
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				cancel()
+				go drain()
+				return r.conn, nil
+			}
+
+			if first == nil {
+				first = r.err
+			}
+
+			if next < len(ips) {
+				attempt()
+			}
+		case <-delay:
+			attempt()
+		case <-ctx.Done():
+			go drain()
+			return nil, ctx.Err()
+		}
+	}
+
+	return nil, first
+}
+
+// Returns the function used to make each connection attempt.
+func (d *HappyDialer) dialFunc() func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.dial != nil {
+		return d.dial
+	}
+
+	if d.Dialer != nil {
+		return d.Dialer.DialContext
+	}
+	return (&net.Dialer{}).DialContext
+}
+
+// Orders the addresses so that they alternate between IPv6 and IPv4, starting
+// with the family of the first address returned by the resolver (which reflects
+// the preference of the system) and otherwise preserving the resolver order.
+func interleave(addrs []net.IPAddr) []net.IPAddr {
+	var first, second []net.IPAddr
+	firstV4 := addrs[0].IP.To4() != nil
+	for _, addr := range addrs {
+		if (addr.IP.To4() != nil) == firstV4 {
+			first = append(first, addr)
+		} else {
+			second = append(second, addr)
+		}
+	}
+
+	ips := make([]net.IPAddr, 0, len(addrs))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			ips = append(ips, first[i])
+		}
+		if i < len(second) {
+			ips = append(ips, second[i])
+		}
+	}
+	return ips
+}
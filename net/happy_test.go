@@ -0,0 +1,122 @@
+package net
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// Test that addresses are interleaved by family in resolver order.
+func TestInterleave(t *testing.T) {
+	addrs := []net.IPAddr{
+		{IP: net.ParseIP("::1")},
+		{IP: net.ParseIP("fe80::2"), Zone: "eth0"},
+		{IP: net.ParseIP("::3")},
+		{IP: net.ParseIP("10.0.0.1")},
+	}
+
+	expected := []string{"::1", "10.0.0.1", "fe80::2%eth0", "::3"}
+	ips := interleave(addrs)
+	if len(ips) != len(expected) {
+		t.Fatalf("expected %d addresses got %d", len(expected), len(ips))
+	}
+
+	for i, ip := range ips {
+		if ip.String() != expected[i] {
+			t.Errorf("expected %s at position %d got %s", expected[i], i, ip)
+		}
+	}
+}
+
+// Test that a failing attempt immediately starts the next attempt and that the
+// first successful connection is returned.
+func TestHappyDialer(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	dialer := &HappyDialer{
+		Stagger: time.Hour,
+		dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if addr == "[::1]:3264" {
+				return nil, errors.New("connection refused")
+			}
+			return client, nil
+		},
+	}
+
+	ips := []net.IPAddr{{IP: net.ParseIP("::1")}, {IP: net.ParseIP("127.0.0.1")}}
+	conn, err := dialer.race(context.Background(), ips, DefaultPort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn != client {
+		t.Error("expected the successful connection to be returned")
+	}
+
+	// The zone of a link-local address is dialed
+	dialer.dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if addr != "[fe80::1%eth0]:3264" {
+			return nil, errors.New("no route to host")
+		}
+		return client, nil
+	}
+
+	if conn, err = dialer.race(context.Background(), []net.IPAddr{{IP: net.ParseIP("fe80::1"), Zone: "eth0"}}, DefaultPort); err != nil || conn != client {
+		t.Errorf("expected the link-local address to be dialed with its zone: %v", err)
+	}
+
+	// If all attempts fail the first error should be returned
+	dialer.dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	if _, err = dialer.race(context.Background(), ips, DefaultPort); err == nil {
+		t.Error("expected an error when all attempts fail")
+	}
+}
+
+// Test that a slow attempt is raced against a later attempt after the stagger.
+func TestHappyDialerStagger(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	dialer := &HappyDialer{
+		Stagger: 10 * time.Millisecond,
+		dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if addr == "127.0.0.1:3264" {
+				return client, nil
+			}
+
+			// Hang until canceled by the winning attempt
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+
+	ips := []net.IPAddr{{IP: net.ParseIP("::1")}, {IP: net.ParseIP("127.0.0.1")}}
+	conn, err := dialer.race(context.Background(), ips, DefaultPort)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn != client {
+		t.Error("expected the successful connection to be returned")
+	}
+}
+
+// Test that literal IP addresses are dialed without a DNS lookup.
+func TestDialContextHappy(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	conn, err := DialContextHappy(context.Background(), "127.0.0.1", port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
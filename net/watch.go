@@ -0,0 +1,79 @@
+package net
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultWatchInterval is used by the IP watchers if the interval is not
+// positive; it keeps WatchPublicIP well within the rate limit of the service.
+const DefaultWatchInterval = time.Minute
+
+// IPChange is emitted by the IP watchers when the IP address of the machine
+// changes. The first change emitted by a watcher has an empty Previous address
+// and reports the address the watcher started with.
+type IPChange struct {
+	Previous  string    // the IP address before the change (empty on start)
+	Current   string    // the IP address after the change
+	Timestamp time.Time // when the change was detected
+}
+
+// WatchExternalIP polls ExternalIP on the specified interval and sends an
+// IPChange on the returned channel whenever the external IP address of the
+// machine changes, e.g. when a new DHCP lease is acquired. This allows
+// services to re-advertise their address to peers automatically. Errors
+// looking up the address are ignored and the previous address is kept. If the
+// interval is not positive, DefaultWatchInterval is used. The channel is closed
+// when the context is done.
+func WatchExternalIP(ctx context.Context, interval time.Duration) <-chan IPChange {
+	return watchIP(ctx, interval, ExternalIP)
+}
+
+// WatchPublicIP polls PublicIP on the specified interval and sends an IPChange
+// on the returned channel whenever the publicly available IP address of the
+// machine changes. See WatchExternalIP for more details.
+//
+// NOTE: the PublicIP service is rate limited to 30 requests per minute so the
+// interval should not be less than a few seconds.
+func WatchPublicIP(ctx context.Context, interval time.Duration) <-chan IPChange {
+	return watchIP(ctx, interval, PublicIP)
+}
+
+// Polls the lookup function and sends changes to the IP address on the
+// returned channel until the context is done.
+func watchIP(ctx context.Context, interval time.Duration, lookup func() (string, error)) <-chan IPChange {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	changes := make(chan IPChange, 1)
+
+	go func() {
+		defer close(changes)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var current string
+		for {
+			if ip, err := lookup(); err == nil && ip != current {
+				change := IPChange{Previous: current, Current: ip, Timestamp: time.Now()}
+				current = ip
+
+				select {
+				case changes <- change:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return changes
+}
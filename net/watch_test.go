@@ -0,0 +1,72 @@
+package net
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Test that the watcher only emits changes when the IP address changes.
+func TestWatchIP(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	lookups := make(chan string, 8)
+	for _, ip := range []string{"10.0.0.1", "10.0.0.1", "", "10.0.0.2", "10.0.0.2"} {
+		lookups <- ip
+	}
+
+	lookup := func() (string, error) {
+		select {
+		case ip := <-lookups:
+			if ip == "" {
+				return "", errors.New("not connected")
+			}
+			return ip, nil
+		default:
+			return "10.0.0.2", nil
+		}
+	}
+
+	changes := watchIP(ctx, time.Millisecond, lookup)
+	expected := []IPChange{{Previous: "", Current: "10.0.0.1"}, {Previous: "10.0.0.1", Current: "10.0.0.2"}}
+	for _, exp := range expected {
+		select {
+		case change := <-changes:
+			if change.Previous != exp.Previous || change.Current != exp.Current {
+				t.Errorf("expected change %q -> %q got %q -> %q", exp.Previous, exp.Current, change.Previous, change.Current)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for IP change")
+		}
+	}
+
+	// No more changes should be emitted and the channel closed on cancel
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	for change := range changes {
+		t.Errorf("unexpected change %q -> %q", change.Previous, change.Current)
+	}
+}
+
+// Test that a non-positive interval uses the default rather than panicking.
+func TestWatchIPInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		ctx, cancel := context.WithCancel(context.Background())
+		changes := watchIP(ctx, interval, func() (string, error) { return "10.0.0.1", nil })
+
+		select {
+		case change := <-changes:
+			if change.Current != "10.0.0.1" {
+				t.Errorf("unexpected change to %q", change.Current)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for the initial address with interval %s", interval)
+		}
+
+		cancel()
+		for range changes {
+		}
+	}
+}
@@ -0,0 +1,401 @@
+package peers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Defaults for mDNS discovery if not specified by the DiscoverOptions.
+const (
+	DefaultService         = "_peers._tcp"
+	DefaultDomain          = "local."
+	DefaultDiscoverTimeout = 2 * time.Second
+	mdnsAddr               = "224.0.0.251:5353"
+)
+
+//===========================================================================
+// mDNS Network Discovery
+//===========================================================================
+
+// DiscoverOptions configures how peers are discovered on the local network.
+type DiscoverOptions struct {
+	Service string        // the DNS-SD service type to browse for, e.g. "_raft._tcp"
+	Domain  string        // the mDNS domain, "local." by default
+	Timeout time.Duration // how long to wait for responses
+	Addr    string        // the mDNS multicast address, 224.0.0.251:5353 by default
+}
+
+// Discover browses the local network with mDNS (Zeroconf/Bonjour) for instances
+// of the service and returns a peer for each instance that responds with its
+// address and port, sorted by name. The peer name is the instance name, the
+// hostname is the target of the SRV record, and the pid is read from the
+// "pid=" TXT record if advertised (otherwise it is zero). Discovery stops when
+// the timeout expires or the context is done.
+func Discover(ctx context.Context, opts *DiscoverOptions) (_ []*Peer, err error) {
+	if opts == nil {
+		opts = &DiscoverOptions{}
+	}
+
+	service := strings.Trim(opts.Service, ".")
+	if service == "" {
+		service = DefaultService
+	}
+
+	domain := strings.Trim(opts.Domain, ".")
+	if domain == "" {
+		domain = strings.Trim(DefaultDomain, ".")
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultDiscoverTimeout
+	}
+
+	addr := opts.Addr
+	if addr == "" {
+		addr = mdnsAddr
+	}
+
+	var group *net.UDPAddr
+	if group, err = net.ResolveUDPAddr("udp4", addr); err != nil {
+		return nil, err
+	}
+
+	// Queries are sent from an ephemeral port, which asks responders to reply
+	// directly to this socket rather than to the multicast group.
+	var conn *net.UDPConn
+	if conn, err = net.ListenUDP("udp4", &net.UDPAddr{}); err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetReadDeadline(deadline)
+
+	// Unblock the read if the context is cancelled
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetReadDeadline(time.Now())
+		case <-stop:
+		}
+	}()
+
+	b := newBrowser(service + "." + domain + ".")
+	if err = b.query(conn, group, b.service, dnsmessage.TypePTR); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 9000)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			var nerr net.Error
+			if errors.As(err, &nerr) && nerr.Timeout() {
+				break
+			}
+			return nil, err
+		}
+
+		// Ask for the records that were not included in the response
+		for _, q := range b.handle(buf[:n]) {
+			b.query(conn, group, q.name, q.qtype)
+		}
+	}
+
+	if err = ctx.Err(); err != nil && err != context.DeadlineExceeded {
+		return nil, err
+	}
+	return b.peers(), nil
+}
+
+// Discover browses the local network for peers as described by the Discover
+// function and merges them into the collection. Discovered peers that are
+// already in the collection (by name) have their address, port, and hostname
+// updated; new peers are added, assigning a pid that does not conflict with the
+// existing peers if the pid was not advertised. Discovered peers are marked as
+// seen. If the collection would not be valid after the merge it is not modified
+// and the validation error is returned.
+func (p *Peers) Discover(ctx context.Context, opts *DiscoverOptions) (added int, err error) {
+	var discovered []*Peer
+	if discovered, err = Discover(ctx, opts); err != nil {
+		return 0, err
+	}
+	return p.merge(discovered)
+}
+
+// Merges the discovered peers into the collection, returning the number added.
+func (p *Peers) merge(discovered []*Peer) (added int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var maxPID uint32
+	for _, peer := range p.Peers {
+		if peer != nil && peer.PID > maxPID {
+			maxPID = peer.PID
+		}
+	}
+
+	orig := p.Peers
+	peers := make([]*Peer, len(orig), len(orig)+len(discovered))
+	copy(peers, orig)
+
+	now := time.Now()
+	p.Peers = peers
+	for _, found := range discovered {
+		if idx := p.index(found.Name); idx >= 0 {
+			// Replace rather than modify the peer so that snapshots are unchanged
+			peer := *p.Peers[idx]
+			peer.IPAddr, peer.Port = found.IPAddr, found.Port
+			if found.Hostname != "" {
+				peer.Hostname = found.Hostname
+			}
+			peer.LastSeen = &now
+			p.Peers[idx] = &peer
+			continue
+		}
+
+		peer := *found
+		peer.LastSeen = &now
+		if peer.PID == 0 {
+			maxPID++
+			peer.PID = maxPID
+		} else if peer.PID > maxPID {
+			maxPID = peer.PID
+		}
+
+		p.Peers = append(p.Peers, &peer)
+		added++
+	}
+
+	if err = p.validate(); err != nil {
+		p.Peers = orig
+		return 0, err
+	}
+
+	p.revive()
+	return added, nil
+}
+
+//===========================================================================
+// mDNS Browser
+//===========================================================================
+
+// Accumulates the records of mDNS responses for the instances of a service.
+type browser struct {
+	service   string
+	instances map[string]*instanceRecords
+	addrs     map[string]net.IP // ipv4 (preferred) or ipv6 addresses of hosts
+	asked     map[question]bool // follow up queries that have already been sent
+}
+
+// The records of a service instance.
+type instanceRecords struct {
+	name   string
+	target string
+	port   uint16
+	txt    map[string]string
+}
+
+// A follow up query for missing records.
+type question struct {
+	name  string
+	qtype dnsmessage.Type
+}
+
+func newBrowser(service string) *browser {
+	return &browser{
+		service:   service,
+		instances: make(map[string]*instanceRecords),
+		addrs:     make(map[string]net.IP),
+		asked:     make(map[question]bool),
+	}
+}
+
+// Sends an mDNS query for the name and type.
+func (b *browser) query(conn *net.UDPConn, group *net.UDPAddr, name string, qtype dnsmessage.Type) (err error) {
+	var qname dnsmessage.Name
+	if qname, err = dnsmessage.NewName(name); err != nil {
+		return err
+	}
+
+	msg := dnsmessage.Message{
+		Questions: []dnsmessage.Question{{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+
+	var data []byte
+	if data, err = msg.Pack(); err != nil {
+		return err
+	}
+
+	_, err = conn.WriteToUDP(data, group)
+	return err
+}
+
+// Records the resources in the answer and additional sections of the response
+// and returns the follow up queries for records that are still missing.
+// Malformed messages and unsupported record types are ignored.
+func (b *browser) handle(msg []byte) []question {
+	var p dnsmessage.Parser
+	if hdr, err := p.Start(msg); err != nil || !hdr.Response {
+		return nil
+	}
+
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil
+	}
+
+	b.section(p.AnswerHeader, p.SkipAnswer, &p)
+	if err := p.SkipAllAuthorities(); err == nil {
+		b.section(p.AdditionalHeader, p.SkipAdditional, &p)
+	}
+	return b.missing()
+}
+
+// Records the supported resources of a section of the message.
+func (b *browser) section(next func() (dnsmessage.ResourceHeader, error), skip func() error, p *dnsmessage.Parser) {
+	for {
+		hdr, err := next()
+		if err != nil {
+			return
+		}
+
+		name := strings.ToLower(hdr.Name.String())
+		switch hdr.Type {
+		case dnsmessage.TypePTR:
+			if rr, err := p.PTRResource(); err == nil && name == strings.ToLower(b.service) {
+				b.instance(rr.PTR.String())
+			}
+		case dnsmessage.TypeSRV:
+			if rr, err := p.SRVResource(); err == nil && b.isInstance(name) {
+				inst := b.instance(hdr.Name.String())
+				inst.target, inst.port = strings.ToLower(rr.Target.String()), rr.Port
+			}
+		case dnsmessage.TypeTXT:
+			if rr, err := p.TXTResource(); err == nil && b.isInstance(name) {
+				inst := b.instance(hdr.Name.String())
+				for _, kv := range rr.TXT {
+					parts := strings.SplitN(kv, "=", 2)
+					if len(parts) == 2 {
+						inst.txt[strings.ToLower(parts[0])] = parts[1]
+					}
+				}
+			}
+		case dnsmessage.TypeA:
+			if rr, err := p.AResource(); err == nil {
+				b.addrs[name] = net.IP(rr.A[:])
+			}
+		case dnsmessage.TypeAAAA:
+			if rr, err := p.AAAAResource(); err == nil {
+				if _, ok := b.addrs[name]; !ok {
+					b.addrs[name] = net.IP(rr.AAAA[:])
+				}
+			}
+		default:
+			if err := skip(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Returns true if the name is an instance of the service.
+func (b *browser) isInstance(name string) bool {
+	return strings.HasSuffix(name, "."+strings.ToLower(b.service))
+}
+
+// Returns the records of the named instance, creating them if necessary.
+func (b *browser) instance(name string) *instanceRecords {
+	key := strings.ToLower(name)
+	inst, ok := b.instances[key]
+	if !ok {
+		inst = &instanceRecords{name: name, txt: make(map[string]string)}
+		b.instances[key] = inst
+	}
+	return inst
+}
+
+// Returns the queries for the SRV, TXT, and address records that are missing
+// and have not been asked for yet.
+func (b *browser) missing() []question {
+	queries := make([]question, 0)
+	ask := func(q question) {
+		if !b.asked[q] {
+			b.asked[q] = true
+			queries = append(queries, q)
+		}
+	}
+
+	for name, inst := range b.instances {
+		if inst.target == "" {
+			ask(question{name, dnsmessage.TypeSRV})
+			ask(question{name, dnsmessage.TypeTXT})
+		} else if _, ok := b.addrs[inst.target]; !ok {
+			ask(question{inst.target, dnsmessage.TypeA})
+		}
+	}
+	return queries
+}
+
+// Returns a peer for every instance with an address and port, sorted by name.
+func (b *browser) peers() []*Peer {
+	peers := make([]*Peer, 0, len(b.instances))
+	suffix := "." + strings.ToLower(b.service)
+	for _, inst := range b.instances {
+		ip, ok := b.addrs[inst.target]
+		if !ok || inst.port == 0 {
+			continue
+		}
+
+		peer := &Peer{
+			Name:        unescape(inst.name[:len(inst.name)-len(suffix)]),
+			Hostname:    strings.TrimSuffix(inst.target, "."),
+			IPAddr:      ip.String(),
+			Port:        inst.port,
+			Description: inst.txt["description"],
+		}
+
+		if pid, err := strconv.ParseUint(inst.txt["pid"], 10, 32); err == nil {
+			peer.PID = uint32(pid)
+		}
+		peers = append(peers, peer)
+	}
+
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Name < peers[j].Name })
+	return peers
+}
+
+// Unescapes a DNS label, e.g. "My\ Service" or "My\032Service".
+func unescape(label string) string {
+	var sb strings.Builder
+	for i := 0; i < len(label); i++ {
+		if label[i] != '\\' || i+1 == len(label) {
+			sb.WriteByte(label[i])
+			continue
+		}
+
+		if i+3 < len(label) {
+			if n, err := strconv.Atoi(label[i+1 : i+4]); err == nil && n < 256 {
+				sb.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+
+		sb.WriteByte(label[i+1])
+		i++
+	}
+	return sb.String()
+}
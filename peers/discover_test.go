@@ -0,0 +1,132 @@
+package peers
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Runs a fake mDNS responder on the loopback interface that advertises two
+// instances of the service. The first query only receives the PTR records so
+// that the browser must follow up for the SRV, TXT, and A records.
+func fakeResponder(t *testing.T) string {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	name := func(s string) dnsmessage.Name { return dnsmessage.MustNewName(s) }
+	hdr := func(s string, rtype dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name(s), Type: rtype, Class: dnsmessage.ClassINET | 1<<15, TTL: 120}
+	}
+
+	go func() {
+		buf := make([]byte, 9000)
+		for {
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+
+			resp := dnsmessage.Message{Header: dnsmessage.Header{Response: true, Authoritative: true}}
+			q := query.Questions[0]
+			switch {
+			case q.Type == dnsmessage.TypePTR && q.Name.String() == "_raft._tcp.local.":
+				resp.Answers = []dnsmessage.Resource{
+					{Header: hdr("_raft._tcp.local.", dnsmessage.TypePTR), Body: &dnsmessage.PTRResource{PTR: name(`Alpha\ Node._raft._tcp.local.`)}},
+					{Header: hdr("_raft._tcp.local.", dnsmessage.TypePTR), Body: &dnsmessage.PTRResource{PTR: name("bravo._raft._tcp.local.")}},
+				}
+
+				// Bravo includes all of its records in the additional section
+				resp.Additionals = []dnsmessage.Resource{
+					{Header: hdr("bravo._raft._tcp.local.", dnsmessage.TypeSRV), Body: &dnsmessage.SRVResource{Target: name("bravo.local."), Port: 3265}},
+					{Header: hdr("bravo._raft._tcp.local.", dnsmessage.TypeTXT), Body: &dnsmessage.TXTResource{TXT: []string{"pid=7"}}},
+					{Header: hdr("bravo.local.", dnsmessage.TypeA), Body: &dnsmessage.AResource{A: [4]byte{10, 10, 10, 2}}},
+				}
+			case q.Type == dnsmessage.TypeSRV && q.Name.String() == `alpha\ node._raft._tcp.local.`:
+				resp.Answers = []dnsmessage.Resource{
+					{Header: hdr(`Alpha\ Node._raft._tcp.local.`, dnsmessage.TypeSRV), Body: &dnsmessage.SRVResource{Target: name("alpha.local."), Port: 3264}},
+				}
+				resp.Additionals = []dnsmessage.Resource{
+					{Header: hdr("alpha.local.", dnsmessage.TypeA), Body: &dnsmessage.AResource{A: [4]byte{10, 10, 10, 1}}},
+				}
+			default:
+				continue
+			}
+
+			data, err := resp.Pack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			conn.WriteToUDP(data, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+// Test that peers are discovered from mDNS responses.
+func TestDiscover(t *testing.T) {
+	addr := fakeResponder(t)
+	opts := &DiscoverOptions{Service: "_raft._tcp", Timeout: 250 * time.Millisecond, Addr: addr}
+
+	peers, err := Discover(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(peers) != 2 {
+		t.Fatalf("expected 2 discovered peers but got %d", len(peers))
+	}
+
+	alpha, bravo := peers[0], peers[1]
+	if alpha.Name != "Alpha Node" || alpha.Endpoint(false) != "10.10.10.1:3264" || alpha.Hostname != "alpha.local" || alpha.PID != 0 {
+		t.Errorf("unexpected discovered peer %+v", alpha)
+	}
+
+	if bravo.Name != "bravo" || bravo.Endpoint(false) != "10.10.10.2:3265" || bravo.PID != 7 {
+		t.Errorf("unexpected discovered peer %+v", bravo)
+	}
+
+	// Discovered peers are merged into the collection
+	roster := new(Peers)
+	roster.Add(&Peer{PID: 7, Name: "bravo", IPAddr: "10.10.10.9", Port: 3264, Description: "original"})
+
+	added, err := roster.Discover(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if added != 1 || roster.Len() != 2 {
+		t.Errorf("expected alpha to be added but added %d", added)
+	}
+
+	if peer, _ := roster.Get("bravo"); peer.IPAddr != "10.10.10.2" || peer.Description != "original" {
+		t.Errorf("expected bravo address to be updated but got %+v", peer)
+	}
+
+	if peer, _ := roster.Get("Alpha Node"); peer.PID != 8 {
+		t.Errorf("expected alpha to be assigned pid 8 but got %d", peer.PID)
+	}
+}
+
+// Test that discovery stops when the context is cancelled.
+func TestDiscoverCancel(t *testing.T) {
+	addr := fakeResponder(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := Discover(ctx, &DiscoverOptions{Timeout: time.Minute, Addr: addr}); err != context.Canceled {
+		t.Errorf("expected context canceled error but got %v", err)
+	}
+}
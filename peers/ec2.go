@@ -0,0 +1,507 @@
+package peers
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version of the EC2 Query API used to describe instances.
+const ec2APIVersion = "2016-11-15"
+
+// Environment variables used to query the EC2 API, the same variables that are
+// used by the AWS CLI and SDKs.
+const (
+	envAWSAccessKey    = "AWS_ACCESS_KEY_ID"
+	envAWSSecretKey    = "AWS_SECRET_ACCESS_KEY"
+	envAWSSessionToken = "AWS_SESSION_TOKEN"
+	envAWSRegion       = "AWS_REGION"
+	envAWSDefRegion    = "AWS_DEFAULT_REGION"
+	envAWSProfile      = "AWS_PROFILE"
+	envAWSCredentials  = "AWS_SHARED_CREDENTIALS_FILE"
+	envAWSEndpoint     = "AWS_ENDPOINT_URL_EC2"
+	envIMDSEndpoint    = "AWS_EC2_METADATA_SERVICE_ENDPOINT"
+	envIMDSDisabled    = "AWS_EC2_METADATA_DISABLED"
+)
+
+// The instance metadata service (IMDSv2) is queried for the region and the
+// credentials of the instance role when they are not otherwise configured.
+const (
+	defaultIMDSEndpoint = "http://169.254.169.254"
+	imdsTokenTTL        = "21600"
+	imdsTimeout         = time.Second
+)
+
+//===========================================================================
+// EC2 Inventory
+//===========================================================================
+
+// ImportEC2 queries the EC2 API for the running instances that have all of the
+// specified tags and values, e.g. {"cluster": "raft"}, and adds a peer for each
+// of them, populating the AWSInstance field of the peer with the instance id,
+// type, region, availability zone, and addresses. The tag filters are applied
+// by the API and the tags are mapped to peer fields with DefaultFields.
+//
+// Requests are signed with the credentials in $AWS_ACCESS_KEY_ID,
+// $AWS_SECRET_ACCESS_KEY, and $AWS_SESSION_TOKEN or, if they are not set, the
+// $AWS_PROFILE (or default) profile of the shared credentials file. The region
+// is read from $AWS_REGION or $AWS_DEFAULT_REGION and the endpoint can be
+// overridden with $AWS_ENDPOINT_URL_EC2, e.g. for a local test stack. On an EC2
+// instance without a region or credentials file, the region and the credentials
+// of the instance role are read from the instance metadata service (IMDSv2)
+// unless $AWS_EC2_METADATA_DISABLED is true.
+func (p *Peers) ImportEC2(ctx context.Context, filters map[string]string) (err error) {
+	var client *ec2Client
+	if client, err = newEC2Client(ctx); err != nil {
+		return err
+	}
+
+	var instances []*instance
+	if instances, err = client.describeInstances(ctx, filters); err != nil {
+		return err
+	}
+	return p.importInstances(instances, nil)
+}
+
+// Queries the EC2 Query API with requests signed with AWS signature version 4.
+type ec2Client struct {
+	endpoint *url.URL
+	region   string
+	creds    awsCredentials
+	client   *http.Client
+}
+
+// AWS access keys used to sign requests.
+type awsCredentials struct {
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+// Creates an EC2 client from the region, credentials, and endpoint in the
+// environment, falling back to the instance metadata service.
+func newEC2Client(ctx context.Context) (_ *ec2Client, err error) {
+	c := &ec2Client{
+		region: os.Getenv(envAWSRegion),
+		client: &http.Client{Timeout: DefaultSyncTimeout},
+	}
+
+	if c.region == "" {
+		c.region = os.Getenv(envAWSDefRegion)
+	}
+
+	if c.region == "" {
+		var meta *imdsClient
+		if meta, err = newIMDSClient(ctx); err == nil {
+			c.region, err = meta.get(ctx, "placement/region")
+		}
+
+		if err != nil || c.region == "" {
+			return nil, errors.New("could not find $AWS_REGION or $AWS_DEFAULT_REGION")
+		}
+	}
+
+	if c.creds, err = loadAWSCredentials(ctx); err != nil {
+		return nil, err
+	}
+
+	endpoint := os.Getenv(envAWSEndpoint)
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://ec2.%s.amazonaws.com/", c.region)
+	}
+
+	if c.endpoint, err = url.Parse(endpoint); err != nil {
+		return nil, fmt.Errorf("could not parse ec2 endpoint: %s", err)
+	}
+	return c, nil
+}
+
+// Describes the running instances with the tags, following the next token until
+// all of the instances have been described.
+func (c *ec2Client) describeInstances(ctx context.Context, filters map[string]string) (instances []*instance, err error) {
+	query := url.Values{}
+	query.Set("Action", "DescribeInstances")
+	query.Set("Version", ec2APIVersion)
+	query.Set("Filter.1.Name", "instance-state-name")
+	query.Set("Filter.1.Value.1", "running")
+
+	// Filters are numbered in key order so that requests are deterministic
+	keys := make([]string, 0, len(filters))
+	for key := range filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for i, key := range keys {
+		n := strconv.Itoa(i + 2)
+		query.Set("Filter."+n+".Name", "tag:"+key)
+		query.Set("Filter."+n+".Value.1", filters[key])
+	}
+
+	instances = make([]*instance, 0)
+	for {
+		var out *ec2DescribeInstances
+		if out, err = c.do(ctx, query); err != nil {
+			return nil, err
+		}
+
+		for _, reservation := range out.Reservations {
+			for _, ec2 := range reservation.Instances {
+				instances = append(instances, ec2.instance(c.region))
+			}
+		}
+
+		if out.NextToken == "" {
+			return instances, nil
+		}
+		query.Set("NextToken", out.NextToken)
+	}
+}
+
+// Executes a signed GET request with the query, parsing the response.
+func (c *ec2Client) do(ctx context.Context, query url.Values) (_ *ec2DescribeInstances, err error) {
+	endpoint := *c.endpoint
+	endpoint.RawQuery = canonicalQuery(query)
+
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil); err != nil {
+		return nil, err
+	}
+	c.sign(req, time.Now().UTC())
+
+	var rep *http.Response
+	if rep, err = c.client.Do(req); err != nil {
+		return nil, fmt.Errorf("could not query ec2: %s", err)
+	}
+	defer rep.Body.Close()
+
+	var body []byte
+	if body, err = ioutil.ReadAll(rep.Body); err != nil {
+		return nil, err
+	}
+
+	if rep.StatusCode != http.StatusOK {
+		var ec2err struct {
+			Errors []struct {
+				Code    string `xml:"Code"`
+				Message string `xml:"Message"`
+			} `xml:"Errors>Error"`
+		}
+
+		if xml.Unmarshal(body, &ec2err) == nil && len(ec2err.Errors) > 0 {
+			return nil, fmt.Errorf("could not query ec2: %s: %s", ec2err.Errors[0].Code, ec2err.Errors[0].Message)
+		}
+		return nil, fmt.Errorf("could not query ec2: %s", rep.Status)
+	}
+
+	out := new(ec2DescribeInstances)
+	if err = xml.Unmarshal(body, out); err != nil {
+		return nil, fmt.Errorf("could not parse ec2 instances: %s", err)
+	}
+	return out, nil
+}
+
+// Signs the request with AWS signature version 4, see
+// https://docs.aws.amazon.com/general/latest/gr/sigv4_signing.html
+func (c *ec2Client) sign(req *http.Request, now time.Time) {
+	amzdate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	scope := strings.Join([]string{date, c.region, "ec2", "aws4_request"}, "/")
+
+	req.Header.Set("X-Amz-Date", amzdate)
+	headers := []string{"host:" + req.URL.Host, "x-amz-date:" + amzdate}
+	signed := "host;x-amz-date"
+
+	if c.creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.creds.sessionToken)
+		headers = append(headers, "x-amz-security-token:"+c.creds.sessionToken)
+		signed += ";x-amz-security-token"
+	}
+
+	canonical := strings.Join([]string{
+		req.Method,
+		"/" + strings.TrimPrefix(req.URL.EscapedPath(), "/"),
+		req.URL.RawQuery,
+		strings.Join(headers, "\n") + "\n",
+		signed,
+		hexSHA256(""),
+	}, "\n")
+
+	toSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzdate, scope, hexSHA256(canonical)}, "\n")
+
+	key := []byte("AWS4" + c.creds.secretKey)
+	for _, part := range []string{date, c.region, "ec2", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.creds.accessKey, scope, signed, signature,
+	))
+}
+
+// Loads AWS credentials from the environment or from the shared credentials file
+// or, if there is no credentials file, from the instance role.
+func loadAWSCredentials(ctx context.Context) (creds awsCredentials, err error) {
+	creds = awsCredentials{
+		accessKey:    os.Getenv(envAWSAccessKey),
+		secretKey:    os.Getenv(envAWSSecretKey),
+		sessionToken: os.Getenv(envAWSSessionToken),
+	}
+
+	if creds.accessKey != "" && creds.secretKey != "" {
+		return creds, nil
+	}
+
+	path := os.Getenv(envAWSCredentials)
+	if path == "" {
+		var home string
+		if home, err = os.UserHomeDir(); err != nil {
+			return creds, errors.New("could not find aws credentials")
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+
+	profile := os.Getenv(envAWSProfile)
+	if profile == "" {
+		profile = "default"
+	}
+
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		if creds, err = roleCredentials(ctx); err != nil {
+			return creds, errors.New("could not find aws credentials")
+		}
+		return creds, nil
+	}
+	defer f.Close()
+
+	// Parse the keys of the profile section of the ini file
+	creds = awsCredentials{}
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		if section != profile {
+			continue
+		}
+
+		if parts := strings.SplitN(line, "=", 2); len(parts) == 2 {
+			val := strings.TrimSpace(parts[1])
+			switch strings.TrimSpace(parts[0]) {
+			case "aws_access_key_id":
+				creds.accessKey = val
+			case "aws_secret_access_key":
+				creds.secretKey = val
+			case "aws_session_token":
+				creds.sessionToken = val
+			}
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return creds, err
+	}
+
+	if creds.accessKey == "" || creds.secretKey == "" {
+		return creds, fmt.Errorf("could not find aws credentials for profile '%s' in %s", profile, path)
+	}
+	return creds, nil
+}
+
+// Loads the temporary credentials of the instance role from the instance
+// metadata service.
+func roleCredentials(ctx context.Context) (creds awsCredentials, err error) {
+	var meta *imdsClient
+	if meta, err = newIMDSClient(ctx); err != nil {
+		return creds, err
+	}
+
+	var role string
+	if role, err = meta.get(ctx, "iam/security-credentials/"); err != nil {
+		return creds, err
+	}
+
+	// The first line is the name of the instance role
+	if role = strings.TrimSpace(strings.SplitN(role, "\n", 2)[0]); role == "" {
+		return creds, errors.New("instance does not have a role")
+	}
+
+	var data string
+	if data, err = meta.get(ctx, "iam/security-credentials/"+role); err != nil {
+		return creds, err
+	}
+
+	var out struct {
+		Code            string `json:"Code"`
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string `json:"SecretAccessKey"`
+		Token           string `json:"Token"`
+	}
+
+	if err = json.Unmarshal([]byte(data), &out); err != nil {
+		return creds, fmt.Errorf("could not parse instance role credentials: %s", err)
+	}
+
+	if out.Code != "Success" || out.AccessKeyID == "" || out.SecretAccessKey == "" {
+		return creds, fmt.Errorf("could not get credentials for instance role %q: %s", role, out.Code)
+	}
+
+	return awsCredentials{accessKey: out.AccessKeyID, secretKey: out.SecretAccessKey, sessionToken: out.Token}, nil
+}
+
+// Queries the instance metadata service with an IMDSv2 session token.
+type imdsClient struct {
+	endpoint string
+	token    string
+	client   *http.Client
+}
+
+// Fetches a session token from the instance metadata service at the endpoint in
+// $AWS_EC2_METADATA_SERVICE_ENDPOINT or the link-local default. The timeout is
+// short so that hosts that are not EC2 instances fail quickly.
+func newIMDSClient(ctx context.Context) (_ *imdsClient, err error) {
+	if disabled, _ := strconv.ParseBool(os.Getenv(envIMDSDisabled)); disabled {
+		return nil, errors.New("instance metadata service is disabled")
+	}
+
+	c := &imdsClient{
+		endpoint: strings.TrimSuffix(os.Getenv(envIMDSEndpoint), "/"),
+		client:   &http.Client{Timeout: imdsTimeout},
+	}
+
+	if c.endpoint == "" {
+		c.endpoint = defaultIMDSEndpoint
+	}
+
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPut, c.endpoint+"/latest/api/token", nil); err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", imdsTokenTTL)
+
+	if c.token, err = c.do(req); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Gets the instance metadata at the path relative to /latest/meta-data/.
+func (c *imdsClient) get(ctx context.Context, path string) (_ string, err error) {
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+"/latest/meta-data/"+path, nil); err != nil {
+		return "", err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", c.token)
+	return c.do(req)
+}
+
+// Executes the request, returning the body of the response.
+func (c *imdsClient) do(req *http.Request) (_ string, err error) {
+	var rep *http.Response
+	if rep, err = c.client.Do(req); err != nil {
+		return "", fmt.Errorf("could not query instance metadata: %s", err)
+	}
+	defer rep.Body.Close()
+
+	var body []byte
+	if body, err = ioutil.ReadAll(rep.Body); err != nil {
+		return "", err
+	}
+
+	if rep.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not query instance metadata: %s", rep.Status)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// Encodes the query as required by signature version 4: keys are sorted and
+// spaces are encoded as %20 rather than +.
+func canonicalQuery(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+func hexSHA256(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// The DescribeInstances response of the EC2 Query API.
+type ec2DescribeInstances struct {
+	Reservations []struct {
+		Instances []ec2Instance `xml:"instancesSet>item"`
+	} `xml:"reservationSet>item"`
+	NextToken string `xml:"nextToken"`
+}
+
+// An instance in the DescribeInstances response of the EC2 Query API.
+type ec2Instance struct {
+	InstanceID       string `xml:"instanceId"`
+	InstanceType     string `xml:"instanceType"`
+	State            string `xml:"instanceState>name"`
+	PrivateIPAddress string `xml:"privateIpAddress"`
+	PublicIPAddress  string `xml:"ipAddress"`
+	PrivateDNSName   string `xml:"privateDnsName"`
+	PublicDNSName    string `xml:"dnsName"`
+	AvailabilityZone string `xml:"placement>availabilityZone"`
+	Tags             []struct {
+		Key   string `xml:"key"`
+		Value string `xml:"value"`
+	} `xml:"tagSet>item"`
+}
+
+// Converts the EC2 instance in the region into an instance to import.
+func (ec2 ec2Instance) instance(region string) *instance {
+	inst := &instance{
+		name:      ec2.InstanceID,
+		hostname:  ec2.PrivateDNSName,
+		privateIP: ec2.PrivateIPAddress,
+		publicIP:  ec2.PublicIPAddress,
+		tags:      make(map[string]string, len(ec2.Tags)),
+	}
+
+	for _, tag := range ec2.Tags {
+		inst.tags[tag.Key] = tag.Value
+	}
+
+	inst.aws = awsInstance(
+		ec2.InstanceID, ec2.InstanceType, ec2.AvailabilityZone,
+		ec2.PrivateIPAddress, ec2.PublicIPAddress, ec2.PrivateDNSName, ec2.PublicDNSName,
+	)
+
+	if _, ok := inst.aws["region"]; !ok && region != "" {
+		inst.aws["region"] = region
+	}
+	return inst
+}
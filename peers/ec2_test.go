@@ -0,0 +1,227 @@
+package peers
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test importing running instances by querying the ec2 api.
+func TestImportEC2(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		query := r.URL.Query()
+		if query.Get("Action") != "DescribeInstances" || query.Get("Filter.2.Name") != "tag:cluster" || query.Get("Filter.2.Value.1") != "raft" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") || !strings.Contains(auth, "/us-east-1/ec2/aws4_request") {
+			t.Errorf("unexpected authorization header %q", auth)
+		}
+
+		if r.Header.Get("X-Amz-Security-Token") != "token" {
+			t.Error("expected the session token to be sent")
+		}
+
+		page := "testdata/ec2-1.xml"
+		if token := query.Get("NextToken"); token != "" {
+			if token != "page/2+" {
+				t.Errorf("unexpected next token %q", token)
+			}
+			page = "testdata/ec2-2.xml"
+		}
+
+		data, err := ioutil.ReadFile(page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	t.Setenv(envAWSEndpoint, srv.URL)
+	t.Setenv(envAWSRegion, "us-east-1")
+	t.Setenv(envAWSAccessKey, "AKIDEXAMPLE")
+	t.Setenv(envAWSSecretKey, "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+	t.Setenv(envAWSSessionToken, "token")
+
+	peers := new(Peers)
+	if err := peers.ImportEC2(context.Background(), map[string]string{"cluster": "raft"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if requests != 2 {
+		t.Errorf("expected 2 pages to be requested, got %d", requests)
+	}
+
+	if len(peers.Peers) != 2 {
+		t.Fatalf("expected 2 peers imported got %d", len(peers.Peers))
+	}
+
+	alpha, bravo := peers.Peers[0], peers.Peers[1]
+	if alpha.Name != "alpha" || alpha.PID != 1 || alpha.IPAddr != "172.31.10.1" || alpha.Port != DefaultPort {
+		t.Errorf("unexpected alpha peer: %+v", alpha)
+	}
+
+	if alpha.AWSInstance["instance_id"] != "i-0a1b2c3d4e5f60001" || alpha.AWSInstance["public_ip"] != "54.10.10.1" || alpha.Region() != "us-east-1" {
+		t.Errorf("unexpected alpha aws instance: %v", alpha.AWSInstance)
+	}
+
+	if bravo.Name != "bravo" || bravo.PID != 2 || bravo.Port != 3265 || bravo.Hostname != "ip-172-31-10-2.ec2.internal" {
+		t.Errorf("unexpected bravo peer: %+v", bravo)
+	}
+}
+
+// Test that errors from the ec2 api are returned.
+func TestImportEC2Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`<Response><Errors><Error><Code>AuthFailure</Code><Message>AWS was not able to validate the provided access credentials</Message></Error></Errors></Response>`))
+	}))
+	defer srv.Close()
+
+	t.Setenv(envAWSEndpoint, srv.URL)
+	t.Setenv(envAWSRegion, "")
+	t.Setenv(envAWSDefRegion, "us-west-2")
+	t.Setenv(envAWSAccessKey, "AKIDEXAMPLE")
+	t.Setenv(envAWSSecretKey, "secret")
+
+	err := new(Peers).ImportEC2(context.Background(), nil)
+	if err == nil || !strings.Contains(err.Error(), "AuthFailure") {
+		t.Errorf("expected an auth failure, got %v", err)
+	}
+
+	t.Setenv(envAWSDefRegion, "")
+	t.Setenv(envIMDSDisabled, "true")
+	if err = new(Peers).ImportEC2(context.Background(), nil); err == nil {
+		t.Error("expected an error without a region")
+	}
+}
+
+// Test that the region and the credentials of the instance role are read from
+// the instance metadata service with an IMDSv2 session token.
+func TestImportEC2Metadata(t *testing.T) {
+	imds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			if r.Method != http.MethodPut || r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") == "" {
+				t.Errorf("unexpected token request %s %v", r.Method, r.Header)
+			}
+			w.Write([]byte("imdstoken"))
+			return
+		}
+
+		if r.Header.Get("X-aws-ec2-metadata-token") != "imdstoken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/latest/meta-data/placement/region":
+			w.Write([]byte("us-east-1"))
+		case "/latest/meta-data/iam/security-credentials/":
+			w.Write([]byte("raft-role\n"))
+		case "/latest/meta-data/iam/security-credentials/raft-role":
+			w.Write([]byte(`{"Code": "Success", "AccessKeyId": "AKIDROLE", "SecretAccessKey": "role", "Token": "roletoken"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer imds.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDROLE/") || !strings.Contains(auth, "/us-east-1/ec2/aws4_request") {
+			t.Errorf("unexpected authorization header %q", auth)
+		}
+
+		if r.Header.Get("X-Amz-Security-Token") != "roletoken" {
+			t.Error("expected the role session token to be sent")
+		}
+		w.Write([]byte(`<DescribeInstancesResponse><reservationSet/></DescribeInstancesResponse>`))
+	}))
+	defer srv.Close()
+
+	t.Setenv(envAWSEndpoint, srv.URL)
+	t.Setenv(envIMDSEndpoint, imds.URL)
+	t.Setenv(envIMDSDisabled, "")
+	t.Setenv(envAWSRegion, "")
+	t.Setenv(envAWSDefRegion, "")
+	t.Setenv(envAWSAccessKey, "")
+	t.Setenv(envAWSSecretKey, "")
+	t.Setenv(envAWSCredentials, filepath.Join(t.TempDir(), "missing"))
+
+	if err := new(Peers).ImportEC2(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// The instance metadata service is not queried if it is disabled
+	t.Setenv(envIMDSDisabled, "true")
+	if _, err := loadAWSCredentials(context.Background()); err == nil {
+		t.Error("expected an error without credentials when the metadata service is disabled")
+	}
+}
+
+// Test loading credentials from the shared credentials file.
+func TestLoadAWSCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "credentials")
+	ini := "[default]\naws_access_key_id = AKIDDEFAULT\naws_secret_access_key = default\n\n# testing\n[test]\naws_access_key_id=AKIDTEST\naws_secret_access_key=test\naws_session_token=token\n"
+	if err = ioutil.WriteFile(path, []byte(ini), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(envAWSAccessKey, "")
+	t.Setenv(envAWSSecretKey, "")
+	t.Setenv(envAWSCredentials, path)
+	t.Setenv(envAWSProfile, "")
+
+	creds, err := loadAWSCredentials(context.Background())
+	if err != nil || creds.accessKey != "AKIDDEFAULT" || creds.secretKey != "default" || creds.sessionToken != "" {
+		t.Errorf("unexpected default credentials %+v: %v", creds, err)
+	}
+
+	t.Setenv(envAWSProfile, "test")
+	creds, err = loadAWSCredentials(context.Background())
+	if err != nil || creds.accessKey != "AKIDTEST" || creds.sessionToken != "token" {
+		t.Errorf("unexpected test credentials %+v: %v", creds, err)
+	}
+
+	t.Setenv(envAWSProfile, "missing")
+	if _, err = loadAWSCredentials(context.Background()); err == nil {
+		t.Error("expected an error for a missing profile")
+	}
+}
+
+// Test that requests are signed with the credential scope and signed headers.
+func TestSignEC2(t *testing.T) {
+	client := &ec2Client{
+		region: "us-east-1",
+		creds:  awsCredentials{accessKey: "AKIDEXAMPLE", secretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://ec2.us-east-1.amazonaws.com/?Action=DescribeInstances&Version=2016-11-15", nil)
+	client.sign(req, time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	if date := req.Header.Get("X-Amz-Date"); date != "20150830T123600Z" {
+		t.Errorf("unexpected date header %q", date)
+	}
+
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/ec2/aws4_request, SignedHeaders=host;x-amz-date, Signature=") {
+		t.Errorf("unexpected authorization header %q", auth)
+	}
+}
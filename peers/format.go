@@ -0,0 +1,135 @@
+package peers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// The file formats of the peers file, determined by the extension of the path.
+const (
+	formatJSON = "json"
+	formatYAML = "yaml"
+	formatTOML = "toml"
+)
+
+// Extensions of the peers file that are looked up in the default directories,
+// in priority order, if the peers.json file does not exist.
+var extensions = []string{".yaml", ".yml", ".toml"}
+
+//===========================================================================
+// File Formats
+//===========================================================================
+
+// Returns the format of the peers file from the extension of its path; files
+// without a YAML or TOML extension are JSON.
+func format(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return formatYAML
+	case ".toml":
+		return formatTOML
+	default:
+		return formatJSON
+	}
+}
+
+// Unmarshals the peers data in the format of the path. YAML and TOML documents
+// are converted to JSON so that all formats use the same field names, e.g.
+// ip_address and replicas, and are validated the same way.
+func decode(path string, data []byte, p *Peers) (err error) {
+	var doc interface{}
+	switch format(path) {
+	case formatYAML:
+		if err = yaml.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+	case formatTOML:
+		if _, err = toml.Decode(string(data), &doc); err != nil {
+			return err
+		}
+	default:
+		return unmarshal(data, p)
+	}
+
+	if data, err = json.Marshal(normalize(doc)); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, p)
+}
+
+// Marshals the peers in the format of the path, indenting JSON and YAML with two
+// spaces.
+func encode(path string, p *Peers) (data []byte, err error) {
+	f := format(path)
+	if f == formatJSON {
+		return json.MarshalIndent(p, "", "  ")
+	}
+
+	// Convert the peers to a document using the JSON field names
+	if data, err = json.Marshal(p); err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err = dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	doc = normalize(doc)
+
+	if f == formatYAML {
+		return yaml.Marshal(doc)
+	}
+
+	buf := &bytes.Buffer{}
+	if err = toml.NewEncoder(buf).Encode(doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Recursively converts a decoded document so that it can be marshaled as JSON,
+// YAML, or TOML: YAML maps are converted to maps with string keys, JSON numbers
+// are converted to integers where possible, and null values are removed since
+// TOML cannot represent them.
+func normalize(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for key, item := range val {
+			if item != nil {
+				m[fmt.Sprint(key)] = normalize(item)
+			}
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for key, item := range val {
+			if item != nil {
+				m[key] = normalize(item)
+			}
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(val))
+		for i, item := range val {
+			s[i] = normalize(item)
+		}
+		return s
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return n
+		}
+		n, _ := val.Float64()
+		return n
+	default:
+		return v
+	}
+}
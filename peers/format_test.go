@@ -0,0 +1,68 @@
+package peers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Test that peers can be loaded from and dumped to YAML and TOML files.
+func TestFormats(t *testing.T) {
+	for _, name := range []string{"peers.yaml", "peers.toml"} {
+		peers := new(Peers)
+		if err := peers.Load(filepath.Join("testdata", name)); err != nil {
+			t.Fatalf("could not load %s: %s", name, err)
+		}
+
+		if peers.Len() != 2 || peers.Peers[1].IPAddr != "10.10.10.2" || peers.Peers[1].Region() != "us-east-1" {
+			t.Errorf("%s was not loaded correctly", name)
+		}
+
+		if n, ok := peers.Info["num_replicas"].(float64); !ok || n != 2 {
+			t.Errorf("%s info was not loaded correctly: %v", name, peers.Info)
+		}
+
+		// Round trip the peers, including the time they were last seen
+		seen := time.Date(2017, 7, 10, 1, 36, 41, 0, time.UTC)
+		peers.Peers[0].LastSeen = &seen
+
+		path := filepath.Join(t.TempDir(), name)
+		if err := peers.Dump(path); err != nil {
+			t.Fatalf("could not dump %s: %s", name, err)
+		}
+
+		loaded := new(Peers)
+		if err := loaded.Load(path); err != nil {
+			t.Fatalf("could not load dumped %s: %s", name, err)
+		}
+
+		if !reflect.DeepEqual(loaded.Peers[1], peers.Peers[1]) || !loaded.Peers[0].LastSeen.Equal(seen) {
+			t.Errorf("%s did not round trip", name)
+		}
+	}
+
+	// Syntax errors are reported with the path
+	path := filepath.Join(t.TempDir(), "peers.yaml")
+	if err := os.WriteFile(path, []byte("replicas: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := new(Peers).Load(path); err == nil {
+		t.Error("expected an error loading invalid yaml")
+	}
+}
+
+// Test that the other formats are looked up if peers.json does not exist.
+func TestWithExtensions(t *testing.T) {
+	paths := withExtensions(filepath.Join("etc", "peers.json"))
+	expected := []string{"etc/peers.json", "etc/peers.yaml", "etc/peers.yml", "etc/peers.toml"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected %v but got %v", expected, paths)
+	}
+
+	if paths := withExtensions("cluster.yaml"); len(paths) != 1 {
+		t.Errorf("expected only the specified path but got %v", paths)
+	}
+}
@@ -0,0 +1,69 @@
+//go:build grpc
+
+package peers
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+//===========================================================================
+// gRPC Client Connections
+//===========================================================================
+
+// GRPCPool is a connection pool of gRPC client connections to the peers.
+type GRPCPool = ConnectionPool[*grpc.ClientConn]
+
+// Dial creates a gRPC client connection to the endpoint of the peer. If the peer
+// has TLS credentials, the connection is secured with its TLSConfig, otherwise
+// the connection is insecure. The options are applied after the transport
+// credentials so that they can be overridden, e.g. with grpc.WithBlock() to
+// wait for the connection to be established.
+func (p *Peer) Dial(opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	return p.DialContext(context.Background(), opts...)
+}
+
+// DialContext creates a gRPC client connection to the endpoint of the peer with
+// the context, which can be used to cancel or expire a blocking dial (see Dial).
+func (p *Peer) DialContext(ctx context.Context, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if p.TLS != nil {
+		conf, err := p.TLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(conf)
+	}
+
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, opts...)
+	return grpc.DialContext(ctx, p.Endpoint(false), opts...)
+}
+
+// NewGRPCPool creates a pool of gRPC client connections to the peers in the
+// roster, which are dialed with Peer.DialContext and the options. Cached
+// connections are evicted and redialed when GRPCHealthy reports that they can
+// no longer be used.
+func NewGRPCPool(peers *Peers, opts ...grpc.DialOption) *GRPCPool {
+	dial := func(ctx context.Context, peer *Peer) (*grpc.ClientConn, error) {
+		return peer.DialContext(ctx, opts...)
+	}
+	return NewPool(peers, dial, GRPCHealthy)
+}
+
+// GRPCHealthy reports if a gRPC client connection can still be used based on its
+// connectivity state: connections that are shut down or in transient failure
+// are unhealthy. Idle connections are healthy but are asked to reconnect so
+// that they are ready when they are used.
+func GRPCHealthy(cc *grpc.ClientConn) bool {
+	switch cc.GetState() {
+	case connectivity.Shutdown, connectivity.TransientFailure:
+		return false
+	case connectivity.Idle:
+		cc.Connect()
+	}
+	return true
+}
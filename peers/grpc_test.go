@@ -0,0 +1,91 @@
+//go:build grpc
+
+package peers
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// Starts a gRPC server without services on a random local port.
+func serveGRPC(t *testing.T) (*grpc.Server, uint16) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := grpc.NewServer()
+	go srv.Serve(lis)
+	return srv, uint16(lis.Addr().(*net.TCPAddr).Port)
+}
+
+// Test that a peer can be dialed with gRPC.
+func TestPeerDial(t *testing.T) {
+	srv, port := serveGRPC(t)
+	defer srv.Stop()
+
+	peer := &Peer{PID: 1, Name: "alpha", IPAddr: "127.0.0.1", Port: port}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cc, err := peer.DialContext(ctx, grpc.WithBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+
+	if state := cc.GetState(); state != connectivity.Ready {
+		t.Errorf("expected a ready connection but got %s", state)
+	}
+
+	// Invalid TLS credentials cannot be dialed
+	peer.TLS = &TLSInfo{Cert: "testdata/missing.crt"}
+	if _, err = peer.Dial(); err == nil {
+		t.Error("expected an error dialing with invalid TLS credentials")
+	}
+}
+
+// Test that the gRPC pool evicts connections that are no longer healthy.
+func TestGRPCPool(t *testing.T) {
+	srv, port := serveGRPC(t)
+	roster := &Peers{Peers: []*Peer{{PID: 1, Name: "alpha", IPAddr: "127.0.0.1", Port: port}}}
+
+	pool := NewGRPCPool(roster, grpc.WithBlock())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cc, err := pool.Get(ctx, "alpha")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if again, _ := pool.Get(ctx, "alpha"); again != cc {
+		t.Error("expected the cached connection to be returned")
+	}
+
+	// Once the server stops the connection fails and is evicted
+	srv.Stop()
+	for deadline := time.Now().Add(5 * time.Second); GRPCHealthy(cc); {
+		if time.Now().After(deadline) {
+			t.Fatalf("connection is still healthy in state %s", cc.GetState())
+		}
+		cc.WaitForStateChange(ctx, cc.GetState())
+	}
+
+	if evicted := pool.Sweep(); len(evicted) != 1 || evicted[0] != "alpha" {
+		t.Errorf("expected the connection to alpha to be evicted but got %v", evicted)
+	}
+
+	if state := cc.GetState(); state != connectivity.Shutdown {
+		t.Errorf("expected the evicted connection to be shut down but got %s", state)
+	}
+
+	if err = pool.Close(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,477 @@
+package peers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultPort is assigned to imported peers that do not have a port tag and
+// when no port is specified in the import options.
+const DefaultPort = 3264
+
+//===========================================================================
+// Import Options
+//===========================================================================
+
+// ImportOptions determine how cloud provider instances are converted into
+// peers. Tags (AWS tags, GCP labels, or the tags of Terraform resources) can
+// be mapped to peer fields so that the roster is defined with the instances.
+type ImportOptions struct {
+	// Fields maps peer fields to the tag the value is read from. Valid fields
+	// are name, pid, port, hostname, domain, and description. Fields that are
+	// not specified use the tag in DefaultFields.
+	Fields map[string]string
+
+	// Filter only imports instances that have all of the specified tags and
+	// values, e.g. {"cluster": "raft"}.
+	Filter map[string]string
+
+	// Port is used for peers without a port tag, DefaultPort if zero.
+	Port uint16
+
+	// Public uses the public ip address of instances if they have one rather
+	// than the private ip address.
+	Public bool
+}
+
+// DefaultFields are the tags peer fields are read from if not specified in the
+// import options. If an instance has no name tag, the instance name (or id)
+// is used; if it has no pid tag, a pid is assigned that does not conflict with
+// the existing peers.
+var DefaultFields = map[string]string{
+	"name":        "Name",
+	"pid":         "pid",
+	"port":        "port",
+	"hostname":    "hostname",
+	"domain":      "domain",
+	"description": "description",
+}
+
+// Returns the tag that the field is read from.
+func (o *ImportOptions) tag(field string) string {
+	if o != nil && o.Fields != nil {
+		if tag, ok := o.Fields[field]; ok {
+			return tag
+		}
+	}
+	return DefaultFields[field]
+}
+
+// Returns true if the instance tags match the filter.
+func (o *ImportOptions) match(tags map[string]string) bool {
+	if o == nil {
+		return true
+	}
+
+	for key, val := range o.Filter {
+		if tags[key] != val {
+			return false
+		}
+	}
+	return true
+}
+
+//===========================================================================
+// Importers
+//===========================================================================
+
+// ImportEC2Output adds a peer for every running instance in the JSON output of
+// `aws ec2 describe-instances`, populating the AWSInstance field of the peer
+// with the instance id, type, region, availability zone, and addresses.
+func (p *Peers) ImportEC2Output(r io.Reader, opts *ImportOptions) error {
+	var out struct {
+		Reservations []struct {
+			Instances []struct {
+				InstanceID       string `json:"InstanceId"`
+				InstanceType     string `json:"InstanceType"`
+				PrivateIPAddress string `json:"PrivateIpAddress"`
+				PublicIPAddress  string `json:"PublicIpAddress"`
+				PrivateDNSName   string `json:"PrivateDnsName"`
+				PublicDNSName    string `json:"PublicDnsName"`
+				Placement        struct {
+					AvailabilityZone string `json:"AvailabilityZone"`
+				} `json:"Placement"`
+				State struct {
+					Name string `json:"Name"`
+				} `json:"State"`
+				Tags []struct {
+					Key   string `json:"Key"`
+					Value string `json:"Value"`
+				} `json:"Tags"`
+			} `json:"Instances"`
+		} `json:"Reservations"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&out); err != nil {
+		return fmt.Errorf("could not parse ec2 instances: %s", err)
+	}
+
+	instances := make([]*instance, 0)
+	for _, reservation := range out.Reservations {
+		for _, ec2 := range reservation.Instances {
+			if ec2.State.Name != "" && ec2.State.Name != "running" {
+				continue
+			}
+
+			inst := &instance{
+				name:      ec2.InstanceID,
+				hostname:  ec2.PrivateDNSName,
+				privateIP: ec2.PrivateIPAddress,
+				publicIP:  ec2.PublicIPAddress,
+				tags:      make(map[string]string, len(ec2.Tags)),
+			}
+
+			for _, tag := range ec2.Tags {
+				inst.tags[tag.Key] = tag.Value
+			}
+
+			inst.aws = awsInstance(
+				ec2.InstanceID, ec2.InstanceType, ec2.Placement.AvailabilityZone,
+				ec2.PrivateIPAddress, ec2.PublicIPAddress, ec2.PrivateDNSName, ec2.PublicDNSName,
+			)
+			instances = append(instances, inst)
+		}
+	}
+
+	return p.importInstances(instances, opts)
+}
+
+// ImportGCPOutput adds a peer for every running instance in the JSON output of
+// `gcloud compute instances list --format=json`. Instance labels are used as
+// the tags that are mapped to peer fields.
+func (p *Peers) ImportGCPOutput(r io.Reader, opts *ImportOptions) error {
+	var out []struct {
+		Name              string            `json:"name"`
+		Zone              string            `json:"zone"`
+		MachineType       string            `json:"machineType"`
+		Status            string            `json:"status"`
+		Labels            map[string]string `json:"labels"`
+		NetworkInterfaces []struct {
+			NetworkIP     string `json:"networkIP"`
+			AccessConfigs []struct {
+				NatIP string `json:"natIP"`
+			} `json:"accessConfigs"`
+		} `json:"networkInterfaces"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&out); err != nil {
+		return fmt.Errorf("could not parse gcp instances: %s", err)
+	}
+
+	instances := make([]*instance, 0, len(out))
+	for _, gcp := range out {
+		if gcp.Status != "" && gcp.Status != "RUNNING" {
+			continue
+		}
+
+		inst := &instance{
+			name:        gcp.Name,
+			hostname:    gcp.Name,
+			tags:        gcp.Labels,
+			description: gcpDescription(gcp.MachineType, gcp.Zone),
+		}
+
+		if len(gcp.NetworkInterfaces) > 0 {
+			inst.privateIP = gcp.NetworkInterfaces[0].NetworkIP
+			if configs := gcp.NetworkInterfaces[0].AccessConfigs; len(configs) > 0 {
+				inst.publicIP = configs[0].NatIP
+			}
+		}
+		instances = append(instances, inst)
+	}
+
+	return p.importInstances(instances, opts)
+}
+
+// ImportTerraformState adds a peer for every aws_instance and
+// google_compute_instance resource in a Terraform (v4) state file. Resource
+// tags and labels are used as the tags that are mapped to peer fields.
+func (p *Peers) ImportTerraformState(r io.Reader, opts *ImportOptions) error {
+	var state struct {
+		Version   int `json:"version"`
+		Resources []struct {
+			Mode      string `json:"mode"`
+			Type      string `json:"type"`
+			Name      string `json:"name"`
+			Instances []struct {
+				IndexKey   interface{}     `json:"index_key"`
+				Attributes json.RawMessage `json:"attributes"`
+			} `json:"instances"`
+		} `json:"resources"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return fmt.Errorf("could not parse terraform state: %s", err)
+	}
+
+	if state.Version != 4 {
+		return fmt.Errorf("unsupported terraform state version %d", state.Version)
+	}
+
+	instances := make([]*instance, 0)
+	for _, resource := range state.Resources {
+		if resource.Mode != "managed" {
+			continue
+		}
+
+		for _, ri := range resource.Instances {
+			// The resource address is used as the name if the instance has no name tag
+			address := resource.Type + "." + resource.Name
+			if ri.IndexKey != nil {
+				address = fmt.Sprintf("%s[%v]", address, ri.IndexKey)
+			}
+
+			var (
+				inst *instance
+				err  error
+			)
+
+			switch resource.Type {
+			case "aws_instance":
+				inst, err = tfAWSInstance(ri.Attributes)
+			case "google_compute_instance":
+				inst, err = tfGCPInstance(ri.Attributes)
+			default:
+				continue
+			}
+
+			if err != nil {
+				return fmt.Errorf("could not parse %s: %s", address, err)
+			}
+
+			if inst.name == "" {
+				inst.name = address
+			}
+			instances = append(instances, inst)
+		}
+	}
+
+	return p.importInstances(instances, opts)
+}
+
+//===========================================================================
+// Helpers
+//===========================================================================
+
+// A cloud provider instance that is converted into a peer.
+type instance struct {
+	name        string
+	hostname    string
+	privateIP   string
+	publicIP    string
+	description string
+	tags        map[string]string
+	aws         map[string]string
+}
+
+// Converts the instances to peers using the options and adds them to the
+// collection, assigning pids that do not conflict with existing peers to
+// instances without a pid tag. If the collection is not valid after the import,
+// the imported peers are removed and the validation error is returned.
+func (p *Peers) importInstances(instances []*instance, opts *ImportOptions) error {
+	port := uint16(DefaultPort)
+	if opts != nil && opts.Port != 0 {
+		port = opts.Port
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Track the pids in use so that assigned pids are unique
+	var maxPID uint32
+	for _, peer := range p.Peers {
+		if peer != nil && peer.PID > maxPID {
+			maxPID = peer.PID
+		}
+	}
+
+	imported := make([]*Peer, 0, len(instances))
+	unassigned := make([]*Peer, 0)
+	for _, inst := range instances {
+		if inst.tags == nil {
+			inst.tags = make(map[string]string)
+		}
+
+		if !opts.match(inst.tags) {
+			continue
+		}
+
+		peer := &Peer{
+			Name:        inst.name,
+			Hostname:    inst.hostname,
+			IPAddr:      inst.privateIP,
+			Port:        port,
+			Description: inst.description,
+			AWSInstance: inst.aws,
+		}
+
+		if opts != nil && opts.Public && inst.publicIP != "" {
+			peer.IPAddr = inst.publicIP
+		}
+
+		if val, ok := inst.tags[opts.tag("name")]; ok && val != "" {
+			peer.Name = val
+		}
+
+		if val, ok := inst.tags[opts.tag("hostname")]; ok && val != "" {
+			peer.Hostname = val
+		}
+
+		if val, ok := inst.tags[opts.tag("domain")]; ok {
+			peer.Domain = val
+		}
+
+		if val, ok := inst.tags[opts.tag("description")]; ok {
+			peer.Description = val
+		}
+
+		if val, ok := inst.tags[opts.tag("port")]; ok {
+			num, err := strconv.ParseUint(val, 10, 16)
+			if err != nil {
+				return fmt.Errorf("could not parse port tag of %q: %s", peer.Name, err)
+			}
+			peer.Port = uint16(num)
+		}
+
+		if val, ok := inst.tags[opts.tag("pid")]; ok {
+			num, err := strconv.ParseUint(val, 10, 32)
+			if err != nil {
+				return fmt.Errorf("could not parse pid tag of %q: %s", peer.Name, err)
+			}
+
+			peer.PID = uint32(num)
+			if peer.PID > maxPID {
+				maxPID = peer.PID
+			}
+		} else {
+			unassigned = append(unassigned, peer)
+		}
+
+		imported = append(imported, peer)
+	}
+
+	// Assign pids in name order so that imports are deterministic
+	sort.SliceStable(unassigned, func(i, j int) bool {
+		return unassigned[i].Name < unassigned[j].Name
+	})
+
+	for _, peer := range unassigned {
+		maxPID++
+		peer.PID = maxPID
+	}
+
+	// Roll back the import if the peers are not valid
+	orig := p.Peers
+	p.Peers = append(append(make([]*Peer, 0, len(orig)+len(imported)), orig...), imported...)
+	if err := p.validate(); err != nil {
+		p.Peers = orig
+		return err
+	}
+
+	p.revive()
+	return nil
+}
+
+// Returns the AWSInstance metadata of a peer, omitting empty values.
+func awsInstance(id, itype, zone, privateIP, publicIP, privateDNS, publicDNS string) map[string]string {
+	meta := map[string]string{
+		"instance_id":       id,
+		"instance_type":     itype,
+		"availability_zone": zone,
+		"private_ip":        privateIP,
+		"public_ip":         publicIP,
+		"private_dns":       privateDNS,
+		"public_dns":        publicDNS,
+	}
+
+	// The region is the availability zone without the zone letter
+	if len(zone) > 1 {
+		meta["region"] = strings.TrimRight(zone, "abcdefghijklmnopqrstuvwxyz")
+	}
+
+	for key, val := range meta {
+		if val == "" {
+			delete(meta, key)
+		}
+	}
+	return meta
+}
+
+// Returns a description of a GCP instance from its machine type and zone, which
+// may be specified as resource URLs.
+func gcpDescription(machineType, zone string) string {
+	machineType, zone = path.Base(machineType), path.Base(zone)
+	if machineType == "." || zone == "." {
+		return ""
+	}
+	return fmt.Sprintf("%s in %s", machineType, zone)
+}
+
+// Parses the attributes of an aws_instance terraform resource.
+func tfAWSInstance(data json.RawMessage) (*instance, error) {
+	var attrs struct {
+		ID               string            `json:"id"`
+		InstanceType     string            `json:"instance_type"`
+		AvailabilityZone string            `json:"availability_zone"`
+		PrivateIP        string            `json:"private_ip"`
+		PublicIP         string            `json:"public_ip"`
+		PrivateDNS       string            `json:"private_dns"`
+		PublicDNS        string            `json:"public_dns"`
+		Tags             map[string]string `json:"tags"`
+	}
+
+	if err := json.Unmarshal(data, &attrs); err != nil {
+		return nil, err
+	}
+
+	return &instance{
+		hostname:  attrs.PrivateDNS,
+		privateIP: attrs.PrivateIP,
+		publicIP:  attrs.PublicIP,
+		tags:      attrs.Tags,
+		aws: awsInstance(
+			attrs.ID, attrs.InstanceType, attrs.AvailabilityZone,
+			attrs.PrivateIP, attrs.PublicIP, attrs.PrivateDNS, attrs.PublicDNS,
+		),
+	}, nil
+}
+
+// Parses the attributes of a google_compute_instance terraform resource.
+func tfGCPInstance(data json.RawMessage) (*instance, error) {
+	var attrs struct {
+		Name             string            `json:"name"`
+		Zone             string            `json:"zone"`
+		MachineType      string            `json:"machine_type"`
+		Labels           map[string]string `json:"labels"`
+		NetworkInterface []struct {
+			NetworkIP    string `json:"network_ip"`
+			AccessConfig []struct {
+				NatIP string `json:"nat_ip"`
+			} `json:"access_config"`
+		} `json:"network_interface"`
+	}
+
+	if err := json.Unmarshal(data, &attrs); err != nil {
+		return nil, err
+	}
+
+	inst := &instance{
+		name:        attrs.Name,
+		hostname:    attrs.Name,
+		tags:        attrs.Labels,
+		description: gcpDescription(attrs.MachineType, attrs.Zone),
+	}
+
+	if len(attrs.NetworkInterface) > 0 {
+		inst.privateIP = attrs.NetworkInterface[0].NetworkIP
+		if configs := attrs.NetworkInterface[0].AccessConfig; len(configs) > 0 {
+			inst.publicIP = configs[0].NatIP
+		}
+	}
+	return inst, nil
+}
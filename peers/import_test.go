@@ -0,0 +1,122 @@
+package peers
+
+import (
+	"os"
+	"testing"
+)
+
+// Test importing running instances from the output of ec2 describe-instances.
+func TestImportEC2Output(t *testing.T) {
+	f, err := os.Open("testdata/ec2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	peers := new(Peers)
+	opts := &ImportOptions{Filter: map[string]string{"cluster": "raft"}, Public: true}
+	if err := peers.ImportEC2Output(f, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(peers.Peers) != 2 {
+		t.Fatalf("expected 2 peers imported got %d", len(peers.Peers))
+	}
+
+	alpha, bravo := peers.Peers[0], peers.Peers[1]
+	if alpha.Name != "alpha" || alpha.PID != 1 || alpha.IPAddr != "54.10.10.1" || alpha.Port != DefaultPort {
+		t.Errorf("unexpected alpha peer: %+v", alpha)
+	}
+
+	if alpha.AWSInstance["instance_id"] != "i-0a1b2c3d4e5f60001" || alpha.AWSInstance["region"] != "us-east-1" {
+		t.Errorf("unexpected alpha aws instance: %v", alpha.AWSInstance)
+	}
+
+	// Without a public ip address the private ip address is used
+	if bravo.Name != "bravo" || bravo.PID != 2 || bravo.IPAddr != "172.31.10.2" || bravo.Port != 3265 {
+		t.Errorf("unexpected bravo peer: %+v", bravo)
+	}
+}
+
+// Test importing running instances from the output of gcloud.
+func TestImportGCPOutput(t *testing.T) {
+	f, err := os.Open("testdata/gcp.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	peers := &Peers{Peers: []*Peer{{PID: 1, Name: "local", IPAddr: "127.0.0.1", Port: 3264}}}
+	opts := &ImportOptions{Fields: map[string]string{"pid": "replica-pid"}, Port: 4000}
+	if err := peers.ImportGCPOutput(f, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(peers.Peers) != 2 {
+		t.Fatalf("expected 1 peer imported got %d", len(peers.Peers)-1)
+	}
+
+	alpha := peers.Peers[1]
+	if alpha.Name != "alpha" || alpha.PID != 7 || alpha.IPAddr != "10.128.0.2" || alpha.Port != 4000 {
+		t.Errorf("unexpected alpha peer: %+v", alpha)
+	}
+
+	if alpha.Description != "e2-small in us-central1-a" {
+		t.Errorf("unexpected alpha description %q", alpha.Description)
+	}
+}
+
+// Test importing aws and gcp instances from a terraform state file.
+func TestImportTerraformState(t *testing.T) {
+	f, err := os.Open("testdata/terraform.tfstate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	peers := new(Peers)
+	if err := peers.ImportTerraformState(f, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		name string
+		pid  uint32
+		ip   string
+		port uint16
+	}{
+		{"replica-0", 10, "10.0.1.10", DefaultPort},
+		{"aws_instance.replica[1]", 11, "10.0.2.10", DefaultPort},
+		{"witness", 12, "10.128.0.9", 4000},
+	}
+
+	if len(peers.Peers) != len(expected) {
+		t.Fatalf("expected %d peers imported got %d", len(expected), len(peers.Peers))
+	}
+
+	for i, exp := range expected {
+		peer := peers.Peers[i]
+		if peer.Name != exp.name || peer.PID != exp.pid || peer.IPAddr != exp.ip || peer.Port != exp.port {
+			t.Errorf("expected peer %+v got %+v", exp, peer)
+		}
+	}
+}
+
+// Test that an invalid import is rolled back.
+func TestImportInvalid(t *testing.T) {
+	f, err := os.Open("testdata/ec2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// The alpha tag conflicts with the existing peer pid
+	peers := &Peers{Peers: []*Peer{{PID: 1, Name: "local", IPAddr: "127.0.0.1", Port: 3264}}}
+	if err := peers.ImportEC2Output(f, nil); err == nil {
+		t.Error("expected a validation error for the duplicate pid")
+	}
+
+	if len(peers.Peers) != 1 {
+		t.Errorf("expected invalid import to be rolled back, have %d peers", len(peers.Peers))
+	}
+}
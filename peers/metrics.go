@@ -0,0 +1,68 @@
+package peers
+
+import (
+	"time"
+
+	"github.com/bbengfort/x/stats"
+)
+
+// Names of the peer health and synchronization metrics recorded in a registry.
+const (
+	MetricPeers        = "peers.count"         // gauge of the number of peers in the roster
+	MetricHealthy      = "peers.healthy"       // gauge of the number of peers that are not stale
+	MetricUnhealthy    = "peers.unhealthy"     // gauge of the number of stale peers
+	MetricLastSync     = "peers.sync.last"     // gauge of the unix time of the last successful sync
+	MetricSyncs        = "peers.sync.count"    // counter of successful syncs
+	MetricSyncFailures = "peers.sync.failures" // counter of failed syncs
+	MetricSyncLatency  = "peers.sync.latency"  // benchmark of the duration of each sync request
+)
+
+//===========================================================================
+// Metrics
+//===========================================================================
+
+// Record sets the peer count and health gauges of the registry so that cluster
+// membership health is observable, e.g. from a metrics endpoint that serializes
+// the registry. Peers are unhealthy if they have not been observed within the
+// TTL (see Stale) and healthy otherwise. Record should be called whenever peers
+// are observed or the roster changes; a Syncer with Metrics calls it after every
+// sync.
+func (p *Peers) Record(registry *stats.Registry) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	now := time.Now()
+	total, unhealthy := 0, 0
+	for _, peer := range p.Peers {
+		if peer == nil {
+			continue
+		}
+
+		total++
+		if peer.stale(p.ttl, now) {
+			unhealthy++
+		}
+	}
+
+	registry.Set(MetricPeers, float64(total))
+	registry.Set(MetricHealthy, float64(total-unhealthy))
+	registry.Set(MetricUnhealthy, float64(unhealthy))
+}
+
+// Records the outcome of a sync that started at the specified time. A sync that
+// updated the roster but failed to dispatch the changes is not a failure.
+func (s *Syncer) record(start time.Time, failed bool) {
+	if s.Metrics == nil {
+		return
+	}
+
+	s.Metrics.Time(MetricSyncLatency, time.Since(start))
+	if failed {
+		s.Metrics.Incr(MetricSyncFailures, 1)
+		return
+	}
+
+	s.Metrics.Incr(MetricSyncs, 1)
+	s.Metrics.Set(MetricLastSync, float64(time.Now().Unix()))
+	s.peers.Record(s.Metrics)
+}
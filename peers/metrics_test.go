@@ -0,0 +1,79 @@
+package peers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bbengfort/x/stats"
+)
+
+// Test that the peer count and health gauges are recorded.
+func TestRecord(t *testing.T) {
+	peers := &Peers{Peers: []*Peer{{PID: 1, Name: "alpha"}, {PID: 2, Name: "bravo"}, {PID: 3, Name: "charlie"}}}
+	peers.SetTTL(time.Minute)
+
+	past := time.Now().Add(-time.Hour)
+	peers.Peers[2].LastSeen = &past
+
+	registry := stats.NewRegistry()
+	peers.Record(registry)
+
+	if registry.Gauge(MetricPeers) != 3 || registry.Gauge(MetricHealthy) != 2 || registry.Gauge(MetricUnhealthy) != 1 {
+		t.Errorf("unexpected peer metrics: %v", registry.Serialize())
+	}
+
+	if err := peers.Seen("charlie"); err != nil {
+		t.Fatal(err)
+	}
+
+	peers.Record(registry)
+	if registry.Gauge(MetricHealthy) != 3 || registry.Gauge(MetricUnhealthy) != 0 {
+		t.Errorf("unexpected peer metrics after seen: %v", registry.Serialize())
+	}
+}
+
+// Test that the syncer records sync metrics.
+func TestSyncerMetrics(t *testing.T) {
+	var fail int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `{"replicas": [{"pid": 1, "name": "alpha", "ip_address": "10.10.10.1", "port": 3264}]}`)
+	}))
+	defer srv.Close()
+
+	registry := stats.NewRegistry()
+	syncer := NewSyncerFrom(nil, srv.URL, "secret")
+	syncer.Metrics = registry
+
+	if _, err := syncer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	atomic.StoreInt32(&fail, 1)
+	if _, err := syncer.Sync(); err == nil {
+		t.Fatal("expected the sync to fail")
+	}
+
+	if registry.Count(MetricSyncs) != 1 || registry.Count(MetricSyncFailures) != 1 {
+		t.Errorf("unexpected sync counts: %v", registry.Serialize())
+	}
+
+	if last := registry.Gauge(MetricLastSync); time.Since(time.Unix(int64(last), 0)) > time.Minute {
+		t.Errorf("unexpected last sync time %v", last)
+	}
+
+	if registry.Gauge(MetricPeers) != 1 || registry.Gauge(MetricHealthy) != 1 {
+		t.Errorf("unexpected peer metrics: %v", registry.Serialize())
+	}
+
+	if n := registry.Benchmark(MetricSyncLatency).N(); n != 2 {
+		t.Errorf("expected the latency of 2 syncs to be recorded, got %d", n)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"strings"
 )
 
 // The expected direcotry and file name of the peers file.
@@ -84,6 +85,21 @@ func cwdPeers() string {
 	return filepath.Join(cwd, filename)
 }
 
+// Returns the path followed by the paths of the peers file in the other supported
+// formats if the path is to the default peers.json file, e.g. peers.yaml.
+func withExtensions(path string) []string {
+	paths := []string{path}
+	if filepath.Base(path) != filename {
+		return paths
+	}
+
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, ext := range extensions {
+		paths = append(paths, base+ext)
+	}
+	return paths
+}
+
 // Returns the path to the peers file specified by the environment or an
 // empty string if there is no path in the environment.
 func envPeers() string {
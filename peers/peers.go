@@ -21,7 +21,13 @@
 // The Peers object can also be synchronized from a remote service using the
 // Sync() method. Synchronization fetches peers.json from a URL that can be
 // specified by the environment, and can also submit an API key along with
-// the request.
+// the request. A Syncer keeps the Peers object synchronized in the background,
+// notifying callbacks and an events dispatcher when the roster changes.
+// Watch() reloads the Peers object when the file it was loaded from changes.
+//
+// Peers that advertise a service with mDNS on the local network can be added
+// to the Peers object with the Discover() method. Peers can also describe the
+// TLS credentials used to connect to them, e.g. issued by the ca package.
 //
 // Other important helpers include the ability to identify the localhost or
 // peer from the hostname of the system, or to identify all local peer
@@ -30,15 +36,16 @@
 package peers
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/bbengfort/x/diff"
 )
 
 // Load is the primary entry point for the peers package. It uses a list of
@@ -53,20 +60,41 @@ import (
 // - $HOME/.fluidfs/peers.json
 // - /etc/fluidfs/peers.json
 //
-// At the moment, the first path that is available short circuits the load
-// process and all remaining paths are ignored.
+// If a peers.json file does not exist in a directory, then peers.yaml,
+// peers.yml, and peers.toml are tried in that order. At the moment, the first
+// file that exists short circuits the load process and all remaining paths are
+// ignored. If that file cannot be parsed or is invalid, an empty collection is
+// returned rather than falling back to a file with lower priority; use Lookup
+// to get the error.
 func Load() *Peers {
-	peers := new(Peers)
+	peers, _ := Lookup()
+	return peers
+}
 
-	for _, path := range peersPaths() {
-		// If there is no error loading the peers, then stop trying to
-		// load peers paths because the loading was successful!
-		if err := peers.Load(path); err == nil {
-			break
+// Lookup finds the peers file in the lookup paths like Load, but returns an
+// error if the first file that exists cannot be read, parsed, or validated, so
+// that a broken peers.json is reported rather than silently ignored. If no
+// peers file exists, an empty collection and no error are returned.
+func Lookup() (*Peers, error) {
+	return lookup(peersPaths())
+}
+
+// Loads the first peers file that exists in the paths, in order of priority.
+func lookup(paths []string) (*Peers, error) {
+	for _, path := range paths {
+		for _, path := range withExtensions(path) {
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				continue
+			}
+
+			peers := new(Peers)
+			if err := peers.Load(path); err != nil {
+				return new(Peers), err
+			}
+			return peers, nil
 		}
 	}
-
-	return peers
+	return new(Peers), nil
 }
 
 // LoadFrom is a secondary entry point for the peers package. It requires a
@@ -77,6 +105,85 @@ func LoadFrom(path string) (*Peers, error) {
 	return peers, err
 }
 
+// LoadAll is an alternative entry point that merges the peers files found in
+// all of the lookup paths rather than stopping at the first one, so that
+// machine-wide and project-local rosters compose. The precedence of the paths
+// is $PEERS_PATH, then $PWD, then $HOME/.fluidfs, then /etc/fluidfs: peers
+// are de-duplicated by name and a peer (or info key) in a file with higher
+// precedence replaces the one with the same name in a file with lower
+// precedence. As with Load, the first of peers.json, peers.yaml, peers.yml,
+// and peers.toml is used in each directory and paths that do not exist are
+// skipped. An error is returned if a file cannot be parsed or if the merged
+// collection is not valid, e.g. because two files assign the same pid to
+// different peers. The merged collection is not associated with a path, so a
+// path must be specified to Dump it.
+func LoadAll() (*Peers, error) {
+	return loadAll(peersPaths())
+}
+
+// Merges the peers files in the paths, which are in order of precedence.
+func loadAll(paths []string) (_ *Peers, err error) {
+	merged := new(Peers)
+
+	// Merge from the lowest precedence so that higher precedence files replace peers
+	for i := len(paths) - 1; i >= 0; i-- {
+		for _, path := range withExtensions(paths[i]) {
+			var data []byte
+			if data, err = ioutil.ReadFile(path); err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, err
+			}
+
+			file := new(Peers)
+			if err = decode(path, data, file); err != nil {
+				return nil, fmt.Errorf("could not parse %s: %s", path, err)
+			}
+
+			merged.overlay(file)
+			break
+		}
+	}
+
+	if err = merged.check(); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// Merges the info and peers of the other collection into this collection,
+// replacing peers with the same name (not thread-safe).
+func (p *Peers) overlay(other *Peers) {
+	if len(other.Info) > 0 && p.Info == nil {
+		p.Info = make(map[string]interface{}, len(other.Info))
+	}
+
+	for key, val := range other.Info {
+		p.Info[key] = val
+	}
+
+	for _, peer := range other.Peers {
+		if peer == nil {
+			continue
+		}
+
+		if idx := p.index(peer.Name); idx >= 0 {
+			p.Peers[idx] = peer
+		} else {
+			p.Peers = append(p.Peers, peer)
+		}
+	}
+
+	for name, deleted := range other.Deleted {
+		if p.Deleted == nil {
+			p.Deleted = make(map[string]time.Time, len(other.Deleted))
+		}
+		p.Deleted[name] = deleted
+	}
+	p.revive()
+}
+
 // Sync is a helper function that performs a SyncFrom() but looks up the
 // url and api key from the environment, expecting the following:
 //
@@ -85,12 +192,12 @@ func LoadFrom(path string) (*Peers, error) {
 //
 // See the SyncFrom function for more details.
 func Sync() (*Peers, error) {
-	url := os.Getenv("PEERS_SYNC_URL")
+	url := os.Getenv(envSyncURL)
 	if url == "" {
 		return nil, errors.New("could not find $PEERS_SYNC_URL")
 	}
 
-	key := os.Getenv("PEERS_SYNC_APIKEY")
+	key := os.Getenv(envSyncAPIKey)
 	if key == "" {
 		return nil, errors.New("could not find $PEERS_SYNC_APIKEY")
 	}
@@ -101,35 +208,13 @@ func Sync() (*Peers, error) {
 // SyncFrom is a remote entry point for the peers package. It uses an HTTP
 // request to synchronize the peers from a remote host and instantiate the
 // peers collection. It expects a url and an api key to perform the GET
-// request, adding the api key to the headers as "X-Api-Key".
+// request, adding the api key to the headers as "X-Api-Key". Use a Syncer to
+// keep a collection synchronized in the background.
 func SyncFrom(url, apikey string) (*Peers, error) {
-	// Conduct the request with a 5 second timeout
-	client := &http.Client{Timeout: time.Second * 5}
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("X-Api-Key", apikey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := client.Do(req)
+	peers, _, _, err := fetch(url, apikey, "", "", DefaultSyncTimeout)
 	if err != nil {
 		return nil, err
 	}
-
-	// Ensure connection is closed on complete
-	defer resp.Body.Close()
-
-	// Check the status from the client
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf(
-			"could not synchronize peers: %s", resp.Status,
-		)
-	}
-
-	// Parse the body of the response
-	peers := new(Peers)
-	if err := json.NewDecoder(resp.Body).Decode(&peers); err != nil {
-		return nil, err
-	}
 	return peers, nil
 }
 
@@ -141,15 +226,31 @@ func SyncFrom(url, apikey string) (*Peers, error) {
 // communicated with, along with associated metadata. The Peers object is the
 // primary interaction with files on disk and exposes methods that select
 // relevent hosts and addresses.
+//
+// The methods of the Peers object are safe for concurrent use so that long
+// running services can mutate the roster with Add, Remove, Update, and Upsert
+// while it is being read. Reading or modifying the Peers field directly is not
+// thread-safe; use List to get a snapshot of the peers instead.
+//
+// Peers removed with Remove are recorded in Deleted with the time they were
+// removed (a tombstone) so that SyncBoth can propagate the deletion rather than
+// restoring the peer from a roster that still has it. The tombstone is cleared
+// if a peer with the same name is added again.
 type Peers struct {
-	Info  map[string]interface{} `json:"info"`     // metadata associated with the collection
-	Peers []*Peer                `json:"replicas"` // the network peers (also called replicas)
-	path  string                 // the path that was successfully loaded
+	Info    map[string]interface{} `json:"info"`              // metadata associated with the collection
+	Peers   []*Peer                `json:"replicas"`          // the network peers (also called replicas)
+	Deleted map[string]time.Time   `json:"deleted,omitempty"` // the names of removed peers and when they were removed
+	path    string                 // the path that was successfully loaded
+	ttl     time.Duration          // how long peers may go unobserved before they are stale
+	mu      sync.RWMutex           // guards the peers and info
 }
 
-// Load the peers collection from a JSON file on disk. If the peers are
-// successfully loaded, the path it was loaded from is stored and no error
-// is returned.
+// Load the peers collection from a JSON, YAML, or TOML file on disk, using the
+// extension of the path to determine the format (.yaml, .yml, or .toml, and JSON
+// otherwise). If the peers are successfully loaded and valid, they replace the
+// collection, the path it was loaded from is stored, and no error is returned.
+// If the peers are invalid, a *ValidationError describing every problem found
+// is returned and the collection is not modified (see SkipValidation).
 func (p *Peers) Load(path string) error {
 	// Read the data from disk
 	data, err := ioutil.ReadFile(path)
@@ -157,20 +258,34 @@ func (p *Peers) Load(path string) error {
 		return err
 	}
 
-	// Unmarshal the JSON data
-	if err := json.Unmarshal(data, &p); err != nil {
+	// Unmarshal the data in the format of the path into a new collection
+	loaded := new(Peers)
+	if err := decode(path, data, loaded); err != nil {
+		return fmt.Errorf("could not parse %s: %s", path, err)
+	}
+
+	// Validate the peers before replacing the collection
+	if err := loaded.check(); err != nil {
 		return err
 	}
 
-	// Save the path and return nil
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Save the peers and the path and return nil
+	p.Info = loaded.Info
+	p.Peers = loaded.Peers
+	p.Deleted = loaded.Deleted
 	p.path = path
 	return nil
 }
 
-// Dump the peers collection as a JSON file to disk. If an empty string is
-// passed in as an argument, then it will dump to the location on disk it
-// was loaded from.
+// Dump the peers collection as a JSON, YAML, or TOML file to disk depending on
+// the extension of the path. If an empty string is passed in as an argument,
+// then it will dump to the location on disk it was loaded from.
 func (p *Peers) Dump(path string) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 
 	// Find the correct path to dump to
 	if path == "" {
@@ -185,8 +300,8 @@ func (p *Peers) Dump(path string) error {
 		return err
 	}
 
-	// Marshal the JSON data
-	data, err := json.MarshalIndent(p, "", "  ")
+	// Marshal the data in the format of the path
+	data, err := encode(path, p)
 	if err != nil {
 		return err
 	}
@@ -207,6 +322,9 @@ func (p *Peers) Local(hostname string) []*Peer {
 		hostname, _ = os.Hostname()
 	}
 
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	peers := make([]*Peer, 0)
 	for _, peer := range p.Peers {
 		name := strings.Split(peer.Hostname, ".")[0]
@@ -216,7 +334,6 @@ func (p *Peers) Local(hostname string) []*Peer {
 	}
 
 	return peers
-
 }
 
 // Localhost returns the peer that is defined by the current localhost. Note
@@ -237,13 +354,180 @@ func (p *Peers) Localhost(hostname string, pid uint32) (*Peer, error) {
 // Get a specific peer by name (which should be unique). If the named peer is
 // not found, then an error is returned.
 func (p *Peers) Get(hostname string) (*Peer, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if idx := p.index(hostname); idx >= 0 {
+		return p.Peers[idx], nil
+	}
+	return nil, fmt.Errorf("could not find a peer named '%s'", hostname)
+}
+
+// List returns a snapshot of the peers in the collection. Because the mutation
+// methods replace peers rather than modifying them, the snapshot is not changed
+// by concurrent mutations to the collection.
+func (p *Peers) List() []*Peer {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	peers := make([]*Peer, len(p.Peers))
+	copy(peers, p.Peers)
+	return peers
+}
+
+// Diff returns the structural changes from the collection to the other roster,
+// e.g. to report the changes made by a synchronization or merge. Peers are keyed
+// by name rather than by their position in the roster so that the changes have
+// paths such as "peers.alpha.port" or "info.updated".
+func (p *Peers) Diff(other *Peers) ([]diff.Change, error) {
+	return diff.Values(p.keyed(), other.keyed())
+}
+
+// Returns the info and the peers keyed by name for structural diffs.
+func (p *Peers) keyed() interface{} {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	peers := make(map[string]*Peer, len(p.Peers))
 	for _, peer := range p.Peers {
-		if peer.Name == hostname {
-			return peer, nil
+		if peer != nil {
+			peers[peer.Name] = peer
 		}
 	}
 
-	return nil, fmt.Errorf("could not find a peer named '%s'", hostname)
+	return struct {
+		Info  map[string]interface{} `json:"info"`
+		Peers map[string]*Peer       `json:"peers"`
+	}{p.Info, peers}
+}
+
+// Len returns the number of peers in the collection.
+func (p *Peers) Len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.Peers)
+}
+
+// Add a peer to the collection. An error is returned and the collection is not
+// modified if a peer with the same name already exists or if the collection
+// would not be valid with the peer, e.g. because its pid is already used.
+func (p *Peers) Add(peer *Peer) error {
+	if peer == nil {
+		return errors.New("cannot add a nil peer")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.index(peer.Name) >= 0 {
+		return fmt.Errorf("a peer named '%s' already exists", peer.Name)
+	}
+	return p.replace(-1, peer)
+}
+
+// Remove the named peer from the collection, recording when it was removed in
+// Deleted. If the named peer is not found, then an error is returned.
+func (p *Peers) Remove(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	idx := p.index(name)
+	if idx < 0 {
+		return fmt.Errorf("could not find a peer named '%s'", name)
+	}
+
+	// Copy the peers rather than removing in place so that snapshots are unchanged
+	peers := make([]*Peer, 0, len(p.Peers)-1)
+	peers = append(peers, p.Peers[:idx]...)
+	p.Peers = append(peers, p.Peers[idx+1:]...)
+
+	if p.Deleted == nil {
+		p.Deleted = make(map[string]time.Time)
+	}
+	p.Deleted[name] = time.Now().UTC()
+	return nil
+}
+
+// Update replaces the peer with the same name in the collection. An error is
+// returned and the collection is not modified if the named peer is not found or
+// if the collection would not be valid with the updated peer.
+func (p *Peers) Update(peer *Peer) error {
+	if peer == nil {
+		return errors.New("cannot update a nil peer")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	idx := p.index(peer.Name)
+	if idx < 0 {
+		return fmt.Errorf("could not find a peer named '%s'", peer.Name)
+	}
+	return p.replace(idx, peer)
+}
+
+// Upsert updates the peer with the same name or adds the peer to the collection
+// if it does not exist, returning true if the peer was added. An error is
+// returned and the collection is not modified if it would not be valid.
+func (p *Peers) Upsert(peer *Peer) (added bool, err error) {
+	if peer == nil {
+		return false, errors.New("cannot upsert a nil peer")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	idx := p.index(peer.Name)
+	return idx < 0, p.replace(idx, peer)
+}
+
+// Returns the index of the named peer or -1 if it is not found (not thread-safe).
+func (p *Peers) index(name string) int {
+	for idx, peer := range p.Peers {
+		if peer != nil && peer.Name == name {
+			return idx
+		}
+	}
+	return -1
+}
+
+// Replaces the peer at the index or appends the peer if the index is negative,
+// then validates the collection. The peers are copied so that snapshots are not
+// modified, and the original peers are restored if they are not valid (not
+// thread-safe).
+func (p *Peers) replace(idx int, peer *Peer) error {
+	orig := p.Peers
+	peers := make([]*Peer, len(orig), len(orig)+1)
+	copy(peers, orig)
+
+	if idx < 0 {
+		peers = append(peers, peer)
+	} else {
+		peers[idx] = peer
+	}
+
+	p.Peers = peers
+	if err := p.validate(); err != nil {
+		p.Peers = orig
+		return err
+	}
+
+	p.revive()
+	return nil
+}
+
+// Clears the tombstones of the peers in the collection, e.g. when a removed peer
+// is added again (not thread-safe).
+func (p *Peers) revive() {
+	if len(p.Deleted) == 0 {
+		return
+	}
+
+	for _, peer := range p.Peers {
+		if peer != nil {
+			delete(p.Deleted, peer.Name)
+		}
+	}
 }
 
 //===========================================================================
@@ -261,6 +545,12 @@ type Peer struct {
 	Domain      string `json:"domain,omitempty"`      // the domain name of hte host
 	Port        uint16 `json:"port"`                  // the port the replica is listening on
 
+	// When the peer was last observed, nil if it has never been observed
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+
+	// Credentials to connect to the peer with TLS or mTLS, nil for plaintext
+	TLS *TLSInfo `json:"tls,omitempty"`
+
 	// Extra information that may be associated with the host
 	AWSInstance map[string]string `json:"aws_instance,omitempty"`
 }
@@ -278,14 +568,27 @@ func (p *Peer) IsLocal() bool {
 	return name == hostname
 }
 
+// Region returns the cloud region of the peer from its instance metadata, e.g.
+// the region of an imported EC2 instance, or an empty string if it is unknown.
+func (p *Peer) Region() string {
+	return p.AWSInstance["region"]
+}
+
 // Endpoint returns an string with the ip address and the port (or the domain)
-// to connect to the peer using TCP.
+// to connect to the peer using TCP. If the peer has no ip address, its hostname
+// or domain is used instead.
 func (p *Peer) Endpoint(dns bool) string {
 	if dns && p.Domain != "" {
 		return fmt.Sprintf("%s:%d", p.Domain, p.Port)
 	}
 
-	return fmt.Sprintf("%s:%d", p.IPAddr, p.Port)
+	host := p.IPAddr
+	for _, name := range []string{p.Hostname, p.Domain} {
+		if host == "" {
+			host = name
+		}
+	}
+	return fmt.Sprintf("%s:%d", host, p.Port)
 }
 
 // ZMQEndpoint returns an endpoint to bind or connect on specifically for the
@@ -3,6 +3,8 @@ package peers
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -107,3 +109,149 @@ func TestLocalhost(t *testing.T) {
 		t.Error("did not find right localhost with arguments")
 	}
 }
+
+// Test that peers can be added, updated, and removed from the collection.
+func TestPeersMutation(t *testing.T) {
+	peers := new(Peers)
+	if err := peers.Add(&Peer{PID: 1, Name: "alpha", IPAddr: "10.10.10.1", Port: 3264}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := peers.Add(&Peer{PID: 2, Name: "alpha", IPAddr: "10.10.10.2", Port: 3264}); err == nil {
+		t.Error("expected error adding a peer with a duplicate name")
+	}
+
+	if err := peers.Add(&Peer{PID: 1, Name: "bravo", IPAddr: "10.10.10.2", Port: 3264}); err == nil {
+		t.Error("expected error adding a peer with a duplicate pid")
+	}
+
+	snapshot := peers.List()
+	if err := peers.Update(&Peer{PID: 1, Name: "alpha", IPAddr: "10.10.10.3", Port: 3264}); err != nil {
+		t.Fatal(err)
+	}
+
+	if snapshot[0].IPAddr != "10.10.10.1" {
+		t.Error("expected update not to modify the snapshot")
+	}
+
+	if peer, _ := peers.Get("alpha"); peer.IPAddr != "10.10.10.3" {
+		t.Error("expected peer to be updated")
+	}
+
+	if err := peers.Update(&Peer{PID: 2, Name: "bravo", IPAddr: "10.10.10.2", Port: 3264}); err == nil {
+		t.Error("expected error updating a peer that does not exist")
+	}
+
+	if err := peers.Update(&Peer{PID: 1, Name: "alpha", IPAddr: "not an ip", Port: 3264}); err == nil {
+		t.Error("expected error updating a peer with an invalid address")
+	}
+
+	if added, err := peers.Upsert(&Peer{PID: 2, Name: "bravo", IPAddr: "10.10.10.2", Port: 3264}); err != nil || !added {
+		t.Errorf("expected upsert to add bravo: %v", err)
+	}
+
+	if added, err := peers.Upsert(&Peer{PID: 2, Name: "bravo", IPAddr: "10.10.10.4", Port: 3264}); err != nil || added {
+		t.Errorf("expected upsert to update bravo: %v", err)
+	}
+
+	if err := peers.Remove("alpha"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := peers.Remove("alpha"); err == nil {
+		t.Error("expected error removing a peer that does not exist")
+	}
+
+	if peers.Len() != 1 || len(snapshot) != 1 || snapshot[0].Name != "alpha" {
+		t.Error("expected only bravo in the collection and the snapshot to be unchanged")
+	}
+}
+
+// Test that the structural changes between rosters are keyed by peer name.
+func TestPeersDiff(t *testing.T) {
+	a := &Peers{Info: map[string]interface{}{"num_replicas": 2}}
+	a.Add(&Peer{PID: 1, Name: "alpha", IPAddr: "10.10.10.1", Port: 3264})
+	a.Add(&Peer{PID: 2, Name: "bravo", IPAddr: "10.10.10.2", Port: 3264})
+
+	b := &Peers{Info: map[string]interface{}{"num_replicas": 2}}
+	b.Add(&Peer{PID: 2, Name: "bravo", IPAddr: "10.10.10.2", Port: 3265})
+	b.Add(&Peer{PID: 3, Name: "charlie", IPAddr: "10.10.10.3", Port: 3264})
+
+	changes, err := a.Diff(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"- peers.alpha",
+		"~ peers.bravo.port",
+		"+ peers.charlie",
+	}
+
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %d: %v", len(expected), len(changes), changes)
+	}
+
+	for i, change := range changes {
+		if !strings.HasPrefix(change.String(), expected[i]+":") {
+			t.Errorf("expected change %q, got %q", expected[i], change)
+		}
+	}
+
+	if changes, _ = a.Diff(a); len(changes) != 0 {
+		t.Errorf("expected no changes to identical rosters, got %v", changes)
+	}
+}
+
+// Test that all of the peers files are merged with higher precedence files
+// replacing peers by name.
+func TestLoadAll(t *testing.T) {
+	project, user, system := t.TempDir(), t.TempDir(), t.TempDir()
+	files := map[string]string{
+		filepath.Join(project, "peers.yaml"): "info: {updated: project}\nreplicas:\n  - {pid: 2, name: bravo, ip_address: 127.0.0.1, port: 3265}\n",
+		filepath.Join(user, "peers.json"):    `{"replicas": [{"pid": 3, "name": "charlie", "ip_address": "10.10.10.3", "port": 3264}]}`,
+		filepath.Join(system, "peers.json"): `{"info": {"updated": "system", "num_replicas": 2}, "replicas": [
+			{"pid": 1, "name": "alpha", "ip_address": "10.10.10.1", "port": 3264},
+			{"pid": 2, "name": "bravo", "ip_address": "10.10.10.2", "port": 3264}]}`,
+	}
+
+	for path, data := range files {
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paths := []string{
+		filepath.Join(t.TempDir(), "peers.json"), // does not exist
+		filepath.Join(project, "peers.json"),
+		filepath.Join(user, "peers.json"),
+		filepath.Join(system, "peers.json"),
+	}
+
+	peers, err := loadAll(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if peers.Len() != 3 {
+		t.Fatalf("expected 3 merged peers but got %d", peers.Len())
+	}
+
+	if peer, _ := peers.Get("bravo"); peer.IPAddr != "127.0.0.1" || peer.Port != 3265 {
+		t.Error("expected the project peer to replace the system peer")
+	}
+
+	if peers.Info["updated"] != "project" || peers.Info["num_replicas"] != float64(2) {
+		t.Errorf("expected info keys to be merged by precedence, got %v", peers.Info)
+	}
+
+	// Conflicting pids across files are reported
+	conflict := `{"replicas": [{"pid": 1, "name": "delta", "ip_address": "10.10.10.4", "port": 3264}]}`
+	if err := ioutil.WriteFile(filepath.Join(user, "peers.json"), []byte(conflict), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadAll(paths); err == nil {
+		t.Error("expected an error merging peers with the same pid")
+	}
+}
@@ -0,0 +1,200 @@
+package peers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrPoolClosed is returned when a connection is requested from a closed pool.
+var ErrPoolClosed = errors.New("connection pool is closed")
+
+//===========================================================================
+// Connection Pool
+//===========================================================================
+
+// DialFunc establishes a client connection to a peer, e.g. by calling
+// peer.DialContext(ctx, opts...) to create a gRPC client connection.
+type DialFunc[C io.Closer] func(ctx context.Context, peer *Peer) (C, error)
+
+// HealthFunc reports if a cached connection can still be used, e.g. GRPCHealthy
+// checks the connectivity state of a gRPC client connection.
+type HealthFunc[C io.Closer] func(conn C) bool
+
+// ConnectionPool lazily establishes and caches one client connection per peer so
+// that replicated systems do not have to manage connections to the roster
+// themselves. The pool is generic over the connection type so that any client
+// that can be closed can be pooled; NewGRPCPool creates a pool of gRPC client
+// connections (with the grpc build tag). Connections are evicted and redialed
+// when they are unhealthy or when the address of the peer changes, and
+// connections to peers that are removed from the roster are closed by Sweep. A
+// connection pool is safe for concurrent use; peers are dialed without holding
+// the lock of the pool so that a slow dial does not block connections to other
+// peers, and concurrent requests for the same peer share a single dial.
+type ConnectionPool[C io.Closer] struct {
+	peers   *Peers
+	dial    DialFunc[C]
+	healthy HealthFunc[C]
+	mu      sync.Mutex
+	conns   map[string]*pooled[C]
+	dialing map[string]*dialCall[C]
+	closed  bool
+}
+
+// A cached connection along with the endpoint it was dialed with.
+type pooled[C io.Closer] struct {
+	conn     C
+	endpoint string
+}
+
+// An in-flight dial to a peer; done is closed once conn and err are set.
+type dialCall[C io.Closer] struct {
+	endpoint string
+	done     chan struct{}
+	conn     C
+	err      error
+}
+
+// NewPool creates a connection pool for the peers in the roster that dials the
+// peers with the dial function. If healthy is not nil, it is called to check a
+// cached connection before it is returned and by Sweep; unhealthy connections
+// are closed and redialed.
+func NewPool[C io.Closer](peers *Peers, dial DialFunc[C], healthy HealthFunc[C]) *ConnectionPool[C] {
+	return &ConnectionPool[C]{
+		peers:   peers,
+		dial:    dial,
+		healthy: healthy,
+		conns:   make(map[string]*pooled[C]),
+		dialing: make(map[string]*dialCall[C]),
+	}
+}
+
+// Get returns the connection to the named peer, dialing the peer if there is no
+// cached connection or if the cached connection is unhealthy or was dialed to a
+// different address. If the peer is already being dialed, Get waits for that
+// dial to complete (or for the context to be done) and returns its result. An
+// error is returned if the peer is not in the roster or if it cannot be dialed.
+func (p *ConnectionPool[C]) Get(ctx context.Context, name string) (conn C, err error) {
+	var peer *Peer
+	if peer, err = p.peers.Get(name); err != nil {
+		return conn, err
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return conn, ErrPoolClosed
+	}
+
+	endpoint := peer.Endpoint(false)
+	if cached, ok := p.conns[name]; ok {
+		if cached.endpoint == endpoint && (p.healthy == nil || p.healthy(cached.conn)) {
+			p.mu.Unlock()
+			return cached.conn, nil
+		}
+		p.evict(name)
+	}
+
+	// Wait for the in-flight dial to the same endpoint rather than dialing again
+	if call, ok := p.dialing[name]; ok && call.endpoint == endpoint {
+		p.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.conn, call.err
+		case <-ctx.Done():
+			return conn, ctx.Err()
+		}
+	}
+
+	call := &dialCall[C]{endpoint: endpoint, done: make(chan struct{})}
+	p.dialing[name] = call
+	p.mu.Unlock()
+
+	if call.conn, call.err = p.dial(ctx, peer); call.err != nil {
+		call.err = fmt.Errorf("could not dial peer '%s': %s", name, call.err)
+	}
+
+	p.mu.Lock()
+	if p.dialing[name] == call {
+		delete(p.dialing, name)
+	}
+
+	if call.err == nil {
+		if p.closed {
+			call.conn.Close()
+			call.conn, call.err = conn, ErrPoolClosed
+		} else {
+			// A connection cached by a dial to a different endpoint is replaced
+			p.evict(name)
+			p.conns[name] = &pooled[C]{conn: call.conn, endpoint: endpoint}
+		}
+	}
+	p.mu.Unlock()
+
+	close(call.done)
+	return call.conn, call.err
+}
+
+// Evict closes and removes the cached connection to the named peer, if any, so
+// that the next call to Get dials the peer again.
+func (p *ConnectionPool[C]) Evict(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.evict(name)
+}
+
+// Sweep evicts the connections that are unhealthy or to peers that have been
+// removed from the roster or whose address changed, returning the names of the
+// peers whose connections were evicted. Long running services can call Sweep
+// periodically or when a PeerChangeEvent is dispatched.
+func (p *ConnectionPool[C]) Sweep() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	evicted := make([]string, 0)
+	for name, cached := range p.conns {
+		peer, err := p.peers.Get(name)
+		if err == nil && cached.endpoint == peer.Endpoint(false) && (p.healthy == nil || p.healthy(cached.conn)) {
+			continue
+		}
+
+		p.evict(name)
+		evicted = append(evicted, name)
+	}
+	return evicted
+}
+
+// Len returns the number of cached connections.
+func (p *ConnectionPool[C]) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.conns)
+}
+
+// Close all of the cached connections; connections cannot be requested from the
+// pool once it is closed. The first error closing a connection is returned.
+func (p *ConnectionPool[C]) Close() (err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	for name := range p.conns {
+		if cerr := p.evict(name); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Closes and removes the cached connection (not thread-safe).
+func (p *ConnectionPool[C]) evict(name string) error {
+	cached, ok := p.conns[name]
+	if !ok {
+		return nil
+	}
+
+	delete(p.conns, name)
+	return cached.conn.Close()
+}
@@ -0,0 +1,185 @@
+package peers
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// A fake client connection that records if it has been closed.
+type testConn struct {
+	endpoint string
+	healthy  bool
+	closed   bool
+}
+
+func (c *testConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+// Test that connections are lazily dialed, cached, and evicted.
+func TestConnectionPool(t *testing.T) {
+	roster := &Peers{Peers: []*Peer{
+		{PID: 1, Name: "alpha", IPAddr: "10.10.10.1", Port: 3264},
+		{PID: 2, Name: "bravo", IPAddr: "10.10.10.2", Port: 3264},
+	}}
+
+	dials := 0
+	dial := func(ctx context.Context, peer *Peer) (*testConn, error) {
+		dials++
+		return &testConn{endpoint: peer.Endpoint(false), healthy: true}, nil
+	}
+
+	pool := NewPool(roster, dial, func(conn *testConn) bool { return conn.healthy })
+	ctx := context.Background()
+
+	alpha, err := pool.Get(ctx, "alpha")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if alpha.endpoint != "10.10.10.1:3264" {
+		t.Errorf("unexpected endpoint %q", alpha.endpoint)
+	}
+
+	// Cached connections are reused
+	if conn, _ := pool.Get(ctx, "alpha"); conn != alpha || dials != 1 {
+		t.Error("expected the cached connection to be returned")
+	}
+
+	if _, err = pool.Get(ctx, "charlie"); err == nil {
+		t.Error("expected an error for a peer that is not in the roster")
+	}
+
+	// Unhealthy connections are closed and redialed
+	alpha.healthy = false
+	conn, err := pool.Get(ctx, "alpha")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if conn == alpha || !alpha.closed || dials != 2 {
+		t.Error("expected the unhealthy connection to be evicted and redialed")
+	}
+
+	// Connections to peers whose address changed or were removed are swept
+	bravo, _ := pool.Get(ctx, "bravo")
+	if err = roster.Update(&Peer{PID: 1, Name: "alpha", IPAddr: "10.10.10.3", Port: 3264}); err != nil {
+		t.Fatal(err)
+	}
+
+	if evicted := pool.Sweep(); len(evicted) != 1 || evicted[0] != "alpha" || !conn.closed {
+		t.Errorf("expected the connection to alpha to be swept, got %v", evicted)
+	}
+
+	if err = roster.Remove("bravo"); err != nil {
+		t.Fatal(err)
+	}
+
+	if evicted := pool.Sweep(); len(evicted) != 1 || !bravo.closed || pool.Len() != 0 {
+		t.Errorf("expected the connection to bravo to be swept, got %v", evicted)
+	}
+
+	// Closing the pool closes all connections
+	alpha, _ = pool.Get(ctx, "alpha")
+	if err = pool.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !alpha.closed || pool.Len() != 0 {
+		t.Error("expected all connections to be closed")
+	}
+
+	if _, err = pool.Get(ctx, "alpha"); err != ErrPoolClosed {
+		t.Errorf("expected ErrPoolClosed, got %v", err)
+	}
+}
+
+// Test that a slow dial does not block connections to other peers and that
+// concurrent requests for the same peer share a single dial.
+func TestConnectionPoolConcurrentDial(t *testing.T) {
+	roster := &Peers{Peers: []*Peer{
+		{PID: 1, Name: "alpha", IPAddr: "10.10.10.1", Port: 3264},
+		{PID: 2, Name: "bravo", IPAddr: "10.10.10.2", Port: 3264},
+	}}
+
+	var dials int32
+	started, release := make(chan struct{}), make(chan struct{})
+	dial := func(ctx context.Context, peer *Peer) (*testConn, error) {
+		atomic.AddInt32(&dials, 1)
+		if peer.Name == "alpha" {
+			close(started)
+			<-release
+		}
+		return &testConn{endpoint: peer.Endpoint(false), healthy: true}, nil
+	}
+
+	pool := NewPool(roster, dial, nil)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	conns := make([]*testConn, 4)
+	for i := range conns {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conns[i], _ = pool.Get(ctx, "alpha")
+		}(i)
+
+		if i == 0 {
+			<-started
+		}
+	}
+
+	// Other peers are dialed while alpha is being dialed
+	if _, err := pool.Get(ctx, "bravo"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Waiting for the dial to alpha respects the context
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := pool.Get(canceled, "alpha"); err != context.Canceled {
+		t.Errorf("expected the context to be canceled, got %v", err)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&dials); n != 2 {
+		t.Errorf("expected alpha and bravo to be dialed once each, dialed %d times", n)
+	}
+
+	for _, conn := range conns {
+		if conn == nil || conn != conns[0] {
+			t.Fatal("expected every request for alpha to share the same connection")
+		}
+	}
+
+	if conn, _ := pool.Get(ctx, "alpha"); conn != conns[0] || pool.Len() != 2 {
+		t.Error("expected the shared connection to be cached")
+	}
+}
+
+// Test that a connection dialed while the pool is closed is not cached.
+func TestConnectionPoolCloseDuringDial(t *testing.T) {
+	roster := &Peers{Peers: []*Peer{{PID: 1, Name: "alpha", IPAddr: "10.10.10.1", Port: 3264}}}
+
+	var pool *ConnectionPool[*testConn]
+	var dialed *testConn
+	pool = NewPool(roster, func(ctx context.Context, peer *Peer) (*testConn, error) {
+		pool.Close()
+		dialed = &testConn{}
+		return dialed, nil
+	}, nil)
+
+	if _, err := pool.Get(context.Background(), "alpha"); err != ErrPoolClosed {
+		t.Errorf("expected ErrPoolClosed, got %v", err)
+	}
+
+	if !dialed.closed || pool.Len() != 0 {
+		t.Error("expected the connection dialed while closing to be closed")
+	}
+}
@@ -0,0 +1,113 @@
+package peers
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+//===========================================================================
+// Selection Queries
+//===========================================================================
+
+// Filter returns a new collection with the peers for which the predicate returns
+// true, in roster order. Selectors return views of the collection that share the
+// same peers (which are replaced rather than modified by mutations) and a copy of
+// the info, so they can be composed to pick a subset of the replicas, e.g.:
+//
+//	roster.ByRegion("us-east-1").Random(3)
+//
+// Selectors exclude stale peers, i.e. peers that have not been observed within
+// the TTL of the collection (see SetTTL). Views are not associated with the file
+// the collection was loaded from, so they cannot be dumped without a path or
+// watched.
+func (p *Peers) Filter(predicate func(*Peer) bool) *Peers {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	peers := make([]*Peer, 0, len(p.Peers))
+	for _, peer := range p.fresh() {
+		if predicate(peer) {
+			peers = append(peers, peer)
+		}
+	}
+	return p.view(peers)
+}
+
+// ByRegion returns a new collection with the peers in the specified region, as
+// determined by the Region of each peer.
+func (p *Peers) ByRegion(region string) *Peers {
+	return p.Filter(func(peer *Peer) bool {
+		return peer.Region() == region
+	})
+}
+
+// ByPIDRange returns a new collection with the peers whose pids are between min
+// and max inclusive.
+func (p *Peers) ByPIDRange(min, max uint32) *Peers {
+	return p.Filter(func(peer *Peer) bool {
+		return peer.PID >= min && peer.PID <= max
+	})
+}
+
+// Random returns a new collection with n peers chosen at random, in random
+// order, e.g. to select gossip partners. If n is greater than the number of
+// peers, all of the peers are returned in random order.
+func (p *Peers) Random(n int) *Peers {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	peers := p.fresh()
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+
+	if n < 0 {
+		n = 0
+	}
+
+	if n < len(peers) {
+		peers = peers[:n]
+	}
+	return p.view(peers)
+}
+
+// Quorum returns a new collection with the smallest majority of the peers that
+// are not stale, i.e. n/2+1 of them, chosen by precedence (the peers with the
+// lowest pids) so that every caller selects the same quorum. An empty collection
+// has an empty quorum.
+func (p *Peers) Quorum() *Peers {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	peers := p.fresh()
+	sort.SliceStable(peers, func(i, j int) bool { return peers[i].PID < peers[j].PID })
+
+	if len(peers) > 0 {
+		peers = peers[:len(peers)/2+1]
+	}
+	return p.view(peers)
+}
+
+// Returns a copy of the peers that are not stale (not thread-safe).
+func (p *Peers) fresh() []*Peer {
+	now := time.Now()
+	peers := make([]*Peer, 0, len(p.Peers))
+	for _, peer := range p.Peers {
+		if peer != nil && !peer.stale(p.ttl, now) {
+			peers = append(peers, peer)
+		}
+	}
+	return peers
+}
+
+// Returns a new collection with the peers, the TTL, and a copy of the info (not
+// thread-safe, the caller must hold at least a read lock).
+func (p *Peers) view(peers []*Peer) *Peers {
+	var info map[string]interface{}
+	if p.Info != nil {
+		info = make(map[string]interface{}, len(p.Info))
+		for key, val := range p.Info {
+			info[key] = val
+		}
+	}
+	return &Peers{Info: info, Peers: peers, ttl: p.ttl}
+}
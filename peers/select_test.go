@@ -0,0 +1,68 @@
+package peers
+
+import (
+	"testing"
+)
+
+// Test that selectors return composable views of the collection.
+func TestSelect(t *testing.T) {
+	roster := &Peers{Info: map[string]interface{}{"num_replicas": 5}}
+	for i, region := range []string{"us-east-1", "us-west-2", "us-east-1", "eu-west-1", "us-east-1"} {
+		peer := &Peer{PID: uint32(5 - i), Name: string(rune('a' + i)), IPAddr: "10.10.10.1", Port: uint16(3264 + i)}
+		peer.AWSInstance = map[string]string{"region": region}
+		if err := roster.Add(peer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(p *Peers) (s string) {
+		for _, peer := range p.List() {
+			s += peer.Name
+		}
+		return s
+	}
+
+	if s := names(roster.ByRegion("us-east-1")); s != "ace" {
+		t.Errorf("expected peers in us-east-1 to be ace but got %q", s)
+	}
+
+	if s := names(roster.ByPIDRange(2, 4)); s != "bcd" {
+		t.Errorf("expected peers with pids 2-4 to be bcd but got %q", s)
+	}
+
+	if s := names(roster.ByRegion("us-east-1").ByPIDRange(1, 3)); s != "ce" {
+		t.Errorf("expected composed selectors to return ce but got %q", s)
+	}
+
+	if s := names(roster.Filter(func(p *Peer) bool { return p.Port > 3265 })); s != "cde" {
+		t.Errorf("expected filtered peers to be cde but got %q", s)
+	}
+
+	// The quorum is the majority of peers with the lowest pids
+	if s := names(roster.Quorum()); s != "edc" {
+		t.Errorf("expected quorum to be edc but got %q", s)
+	}
+
+	if n := new(Peers).Quorum().Len(); n != 0 {
+		t.Errorf("expected an empty quorum but got %d peers", n)
+	}
+
+	if n := roster.Random(3).Len(); n != 3 {
+		t.Errorf("expected 3 random peers but got %d", n)
+	}
+
+	if n := roster.Random(10).Len(); n != 5 {
+		t.Errorf("expected all 5 peers but got %d", n)
+	}
+
+	// Views do not modify the collection they were selected from
+	view := roster.ByRegion("eu-west-1")
+	view.Info["num_replicas"] = 1
+	if err := view.Remove("d"); err != nil {
+		t.Fatal(err)
+	}
+
+	if roster.Len() != 5 || roster.Info["num_replicas"] != 5 {
+		t.Error("expected the view not to modify the collection")
+	}
+}
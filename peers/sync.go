@@ -0,0 +1,533 @@
+package peers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/bbengfort/x/events"
+	"github.com/bbengfort/x/stats"
+)
+
+// Environment variables used to configure synchronization.
+const (
+	envSyncURL    = "PEERS_SYNC_URL"
+	envSyncAPIKey = "PEERS_SYNC_APIKEY"
+)
+
+// Default intervals of the syncer if none are specified.
+const (
+	DefaultSyncInterval = time.Minute
+	DefaultSyncTimeout  = 5 * time.Second
+)
+
+//===========================================================================
+// Background Synchronization
+//===========================================================================
+
+// Syncer periodically refreshes a peers collection from a remote service. The
+// ETag and Last-Modified headers of the last response are sent with each
+// request so that the roster is only downloaded when it has changed. When the
+// roster changes, the callbacks registered with OnAdd, OnRemove, and OnChange
+// are invoked and, if a Dispatcher is specified, a PeerChangeEvent is
+// dispatched for every changed peer; errors during background synchronization
+// are dispatched as an ErrorEvent. If a Metrics registry is specified, the
+// number of syncs, failures, the time of the last sync, and the health of the
+// peers are recorded in it.
+//
+// The syncer replaces the peers and info of the collection in a single update,
+// so readers of the collection never see a partially synchronized roster.
+type Syncer struct {
+	URL        string             // url endpoint for the sync GET request
+	APIKey     string             // key to add to headers as X-Api-Key
+	Interval   time.Duration      // how often to refresh the peers in the background
+	Timeout    time.Duration      // the timeout of each sync request
+	Dispatcher *events.Dispatcher // if not nil, peer changes and errors are dispatched
+	Metrics    *stats.Registry    // if not nil, sync and peer health metrics are recorded
+
+	sync.Mutex
+	peers    *Peers                  // the collection being synchronized
+	etag     string                  // the etag of the last successful response
+	modified string                  // the last modified header of the last successful response
+	onAdd    []func(*Peer)           // called when a peer is added to the roster
+	onRemove []func(*Peer)           // called when a peer is removed from the roster
+	onChange []func(prev, cur *Peer) // called when a peer in the roster is changed
+	stop     chan struct{}           // stops background synchronization
+	done     chan struct{}           // closed when background synchronization has stopped
+}
+
+// NewSyncer creates a syncer for the peers collection that looks up the url and
+// api key from the environment, expecting $PEERS_SYNC_URL and $PEERS_SYNC_APIKEY
+// as described by Sync. If peers is nil, a new collection is created.
+func NewSyncer(peers *Peers) (*Syncer, error) {
+	url := os.Getenv(envSyncURL)
+	if url == "" {
+		return nil, errors.New("could not find $PEERS_SYNC_URL")
+	}
+
+	key := os.Getenv(envSyncAPIKey)
+	if key == "" {
+		return nil, errors.New("could not find $PEERS_SYNC_APIKEY")
+	}
+
+	return NewSyncerFrom(peers, url, key), nil
+}
+
+// NewSyncerFrom creates a syncer for the peers collection with the specified url
+// and api key. If peers is nil, a new collection is created.
+func NewSyncerFrom(peers *Peers, url, apikey string) *Syncer {
+	if peers == nil {
+		peers = new(Peers)
+	}
+
+	return &Syncer{
+		URL:      url,
+		APIKey:   apikey,
+		Interval: DefaultSyncInterval,
+		Timeout:  DefaultSyncTimeout,
+		peers:    peers,
+	}
+}
+
+// Peers returns the collection that is being synchronized.
+func (s *Syncer) Peers() *Peers {
+	return s.peers
+}
+
+// OnAdd registers a callback that is called when a peer is added to the roster.
+func (s *Syncer) OnAdd(callback func(peer *Peer)) {
+	s.Lock()
+	defer s.Unlock()
+	s.onAdd = append(s.onAdd, callback)
+}
+
+// OnRemove registers a callback that is called when a peer is removed from the
+// roster.
+func (s *Syncer) OnRemove(callback func(peer *Peer)) {
+	s.Lock()
+	defer s.Unlock()
+	s.onRemove = append(s.onRemove, callback)
+}
+
+// OnChange registers a callback that is called with the previous and current
+// definition of a peer when it changes in the roster.
+func (s *Syncer) OnChange(callback func(prev, cur *Peer)) {
+	s.Lock()
+	defer s.Unlock()
+	s.onChange = append(s.onChange, callback)
+}
+
+// Sync fetches the peers from the remote service and updates the collection,
+// returning true if the roster was downloaded, i.e. the service did not respond
+// that the roster is not modified. Callbacks are invoked after the collection
+// is updated; if dispatching a peer change event fails, the error is returned.
+func (s *Syncer) Sync() (updated bool, err error) {
+	defer func(start time.Time) {
+		s.record(start, err != nil && !updated)
+	}(time.Now())
+
+	s.Lock()
+	etag, modified := s.etag, s.modified
+	s.Unlock()
+
+	var remote *Peers
+	if remote, etag, modified, err = fetch(s.URL, s.APIKey, etag, modified, s.Timeout); err != nil {
+		return false, err
+	}
+
+	// The roster has not been modified since the last sync, but was observed
+	if remote == nil {
+		s.peers.seenAll()
+		return false, nil
+	}
+
+	s.Lock()
+	s.etag, s.modified = etag, modified
+	onAdd, onRemove, onChange := s.onAdd, s.onRemove, s.onChange
+	s.Unlock()
+
+	added, removed, changed := s.peers.replaceAll(remote)
+	s.peers.seenAll()
+
+	for _, peer := range removed {
+		for _, callback := range onRemove {
+			callback(peer)
+		}
+	}
+
+	for _, peer := range added {
+		for _, callback := range onAdd {
+			callback(peer)
+		}
+	}
+
+	for _, pair := range changed {
+		for _, callback := range onChange {
+			callback(pair[0], pair[1])
+		}
+	}
+
+	return true, dispatchChanges(s.Dispatcher, added, removed, changed)
+}
+
+// Start synchronizing the peers every interval in a background go routine until
+// Stop is called. The peers are synchronized immediately when started.
+func (s *Syncer) Start() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.stop != nil {
+		return errors.New("syncer is already running")
+	}
+
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultSyncInterval
+	}
+
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	go s.run(interval, s.stop, s.done)
+	return nil
+}
+
+// Stop background synchronization, waiting for an in-flight sync to complete.
+func (s *Syncer) Stop() {
+	s.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// Synchronizes the peers every interval, dispatching errors to the dispatcher.
+func (s *Syncer) run(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Sync(); err != nil && s.Dispatcher != nil {
+			s.Dispatcher.Dispatch(events.ErrorEvent, &events.Error{Source: "peers sync", Err: err})
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+//===========================================================================
+// Push and Bidirectional Synchronization
+//===========================================================================
+
+// Push the peers collection to a remote service with an HTTP PUT request of the
+// serialized collection, adding the api key to the headers as "X-Api-Key". The
+// collection is validated before it is pushed so that an invalid roster is never
+// sent to the remote service.
+func (p *Peers) Push(url, apikey string) (err error) {
+	p.mu.RLock()
+	if err = p.validate(); err != nil {
+		p.mu.RUnlock()
+		return err
+	}
+
+	var data []byte
+	data, err = json.Marshal(p)
+	p.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	return push(url, apikey, data, DefaultSyncTimeout)
+}
+
+// SyncBoth merges the remote and local rosters then updates both the local
+// collection and, if it differs from the merged roster, the remote service. If a
+// peer is defined differently in both rosters, the definition from the roster
+// with the later Info["updated"] timestamp wins (the local roster wins ties);
+// peers that are only in one roster are kept unless the other roster removed
+// the peer after the roster with the peer was updated. The tombstones of removed
+// peers are merged so that deletions propagate in both directions. If the merged
+// roster contains peers from both rosters or drops a removed peer, its updated
+// timestamp is set to the current time.
+func (p *Peers) SyncBoth(url, apikey string) (err error) {
+	var remote *Peers
+	if remote, _, _, err = fetch(url, apikey, "", "", DefaultSyncTimeout); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	info, peers, deleted := merge(p, remote)
+
+	// Validate the merged roster before modifying the local collection
+	orig, origInfo, origDeleted := p.Peers, p.Info, p.Deleted
+	p.Info, p.Peers, p.Deleted = info, peers, deleted
+	if err = p.check(); err != nil {
+		p.Info, p.Peers, p.Deleted = origInfo, orig, origDeleted
+		p.mu.Unlock()
+		return fmt.Errorf("could not merge peers: %w", err)
+	}
+
+	unchanged := reflect.DeepEqual(remote.Peers, peers) && reflect.DeepEqual(remote.Info, info) && reflect.DeepEqual(remote.Deleted, deleted)
+	var data []byte
+	data, err = json.Marshal(p)
+	p.mu.Unlock()
+
+	if err != nil || unchanged {
+		return err
+	}
+	return push(url, apikey, data, DefaultSyncTimeout)
+}
+
+// Merges the local and remote rosters, returning the merged info, peers, and
+// tombstones (not thread-safe, the local collection must be locked). A peer is
+// dropped if it was removed after the roster it is in was last updated; the
+// tombstones of the peers in the merged roster are discarded.
+func merge(local, remote *Peers) (info map[string]interface{}, peers []*Peer, deleted map[string]time.Time) {
+	newer, older := local, remote
+	if updated(remote).After(updated(local)) {
+		newer, older = remote, local
+	}
+
+	for _, roster := range []*Peers{older, newer} {
+		for name, ts := range roster.Deleted {
+			if deleted == nil {
+				deleted = make(map[string]time.Time, len(local.Deleted)+len(remote.Deleted))
+			}
+			if ts.After(deleted[name]) {
+				deleted[name] = ts
+			}
+		}
+	}
+
+	dropped := 0
+	names := make(map[string]struct{}, len(newer.Peers))
+	peers = make([]*Peer, 0, len(newer.Peers)+len(older.Peers))
+	for _, roster := range []*Peers{newer, older} {
+		ts := updated(roster)
+		for _, peer := range roster.Peers {
+			if _, ok := names[peer.Name]; ok {
+				continue
+			}
+
+			names[peer.Name] = struct{}{}
+			if deleted[peer.Name].After(ts) {
+				dropped++
+				continue
+			}
+			peers = append(peers, peer)
+		}
+	}
+
+	for _, peer := range peers {
+		delete(deleted, peer.Name)
+	}
+
+	if len(deleted) == 0 {
+		deleted = nil
+	}
+
+	info = make(map[string]interface{}, len(newer.Info)+1)
+	for key, val := range newer.Info {
+		info[key] = val
+	}
+
+	if dropped > 0 || len(peers) != len(newer.Peers) {
+		info["updated"] = time.Now().UTC().Format(time.RFC3339Nano)
+		if _, ok := info["num_replicas"]; ok {
+			info["num_replicas"] = len(peers)
+		}
+	}
+	return info, peers, deleted
+}
+
+// Returns the Info["updated"] timestamp of the peers or the zero time if it is
+// missing or cannot be parsed.
+func updated(p *Peers) time.Time {
+	switch val := p.Info["updated"].(type) {
+	case time.Time:
+		return val
+	case string:
+		if ts, err := time.Parse(time.RFC3339Nano, val); err == nil {
+			return ts
+		}
+	}
+	return time.Time{}
+}
+
+// Replaces the info and peers of the collection with the remote collection and
+// returns the peers that were added and removed, and the previous and current
+// definitions of the peers that changed, in roster order. The latest time that
+// each peer was last seen is kept and is not considered a change to the peer.
+func (p *Peers) replaceAll(remote *Peers) (added, removed []*Peer, changed [][2]*Peer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current := make(map[string]*Peer, len(p.Peers))
+	for _, peer := range p.Peers {
+		current[peer.Name] = peer
+	}
+
+	peers := make([]*Peer, len(remote.Peers))
+	names := make(map[string]struct{}, len(remote.Peers))
+	for idx, peer := range remote.Peers {
+		names[peer.Name] = struct{}{}
+		prev, ok := current[peer.Name]
+		if ok && prev.LastSeen != nil && (peer.LastSeen == nil || prev.LastSeen.After(*peer.LastSeen)) {
+			peer = seen(peer, *prev.LastSeen)
+		}
+		peers[idx] = peer
+
+		switch {
+		case !ok:
+			added = append(added, peer)
+		case !equal(prev, peer):
+			changed = append(changed, [2]*Peer{prev, peer})
+		}
+	}
+
+	for _, peer := range p.Peers {
+		if _, ok := names[peer.Name]; !ok {
+			removed = append(removed, peer)
+		}
+	}
+
+	p.Info, p.Peers, p.Deleted = remote.Info, peers, remote.Deleted
+	return added, removed, changed
+}
+
+// Returns true if the peers are the same other than when they were last seen.
+func equal(a, b *Peer) bool {
+	ca, cb := *a, *b
+	ca.LastSeen, cb.LastSeen = nil, nil
+	return reflect.DeepEqual(&ca, &cb)
+}
+
+// Dispatches a PeerChangeEvent for every removed, added, and changed peer if the
+// dispatcher is not nil, stopping at the first error.
+func dispatchChanges(d *events.Dispatcher, added, removed []*Peer, changed [][2]*Peer) (err error) {
+	if d == nil {
+		return nil
+	}
+
+	for _, peer := range removed {
+		if err = d.Dispatch(events.PeerChangeEvent, peerChange(events.PeerRemoved, peer)); err != nil {
+			return err
+		}
+	}
+
+	for _, peer := range added {
+		if err = d.Dispatch(events.PeerChangeEvent, peerChange(events.PeerAdded, peer)); err != nil {
+			return err
+		}
+	}
+
+	for _, pair := range changed {
+		if err = d.Dispatch(events.PeerChangeEvent, peerChange(events.PeerUpdated, pair[1])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Creates the value of a PeerChangeEvent for the peer.
+func peerChange(kind events.PeerChangeKind, peer *Peer) *events.PeerChange {
+	return &events.PeerChange{
+		Kind: kind,
+		PID:  uint16(peer.PID),
+		Name: peer.Name,
+		Addr: peer.Endpoint(false),
+	}
+}
+
+// Conducts a PUT request of the serialized peers with the api key.
+func push(url, apikey string, data []byte, timeout time.Duration) (err error) {
+	client := &http.Client{Timeout: timeout}
+	var req *http.Request
+	if req, err = http.NewRequest("PUT", url, bytes.NewReader(data)); err != nil {
+		return err
+	}
+
+	req.Header.Set("X-Api-Key", apikey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	var resp *http.Response
+	if resp, err = client.Do(req); err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("could not push peers: %s", resp.Status)
+	}
+	return nil
+}
+
+// Conducts a GET request for the peers with the api key, returning the valid
+// peers and the etag and last modified headers of the response. If the etag or
+// last modified time are specified, they are sent as conditional headers and
+// nil peers are returned if the service responds that they are not modified.
+func fetch(url, apikey, etag, modified string, timeout time.Duration) (_ *Peers, _, _ string, err error) {
+	if timeout <= 0 {
+		timeout = DefaultSyncTimeout
+	}
+
+	client := &http.Client{Timeout: timeout}
+	var req *http.Request
+	if req, err = http.NewRequest("GET", url, nil); err != nil {
+		return nil, "", "", err
+	}
+
+	req.Header.Set("X-Api-Key", apikey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	if modified != "" {
+		req.Header.Set("If-Modified-Since", modified)
+	}
+
+	var resp *http.Response
+	if resp, err = client.Do(req); err != nil {
+		return nil, "", "", err
+	}
+
+	// Ensure connection is closed on complete
+	defer resp.Body.Close()
+
+	// Check the status from the client
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, etag, modified, nil
+	default:
+		return nil, "", "", fmt.Errorf("could not synchronize peers: %s", resp.Status)
+	}
+
+	// Parse the body of the response
+	peers := new(Peers)
+	if err = json.NewDecoder(resp.Body).Decode(peers); err != nil {
+		return nil, "", "", err
+	}
+
+	if err = peers.check(); err != nil {
+		return nil, "", "", err
+	}
+	return peers, resp.Header.Get("ETag"), resp.Header.Get("Last-Modified"), nil
+}
@@ -0,0 +1,296 @@
+package peers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bbengfort/x/events"
+)
+
+// Test that the syncer only downloads the roster when it changes and notifies
+// callbacks and the dispatcher of the changes.
+func TestSyncer(t *testing.T) {
+	var version, downloads int32
+	atomic.StoreInt32(&version, 1)
+
+	rosters := map[int32]string{
+		1: `{"info": {"updated": "1"}, "replicas": [
+			{"pid": 1, "name": "alpha", "ip_address": "10.10.10.1", "port": 3264},
+			{"pid": 2, "name": "bravo", "ip_address": "10.10.10.2", "port": 3264}]}`,
+		2: `{"info": {"updated": "2"}, "replicas": [
+			{"pid": 2, "name": "bravo", "ip_address": "10.10.10.4", "port": 3264},
+			{"pid": 3, "name": "charlie", "ip_address": "10.10.10.3", "port": 3264}]}`,
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		vers := atomic.LoadInt32(&version)
+		etag := fmt.Sprintf(`"v%d"`, vers)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		atomic.AddInt32(&downloads, 1)
+		w.Header().Set("ETag", etag)
+		fmt.Fprint(w, rosters[vers])
+	}))
+	defer srv.Close()
+
+	dispatcher := new(events.Dispatcher)
+	dispatcher.Init(nil)
+	dispatcher.ValidateStandard()
+
+	var changes []string
+	dispatcher.Register(events.PeerChangeEvent, func(e events.Event) error {
+		change := e.Value().(*events.PeerChange)
+		changes = append(changes, change.Kind.String()+" "+change.Name)
+		return nil
+	})
+
+	syncer := NewSyncerFrom(nil, srv.URL, "secret")
+	syncer.Dispatcher = dispatcher
+
+	var added, removed, changed []string
+	syncer.OnAdd(func(peer *Peer) { added = append(added, peer.Name) })
+	syncer.OnRemove(func(peer *Peer) { removed = append(removed, peer.Name) })
+	syncer.OnChange(func(prev, cur *Peer) { changed = append(changed, prev.IPAddr+" "+cur.IPAddr) })
+
+	if updated, err := syncer.Sync(); err != nil || !updated {
+		t.Fatalf("expected initial sync to download the roster: %v", err)
+	}
+
+	if syncer.Peers().Len() != 2 || len(added) != 2 {
+		t.Errorf("expected two peers to be added but got %v", added)
+	}
+
+	// The roster is not downloaded again if it has not changed
+	if updated, err := syncer.Sync(); err != nil || updated {
+		t.Fatalf("expected the roster not to be modified: %v", err)
+	}
+
+	if n := atomic.LoadInt32(&downloads); n != 1 {
+		t.Errorf("expected one download but got %d", n)
+	}
+
+	atomic.StoreInt32(&version, 2)
+	if updated, err := syncer.Sync(); err != nil || !updated {
+		t.Fatalf("expected the changed roster to be downloaded: %v", err)
+	}
+
+	if len(added) != 3 || added[2] != "charlie" {
+		t.Errorf("expected charlie to be added but got %v", added)
+	}
+
+	if len(removed) != 1 || removed[0] != "alpha" {
+		t.Errorf("expected alpha to be removed but got %v", removed)
+	}
+
+	if len(changed) != 1 || changed[0] != "10.10.10.2 10.10.10.4" {
+		t.Errorf("expected bravo to be changed but got %v", changed)
+	}
+
+	if syncer.Peers().Info["updated"] != "2" {
+		t.Error("expected the roster info to be synchronized")
+	}
+
+	expected := []string{"added alpha", "added bravo", "removed alpha", "added charlie", "updated bravo"}
+	if fmt.Sprint(changes) != fmt.Sprint(expected) {
+		t.Errorf("expected peer change events %v but got %v", expected, changes)
+	}
+
+	// Errors are returned and do not modify the roster
+	syncer.APIKey = "wrong"
+	if _, err := syncer.Sync(); err == nil {
+		t.Error("expected an error with the wrong api key")
+	}
+
+	if syncer.Peers().Len() != 2 {
+		t.Error("expected the roster to be unchanged after an error")
+	}
+}
+
+// A remote service that stores the roster that is pushed to it.
+type rosterServer struct {
+	sync.Mutex
+	roster []byte
+	pushes int
+}
+
+func (s *rosterServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Write(s.roster)
+	case http.MethodPut:
+		s.roster, _ = ioutil.ReadAll(r.Body)
+		s.pushes++
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Test that the local roster can be pushed to and merged with a remote roster.
+func TestPushSyncBoth(t *testing.T) {
+	remote := &rosterServer{}
+	srv := httptest.NewServer(remote)
+	defer srv.Close()
+
+	local := new(Peers)
+	local.Info = map[string]interface{}{"updated": "2021-01-01T00:00:00Z"}
+	local.Add(&Peer{PID: 1, Name: "alpha", IPAddr: "10.10.10.1", Port: 3264})
+	local.Add(&Peer{PID: 2, Name: "bravo", IPAddr: "10.10.10.2", Port: 3264})
+
+	if err := local.Push(srv.URL, "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The remote roster is newer and changes bravo and adds charlie
+	remote.roster = []byte(`{"info": {"updated": "2021-01-02T00:00:00Z"}, "replicas": [
+		{"pid": 2, "name": "bravo", "ip_address": "10.10.10.4", "port": 3264},
+		{"pid": 3, "name": "charlie", "ip_address": "10.10.10.3", "port": 3264}]}`)
+
+	// The local roster adds delta
+	local.Add(&Peer{PID: 4, Name: "delta", IPAddr: "10.10.10.5", Port: 3264})
+
+	if err := local.SyncBoth(srv.URL, "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	if local.Len() != 4 {
+		t.Fatalf("expected 4 merged peers but got %d", local.Len())
+	}
+
+	if bravo, _ := local.Get("bravo"); bravo.IPAddr != "10.10.10.4" {
+		t.Error("expected the newer remote definition of bravo to win")
+	}
+
+	if remote.pushes != 2 {
+		t.Errorf("expected the merged roster to be pushed but got %d pushes", remote.pushes)
+	}
+
+	merged, err := LoadFrom(writeTemp(t, remote.roster))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if merged.Len() != 4 || !updated(merged).After(time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Error("expected the merged roster with a new updated timestamp on the remote")
+	}
+
+	// Nothing is pushed if the remote is already up to date
+	if err := local.SyncBoth(srv.URL, "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	if remote.pushes != 2 {
+		t.Errorf("expected no push when the remote is up to date but got %d pushes", remote.pushes)
+	}
+}
+
+func TestSyncBothDeleted(t *testing.T) {
+	remote := &rosterServer{}
+	srv := httptest.NewServer(remote)
+	defer srv.Close()
+
+	// The remote roster has alpha, bravo, and charlie
+	remote.roster = []byte(`{"info": {"updated": "2021-01-02T00:00:00Z"}, "replicas": [
+		{"pid": 1, "name": "alpha", "ip_address": "10.10.10.1", "port": 3264},
+		{"pid": 2, "name": "bravo", "ip_address": "10.10.10.2", "port": 3264},
+		{"pid": 3, "name": "charlie", "ip_address": "10.10.10.3", "port": 3264}]}`)
+	stale := remote.roster
+
+	local := new(Peers)
+	local.Info = map[string]interface{}{"updated": "2021-01-01T00:00:00Z"}
+	if err := local.SyncBoth(srv.URL, "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Removing a peer locally removes it from the remote roster
+	if err := local.Remove("charlie"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := local.Deleted["charlie"]; !ok {
+		t.Fatal("expected a tombstone for the removed peer")
+	}
+
+	if err := local.SyncBoth(srv.URL, "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := local.Get("charlie"); err == nil {
+		t.Error("expected the removed peer not to be restored by the remote roster")
+	}
+
+	merged, err := LoadFrom(writeTemp(t, remote.roster))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if merged.Len() != 2 {
+		t.Errorf("expected the removed peer to be deleted from the remote but got %d peers", merged.Len())
+	}
+
+	if _, ok := merged.Deleted["charlie"]; !ok {
+		t.Error("expected the tombstone to be pushed to the remote")
+	}
+
+	// A stale roster that was updated before the removal does not restore the peer
+	other, err := LoadFrom(writeTemp(t, stale))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := other.SyncBoth(srv.URL, "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	if other.Len() != 2 {
+		t.Errorf("expected the removal to propagate to the stale roster but got %d peers", other.Len())
+	}
+
+	// Adding the peer again clears the tombstone and restores it on the remote
+	if err := local.Add(&Peer{PID: 3, Name: "charlie", IPAddr: "10.10.10.3", Port: 3264}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(local.Deleted) != 0 {
+		t.Error("expected the tombstone to be cleared when the peer is added")
+	}
+
+	if err := local.SyncBoth(srv.URL, "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	if merged, err = LoadFrom(writeTemp(t, remote.roster)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := merged.Get("charlie"); err != nil || len(merged.Deleted) != 0 {
+		t.Error("expected the added peer to be restored on the remote")
+	}
+}
+
+// Writes the data to a temporary file and returns its path.
+func writeTemp(t *testing.T, data []byte) string {
+	path := filepath.Join(t.TempDir(), "peers.json")
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
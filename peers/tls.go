@@ -0,0 +1,136 @@
+package peers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/bbengfort/x/ca"
+)
+
+//===========================================================================
+// TLS Credentials
+//===========================================================================
+
+// TLSInfo describes the credentials used to connect to a peer securely so that
+// the dial configuration can live alongside the address book. The CA
+// certificate verifies the certificate presented by the peer; if the client
+// certificate and key are specified they are presented to the peer for mutual
+// TLS. Paths are relative to the working directory of the process.
+type TLSInfo struct {
+	CACert     string `json:"ca_cert,omitempty"`     // path to the PEM encoded CA certificate(s) that signed the peer's certificate
+	Cert       string `json:"cert,omitempty"`        // path to the PEM encoded client certificate for mTLS
+	Key        string `json:"key,omitempty"`         // path to the PEM encoded client private key for mTLS
+	ServerName string `json:"server_name,omitempty"` // the name to verify the peer's certificate with, the hostname or ip address if empty
+}
+
+// UseCA sets the TLS credentials of the peer to the files written by the ca
+// package (or the ca command) to the specified directory: the CA certificate in
+// ca.crt and the certificate and key issued to the peer in name.crt and
+// name.key, where name is the name of the peer. If the certificate was issued
+// with a chain, then the bundle in name.bundle.crt is used instead.
+func (p *Peer) UseCA(dir string) {
+	info := &TLSInfo{
+		CACert: filepath.Join(dir, ca.CertFile),
+		Cert:   filepath.Join(dir, p.Name+".crt"),
+		Key:    filepath.Join(dir, p.Name+".key"),
+	}
+
+	if bundle := filepath.Join(dir, p.Name+".bundle.crt"); exists(bundle) {
+		info.Cert = bundle
+	}
+
+	if p.TLS != nil {
+		info.ServerName = p.TLS.ServerName
+	}
+	p.TLS = info
+}
+
+// Subject returns the subject to issue the peer a certificate with from a ca.CA
+// so that the certificate can be verified by the hostname, domain, or ip
+// address of the peer, e.g. authority.Issue(peer.Subject()).
+func (p *Peer) Subject() ca.Subject {
+	subject := ca.Subject{CommonName: p.Name}
+	for _, name := range []string{p.Hostname, p.Domain} {
+		if name != "" {
+			subject.DNSNames = append(subject.DNSNames, name)
+		}
+	}
+
+	if ip := net.ParseIP(p.IPAddr); ip != nil {
+		subject.IPAddresses = append(subject.IPAddresses, ip)
+	}
+	return subject
+}
+
+// TLSConfig returns the configuration to dial the peer with TLS, verifying the
+// certificate of the peer with the CA certificate and presenting the client
+// certificate if one is specified for mutual TLS. An error is returned if the
+// peer does not have TLS credentials or if they cannot be loaded.
+func (p *Peer) TLSConfig() (_ *tls.Config, err error) {
+	if p.TLS == nil {
+		return nil, fmt.Errorf("peer '%s' has no TLS credentials", p.Name)
+	}
+
+	conf := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: p.TLS.ServerName,
+	}
+
+	if conf.ServerName == "" {
+		conf.ServerName = p.Hostname
+		if conf.ServerName == "" {
+			conf.ServerName = p.IPAddr
+		}
+	}
+
+	if p.TLS.CACert != "" {
+		if conf.RootCAs, err = certPool(p.TLS.CACert); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.TLS.Cert != "" || p.TLS.Key != "" {
+		if p.TLS.Cert == "" || p.TLS.Key == "" {
+			return nil, errors.New("both a client certificate and key are required for mTLS")
+		}
+
+		var cert tls.Certificate
+		if cert, err = tls.LoadX509KeyPair(p.TLS.Cert, p.TLS.Key); err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %s", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	return conf, nil
+}
+
+// Returns a certificate pool with the PEM encoded certificates in the file.
+func certPool(path string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	certs, err := ca.ParseCertificates(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %s", path, err)
+	}
+
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return pool, nil
+}
+
+// Returns true if the file exists.
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,129 @@
+package peers
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/bbengfort/x/ca"
+)
+
+// Test that a peer can be dialed with mTLS using credentials issued by the ca.
+func TestTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peers-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	authority := ca.New(dir)
+	authority.KeyBits = 1024
+	if err = authority.Init(ca.Subject{Organization: "Testing"}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	server := &Peer{PID: 1, Name: "alpha", Hostname: "localhost", IPAddr: "127.0.0.1"}
+	client := &Peer{PID: 2, Name: "bravo", IPAddr: "127.0.0.1"}
+	for _, peer := range []*Peer{server, client} {
+		cert, err := authority.Issue(peer.Subject())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = cert.Write(dir, peer.Name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err = server.TLSConfig(); err == nil {
+		t.Error("expected an error for a peer without TLS credentials")
+	}
+
+	// The server requires a client certificate issued by the ca
+	server.UseCA(dir)
+	serverConf, err := server.TLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConf.ClientCAs = serverConf.RootCAs
+	serverConf.ClientAuth = tls.RequireAndVerifyClientCert
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", serverConf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	peer := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			peer <- err.Error()
+			return
+		}
+		defer conn.Close()
+
+		tlsConn := conn.(*tls.Conn)
+		if err := tlsConn.Handshake(); err != nil {
+			peer <- err.Error()
+			return
+		}
+		peer <- tlsConn.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}()
+
+	// The client dials the server with the credentials of the client peer
+	client.UseCA(dir)
+	client.TLS.ServerName = server.Hostname
+	conf, err := client.TLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if name := <-peer; name != "bravo" {
+		t.Errorf("expected the server to verify client bravo, got %q", name)
+	}
+
+	// The server name defaults to the hostname or the ip address of the peer
+	client.TLS.ServerName = ""
+	if conf, _ = client.TLSConfig(); conf.ServerName != "127.0.0.1" {
+		t.Errorf("expected server name 127.0.0.1, got %q", conf.ServerName)
+	}
+
+	// A client certificate without a key is an error
+	client.TLS.Key = ""
+	if _, err = client.TLSConfig(); err == nil {
+		t.Error("expected an error for a client certificate without a key")
+	}
+
+	// A CA certificate that does not exist is an error
+	client.TLS = &TLSInfo{CACert: "testdata/missing.crt"}
+	if _, err = client.TLSConfig(); err == nil {
+		t.Error("expected an error for a missing CA certificate")
+	}
+}
+
+// Test that the subject of a peer includes its names and address.
+func TestPeerSubject(t *testing.T) {
+	peer := &Peer{Name: "alpha", Hostname: "alpha.local", Domain: "alpha.example.com", IPAddr: "10.10.10.1"}
+	subject := peer.Subject()
+
+	if subject.CommonName != "alpha" {
+		t.Errorf("expected common name alpha, got %q", subject.CommonName)
+	}
+
+	if len(subject.DNSNames) != 2 || subject.DNSNames[1] != "alpha.example.com" {
+		t.Errorf("unexpected dns names %v", subject.DNSNames)
+	}
+
+	if len(subject.IPAddresses) != 1 || !subject.IPAddresses[0].Equal(net.IPv4(10, 10, 10, 1)) {
+		t.Errorf("unexpected ip addresses %v", subject.IPAddresses)
+	}
+}
@@ -0,0 +1,119 @@
+package peers
+
+import (
+	"fmt"
+	"time"
+)
+
+//===========================================================================
+// Staleness and TTL
+//===========================================================================
+
+// SetTTL sets how long a peer may go unobserved before it is considered stale.
+// Peers are observed when they are marked as Seen, e.g. by a health check, when
+// they are discovered, or when they are synchronized from a remote service.
+// Stale peers are excluded from the selectors (Filter, ByRegion, ByPIDRange,
+// Random, and Quorum) so that membership decays gracefully as peers go away.
+// Peers that have never been observed are never stale, so that statically
+// configured peers are not excluded. A TTL of zero (the default) disables
+// staleness; views returned by the selectors have the same TTL.
+func (p *Peers) SetTTL(ttl time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ttl = ttl
+}
+
+// TTL returns how long a peer may go unobserved before it is considered stale.
+func (p *Peers) TTL() time.Duration {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.ttl
+}
+
+// Seen marks the named peer as observed at the current time. If the named peer
+// is not found, then an error is returned.
+func (p *Peers) Seen(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	idx := p.index(name)
+	if idx < 0 {
+		return fmt.Errorf("could not find a peer named '%s'", name)
+	}
+
+	p.Peers[idx] = seen(p.Peers[idx], time.Now())
+	return nil
+}
+
+// Stale returns a new collection with the peers that have not been observed
+// within the TTL, e.g. to log or to remove them.
+func (p *Peers) Stale() *Peers {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	now := time.Now()
+	peers := make([]*Peer, 0)
+	for _, peer := range p.Peers {
+		if peer != nil && peer.stale(p.ttl, now) {
+			peers = append(peers, peer)
+		}
+	}
+	return p.view(peers)
+}
+
+// Prune removes the peers that have not been observed within the TTL from the
+// collection, returning the peers that were removed.
+func (p *Peers) Prune() []*Peer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	pruned := make([]*Peer, 0)
+	peers := make([]*Peer, 0, len(p.Peers))
+	for _, peer := range p.Peers {
+		if peer != nil && peer.stale(p.ttl, now) {
+			pruned = append(pruned, peer)
+			continue
+		}
+		peers = append(peers, peer)
+	}
+
+	if len(pruned) > 0 {
+		p.Peers = peers
+	}
+	return pruned
+}
+
+// Stale returns true if the peer has been observed but not within the TTL. Peers
+// that have never been observed are never stale.
+func (p *Peer) Stale(ttl time.Duration) bool {
+	return p.stale(ttl, time.Now())
+}
+
+func (p *Peer) stale(ttl time.Duration, now time.Time) bool {
+	return ttl > 0 && p.LastSeen != nil && now.Sub(*p.LastSeen) > ttl
+}
+
+// Returns a copy of the peer observed at the specified time; the peer is copied
+// rather than modified so that snapshots of the collection are unchanged.
+func seen(peer *Peer, now time.Time) *Peer {
+	observed := *peer
+	observed.LastSeen = &now
+	return &observed
+}
+
+// Marks every peer in the collection as observed at the current time.
+func (p *Peers) seenAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	peers := make([]*Peer, len(p.Peers))
+	for idx, peer := range p.Peers {
+		if peer != nil {
+			peer = seen(peer, now)
+		}
+		peers[idx] = peer
+	}
+	p.Peers = peers
+}
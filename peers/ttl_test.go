@@ -0,0 +1,83 @@
+package peers
+
+import (
+	"testing"
+	"time"
+)
+
+// Test that peers that have not been seen within the TTL are stale and are
+// excluded from selectors.
+func TestStale(t *testing.T) {
+	old := time.Now().Add(-time.Hour)
+	roster := new(Peers)
+	roster.Add(&Peer{PID: 1, Name: "alpha", IPAddr: "10.10.10.1", Port: 3264, LastSeen: &old})
+	roster.Add(&Peer{PID: 2, Name: "bravo", IPAddr: "10.10.10.2", Port: 3264, LastSeen: &old})
+	roster.Add(&Peer{PID: 3, Name: "charlie", IPAddr: "10.10.10.3", Port: 3264})
+
+	// Without a TTL no peers are stale
+	if n := roster.Stale().Len(); n != 0 {
+		t.Errorf("expected no stale peers without a ttl but got %d", n)
+	}
+
+	roster.SetTTL(time.Minute)
+	if roster.TTL() != time.Minute {
+		t.Error("expected the ttl to be set")
+	}
+
+	snapshot := roster.List()
+	if err := roster.Seen("bravo"); err != nil {
+		t.Fatal(err)
+	}
+
+	if snapshot[1].LastSeen != &old {
+		t.Error("expected seen not to modify the snapshot")
+	}
+
+	if err := roster.Seen("delta"); err == nil {
+		t.Error("expected an error marking an unknown peer as seen")
+	}
+
+	// Alpha is stale, bravo was just seen, and charlie has never been observed
+	stale := roster.Stale()
+	if stale.Len() != 1 || stale.Peers[0].Name != "alpha" {
+		t.Errorf("expected only alpha to be stale but got %d peers", stale.Len())
+	}
+
+	if n := roster.Filter(func(*Peer) bool { return true }).Len(); n != 2 {
+		t.Errorf("expected selectors to exclude the stale peer but got %d peers", n)
+	}
+
+	if q := roster.Quorum(); q.Len() != 2 || q.Peers[0].Name != "bravo" {
+		t.Error("expected the quorum to be selected from the peers that are not stale")
+	}
+
+	if roster.Random(3).TTL() != time.Minute {
+		t.Error("expected views to have the same ttl")
+	}
+
+	pruned := roster.Prune()
+	if len(pruned) != 1 || pruned[0].Name != "alpha" || roster.Len() != 2 {
+		t.Error("expected alpha to be pruned from the roster")
+	}
+}
+
+// Test that replacing the roster keeps the latest time the peers were seen and
+// does not consider it a change.
+func TestReplaceAllSeen(t *testing.T) {
+	now := time.Now()
+	roster := new(Peers)
+	roster.Add(&Peer{PID: 1, Name: "alpha", IPAddr: "10.10.10.1", Port: 3264, LastSeen: &now})
+
+	remote := &Peers{Peers: []*Peer{{PID: 1, Name: "alpha", IPAddr: "10.10.10.1", Port: 3264}}}
+	if added, removed, changed := roster.replaceAll(remote); len(added)+len(removed)+len(changed) != 0 {
+		t.Error("expected no changes to the roster")
+	}
+
+	if peer, _ := roster.Get("alpha"); peer.LastSeen == nil || !peer.LastSeen.Equal(now) {
+		t.Error("expected the time the peer was last seen to be kept")
+	}
+
+	if remote.Peers[0].LastSeen != nil {
+		t.Error("expected the remote peers not to be modified")
+	}
+}
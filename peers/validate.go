@@ -0,0 +1,177 @@
+package peers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ValidationError aggregates all of the problems found in a peers collection
+// so that they can be fixed at once rather than one at a time. Each problem is
+// reported on its own line.
+type ValidationError struct {
+	Problems []string
+}
+
+// Error returns the problems with the peers collection, one per line.
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return "invalid peers: " + e.Problems[0]
+	}
+	return fmt.Sprintf("invalid peers (%d problems):\n  %s", len(e.Problems), strings.Join(e.Problems, "\n  "))
+}
+
+// Adds a problem to the validation error.
+func (e *ValidationError) add(format string, a ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, a...))
+}
+
+// Validate the peers collection, ensuring that every peer has a unique name
+// and precedence id, an address to reach it by (a valid ip address, a
+// hostname, or a domain), and a port that does not collide with the port of
+// another peer on the same host (by ip address, hostname, or domain).
+// All of the problems found are returned as a *ValidationError, or nil if the
+// peers are valid.
+//
+// Validate is called by Load (unless SkipValidation is set) and can be used
+// before Dump to ensure that an invalid peers.json file is not written to disk.
+func (p *Peers) Validate() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.validate()
+}
+
+// SkipValidation disables the validation of peers that are loaded by Load,
+// Lookup, LoadFrom, LoadAll, and Peers.Load or synchronized by Sync, SyncFrom,
+// SyncBoth, and a Syncer, e.g. so that a tool can load an invalid peers.json
+// file to repair it. Validate can still be called explicitly, and Add, Update,
+// and Upsert still reject changes that would make the collection invalid. It
+// should be set before peers are loaded since it is not safe for concurrent use.
+var SkipValidation = false
+
+// Validates the loaded or synchronized peers unless SkipValidation is set (not
+// thread-safe).
+func (p *Peers) check() error {
+	if SkipValidation {
+		return nil
+	}
+	return p.validate()
+}
+
+// Validates the peers collection (not thread-safe).
+func (p *Peers) validate() error {
+	verr := new(ValidationError)
+	names := make(map[string]int)
+	pids := make(map[uint32]string)
+	addrs := make(map[string]string)
+
+	for idx, peer := range p.Peers {
+		if peer == nil {
+			verr.add("peer %d is null", idx+1)
+			continue
+		}
+
+		// Identify the peer by name if it has one, otherwise by position
+		ident := fmt.Sprintf("peer %d", idx+1)
+		if peer.Name == "" {
+			verr.add("%s missing name", ident)
+		} else {
+			ident = fmt.Sprintf("peer %q", peer.Name)
+			if names[peer.Name]++; names[peer.Name] == 2 {
+				verr.add("duplicate peer name %q", peer.Name)
+			}
+		}
+
+		if peer.PID == 0 {
+			verr.add("%s missing pid", ident)
+		} else if other, ok := pids[peer.PID]; ok {
+			verr.add("%s has duplicate pid %d (also used by %s)", ident, peer.PID, other)
+		} else {
+			pids[peer.PID] = ident
+		}
+
+		// A peer can be reached by its ip address, hostname, or domain
+		if peer.IPAddr == "" && peer.Hostname == "" && peer.Domain == "" {
+			verr.add("%s missing ip_address, hostname, or domain", ident)
+		} else if peer.IPAddr != "" && net.ParseIP(peer.IPAddr) == nil {
+			verr.add("%s has invalid ip_address %q", ident, peer.IPAddr)
+		}
+
+		if peer.Port == 0 {
+			verr.add("%s missing port", ident)
+			continue
+		}
+
+		// Peers on the same host cannot listen on the same port; the collision
+		// is reported once even if the peers share both the ip and hostname
+		var collision string
+		for _, host := range hosts(peer) {
+			addr := net.JoinHostPort(host, strconv.Itoa(int(peer.Port)))
+			if other, ok := addrs[addr]; !ok {
+				addrs[addr] = ident
+			} else if collision == "" {
+				collision = fmt.Sprintf("%s port %d collides with %s on host %s", ident, peer.Port, other, host)
+			}
+		}
+
+		if collision != "" {
+			verr.add(collision)
+		}
+	}
+
+	if len(verr.Problems) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// Returns the hosts that identify where the peer listens: its ip address if it
+// is valid and its hostname and domain, if specified.
+func hosts(peer *Peer) []string {
+	hosts := make([]string, 0, 3)
+	if ip := net.ParseIP(peer.IPAddr); ip != nil {
+		hosts = append(hosts, ip.String())
+	}
+
+	// The domain is often the same name as the hostname
+	for _, name := range []string{peer.Hostname, peer.Domain} {
+		name = strings.ToLower(strings.TrimSuffix(name, "."))
+		if name == "" || (len(hosts) > 0 && hosts[len(hosts)-1] == name) {
+			continue
+		}
+		hosts = append(hosts, name)
+	}
+	return hosts
+}
+
+// Unmarshals the peers JSON data, reporting the line of syntax and type errors
+// so that problems in hand-edited peers.json files are easy to find.
+func unmarshal(data []byte, p *Peers) error {
+	err := json.Unmarshal(data, p)
+	if err == nil {
+		return nil
+	}
+
+	var offset int64
+	var serr *json.SyntaxError
+	var terr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &serr):
+		offset = serr.Offset
+	case errors.As(err, &terr):
+		offset = terr.Offset
+	default:
+		return err
+	}
+
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	line := bytes.Count(data[:offset], []byte("\n")) + 1
+	return fmt.Errorf("line %d: %s", line, err)
+}
@@ -0,0 +1,249 @@
+package peers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Test that the valid peers fixture has no problems.
+func TestValidate(t *testing.T) {
+	peers := new(Peers)
+	if err := peers.Load("testdata/peers.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := peers.Validate(); err != nil {
+		t.Errorf("expected valid peers but got %s", err)
+	}
+
+	// Invalidate a peer before dumping it
+	peers.Peers[1].IPAddr = "bravo"
+	if err := peers.Validate(); err == nil {
+		t.Error("expected invalid ip address to fail validation")
+	}
+}
+
+// Test that all of the problems with the peers are reported when loaded.
+func TestLoadInvalid(t *testing.T) {
+	peers := new(Peers)
+	err := peers.Load("testdata/invalid.json")
+	if err == nil {
+		t.Fatal("expected invalid peers to fail validation")
+	}
+
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected a validation error but got %T", err)
+	}
+
+	expected := []string{
+		`duplicate peer name "alpha"`,
+		`peer "alpha" has duplicate pid 1 (also used by peer "alpha")`,
+		`peer "alpha" has invalid ip_address "10.10.10.300"`,
+		`peer 3 missing name`,
+		`peer 3 missing ip_address, hostname, or domain`,
+		`peer "delta" missing port`,
+	}
+
+	if len(verr.Problems) != len(expected) {
+		t.Fatalf("expected %d problems but got %d:\n%s", len(expected), len(verr.Problems), err)
+	}
+
+	for i, problem := range expected {
+		if verr.Problems[i] != problem {
+			t.Errorf("expected problem %q but got %q", problem, verr.Problems[i])
+		}
+	}
+
+	if lines := strings.Split(err.Error(), "\n"); len(lines) != len(expected)+1 {
+		t.Errorf("expected one problem per line but got %q", err.Error())
+	}
+
+	if peers.path != "" {
+		t.Error("path should not be stored for invalid peers")
+	}
+
+	if len(peers.Peers) != 0 {
+		t.Errorf("invalid peers should not be loaded but got %d peers", len(peers.Peers))
+	}
+}
+
+// Test that loading an invalid file does not modify a loaded collection.
+func TestLoadInvalidReplace(t *testing.T) {
+	peers := new(Peers)
+	if err := peers.Load("testdata/peers.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := peers.Load("testdata/invalid.json"); err == nil {
+		t.Fatal("expected invalid peers to fail validation")
+	}
+
+	if len(peers.Peers) != 6 || peers.path != "testdata/peers.json" {
+		t.Errorf("expected the loaded peers to be unmodified but got %d peers from %q", len(peers.Peers), peers.path)
+	}
+
+	if err := peers.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+// Test that the Load entry point stops at the first file that exists rather
+// than falling back to a file with lower priority if it is invalid.
+func TestLoadStopsAtInvalid(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("PEERS_PATH", "testdata/invalid.json")
+
+	peers := Load()
+	if len(peers.Peers) != 0 {
+		t.Errorf("expected an empty collection but got %d peers", len(peers.Peers))
+	}
+
+	if peers.path != "" {
+		t.Error("path should not be stored for invalid peers")
+	}
+
+	if _, err := Lookup(); err == nil {
+		t.Error("expected lookup to report the invalid peers file")
+	}
+
+	peers, err := lookup([]string{"testdata/missing.json", "testdata/invalid.json", "testdata/peers.json"})
+	if _, ok := err.(*ValidationError); !ok {
+		t.Errorf("expected a validation error but got %v", err)
+	}
+
+	if len(peers.Peers) != 0 {
+		t.Errorf("expected lookup not to fall back to a valid file but got %d peers", len(peers.Peers))
+	}
+
+	if peers, err = lookup([]string{"testdata/missing.json", "testdata/peers.json"}); err != nil || peers.path != "testdata/peers.json" {
+		t.Errorf("expected missing files to be skipped, got %v", err)
+	}
+
+	if peers, err = lookup([]string{"testdata/missing.json"}); err != nil || len(peers.Peers) != 0 {
+		t.Errorf("expected an empty collection without an error, got %v", err)
+	}
+}
+
+// Test that a peer can be addressed by its ip address, hostname, or domain.
+func TestValidateAddress(t *testing.T) {
+	peers := &Peers{Peers: []*Peer{
+		{PID: 1, Name: "alpha", IPAddr: "10.10.10.1", Port: 3264},
+		{PID: 2, Name: "bravo", Hostname: "bravo", Port: 3264},
+		{PID: 3, Name: "charlie", Domain: "charlie.example.com", Port: 3264},
+		{PID: 4, Name: "delta", Hostname: "delta.example.com", Domain: "delta.example.com.", Port: 3264},
+	}}
+
+	if err := peers.Validate(); err != nil {
+		t.Fatalf("expected valid peers but got %s", err)
+	}
+
+	for i, endpoint := range []string{"10.10.10.1:3264", "bravo:3264", "charlie.example.com:3264", "delta.example.com:3264"} {
+		if ep := peers.Peers[i].Endpoint(false); ep != endpoint {
+			t.Errorf("expected endpoint %q but got %q", endpoint, ep)
+		}
+	}
+
+	peers.Peers = append(peers.Peers, &Peer{PID: 5, Name: "echo", Domain: "Charlie.example.com", Port: 3264}, &Peer{PID: 6, Name: "foxtrot", Port: 3264})
+	verr, ok := peers.Validate().(*ValidationError)
+	if !ok || len(verr.Problems) != 2 {
+		t.Fatalf("expected two problems but got %v", verr)
+	}
+
+	if !strings.Contains(verr.Problems[0], "collides with peer \"charlie\"") || verr.Problems[1] != `peer "foxtrot" missing ip_address, hostname, or domain` {
+		t.Errorf("unexpected problems %q", verr.Problems)
+	}
+}
+
+// Test that syntax errors report the line they occurred on.
+func TestLoadSyntaxError(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "peers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	path := filepath.Join(tmpdir, "peers.json")
+	if err := ioutil.WriteFile(path, []byte("{\n\t\"replicas\": [\n\t\t{\"pid\": \"one\"}\n\t]\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	peers := new(Peers)
+	err = peers.Load(path)
+	if err == nil {
+		t.Fatal("expected type error when loading peers")
+	}
+
+	if !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("expected error to report line 3 but got %q", err)
+	}
+}
+
+// Test that peers on the same host cannot listen on the same port.
+func TestValidatePortCollisions(t *testing.T) {
+	peers := &Peers{Peers: []*Peer{
+		{PID: 1, Name: "alpha", Hostname: "alpha.example.com", IPAddr: "10.10.10.1", Port: 3264},
+		{PID: 2, Name: "bravo", Hostname: "alpha.example.com", IPAddr: "10.10.10.1", Port: 3265},
+		{PID: 3, Name: "charlie", Hostname: "ALPHA.example.com", IPAddr: "10.10.10.1", Port: 3264},
+		{PID: 4, Name: "delta", Hostname: "delta.example.com", IPAddr: "10.10.10.4", Port: 3265},
+	}}
+
+	err := peers.Validate()
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected a validation error but got %v", err)
+	}
+
+	expected := `peer "charlie" port 3264 collides with peer "alpha" on host 10.10.10.1`
+	if len(verr.Problems) != 1 || verr.Problems[0] != expected {
+		t.Errorf("expected the port collision to be reported once, got %q", verr.Problems)
+	}
+
+	// Peers on different hosts may listen on the same port
+	peers.Peers[2].Port = 3266
+	if err = peers.Validate(); err != nil {
+		t.Errorf("expected valid peers but got %s", err)
+	}
+}
+
+// Test that validation of loaded and synchronized peers can be disabled.
+func TestSkipValidation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/invalid.json")
+	}))
+	defer srv.Close()
+
+	if _, err := LoadFrom("testdata/invalid.json"); err == nil {
+		t.Fatal("expected invalid peers to fail validation")
+	}
+
+	if _, err := SyncFrom(srv.URL, "secret"); err == nil {
+		t.Fatal("expected invalid synchronized peers to fail validation")
+	}
+
+	SkipValidation = true
+	defer func() { SkipValidation = false }()
+
+	peers, err := LoadFrom("testdata/invalid.json")
+	if err != nil || len(peers.Peers) != 4 {
+		t.Fatalf("expected the invalid peers to be loaded, got %v", err)
+	}
+
+	if peers, err = loadAll([]string{"testdata/invalid.json"}); err != nil || len(peers.Peers) != 3 {
+		t.Errorf("expected the invalid peers to be merged, got %v", err)
+	}
+
+	if peers, err = SyncFrom(srv.URL, "secret"); err != nil || len(peers.Peers) != 4 {
+		t.Errorf("expected the invalid peers to be synchronized, got %v", err)
+	}
+
+	// Explicit validation still reports the problems
+	if err = peers.Validate(); err == nil {
+		t.Error("expected the invalid peers to fail explicit validation")
+	}
+}
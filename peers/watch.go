@@ -0,0 +1,155 @@
+package peers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bbengfort/x/events"
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultWatchInterval is how often the peers file is checked for changes when
+// file system notifications are not available.
+const DefaultWatchInterval = time.Second
+
+// How long to wait after the last notification before reloading the file, so
+// that a burst of writes by an editor or a deploy tool causes a single reload.
+const watchDelay = 100 * time.Millisecond
+
+//===========================================================================
+// File Watching
+//===========================================================================
+
+// Watch the file the peers were loaded from in a background go routine until the
+// context is done, reloading the collection when the file is modified externally
+// so that long running services pick up roster changes without a restart. The
+// file is watched with file system notifications, falling back to polling the
+// modification time of the file every DefaultWatchInterval if notifications are
+// not available on the system.
+//
+// If the dispatcher is not nil, a PeerChangeEvent is dispatched for every peer
+// that changed when the file is reloaded. If the modified file cannot be read or
+// is not valid, the collection is not changed and the error is dispatched as an
+// ErrorEvent. An error is returned if the peers were not loaded from a file.
+func (p *Peers) Watch(ctx context.Context, dispatcher *events.Dispatcher) (err error) {
+	p.mu.RLock()
+	path := p.path
+	p.mu.RUnlock()
+
+	if path == "" {
+		return errors.New("no path to watch, peers must be loaded from a file")
+	}
+
+	if path, err = filepath.Abs(path); err != nil {
+		return err
+	}
+
+	report := func(err error) {
+		if dispatcher != nil {
+			dispatcher.Dispatch(events.ErrorEvent, &events.Error{Source: "peers watch", Err: err})
+		}
+	}
+
+	reload := func() {
+		if err := p.reload(path, dispatcher); err != nil {
+			report(err)
+		}
+	}
+
+	// Watch the directory rather than the file, since editors and atomic writes
+	// replace the file by renaming a new file over it.
+	var watcher *fsnotify.Watcher
+	if watcher, err = fsnotify.NewWatcher(); err == nil {
+		if err = watcher.Add(filepath.Dir(path)); err == nil {
+			go notify(ctx, watcher, path, reload, report)
+			return nil
+		}
+		watcher.Close()
+	}
+
+	// Stat the file before polling so that changes made after Watch returns are seen
+	last, _ := os.Stat(path)
+	go poll(ctx, path, DefaultWatchInterval, last, reload)
+	return nil
+}
+
+// Reloads the peers from the file, replacing the collection and dispatching the
+// changes if the file is valid; otherwise the collection is not modified.
+func (p *Peers) reload(path string, dispatcher *events.Dispatcher) (err error) {
+	var data []byte
+	if data, err = ioutil.ReadFile(path); err != nil {
+		return err
+	}
+
+	loaded := new(Peers)
+	if err = decode(path, data, loaded); err != nil {
+		return fmt.Errorf("could not parse %s: %s", path, err)
+	}
+
+	if err = loaded.validate(); err != nil {
+		return err
+	}
+
+	added, removed, changed := p.replaceAll(loaded)
+	return dispatchChanges(dispatcher, added, removed, changed)
+}
+
+// Reloads the file after it is written or created in the watched directory,
+// closing the watcher when the context is done.
+func notify(ctx context.Context, watcher *fsnotify.Watcher, path string, reload func(), report func(error)) {
+	defer watcher.Close()
+
+	var pending <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			if filepath.Clean(event.Name) == path && event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+				pending = time.After(watchDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			report(err)
+		case <-pending:
+			pending = nil
+			reload()
+		}
+	}
+}
+
+// Reloads the file every interval if its modification time or size has changed
+// since the last time it was checked.
+func poll(ctx context.Context, path string, interval time.Duration, last os.FileInfo, reload func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(path)
+			if err != nil {
+				// The file may be in the middle of being replaced
+				continue
+			}
+
+			if last == nil || !info.ModTime().Equal(last.ModTime()) || info.Size() != last.Size() {
+				last = info
+				reload()
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package peers
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bbengfort/x/events"
+)
+
+// Test that the collection is reloaded when the file is modified and that
+// invalid modifications are reported without changing the collection.
+func TestWatch(t *testing.T) {
+	path := writeTemp(t, []byte(`{"replicas": [
+		{"pid": 1, "name": "alpha", "ip_address": "10.10.10.1", "port": 3264}]}`))
+
+	peers := new(Peers)
+	if err := peers.Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher := new(events.Dispatcher)
+	dispatcher.Init(nil)
+
+	changes := make(chan string, 8)
+	errs := make(chan error, 8)
+	dispatcher.Register(events.PeerChangeEvent, func(e events.Event) error {
+		change := e.Value().(*events.PeerChange)
+		changes <- change.Kind.String() + " " + change.Name
+		return nil
+	})
+	dispatcher.Register(events.ErrorEvent, func(e events.Event) error {
+		errs <- e.Value().(*events.Error)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := peers.Watch(ctx, dispatcher); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replace the file as an editor would with an atomic rename
+	tmp := path + ".tmp"
+	data := []byte(`{"replicas": [
+		{"pid": 1, "name": "alpha", "ip_address": "10.10.10.1", "port": 3264},
+		{"pid": 2, "name": "bravo", "ip_address": "10.10.10.2", "port": 3264}]}`)
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case change := <-changes:
+		if change != "added bravo" {
+			t.Errorf("expected bravo to be added but got %q", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the peers to be reloaded")
+	}
+
+	if peers.Len() != 2 {
+		t.Errorf("expected two peers after reload but got %d", peers.Len())
+	}
+
+	// An invalid file is reported and does not modify the collection
+	if err := ioutil.WriteFile(path, []byte(`{"replicas": [`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the invalid file to be reported")
+	}
+
+	if peers.Len() != 2 {
+		t.Errorf("expected the peers to be unchanged by an invalid file")
+	}
+
+	// Collections that were not loaded from a file cannot be watched
+	if err := new(Peers).Watch(ctx, nil); err == nil {
+		t.Error("expected an error watching peers without a path")
+	}
+}
+
+// Test that polling reloads the file when its modification time changes.
+func TestWatchPoll(t *testing.T) {
+	path := writeTemp(t, []byte(`{"replicas": []}`))
+
+	reloads := make(chan struct{}, 8)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	last, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go poll(ctx, path, 10*time.Millisecond, last, func() { reloads <- struct{}{} })
+
+	// Ensure the modification time changes even on coarse grained file systems
+	mtime := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the file to be reloaded")
+	}
+
+	select {
+	case <-reloads:
+		t.Error("expected the unchanged file not to be reloaded again")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
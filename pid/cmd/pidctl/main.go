@@ -0,0 +1,243 @@
+/*
+Command pidctl inspects and controls background processes from their PID files.
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bbengfort/x/pid"
+	cli "github.com/urfave/cli/v2"
+)
+
+func main() {
+	app := cli.NewApp()
+	app.Name = "pidctl"
+	app.Version = "1.0"
+	app.Usage = "inspect and control processes managed by pid files"
+	app.UsageText = "pidctl [--strict] cmd [cmdopts] <pidfile>"
+	app.Flags = []cli.Flag{
+		&cli.BoolFlag{
+			Name:    "strict",
+			Aliases: []string{"s"},
+			Usage:   "refuse to trust pid files owned by other users or writable by others",
+			EnvVars: []string{"PIDCTL_STRICT"},
+		},
+	}
+	app.Commands = []*cli.Command{
+		{
+			Name:      "status",
+			Usage:     "report if the process in the pid file is running",
+			UsageText: "pidctl status <pidfile>",
+			Action:    status,
+		},
+		{
+			Name:      "stop",
+			Usage:     "terminate the process and wait for it to exit",
+			UsageText: "pidctl stop [opts] <pidfile>",
+			Action:    stop,
+			Flags: []cli.Flag{
+				&cli.DurationFlag{
+					Name:    "timeout",
+					Aliases: []string{"t"},
+					Usage:   "time to wait for the process to exit",
+					Value:   10 * time.Second,
+				},
+			},
+		},
+		{
+			Name:      "kill",
+			Usage:     "kill the process immediately and free the pid file",
+			UsageText: "pidctl kill <pidfile>",
+			Action:    kill,
+		},
+		{
+			Name:      "watch",
+			Usage:     "block until the process exits",
+			UsageText: "pidctl watch [opts] <pidfile>",
+			Action:    watch,
+			Flags: []cli.Flag{
+				&cli.DurationFlag{
+					Name:    "timeout",
+					Aliases: []string{"t"},
+					Usage:   "time to wait for the process to exit (forever if zero)",
+				},
+			},
+		},
+		{
+			Name:      "signal",
+			Usage:     "send a signal to the process by name or number",
+			UsageText: "pidctl signal <pidfile> <signal>",
+			Action:    signal,
+		},
+	}
+
+	app.Run(os.Args)
+}
+
+//===========================================================================
+// CLI Commands
+//===========================================================================
+
+func status(c *cli.Context) (err error) {
+	var proc *pid.PID
+	if proc, err = load(c); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if !proc.Running() {
+		return cli.Exit(fmt.Sprintf("not running (stale pid file %s for pid %d)", proc.Path(), proc.PID), 1)
+	}
+
+	fmt.Printf("running (pid %d, ppid %d)\n", proc.PID, proc.PPID)
+	return nil
+}
+
+func stop(c *cli.Context) (err error) {
+	var proc *pid.PID
+	if proc, err = load(c); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if !proc.Running() {
+		return cli.Exit(fmt.Sprintf("process %d is not running", proc.PID), 1)
+	}
+
+	if err = proc.Signal(syscall.SIGTERM); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	deadline := time.Now().Add(c.Duration("timeout"))
+	for proc.Running() {
+		if time.Now().After(deadline) {
+			return cli.Exit(fmt.Sprintf("process %d did not exit after %s", proc.PID, c.Duration("timeout")), 1)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	fmt.Printf("stopped process %d\n", proc.PID)
+	return nil
+}
+
+func kill(c *cli.Context) (err error) {
+	var proc *pid.PID
+	if proc, err = load(c); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if err = proc.Kill(); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	// A killed process cannot clean up its own pid file
+	if err = proc.Free(); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	fmt.Printf("killed process %d\n", proc.PID)
+	return nil
+}
+
+func watch(c *cli.Context) (err error) {
+	var proc *pid.PID
+	if proc, err = load(c); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	ctx := context.Background()
+	if timeout := c.Duration("timeout"); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	event, ok := <-proc.Watch(ctx)
+	if !ok {
+		return cli.Exit(fmt.Sprintf("process %d did not exit after %s", proc.PID, c.Duration("timeout")), 1)
+	}
+
+	if event.Err != nil {
+		return cli.Exit(event.Err, 1)
+	}
+
+	fmt.Printf("process %d exited at %s\n", event.PID, event.Time.Format(time.RFC3339))
+	return nil
+}
+
+func signal(c *cli.Context) (err error) {
+	if c.NArg() != 2 {
+		return cli.Exit("specify the pid file and the signal to send", 1)
+	}
+
+	var sig syscall.Signal
+	if sig, err = parseSignal(c.Args().Get(1)); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	var proc *pid.PID
+	if proc, err = load(c); err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	if err = proc.Signal(sig); err != nil {
+		return cli.Exit(err, 1)
+	}
+	return nil
+}
+
+//===========================================================================
+// Helper Functions
+//===========================================================================
+
+// Signals that can be sent by name with or without the SIG prefix.
+var signals = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"TERM": syscall.SIGTERM,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+}
+
+// load the pid file specified by the first argument. If the argument is a
+// name rather than a path then the file is looked up in the standard path.
+func load(c *cli.Context) (*pid.PID, error) {
+	if c.NArg() == 0 {
+		return nil, fmt.Errorf("specify the pid file or name of the pid file")
+	}
+
+	path := c.Args().First()
+	if !strings.ContainsRune(path, filepath.Separator) {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			path = pid.Path(path)
+		}
+	}
+
+	proc := pid.New(path)
+	proc.Strict = c.Bool("strict")
+	if err := proc.Load(); err != nil {
+		return nil, err
+	}
+	return proc, nil
+}
+
+// parse a signal by name (e.g. HUP or SIGHUP) or by number.
+func parseSignal(s string) (syscall.Signal, error) {
+	if num, err := strconv.Atoi(s); err == nil {
+		return syscall.Signal(num), nil
+	}
+
+	name := strings.TrimPrefix(strings.ToUpper(s), "SIG")
+	if sig, ok := signals[name]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("unknown signal %q", s)
+}
@@ -0,0 +1,158 @@
+package pid
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Container describes the container the current process is running in, if any,
+// so that daemons can adjust to PID namespaces: inside a container the daemon is
+// often PID 1 and pids are reused every time the container restarts.
+type Container struct {
+	Detected  bool   // true if the process is running in a container
+	Runtime   string // the container runtime, e.g. docker, podman, kubernetes, or lxc
+	ID        string // the container id if it could be determined from the cgroups
+	Init      bool   // true if the process is PID 1 in its namespace
+	Namespace string // the PID namespace of the process, e.g. pid:[4026531836]
+}
+
+var (
+	containerOnce sync.Once
+	container     *Container
+	runDirOnce    sync.Once
+	runDir        string
+)
+
+// Matches container ids (64 hex characters) in cgroup and mountinfo paths.
+var containerID = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// Cgroup path components that identify the container runtime.
+var cgroupRuntimes = []struct {
+	marker  string
+	runtime string
+}{
+	{"kubepods", "kubernetes"},
+	{"libpod", "podman"},
+	{"docker", "docker"},
+	{"containerd", "containerd"},
+	{"lxc", "lxc"},
+}
+
+// ContainerInfo returns information about the container the current process is
+// running in. On Linux the container is detected by the marker files created by
+// docker and podman, the $container environment variable, and by inspecting the
+// cgroups of the process; on other systems the process is never in a container.
+// The detection is performed once and cached.
+func ContainerInfo() Container {
+	containerOnce.Do(func() {
+		container = detectContainer("/")
+		container.Init = os.Getpid() == 1
+	})
+	return *container
+}
+
+// Detects if the process is running in a container using the filesystem rooted
+// at root (which allows detection to be tested against a fake root).
+func detectContainer(root string) *Container {
+	info := &Container{}
+	if runtime.GOOS != "linux" {
+		return info
+	}
+
+	if link, err := os.Readlink(filepath.Join(root, "proc", "self", "ns", "pid")); err == nil {
+		info.Namespace = link
+	}
+
+	// Runtimes identified by cgroup paths, which also contain the container id
+	for _, name := range []string{"cgroup", "mountinfo"} {
+		for _, line := range readLines(filepath.Join(root, "proc", "self", name)) {
+			for _, rt := range cgroupRuntimes {
+				if !strings.Contains(line, rt.marker) {
+					continue
+				}
+
+				// Mountinfo lists container paths for volumes, only trust it with an id
+				id := containerID.FindString(line)
+				if name == "mountinfo" && id == "" {
+					continue
+				}
+
+				info.Detected = true
+				if info.Runtime == "" {
+					info.Runtime = rt.runtime
+				}
+				if info.ID == "" {
+					info.ID = id
+				}
+			}
+		}
+
+		if info.Detected {
+			break
+		}
+	}
+
+	// Runtimes identified by marker files or the environment take precedence
+	switch {
+	case exists(filepath.Join(root, ".dockerenv")):
+		info.Detected, info.Runtime = true, "docker"
+	case exists(filepath.Join(root, "run", ".containerenv")):
+		info.Detected, info.Runtime = true, "podman"
+	case os.Getenv("container") != "":
+		info.Detected, info.Runtime = true, os.Getenv("container")
+	case info.Runtime != "kubernetes" && os.Getenv("KUBERNETES_SERVICE_HOST") != "":
+		info.Detected, info.Runtime = true, "kubernetes"
+	}
+	return info
+}
+
+// Returns the lines of the file or nil if it cannot be read.
+func readLines(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// Returns true if the path exists.
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Returns the first writable directory of /run, /var/run, and the temp directory
+// for PID files in a container. The directories are probed once and cached so
+// that computing a path does not create and remove a file every time.
+func containerRunDir() string {
+	runDirOnce.Do(func() {
+		runDir = writableDir("/run", filepath.Join("/", "var", "run"), os.TempDir())
+	})
+	return runDir
+}
+
+// Returns the first directory that exists and is writable by the process.
+func writableDir(dirs ...string) string {
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			if f, err := os.CreateTemp(dir, ".pid-*"); err == nil {
+				f.Close()
+				os.Remove(f.Name())
+				return dir
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package pid
+
+import (
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/bbengfort/x/fixtures"
+)
+
+// Test that containers are detected from cgroups and marker files.
+func TestDetectContainer(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("container detection is only supported on linux")
+	}
+
+	t.Setenv("container", "")
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+
+	id := "3f1c2d4e5b6a79880a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f6071"
+	tests := []struct {
+		files   map[string]string
+		runtime string
+		id      string
+	}{
+		{map[string]string{"proc/self/cgroup": "0::/\n"}, "", ""},
+		{map[string]string{"proc/self/cgroup": "12:pids:/docker/" + id + "\n0::/\n"}, "docker", id},
+		{map[string]string{"proc/self/cgroup": "0::/kubepods/besteffort/pod1234/" + id + "\n"}, "kubernetes", id},
+		{map[string]string{"proc/self/cgroup": "0::/\n", "proc/self/mountinfo": "1 2 0:1 /var/lib/docker/containers/" + id + "/hostname /etc/hostname rw\n"}, "docker", id},
+		{map[string]string{"proc/self/mountinfo": "1 2 0:1 /home/docker/data /data rw\n"}, "", ""},
+		{map[string]string{".dockerenv": ""}, "docker", ""},
+		{map[string]string{"run/.containerenv": "", "proc/self/cgroup": "0::/machine.slice/libpod-" + id + ".scope\n"}, "podman", id},
+	}
+
+	for i, tc := range tests {
+		info := detectContainer(fixtures.Scaffold(t, tc.files))
+		if info.Detected != (tc.runtime != "") || info.Runtime != tc.runtime || info.ID != tc.id {
+			t.Errorf("test %d: expected runtime %q with id %q but got %+v", i, tc.runtime, tc.id, info)
+		}
+	}
+
+	t.Setenv("container", "systemd-nspawn")
+	if info := detectContainer(fixtures.Scaffold(t, nil)); !info.Detected || info.Runtime != "systemd-nspawn" {
+		t.Errorf("expected container to be detected from the environment but got %+v", info)
+	}
+}
+
+// Test that a PID file written in another PID namespace is not running.
+func TestRunningNamespace(t *testing.T) {
+	if ContainerInfo().Namespace == "" {
+		t.Skip("could not determine the pid namespace")
+	}
+
+	pid := &PID{PID: os.Getpid(), PPID: os.Getppid(), Namespace: ContainerInfo().Namespace}
+	if !pid.Running() {
+		t.Error("expected the current process to be running in the same namespace")
+	}
+
+	pid.Namespace = "pid:[1]"
+	if pid.Running() {
+		t.Error("expected a pid from another namespace not to be running")
+	}
+}
+
+// Test that the writable directory for container pid files is probed once.
+func TestContainerRunDir(t *testing.T) {
+	dir := containerRunDir()
+	if dir == "" {
+		t.Fatal("expected a writable directory for pid files")
+	}
+
+	// The cached directory is returned even if the directories change
+	runDir = "cached"
+	defer func() { runDir = dir }()
+	if cached := containerRunDir(); cached != "cached" {
+		t.Errorf("expected the cached directory got %q", cached)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package pid
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Returns an error if the file is not owned by the uid or by root.
+func checkOwner(info os.FileInfo, uid int) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return errors.New("could not determine owner")
+	}
+
+	if int(stat.Uid) != uid && stat.Uid != 0 {
+		return fmt.Errorf("owned by uid %d not %d", stat.Uid, uid)
+	}
+	return nil
+}
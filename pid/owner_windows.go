@@ -0,0 +1,13 @@
+//go:build windows
+
+package pid
+
+import (
+	"errors"
+	"os"
+)
+
+// File ownership cannot be verified on Windows so strict pid files are refused.
+func checkOwner(info os.FileInfo, uid int) error {
+	return errors.New("ownership cannot be verified on windows")
+}
@@ -9,16 +9,45 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"syscall"
 )
 
+// DefaultMode is the permissions the PID file is written with if not specified.
+const DefaultMode os.FileMode = 0644
+
 //===========================================================================
 // Helper Methods
 //===========================================================================
 
+// UseRuntimeDir opts into placing PID files in the per-user runtime directory
+// ($XDG_RUNTIME_DIR or /run/user/$UID) on Linux. It can also be enabled by
+// setting $PID_RUNTIME_DIR to a true value such as 1 or true. It is disabled by
+// default so that processes started by earlier versions still find their PID
+// files in ~/.run.
+var UseRuntimeDir = false
+
 // Path is a helper function that computes the best possible PID file for the
-// current system, by first attempting to get the user directory then
-// resorting to /var/run on Linux systems and elsewhere on other systems.
+// current system. If the runtime directory is enabled (see UseRuntimeDir) and
+// exists, it is used on Linux. In a container, where the user may not have a
+// home directory and /var/run is often missing, the first writable directory of
+// /run, /var/run, and the temp directory is used. Otherwise it attempts to get
+// the user directory then resorts to /var/run. The writable directory of a
+// container is probed once and cached.
 func Path(filename string) string {
+	if useRuntimeDir() {
+		if dir := runtimeDir(); dir != "" {
+			return filepath.Join(dir, filename)
+		}
+	}
+
+	if ContainerInfo().Detected {
+		if dir := containerRunDir(); dir != "" {
+			return filepath.Join(dir, filename)
+		}
+	}
+
 	usr, err := user.Current()
 	if err == nil {
 		return filepath.Join(usr.HomeDir, ".run", filename)
@@ -27,6 +56,40 @@ func Path(filename string) string {
 	return filepath.Join("/", "var", "run", filename)
 }
 
+// Returns true if the runtime directory is enabled by UseRuntimeDir or by the
+// $PID_RUNTIME_DIR environment variable.
+func useRuntimeDir() bool {
+	if UseRuntimeDir {
+		return true
+	}
+
+	enabled, _ := strconv.ParseBool(os.Getenv("PID_RUNTIME_DIR"))
+	return enabled
+}
+
+// Returns the per-user runtime directory on Linux if it exists.
+func runtimeDir() string {
+	if runtime.GOOS != "linux" {
+		return ""
+	}
+
+	dirs := []string{os.Getenv("XDG_RUNTIME_DIR")}
+	if uid := os.Getuid(); uid >= 0 {
+		dirs = append(dirs, filepath.Join("/", "run", "user", strconv.Itoa(uid)))
+	}
+
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+	}
+	return ""
+}
+
 // New PID file at the given location. Note that this function only creates
 // an empty PID, which can then be loaded or saved in order to obtain
 // process information.
@@ -40,20 +103,40 @@ func New(path string) *PID {
 
 // PID describes the server process and is accessed by both the server and the
 // command line client in order to facilitate cross-process communication.
+//
+// The Mode, Owner, and Strict fields harden the PID file for services that run
+// as dedicated service accounts and must be set before Save or Load.
+//
+// On Linux the PID namespace of the process is also recorded so that a PID file
+// written in another namespace (e.g. by a previous run of a container that
+// wrote its PID file to a volume) is not mistaken for a running process.
 type PID struct {
-	PID  int    `json:"pid"`  // The process id assigned by the OS
-	PPID int    `json:"ppid"` // The parent process id
-	path string // The path to the pid file
+	PID       int         `json:"pid"`             // The process id assigned by the OS
+	PPID      int         `json:"ppid"`            // The parent process id
+	Namespace string      `json:"pidns,omitempty"` // The PID namespace of the process, if known
+	Mode      os.FileMode `json:"-"`               // The permissions of the pid file, DefaultMode if zero
+	Owner     *Owner      `json:"-"`               // The owner of the pid file, the current user if nil
+	Strict    bool        `json:"-"`               // Refuse to load pid files that are not trusted
+	path      string      // The path to the pid file
+}
+
+// Owner specifies the user and group ids the PID file is owned by.
+type Owner struct {
+	UID int
+	GID int
 }
 
 // Save the PID file to disk after first determining the process ids.
-// NOTE: This method will fail if the PID file already exists.
+// NOTE: This method will fail if the PID file already exists, unless running
+// in a container and the PID file was written in another PID namespace, in
+// which case the PID file is stale and is replaced.
 func (pid *PID) Save() error {
 	var err error
 
-	// Get the currently running Process ID and Parent ID
+	// Get the currently running Process ID, Parent ID, and PID namespace
 	pid.PID = os.Getpid()
 	pid.PPID = os.Getppid()
+	pid.Namespace = ContainerInfo().Namespace
 
 	// Marshall the JSON representation
 	data, err := json.Marshal(pid)
@@ -62,6 +145,12 @@ func (pid *PID) Save() error {
 	}
 
 	path := pid.Path()
+	if pid.stale() {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+
 	// Ensure that a PID file does not exist (race possible)
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		// Make sure the directory exists.
@@ -70,14 +159,37 @@ func (pid *PID) Save() error {
 		}
 
 		// Write the JSON representation of the PID file to disk
-		return ioutil.WriteFile(path, data, 0644)
+		if err := ioutil.WriteFile(path, data, pid.mode()); err != nil {
+			return err
+		}
+
+		// Ensure the mode is not modified by the umask and set the owner
+		if err := pid.secure(path); err != nil {
+			os.Remove(path)
+			return err
+		}
+		return nil
 	}
 
 	return fmt.Errorf("PID file exists already at '%s'", path)
 }
 
 // Load the PID file -- used by the command line client to populate the PID.
+// If Strict is set, the PID file is only loaded if it is trusted: it must be a
+// regular file (not a symlink) that is owned by the Owner (or the current user
+// if Owner is nil) or root and that is not writable by group or other users.
 func (pid *PID) Load() error {
+	if pid.Strict {
+		info, err := os.Lstat(pid.Path())
+		if err != nil {
+			return fmt.Errorf("no PID file exists at %s; process not running?", pid.Path())
+		}
+
+		if err = pid.trusted(info); err != nil {
+			return fmt.Errorf("untrusted PID file at %s: %s", pid.Path(), err)
+		}
+	}
+
 	data, err := ioutil.ReadFile(pid.Path())
 	if err != nil {
 		return fmt.Errorf("no PID file exists at %s; process not running?", pid.Path())
@@ -104,6 +216,59 @@ func (pid *PID) Path() string {
 	return pid.path
 }
 
+// Returns true if running in a container and the PID file on disk was written
+// in a different PID namespace, e.g. by a previous run of the container.
+func (pid *PID) stale() bool {
+	if !ContainerInfo().Detected || pid.Namespace == "" {
+		return false
+	}
+
+	other := &PID{path: pid.path}
+	if err := other.Load(); err != nil {
+		return false
+	}
+	return other.Namespace != "" && other.Namespace != pid.Namespace
+}
+
+// Returns the mode to write the pid file with.
+func (pid *PID) mode() os.FileMode {
+	if pid.Mode == 0 {
+		return DefaultMode
+	}
+	return pid.Mode
+}
+
+// Sets the permissions and owner of the pid file after it is written.
+func (pid *PID) secure(path string) error {
+	if err := os.Chmod(path, pid.mode()); err != nil {
+		return err
+	}
+
+	if pid.Owner != nil {
+		if err := os.Chown(path, pid.Owner.UID, pid.Owner.GID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns an error if the pid file described by info cannot be trusted.
+func (pid *PID) trusted(info os.FileInfo) error {
+	if !info.Mode().IsRegular() {
+		return errors.New("not a regular file")
+	}
+
+	if info.Mode().Perm()&0022 != 0 {
+		return fmt.Errorf("writable by group or other users (mode %s)", info.Mode().Perm())
+	}
+
+	uid := os.Geteuid()
+	if pid.Owner != nil {
+		uid = pid.Owner.UID
+	}
+	return checkOwner(info, uid)
+}
+
 //===========================================================================
 // PID Process Management
 //===========================================================================
@@ -138,3 +303,38 @@ func (pid *PID) Signal(sig os.Signal) error {
 
 	return proc.Signal(sig)
 }
+
+// Running returns true if the process identified by the PID file exists. It
+// sends the null signal to the process, which performs error checking without
+// actually signaling the process; a process owned by another user that cannot be
+// signaled (EPERM) is still running. Returns false if the
+// PID has not been loaded or if the process does not exist (e.g. the PID file
+// is stale because the process exited without freeing it). Also returns false
+// if the PID file was written in a different PID namespace, since the pid does
+// not identify the same process in this namespace (pids, especially PID 1, are
+// reused every time a container restarts).
+func (pid *PID) Running() bool {
+	if ns := ContainerInfo().Namespace; pid.Namespace != "" && ns != "" && pid.Namespace != ns {
+		return false
+	}
+
+	proc, err := pid.Process()
+	if err != nil {
+		return false
+	}
+	defer proc.Release()
+	return running(proc)
+}
+
+// Returns true if the process exists, sending it the null signal. Used by both
+// Running and Watch so that they agree on whether a process is alive.
+func running(proc *os.Process) bool {
+	return alive(proc.Signal(syscall.Signal(0)))
+}
+
+// Returns true if the error from sending the null signal to a process means the
+// process exists: either it was signaled or it belongs to another user and this
+// process is not permitted to signal it.
+func alive(err error) bool {
+	return err == nil || errors.Is(err, os.ErrPermission)
+}
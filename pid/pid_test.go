@@ -4,6 +4,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
+	"syscall"
 	"testing"
 )
 
@@ -22,6 +24,34 @@ func TestPath(t *testing.T) {
 	}
 }
 
+// Test that the runtime directory is only used when it is enabled.
+func TestPathRuntimeDir(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the runtime directory is only used on linux")
+	}
+
+	dir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+	t.Setenv("PID_RUNTIME_DIR", "")
+
+	if filepath.Dir(Path("test.pid")) == dir {
+		t.Error("expected the runtime directory to be disabled by default")
+	}
+
+	t.Setenv("PID_RUNTIME_DIR", "true")
+	if path := Path("test.pid"); path != filepath.Join(dir, "test.pid") {
+		t.Errorf("expected the runtime directory to be enabled by the environment, got %s", path)
+	}
+
+	t.Setenv("PID_RUNTIME_DIR", "")
+	UseRuntimeDir = true
+	defer func() { UseRuntimeDir = false }()
+
+	if path := Path("test.pid"); path != filepath.Join(dir, "test.pid") {
+		t.Errorf("expected the runtime directory to be enabled, got %s", path)
+	}
+}
+
 // Test the PID New function
 func TestNew(t *testing.T) {
 	if err := makeTmpDir(); err != nil {
@@ -200,6 +230,136 @@ func TestLoadMissing(t *testing.T) {
 	}
 }
 
+// Test that a saved PID is running and an unsaved PID is not
+func TestRunning(t *testing.T) {
+	if err := makeTmpDir(); err != nil {
+		t.Fatal(err)
+	}
+	defer removeTmpDir()
+
+	pid := New(filepath.Join(tmpDir, "test.pid"))
+	if pid.Running() {
+		t.Error("unsaved pid should not be running")
+	}
+
+	if err := pid.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !pid.Running() {
+		t.Error("saved pid of the current process should be running")
+	}
+}
+
+// Test that a process that cannot be signaled because it belongs to another user
+// is running and that a process that does not exist is not.
+func TestRunningAlive(t *testing.T) {
+	tests := []struct {
+		err   error
+		alive bool
+	}{
+		{nil, true},
+		{syscall.EPERM, true},
+		{&os.SyscallError{Syscall: "kill", Err: syscall.EPERM}, true},
+		{os.ErrPermission, true},
+		{syscall.ESRCH, false},
+		{os.ErrProcessDone, false},
+	}
+
+	for _, tc := range tests {
+		if alive(tc.err) != tc.alive {
+			t.Errorf("expected alive to be %t for %v", tc.alive, tc.err)
+		}
+	}
+
+	// The init process belongs to root, so other users cannot signal it
+	if runtime.GOOS == "windows" || os.Getuid() == 0 {
+		return
+	}
+
+	if pid := (&PID{PID: 1}); !pid.Running() {
+		t.Error("expected the init process owned by root to be running")
+	}
+}
+
+// Test that a PID is saved with the specified mode and owner
+func TestSaveMode(t *testing.T) {
+	if err := makeTmpDir(); err != nil {
+		t.Fatal(err)
+	}
+	defer removeTmpDir()
+
+	pid := New(filepath.Join(tmpDir, "test.pid"))
+	pid.Mode = 0600
+	pid.Owner = &Owner{UID: os.Getuid(), GID: os.Getgid()}
+	if err := pid.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(pid.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600 got %s", info.Mode().Perm())
+	}
+}
+
+// Test that a strict PID refuses to load untrusted pid files
+func TestLoadStrict(t *testing.T) {
+	if err := makeTmpDir(); err != nil {
+		t.Fatal(err)
+	}
+	defer removeTmpDir()
+
+	path := filepath.Join(tmpDir, "test.pid")
+	if err := New(path).Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	pid := New(path)
+	pid.Strict = true
+	if err := pid.Load(); err != nil {
+		t.Errorf("could not load trusted pid file: %s", err)
+	}
+
+	// Symlinks are not trusted
+	link := filepath.Join(tmpDir, "link.pid")
+	if err := os.Symlink(path, link); err != nil {
+		t.Fatal(err)
+	}
+
+	pid = New(link)
+	pid.Strict = true
+	if err := pid.Load(); err == nil {
+		t.Error("loaded a symlinked pid file in strict mode")
+	}
+
+	// World writable pid files are not trusted
+	if err := os.Chmod(path, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	pid = New(path)
+	pid.Strict = true
+	if err := pid.Load(); err == nil {
+		t.Error("loaded a world writable pid file in strict mode")
+	}
+
+	// Pid files owned by other users are not trusted
+	if os.Getuid() != 0 {
+		if err := os.Chmod(path, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		pid.Owner = &Owner{UID: os.Getuid() + 1}
+		if err := pid.Load(); err == nil {
+			t.Error("loaded a pid file owned by another user in strict mode")
+		}
+	}
+}
+
 //===========================================================================
 // Test Helper Functions
 //===========================================================================
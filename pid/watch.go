@@ -0,0 +1,69 @@
+package pid
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// WatchInterval is how often Watch polls the process if it cannot be monitored
+// by the operating system (e.g. with a pidfd on Linux). It is also the longest
+// Watch takes to notice that its context has been canceled.
+var WatchInterval = 250 * time.Millisecond
+
+// ExitEvent is sent by Watch when the process identified by the PID file exits.
+type ExitEvent struct {
+	PID  int       // The process id of the process that exited
+	Time time.Time // The time the exit was observed
+	Err  error     // Set if the process could not be watched
+}
+
+// Watch monitors the process identified by the PID file and sends an ExitEvent
+// on the returned channel when it exits, enabling supervisor-style CLIs to react
+// promptly when a daemon crashes. If the process is not running when Watch is
+// called, the event is sent immediately. The channel is closed after the event
+// is sent or when the context is canceled, whichever comes first. On Linux the
+// process is monitored with a pidfd, elsewhere (or on kernels older than 5.3)
+// it is polled every WatchInterval.
+func (pid *PID) Watch(ctx context.Context) <-chan ExitEvent {
+	events := make(chan ExitEvent, 1)
+	go func() {
+		defer close(events)
+		if pid.PID == 0 {
+			events <- ExitEvent{Time: time.Now(), Err: errors.New("PID has not yet been saved or loaded")}
+			return
+		}
+
+		if pid.Running() && !wait(ctx, pid) {
+			return
+		}
+		events <- ExitEvent{PID: pid.PID, Time: time.Now()}
+	}()
+	return events
+}
+
+// Polls the process every WatchInterval until it is no longer running, returning
+// true when the process exits or false if the context is canceled first. The
+// process is found once rather than on every tick and is checked with the same
+// null signal as Running, so a process owned by another user is still running.
+func poll(ctx context.Context, pid *PID) bool {
+	proc, err := pid.Process()
+	if err != nil {
+		return true
+	}
+	defer proc.Release()
+
+	ticker := time.NewTicker(WatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+			if !running(proc) {
+				return true
+			}
+		}
+	}
+}
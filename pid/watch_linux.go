@@ -0,0 +1,43 @@
+//go:build linux
+
+package pid
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Waits for the process to exit using a pidfd, which becomes readable when the
+// process terminates, returning true when the process exits or false if the
+// context is canceled first. Falls back to polling if pidfds are not supported.
+func wait(ctx context.Context, pid *PID) bool {
+	fd, _, errno := unix.Syscall(unix.SYS_PIDFD_OPEN, uintptr(pid.PID), 0, 0)
+	if errno != 0 {
+		if errno == unix.ESRCH {
+			return true
+		}
+		return poll(ctx, pid)
+	}
+	defer unix.Close(int(fd))
+
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+	timeout := int(WatchInterval / time.Millisecond)
+	for {
+		n, err := unix.Poll(fds, timeout)
+		if err != nil && err != unix.EINTR {
+			return poll(ctx, pid)
+		}
+
+		if n > 0 {
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		default:
+		}
+	}
+}
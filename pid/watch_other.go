@@ -0,0 +1,11 @@
+//go:build !linux
+
+package pid
+
+import "context"
+
+// Waits for the process to exit by polling it, returning true when the process
+// exits or false if the context is canceled first.
+func wait(ctx context.Context, pid *PID) bool {
+	return poll(ctx, pid)
+}
@@ -0,0 +1,123 @@
+package pid
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// Test that an exit event is sent when a watched process exits.
+func TestWatch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires the sleep command")
+	}
+
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reap the process when it exits so that polling does not see a zombie
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	proc := &PID{PID: cmd.Process.Pid}
+	events := proc.Watch(context.Background())
+
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected exit event before the process exited: %+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := cmd.Process.Kill(); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	select {
+	case e, ok := <-events:
+		if !ok || e.PID != cmd.Process.Pid || e.Err != nil {
+			t.Errorf("unexpected exit event: %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no exit event after the process was killed")
+	}
+
+	if _, ok := <-events; ok {
+		t.Error("expected the events channel to be closed after the exit event")
+	}
+}
+
+// Test that the events channel is closed without an event if the context is
+// canceled and that an event is sent immediately if the process is not running.
+func TestWatchCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	events := (&PID{PID: os.Getpid()}).Watch(ctx)
+	cancel()
+
+	select {
+	case e, ok := <-events:
+		if ok {
+			t.Errorf("unexpected exit event for the current process: %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("events channel was not closed after the context was canceled")
+	}
+
+	if e := <-New("missing.pid").Watch(context.Background()); e.Err == nil {
+		t.Error("expected an error watching a pid that was not loaded")
+	}
+
+	// Pids are limited to 2^22 on linux, so this process does not exist
+	if e := <-(&PID{PID: 1 << 23}).Watch(context.Background()); e.PID != 1<<23 || e.Err != nil {
+		t.Errorf("expected an immediate exit event but got %+v", e)
+	}
+}
+
+// Test that polling reports when the process exits and does not mistake a
+// process owned by another user for an exited process.
+func TestPoll(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires the sleep command")
+	}
+
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	exited := make(chan bool, 1)
+	go func() { exited <- poll(context.Background(), &PID{PID: cmd.Process.Pid}) }()
+
+	if err := cmd.Process.Kill(); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	select {
+	case ok := <-exited:
+		if !ok {
+			t.Error("expected poll to report that the process exited")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("poll did not notice that the process exited")
+	}
+
+	// The init process belongs to root, so other users cannot signal it
+	if os.Getuid() == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*WatchInterval)
+	defer cancel()
+	if poll(ctx, &PID{PID: 1}) {
+		t.Error("expected the init process owned by root to be running")
+	}
+}
@@ -16,11 +16,17 @@ import (
 // or more time.Durations can be passed. This object has unexported fields
 // because it is thread-safe (via a sync.RWMutex). All properties must be
 // accesesd from read-locked access methods.
+//
+// The wall-clock duration of a benchmark run can be tracked by bracketing the
+// run with Start and Stop, in which case throughput is computed from the
+// elapsed wall time rather than from the sum of the sample durations.
 type Benchmark struct {
 	sync.RWMutex
 	Statistics
 	timeouts uint64        // the number of 0 durations (null durations) or timeouts
 	duration time.Duration // externally set duration of the benchmark
+	started  time.Time     // wall-clock time the benchmark run was started
+	stopped  time.Time     // wall-clock time the benchmark run was stopped
 }
 
 // Update the benchmark with a duration or durations (thread-safe). If a
@@ -42,20 +48,60 @@ func (s *Benchmark) Update(durations ...time.Duration) {
 	}
 }
 
-// SetDuration allows an external setting of the duration. This is especially
-// useful in the case where multiple threads are updating the benchmark and
-// the internal measurement of total time might double count concurrent
-// accesses. In fact it is strongly recommended that this method is called
-// from the external measurerer after all updating is complete.
+// SetDuration allows an external setting of the duration (thread-safe). This
+// is especially useful in the case where multiple threads are updating the
+// benchmark and the internal measurement of total time might double count
+// concurrent accesses. In fact it is strongly recommended that this method is
+// called from the external measurerer after all updating is complete, or that
+// the run is bracketed with Start and Stop instead. An externally set
+// duration takes precedence over the wall-clock duration.
 func (s *Benchmark) SetDuration(duration time.Duration) {
+	s.Lock()
+	defer s.Unlock()
 	s.duration = duration
 }
 
+// Start the wall-clock timer of the benchmark run (thread-safe). Calling Start
+// again restarts the timer from the current time.
+func (s *Benchmark) Start() {
+	s.Lock()
+	defer s.Unlock()
+	s.started = time.Now()
+	s.stopped = time.Time{}
+}
+
+// Stop the wall-clock timer of the benchmark run (thread-safe) and return the
+// elapsed wall time since Start was called. If the benchmark was not started
+// a zero duration is returned.
+func (s *Benchmark) Stop() time.Duration {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.started.IsZero() {
+		return 0
+	}
+
+	s.stopped = time.Now()
+	return s.stopped.Sub(s.started)
+}
+
+// Duration returns the duration of the benchmark (thread-safe): the externally
+// set duration if SetDuration was called, otherwise the elapsed wall time
+// between Start and Stop. If the benchmark has been started but not stopped,
+// the wall time elapsed so far is returned. If neither a duration has been set
+// nor the benchmark started, a zero duration is returned.
+func (s *Benchmark) Duration() time.Duration {
+	s.RLock()
+	defer s.RUnlock()
+	return s.elapsed()
+}
+
 // Throughput returns the number of samples per second, measured as the
 // inverse mean: number of samples divided by the total duration in seconds.
-// The duration is computed in two ways:
+// The duration is computed in three ways:
 //
 //   - if SetDuration is called, that duration is used
+//   - if Start is called, the elapsed wall time of the run is used
 //   - otherwise, the total number of observed seconds is used
 //
 // This metric does not express a duration, so a float64 value is returned
@@ -63,9 +109,13 @@ func (s *Benchmark) SetDuration(duration time.Duration) {
 func (s *Benchmark) Throughput() float64 {
 	s.RLock()
 	defer s.RUnlock()
+	return s.throughput()
+}
 
-	if s.samples > 0 && s.duration > 0 {
-		return float64(s.Statistics.samples) / s.duration.Seconds()
+// Computes the throughput without locking.
+func (s *Benchmark) throughput() float64 {
+	if duration := s.elapsed(); s.samples > 0 && duration > 0 {
+		return float64(s.Statistics.samples) / duration.Seconds()
 	}
 
 	if s.samples > 0 && s.total > 0 {
@@ -152,38 +202,115 @@ func (s *Benchmark) Range() time.Duration {
 	return s.castSeconds(s.Statistics.Range())
 }
 
+// Quantile returns the estimated duration below which the fraction q (between
+// 0 and 1) of the durations fall, e.g. Quantile(0.99) is the 99th percentile
+// latency. Timeouts are not included in the distribution. If no durations have
+// been recorded, a zero valued duration is returned.
+func (s *Benchmark) Quantile(q float64) time.Duration {
+	s.RLock()
+	defer s.RUnlock()
+	return s.castSeconds(s.Statistics.Quantile(q))
+}
+
+// SetQuantiles specifies the quantiles (between 0 and 1) that are returned by
+// Quantiles and Serialize instead of DefaultQuantiles (thread-safe).
+func (s *Benchmark) SetQuantiles(quantiles ...float64) {
+	s.Lock()
+	defer s.Unlock()
+	s.Statistics.SetQuantiles(quantiles...)
+}
+
+// Quantiles returns the estimated duration of each of the quantiles specified
+// by SetQuantiles (DefaultQuantiles by default) keyed by the quantile.
+func (s *Benchmark) Quantiles() map[float64]time.Duration {
+	s.RLock()
+	defer s.RUnlock()
+
+	data := make(map[float64]time.Duration)
+	for q, seconds := range s.Statistics.Quantiles() {
+		data[q] = s.castSeconds(seconds)
+	}
+	return data
+}
+
+// Histogram returns the estimated number of durations in each of the buckets,
+// which are specified by their upper bounds in increasing order, e.g. to plot
+// the latency distribution. The last count is the number of durations slower
+// than the last bucket (see Statistics.Histogram); timeouts are not counted.
+func (s *Benchmark) Histogram(buckets []time.Duration) []uint64 {
+	s.RLock()
+	defer s.RUnlock()
+
+	bounds := make([]float64, 0, len(buckets))
+	for _, bucket := range buckets {
+		bounds = append(bounds, bucket.Seconds())
+	}
+	return s.Statistics.Histogram(bounds)
+}
+
 // Serialize returns a map of summary statistics. This map is useful for
 // dumping statistics to disk (using JSON for example) or for reporting the
 // statistics elsewhere. The values in the maps are string representations of
 // the time.Duration objects, which are reported in a human readable form.
-// They can be converted back to durations with time.ParseDuration.
+// They can be converted back to durations with time.ParseDuration. The
+// estimates of the quantiles specified by SetQuantiles are keyed by their
+// percentile, e.g. p99 or p99.9.
 //
-// TODO: Create Dump and Load functions to get statistical data to and from
-// offline sources.
+// The summary cannot be loaded again; use Dump and Load to persist the
+// aggregate state of the benchmark instead.
 func (s *Benchmark) Serialize() map[string]interface{} {
 	s.RLock()
 	defer s.RUnlock()
 
+	// Use the unlocked statistics rather than the accessors so that the read
+	// lock is not acquired recursively, which can deadlock with a writer.
 	data := make(map[string]interface{})
 	data["samples"] = s.samples
-	data["total"] = s.Total().String()
-	data["mean"] = s.Mean().String()
-	data["stddev"] = s.StdDev().String()
-	data["variance"] = s.Variance().String()
-	data["fastest"] = s.Fastest().String()
-	data["slowest"] = s.Slowest().String()
-	data["range"] = s.Range().String()
-	data["throughput"] = s.Throughput()
-	data["duration"] = s.duration.String()
+	data["total"] = s.castSeconds(s.Statistics.total).String()
+	data["mean"] = s.castSeconds(s.Statistics.Mean()).String()
+	data["stddev"] = s.castSeconds(s.Statistics.StdDev()).String()
+	data["variance"] = s.castSeconds(s.Statistics.Variance()).String()
+	data["fastest"] = s.castSeconds(s.Statistics.Minimum()).String()
+	data["slowest"] = s.castSeconds(s.Statistics.Maximum()).String()
+	data["range"] = s.castSeconds(s.Statistics.Range()).String()
+	data["throughput"] = s.throughput()
+	data["duration"] = s.elapsed().String()
 	data["timeouts"] = s.timeouts
+
+	for _, q := range s.reported() {
+		data[percentile(q)] = s.castSeconds(s.quantile(q)).String()
+	}
 	return data
 }
 
 // Append another benchmark object to the current benchmark object,
-// incrementing the distribution from the other object.
+// incrementing the distribution from the other object (thread-safe). The other
+// object is copied under its own lock before this object is locked so that
+// concurrent appends in opposite directions cannot deadlock.
 func (s *Benchmark) Append(o *Benchmark) {
-	s.Statistics.Append(&o.Statistics)
-	s.timeouts += o.timeouts
+	o.RLock()
+	other := o.Statistics.snapshot()
+	timeouts := o.timeouts
+	o.RUnlock()
+
+	s.Lock()
+	defer s.Unlock()
+	s.Statistics.Append(other)
+	s.timeouts += timeouts
+}
+
+// Returns the externally set or wall-clock duration without locking.
+func (s *Benchmark) elapsed() time.Duration {
+	switch {
+	case s.duration > 0:
+		return s.duration
+	case s.started.IsZero():
+		return 0
+	case s.stopped.IsZero():
+		return time.Since(s.started)
+	default:
+		return s.stopped.Sub(s.started)
+	}
 }
 
 // Internal Helper Method to cast float64 seconds into a duration
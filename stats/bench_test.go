@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"sort"
 	"testing"
 	"time"
 
@@ -52,6 +53,9 @@ func ExampleBenchmark() {
 	//   "duration": "0s",
 	//   "fastest": "41.219436ms",
 	//   "mean": "120.993689ms",
+	//   "p50": "120.998932ms",
+	//   "p95": "149.449107ms",
+	//   "p99": "161.284598ms",
 	//   "range": "167.175236ms",
 	//   "samples": 1000000,
 	//   "slowest": "208.394672ms",
@@ -123,6 +127,102 @@ func TestThroughput(t *testing.T) {
 	Ω(stats.Throughput()).Should(BeNumerically("~", 20.0, 0.00001))
 }
 
+func TestWallClock(t *testing.T) {
+	RegisterTestingT(t)
+
+	stats := new(Benchmark)
+
+	// Without starting the benchmark there is no duration
+	Ω(stats.Stop()).Should(BeZero())
+	Ω(stats.Duration()).Should(BeZero())
+
+	stats.Start()
+	time.Sleep(50 * time.Millisecond)
+	Ω(stats.Duration()).Should(BeNumerically(">=", 50*time.Millisecond))
+
+	stats.Update(time.Second, time.Second, time.Second, time.Second, time.Second)
+	elapsed := stats.Stop()
+	Ω(elapsed).Should(BeNumerically(">=", 50*time.Millisecond))
+	Ω(stats.Duration()).Should(Equal(elapsed))
+
+	// Throughput should use the elapsed wall time rather than the total
+	Ω(stats.Throughput()).Should(BeNumerically("~", 5.0/elapsed.Seconds(), 0.00001))
+	Ω(stats.Serialize()["duration"]).Should(Equal(elapsed.String()))
+
+	// An externally set duration should take precedence
+	stats.SetDuration(time.Second)
+	Ω(stats.Duration()).Should(Equal(time.Second))
+	Ω(stats.Throughput()).Should(BeNumerically("~", 5.0, 0.00001))
+}
+
+func TestBenchmarkConcurrency(t *testing.T) {
+	RegisterTestingT(t)
+
+	stats := new(Benchmark)
+	stats.Start()
+
+	// Concurrent updates and accessors should not race
+	done := make(chan bool)
+	for i := 0; i < 4; i++ {
+		go func() {
+			for j := 0; j < 100; j++ {
+				stats.Update(time.Millisecond)
+				stats.Throughput()
+				stats.Serialize()
+			}
+			done <- true
+		}()
+	}
+
+	go func() {
+		for j := 0; j < 100; j++ {
+			stats.SetDuration(time.Duration(j+1) * time.Millisecond)
+			stats.Duration()
+		}
+		done <- true
+	}()
+
+	for i := 0; i < 5; i++ {
+		<-done
+	}
+
+	stats.Stop()
+	Ω(stats.N()).Should(Equal(uint64(400)))
+}
+
+func TestBenchmarkAppendConcurrency(t *testing.T) {
+	RegisterTestingT(t)
+
+	a, b := new(Benchmark), new(Benchmark)
+	a.Update(time.Millisecond)
+	b.Update(2 * time.Millisecond)
+
+	// Appending in opposite directions concurrently should not deadlock
+	done := make(chan bool)
+	for _, pair := range [][2]*Benchmark{{a, b}, {b, a}} {
+		go func(s, o *Benchmark) {
+			for j := 0; j < 100; j++ {
+				s.Append(o)
+				s.Update(time.Millisecond)
+			}
+			done <- true
+		}(pair[0], pair[1])
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("concurrent appends deadlocked")
+		}
+	}
+
+	// A benchmark can be appended to itself
+	n := a.N()
+	a.Append(a)
+	Ω(a.N()).Should(Equal(2 * n))
+}
+
 func TestBenchmarkAppend(t *testing.T) {
 	RegisterTestingT(t)
 
@@ -244,6 +344,39 @@ func TestBenchmarkAppend(t *testing.T) {
 
 }
 
+func TestBenchmarkQuantiles(t *testing.T) {
+	RegisterTestingT(t)
+
+	data, err := loadBenchData()
+	Ω(err).ShouldNot(HaveOccurred())
+
+	stats := new(Benchmark)
+	stats.Update(data...)
+	stats.Update(0, 0)
+
+	sorted := append([]time.Duration(nil), data...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := func(value time.Duration) float64 {
+		i := sort.Search(len(sorted), func(i int) bool { return sorted[i] >= value })
+		return float64(i) / float64(len(sorted))
+	}
+
+	// Timeouts are not included in the distribution
+	for _, q := range []float64{0.5, 0.95, 0.99} {
+		Ω(rank(stats.Quantile(q))).Should(BeNumerically("~", q, 0.0001+0.005*q*(1-q)), "quantile %v", q)
+	}
+
+	stats.SetQuantiles(0.999)
+	Ω(stats.Quantiles()).Should(HaveKeyWithValue(0.999, stats.Quantile(0.999)))
+	Ω(stats.Serialize()).Should(HaveKeyWithValue("p99.9", stats.Quantile(0.999).String()))
+
+	counts := stats.Histogram([]time.Duration{100 * time.Millisecond, 150 * time.Millisecond})
+	Ω(counts).Should(HaveLen(3))
+	Ω(counts[0] + counts[1] + counts[2]).Should(Equal(uint64(len(data))))
+	Ω(counts[1]).Should(BeNumerically(">", counts[0]))
+	Ω(counts[1]).Should(BeNumerically(">", counts[2]))
+}
+
 func BenchmarkBenchmark_Update(b *testing.B) {
 	rand.Seed(42)
 	stats := new(Benchmark)
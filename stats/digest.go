@@ -0,0 +1,257 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// The compression of the digest bounds the number of centroids it keeps to
+// roughly the compression, trading memory for the accuracy of the estimates.
+const (
+	compression = 200
+	bufferSize  = 5 * compression
+)
+
+// digest is a merging t-digest (Dunning & Ertl, "Computing Extremely Accurate
+// Quantiles Using t-Digests"), a streaming estimator of the distribution of the
+// samples that keeps a bounded number of weighted centroids instead of every
+// sample. Centroids near the tails of the distribution are kept small so that
+// extreme quantiles such as the 99th percentile are accurate. Samples are
+// buffered and merged into the centroids in batches. The digest is not
+// thread-safe; it is protected by the lock of the Statistics that owns it.
+type digest struct {
+	centroids []centroid // merged centroids sorted by mean
+	buffer    []float64  // samples not yet merged into the centroids
+	scratch   []centroid // reused to merge the buffer into the centroids
+	weight    float64    // the total weight of the merged centroids
+	minimum   float64    // the minimum sample observed
+	maximum   float64    // the maximum sample observed
+}
+
+// centroid is the mean of a cluster of samples weighted by the number of samples.
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// Adds a sample to the digest, merging the buffer if it is full.
+func (d *digest) add(sample float64) {
+	if d.weight == 0 && len(d.buffer) == 0 {
+		d.minimum, d.maximum = sample, sample
+	} else {
+		d.minimum = math.Min(d.minimum, sample)
+		d.maximum = math.Max(d.maximum, sample)
+	}
+
+	d.buffer = append(d.buffer, sample)
+	if len(d.buffer) >= bufferSize {
+		sort.Float64s(d.buffer)
+		merged, weight := combine(d.centroids, d.buffer, d.weight, d.scratch[:0])
+		d.centroids, d.scratch, d.weight = merged, d.centroids, weight
+		d.buffer = d.buffer[:0]
+	}
+}
+
+// Merges the centroids and buffered samples of another digest into the digest.
+func (d *digest) append(o *digest) {
+	others, weight := o.merged()
+	if weight == 0 {
+		return
+	}
+
+	if d.weight == 0 && len(d.buffer) == 0 {
+		d.minimum, d.maximum = o.minimum, o.maximum
+	} else {
+		d.minimum = math.Min(d.minimum, o.minimum)
+		d.maximum = math.Max(d.maximum, o.maximum)
+	}
+
+	centroids, total := d.merged()
+	all := make([]centroid, 0, len(centroids)+len(others))
+	all = append(append(all, centroids...), others...)
+	sort.Stable(byMean(all))
+
+	m := &merger{total: total + weight}
+	for _, c := range all {
+		m.push(c)
+	}
+
+	d.centroids, d.weight = m.done(), m.total
+	d.buffer = d.buffer[:0]
+}
+
+// Returns a copy of the digest with the buffer merged into its centroids, or nil
+// if the digest is nil.
+func (d *digest) clone() *digest {
+	if d == nil {
+		return nil
+	}
+
+	centroids, weight := d.merged()
+	return &digest{
+		centroids: append([]centroid(nil), centroids...),
+		weight:    weight,
+		minimum:   d.minimum,
+		maximum:   d.maximum,
+	}
+}
+
+// Returns the centroids with the buffer merged into them and their total weight
+// without modifying the digest, so that it can be read concurrently.
+func (d *digest) merged() ([]centroid, float64) {
+	if len(d.buffer) == 0 {
+		return d.centroids, d.weight
+	}
+
+	buffer := append([]float64(nil), d.buffer...)
+	sort.Float64s(buffer)
+	return combine(d.centroids, buffer, d.weight, nil)
+}
+
+// Merges the sorted samples into the sorted centroids, appending the result to
+// dst, and returns the result with its total weight.
+func combine(centroids []centroid, samples []float64, weight float64, dst []centroid) ([]centroid, float64) {
+	m := &merger{dst: dst, total: weight + float64(len(samples))}
+	i, j := 0, 0
+	for i < len(centroids) || j < len(samples) {
+		if j >= len(samples) || (i < len(centroids) && centroids[i].mean <= samples[j]) {
+			m.push(centroids[i])
+			i++
+		} else {
+			m.push(centroid{mean: samples[j], weight: 1})
+			j++
+		}
+	}
+	return m.done(), m.total
+}
+
+// merger combines centroids pushed in order of their means as long as the
+// combined centroid does not span more than one unit of the scale function,
+// which is steep near the tails so that the centroids there stay small.
+type merger struct {
+	dst     []centroid // the merged centroids
+	current centroid   // the centroid that pushed centroids are combined into
+	total   float64    // the total weight of all centroids to be pushed
+	sofar   float64    // the weight of the merged centroids before current
+	limit   float64    // the maximum weight of the merged centroids and current
+	started bool       // if current has been set by the first centroid
+}
+
+func (m *merger) push(c centroid) {
+	if !m.started {
+		m.current, m.started = c, true
+		m.limit = m.total * scaleInverse(scale(0)+1)
+		return
+	}
+
+	if m.sofar+m.current.weight+c.weight <= m.limit {
+		m.current.weight += c.weight
+		m.current.mean += (c.mean - m.current.mean) * c.weight / m.current.weight
+		return
+	}
+
+	m.sofar += m.current.weight
+	m.dst = append(m.dst, m.current)
+	m.current = c
+	m.limit = m.total * scaleInverse(scale(m.sofar/m.total)+1)
+}
+
+func (m *merger) done() []centroid {
+	if m.started {
+		m.dst = append(m.dst, m.current)
+	}
+	return m.dst
+}
+
+// byMean sorts centroids by their means.
+type byMean []centroid
+
+func (c byMean) Len() int           { return len(c) }
+func (c byMean) Less(i, j int) bool { return c[i].mean < c[j].mean }
+func (c byMean) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+
+// Quantile estimates the value below which the fraction q of the samples fall by
+// interpolating between the centers of the centroids.
+func (d *digest) quantile(q float64) float64 {
+	centroids, total := d.merged()
+	switch {
+	case len(centroids) == 0:
+		return 0.0
+	case q <= 0:
+		return d.minimum
+	case q >= 1:
+		return d.maximum
+	case len(centroids) == 1:
+		return centroids[0].mean
+	}
+
+	// Interpolate between the minimum and the center of the first centroid
+	index := q * total
+	first := centroids[0]
+	if index < first.weight/2 {
+		return d.minimum + (first.mean-d.minimum)*index/(first.weight/2)
+	}
+
+	cumulative := first.weight / 2
+	for i := 0; i < len(centroids)-1; i++ {
+		width := (centroids[i].weight + centroids[i+1].weight) / 2
+		if cumulative+width > index {
+			t := (index - cumulative) / width
+			return centroids[i].mean + t*(centroids[i+1].mean-centroids[i].mean)
+		}
+		cumulative += width
+	}
+
+	// Interpolate between the center of the last centroid and the maximum
+	last := centroids[len(centroids)-1]
+	t := math.Min((index-cumulative)/(last.weight/2), 1)
+	return last.mean + t*(d.maximum-last.mean)
+}
+
+// CDF estimates the fraction of the samples that are less than or equal to x.
+func (d *digest) cdf(x float64) float64 {
+	centroids, total := d.merged()
+	switch {
+	case len(centroids) == 0 || x < d.minimum:
+		return 0.0
+	case x >= d.maximum:
+		return 1.0
+	case len(centroids) == 1:
+		return (x - d.minimum) / (d.maximum - d.minimum)
+	}
+
+	// Interpolate between the minimum and the center of the first centroid
+	first := centroids[0]
+	if x < first.mean {
+		return (x - d.minimum) / (first.mean - d.minimum) * (first.weight / 2) / total
+	}
+
+	cumulative := first.weight / 2
+	for i := 0; i < len(centroids)-1; i++ {
+		width := (centroids[i].weight + centroids[i+1].weight) / 2
+		if x < centroids[i+1].mean {
+			t := (x - centroids[i].mean) / (centroids[i+1].mean - centroids[i].mean)
+			return (cumulative + t*width) / total
+		}
+		cumulative += width
+	}
+
+	// Interpolate between the center of the last centroid and the maximum
+	last := centroids[len(centroids)-1]
+	t := (x - last.mean) / (d.maximum - last.mean)
+	return (cumulative + t*last.weight/2) / total
+}
+
+// The k1 scale function of the t-digest maps a quantile to a scale where each
+// centroid spans at most one unit.
+func scale(q float64) float64 {
+	return compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+// Returns the quantile at the scale k, the inverse of the scale function.
+func scaleInverse(k float64) float64 {
+	if k >= compression/4 {
+		return 1.0
+	}
+	return (math.Sin(k*2*math.Pi/compression) + 1) / 2
+}
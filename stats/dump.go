@@ -0,0 +1,387 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// The version of the binary encoding of statistics and benchmarks.
+const binaryVersion uint8 = 1
+
+//===========================================================================
+// Statistics Persistence
+//===========================================================================
+
+// The persisted aggregate state of Statistics. Unlike the serialized summary,
+// the state can be loaded to continue updating or appending the statistics.
+type statistics struct {
+	Samples   uint64       `json:"samples"`
+	Total     float64      `json:"total"`
+	Mean      float64      `json:"mean"`
+	M2        float64      `json:"m2"`
+	Minimum   float64      `json:"minimum"`
+	Maximum   float64      `json:"maximum"`
+	Quantiles []float64    `json:"quantiles,omitempty"`
+	Centroids [][2]float64 `json:"centroids,omitempty"`
+}
+
+// The fixed size header of the binary encoding of statistics, followed by the
+// quantiles and the mean and weight of each centroid as float64 values.
+type statisticsHeader struct {
+	Samples    uint64
+	Total      float64
+	Mean       float64
+	M2         float64
+	Minimum    float64
+	Maximum    float64
+	NQuantiles uint32
+	NCentroids uint32
+}
+
+// MarshalJSON encodes the aggregate state of the statistics, including the
+// centroids of the quantile estimator, so that the statistics can be restored
+// with UnmarshalJSON, e.g. to persist statistics across process restarts.
+func (s *Statistics) MarshalJSON() ([]byte, error) {
+	s.RLock()
+	defer s.RUnlock()
+	return json.Marshal(s.state())
+}
+
+// UnmarshalJSON replaces the state of the statistics with the state encoded by
+// MarshalJSON. To merge persisted statistics with current statistics, load
+// them into new Statistics and Append them.
+func (s *Statistics) UnmarshalJSON(data []byte) error {
+	in := statistics{}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	return s.restore(in)
+}
+
+// MarshalBinary encodes the aggregate state of the statistics in a compact
+// binary format that can be restored with UnmarshalBinary.
+func (s *Statistics) MarshalBinary() ([]byte, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	buf := &bytes.Buffer{}
+	if err := writeStatistics(buf, s.state()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the state of the statistics with the state encoded
+// by MarshalBinary.
+func (s *Statistics) UnmarshalBinary(data []byte) error {
+	in, err := readStatistics(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	return s.restore(in)
+}
+
+// Dump writes the aggregate state of the statistics to w as JSON (see
+// MarshalJSON) so that it can be loaded again with Load.
+func (s *Statistics) Dump(w io.Writer) error {
+	return json.NewEncoder(w).Encode(s)
+}
+
+// Load replaces the state of the statistics with the JSON state read from r,
+// written by Dump.
+func (s *Statistics) Load(r io.Reader) error {
+	return json.NewDecoder(r).Decode(s)
+}
+
+// Returns the aggregate state of the statistics (not thread-safe).
+func (s *Statistics) state() statistics {
+	out := statistics{
+		Samples:   s.samples,
+		Total:     s.total,
+		Mean:      s.mean,
+		M2:        s.m2,
+		Minimum:   s.minimum,
+		Maximum:   s.maximum,
+		Quantiles: s.quantiles,
+	}
+
+	if s.digest != nil {
+		centroids, _ := s.digest.merged()
+		out.Centroids = make([][2]float64, 0, len(centroids))
+		for _, c := range centroids {
+			out.Centroids = append(out.Centroids, [2]float64{c.mean, c.weight})
+		}
+	}
+	return out
+}
+
+// Replaces the aggregate state of the statistics (not thread-safe). The state
+// is checked before any of the statistics are modified.
+func (s *Statistics) restore(in statistics) error {
+	var (
+		weight    float64
+		centroids = make([]centroid, 0, len(in.Centroids))
+	)
+
+	for _, c := range in.Centroids {
+		if !(c[1] > 0) {
+			return fmt.Errorf("invalid weight %v of quantile centroid", c[1])
+		}
+		centroids = append(centroids, centroid{mean: c[0], weight: c[1]})
+		weight += c[1]
+	}
+
+	if len(centroids) > 0 && in.Samples == 0 {
+		return errors.New("quantile centroids without samples")
+	}
+	sort.Stable(byMean(centroids))
+
+	s.samples = in.Samples
+	s.total = in.Total
+	s.mean = in.Mean
+	s.m2 = in.M2
+	s.minimum = in.Minimum
+	s.maximum = in.Maximum
+	s.quantiles = in.Quantiles
+	s.digest = nil
+
+	if len(centroids) > 0 {
+		s.digest = &digest{centroids: centroids, weight: weight, minimum: in.Minimum, maximum: in.Maximum}
+	}
+	return nil
+}
+
+// Writes the versioned binary encoding of the statistics.
+func writeStatistics(w io.Writer, s statistics) (err error) {
+	header := statisticsHeader{
+		Samples:    s.Samples,
+		Total:      s.Total,
+		Mean:       s.Mean,
+		M2:         s.M2,
+		Minimum:    s.Minimum,
+		Maximum:    s.Maximum,
+		NQuantiles: uint32(len(s.Quantiles)),
+		NCentroids: uint32(len(s.Centroids)),
+	}
+
+	for _, v := range []interface{}{binaryVersion, header, s.Quantiles, s.Centroids} {
+		if err = binary.Write(w, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reads the versioned binary encoding of the statistics.
+func readStatistics(r *bytes.Reader) (s statistics, err error) {
+	var version uint8
+	if err = binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return s, fmt.Errorf("could not read statistics: %s", err)
+	}
+
+	if version != binaryVersion {
+		return s, fmt.Errorf("unknown statistics encoding version %d", version)
+	}
+
+	var header statisticsHeader
+	if err = binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return s, fmt.Errorf("could not read statistics: %s", err)
+	}
+
+	// Check the lengths against the remaining data before allocating
+	if size := 8*int64(header.NQuantiles) + 16*int64(header.NCentroids); size > int64(r.Len()) {
+		return s, errors.New("could not read statistics: data is truncated")
+	}
+
+	s = statistics{
+		Samples: header.Samples,
+		Total:   header.Total,
+		Mean:    header.Mean,
+		M2:      header.M2,
+		Minimum: header.Minimum,
+		Maximum: header.Maximum,
+	}
+
+	if header.NQuantiles > 0 {
+		s.Quantiles = make([]float64, header.NQuantiles)
+		if err = binary.Read(r, binary.LittleEndian, s.Quantiles); err != nil {
+			return s, fmt.Errorf("could not read statistics: %s", err)
+		}
+	}
+
+	if header.NCentroids > 0 {
+		s.Centroids = make([][2]float64, header.NCentroids)
+		if err = binary.Read(r, binary.LittleEndian, s.Centroids); err != nil {
+			return s, fmt.Errorf("could not read statistics: %s", err)
+		}
+	}
+	return s, nil
+}
+
+//===========================================================================
+// Benchmark Persistence
+//===========================================================================
+
+// The persisted aggregate state of a Benchmark: the state of its statistics in
+// seconds along with its timeouts and duration.
+type benchmark struct {
+	statistics
+	Timeouts uint64        `json:"timeouts"`
+	Duration time.Duration `json:"duration"`
+	Started  time.Time     `json:"started"`
+	Stopped  time.Time     `json:"stopped"`
+}
+
+// The fixed size binary encoding of the benchmark that follows its statistics;
+// the wall-clock times are encoded as Unix nanoseconds, zero if not set.
+type benchmarkTrailer struct {
+	Timeouts uint64
+	Duration int64
+	Started  int64
+	Stopped  int64
+}
+
+// MarshalJSON encodes the aggregate state of the benchmark, including its
+// timeouts and duration, so that it can be restored with UnmarshalJSON.
+func (s *Benchmark) MarshalJSON() ([]byte, error) {
+	s.RLock()
+	defer s.RUnlock()
+	return json.Marshal(s.state())
+}
+
+// UnmarshalJSON replaces the state of the benchmark with the state encoded by
+// MarshalJSON. To merge a persisted benchmark with a current benchmark, load it
+// into a new Benchmark and Append it.
+func (s *Benchmark) UnmarshalJSON(data []byte) error {
+	in := benchmark{}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	return s.restore(in)
+}
+
+// MarshalBinary encodes the aggregate state of the benchmark in a compact
+// binary format that can be restored with UnmarshalBinary.
+func (s *Benchmark) MarshalBinary() ([]byte, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	state := s.state()
+	buf := &bytes.Buffer{}
+	if err := writeStatistics(buf, state.statistics); err != nil {
+		return nil, err
+	}
+
+	trailer := benchmarkTrailer{
+		Timeouts: state.Timeouts,
+		Duration: int64(state.Duration),
+		Started:  unixNano(state.Started),
+		Stopped:  unixNano(state.Stopped),
+	}
+
+	if err := binary.Write(buf, binary.LittleEndian, trailer); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the state of the benchmark with the state encoded by
+// MarshalBinary.
+func (s *Benchmark) UnmarshalBinary(data []byte) (err error) {
+	r := bytes.NewReader(data)
+	in := benchmark{}
+	if in.statistics, err = readStatistics(r); err != nil {
+		return err
+	}
+
+	var trailer benchmarkTrailer
+	if err = binary.Read(r, binary.LittleEndian, &trailer); err != nil {
+		return fmt.Errorf("could not read benchmark: %s", err)
+	}
+
+	in.Timeouts = trailer.Timeouts
+	in.Duration = time.Duration(trailer.Duration)
+	in.Started = fromUnixNano(trailer.Started)
+	in.Stopped = fromUnixNano(trailer.Stopped)
+
+	s.Lock()
+	defer s.Unlock()
+	return s.restore(in)
+}
+
+// Dump writes the aggregate state of the benchmark to w as JSON (see
+// MarshalJSON) so that it can be loaded again with Load.
+func (s *Benchmark) Dump(w io.Writer) error {
+	return json.NewEncoder(w).Encode(s)
+}
+
+// Load replaces the state of the benchmark with the JSON state read from r,
+// written by Dump.
+func (s *Benchmark) Load(r io.Reader) error {
+	return json.NewDecoder(r).Decode(s)
+}
+
+// Returns the aggregate state of the benchmark (not thread-safe). The monotonic
+// clock readings cannot be persisted, so the stopped time is computed from the
+// monotonic elapsed time to preserve the wall-clock duration of the run.
+func (s *Benchmark) state() benchmark {
+	out := benchmark{
+		statistics: s.Statistics.state(),
+		Timeouts:   s.timeouts,
+		Duration:   s.duration,
+		Started:    s.started.Round(0),
+		Stopped:    s.stopped.Round(0),
+	}
+
+	if !s.started.IsZero() && !s.stopped.IsZero() {
+		out.Stopped = out.Started.Add(s.stopped.Sub(s.started))
+	}
+	return out
+}
+
+// Replaces the aggregate state of the benchmark (not thread-safe).
+func (s *Benchmark) restore(in benchmark) error {
+	s.Statistics.Lock()
+	defer s.Statistics.Unlock()
+
+	if err := s.Statistics.restore(in.statistics); err != nil {
+		return err
+	}
+
+	s.timeouts = in.Timeouts
+	s.duration = in.Duration
+	s.started = in.Started
+	s.stopped = in.Stopped
+	return nil
+}
+
+// Returns the time as Unix nanoseconds or zero if the time is not set.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// Returns the time of the Unix nanoseconds or the zero time if they are zero.
+func fromUnixNano(nsec int64) time.Time {
+	if nsec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nsec)
+}
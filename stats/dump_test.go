@@ -0,0 +1,146 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestStatisticsDumpLoad(t *testing.T) {
+	RegisterTestingT(t)
+
+	data, err := loadTestData()
+	Ω(err).ShouldNot(HaveOccurred())
+
+	stats := new(Statistics)
+	stats.Update(data...)
+	stats.SetQuantiles(0.5, 0.999)
+
+	t.Run("JSON", func(t *testing.T) {
+		RegisterTestingT(t)
+
+		buf := &bytes.Buffer{}
+		Ω(stats.Dump(buf)).Should(Succeed())
+
+		loaded := new(Statistics)
+		Ω(loaded.Load(buf)).Should(Succeed())
+		Ω(loaded.Serialize()).Should(Equal(stats.Serialize()))
+		Ω(loaded.Quantile(0.25)).Should(Equal(stats.Quantile(0.25)))
+	})
+
+	t.Run("Binary", func(t *testing.T) {
+		RegisterTestingT(t)
+
+		raw, err := stats.MarshalBinary()
+		Ω(err).ShouldNot(HaveOccurred())
+
+		loaded := new(Statistics)
+		Ω(loaded.UnmarshalBinary(raw)).Should(Succeed())
+		Ω(loaded.Serialize()).Should(Equal(stats.Serialize()))
+		Ω(loaded.Quantile(0.25)).Should(Equal(stats.Quantile(0.25)))
+
+		Ω(loaded.UnmarshalBinary(raw[:len(raw)-1])).ShouldNot(Succeed())
+		Ω(loaded.UnmarshalBinary(append([]byte{42}, raw[1:]...))).ShouldNot(Succeed())
+
+		// Failed loads should not modify the statistics
+		Ω(loaded.Serialize()).Should(Equal(stats.Serialize()))
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		RegisterTestingT(t)
+
+		raw, err := json.Marshal(new(Statistics))
+		Ω(err).ShouldNot(HaveOccurred())
+
+		loaded := new(Statistics)
+		loaded.Update(1, 2, 3)
+		Ω(json.Unmarshal(raw, loaded)).Should(Succeed())
+		Ω(loaded.N()).Should(BeZero())
+		Ω(loaded.Quantile(0.5)).Should(BeZero())
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		RegisterTestingT(t)
+
+		// Persist the first half of the data and load it after a "restart"
+		s := new(Statistics)
+		s.Update(data[:len(data)/2]...)
+		raw, err := s.MarshalBinary()
+		Ω(err).ShouldNot(HaveOccurred())
+
+		loaded := new(Statistics)
+		Ω(loaded.UnmarshalBinary(raw)).Should(Succeed())
+		loaded.Update(data[len(data)/2:]...)
+
+		Ω(loaded.N()).Should(Equal(stats.N()))
+		Ω(loaded.Mean()).Should(BeNumerically("~", stats.Mean(), 1e-12))
+		Ω(loaded.Variance()).Should(BeNumerically("~", stats.Variance(), 1e-12))
+		Ω(loaded.Quantile(0.99)).Should(BeNumerically("~", stats.Quantile(0.99), 0.01))
+
+		// Loaded statistics can also be appended to current statistics
+		o := new(Statistics)
+		Ω(o.UnmarshalBinary(raw)).Should(Succeed())
+		c := new(Statistics)
+		c.Update(data[len(data)/2:]...)
+		c.Append(o)
+		Ω(c.N()).Should(Equal(stats.N()))
+		Ω(c.Mean()).Should(BeNumerically("~", stats.Mean(), 1e-12))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		RegisterTestingT(t)
+
+		loaded := new(Statistics)
+		Ω(json.Unmarshal([]byte(`{"samples": 1, "centroids": [[1, 0]]}`), loaded)).ShouldNot(Succeed())
+		Ω(json.Unmarshal([]byte(`{"samples": 0, "centroids": [[1, 1]]}`), loaded)).ShouldNot(Succeed())
+	})
+}
+
+func TestBenchmarkDumpLoad(t *testing.T) {
+	RegisterTestingT(t)
+
+	bench := new(Benchmark)
+	bench.Start()
+	for i := 1; i <= 1000; i++ {
+		bench.Update(time.Duration(i) * time.Microsecond)
+	}
+	bench.Update(0, 0)
+	bench.Stop()
+
+	check := func(loaded *Benchmark) {
+		Ω(loaded.Serialize()).Should(Equal(bench.Serialize()))
+		Ω(loaded.Timeouts()).Should(Equal(uint64(2)))
+		Ω(loaded.Duration()).Should(Equal(bench.Duration()))
+		Ω(loaded.Quantile(0.9)).Should(Equal(bench.Quantile(0.9)))
+	}
+
+	buf := &bytes.Buffer{}
+	Ω(bench.Dump(buf)).Should(Succeed())
+	loaded := new(Benchmark)
+	Ω(loaded.Load(buf)).Should(Succeed())
+	check(loaded)
+
+	raw, err := bench.MarshalBinary()
+	Ω(err).ShouldNot(HaveOccurred())
+	loaded = new(Benchmark)
+	Ω(loaded.UnmarshalBinary(raw)).Should(Succeed())
+	check(loaded)
+
+	// The benchmark trailer is required by the binary encoding
+	stats, err := bench.Statistics.MarshalBinary()
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(new(Benchmark).UnmarshalBinary(stats)).ShouldNot(Succeed())
+
+	// An unstarted benchmark should remain unstarted when loaded
+	unstarted := new(Benchmark)
+	unstarted.Update(time.Second)
+	raw, err = unstarted.MarshalBinary()
+	Ω(err).ShouldNot(HaveOccurred())
+	loaded = new(Benchmark)
+	Ω(loaded.UnmarshalBinary(raw)).Should(Succeed())
+	Ω(loaded.Duration()).Should(BeZero())
+	Ω(loaded.Throughput()).Should(Equal(unstarted.Throughput()))
+}
@@ -0,0 +1,187 @@
+package stats
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Registry is a thread-safe collection of named metrics: counters, gauges,
+// statistics, and benchmarks. Metrics are created on first use so that
+// instrumented code can simply record values by name, e.g.
+//
+//	registry.Incr("heartbeat.fires", 1)
+//	registry.Time("heartbeat.latency", elapsed)
+//
+// The registry can be serialized as a whole to report every metric, e.g. from
+// a metrics endpoint. A name identifies a single metric, so using the same
+// name for metrics of different kinds, e.g. as both a counter and a gauge,
+// panics rather than silently shadowing the other metric when serialized.
+type Registry struct {
+	sync.RWMutex
+	counters   map[string]uint64
+	gauges     map[string]float64
+	statistics map[string]*Statistics
+	benchmarks map[string]*Benchmark
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		counters:   make(map[string]uint64),
+		gauges:     make(map[string]float64),
+		statistics: make(map[string]*Statistics),
+		benchmarks: make(map[string]*Benchmark),
+	}
+}
+
+// Incr increments the named counter by delta (thread-safe).
+func (r *Registry) Incr(name string, delta uint64) {
+	r.Lock()
+	defer r.Unlock()
+	r.claim(name, kindCounter)
+	r.counters[name] += delta
+}
+
+// Count returns the value of the named counter or zero if it does not exist.
+func (r *Registry) Count(name string) uint64 {
+	r.RLock()
+	defer r.RUnlock()
+	return r.counters[name]
+}
+
+// Set the named gauge to the value (thread-safe).
+func (r *Registry) Set(name string, value float64) {
+	r.Lock()
+	defer r.Unlock()
+	r.claim(name, kindGauge)
+	r.gauges[name] = value
+}
+
+// Gauge returns the value of the named gauge or zero if it does not exist.
+func (r *Registry) Gauge(name string) float64 {
+	r.RLock()
+	defer r.RUnlock()
+	return r.gauges[name]
+}
+
+// Observe updates the named statistics with one or more samples (thread-safe).
+func (r *Registry) Observe(name string, samples ...float64) {
+	r.Statistics(name).Update(samples...)
+}
+
+// Time updates the named benchmark with one or more durations (thread-safe).
+func (r *Registry) Time(name string, durations ...time.Duration) {
+	r.Benchmark(name).Update(durations...)
+}
+
+// Statistics returns the named statistics, creating them if they do not exist.
+func (r *Registry) Statistics(name string) *Statistics {
+	r.RLock()
+	stats, ok := r.statistics[name]
+	r.RUnlock()
+	if ok {
+		return stats
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if stats, ok = r.statistics[name]; !ok {
+		r.claim(name, kindStatistics)
+		stats = new(Statistics)
+		r.statistics[name] = stats
+	}
+	return stats
+}
+
+// Benchmark returns the named benchmark, creating it if it does not exist.
+func (r *Registry) Benchmark(name string) *Benchmark {
+	r.RLock()
+	bench, ok := r.benchmarks[name]
+	r.RUnlock()
+	if ok {
+		return bench
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if bench, ok = r.benchmarks[name]; !ok {
+		r.claim(name, kindBenchmark)
+		bench = new(Benchmark)
+		r.benchmarks[name] = bench
+	}
+	return bench
+}
+
+// Names returns the sorted names of all of the metrics in the registry.
+func (r *Registry) Names() []string {
+	r.RLock()
+	defer r.RUnlock()
+
+	names := make([]string, 0, len(r.counters)+len(r.gauges)+len(r.statistics)+len(r.benchmarks))
+	for name := range r.counters {
+		names = append(names, name)
+	}
+	for name := range r.gauges {
+		names = append(names, name)
+	}
+	for name := range r.statistics {
+		names = append(names, name)
+	}
+	for name := range r.benchmarks {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Serialize returns a map of every metric in the registry keyed by name. The
+// values of counters and gauges are numbers, while statistics and benchmarks
+// are serialized as maps of their summary statistics.
+func (r *Registry) Serialize() map[string]interface{} {
+	r.RLock()
+	defer r.RUnlock()
+
+	data := make(map[string]interface{})
+	for name, count := range r.counters {
+		data[name] = count
+	}
+	for name, value := range r.gauges {
+		data[name] = value
+	}
+	for name, stats := range r.statistics {
+		data[name] = stats.Serialize()
+	}
+	for name, bench := range r.benchmarks {
+		data[name] = bench.Serialize()
+	}
+	return data
+}
+
+// Kinds of metrics used to report conflicting names.
+const (
+	kindCounter    = "counter"
+	kindGauge      = "gauge"
+	kindStatistics = "statistics"
+	kindBenchmark  = "benchmark"
+)
+
+// claim panics if the name is already used by a metric of a different kind; it
+// must be called with the write lock held before a metric is created.
+func (r *Registry) claim(name, kind string) {
+	var existing string
+	if _, ok := r.counters[name]; ok {
+		existing = kindCounter
+	} else if _, ok := r.gauges[name]; ok {
+		existing = kindGauge
+	} else if _, ok := r.statistics[name]; ok {
+		existing = kindStatistics
+	} else if _, ok := r.benchmarks[name]; ok {
+		existing = kindBenchmark
+	}
+
+	if existing != "" && existing != kind {
+		panic("stats: metric " + name + " is a " + existing + " not a " + kind)
+	}
+}
@@ -0,0 +1,72 @@
+package stats
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRegistry(t *testing.T) {
+	RegisterTestingT(t)
+
+	registry := NewRegistry()
+	registry.Incr("requests", 1)
+	registry.Incr("requests", 2)
+	registry.Set("peers", 5)
+	registry.Set("peers", 3)
+	registry.Observe("size", 1, 2, 3)
+	registry.Time("latency", time.Second, 3*time.Second)
+
+	Ω(registry.Count("requests")).Should(Equal(uint64(3)))
+	Ω(registry.Count("missing")).Should(BeZero())
+	Ω(registry.Gauge("peers")).Should(Equal(3.0))
+	Ω(registry.Statistics("size").Mean()).Should(Equal(2.0))
+	Ω(registry.Benchmark("latency").Mean()).Should(Equal(2 * time.Second))
+	Ω(registry.Names()).Should(Equal([]string{"latency", "peers", "requests", "size"}))
+
+	data := registry.Serialize()
+	Ω(data).Should(HaveLen(4))
+	Ω(data["requests"]).Should(Equal(uint64(3)))
+	Ω(data["peers"]).Should(Equal(3.0))
+	Ω(data["size"]).Should(HaveKeyWithValue("mean", 2.0))
+	Ω(data["latency"]).Should(HaveKeyWithValue("mean", "2s"))
+}
+
+func TestRegistryConflict(t *testing.T) {
+	RegisterTestingT(t)
+
+	registry := NewRegistry()
+	registry.Incr("requests", 1)
+	registry.Time("latency", time.Second)
+
+	Ω(func() { registry.Set("requests", 1) }).Should(PanicWith("stats: metric requests is a counter not a gauge"))
+	Ω(func() { registry.Observe("requests", 1) }).Should(Panic())
+	Ω(func() { registry.Incr("latency", 1) }).Should(Panic())
+	Ω(func() { registry.Incr("requests", 1) }).ShouldNot(Panic())
+	Ω(registry.Names()).Should(Equal([]string{"latency", "requests"}))
+	Ω(registry.Serialize()).Should(HaveKeyWithValue("requests", uint64(2)))
+}
+
+func TestRegistryConcurrency(t *testing.T) {
+	RegisterTestingT(t)
+
+	registry := NewRegistry()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				registry.Incr("count", 1)
+				registry.Time("latency", time.Millisecond)
+				registry.Serialize()
+			}
+		}()
+	}
+
+	wg.Wait()
+	Ω(registry.Count("count")).Should(Equal(uint64(800)))
+	Ω(registry.Benchmark("latency").N()).Should(Equal(uint64(800)))
+}
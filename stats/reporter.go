@@ -0,0 +1,248 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bbengfort/x/events"
+	"github.com/bbengfort/x/interval"
+)
+
+// DefaultReportInterval is how often a reporter writes the metrics if no
+// interval is specified.
+const DefaultReportInterval = 10 * time.Second
+
+//===========================================================================
+// Periodic Reporter
+//===========================================================================
+
+// Reporter periodically writes the serialized metrics of statistics,
+// benchmarks, rolling statistics, registries, and samplers to a writer so that
+// long benchmark runs leave a progress trail rather than only reporting when
+// they complete. Each report is written as a single line of JSON with the time
+// of the report, the time elapsed since the reporter was started, and the
+// serialized metrics keyed by the name they were added with, e.g.
+//
+//	{"timestamp":"2026-10-16T12:00:10Z","elapsed":10.0,"metrics":{"latency":{...}}}
+//
+// A final report is written when the reporter is closed. Errors writing
+// periodic reports do not stop the reporter; the first error is returned by
+// Close.
+type Reporter struct {
+	sync.Mutex
+	Interval time.Duration           // how often the metrics are written
+	w        io.Writer               // the writer the reports are written to
+	closer   io.Closer               // closed with the reporter if the reporter opened it
+	sources  map[string]interface{}  // the metrics to report keyed by name
+	ticker   *interval.FixedInterval // dispatches the periodic reports while running
+	started  time.Time               // when the reporter was started
+	reports  uint64                  // the number of reports written
+	err      error                   // the first error writing a periodic report
+	closed   bool                    // if the reporter has been closed
+}
+
+// NewReporter creates a reporter that writes to w every interval (or
+// DefaultReportInterval if the interval is not positive). The writer is not
+// closed when the reporter is closed.
+func NewReporter(w io.Writer, interval time.Duration) *Reporter {
+	if interval <= 0 {
+		interval = DefaultReportInterval
+	}
+
+	return &Reporter{
+		Interval: interval,
+		w:        w,
+		sources:  make(map[string]interface{}),
+	}
+}
+
+// NewFileReporter creates a reporter that appends to the file at path, creating
+// it if it does not exist. The file is closed when the reporter is closed.
+func NewFileReporter(path string, interval time.Duration) (*Reporter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open report file: %s", err)
+	}
+
+	r := NewReporter(f, interval)
+	r.closer = f
+	return r, nil
+}
+
+// Add metrics to the reports with the specified name, replacing any metrics
+// previously added with the name. The metrics must be a *Statistics,
+// *Benchmark, *Rolling, *Registry, or *Sampler.
+func (r *Reporter) Add(name string, metrics interface{}) error {
+	switch metrics.(type) {
+	case *Statistics, *Benchmark, *Rolling, *Registry, *Sampler:
+	default:
+		return fmt.Errorf("cannot report metrics of type %T", metrics)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.sources[name] = metrics
+	return nil
+}
+
+// Remove the named metrics from the reports.
+func (r *Reporter) Remove(name string) {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.sources, name)
+}
+
+// Start writing reports every interval in the background until Close is
+// called. The elapsed time of each report is measured from when the reporter
+// was first started.
+func (r *Reporter) Start() error {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.closed {
+		return errors.New("reporter is closed")
+	}
+
+	if r.ticker != nil && r.ticker.Running() {
+		return errors.New("reporter is already running")
+	}
+
+	if r.started.IsZero() {
+		r.started = time.Now()
+	}
+
+	delay := r.Interval
+	if delay <= 0 {
+		delay = DefaultReportInterval
+	}
+
+	// The ticker does not need an error channel since reports never return errors
+	r.ticker = interval.NewFixedInterval(delay, events.HeartbeatEvent, nil)
+	r.ticker.Register(r.tick)
+	r.ticker.Start()
+	return nil
+}
+
+// Report writes the current metrics immediately (thread-safe).
+func (r *Reporter) Report() error {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.closed {
+		return errors.New("reporter is closed")
+	}
+	return r.report()
+}
+
+// Reports returns the number of reports that have been written.
+func (r *Reporter) Reports() uint64 {
+	r.Lock()
+	defer r.Unlock()
+	return r.reports
+}
+
+// Close stops the reporter, waiting for an in-flight report to complete, then
+// writes a final report and closes the file if the reporter opened it. Returns
+// the first error writing a periodic report, if any, or the error writing the
+// final report. Closing a closed reporter returns nil.
+func (r *Reporter) Close() error {
+	r.Lock()
+	if r.closed {
+		r.Unlock()
+		return nil
+	}
+
+	ticker := r.ticker
+	r.ticker = nil
+	r.closed = true
+	r.Unlock()
+
+	// The lock is not held while waiting so that an in-flight report can finish
+	if ticker != nil {
+		ticker.StopWait(context.Background())
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	err := r.report()
+	if r.err != nil {
+		err = r.err
+	}
+
+	if r.closer != nil {
+		if cerr := r.closer.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("could not close report file: %s", cerr)
+		}
+	}
+	return err
+}
+
+// Writes a periodic report, recording the first error rather than returning
+// it, which would stop the interval.
+func (r *Reporter) tick(events.Event) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.report(); err != nil && r.err == nil {
+		r.err = err
+	}
+	return nil
+}
+
+// Writes the serialized metrics as a line of JSON (not thread-safe).
+func (r *Reporter) report() error {
+	now := time.Now()
+	report := struct {
+		Timestamp time.Time              `json:"timestamp"`
+		Elapsed   float64                `json:"elapsed"`
+		Metrics   map[string]interface{} `json:"metrics"`
+	}{
+		Timestamp: now,
+		Metrics:   make(map[string]interface{}, len(r.sources)),
+	}
+
+	if !r.started.IsZero() {
+		report.Elapsed = now.Sub(r.started).Seconds()
+	}
+
+	for name, metrics := range r.sources {
+		report.Metrics[name] = serialize(metrics)
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("could not serialize report: %s", err)
+	}
+
+	if _, err = r.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("could not write report: %s", err)
+	}
+
+	r.reports++
+	return nil
+}
+
+// Returns the serialized representation of the metrics added to a reporter.
+func serialize(metrics interface{}) interface{} {
+	switch m := metrics.(type) {
+	case *Statistics:
+		return m.Serialize()
+	case *Benchmark:
+		return m.Serialize()
+	case *Rolling:
+		return m.Serialize()
+	case *Registry:
+		return m.Serialize()
+	case *Sampler:
+		return m.Serialize()
+	default:
+		return nil
+	}
+}
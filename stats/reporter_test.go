@@ -0,0 +1,120 @@
+package stats
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestReporter(t *testing.T) {
+	RegisterTestingT(t)
+
+	buf := new(syncBuffer)
+	reporter := NewReporter(buf, 10*time.Millisecond)
+
+	latency := new(Benchmark)
+	registry := NewRegistry()
+	Ω(reporter.Add("latency", latency)).Should(Succeed())
+	Ω(reporter.Add("registry", registry)).Should(Succeed())
+	Ω(reporter.Add("invalid", "foo")).ShouldNot(Succeed())
+
+	Ω(reporter.Start()).Should(Succeed())
+	Ω(reporter.Start()).ShouldNot(Succeed())
+
+	latency.Update(time.Millisecond, 2*time.Millisecond)
+	registry.Incr("requests", 2)
+	Eventually(reporter.Reports).Should(BeNumerically(">=", 2))
+
+	Ω(reporter.Close()).Should(Succeed())
+	Ω(reporter.Close()).Should(Succeed())
+	Ω(reporter.Report()).ShouldNot(Succeed())
+	Ω(reporter.Start()).ShouldNot(Succeed())
+
+	// Every report is a line of JSON and the final report has the latest metrics
+	lines := buf.Lines()
+	Ω(lines).Should(HaveLen(int(reporter.Reports())))
+
+	var report struct {
+		Timestamp time.Time                         `json:"timestamp"`
+		Elapsed   float64                           `json:"elapsed"`
+		Metrics   map[string]map[string]interface{} `json:"metrics"`
+	}
+	Ω(json.Unmarshal(lines[len(lines)-1], &report)).Should(Succeed())
+	Ω(report.Elapsed).Should(BeNumerically(">", 0))
+	Ω(report.Metrics).Should(HaveKey("latency"))
+	Ω(report.Metrics["latency"]).Should(HaveKeyWithValue("samples", BeNumerically("==", 2)))
+	Ω(report.Metrics["registry"]).Should(HaveKey("requests"))
+}
+
+func TestFileReporter(t *testing.T) {
+	RegisterTestingT(t)
+
+	path := filepath.Join(t.TempDir(), "report.jsonl")
+	stats := new(Statistics)
+	stats.Update(1, 2, 3)
+
+	reporter, err := NewFileReporter(path, 0)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(reporter.Interval).Should(Equal(DefaultReportInterval))
+	Ω(reporter.Add("stats", stats)).Should(Succeed())
+
+	// Without starting, only the manual and final reports are written
+	Ω(reporter.Report()).Should(Succeed())
+	Ω(reporter.Close()).Should(Succeed())
+
+	data, err := os.ReadFile(path)
+	Ω(err).ShouldNot(HaveOccurred())
+	Ω(bytes.Count(data, []byte("\n"))).Should(Equal(2))
+	Ω(string(data)).Should(ContainSubstring(`"mean":2`))
+
+	_, err = NewFileReporter(filepath.Join(path, "missing", "report.jsonl"), 0)
+	Ω(err).Should(HaveOccurred())
+}
+
+func TestReporterWriteError(t *testing.T) {
+	RegisterTestingT(t)
+
+	reporter := NewReporter(failWriter{}, time.Millisecond)
+	Ω(reporter.Start()).Should(Succeed())
+	time.Sleep(10 * time.Millisecond)
+
+	err := reporter.Close()
+	Ω(err).Should(MatchError(ContainSubstring("could not write report")))
+	Ω(reporter.Reports()).Should(BeZero())
+}
+
+// A thread-safe buffer to read the reports while the reporter is writing.
+type syncBuffer struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Lines() (lines [][]byte) {
+	b.Lock()
+	defer b.Unlock()
+	scanner := bufio.NewScanner(bytes.NewReader(b.buf.Bytes()))
+	for scanner.Scan() {
+		lines = append(lines, append([]byte(nil), scanner.Bytes()...))
+	}
+	return lines
+}
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
@@ -0,0 +1,164 @@
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultRollingBuckets is the number of buckets a rolling window is divided
+// into if the number of buckets is not positive.
+const DefaultRollingBuckets = 10
+
+//===========================================================================
+// Rolling Statistics
+//===========================================================================
+
+// Rolling keeps descriptive statistics of the samples in a sliding window,
+// either the samples of a recent duration of time (e.g. the last 5 minutes) or
+// the most recent number of samples, rather than of every sample since the
+// process started, e.g. so that dashboards can report the p95 latency of the
+// last 5 minutes. The window is divided into a ring of buckets, each of which
+// aggregates the samples of a fraction of the window in its own Statistics; as
+// the window slides, the oldest bucket expires and is reset. The statistics of
+// the window are computed by appending the statistics of the buckets that have
+// not expired, so the memory used does not depend on the number of samples.
+//
+// The window slides one bucket at a time, so the statistics include between
+// buckets-1 and buckets full buckets of samples: a window of 5 minutes with 10
+// buckets reports the samples of the last 4.5 to 5 minutes. More buckets slide
+// more smoothly at the cost of memory and of the time to compute the
+// statistics of the window.
+type Rolling struct {
+	sync.Mutex
+	window    time.Duration    // the duration of each bucket if the window is a duration of time
+	limit     uint64           // the number of samples of each bucket if the window is a number of samples
+	buckets   []bucket         // the ring of buckets of the window
+	head      int              // the index of the current bucket
+	epoch     int64            // the interval of the current bucket if the window is a duration of time
+	quantiles []float64        // the quantiles to serialize, DefaultQuantiles if nil
+	now       func() time.Time // the clock used to expire buckets of a time window
+}
+
+// A bucket aggregates the samples of a fraction of the window.
+type bucket struct {
+	stats *Statistics // the statistics of the samples in the bucket
+	epoch int64       // the index of the interval of the bucket if the window is a duration of time
+}
+
+// NewRolling creates rolling statistics of the samples in the last duration of
+// the window, divided into the number of buckets (or DefaultRollingBuckets if
+// buckets is not positive).
+func NewRolling(window time.Duration, buckets int) *Rolling {
+	r := newRolling(buckets)
+	r.window = window / time.Duration(len(r.buckets))
+	if r.window <= 0 {
+		r.window = 1
+	}
+	return r
+}
+
+// NewRollingCount creates rolling statistics of the last number of samples,
+// divided into the number of buckets (or DefaultRollingBuckets if buckets is
+// not positive).
+func NewRollingCount(samples uint64, buckets int) *Rolling {
+	r := newRolling(buckets)
+	r.limit = (samples + uint64(len(r.buckets)) - 1) / uint64(len(r.buckets))
+	if r.limit == 0 {
+		r.limit = 1
+	}
+	return r
+}
+
+// Creates rolling statistics with empty buckets.
+func newRolling(buckets int) *Rolling {
+	if buckets <= 0 {
+		buckets = DefaultRollingBuckets
+	}
+
+	r := &Rolling{buckets: make([]bucket, buckets), now: time.Now}
+	for i := range r.buckets {
+		r.buckets[i].stats = new(Statistics)
+	}
+	return r
+}
+
+// Update the current bucket of the window with a sample or samples
+// (thread-safe), expiring the oldest buckets as the window slides.
+func (r *Rolling) Update(samples ...float64) {
+	r.Lock()
+	defer r.Unlock()
+
+	// Time windows add all samples to the bucket of the current time
+	if r.limit == 0 {
+		r.slide()
+		r.buckets[r.head].stats.Update(samples...)
+		return
+	}
+
+	// Count windows move to the next bucket when the current bucket is full
+	for _, sample := range samples {
+		if r.buckets[r.head].stats.N() >= r.limit {
+			r.head = (r.head + 1) % len(r.buckets)
+			r.buckets[r.head].stats = new(Statistics)
+		}
+		r.buckets[r.head].stats.Update(sample)
+	}
+}
+
+// Statistics returns the statistics of the samples in the window by appending
+// the statistics of the buckets that have not expired. The returned statistics
+// are a copy that is not updated as the window slides.
+func (r *Rolling) Statistics() *Statistics {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.limit == 0 {
+		r.slide()
+	}
+
+	stats := new(Statistics)
+	stats.quantiles = r.quantiles
+	for _, b := range r.buckets {
+		b.stats.RLock()
+		stats.Append(b.stats)
+		b.stats.RUnlock()
+	}
+	return stats
+}
+
+// SetQuantiles specifies the quantiles (between 0 and 1) that are returned by
+// Serialize and by the Quantiles of the statistics of the window instead of
+// DefaultQuantiles.
+func (r *Rolling) SetQuantiles(quantiles ...float64) {
+	r.Lock()
+	defer r.Unlock()
+	r.quantiles = append([]float64(nil), quantiles...)
+}
+
+// Serialize returns a map of the summary statistics of the samples in the
+// window (see Statistics.Serialize).
+func (r *Rolling) Serialize() map[string]float64 {
+	return r.Statistics().Serialize()
+}
+
+// Moves the head of a time window to the bucket of the current time, resetting
+// the buckets whose intervals have expired (not thread-safe).
+func (r *Rolling) slide() {
+	epoch := r.now().UnixNano() / int64(r.window)
+	if epoch == r.epoch {
+		return
+	}
+
+	n := int64(len(r.buckets))
+	r.epoch = epoch
+	r.head = int(((epoch % n) + n) % n)
+
+	// Each bucket holds the one interval of the window that it is congruent to
+	for i := range r.buckets {
+		b := &r.buckets[i]
+		if expected := epoch - int64((r.head-i+len(r.buckets))%len(r.buckets)); b.epoch != expected {
+			b.stats = new(Statistics)
+			b.epoch = expected
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRolling(t *testing.T) {
+	RegisterTestingT(t)
+
+	clock := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	rolling := NewRolling(5*time.Minute, 5)
+	rolling.now = func() time.Time { return clock }
+
+	// Empty windows have empty statistics
+	Ω(rolling.Statistics().N()).Should(BeZero())
+
+	// One sample per minute with the value of the minute
+	for i := 1; i <= 5; i++ {
+		rolling.Update(float64(i))
+		clock = clock.Add(time.Minute)
+	}
+
+	// The first bucket has expired when the sixth minute starts
+	stats := rolling.Statistics()
+	Ω(stats.N()).Should(Equal(uint64(4)))
+	Ω(stats.Minimum()).Should(Equal(2.0))
+	Ω(stats.Maximum()).Should(Equal(5.0))
+	Ω(stats.Mean()).Should(Equal(3.5))
+
+	rolling.Update(100, 200)
+	stats = rolling.Statistics()
+	Ω(stats.N()).Should(Equal(uint64(6)))
+	Ω(stats.Maximum()).Should(Equal(200.0))
+
+	// Skipping ahead a full window expires every bucket
+	clock = clock.Add(5*time.Minute + 30*time.Second)
+	Ω(rolling.Statistics().N()).Should(BeZero())
+
+	rolling.Update(-1, -2, -3)
+	stats = rolling.Statistics()
+	Ω(stats.N()).Should(Equal(uint64(3)))
+	Ω(stats.Maximum()).Should(Equal(-1.0))
+	Ω(stats.Minimum()).Should(Equal(-3.0))
+
+	// A clock that goes backwards resets the window
+	clock = clock.Add(-time.Hour)
+	Ω(rolling.Statistics().N()).Should(BeZero())
+}
+
+func TestRollingQuantiles(t *testing.T) {
+	RegisterTestingT(t)
+
+	data, err := loadTestData()
+	Ω(err).ShouldNot(HaveOccurred())
+
+	clock := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	rolling := NewRolling(time.Minute, 0)
+	rolling.now = func() time.Time { return clock }
+	rolling.SetQuantiles(0.5, 0.95)
+
+	// Outliers in the expired buckets should not affect the quantiles
+	rolling.Update(1e6, 1e6, 1e6)
+	clock = clock.Add(time.Minute)
+	rolling.Update(data...)
+
+	expected := new(Statistics)
+	expected.Update(data...)
+
+	stats := rolling.Statistics()
+	Ω(stats.N()).Should(Equal(expected.N()))
+	Ω(stats.Maximum()).Should(Equal(expected.Maximum()))
+	Ω(stats.Quantile(0.95)).Should(BeNumerically("~", expected.Quantile(0.95), 0.01))
+
+	serialized := rolling.Serialize()
+	Ω(serialized).Should(HaveKey("p50"))
+	Ω(serialized).Should(HaveKey("p95"))
+	Ω(serialized).ShouldNot(HaveKey("p99"))
+	Ω(serialized["samples"]).Should(Equal(float64(len(data))))
+}
+
+func TestRollingCount(t *testing.T) {
+	RegisterTestingT(t)
+
+	rolling := NewRollingCount(100, 4)
+	for i := 1; i <= 100; i++ {
+		rolling.Update(float64(i))
+	}
+
+	stats := rolling.Statistics()
+	Ω(stats.N()).Should(Equal(uint64(100)))
+	Ω(stats.Minimum()).Should(Equal(1.0))
+
+	// The next sample expires the oldest bucket of 25 samples
+	rolling.Update(101)
+	stats = rolling.Statistics()
+	Ω(stats.N()).Should(Equal(uint64(76)))
+	Ω(stats.Minimum()).Should(Equal(26.0))
+	Ω(stats.Maximum()).Should(Equal(101.0))
+
+	for i := 102; i <= 1000; i++ {
+		rolling.Update(float64(i))
+	}
+
+	stats = rolling.Statistics()
+	Ω(stats.N()).Should(BeNumerically(">", 75))
+	Ω(stats.N()).Should(BeNumerically("<=", 100))
+	Ω(stats.Maximum()).Should(Equal(1000.0))
+	Ω(stats.Minimum()).Should(Equal(float64(1001 - stats.N())))
+}
+
+func TestStatisticsAppendNegative(t *testing.T) {
+	RegisterTestingT(t)
+
+	s, o := new(Statistics), new(Statistics)
+	o.Update(-3, -2, -1)
+	s.Append(o)
+	Ω(s.Maximum()).Should(Equal(-1.0))
+	Ω(s.Minimum()).Should(Equal(-3.0))
+}
@@ -0,0 +1,180 @@
+package stats
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// DefaultSampleInterval is how often the runtime is sampled if no interval is
+// specified. Sampling reads runtime.MemStats, which briefly stops the world, so
+// the interval should not be much shorter than this.
+const DefaultSampleInterval = time.Second
+
+// Names of the runtime metrics when a sampler records into a registry.
+const (
+	MetricGCPause    = "runtime.gc.pause"
+	MetricGCCount    = "runtime.gc.count"
+	MetricHeapAlloc  = "runtime.heap.alloc"
+	MetricGoroutines = "runtime.goroutines"
+)
+
+//===========================================================================
+// Runtime Sampler
+//===========================================================================
+
+// Sampler periodically records garbage collection pauses and runtime memory
+// statistics so that benchmarks can correlate latency spikes with the GC
+// without external tooling. Every GC pause since the previous sample is
+// recorded in the Pauses benchmark, while the heap and the number of go
+// routines are recorded at each sample. Only the garbage collections that
+// occur after the sampler is created (or started) are recorded.
+type Sampler struct {
+	sync.Mutex
+	Interval   time.Duration // how often the runtime is sampled
+	Pauses     *Benchmark    // the stop-the-world pause duration of each GC
+	HeapAlloc  *Statistics   // the bytes of allocated heap objects at each sample
+	Goroutines *Statistics   // the number of go routines at each sample
+	registry   *Registry     // if not nil, the number of GCs is also counted here
+	numGC      uint32        // the number of GCs completed as of the last sample
+	count      uint64        // the number of GCs recorded by the sampler
+	stop       chan struct{} // stops background sampling
+	done       chan struct{} // closed when background sampling has stopped
+}
+
+// NewSampler creates a sampler that records into new metrics every interval (or
+// DefaultSampleInterval if the interval is not positive). If registry is not nil,
+// the sampler records into the registry metrics named runtime.gc.pause,
+// runtime.heap.alloc, and runtime.goroutines, and counts GCs in
+// runtime.gc.count, so that they are serialized with the other metrics.
+func NewSampler(interval time.Duration, registry *Registry) *Sampler {
+	if interval <= 0 {
+		interval = DefaultSampleInterval
+	}
+
+	s := &Sampler{Interval: interval, registry: registry}
+	if registry != nil {
+		s.Pauses = registry.Benchmark(MetricGCPause)
+		s.HeapAlloc = registry.Statistics(MetricHeapAlloc)
+		s.Goroutines = registry.Statistics(MetricGoroutines)
+	} else {
+		s.Pauses = new(Benchmark)
+		s.HeapAlloc = new(Statistics)
+		s.Goroutines = new(Statistics)
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	s.numGC = mem.NumGC
+	return s
+}
+
+// Start sampling the runtime every interval in a background go routine until
+// Stop is called. The pauses of garbage collections that completed before the
+// sampler was started are not recorded.
+func (s *Sampler) Start() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.stop != nil {
+		return errors.New("sampler is already running")
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	s.numGC = mem.NumGC
+
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultSampleInterval
+	}
+
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	go s.run(interval, s.stop, s.done)
+	return nil
+}
+
+// Stop sampling the runtime, taking a final sample so that the GCs since the
+// last sample are recorded.
+func (s *Sampler) Stop() {
+	s.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+		s.Sample()
+	}
+}
+
+// Sample records the pauses of the GCs that completed since the last sample and
+// the current heap and number of go routines (thread-safe).
+func (s *Sampler) Sample() {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	s.Lock()
+	defer s.Unlock()
+
+	// The runtime only keeps the pauses of the most recent GCs
+	pauses := mem.NumGC - s.numGC
+	if pauses > uint32(len(mem.PauseNs)) {
+		pauses = uint32(len(mem.PauseNs))
+	}
+
+	durations := make([]time.Duration, 0, pauses)
+	for i := mem.NumGC - pauses; i < mem.NumGC; i++ {
+		// A zero duration is a timeout in a benchmark, so pauses are at least 1ns
+		pause := time.Duration(mem.PauseNs[i%uint32(len(mem.PauseNs))])
+		if pause == 0 {
+			pause = time.Nanosecond
+		}
+		durations = append(durations, pause)
+	}
+
+	s.numGC = mem.NumGC
+	s.count += uint64(pauses)
+	s.Pauses.Update(durations...)
+	s.HeapAlloc.Update(float64(mem.HeapAlloc))
+	s.Goroutines.Update(float64(runtime.NumGoroutine()))
+
+	if s.registry != nil && pauses > 0 {
+		s.registry.Incr(MetricGCCount, uint64(pauses))
+	}
+}
+
+// NumGC returns the number of garbage collections recorded by the sampler.
+func (s *Sampler) NumGC() uint64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.count
+}
+
+// Serialize returns a map of the runtime metrics keyed by their registry names.
+func (s *Sampler) Serialize() map[string]interface{} {
+	return map[string]interface{}{
+		MetricGCPause:    s.Pauses.Serialize(),
+		MetricGCCount:    s.NumGC(),
+		MetricHeapAlloc:  s.HeapAlloc.Serialize(),
+		MetricGoroutines: s.Goroutines.Serialize(),
+	}
+}
+
+// Samples the runtime every interval until stopped.
+func (s *Sampler) run(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.Sample()
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package stats
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestSampler(t *testing.T) {
+	RegisterTestingT(t)
+
+	sampler := NewSampler(10*time.Millisecond, nil)
+	Ω(sampler.Start()).Should(Succeed())
+	Ω(sampler.Start()).ShouldNot(Succeed())
+
+	for i := 0; i < 3; i++ {
+		runtime.GC()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	sampler.Stop()
+
+	Ω(sampler.NumGC()).Should(BeNumerically(">=", 3))
+	Ω(sampler.Pauses.N()).Should(Equal(sampler.NumGC()))
+	Ω(sampler.Pauses.Timeouts()).Should(BeZero())
+	Ω(sampler.HeapAlloc.N()).Should(BeNumerically(">=", 2))
+	Ω(sampler.Goroutines.Minimum()).Should(BeNumerically(">=", 1))
+
+	data := sampler.Serialize()
+	Ω(data).Should(HaveKeyWithValue(MetricGCCount, sampler.NumGC()))
+	Ω(data).Should(HaveKey(MetricGCPause))
+
+	// The sampler can be restarted after it is stopped
+	Ω(sampler.Start()).Should(Succeed())
+	sampler.Stop()
+}
+
+func TestSamplerRegistry(t *testing.T) {
+	RegisterTestingT(t)
+
+	registry := NewRegistry()
+	sampler := NewSampler(0, registry)
+	Ω(sampler.Interval).Should(Equal(DefaultSampleInterval))
+
+	runtime.GC()
+	sampler.Sample()
+
+	Ω(registry.Count(MetricGCCount)).Should(BeNumerically(">=", 1))
+	Ω(registry.Benchmark(MetricGCPause).N()).Should(BeNumerically(">=", 1))
+	Ω(registry.Statistics(MetricHeapAlloc).N()).Should(Equal(uint64(1)))
+	Ω(registry.Names()).Should(ContainElement(MetricGoroutines))
+}
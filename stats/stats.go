@@ -10,26 +10,42 @@ statistics at runtime, saving memory.
 To track statistics in an online fashion, you need to keep track of the
 various aggregates that are used to compute the final descriptives statistics
 of the distribution. For simple statistics such as the minimum, maximum,
-standard deviation, and mean you need to track the number of samples, the sum
-of samples, and the sum of the squares of all samples (along with the minimum
-and maximum value seen).
+standard deviation, and mean you need to track the number of samples, the
+running mean, and the sum of the squared differences from the mean (along with
+the minimum and maximum value seen). The mean and squared differences are
+updated with Welford's algorithm, which unlike the sum of the squares of the
+samples does not lose precision to cancellation when the variance is small
+relative to the mean, e.g. for long durations with little jitter.
 
 The primary entry point into this function is the Update method, where you
 can pass sample values and retrieve data back. All other methods are simply
 computations for values.
+
+Means and standard deviations do not describe skewed distributions such as
+latencies, so quantiles (e.g. the median and 99th percentile) and histograms
+are estimated from a t-digest, a streaming estimator that keeps a bounded
+number of weighted centroids rather than every sample.
 */
 package stats
 
 import (
 	"math"
+	"sort"
+	"strconv"
 	"sync"
 )
 
+// DefaultQuantiles are the quantiles reported by Serialize unless others are
+// specified with SetQuantiles: the median, 95th, and 99th percentiles.
+var DefaultQuantiles = []float64{0.5, 0.95, 0.99}
+
 // Statistics keeps track of descriptive statistics in an online fashion at
 // runtime without saving each individual sample in an array. It does this by
 // updating the internal state of summary aggregates including the number of
-// samples seen, the sum of values, and the sum of the value squared. It also
-// tracks the minimum and maximum values seen.
+// samples seen, the sum of values, the running mean, and the sum of the squared
+// differences from the mean (Welford's online algorithm). It also
+// tracks the minimum and maximum values seen. Quantiles and histograms are
+// estimated from a bounded t-digest of the samples.
 //
 // The primary entry point to the object is via the Update method, where one
 // or more samples can be passed. This object has unexported fields because
@@ -37,11 +53,14 @@ import (
 // from read-locked access methods.
 type Statistics struct {
 	sync.RWMutex
-	samples uint64  // number of samples seen
-	total   float64 // the sum of all samples
-	squares float64 // the sum of the squares of each sample
-	maximum float64 // the maximum sample observed
-	minimum float64 // the minimum sample observed
+	samples   uint64    // number of samples seen
+	total     float64   // the sum of all samples
+	mean      float64   // the running mean of the samples
+	m2        float64   // the sum of the squared differences from the mean
+	maximum   float64   // the maximum sample observed
+	minimum   float64   // the minimum sample observed
+	digest    *digest   // streaming estimate of the distribution of samples
+	quantiles []float64 // the quantiles to serialize, DefaultQuantiles if nil
 }
 
 // Update the statistics with a sample or samples (thread-safe). Note that
@@ -55,7 +74,16 @@ func (s *Statistics) Update(samples ...float64) {
 	for _, sample := range samples {
 		s.samples++
 		s.total += sample
-		s.squares += (sample * sample)
+
+		// Welford's update of the mean and squared differences from the mean
+		delta := sample - s.mean
+		s.mean += delta / float64(s.samples)
+		s.m2 += delta * (sample - s.mean)
+
+		if s.digest == nil {
+			s.digest = new(digest)
+		}
+		s.digest.add(sample)
 
 		// If this is our first sample then this value is both our maximum and
 		// our minimum value. Otherwise, perform comparisions.
@@ -88,17 +116,15 @@ func (s *Statistics) Total() float64 {
 	return s.total
 }
 
-// Mean returns the average for all samples, computed as the sum of values
-// divided by the total number of samples seen. If no samples have been added
-// then this function returns 0.0. Note that 0.0 is a valid mean and does
-// not necessarily mean that no samples have been tracked.
+// Mean returns the average for all samples, the running mean that is updated
+// with each sample rather than the sum of values divided by the number of
+// samples, which loses precision as the sum grows. If no samples have been
+// added then this function returns 0.0. Note that 0.0 is a valid mean and
+// does not necessarily mean that no samples have been tracked.
 func (s *Statistics) Mean() float64 {
 	s.RLock()
 	defer s.RUnlock()
-	if s.samples > 0 {
-		return s.total / float64(s.samples)
-	}
-	return 0.0
+	return s.mean
 }
 
 // Variance computes the variability of samples and describes the distance of
@@ -109,11 +135,8 @@ func (s *Statistics) Variance() float64 {
 	s.RLock()
 	defer s.RUnlock()
 
-	n := float64(s.samples)
 	if s.samples > 1 {
-		num := (n*s.squares - s.total*s.total)
-		den := (n * (n - 1))
-		return num / den
+		return s.m2 / float64(s.samples-1)
 	}
 
 	return 0.0
@@ -160,12 +183,94 @@ func (s *Statistics) Range() float64 {
 	return s.maximum - s.minimum
 }
 
+// Quantile returns the estimated value below which the fraction q (between 0
+// and 1) of the samples fall, e.g. Quantile(0.99) is the 99th percentile. The
+// estimate is interpolated from the t-digest of the samples; it is exact for
+// the minimum and maximum and most accurate near the tails. If no samples have
+// been added to the dataset, then this function returns 0.0.
+func (s *Statistics) Quantile(q float64) float64 {
+	s.RLock()
+	defer s.RUnlock()
+	return s.quantile(q)
+}
+
+// Computes the quantile without locking.
+func (s *Statistics) quantile(q float64) float64 {
+	if s.digest == nil {
+		return 0.0
+	}
+	return s.digest.quantile(q)
+}
+
+// SetQuantiles specifies the quantiles (between 0 and 1) that are returned by
+// Quantiles and Serialize instead of DefaultQuantiles, e.g. 0.999 to report
+// the 99.9th percentile. Any quantile can be estimated with Quantile no
+// matter which quantiles are specified.
+func (s *Statistics) SetQuantiles(quantiles ...float64) {
+	s.Lock()
+	defer s.Unlock()
+	s.quantiles = append([]float64(nil), quantiles...)
+}
+
+// Quantiles returns the estimate of each of the quantiles specified by
+// SetQuantiles (DefaultQuantiles by default) keyed by the quantile.
+func (s *Statistics) Quantiles() map[float64]float64 {
+	s.RLock()
+	defer s.RUnlock()
+
+	data := make(map[float64]float64)
+	for _, q := range s.reported() {
+		data[q] = s.quantile(q)
+	}
+	return data
+}
+
+// Histogram returns the estimated number of samples in each of the buckets,
+// which are specified by their upper bounds in increasing order. The count of
+// each bucket is the number of samples greater than the upper bound of the
+// previous bucket and less than or equal to its upper bound; the last count,
+// one more than the number of buckets, is the number of samples greater than
+// the upper bound of the last bucket, so the counts sum to the number of
+// samples. The counts are estimated from the t-digest of the samples. If the
+// buckets are not in increasing order, nil is returned.
+func (s *Statistics) Histogram(buckets []float64) []uint64 {
+	s.RLock()
+	defer s.RUnlock()
+
+	if !sort.Float64sAreSorted(buckets) {
+		return nil
+	}
+
+	counts := make([]uint64, len(buckets)+1)
+	if s.digest == nil {
+		return counts
+	}
+
+	var previous uint64
+	for i, bound := range buckets {
+		cumulative := uint64(math.Round(s.digest.cdf(bound) * float64(s.samples)))
+		counts[i] = cumulative - previous
+		previous = cumulative
+	}
+	counts[len(buckets)] = s.samples - previous
+	return counts
+}
+
+// Returns the quantiles to report without locking.
+func (s *Statistics) reported() []float64 {
+	if s.quantiles != nil {
+		return s.quantiles
+	}
+	return DefaultQuantiles
+}
+
 // Serialize returns a map of summary statistics. This map is useful for
 // dumping statistics to disk (using JSON for example) or for reporting the
-// statistics elsewhere.
+// statistics elsewhere. The estimates of the quantiles specified by
+// SetQuantiles are keyed by their percentile, e.g. p99 or p99.9.
 //
-// TODO: Create Dump and Load functions to get statistical data to and from
-// offline sources.
+// The summary cannot be loaded again; use Dump and Load to persist the
+// aggregate state of the statistics instead.
 func (s *Statistics) Serialize() map[string]float64 {
 	s.RLock()
 	defer s.RUnlock()
@@ -179,9 +284,33 @@ func (s *Statistics) Serialize() map[string]float64 {
 	data["minimum"] = s.Minimum()
 	data["maximum"] = s.Maximum()
 	data["range"] = s.Range()
+
+	for _, q := range s.reported() {
+		data[percentile(q)] = s.quantile(q)
+	}
 	return data
 }
 
+// Returns the serialization key of the quantile as a percentile, e.g. p99.9.
+func percentile(q float64) string {
+	return "p" + strconv.FormatFloat(math.Round(q*1e6)/1e4, 'f', -1, 64)
+}
+
+// Returns a copy of the statistics that does not share the digest, so that it
+// can be read after the lock is released (not thread-safe).
+func (s *Statistics) snapshot() *Statistics {
+	return &Statistics{
+		samples:   s.samples,
+		total:     s.total,
+		mean:      s.mean,
+		m2:        s.m2,
+		maximum:   s.maximum,
+		minimum:   s.minimum,
+		digest:    s.digest.clone(),
+		quantiles: s.quantiles,
+	}
+}
+
 // Append another statistics object to the current statistics object,
 // incrementing the distribution from the other object.
 func (s *Statistics) Append(o *Statistics) {
@@ -189,7 +318,7 @@ func (s *Statistics) Append(o *Statistics) {
 	// ensuring that zero valued items are not overriding the comparision.
 	// Must come before any other aggregation.
 	if o.samples > 0 {
-		if o.maximum > s.maximum {
+		if s.samples == 0 || o.maximum > s.maximum {
 			s.maximum = o.maximum
 		}
 
@@ -198,8 +327,24 @@ func (s *Statistics) Append(o *Statistics) {
 		}
 	}
 
+	// Combine the means and squared differences of both objects (Chan et al.),
+	// weighting the difference of the means so that the variance is the same
+	// as if the samples of both objects had been added to this object.
+	if o.samples > 0 {
+		n := float64(s.samples + o.samples)
+		delta := o.mean - s.mean
+		s.m2 += o.m2 + delta*delta*float64(s.samples)*float64(o.samples)/n
+		s.mean += delta * float64(o.samples) / n
+	}
+
 	// Update the current statistics object
 	s.total += o.total
 	s.samples += o.samples
-	s.squares += o.squares
+
+	if o.digest != nil {
+		if s.digest == nil {
+			s.digest = new(digest)
+		}
+		s.digest.append(o.digest)
+	}
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"sort"
 	"strconv"
 	"testing"
 
@@ -50,13 +51,16 @@ func ExampleStatistics() {
 	// Output:
 	// {
 	//   "maximum": 5.30507026071,
-	//   "mean": 0.00041124313405184064,
+	//   "mean": 0.0004112431340517687,
 	//   "minimum": -4.72206033824,
+	//   "p50": 0.0007611738592279635,
+	//   "p95": 1.6427796345535275,
+	//   "p99": 2.3239628984752008,
 	//   "range": 10.02713059895,
 	//   "samples": 1000000,
-	//   "stddev": 0.9988808397330513,
+	//   "stddev": 0.9988808397330432,
 	//   "total": 411.2431340518406,
-	//   "variance": 0.9977629319858057
+	//   "variance": 0.9977629319857896
 	// }
 }
 
@@ -73,9 +77,9 @@ func TestStatistics(t *testing.T) {
 	}
 
 	Ω(stats.N()).Should(Equal(uint64(1000000)))
-	Ω(stats.Mean()).Should(Equal(0.00041124313405184064))
-	Ω(stats.StdDev()).Should(Equal(0.9988808397330513))
-	Ω(stats.Variance()).Should(Equal(0.9977629319858057))
+	Ω(stats.Mean()).Should(Equal(0.0004112431340517687))
+	Ω(stats.StdDev()).Should(Equal(0.9988808397330432))
+	Ω(stats.Variance()).Should(Equal(0.9977629319857896))
 	Ω(stats.Maximum()).Should(Equal(5.30507026071))
 	Ω(stats.Minimum()).Should(Equal(-4.7220603382400004))
 	Ω(stats.Range()).Should(Equal(10.02713059895))
@@ -91,9 +95,9 @@ func TestStatisticsBulk(t *testing.T) {
 	stats.Update(data...)
 
 	Ω(stats.N()).Should(Equal(uint64(1000000)))
-	Ω(stats.Mean()).Should(Equal(0.00041124313405184064))
-	Ω(stats.StdDev()).Should(Equal(0.9988808397330513))
-	Ω(stats.Variance()).Should(Equal(0.9977629319858057))
+	Ω(stats.Mean()).Should(Equal(0.0004112431340517687))
+	Ω(stats.StdDev()).Should(Equal(0.9988808397330432))
+	Ω(stats.Variance()).Should(Equal(0.9977629319857896))
 	Ω(stats.Maximum()).Should(Equal(5.30507026071))
 	Ω(stats.Minimum()).Should(Equal(-4.7220603382400004))
 	Ω(stats.Range()).Should(Equal(10.02713059895))
@@ -137,9 +141,9 @@ func TestStatisticsAppend(t *testing.T) {
 		o.Update(values...)
 		s.Append(o)
 
-		Ω(s.Mean()).Should(Equal(9.813435956500003))
-		Ω(s.StdDev()).Should(Equal(2.184890253256818))
-		Ω(s.Variance()).Should(Equal(4.773745418776643))
+		Ω(s.Mean()).Should(Equal(9.813435956499996))
+		Ω(s.StdDev()).Should(Equal(2.1848902532568277))
+		Ω(s.Variance()).Should(Equal(4.773745418776685))
 		Ω(s.Maximum()).Should(Equal(15.45832771))
 		Ω(s.Minimum()).Should(Equal(5.51224787))
 		Ω(s.Range()).Should(Equal(9.94607984))
@@ -152,9 +156,9 @@ func TestStatisticsAppend(t *testing.T) {
 		s.Update(values...)
 		s.Append(o)
 
-		Ω(s.Mean()).Should(Equal(9.813435956500003))
-		Ω(s.StdDev()).Should(Equal(2.184890253256818))
-		Ω(s.Variance()).Should(Equal(4.773745418776643))
+		Ω(s.Mean()).Should(Equal(9.813435956499996))
+		Ω(s.StdDev()).Should(Equal(2.1848902532568277))
+		Ω(s.Variance()).Should(Equal(4.773745418776685))
 		Ω(s.Maximum()).Should(Equal(15.45832771))
 		Ω(s.Minimum()).Should(Equal(5.51224787))
 		Ω(s.Range()).Should(Equal(9.94607984))
@@ -177,9 +181,9 @@ func TestStatisticsAppend(t *testing.T) {
 
 		s.Append(o)
 
-		Ω(s.Mean()).Should(Equal(9.8134359565))
-		Ω(s.StdDev()).Should(Equal(2.1848902532568206))
-		Ω(s.Variance()).Should(Equal(4.773745418776654))
+		Ω(s.Mean()).Should(Equal(9.813435956500001))
+		Ω(s.StdDev()).Should(Equal(2.184890253256827))
+		Ω(s.Variance()).Should(Equal(4.773745418776681))
 		Ω(s.Maximum()).Should(Equal(15.45832771))
 		Ω(s.Minimum()).Should(Equal(5.51224787))
 		Ω(s.Range()).Should(Equal(9.94607984))
@@ -202,9 +206,9 @@ func TestStatisticsAppend(t *testing.T) {
 
 		s.Append(o)
 
-		Ω(s.Mean()).Should(Equal(9.8134359565))
-		Ω(s.StdDev()).Should(Equal(2.1848902532568206))
-		Ω(s.Variance()).Should(Equal(4.773745418776654))
+		Ω(s.Mean()).Should(Equal(9.813435956500001))
+		Ω(s.StdDev()).Should(Equal(2.184890253256827))
+		Ω(s.Variance()).Should(Equal(4.773745418776681))
 		Ω(s.Maximum()).Should(Equal(15.45832771))
 		Ω(s.Minimum()).Should(Equal(5.51224787))
 		Ω(s.Range()).Should(Equal(9.94607984))
@@ -212,6 +216,104 @@ func TestStatisticsAppend(t *testing.T) {
 
 }
 
+func TestStatisticsPrecision(t *testing.T) {
+	RegisterTestingT(t)
+
+	// The sum of squares formula loses all precision to cancellation when the
+	// variance is small relative to the mean, e.g. for long durations
+	stats := new(Statistics)
+	stats.Update(1e9+4, 1e9+7, 1e9+13, 1e9+16)
+	Ω(stats.Mean()).Should(Equal(1e9 + 10))
+	Ω(stats.Variance()).Should(Equal(30.0))
+
+	// Appending should be as precise as updating with all of the samples
+	s, o := new(Statistics), new(Statistics)
+	s.Update(1e9+4, 1e9+7)
+	o.Update(1e9+13, 1e9+16)
+	s.Append(o)
+	Ω(s.N()).Should(Equal(uint64(4)))
+	Ω(s.Mean()).Should(Equal(1e9 + 10))
+	Ω(s.Variance()).Should(Equal(30.0))
+}
+
+func TestStatisticsQuantiles(t *testing.T) {
+	RegisterTestingT(t)
+
+	data, err := loadTestData()
+	Ω(err).ShouldNot(HaveOccurred())
+
+	stats := new(Statistics)
+	stats.Update(data...)
+
+	sorted := append([]float64(nil), data...)
+	sort.Float64s(sorted)
+	rank := func(value float64) float64 {
+		return float64(sort.SearchFloat64s(sorted, value)) / float64(len(sorted))
+	}
+
+	// The error of the rank of each estimate is smallest near the tails
+	for _, q := range []float64{0.001, 0.01, 0.05, 0.25, 0.5, 0.75, 0.95, 0.99, 0.999} {
+		Ω(rank(stats.Quantile(q))).Should(BeNumerically("~", q, 0.0001+0.005*q*(1-q)), "quantile %v", q)
+	}
+	Ω(stats.Quantile(0)).Should(Equal(stats.Minimum()))
+	Ω(stats.Quantile(1)).Should(Equal(stats.Maximum()))
+
+	quantiles := stats.Quantiles()
+	Ω(quantiles).Should(HaveLen(3))
+	Ω(quantiles).Should(HaveKeyWithValue(0.99, stats.Quantile(0.99)))
+
+	stats.SetQuantiles(0.9, 0.999)
+	Ω(stats.Quantiles()).Should(HaveLen(2))
+	data2 := stats.Serialize()
+	Ω(data2).Should(HaveKey("p90"))
+	Ω(data2).Should(HaveKey("p99.9"))
+	Ω(data2).ShouldNot(HaveKey("p50"))
+
+	// Quantiles should be preserved when statistics are appended
+	s, o := new(Statistics), new(Statistics)
+	s.Update(data[:len(data)/2]...)
+	o.Update(data[len(data)/2:]...)
+	s.Append(o)
+	Ω(rank(s.Quantile(0.99))).Should(BeNumerically("~", 0.99, 0.0001))
+	Ω(rank(s.Quantile(0.5))).Should(BeNumerically("~", 0.5, 0.0015))
+
+	// Empty statistics have zero quantiles
+	Ω(new(Statistics).Quantile(0.5)).Should(BeZero())
+}
+
+func TestStatisticsHistogram(t *testing.T) {
+	RegisterTestingT(t)
+
+	data, err := loadTestData()
+	Ω(err).ShouldNot(HaveOccurred())
+
+	stats := new(Statistics)
+	stats.Update(data...)
+
+	buckets := []float64{-2, -1, 0, 1, 2}
+	counts := stats.Histogram(buckets)
+	Ω(counts).Should(HaveLen(len(buckets) + 1))
+
+	exact := make([]uint64, len(buckets)+1)
+	for _, v := range data {
+		exact[sort.SearchFloat64s(buckets, v)]++
+	}
+
+	var total uint64
+	for i, count := range counts {
+		total += count
+		Ω(float64(count)).Should(BeNumerically("~", float64(exact[i]), 0.002*float64(len(data))), "bucket %d", i)
+	}
+	Ω(total).Should(Equal(stats.N()))
+
+	// Buckets must be in increasing order
+	Ω(stats.Histogram([]float64{1, 0})).Should(BeNil())
+
+	// Buckets outside of the range of the samples are empty
+	Ω(stats.Histogram([]float64{-10, 10})).Should(Equal([]uint64{0, stats.N(), 0}))
+	Ω(new(Statistics).Histogram(buckets)).Should(Equal(make([]uint64, len(buckets)+1)))
+}
+
 func BenchmarkStatistics_Update(b *testing.B) {
 	rand.Seed(42)
 	stats := new(Statistics)